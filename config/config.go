@@ -16,12 +16,15 @@ package config
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -61,10 +64,18 @@ const (
 	DefHost = "0.0.0.0"
 	// DefStatusHost is the default status host of TiDB
 	DefStatusHost = "0.0.0.0"
+	// DefMaxChunkSize is the default value of Performance.MaxChunkSize.
+	DefMaxChunkSize = 1024
+	// MaxChunkSizeLowerBound is the lower bound of Performance.MaxChunkSize.
+	MaxChunkSizeLowerBound = 32
+	// MaxChunkSizeUpperBound is the upper bound of Performance.MaxChunkSize.
+	MaxChunkSizeUpperBound = 65536
 	// DefTableColumnCountLimit is limit of the number of columns in a table
 	DefTableColumnCountLimit = 1017
 	// DefMaxOfTableColumnCountLimit is maximum limitation of the number of columns in a table
 	DefMaxOfTableColumnCountLimit = 4096
+	// MinRSAKeySize is the minimum size of the automatically generated RSA keys.
+	MinRSAKeySize = 2048
 )
 
 // Valid config maps
@@ -122,17 +133,24 @@ type Config struct {
 	Binlog                     Binlog             `toml:"binlog" json:"binlog"`
 	CompatibleKillQuery        bool               `toml:"compatible-kill-query" json:"compatible-kill-query"`
 	Plugin                     Plugin             `toml:"plugin" json:"plugin"`
+	QuotaPlugin                QuotaPlugin        `toml:"quota-plugin" json:"quota-plugin"`
 	PessimisticTxn             PessimisticTxn     `toml:"pessimistic-txn" json:"pessimistic-txn"`
 	CheckMb4ValueInUTF8        bool               `toml:"check-mb4-value-in-utf8" json:"check-mb4-value-in-utf8"`
 	MaxIndexLength             int                `toml:"max-index-length" json:"max-index-length"`
 	IndexLimit                 int                `toml:"index-limit" json:"index-limit"`
 	TableColumnCountLimit      uint32             `toml:"table-column-count-limit" json:"table-column-count-limit"`
 	GracefulWaitBeforeShutdown int                `toml:"graceful-wait-before-shutdown" json:"graceful-wait-before-shutdown"`
+	GracefulDrainTimeout       int                `toml:"graceful-drain-timeout" json:"graceful-drain-timeout"`
 	// AlterPrimaryKey is used to control alter primary key feature.
 	AlterPrimaryKey bool `toml:"alter-primary-key" json:"alter-primary-key"`
 	// TreatOldVersionUTF8AsUTF8MB4 is use to treat old version table/column UTF8 charset as UTF8MB4. This is for compatibility.
 	// Currently not support dynamic modify, because this need to reload all old version schema.
 	TreatOldVersionUTF8AsUTF8MB4 bool `toml:"treat-old-version-utf8-as-utf8mb4" json:"treat-old-version-utf8-as-utf8mb4"`
+	// SocketPermissions is the file mode of the unix socket file in octal, such as "0770".
+	SocketPermissions string `toml:"socket-permissions" json:"socket-permissions"`
+	// CharsetMismatchCheck is the action when a statement declared in a legacy character_set_client,
+	// such as gbk, is actually encoded in utf8.
+	CharsetMismatchCheck string `toml:"charset-mismatch-check" json:"charset-mismatch-check"`
 	// EnableTableLock indicate whether enable table lock.
 	// TODO: remove this after table lock features stable.
 	EnableTableLock     bool        `toml:"enable-table-lock" json:"enable-table-lock"`
@@ -147,6 +165,8 @@ type Config struct {
 	IsolationRead IsolationRead `toml:"isolation-read" json:"isolation-read"`
 	// MaxServerConnections is the maximum permitted number of simultaneous client connections.
 	MaxServerConnections uint32 `toml:"max-server-connections" json:"max-server-connections"`
+	// MaxConnectionsPerIP is the maximum permitted number of simultaneous client connections from the same IP.
+	MaxConnectionsPerIP uint32 `toml:"max-connections-per-ip" json:"max-connections-per-ip"`
 	// NewCollationsEnabledOnFirstBootstrap indicates if the new collations are enabled, it effects only when a TiDB cluster bootstrapped on the first time.
 	NewCollationsEnabledOnFirstBootstrap bool `toml:"new_collations_enabled_on_first_bootstrap" json:"new_collations_enabled_on_first_bootstrap"`
 	// Experimental contains parameters for experimental features.
@@ -203,6 +223,16 @@ func (c *Config) UpdateTempStoragePath() {
 	}
 }
 
+// DataDir returns the local directory of the files kept across restarts, such as the automatically created
+// TLS certificates. It is the path of the local stores, or the directory of the instance which contains the
+// temp storage path if the path is not a local directory, e.g. the PD addresses of TiKV.
+func (c *Config) DataDir() string {
+	if c.Store != "tikv" && c.Path != "" {
+		return c.Path
+	}
+	return filepath.Dir(c.TempStoragePath)
+}
+
 func (c *Config) getTiKVConfig() *tikvcfg.Config {
 	return &tikvcfg.Config{
 		CommitterConcurrency:  c.Performance.CommitterConcurrency,
@@ -355,13 +385,23 @@ type Log struct {
 	EnableErrorStack nullableBool `toml:"enable-error-stack" json:"enable-error-stack"`
 	// File log config.
 	File logutil.FileLogConfig `toml:"file" json:"file"`
+	// KeepRawBinaryBytes keeps the bytes which are not valid UTF-8 in the statement text written to
+	// the logs and warnings, instead of hex-escaping them.
+	KeepRawBinaryBytes bool `toml:"keep-raw-binary-bytes" json:"keep-raw-binary-bytes"`
 
 	EnableSlowLog       AtomicBool `toml:"enable-slow-log" json:"enable-slow-log"`
 	SlowQueryFile       string     `toml:"slow-query-file" json:"slow-query-file"`
+	SlowQueryFormat     string     `toml:"slow-query-format" json:"slow-query-format"`
 	SlowThreshold       uint64     `toml:"slow-threshold" json:"slow-threshold"`
 	ExpensiveThreshold  uint       `toml:"expensive-threshold" json:"expensive-threshold"`
 	QueryLogMaxLen      uint64     `toml:"query-log-max-len" json:"query-log-max-len"`
 	RecordPlanInSlowLog uint32     `toml:"record-plan-in-slow-log" json:"record-plan-in-slow-log"`
+
+	// AuditLogFile is the file the connection and statement audit events are written to as JSON lines,
+	// the audit log is disabled if it is empty.
+	AuditLogFile string `toml:"audit-log-file" json:"audit-log-file"`
+	// GeneralLogFile is the file the general query log is written to when tidb_general_log_target is FILE.
+	GeneralLogFile string `toml:"general-log-file" json:"general-log-file"`
 }
 
 func (l *Log) getDisableTimestamp() bool {
@@ -410,10 +450,21 @@ type Security struct {
 	// EnableSEM prevents SUPER users from having full access.
 	EnableSEM bool `toml:"enable-sem" json:"enable-sem"`
 	// Allow automatic TLS certificate generation
-	AutoTLS         bool   `toml:"auto-tls" json:"auto-tls"`
+	AutoTLS bool `toml:"auto-tls" json:"auto-tls"`
+	// MinTLSVersion is the minimum TLS version accepted by the MySQL protocol server. See ParseTLSVersion.
 	MinTLSVersion   string `toml:"tls-version" json:"tls-version"`
 	RSAKeySize      int    `toml:"rsa-key-size" json:"rsa-key-size"`
 	SecureBootstrap bool   `toml:"secure-bootstrap" json:"secure-bootstrap"`
+	// The directory of the automatically created TLS certificates, the "auto-tls" directory under the data
+	// directory is used when unset.
+	AutoTLSPath string `toml:"auto-tls-path" json:"auto-tls-path"`
+	// The RSA private key used by sha256_password and caching_sha2_password to protect passwords
+	// sent over insecure transport. A key pair is generated under the data directory when unset.
+	SHA256PasswordPrivateKeyPath string `toml:"sha256-password-private-key-path" json:"sha256-password-private-key-path"`
+	// The max size in bytes of a file sent by LOAD DATA LOCAL INFILE, 0 means unlimited.
+	MaxLoadDataFileSize int64 `toml:"max-load-data-file-size" json:"max-load-data-file-size"`
+	// The max size in bytes of a file written by SELECT INTO OUTFILE, 0 means unlimited.
+	MaxOutfileBytes int64 `toml:"max-outfile-bytes" json:"max-outfile-bytes"`
 }
 
 // The ErrConfigValidationFailed error is used so that external callers can do a type assertion
@@ -445,6 +496,39 @@ type Status struct {
 	MetricsInterval uint   `toml:"metrics-interval" json:"metrics-interval"`
 	ReportStatus    bool   `toml:"report-status" json:"report-status"`
 	RecordQPSbyDB   bool   `toml:"record-db-qps" json:"record-db-qps"`
+	// PauseToken is required by the /pause, /resume, /drain and /drain/cancel APIs in the Authorization header.
+	// These APIs are disabled if it is empty.
+	PauseToken string `toml:"pause-token" json:"-"`
+	// AuthToken is required by the status APIs in the Authorization header if it is set,
+	// except the paths in AuthAllowlist.
+	AuthToken     string   `toml:"auth-token" json:"-"`
+	AuthAllowlist []string `toml:"auth-allowlist" json:"auth-allowlist"`
+	// AllowedHosts is the list of the IPs or CIDRs which can access the status server, all hosts are
+	// allowed if it is empty. See ParseHosts.
+	AllowedHosts []string `toml:"allowed-hosts" json:"allowed-hosts"`
+	// TrustedProxies is the list of the IPs or CIDRs of the proxies whose X-Forwarded-For header is
+	// used to find the client address for AllowedHosts.
+	TrustedProxies []string `toml:"trusted-proxies" json:"trusted-proxies"`
+	// TLS overrides the cluster-ssl-* configurations for the status server if it is set.
+	TLS StatusTLS `toml:"tls" json:"tls"`
+}
+
+// StatusTLS is the TLS configuration of the status server.
+type StatusTLS struct {
+	CA       string   `toml:"ca" json:"ca"`
+	Cert     string   `toml:"cert" json:"cert"`
+	Key      string   `toml:"key" json:"key"`
+	VerifyCN []string `toml:"verify-cn" json:"verify-cn"`
+}
+
+// StatusSecurity returns Security info for the status server, which is the status TLS configuration
+// if it is set, otherwise the Security info for cluster.
+func (c *Config) StatusSecurity() tikvcfg.Security {
+	statusTLS := c.Status.TLS
+	if statusTLS.CA == "" && statusTLS.Cert == "" && statusTLS.Key == "" {
+		return c.Security.ClusterSecurity()
+	}
+	return tikvcfg.NewSecurity(statusTLS.CA, statusTLS.Cert, statusTLS.Key, statusTLS.VerifyCN)
 }
 
 // Performance is the performance section of the config.
@@ -476,6 +560,18 @@ type Performance struct {
 	PlanReplayerGCLease string `toml:"plan-replayer-gc-lease" json:"plan-replayer-gc-lease"`
 	GOGC                int    `toml:"gogc" json:"gogc"`
 	EnforceMPP          bool   `toml:"enforce-mpp" json:"enforce-mpp"`
+	// ServerWriteBufferQuotaRatio is the ratio of ServerMemoryQuota that the write buffers and
+	// the spooled cursor rows of all the connections can hold.
+	ServerWriteBufferQuotaRatio float64 `toml:"server-write-buffer-quota-ratio" json:"server-write-buffer-quota-ratio"`
+	// MaxChunkSize is the default value of tidb_max_chunk_size, the max row count of a chunk during query execution.
+	// It should be in the range [MaxChunkSizeLowerBound, MaxChunkSizeUpperBound], that is [32, 65536].
+	MaxChunkSize uint `toml:"max-chunk-size" json:"max-chunk-size"`
+	// QueryCacheSizeBytes is the memory size of the server-side result cache for the read-only queries,
+	// 0 disables the cache.
+	QueryCacheSizeBytes uint64 `toml:"query-cache-size-bytes" json:"query-cache-size-bytes"`
+	// HandshakeTimeoutSeconds is the time limit of the handshake of a connection, including the authentication,
+	// 0 means no limit.
+	HandshakeTimeoutSeconds uint `toml:"handshake-timeout-seconds" json:"handshake-timeout-seconds"`
 }
 
 // PlanCache is the PlanCache section of the config.
@@ -567,6 +663,14 @@ type Plugin struct {
 	Load string `toml:"load" json:"load"`
 }
 
+// QuotaPlugin is the config for metering the usage of the users, such as for billing the tenants of the
+// SaaS deployments.
+type QuotaPlugin struct {
+	// StatementCounterEndpoint is the HTTP endpoint the number of the statements executed by each user is
+	// posted to, the statements are not metered if it is empty.
+	StatementCounterEndpoint string `toml:"statement-counter-endpoint" json:"statement-counter-endpoint"`
+}
+
 // StmtSummary is the config for statement summary.
 type StmtSummary struct {
 	// Enable statement summary or not.
@@ -585,8 +689,13 @@ type StmtSummary struct {
 
 // TopSQL is the config for TopSQL.
 type TopSQL struct {
-	// The TopSQL's data receiver address.
+	// The comma-separated addresses of the TopSQL data receivers, the data is sent to each of them.
 	ReceiverAddress string `toml:"receiver-address" json:"receiver-address"`
+	// ReportDir is the directory the TopSQL data is written to when the receiver address is empty.
+	ReportDir string `toml:"report-dir" json:"report-dir"`
+	// Compression is the gRPC compressor of the TopSQL data sent to the receivers, which is "none" or "gzip".
+	// The receivers must register the compressor to decompress the data.
+	Compression string `toml:"compression" json:"compression"`
 }
 
 // IsolationRead is the config for isolation read.
@@ -612,6 +721,8 @@ var defaultConf = Config{
 	AdvertiseAddress:             "",
 	Port:                         DefPort,
 	Socket:                       "/tmp/tidb-{Port}.sock",
+	SocketPermissions:            "0777",
+	CharsetMismatchCheck:         CharsetMismatchCheckOff,
 	Cors:                         "",
 	Store:                        "unistore",
 	Path:                         "/tmp/tidb",
@@ -638,15 +749,19 @@ var defaultConf = Config{
 	RepairMode:                   false,
 	RepairTableList:              []string{},
 	MaxServerConnections:         0,
+	MaxConnectionsPerIP:          0,
 	TxnLocalLatches:              defTiKVCfg.TxnLocalLatches,
 	LowerCaseTableNames:          2,
 	GracefulWaitBeforeShutdown:   0,
+	GracefulDrainTimeout:         60,
 	ServerVersion:                "",
 	Log: Log{
 		Level:               "info",
 		Format:              "text",
 		File:                logutil.NewFileLogConfig(logutil.DefaultLogMaxSize),
 		SlowQueryFile:       "tidb-slow.log",
+		SlowQueryFormat:     SlowQueryFormatText,
+		GeneralLogFile:      "tidb-general.log",
 		SlowThreshold:       logutil.DefaultSlowThreshold,
 		ExpensiveThreshold:  10000,
 		DisableErrorStack:   nbUnset,
@@ -663,6 +778,9 @@ var defaultConf = Config{
 		StatusPort:      DefStatusPort,
 		MetricsInterval: 15,
 		RecordQPSbyDB:   false,
+		AuthAllowlist:   []string{"/health", "/metrics"},
+		AllowedHosts:    []string{},
+		TrustedProxies:  []string{},
 	},
 	Performance: Performance{
 		MaxMemory:             0,
@@ -689,6 +807,11 @@ var defaultConf = Config{
 		GOGC:                100,
 		EnforceMPP:          false,
 		PlanReplayerGCLease: "10m",
+		// The write buffers can hold 20% of server-memory-quota.
+		ServerWriteBufferQuotaRatio: 0.2,
+		MaxChunkSize:                DefMaxChunkSize,
+		QueryCacheSizeBytes:         0,
+		HandshakeTimeoutSeconds:     10,
 	},
 	ProxyProtocol: ProxyProtocol{
 		Networks:      "",
@@ -910,6 +1033,18 @@ func (c *Config) Valid() error {
 	if c.Store == "mocktikv" && !c.RunDDL {
 		return fmt.Errorf("can't disable DDL on mocktikv")
 	}
+	if _, err := ParseSocketPermissions(c.SocketPermissions); err != nil {
+		return err
+	}
+	if _, err := ParseTLSVersion(c.Security.MinTLSVersion); err != nil {
+		return err
+	}
+	if _, err := ParseHosts(c.Status.AllowedHosts); err != nil {
+		return fmt.Errorf("invalid status.allowed-hosts: %v", err)
+	}
+	if _, err := ParseHosts(c.Status.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid status.trusted-proxies: %v", err)
+	}
 	if c.MaxIndexLength < DefMaxIndexLength || c.MaxIndexLength > DefMaxOfMaxIndexLength {
 		return fmt.Errorf("max-index-length should be [%d, %d]", DefMaxIndexLength, DefMaxOfMaxIndexLength)
 	}
@@ -919,6 +1054,24 @@ func (c *Config) Valid() error {
 	if c.Log.File.MaxSize > MaxLogFileSize {
 		return fmt.Errorf("invalid max log file size=%v which is larger than max=%v", c.Log.File.MaxSize, MaxLogFileSize)
 	}
+	c.CharsetMismatchCheck = strings.ToLower(c.CharsetMismatchCheck)
+	switch c.CharsetMismatchCheck {
+	case CharsetMismatchCheckOff, CharsetMismatchCheckWarn, CharsetMismatchCheckStrict:
+	default:
+		return fmt.Errorf("unsupported charset-mismatch-check %v, TiDB only supports [%v, %v, %v]", c.CharsetMismatchCheck,
+			CharsetMismatchCheckOff, CharsetMismatchCheckWarn, CharsetMismatchCheckStrict)
+	}
+	c.Log.SlowQueryFormat = strings.ToLower(c.Log.SlowQueryFormat)
+	if c.Log.SlowQueryFormat != SlowQueryFormatText && c.Log.SlowQueryFormat != SlowQueryFormatJSON {
+		return fmt.Errorf("unsupported slow-query-format %v, TiDB only supports [%v, %v]", c.Log.SlowQueryFormat, SlowQueryFormatText, SlowQueryFormatJSON)
+	}
+	c.TopSQL.Compression = strings.ToLower(c.TopSQL.Compression)
+	switch c.TopSQL.Compression {
+	case "", TopSQLCompressionNone, TopSQLCompressionGzip:
+	default:
+		return fmt.Errorf("unsupported top-sql.compression %v, TiDB only supports [%v, %v]", c.TopSQL.Compression,
+			TopSQLCompressionNone, TopSQLCompressionGzip)
+	}
 	c.OOMAction = strings.ToLower(c.OOMAction)
 	if c.OOMAction != OOMActionLog && c.OOMAction != OOMActionCancel {
 		return fmt.Errorf("unsupported OOMAction %v, TiDB only supports [%v, %v]", c.OOMAction, OOMActionLog, OOMActionCancel)
@@ -950,6 +1103,10 @@ func (c *Config) Valid() error {
 		return fmt.Errorf("memory-usage-alarm-ratio in [Performance] must be greater than or equal to 0 and less than or equal to 1")
 	}
 
+	if c.Performance.ServerWriteBufferQuotaRatio > 1 || c.Performance.ServerWriteBufferQuotaRatio < 0 {
+		return fmt.Errorf("server-write-buffer-quota-ratio in [Performance] must be greater than or equal to 0 and less than or equal to 1")
+	}
+
 	if c.StmtSummary.MaxStmtCount <= 0 {
 		return fmt.Errorf("max-stmt-count in [stmt-summary] should be greater than 0")
 	}
@@ -976,6 +1133,9 @@ func (c *Config) Valid() error {
 	}
 
 	// test security
+	if c.Security.RSAKeySize < MinRSAKeySize {
+		return fmt.Errorf("rsa-key-size in [security] must be at least %d", MinRSAKeySize)
+	}
 	c.Security.SpilledFileEncryptionMethod = strings.ToLower(c.Security.SpilledFileEncryptionMethod)
 	switch c.Security.SpilledFileEncryptionMethod {
 	case SpilledFileEncryptionMethodPlaintext, SpilledFileEncryptionMethodAES128CTR:
@@ -1019,9 +1179,63 @@ var TableLockDelayClean = func() uint64 {
 	return GetGlobalConfig().DelayCleanTableLock
 }
 
+// ParseSocketPermissions parses the file mode of the unix socket file. The mode must be in [0000, 0777]
+// octal, and it must allow the owner, which is the server process itself, to read and write the socket.
+func ParseSocketPermissions(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket-permissions %q, it should be an octal number in [0000, 0777]", s)
+	}
+	if mode&0600 != 0600 {
+		return 0, fmt.Errorf("invalid socket-permissions %q, it denies the tidb-server process itself", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ParseTLSVersion parses the tls-version, which is one of TLS10, TLS11, TLS12 and TLS13, or the
+// equivalent TLSv1.0, TLSv1.1, TLSv1.2 and TLSv1.3. TLS 1.2 is used if it is empty.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "TLS10", "TLSv1.0":
+		return tls.VersionTLS10, nil
+	case "TLS11", "TLSv1.1":
+		return tls.VersionTLS11, nil
+	case "TLS12", "TLSv1.2", "":
+		return tls.VersionTLS12, nil
+	case "TLS13", "TLSv1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid tls-version %q, it should be one of TLS10, TLS11, TLS12 and TLS13", version)
+}
+
+// ParseHosts parses the list of the IPs and CIDRs, such as "10.0.0.1" and "10.0.0.0/8". An IP is
+// the same as the CIDR which only contains itself.
+func ParseHosts(hosts []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(hosts))
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(host)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an IP nor a CIDR", host)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
 // ToLogConfig converts *Log to *logutil.LogConfig.
 func (l *Log) ToLogConfig() *logutil.LogConfig {
-	return logutil.NewLogConfig(l.Level, l.Format, l.SlowQueryFile, l.File, l.getDisableTimestamp(), func(config *zaplog.Config) { config.DisableErrorVerbose = l.getDisableErrorStack() })
+	c := logutil.NewLogConfig(l.Level, l.Format, l.SlowQueryFile, l.File, l.getDisableTimestamp(), func(config *zaplog.Config) { config.DisableErrorVerbose = l.getDisableErrorStack() })
+	c.KeepRawBinaryBytes = l.KeepRawBinaryBytes
+	c.GeneralLogFile = l.GeneralLogFile
+	return c
 }
 
 // ToTracingConfig converts *OpenTracing to *tracing.Configuration.
@@ -1069,6 +1283,32 @@ const (
 	OOMActionLog    = "log"
 )
 
+// The following constants represents the valid configurations for CharsetMismatchCheck.
+const (
+	// CharsetMismatchCheckOff disables the detection.
+	CharsetMismatchCheckOff = "off"
+	// CharsetMismatchCheckWarn counts the suspected statements and warns once per connection.
+	CharsetMismatchCheckWarn = "warn"
+	// CharsetMismatchCheckStrict rejects the suspected statements.
+	CharsetMismatchCheckStrict = "strict"
+)
+
+// The following constants represents the valid configurations for the compression of the TopSQL data.
+const (
+	// TopSQLCompressionNone sends the TopSQL data without compression.
+	TopSQLCompressionNone = "none"
+	// TopSQLCompressionGzip compresses the TopSQL data with gzip.
+	TopSQLCompressionGzip = "gzip"
+)
+
+// The following constants represents the valid configurations for the format of the slow query log.
+const (
+	// SlowQueryFormatText writes each slow query as the "# Key: Value" lines followed by the statement.
+	SlowQueryFormatText = "text"
+	// SlowQueryFormatJSON writes each slow query as a single-line JSON object.
+	SlowQueryFormatJSON = "json"
+)
+
 // hideConfig is used to filter a single line of config for hiding.
 var hideConfig = []string{
 	"index-usage-sync-lease",
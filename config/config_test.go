@@ -16,6 +16,7 @@ package config
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"os"
 	"os/user"
@@ -129,7 +130,9 @@ func TestLogConfig(t *testing.T) {
 		require.Equal(t, expectedDisableErrorStack, conf.Log.DisableErrorStack)
 		require.Equal(t, expectedEnableTimestamp, conf.Log.EnableTimestamp)
 		require.Equal(t, expectedDisableTimestamp, conf.Log.DisableTimestamp)
-		require.Equal(t, logutil.NewLogConfig("info", "text", "tidb-slow.log", conf.Log.File, resultedDisableTimestamp, func(config *zaplog.Config) { config.DisableErrorVerbose = resultedDisableErrorVerbose }), conf.Log.ToLogConfig())
+		expectedLogConfig := logutil.NewLogConfig("info", "text", "tidb-slow.log", conf.Log.File, resultedDisableTimestamp, func(config *zaplog.Config) { config.DisableErrorVerbose = resultedDisableErrorVerbose })
+		expectedLogConfig.GeneralLogFile = "tidb-general.log"
+		require.Equal(t, expectedLogConfig, conf.Log.ToLogConfig())
 		err := f.Truncate(0)
 		require.NoError(t, err)
 		_, err = f.Seek(0, 0)
@@ -259,11 +262,14 @@ group= "abc"
 zone= "dc-1"
 [security]
 spilled-file-encryption-method = "plaintext"
+require-secure-transport = true
 [pessimistic-txn]
 deadlock-history-capacity = 123
 deadlock-history-collect-retryable = true
 [top-sql]
 receiver-address = "127.0.0.1:10100"
+report-dir = "/tmp/top-sql"
+compression = "gzip"
 `)
 
 	require.NoError(t, err)
@@ -312,6 +318,7 @@ receiver-address = "127.0.0.1:10100"
 	require.Equal(t, "abc", conf.Labels["group"])
 	require.Equal(t, "dc-1", conf.Labels["zone"])
 	require.Equal(t, SpilledFileEncryptionMethodPlaintext, conf.Security.SpilledFileEncryptionMethod)
+	require.True(t, conf.Security.RequireSecureTransport)
 	require.True(t, conf.DeprecateIntegerDisplayWidth)
 	require.False(t, conf.EnableEnumLengthLimit)
 	require.True(t, conf.EnableForwarding)
@@ -320,6 +327,8 @@ receiver-address = "127.0.0.1:10100"
 	require.True(t, conf.PessimisticTxn.DeadlockHistoryCollectRetryable)
 	require.False(t, conf.Experimental.EnableNewCharset)
 	require.Equal(t, "127.0.0.1:10100", conf.TopSQL.ReceiverAddress)
+	require.Equal(t, "/tmp/top-sql", conf.TopSQL.ReportDir)
+	require.Equal(t, TopSQLCompressionGzip, conf.TopSQL.Compression)
 	require.True(t, conf.Experimental.AllowsExpressionIndex)
 
 	err = f.Truncate(0)
@@ -383,7 +392,9 @@ spilled-file-encryption-method = "aes128-ctr"
 	require.Equal(t, GetGlobalConfig(), conf)
 
 	// Test for log config.
-	require.Equal(t, logutil.NewLogConfig("info", "text", "tidb-slow.log", conf.Log.File, false, func(config *zaplog.Config) { config.DisableErrorVerbose = conf.Log.getDisableErrorStack() }), conf.Log.ToLogConfig())
+	expectedLogConfig := logutil.NewLogConfig("info", "text", "tidb-slow.log", conf.Log.File, false, func(config *zaplog.Config) { config.DisableErrorVerbose = conf.Log.getDisableErrorStack() })
+	expectedLogConfig.GeneralLogFile = "tidb-general.log"
+	require.Equal(t, expectedLogConfig, conf.Log.ToLogConfig())
 
 	// Test for tracing config.
 	tracingConf := &tracing.Configuration{
@@ -499,6 +510,63 @@ func TestOOMActionValid(t *testing.T) {
 	}
 }
 
+func TestCharsetMismatchCheckValid(t *testing.T) {
+	t.Parallel()
+
+	c1 := NewConfig()
+	tests := []struct {
+		check string
+		valid bool
+	}{
+		{"off", true},
+		{"Warn", true},
+		{"STRICT", true},
+		{"reject", false},
+	}
+	for _, tt := range tests {
+		c1.CharsetMismatchCheck = tt.check
+		require.Equal(t, tt.valid, c1.Valid() == nil)
+	}
+}
+
+func TestSlowQueryFormatValid(t *testing.T) {
+	t.Parallel()
+
+	c1 := NewConfig()
+	tests := []struct {
+		format string
+		valid  bool
+	}{
+		{"text", true},
+		{"JSON", true},
+		{"", false},
+		{"csv", false},
+	}
+	for _, tt := range tests {
+		c1.Log.SlowQueryFormat = tt.format
+		require.Equal(t, tt.valid, c1.Valid() == nil)
+	}
+}
+
+func TestTopSQLCompressionValid(t *testing.T) {
+	t.Parallel()
+
+	c1 := NewConfig()
+	tests := []struct {
+		compression string
+		valid       bool
+	}{
+		{"", true},
+		{"none", true},
+		{"GZIP", true},
+		{"snappy", false},
+	}
+	for _, tt := range tests {
+		c1.TopSQL.Compression = tt.compression
+		require.Equal(t, tt.valid, c1.Valid() == nil)
+	}
+}
+
 func TestTxnTotalSizeLimitValid(t *testing.T) {
 	t.Parallel()
 
@@ -551,6 +619,30 @@ func TestMaxIndexLength(t *testing.T) {
 	checkValid(DefMaxOfMaxIndexLength+1, false)
 }
 
+func TestRSAKeySize(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.Security.RSAKeySize = MinRSAKeySize
+	require.NoError(t, conf.Valid())
+	conf.Security.RSAKeySize = MinRSAKeySize - 1
+	require.EqualError(t, conf.Valid(), "rsa-key-size in [security] must be at least 2048")
+}
+
+func TestDataDir(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.Store = "unistore"
+	conf.Path = "/data/tidb"
+	require.Equal(t, "/data/tidb", conf.DataDir())
+	// The path of TiKV is the PD addresses.
+	conf.Store = "tikv"
+	conf.Path = "pd1:2379,pd2:2379"
+	conf.TempStoragePath = "/tmp/1000_tidb/instance/tmp-storage"
+	require.Equal(t, "/tmp/1000_tidb/instance", conf.DataDir())
+}
+
 func TestIndexLimit(t *testing.T) {
 	t.Parallel()
 
@@ -579,6 +671,81 @@ func TestTableColumnCountLimit(t *testing.T) {
 	checkValid(DefMaxOfTableColumnCountLimit+1, false)
 }
 
+func TestSocketPermissions(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	checkValid := func(socketPermissions string, shouldBeValid bool) {
+		conf.SocketPermissions = socketPermissions
+		require.Equal(t, shouldBeValid, conf.Valid() == nil)
+	}
+	checkValid("0777", true)
+	checkValid("0600", true)
+	checkValid("660", true)
+	checkValid("0000", false)
+	checkValid("0477", false)
+	checkValid("1777", false)
+	checkValid("0778", false)
+	checkValid("rwx", false)
+
+	mode, err := ParseSocketPermissions("0770")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0770), mode)
+}
+
+func TestTLSVersion(t *testing.T) {
+	t.Parallel()
+
+	for version, expected := range map[string]uint16{
+		"":        tls.VersionTLS12,
+		"TLS10":   tls.VersionTLS10,
+		"TLS11":   tls.VersionTLS11,
+		"TLS12":   tls.VersionTLS12,
+		"TLS13":   tls.VersionTLS13,
+		"TLSv1.0": tls.VersionTLS10,
+		"TLSv1.3": tls.VersionTLS13,
+	} {
+		v, err := ParseTLSVersion(version)
+		require.NoError(t, err, version)
+		require.Equal(t, expected, v, version)
+	}
+
+	conf := NewConfig()
+	conf.Security.MinTLSVersion = "TLS13"
+	require.NoError(t, conf.Valid())
+	for _, version := range []string{"TLS14", "tls12", "SSLv3"} {
+		conf.Security.MinTLSVersion = version
+		require.Error(t, conf.Valid(), version)
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	t.Parallel()
+
+	nets, err := ParseHosts([]string{"10.0.0.1", "192.168.0.0/16", "::1", "fe80::/10"})
+	require.NoError(t, err)
+	require.Len(t, nets, 4)
+	require.Equal(t, "10.0.0.1/32", nets[0].String())
+	require.Equal(t, "192.168.0.0/16", nets[1].String())
+	require.Equal(t, "::1/128", nets[2].String())
+	require.Equal(t, "fe80::/10", nets[3].String())
+
+	for _, host := range []string{"", "localhost", "10.0.0.0/33", "10.0.0.256"} {
+		_, err = ParseHosts([]string{host})
+		require.Error(t, err, host)
+	}
+
+	conf := NewConfig()
+	conf.Status.AllowedHosts = []string{"127.0.0.1", "10.0.0.0/8"}
+	conf.Status.TrustedProxies = []string{"10.1.1.1"}
+	require.NoError(t, conf.Valid())
+	conf.Status.AllowedHosts = []string{"127.0.0.1/40"}
+	require.Error(t, conf.Valid())
+	conf.Status.AllowedHosts = nil
+	conf.Status.TrustedProxies = []string{"proxy"}
+	require.Error(t, conf.Valid())
+}
+
 func TestEncodeDefTempStorageDir(t *testing.T) {
 	t.Parallel()
 
@@ -96,6 +96,12 @@ type Domain struct {
 	renewLeaseCh         chan func()       // It is used to call the renewLease function of the cache table.
 	onClose              func()
 	sysExecutorFactory   func(*Domain) (pools.Resource, error)
+
+	// invalidationListeners are called after a newer schema or the privileges are loaded.
+	invalidationListeners struct {
+		sync.Mutex
+		fns []func()
+	}
 }
 
 // loadInfoSchema loads infoschema at startTS.
@@ -400,6 +406,7 @@ func (do *Domain) Reload() error {
 	if !hitCache {
 		// loaded newer schema
 		if oldSchemaVersion < is.SchemaMetaVersion() {
+			do.notifyInvalidationListeners()
 			// Update self schema version to etcd.
 			err = do.ddl.SchemaSyncer().UpdateSelfVersion(context.Background(), is.SchemaMetaVersion())
 			if err != nil {
@@ -970,6 +977,8 @@ func (do *Domain) LoadPrivilegeLoop(ctx sessionctx.Context) error {
 			metrics.LoadPrivilegeCounter.WithLabelValues(metrics.RetLabel(err)).Inc()
 			if err != nil {
 				logutil.BgLogger().Error("load privilege failed", zap.Error(err))
+			} else {
+				do.notifyInvalidationListeners()
 			}
 		}
 	}()
@@ -1484,7 +1493,27 @@ func (do *Domain) NotifyUpdatePrivilege() error {
 		return err
 	}
 	defer sysSessionPool.Put(ctx)
-	return do.PrivilegeHandle().Update(ctx.(sessionctx.Context))
+	if err := do.PrivilegeHandle().Update(ctx.(sessionctx.Context)); err != nil {
+		return err
+	}
+	do.notifyInvalidationListeners()
+	return nil
+}
+
+// AddInvalidationListener registers fn, which is called after a newer schema or the privileges are loaded,
+// so the caches built on the old ones can be dropped. fn should return quickly.
+func (do *Domain) AddInvalidationListener(fn func()) {
+	do.invalidationListeners.Lock()
+	defer do.invalidationListeners.Unlock()
+	do.invalidationListeners.fns = append(do.invalidationListeners.fns, fn)
+}
+
+func (do *Domain) notifyInvalidationListeners() {
+	do.invalidationListeners.Lock()
+	defer do.invalidationListeners.Unlock()
+	for _, fn := range do.invalidationListeners.fns {
+		fn()
+	}
 }
 
 // NotifyUpdateSysVarCache updates the sysvar cache key in etcd, which other TiDB
@@ -263,6 +263,7 @@ func (is *InfoSyncer) getServerInfoByID(ctx context.Context, id string) (*Server
 }
 
 // GetAllServerInfo gets all servers static information from etcd.
+// It is not cached, INFORMATION_SCHEMA.TIDB_SERVERS_INFO always reads the latest information by it.
 func GetAllServerInfo(ctx context.Context) (map[string]*ServerInfo, error) {
 	is, err := getGlobalInfoSyncer()
 	if err != nil {
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/stmtsummary"
+	"github.com/pingcap/tidb/util/timeutil"
 	storekv "github.com/tikv/client-go/v2/kv"
 	pd "github.com/tikv/pd/client"
 	"go.uber.org/zap"
@@ -37,6 +38,9 @@ import (
 // - the cache is invalidated on update
 // - an etcd notification is sent to other tidb servers.
 
+// systemTZKey is the name of the system time zone in mysql.tidb.
+const systemTZKey = "system_tz"
+
 // sysVarCache represents the cache of system variables broken up into session and global scope.
 type sysVarCache struct {
 	sync.RWMutex // protects global and session maps
@@ -110,6 +114,30 @@ func (do *Domain) fetchTableValues(ctx sessionctx.Context) (map[string]string, e
 	return tableContents, nil
 }
 
+// loadSystemTZ applies the system time zone stored in mysql.tidb if it is changed.
+func (do *Domain) loadSystemTZ(ctx sessionctx.Context) error {
+	exec := ctx.(sqlexec.RestrictedSQLExecutor)
+	stmt, err := exec.ParseWithParams(context.Background(), `SELECT variable_value FROM mysql.tidb WHERE variable_name=%?`, systemTZKey)
+	if err != nil {
+		return err
+	}
+	rows, _, err := exec.ExecRestrictedStmt(context.TODO(), stmt)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	tz := rows[0].GetString(0)
+	if current, err := timeutil.GetSystemTZ(); err == nil && current == tz {
+		return nil
+	}
+	timeutil.UpdateSystemTZ(tz)
+	variable.SetSysVar(variable.SystemTimeZone, tz)
+	logutil.BgLogger().Info("system time zone is reloaded", zap.String("system_time_zone", tz))
+	return nil
+}
+
 // rebuildSysVarCache rebuilds the sysvar cache both globally and for session vars.
 // It needs to be called when sysvars are added or removed.
 func (do *Domain) rebuildSysVarCache(ctx sessionctx.Context) error {
@@ -132,6 +160,11 @@ func (do *Domain) rebuildSysVarCache(ctx sessionctx.Context) error {
 	if err != nil {
 		return err
 	}
+	// The system time zone is stored in mysql.tidb instead of mysql.global_variables,
+	// it is reloaded here so that ADMIN RELOAD SYSTEM_TZ takes effect on all the tidb servers.
+	if err := do.loadSystemTZ(ctx); err != nil {
+		logutil.BgLogger().Warn("load system time zone failed", zap.Error(err))
+	}
 
 	for _, sv := range variable.GetSysVars() {
 		sVal := sv.Value
@@ -185,6 +218,18 @@ func (do *Domain) checkEnableServerGlobalVar(name, sVal string) {
 			break
 		}
 		variable.EnableTSOFollowerProxy.Store(val)
+	case variable.RequireSecureTransport:
+		variable.SetRequireSecureTransport(variable.TiDBOptOn(sVal))
+	case variable.TiDBMaxServerConnections:
+		var val uint64
+		if val, err = strconv.ParseUint(sVal, 10, 32); err == nil {
+			variable.SetMaxServerConnections(uint32(val))
+		}
+	case variable.TiDBMaxConnectionsPerIP:
+		var val uint64
+		if val, err = strconv.ParseUint(sVal, 10, 32); err == nil {
+			variable.SetMaxConnectionsPerIP(uint32(val))
+		}
 	case variable.TiDBEnableLocalTxn:
 		variable.EnableLocalTxn.Store(variable.TiDBOptOn(sVal))
 	case variable.TiDBEnableStmtSummary:
@@ -231,6 +276,19 @@ func (do *Domain) checkEnableServerGlobalVar(name, sVal string) {
 			break
 		}
 		variable.TopSQLVariable.ReportIntervalSeconds.Store(val)
+	case variable.TiDBTopSQLEvictionPolicy:
+		variable.TopSQLVariable.EvictionPolicy.Store(sVal)
+	case variable.TiDBTopSQLTopNMetric:
+		variable.TopSQLVariable.TopNMetric.Store(sVal)
+	case variable.TiDBTopSQLMaxPendingReports:
+		var val int64
+		val, err = strconv.ParseInt(sVal, 10, 64)
+		if err != nil {
+			break
+		}
+		variable.TopSQLVariable.MaxPendingReports.Store(val)
+	case variable.TiDBTopSQLIncludeInternal:
+		variable.TopSQLVariable.IncludeInternal.Store(variable.TiDBOptOn(sVal))
 	case variable.TiDBRestrictedReadOnly:
 		variable.RestrictedReadOnly.Store(variable.TiDBOptOn(sVal))
 	case variable.TiDBStoreLimit:
@@ -837,6 +837,7 @@ const (
 	ErrGeneratedColumnNonPrior                               = 3107
 	ErrDependentByGeneratedColumn                            = 3108
 	ErrGeneratedColumnRefAutoInc                             = 3109
+	ErrAccountHasBeenLocked                                  = 3118
 	ErrWarnConflictingHint                                   = 3126
 	ErrUnresolvedHintName                                    = 3128
 	ErrInvalidJSONText                                       = 3140
@@ -847,6 +848,7 @@ const (
 	ErrJSONUsedAsKey                                         = 3152
 	ErrJSONDocumentNULLKey                                   = 3158
 	ErrSecureTransportRequired                               = 3159
+	ErrNoSecureTransportsConfigured                          = 3161
 	ErrBadUser                                               = 3162
 	ErrUserAlreadyExists                                     = 3163
 	ErrInvalidJSONPathArrayCell                              = 3165
@@ -907,6 +909,7 @@ const (
 	ErrFunctionalIndexDataIsTooLong                          = 3907
 	ErrFunctionalIndexNotApplicable                          = 3909
 	ErrDynamicPrivilegeNotRegistered                         = 3929
+	ErrUserAccessDeniedForUserAccountBlockedByPasswordLock   = 3955
 	// MariaDB errors.
 	ErrOnlyOneDefaultPartionAllowed         = 4030
 	ErrWrongPartitionTypeExpectedSystemTime = 4113
@@ -1056,6 +1059,10 @@ const (
 	ErrPlacementPolicyInUse               = 8241
 	ErrOptOnCacheTable                    = 8242
 	ErrHTTPServiceError                   = 8243
+	ErrWriteBufferQuotaExceeded           = 8244
+	ErrCharsetMismatch                    = 8245
+	ErrWarnUnsupportedSyntaxNoError       = 8246
+	ErrOutfileTooLarge                    = 8247
 	// TiKV/PD/TiFlash errors.
 	ErrPDServerTimeout           = 9001
 	ErrTiKVServerTimeout         = 9002
@@ -833,6 +833,7 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrGeneratedColumnNonPrior:                               mysql.Message("Generated column can refer only to generated columns defined prior to it.", nil),
 	ErrDependentByGeneratedColumn:                            mysql.Message("Column '%s' has a generated column dependency.", nil),
 	ErrGeneratedColumnRefAutoInc:                             mysql.Message("Generated column '%s' cannot refer to auto-increment column.", nil),
+	ErrAccountHasBeenLocked:                                  mysql.Message("Access denied for user '%-.48s'@'%-.255s'. Account is locked.", nil),
 	ErrWarnConflictingHint:                                   mysql.Message("Hint %s is ignored as conflicting/duplicated.", nil),
 	ErrUnresolvedHintName:                                    mysql.Message("Unresolved name '%s' for %s hint", nil),
 	ErrInvalidFieldSize:                                      mysql.Message("Invalid size for column '%s'.", nil),
@@ -850,6 +851,7 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrJSONUsedAsKey:                                         mysql.Message("JSON column '%-.192s' cannot be used in key specification.", nil),
 	ErrJSONDocumentNULLKey:                                   mysql.Message("JSON documents may not contain NULL member names.", nil),
 	ErrSecureTransportRequired:                               mysql.Message("Connections using insecure transport are prohibited while --require_secure_transport=ON.", nil),
+	ErrNoSecureTransportsConfigured:                          mysql.Message("No secure transports (SSL or Shared Memory) are configured, unable to set --require_secure_transport=ON.", nil),
 	ErrBadUser:                                               mysql.Message("User %s does not exist.", nil),
 	ErrUserAlreadyExists:                                     mysql.Message("User %s already exists.", nil),
 	ErrInvalidJSONPathArrayCell:                              mysql.Message("A path expression is not a path to a cell in an array.", nil),
@@ -903,6 +905,7 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrFunctionalIndexNotApplicable:                          mysql.Message("Cannot use expression index '%s' due to type or collation conversion", nil),
 	ErrUnsupportedConstraintCheck:                            mysql.Message("%s is not supported", nil),
 	ErrDynamicPrivilegeNotRegistered:                         mysql.Message("Dynamic privilege '%s' is not registered with the server.", nil),
+	ErrUserAccessDeniedForUserAccountBlockedByPasswordLock:   mysql.Message("Access denied for user '%-.48s'@'%-.255s'. Account is blocked for %s day(s) (%s day(s) remaining) due to %d consecutive failed logins.", nil),
 	ErrIllegalPrivilegeLevel:                                 mysql.Message("Illegal privilege level specified for %s", nil),
 	ErrCTERecursiveRequiresUnion:                             mysql.Message("Recursive Common Table Expression '%s' should contain a UNION", nil),
 	ErrCTERecursiveRequiresNonRecursiveFirst:                 mysql.Message("Recursive Common Table Expression '%s' should have one or more non-recursive query blocks followed by one or more recursive ones", nil),
@@ -1062,6 +1065,10 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrPlacementPolicyWithDirectOption: mysql.Message("Placement policy '%s' can't co-exist with direct placement options", nil),
 	ErrPlacementPolicyInUse:            mysql.Message("Placement policy '%-.192s' is still in use", nil),
 	ErrOptOnCacheTable:                 mysql.Message("'%s' is unsupported on cache tables.", nil),
+	ErrWriteBufferQuotaExceeded:        mysql.Message("Connection %d holds %dB in its write buffer and spooled rows, the write buffers of all the connections exceed the quota %dB.", nil),
+	ErrCharsetMismatch:                 mysql.Message("The statement is valid utf8 but invalid %s, character_set_client may not match the encoding of the client", nil),
+	ErrWarnUnsupportedSyntaxNoError:    mysql.Message("%s is not applicable in TiDB; %s is a no-op", nil),
+	ErrOutfileTooLarge:                 mysql.Message("The file of SELECT INTO OUTFILE is larger than max-outfile-bytes %d, the partial file is removed", nil),
 	// TiKV/PD errors.
 	ErrPDServerTimeout:           mysql.Message("PD server timeout", nil),
 	ErrTiKVServerTimeout:         mysql.Message("TiKV server timeout", nil),
@@ -323,6 +323,16 @@ func (a *ExecStmt) setPlanLabelForTopSQL(ctx context.Context) context.Context {
 	return topsql.AttachSQLInfo(ctx, normalizedSQL, sqlDigest, normalizedPlan, planDigest, vars.InRestrictedSQL)
 }
 
+func (a *ExecStmt) observeStmtFinishedForTopSQL() {
+	if a.Plan == nil || !variable.TopSQLEnabled() {
+		return
+	}
+	vars := a.Ctx.GetSessionVars()
+	_, sqlDigest := vars.StmtCtx.SQLDigest()
+	_, planDigest := getPlanDigest(a.Ctx, a.Plan)
+	topsql.ObserveStmtFinished(sqlDigest, planDigest, vars.InRestrictedSQL, time.Since(vars.StartTime))
+}
+
 // Exec builds an Executor from a plan. If the Executor doesn't return result,
 // like the INSERT, UPDATE statements, it executes in this function, if the Executor returns
 // result, execution is done after this function returns, in the returned sqlexec.RecordSet Next method.
@@ -840,6 +850,7 @@ func (a *ExecStmt) logAudit() {
 		if audit.OnGeneralEvent != nil {
 			cmd := mysql.Command2Str[byte(atomic.LoadUint32(&a.Ctx.GetSessionVars().CommandValue))]
 			ctx := context.WithValue(context.Background(), plugin.ExecStartTimeCtxKey, a.Ctx.GetSessionVars().StartTime)
+			ctx = context.WithValue(ctx, plugin.QueryTextCtxKey, logutil.EscapeBinaryBytes(sessVars.StmtCtx.OriginalSQL))
 			audit.OnGeneralEvent(ctx, sessVars, plugin.Completed, cmd)
 		}
 		return nil
@@ -857,7 +868,7 @@ func FormatSQL(sql string) stringutil.StringerFunc {
 		if maxQueryLen := atomic.LoadUint64(&cfg.Log.QueryLogMaxLen); uint64(length) > maxQueryLen {
 			sql = fmt.Sprintf("%.*q(len:%d)", maxQueryLen, sql, length)
 		}
-		return QueryReplacer.Replace(sql)
+		return QueryReplacer.Replace(logutil.EscapeBinaryBytes(sql))
 	}
 }
 
@@ -910,7 +921,9 @@ func (a *ExecStmt) FinishExecuteStmt(txnTS uint64, err error, hasMoreResults boo
 		sessionExecuteRunDurationInternal.Observe(executeDuration.Seconds())
 	} else {
 		sessionExecuteRunDurationGeneral.Observe(executeDuration.Seconds())
+		sessVars.AddOpenTables(sessVars.StmtCtx.Tables)
 	}
+	a.observeStmtFinishedForTopSQL()
 	// Reset DurationParse due to the next statement may not need to be parsed (not a text protocol query).
 	sessVars.DurationParse = 0
 	// Clean the stale read flag when statement execution finish
@@ -1022,7 +1035,14 @@ func (a *ExecStmt) LogSlowQuery(txnTS uint64, succ bool, hasMoreResults bool) {
 	if _, ok := a.StmtNode.(*ast.CommitStmt); ok {
 		slowItems.PrevStmt = sessVars.PrevStmt.String()
 	}
-	slowLog := sessVars.SlowLogFormat(slowItems)
+	var slowLog string
+	if cfg.Log.SlowQueryFormat == config.SlowQueryFormatJSON {
+		// The `use DB` statement is only written for the compatibility with MySQL, the DB is kept in the DB field.
+		sessVars.CurrentDBChanged = false
+		slowLog = formatSlowLogJSON(time.Now(), sessVars.SlowLogFormat(slowItems))
+	} else {
+		slowLog = sessVars.SlowLogFormat(slowItems)
+	}
 	if trace.IsEnabled() {
 		trace.Log(a.GoCtx, "details", slowLog)
 	}
@@ -741,6 +741,7 @@ func (b *executorBuilder) buildShow(v *plannercore.PhysicalShow) Executor {
 		Flag:         v.Flag,
 		Roles:        v.Roles,
 		User:         v.User,
+		Engine:       v.Engine,
 		is:           b.is,
 		Full:         v.Full,
 		IfNotExists:  v.IfNotExists,
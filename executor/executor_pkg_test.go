@@ -105,9 +105,9 @@ func TestExecutorPkg(t *testing.T) {
 func SubTestShowProcessList(t *testing.T) {
 	t.Parallel()
 	// Compose schema.
-	names := []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
+	names := []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Proxy_user"}
 	ftypes := []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
-		mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString}
+		mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString, mysql.TypeVarchar}
 	schema := buildSchema(names, ftypes)
 
 	// Compose a mocked session manager.
@@ -5738,6 +5738,7 @@ func (s *testSuiteWithCliBaseCharset) TestCharsetFeature(c *C) {
 	tk.MustQuery("show charset").Check(testkit.Rows(
 		"ascii US ASCII ascii_bin 1",
 		"binary binary binary 1",
+		"gb18030 China National Standard GB18030 gb18030_chinese_ci 4",
 		"gbk Chinese Internal Code Specification gbk_chinese_ci 2",
 		"latin1 Latin1 latin1_bin 1",
 		"utf8 UTF-8 Unicode utf8_bin 3",
@@ -5746,6 +5747,8 @@ func (s *testSuiteWithCliBaseCharset) TestCharsetFeature(c *C) {
 	tk.MustQuery("show collation").Check(testkit.Rows(
 		"ascii_bin ascii 65 Yes Yes 1",
 		"binary binary 63 Yes Yes 1",
+		"gb18030_bin gb18030 249  Yes 1",
+		"gb18030_chinese_ci gb18030 248 Yes Yes 1",
 		"gbk_bin gbk 87  Yes 1",
 		"gbk_chinese_ci gbk 28 Yes Yes 1",
 		"latin1_bin latin1 47 Yes Yes 1",
@@ -5796,6 +5799,32 @@ func (s *testSuiteWithCliBaseCharset) TestCharsetFeature(c *C) {
 	))
 }
 
+func (s *testSuiteWithCliBaseCharset) TestCharsetFeatureGB18030(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("set names gb18030;")
+	tk.MustQuery("select @@character_set_connection, @@collation_connection;").Check(testkit.Rows("gb18030 gb18030_chinese_ci"))
+	tk.MustExec("set names utf8mb4;")
+
+	tk.MustExec("drop table if exists t;")
+	tk.MustExec("create table t(a varchar(10) charset gb18030, b varchar(10) charset gb18030 collate gb18030_bin);")
+	tk.MustQuery("show create table t").Check(testkit.Rows("t CREATE TABLE `t` (\n" +
+		"  `a` varchar(10) CHARACTER SET gb18030 COLLATE gb18030_chinese_ci DEFAULT NULL,\n" +
+		"  `b` varchar(10) CHARACTER SET gb18030 COLLATE gb18030_bin DEFAULT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+	))
+	// The characters outside the BMP are encoded in 4 bytes, "︐" and "龴" are encoded in 2 bytes since GB18030-2022.
+	tk.MustExec("insert into t values ('一😀', '一😀'), ('︐龴', '︐龴'), ('a', 'A');")
+	tk.MustQuery("select a, hex(a), length(a), char_length(a) from t order by b;").Check(testkit.Rows(
+		"a 61 1 1",
+		"︐龴 A6D9FE59 4 2",
+		"一😀 D2BB9439FC36 6 2",
+	))
+	tk.MustQuery("select a from t where a = 'A';").Check(testkit.Rows("a"))
+	tk.MustQuery("select b from t where b = 'a';").Check(testkit.Rows())
+	tk.MustQuery("select hex(convert('𠂇︙' using gb18030)), convert(0x95329031A6F3 using gb18030);").Check(testkit.Rows("95329031A6F3 𠂇︙"))
+}
+
 func (s *testSuiteWithCliBaseCharset) TestCharsetFeatureCollation(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
@@ -436,6 +436,76 @@ func tlsOption2GlobalPriv(tlsOptions []*ast.TLSOption) (priv []byte, err error)
 	return
 }
 
+// maxPasswordLockingValue is the max value of FAILED_LOGIN_ATTEMPTS and PASSWORD_LOCK_TIME, the same as MySQL.
+const maxPasswordLockingValue = 32767
+
+// passwordLocking2UserAttributes converts FAILED_LOGIN_ATTEMPTS and PASSWORD_LOCK_TIME to the json patch of
+// User_attributes in mysql.user, only the specified options are in the patch. It returns nil if none is specified.
+func passwordLocking2UserAttributes(options []*ast.PasswordOrLockOption) (attributes []byte, err error) {
+	locking := make(map[string]int64)
+	for _, opt := range options {
+		switch opt.Type {
+		case ast.FailedLoginAttempts:
+			if opt.Count > maxPasswordLockingValue {
+				return nil, errors.Errorf("FAILED_LOGIN_ATTEMPTS must be between 0 and %d", maxPasswordLockingValue)
+			}
+			locking["failed_login_attempts"] = opt.Count
+		case ast.PasswordLockTime:
+			if opt.Count > maxPasswordLockingValue {
+				return nil, errors.Errorf("PASSWORD_LOCK_TIME must be between 0 and %d", maxPasswordLockingValue)
+			}
+			locking["password_lock_time_days"] = opt.Count
+		case ast.PasswordLockTimeUnbounded:
+			locking["password_lock_time_days"] = -1
+		}
+	}
+	if len(locking) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(map[string]interface{}{"Password_locking": locking})
+}
+
+// maxPasswordLifetimeValue is the max value of PASSWORD EXPIRE INTERVAL N DAY, the same as MySQL.
+const maxPasswordLifetimeValue = 65535
+
+// passwordExpire2Lifetime converts PASSWORD EXPIRE DEFAULT, NEVER and INTERVAL N DAY to the Password_lifetime
+// in mysql.user, the last one wins. The lifetime is nil for DEFAULT and 0 for NEVER. specified is false if
+// none is in options.
+func passwordExpire2Lifetime(options []*ast.PasswordOrLockOption) (lifetime interface{}, specified bool, err error) {
+	for _, opt := range options {
+		switch opt.Type {
+		case ast.PasswordExpireDefault:
+			lifetime, specified = nil, true
+		case ast.PasswordExpireNever:
+			lifetime, specified = 0, true
+		case ast.PasswordExpireInterval:
+			if opt.Count < 1 || opt.Count > maxPasswordLifetimeValue {
+				return nil, false, errors.Errorf("PASSWORD EXPIRE INTERVAL must be between 1 and %d", maxPasswordLifetimeValue)
+			}
+			lifetime, specified = opt.Count, true
+		}
+	}
+	return lifetime, specified, nil
+}
+
+// maxUserConnectionsValue is the max value of MAX_USER_CONNECTIONS, the same as MySQL.
+const maxUserConnectionsValue = 4294967295
+
+// resourceOption2MaxUserConnections returns the MAX_USER_CONNECTIONS in options, the last one wins.
+// specified is false if it is not in options. The other resource options are ignored.
+func resourceOption2MaxUserConnections(options []*ast.ResourceOption) (count int64, specified bool, err error) {
+	for _, opt := range options {
+		if opt.Type != ast.MaxUserConnections {
+			continue
+		}
+		if opt.Count < 0 || opt.Count > maxUserConnectionsValue {
+			return 0, false, errors.Errorf("MAX_USER_CONNECTIONS must be between 0 and %d", maxUserConnectionsValue)
+		}
+		count, specified = opt.Count, true
+	}
+	return count, specified, nil
+}
+
 // grantLevelPriv grants priv to user in s.Level scope.
 func (e *GrantExec) grantLevelPriv(priv *ast.PrivElem, user *ast.UserSpec, internalSession sessionctx.Context) error {
 	if priv.Priv == mysql.ExtendedPriv {
@@ -745,8 +745,9 @@ func (e *hugeMemTableRetriever) dataForColumnsInTable(ctx context.Context, sctx
 				numericScale = decimal
 			}
 		}
-		columnType := col.FieldType.InfoSchemaStr()
 		columnDesc := table.NewColDesc(table.ToColumn(col))
+		// COLUMN_TYPE is the same as the Type of SHOW COLUMNS, including unsigned and zerofill.
+		columnType := columnDesc.Type
 		var columnDefault interface{}
 		if columnDesc.DefaultValue != nil {
 			columnDefault = fmt.Sprintf("%v", columnDesc.DefaultValue)
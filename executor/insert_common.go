@@ -318,6 +318,13 @@ func (e *InsertValues) handleErr(col *table.Column, val *types.Datum, rowIdx int
 		err = table.ErrTruncatedWrongValueForField.GenWithStackByArgs(types.TypeStr(colTp), valStr, colName, rowIdx+1)
 	} else if types.ErrWarnDataOutOfRange.Equal(err) {
 		err = types.ErrWarnDataOutOfRange.GenWithStackByArgs(colName, rowIdx+1)
+	} else if table.ErrTruncatedWrongValueForField.Equal(err) && col != nil {
+		// The string is invalid in the charset of the column, report it with the row.
+		if valStr, err1 := val.ToString(); err1 == nil {
+			if err1 = table.WrongCharsetValueErr(e.ctx, col.ToInfo(), valStr, val.Collation(), rowIdx+1); err1 != nil {
+				err = err1
+			}
+		}
 	}
 
 	if !e.ctx.GetSessionVars().StmtCtx.DupKeyAsWarning {
@@ -848,6 +848,11 @@ func (e *hotRegionsHistoryRetriver) startRetrieving(
 					defer func() {
 						terror.Log(resp.Body.Close())
 					}()
+					if resp.StatusCode == http.StatusNotFound {
+						// The API is added in PD v5.3.0.
+						ch <- hotRegionsResult{err: errors.Errorf("request %s failed: %s, the history hot regions require PD v5.3.0 or later", url, resp.Status)}
+						return
+					}
 					if resp.StatusCode != http.StatusOK {
 						ch <- hotRegionsResult{err: errors.Errorf("request %s failed: %s", url, resp.Status)}
 						return
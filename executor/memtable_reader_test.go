@@ -983,6 +983,8 @@ type testHotRegionsHistoryTableSuite struct {
 	*testInfoschemaTableSuiteBase
 	httpServers []*httptest.Server
 	startTime   time.Time
+	// historyNotSupported makes the mock PD servers act like the ones before v5.3.0.
+	historyNotSupported bool
 }
 
 func (s *testHotRegionsHistoryTableSuite) SetUpSuite(c *C) {
@@ -1058,7 +1060,13 @@ func (s *testHotRegionsHistoryTableSuite) setUpMockPDHTTPServer(c *C) (*httptest
 		}, nil
 	}))
 	// mock hisory hot regions response
-	router.HandleFunc(pdapi.HotHistory, hisHotRegionsHandler)
+	router.HandleFunc(pdapi.HotHistory, func(w http.ResponseWriter, r *http.Request) {
+		if s.historyNotSupported {
+			http.NotFound(w, r)
+			return
+		}
+		hisHotRegionsHandler(w, r)
+	})
 	return server, mockAddr
 }
 
@@ -1400,4 +1408,14 @@ func (s *testHotRegionsHistoryTableSuite) TestTiDBHotRegionsHistoryError(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, "denied to scan hot regions, please specified the end time, such as `update_time < '2020-01-01 00:00:00'`")
 	c.Assert(rs.Close(), IsNil)
+
+	// Test the PD servers without the history API, the errors of the servers are returned as warnings.
+	s.historyNotSupported = true
+	defer func() { s.historyNotSupported = false }()
+	tk.MustQuery("select * from information_schema.tidb_hot_regions_history where update_time>='2019/08/26 06:18:13.011' and update_time<='2019/08/26 06:28:13.011'").Check(testkit.Rows())
+	warnings := tk.Se.GetSessionVars().StmtCtx.GetWarnings()
+	c.Assert(warnings, Not(HasLen), 0)
+	for _, w := range warnings {
+		c.Assert(w.Err.Error(), Matches, ".*404 Not Found, the history hot regions require PD v5.3.0 or later")
+	}
 }
@@ -314,7 +314,7 @@ func (e *DeallocateExec) Next(ctx context.Context, req *chunk.Chunk) error {
 	prepared := preparedObj.PreparedAst
 	delete(vars.PreparedStmtNameToID, e.Name)
 	if plannercore.PreparedPlanCacheEnabled() {
-		e.ctx.PreparedPlanCache().Delete(plannercore.NewPSTMTPlanCacheKey(
+		plannercore.DeletePreparedPlanCache(e.ctx, plannercore.NewPSTMTPlanCacheKey(
 			vars, id, prepared.SchemaVersion,
 		))
 	}
@@ -21,8 +21,10 @@ import (
 	"math"
 	"os"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/types"
@@ -43,6 +45,12 @@ type SelectIntoExec struct {
 	dstFile   *os.File
 	chk       *chunk.Chunk
 	started   bool
+
+	// maxBytes is the max size of the file, 0 means unlimited.
+	maxBytes int64
+	written  int64
+	// tooLarge is set if the file reaches maxBytes, the partial file is removed on Close.
+	tooLarge bool
 }
 
 // Open implements the Executor Open interface.
@@ -63,6 +71,8 @@ func (s *SelectIntoExec) Open(ctx context.Context) error {
 	s.lineBuf = make([]byte, 0, 1024)
 	s.fieldBuf = make([]byte, 0, 64)
 	s.escapeBuf = make([]byte, 0, 64)
+	s.maxBytes = config.GetGlobalConfig().Security.MaxOutfileBytes
+	atomic.StoreInt64(&s.ctx.GetSessionVars().OutfileBytesWritten, 0)
 	return s.baseExecutor.Open(ctx)
 }
 
@@ -207,9 +217,15 @@ func (s *SelectIntoExec) dumpToOutfile() error {
 			}
 		}
 		s.lineBuf = append(s.lineBuf, lineTerm...)
+		if s.maxBytes > 0 && s.written+int64(len(s.lineBuf)) > s.maxBytes {
+			s.tooLarge = true
+			return ErrOutfileTooLarge.GenWithStackByArgs(s.maxBytes)
+		}
 		if _, err := s.writer.Write(s.lineBuf); err != nil {
 			return errors.Trace(err)
 		}
+		s.written += int64(len(s.lineBuf))
+		atomic.StoreInt64(&s.ctx.GetSessionVars().OutfileBytesWritten, s.written)
 	}
 	return nil
 }
@@ -222,6 +238,10 @@ func (s *SelectIntoExec) Close() error {
 	err1 := s.writer.Flush()
 	err2 := s.dstFile.Close()
 	err3 := s.baseExecutor.Close()
+	if s.tooLarge {
+		// The error of the statement is ErrOutfileTooLarge, the errors of flushing the partial file are ignored.
+		return errors.Trace(os.Remove(s.intoOpt.FileName))
+	}
 	if err1 != nil {
 		return errors.Trace(err1)
 	} else if err2 != nil {
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/types"
@@ -154,6 +155,34 @@ func (s *testSuite1) TestSelectIntoOutfileConstant(c *C) {
 `, outfile, c)
 }
 
+func (s *testSuite1) TestSelectIntoOutfileMaxBytes(c *C) {
+	outfile := randomSelectFilePath("TestSelectIntoOutfileMaxBytes")
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert into t values (1), (2), (3), (4), (5), (6), (7), (8), (9), (10)")
+	defer config.RestoreFunc()()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security.MaxOutfileBytes = 10
+	})
+
+	// Each line is 2 bytes except the last one, so the 6th line exceeds the limit.
+	err := tk.ExecToErr(fmt.Sprintf("select a from t order by a into outfile %q", outfile))
+	c.Assert(executor.ErrOutfileTooLarge.Equal(err), IsTrue, Commentf("err: %v", err))
+	_, err = os.Stat(outfile)
+	c.Assert(os.IsNotExist(err), IsTrue, Commentf("err: %v", err))
+	tk.MustQuery("show status like 'Outfile_bytes_written'").Check(testkit.Rows("Outfile_bytes_written 10"))
+
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security.MaxOutfileBytes = 0
+	})
+	tk.MustExec(fmt.Sprintf("select a from t order by a into outfile %q", outfile))
+	cmpAndRm("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", outfile, c)
+	tk.MustQuery("show status like 'Outfile_bytes_written'").Check(testkit.Rows("Outfile_bytes_written 21"))
+	tk.MustQuery("show global status like 'Outfile_bytes_written'").Check(testkit.Rows())
+}
+
 func (s *testSuite1) TestDeliminators(c *C) {
 	outfile := randomSelectFilePath("TestDeliminators")
 	tk := testkit.NewTestKit(c, s.store)
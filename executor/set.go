@@ -246,6 +246,14 @@ func (e *SetExecutor) checkPDClientDynamicOption(name string, sessionVars *varia
 func (e *SetExecutor) setCharset(cs, co string, isSetName bool) error {
 	var err error
 	sessionVars := e.ctx.GetSessionVars()
+	if isSetName {
+		// The charset of the connection is used to encode and decode the data sent
+		// by the client, so it must be one that the encoding table knows.
+		if !charset.IsClientEncoding(cs) {
+			return charset.ErrUnknownCharacterSet.GenWithStackByArgs(cs)
+		}
+		cs = strings.ToLower(cs)
+	}
 	if co == "" {
 		if co, err = charset.GetDefaultCollation(cs); err != nil {
 			return err
@@ -464,6 +464,13 @@ func (s *testSerialSuite1) TestSetVar(c *C) {
 	c.Assert(tk.ExecToErr("set tidb_general_log = abc"), NotNil)
 	c.Assert(tk.ExecToErr("set tidb_general_log = 123"), NotNil)
 
+	tk.MustQuery(`select @@session.tidb_general_log_target;`).Check(testkit.Rows("OFF"))
+	tk.MustExec("set tidb_general_log_target = stderr")
+	tk.MustQuery(`select @@session.tidb_general_log_target;`).Check(testkit.Rows("STDERR"))
+	tk.MustExec("set tidb_general_log_target = off")
+	tk.MustQuery(`show variables like 'tidb_general_log_target';`).Check(testkit.Rows("tidb_general_log_target OFF"))
+	c.Assert(tk.ExecToErr("set tidb_general_log_target = abc"), NotNil)
+
 	tk.MustExec(`SET @@character_set_results = NULL;`)
 	tk.MustQuery(`select @@character_set_results;`).Check(testkit.Rows(""))
 
@@ -698,6 +705,31 @@ func (s *testSuite5) TestSetCharset(c *C) {
 		"binary",
 	)
 
+	// The charset name of SET NAMES is case-insensitive.
+	tk.MustExec(`SET NAMES LATIN1`)
+	check(
+		"latin1",
+		"latin1",
+		"latin1",
+		"utf8mb4",
+		"utf8mb4",
+		"utf8",
+		"binary",
+	)
+	tk.MustExec(`SET NAMES 'Utf8MB4' COLLATE utf8mb4_bin`)
+	check(
+		"utf8mb4",
+		"utf8mb4",
+		"utf8mb4",
+		"utf8mb4",
+		"utf8mb4",
+		"utf8",
+		"binary",
+	)
+	tk.MustGetErrCode(`SET NAMES boguscharsetname`, mysql.ErrUnknownCharacterSet)
+	tk.MustGetErrCode(`SET NAMES 'BogusCharsetName'`, mysql.ErrUnknownCharacterSet)
+
+	tk.MustExec(`SET NAMES utf8`)
 	tk.MustExec(`SET CHARACTER SET latin1`)
 	check(
 		"latin1",
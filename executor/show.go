@@ -63,6 +63,7 @@ import (
 	"github.com/pingcap/tidb/util/format"
 	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/hint"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/sem"
 	"github.com/pingcap/tidb/util/set"
 	"github.com/pingcap/tidb/util/sqlexec"
@@ -84,6 +85,7 @@ type ShowExec struct {
 	Flag      int                  // Some flag parsed from sql, such as FULL.
 	Roles     []*auth.RoleIdentity // Used for show grants.
 	User      *auth.UserIdentity   // Used by show grants, show create user.
+	Engine    string               // Used by show engine status.
 
 	is infoschema.InfoSchema
 
@@ -207,6 +209,8 @@ func (e *ShowExec) fetchAll(ctx context.Context) error {
 		// empty result
 	case ast.ShowMasterStatus:
 		return e.fetchShowMasterStatus()
+	case ast.ShowEngineStatus:
+		return e.fetchShowEngineStatus()
 	case ast.ShowPrivileges:
 		return e.fetchShowPrivileges()
 	case ast.ShowBindings:
@@ -413,8 +417,50 @@ func (e *ShowExec) fetchShowProcessList() error {
 }
 
 func (e *ShowExec) fetchShowOpenTables() error {
-	// TiDB has no concept like mysql's "table cache" and "open table"
-	// For simplicity, we just return an empty result with the same structure as MySQL's SHOW OPEN TABLES
+	// TiDB has no concept like mysql's "table cache" and "open table", the tables recently accessed by the
+	// session are shown instead. In_use is the number of the sessions whose running statement or transaction
+	// uses the table.
+	inUse := make(map[stmtctx.TableEntry]int)
+	if sm := e.ctx.GetSessionManager(); sm != nil {
+		for _, pi := range sm.ShowProcessList() {
+			seen := make(map[stmtctx.TableEntry]struct{}, len(pi.TablesInUse))
+			for _, table := range pi.TablesInUse {
+				table = stmtctx.TableEntry{DB: strings.ToLower(table.DB), Table: strings.ToLower(table.Table)}
+				if _, ok := seen[table]; !ok {
+					seen[table] = struct{}{}
+					inUse[table]++
+				}
+			}
+		}
+	}
+
+	tables := e.ctx.GetSessionVars().OpenTables()
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].DB != tables[j].DB {
+			return tables[i].DB < tables[j].DB
+		}
+		return tables[i].Table < tables[j].Table
+	})
+	checker := privilege.GetPrivilegeManager(e.ctx)
+	activeRoles := e.ctx.GetSessionVars().ActiveRoles
+	for _, table := range tables {
+		if e.DBName.L != "" && table.DB != e.DBName.L {
+			continue
+		}
+		// Skip the tables dropped after being accessed.
+		db, ok := e.is.SchemaByName(model.NewCIStr(table.DB))
+		if !ok {
+			continue
+		}
+		tbl, err := e.is.TableByName(db.Name, model.NewCIStr(table.Table))
+		if err != nil {
+			continue
+		}
+		if checker != nil && !checker.RequestVerification(activeRoles, db.Name.O, tbl.Meta().Name.O, "", mysql.AllPrivMask) {
+			continue
+		}
+		e.appendRow([]interface{}{db.Name.O, tbl.Meta().Name.O, inUse[table], 0})
+	}
 	return nil
 }
 
@@ -710,6 +756,56 @@ func (e *ShowExec) fetchShowMasterStatus() error {
 	return nil
 }
 
+// fetchShowEngineStatus returns a compatible result of SHOW ENGINE INNODB STATUS, which is used by the
+// applications migrated from MySQL to inspect the lock waits. TiDB doesn't use InnoDB, so the status is
+// about the regions and the lock waits of TiKV.
+func (e *ShowExec) fetchShowEngineStatus() error {
+	if !strings.EqualFold(e.Engine, "innodb") {
+		return ddl.ErrUnknownEngine.GenWithStackByArgs(e.Engine)
+	}
+	var status strings.Builder
+	status.WriteString("\n=====================================\n")
+	fmt.Fprintf(&status, "%s TIDB STATUS\n", time.Now().In(e.ctx.GetSessionVars().Location()).Format(types.TimeFormat))
+	status.WriteString("=====================================\n")
+	status.WriteString("NOTE: TiDB doesn't use InnoDB, this is the status of the TiKV cluster for compatibility.\n")
+	status.WriteString("------\nREGIONS\n------\n")
+	if regions, stores, err := e.getRegionAndStoreCount(); err != nil {
+		fmt.Fprintf(&status, "Region count: unavailable (%s)\n", err.Error())
+	} else {
+		fmt.Fprintf(&status, "Region count: %d\nStore count: %d\n", regions, stores)
+	}
+	status.WriteString("------------\nTRANSACTIONS\n------------\n")
+	if lockWaits, err := e.ctx.GetStore().GetLockWaits(); err != nil {
+		fmt.Fprintf(&status, "Pending lock count: unavailable (%s)\n", err.Error())
+	} else {
+		fmt.Fprintf(&status, "Pending lock count: %d\n", len(lockWaits))
+	}
+	status.WriteString("----------------------------\nEND OF TIDB STATUS\n============================\n")
+	e.appendRow([]interface{}{"InnoDB", "", status.String()})
+	return nil
+}
+
+// getRegionAndStoreCount returns the number of the regions and the TiKV stores from PD.
+func (e *ShowExec) getRegionAndStoreCount() (regions int64, stores int, err error) {
+	tikvStore, ok := e.ctx.GetStore().(helper.Storage)
+	if !ok {
+		return 0, 0, errors.New("the storage is not TiKV")
+	}
+	tikvHelper := &helper.Helper{
+		Store:       tikvStore,
+		RegionCache: tikvStore.GetRegionCache(),
+	}
+	storesStat, err := tikvHelper.GetStoresStat()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, storeStat := range storesStat.Stores {
+		// Every region has a leader, the leader counts of all the stores sum to the region count.
+		regions += storeStat.Status.LeaderCount
+	}
+	return regions, len(storesStat.Stores), nil
+}
+
 func (e *ShowExec) sysVarHiddenForSem(sysVarNameInLower string) bool {
 	if !sem.IsEnabled() || !sem.IsInvisibleSysVar(sysVarNameInLower) {
 		return false
@@ -1433,7 +1529,7 @@ func (e *ShowExec) fetchShowCreateUser(ctx context.Context) error {
 
 	exec := e.ctx.(sqlexec.RestrictedSQLExecutor)
 
-	stmt, err := exec.ParseWithParams(ctx, `SELECT plugin FROM %n.%n WHERE User=%? AND Host=%?`, mysql.SystemDB, mysql.UserTable, userName, strings.ToLower(hostName))
+	stmt, err := exec.ParseWithParams(ctx, `SELECT plugin, User_attributes, Password_lifetime, Account_locked FROM %n.%n WHERE User=%? AND Host=%?`, mysql.SystemDB, mysql.UserTable, userName, strings.ToLower(hostName))
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1453,6 +1549,36 @@ func (e *ShowExec) fetchShowCreateUser(ctx context.Context) error {
 		authplugin = rows[0].GetString(0)
 	}
 
+	passwordLocking := ""
+	if len(rows) == 1 && !rows[0].IsNull(1) {
+		var attributes privileges.UserAttributes
+		err = gjson.Unmarshal(hack.Slice(rows[0].GetJSON(1).String()), &attributes)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if locking := attributes.PasswordLocking; locking != nil && (locking.FailedLoginAttempts != 0 || locking.PasswordLockTimeDays != 0) {
+			lockTime := strconv.FormatInt(locking.PasswordLockTimeDays, 10)
+			if locking.PasswordLockTimeDays < 0 {
+				lockTime = "UNBOUNDED"
+			}
+			passwordLocking = fmt.Sprintf(" FAILED_LOGIN_ATTEMPTS %d PASSWORD_LOCK_TIME %s", locking.FailedLoginAttempts, lockTime)
+		}
+	}
+
+	passwordExpire := "DEFAULT"
+	if len(rows) == 1 && !rows[0].IsNull(2) {
+		if lifetime := rows[0].GetUint64(2); lifetime == 0 {
+			passwordExpire = "NEVER"
+		} else {
+			passwordExpire = fmt.Sprintf("INTERVAL %d DAY", lifetime)
+		}
+	}
+
+	accountLocked := "UNLOCK"
+	if len(rows) == 1 && rows[0].GetEnum(3).String() == "Y" {
+		accountLocked = "LOCK"
+	}
+
 	stmt, err = exec.ParseWithParams(ctx, `SELECT Priv FROM %n.%n WHERE User=%? AND Host=%?`, mysql.SystemDB, mysql.GlobalPrivTable, userName, hostName)
 	if err != nil {
 		return errors.Trace(err)
@@ -1480,8 +1606,8 @@ func (e *ShowExec) fetchShowCreateUser(ctx context.Context) error {
 	}
 
 	// FIXME: the returned string is not escaped safely
-	showStr := fmt.Sprintf("CREATE USER '%s'@'%s' IDENTIFIED WITH '%s'%s REQUIRE %s PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK",
-		e.User.Username, e.User.Hostname, authplugin, authStr, require)
+	showStr := fmt.Sprintf("CREATE USER '%s'@'%s' IDENTIFIED WITH '%s'%s REQUIRE %s PASSWORD EXPIRE %s ACCOUNT %s%s",
+		e.User.Username, e.User.Hostname, authplugin, authStr, require, passwordExpire, accountLocked, passwordLocking)
 	e.appendRow([]interface{}{showStr})
 	return nil
 }
@@ -1596,9 +1722,9 @@ func (e *ShowExec) fetchShowWarnings(errOnly bool) error {
 		switch x := warn.(type) {
 		case *terror.Error:
 			sqlErr := terror.ToSQLError(x)
-			e.appendRow([]interface{}{w.Level, int64(sqlErr.Code), sqlErr.Message})
+			e.appendRow([]interface{}{w.Level, int64(sqlErr.Code), logutil.EscapeBinaryBytes(sqlErr.Message)})
 		default:
-			e.appendRow([]interface{}{w.Level, int64(mysql.ErrUnknown), warn.Error()})
+			e.appendRow([]interface{}{w.Level, int64(mysql.ErrUnknown), logutil.EscapeBinaryBytes(warn.Error())})
 		}
 	}
 	return nil
@@ -35,6 +35,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testutil"
 )
@@ -130,6 +131,20 @@ func (s *testSuite5) TestShowWarnings(c *C) {
 	tk.MustQuery("show warnings").Check(testutil.RowsWithSep("|", "Note|1050|Table 'test.show_warnings_2' already exists"))
 	tk.MustQuery("select @@warning_count").Check(testutil.RowsWithSep("|", "1"))
 	tk.MustQuery("select @@warning_count").Check(testutil.RowsWithSep("|", "0"))
+
+	// The bytes which are not valid UTF-8 are hex-escaped.
+	tk.MustQuery("select concat(0x61ff62) + 0").Check(testkit.Rows("0"))
+	tk.MustQuery("show warnings").Check(testutil.RowsWithSep("|", `Warning|1292|Truncated incorrect INTEGER value: 'a\xFFb'`))
+
+	// Test the LIMIT clause, it doesn't change the warnings.
+	tk.MustExec("insert show_warnings values ('a'), ('b'), ('c')")
+	tk.MustQuery("show warnings limit 2").Check(testutil.RowsWithSep("|",
+		"Warning|1292|Truncated incorrect DOUBLE value: 'a'", "Warning|1292|Truncated incorrect DOUBLE value: 'b'"))
+	tk.MustQuery("show warnings limit 1, 1").Check(testutil.RowsWithSep("|", "Warning|1292|Truncated incorrect DOUBLE value: 'b'"))
+	tk.MustQuery("show warnings limit 1 offset 2").Check(testutil.RowsWithSep("|", "Warning|1292|Truncated incorrect DOUBLE value: 'c'"))
+	tk.MustQuery("show warnings limit 3, 1").Check(testkit.Rows())
+	tk.MustQuery("show warnings limit 0").Check(testkit.Rows())
+	tk.MustQuery("select @@warning_count").Check(testkit.Rows("3"))
 }
 
 func (s *testSuite5) TestShowErrors(c *C) {
@@ -142,6 +157,8 @@ func (s *testSuite5) TestShowErrors(c *C) {
 	_, _ = tk.Exec(testSQL)
 
 	tk.MustQuery("show errors").Check(testutil.RowsWithSep("|", "Error|1050|Table 'test.show_errors' already exists"))
+	tk.MustQuery("show errors limit 1").Check(testutil.RowsWithSep("|", "Error|1050|Table 'test.show_errors' already exists"))
+	tk.MustQuery("show errors limit 1, 1").Check(testkit.Rows())
 }
 
 func (s *testSuite5) TestShowWarningsForExprPushdown(c *C) {
@@ -435,6 +452,24 @@ func (s *testSuite5) TestShowCreateUser(c *C) {
 	tk.MustQuery("show create user 'test_show_create_user'@'localhost';").
 		Check(testkit.Rows(`CREATE USER 'test_show_create_user'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*94BDCEBE19083CE2A1F959FD02F964C7AF4CFC29' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK`))
 
+	tk.MustExec(`ALTER USER 'test_show_create_user'@'localhost' PASSWORD EXPIRE INTERVAL 90 DAY;`)
+	tk.MustQuery("show create user 'test_show_create_user'@'localhost';").
+		Check(testkit.Rows(`CREATE USER 'test_show_create_user'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*94BDCEBE19083CE2A1F959FD02F964C7AF4CFC29' REQUIRE NONE PASSWORD EXPIRE INTERVAL 90 DAY ACCOUNT UNLOCK`))
+	tk.MustExec(`ALTER USER 'test_show_create_user'@'localhost' PASSWORD EXPIRE NEVER;`)
+	tk.MustQuery("show create user 'test_show_create_user'@'localhost';").
+		Check(testkit.Rows(`CREATE USER 'test_show_create_user'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*94BDCEBE19083CE2A1F959FD02F964C7AF4CFC29' REQUIRE NONE PASSWORD EXPIRE NEVER ACCOUNT UNLOCK`))
+	tk.MustExec(`ALTER USER 'test_show_create_user'@'localhost' PASSWORD EXPIRE DEFAULT;`)
+	tk.MustExec(`ALTER USER 'test_show_create_user'@'localhost' ACCOUNT LOCK;`)
+	tk.MustQuery("show create user 'test_show_create_user'@'localhost';").
+		Check(testkit.Rows(`CREATE USER 'test_show_create_user'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*94BDCEBE19083CE2A1F959FD02F964C7AF4CFC29' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT LOCK`))
+	tk.MustExec(`ALTER USER 'test_show_create_user'@'localhost' ACCOUNT UNLOCK;`)
+	tk.MustQuery("show create user 'test_show_create_user'@'localhost';").
+		Check(testkit.Rows(`CREATE USER 'test_show_create_user'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*94BDCEBE19083CE2A1F959FD02F964C7AF4CFC29' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK`))
+	tk.MustExec(`CREATE USER 'test_show_create_user_locked'@'%' ACCOUNT LOCK;`)
+	tk.MustQuery("show create user 'test_show_create_user_locked'@'%';").
+		Check(testkit.Rows(`CREATE USER 'test_show_create_user_locked'@'%' IDENTIFIED WITH 'mysql_native_password' AS '' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT LOCK`))
+	tk.MustExec(`DROP USER 'test_show_create_user_locked'@'%';`)
+
 	// Case: the user exists but the host portion doesn't match
 	err := tk.QueryToErr("show create user 'test_show_create_user'@'asdf';")
 	c.Assert(err.Error(), Equals, executor.ErrCannotUser.GenWithStackByArgs("SHOW CREATE USER", "'test_show_create_user'@'asdf'").Error())
@@ -611,6 +646,32 @@ func (s *testSuite5) TestShowOpenTables(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustQuery("show open tables")
 	tk.MustQuery("show open tables in test")
+
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t_open1, t_open2")
+	tk.MustExec("create table t_open1(a int)")
+	tk.MustExec("create table t_open2(a int)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustExec("begin")
+	tk1.MustQuery("select * from t_open1").Check(testkit.Rows())
+	tk1.MustQuery("select 1").Check(testkit.Rows("1"))
+	sm := &mockSessionManager1{PS: []*util.ProcessInfo{tk1.Se.ShowProcess()}}
+	tk.Se.SetSessionManager(sm)
+
+	// The tables accessed by the session are open, In_use counts the transactions using the table.
+	tk.MustQuery("select * from t_open1, t_open2").Check(testkit.Rows())
+	tk.MustQuery("show open tables in test").Check(testkit.Rows("test t_open1 1 0", "test t_open2 0 0"))
+	tk.MustQuery("show open tables in mysql").Check(testkit.Rows())
+	tk1.MustExec("commit")
+	tk1.MustQuery("select 1").Check(testkit.Rows("1"))
+	sm.PS = []*util.ProcessInfo{tk1.Se.ShowProcess()}
+	tk.MustQuery("show open tables in test").Check(testkit.Rows("test t_open1 0 0", "test t_open2 0 0"))
+
+	// The dropped tables are not shown.
+	tk.MustExec("drop table t_open2")
+	tk.MustQuery("show open tables in test").Check(testkit.Rows("test t_open1 0 0"))
+	tk.MustExec("drop table t_open1")
 }
 func (s *testSuite5) TestShowCreateViewDefiner(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
@@ -1602,6 +1663,34 @@ func (s *testSuite5) TestShowPerformanceSchema(c *C) {
 			"events_statements_summary_by_digest 0 SCHEMA_NAME 2 DIGEST A 0 <nil> <nil> YES BTREE   YES <nil> NO"))
 }
 
+func (s *testSuite5) TestShowEngineStatus(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	rows := tk.MustQuery("show engine innodb status").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], Equals, "InnoDB")
+	status := rows[0][2].(string)
+	c.Assert(status, Matches, "(?s).*TiDB doesn't use InnoDB.*")
+	c.Assert(status, Matches, "(?s).*Region count: .*")
+	c.Assert(status, Matches, "(?s).*Pending lock count: 0.*")
+
+	err := tk.QueryToErr("show engine myisam status")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "[ddl:1286]Unknown storage engine 'myisam'")
+
+	// The PROCESS privilege is required.
+	tk.MustExec("create user show_engine")
+	tk1 := testkit.NewTestKit(c, s.store)
+	se, err := session.CreateSession4Test(s.store)
+	c.Assert(err, IsNil)
+	c.Assert(se.Auth(&auth.UserIdentity{Username: "show_engine", Hostname: "%"}, nil, nil), IsTrue)
+	tk1.Se = se
+	_, err = tk1.Exec("show engine innodb status")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*PROCESS privilege.*")
+	tk.MustExec("grant process on *.* to show_engine")
+	c.Assert(tk1.MustQuery("show engine innodb status").Rows(), HasLen, 1)
+}
+
 func (s *testSuite5) TestShowCreatePlacementPolicy(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("CREATE PLACEMENT POLICY xyz PRIMARY_REGION='us-east-1' REGIONS='us-east-1,us-east-2' FOLLOWERS=4")
@@ -40,6 +40,7 @@ import (
 	plannercore "github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/plugin"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
@@ -122,6 +123,8 @@ func (e *SimpleExec) Next(ctx context.Context, req *chunk.Chunk) (err error) {
 	switch x := e.Statement.(type) {
 	case *ast.GrantRoleStmt:
 		err = e.executeGrantRole(ctx, x)
+	case *ast.GrantProxyStmt:
+		err = e.executeGrantProxy(ctx, x)
 	case *ast.UseStmt:
 		err = e.executeUse(x)
 	case *ast.FlushStmt:
@@ -159,8 +162,15 @@ func (e *SimpleExec) Next(ctx context.Context, req *chunk.Chunk) (err error) {
 		err = e.executeSetDefaultRole(ctx, x)
 	case *ast.ShutdownStmt:
 		err = e.executeShutdown(x)
+	case *ast.ResetMasterStmt:
+		// TiDB does not write binary logs like MySQL, so there is nothing to reset.
+		e.ctx.GetSessionVars().StmtCtx.AppendWarning(ErrWarnUnsupportedSyntaxNoError.GenWithStackByArgs("RESET MASTER", "binlog position reset"))
 	case *ast.AdminStmt:
-		err = e.executeAdminReloadStatistics(x)
+		if x.Tp == ast.AdminReloadSystemTZ {
+			err = e.executeAdminReloadSystemTZ(ctx, x)
+		} else {
+			err = e.executeAdminReloadStatistics(x)
+		}
 	}
 	e.done = true
 	return err
@@ -775,12 +785,39 @@ func (e *SimpleExec) executeCreateUser(ctx context.Context, s *ast.CreateUserStm
 	if err != nil {
 		return err
 	}
+	userAttributes, err := passwordLocking2UserAttributes(s.PasswordOrLockOptions)
+	if err != nil {
+		return err
+	}
+	var userAttributesValue interface{}
+	if len(userAttributes) > 0 {
+		userAttributesValue = string(hack.String(userAttributes))
+	}
+	passwordExpired, accountLocked := "N", "N"
+	for _, opt := range s.PasswordOrLockOptions {
+		switch opt.Type {
+		case ast.PasswordExpire:
+			passwordExpired = "Y"
+		case ast.Lock:
+			accountLocked = "Y"
+		case ast.Unlock:
+			accountLocked = "N"
+		}
+	}
+	maxUserConns, _, err := resourceOption2MaxUserConnections(s.ResourceOptions)
+	if err != nil {
+		return err
+	}
+	passwordLifetime, _, err := passwordExpire2Lifetime(s.PasswordOrLockOptions)
+	if err != nil {
+		return err
+	}
 
 	sql := new(strings.Builder)
 	if s.IsCreateRole {
 		sqlexec.MustFormatSQL(sql, `INSERT INTO %n.%n (Host, User, authentication_string, plugin, Account_locked) VALUES `, mysql.SystemDB, mysql.UserTable)
 	} else {
-		sqlexec.MustFormatSQL(sql, `INSERT INTO %n.%n (Host, User, authentication_string, plugin) VALUES `, mysql.SystemDB, mysql.UserTable)
+		sqlexec.MustFormatSQL(sql, `INSERT INTO %n.%n (Host, User, authentication_string, plugin, User_attributes, Password_expired, max_user_connections, Password_last_changed, Password_lifetime, Account_locked) VALUES `, mysql.SystemDB, mysql.UserTable)
 	}
 
 	users := make([]*auth.UserIdentity, 0, len(s.Specs))
@@ -815,16 +852,18 @@ func (e *SimpleExec) executeCreateUser(ctx context.Context, s *ast.CreateUserStm
 		}
 
 		switch authPlugin {
-		case mysql.AuthNativePassword, mysql.AuthCachingSha2Password, mysql.AuthSocket:
+		case mysql.AuthNativePassword, mysql.AuthCachingSha2Password, mysql.AuthSha256Password, mysql.AuthSocket:
 		default:
-			return ErrPluginIsNotLoaded.GenWithStackByArgs(spec.AuthOpt.AuthPlugin)
+			if !privileges.IsExternalAuthPlugin(authPlugin) {
+				return ErrPluginIsNotLoaded.GenWithStackByArgs(spec.AuthOpt.AuthPlugin)
+			}
 		}
 
 		hostName := strings.ToLower(spec.User.Hostname)
 		if s.IsCreateRole {
 			sqlexec.MustFormatSQL(sql, `(%?, %?, %?, %?, %?)`, hostName, spec.User.Username, pwd, authPlugin, "Y")
 		} else {
-			sqlexec.MustFormatSQL(sql, `(%?, %?, %?, %?)`, hostName, spec.User.Username, pwd, authPlugin)
+			sqlexec.MustFormatSQL(sql, `(%?, %?, %?, %?, %?, %?, %?, CURRENT_TIMESTAMP(), %?, %?)`, hostName, spec.User.Username, pwd, authPlugin, userAttributesValue, passwordExpired, maxUserConns, passwordLifetime, accountLocked)
 		}
 		users = append(users, spec.User)
 	}
@@ -892,6 +931,35 @@ func (e *SimpleExec) executeAlterUser(ctx context.Context, s *ast.AlterUserStmt)
 	if err != nil {
 		return err
 	}
+	userAttributes, err := passwordLocking2UserAttributes(s.PasswordOrLockOptions)
+	if err != nil {
+		return err
+	}
+	// Like MySQL, setting the password locking options or unlocking the account
+	// resets the failed-login state of the account.
+	resetFailedLogin := len(userAttributes) > 0
+	passwordExpired := false
+	// accountLocked is empty if neither ACCOUNT LOCK nor ACCOUNT UNLOCK is specified.
+	accountLocked := ""
+	for _, opt := range s.PasswordOrLockOptions {
+		switch opt.Type {
+		case ast.Lock:
+			accountLocked = "Y"
+		case ast.Unlock:
+			accountLocked = "N"
+			resetFailedLogin = true
+		case ast.PasswordExpire:
+			passwordExpired = true
+		}
+	}
+	maxUserConns, setMaxUserConns, err := resourceOption2MaxUserConnections(s.ResourceOptions)
+	if err != nil {
+		return err
+	}
+	passwordLifetime, setPasswordLifetime, err := passwordExpire2Lifetime(s.PasswordOrLockOptions)
+	if err != nil {
+		return err
+	}
 
 	failedUsers := make([]string, 0, len(s.Specs))
 	checker := privilege.GetPrivilegeManager(e.ctx)
@@ -958,16 +1026,18 @@ func (e *SimpleExec) executeAlterUser(ctx context.Context, s *ast.AlterUserStmt)
 				spec.AuthOpt.AuthPlugin = authplugin
 			}
 			switch spec.AuthOpt.AuthPlugin {
-			case mysql.AuthNativePassword, mysql.AuthCachingSha2Password, mysql.AuthSocket, "":
+			case mysql.AuthNativePassword, mysql.AuthCachingSha2Password, mysql.AuthSha256Password, mysql.AuthSocket, "":
 			default:
-				return ErrPluginIsNotLoaded.GenWithStackByArgs(spec.AuthOpt.AuthPlugin)
+				if !privileges.IsExternalAuthPlugin(spec.AuthOpt.AuthPlugin) {
+					return ErrPluginIsNotLoaded.GenWithStackByArgs(spec.AuthOpt.AuthPlugin)
+				}
 			}
 			pwd, ok := spec.EncodedPassword()
 			if !ok {
 				return errors.Trace(ErrPasswordFormat)
 			}
 			stmt, err := exec.ParseWithParams(ctx,
-				`UPDATE %n.%n SET authentication_string=%?, plugin=%? WHERE Host=%? and User=%?;`,
+				`UPDATE %n.%n SET authentication_string=%?, plugin=%?, Password_expired='N', Password_last_changed=CURRENT_TIMESTAMP() WHERE Host=%? and User=%?;`,
 				mysql.SystemDB, mysql.UserTable, pwd, spec.AuthOpt.AuthPlugin, strings.ToLower(spec.User.Hostname), spec.User.Username,
 			)
 			if err != nil {
@@ -989,6 +1059,67 @@ func (e *SimpleExec) executeAlterUser(ctx context.Context, s *ast.AlterUserStmt)
 				failedUsers = append(failedUsers, spec.User.String())
 			}
 		}
+
+		if len(userAttributes) > 0 {
+			stmt, err := exec.ParseWithParams(ctx,
+				"UPDATE %n.%n SET User_attributes=JSON_MERGE_PATCH(COALESCE(User_attributes, JSON_OBJECT()), CAST(%? AS JSON)) WHERE Host=%? and User=%?;",
+				mysql.SystemDB, mysql.UserTable, string(hack.String(userAttributes)), strings.ToLower(spec.User.Hostname), spec.User.Username,
+			)
+			if err != nil {
+				return err
+			}
+			_, _, err = exec.ExecRestrictedStmt(ctx, stmt)
+			if err != nil {
+				failedUsers = append(failedUsers, spec.User.String())
+			}
+		}
+		if passwordExpired {
+			stmt, err := exec.ParseWithParams(ctx, "UPDATE %n.%n SET Password_expired='Y' WHERE Host=%? and User=%?;",
+				mysql.SystemDB, mysql.UserTable, strings.ToLower(spec.User.Hostname), spec.User.Username)
+			if err != nil {
+				return err
+			}
+			_, _, err = exec.ExecRestrictedStmt(ctx, stmt)
+			if err != nil {
+				failedUsers = append(failedUsers, spec.User.String())
+			}
+		}
+		if setPasswordLifetime {
+			stmt, err := exec.ParseWithParams(ctx, "UPDATE %n.%n SET Password_lifetime=%? WHERE Host=%? and User=%?;",
+				mysql.SystemDB, mysql.UserTable, passwordLifetime, strings.ToLower(spec.User.Hostname), spec.User.Username)
+			if err != nil {
+				return err
+			}
+			_, _, err = exec.ExecRestrictedStmt(ctx, stmt)
+			if err != nil {
+				failedUsers = append(failedUsers, spec.User.String())
+			}
+		}
+		if accountLocked != "" {
+			stmt, err := exec.ParseWithParams(ctx, "UPDATE %n.%n SET Account_locked=%? WHERE Host=%? and User=%?;",
+				mysql.SystemDB, mysql.UserTable, accountLocked, strings.ToLower(spec.User.Hostname), spec.User.Username)
+			if err != nil {
+				return err
+			}
+			_, _, err = exec.ExecRestrictedStmt(ctx, stmt)
+			if err != nil {
+				failedUsers = append(failedUsers, spec.User.String())
+			}
+		}
+		if setMaxUserConns {
+			stmt, err := exec.ParseWithParams(ctx, "UPDATE %n.%n SET max_user_connections=%? WHERE Host=%? and User=%?;",
+				mysql.SystemDB, mysql.UserTable, maxUserConns, strings.ToLower(spec.User.Hostname), spec.User.Username)
+			if err != nil {
+				return err
+			}
+			_, _, err = exec.ExecRestrictedStmt(ctx, stmt)
+			if err != nil {
+				failedUsers = append(failedUsers, spec.User.String())
+			}
+		}
+		if resetFailedLogin {
+			privileges.ResetFailedLogin(spec.User.Username, strings.ToLower(spec.User.Hostname))
+		}
 	}
 	if len(failedUsers) > 0 {
 		// Commit the transaction even if we returns error
@@ -1071,6 +1202,55 @@ func (e *SimpleExec) executeGrantRole(ctx context.Context, s *ast.GrantRoleStmt)
 	return domain.GetDomain(e.ctx).NotifyUpdatePrivilege()
 }
 
+func (e *SimpleExec) executeGrantProxy(ctx context.Context, s *ast.GrantProxyStmt) error {
+	sessionVars := e.ctx.GetSessionVars()
+	users := append([]*auth.UserIdentity{s.LocalUser}, s.ExternalUsers...)
+	for _, user := range users {
+		if user.CurrentUser {
+			user.Username = sessionVars.User.AuthUsername
+			user.Hostname = sessionVars.User.AuthHostname
+		}
+		exists, err := userExists(ctx, e.ctx, user.Username, user.Hostname)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrCannotUser.GenWithStackByArgs("GRANT PROXY", user.String())
+		}
+	}
+
+	restrictedCtx, err := e.getSysSession()
+	if err != nil {
+		return err
+	}
+	defer e.releaseSysSession(restrictedCtx)
+	sqlExecutor := restrictedCtx.(sqlexec.SQLExecutor)
+
+	if _, err := sqlExecutor.ExecuteInternal(context.TODO(), "begin"); err != nil {
+		return err
+	}
+	withGrant := "N"
+	if s.WithGrant {
+		withGrant = "Y"
+	}
+	sql := new(strings.Builder)
+	for _, user := range s.ExternalUsers {
+		sql.Reset()
+		sqlexec.MustFormatSQL(sql, `REPLACE INTO %n.%n (Host, User, Proxied_host, Proxied_user, With_grant, Grantor) VALUES (%?,%?,%?,%?,%?,%?)`,
+			mysql.SystemDB, "proxies_priv", user.Hostname, user.Username, s.LocalUser.Hostname, s.LocalUser.Username, withGrant, sessionVars.User.String())
+		if _, err := sqlExecutor.ExecuteInternal(context.TODO(), sql.String()); err != nil {
+			if _, err := sqlExecutor.ExecuteInternal(context.TODO(), "rollback"); err != nil {
+				return err
+			}
+			return ErrCannotUser.GenWithStackByArgs("GRANT PROXY", user.String())
+		}
+	}
+	if _, err := sqlExecutor.ExecuteInternal(context.TODO(), "commit"); err != nil {
+		return err
+	}
+	return domain.GetDomain(e.ctx).NotifyUpdatePrivilege()
+}
+
 // Should cover same internal mysql.* tables as DROP USER, so this function is very similar
 func (e *SimpleExec) executeRenameUser(s *ast.RenameUserStmt) error {
 
@@ -1320,6 +1500,14 @@ func (e *SimpleExec) executeDropUser(ctx context.Context, s *ast.DropUserStmt) e
 			break
 		}
 
+		// delete privileges from mysql.proxies_priv
+		sql.Reset()
+		sqlexec.MustFormatSQL(sql, `DELETE FROM %n.%n WHERE (Host = %? and User = %?) or (Proxied_host = %? and Proxied_user = %?);`, mysql.SystemDB, "proxies_priv", user.Hostname, user.Username, user.Hostname, user.Username)
+		if _, err = sqlExecutor.ExecuteInternal(context.TODO(), sql.String()); err != nil {
+			failedUsers = append(failedUsers, user.String())
+			break
+		}
+
 		// delete from activeRoles
 		if s.IsDropRole {
 			for i := 0; i < len(activeRoles); i++ {
@@ -1430,7 +1618,7 @@ func (e *SimpleExec) executeSetPwd(ctx context.Context, s *ast.SetPwdStmt) error
 	}
 	var pwd string
 	switch authplugin {
-	case mysql.AuthCachingSha2Password:
+	case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
 		pwd = auth.NewSha2Password(s.Password)
 	case mysql.AuthSocket:
 		e.ctx.GetSessionVars().StmtCtx.AppendNote(ErrSetPasswordAuthPlugin.GenWithStackByArgs(u, h))
@@ -1441,7 +1629,7 @@ func (e *SimpleExec) executeSetPwd(ctx context.Context, s *ast.SetPwdStmt) error
 
 	// update mysql.user
 	exec := e.ctx.(sqlexec.RestrictedSQLExecutor)
-	stmt, err := exec.ParseWithParams(ctx, `UPDATE %n.%n SET authentication_string=%? WHERE User=%? AND Host=%?;`, mysql.SystemDB, mysql.UserTable, pwd, u, strings.ToLower(h))
+	stmt, err := exec.ParseWithParams(ctx, `UPDATE %n.%n SET authentication_string=%?, Password_expired='N', Password_last_changed=CURRENT_TIMESTAMP() WHERE User=%? AND Host=%?;`, mysql.SystemDB, mysql.UserTable, pwd, u, strings.ToLower(h))
 	if err != nil {
 		return err
 	}
@@ -1460,7 +1648,7 @@ func (e *SimpleExec) executeKillStmt(ctx context.Context, s *ast.KillStmt) error
 			if sm == nil {
 				return nil
 			}
-			sm.Kill(s.ConnectionID, s.Query)
+			killLocalConn(sm, s)
 		} else {
 			err := errors.New("Invalid operation. Please use 'KILL TIDB [CONNECTION | QUERY] connectionID' instead")
 			e.ctx.GetSessionVars().StmtCtx.AppendWarning(err)
@@ -1475,7 +1663,7 @@ func (e *SimpleExec) executeKillStmt(ctx context.Context, s *ast.KillStmt) error
 	if e.IsFromRemote {
 		logutil.BgLogger().Info("Killing connection in current instance redirected from remote TiDB", zap.Uint64("connID", s.ConnectionID), zap.Bool("query", s.Query),
 			zap.String("sourceAddr", e.ctx.GetSessionVars().SourceAddr.IP.String()))
-		sm.Kill(s.ConnectionID, s.Query)
+		killLocalConn(sm, s)
 		return nil
 	}
 
@@ -1496,17 +1684,49 @@ func (e *SimpleExec) executeKillStmt(ctx context.Context, s *ast.KillStmt) error
 	}
 
 	if connID.ServerID != sm.ServerID() {
+		if s.WaitSeconds > 0 {
+			e.ctx.GetSessionVars().StmtCtx.AppendWarning(errors.New("WAIT is ignored when killing the query on another TiDB instance"))
+		}
 		if err := killRemoteConn(ctx, e.ctx, &connID, s.Query); err != nil {
 			err1 := errors.New("KILL remote connection failed: " + err.Error())
 			e.ctx.GetSessionVars().StmtCtx.AppendWarning(err1)
 		}
 	} else {
-		sm.Kill(s.ConnectionID, s.Query)
+		killLocalConn(sm, s)
 	}
 
 	return nil
 }
 
+func killLocalConn(sm util.SessionManager, s *ast.KillStmt) {
+	if s.Query && s.WaitSeconds > 0 {
+		killQueryWithGracePeriod(sm, s.ConnectionID, time.Duration(s.WaitSeconds)*time.Second)
+		return
+	}
+	sm.Kill(s.ConnectionID, s.Query)
+}
+
+// killQueryWithGracePeriod asks the running query of the connection to stop with a partial result,
+// and kills it if the same query is still running after the grace period.
+func killQueryWithGracePeriod(sm util.SessionManager, connID uint64, gracePeriod time.Duration) {
+	pi, ok := sm.GetProcessInfo(connID)
+	if !ok || pi.StmtCtx == nil || pi.Command == mysql.ComSleep {
+		sm.Kill(connID, true)
+		return
+	}
+	// The statement contexts are reused by the session, so the start time is also checked to
+	// identify the query.
+	stmtCtx, startTime := pi.StmtCtx, pi.Time
+	stmtCtx.SoftKilled.Store(true)
+	logutil.BgLogger().Info("soft kill query", zap.Uint64("connID", connID), zap.Duration("gracePeriod", gracePeriod))
+	time.AfterFunc(gracePeriod, func() {
+		pi, ok := sm.GetProcessInfo(connID)
+		if ok && pi.StmtCtx == stmtCtx && pi.Time.Equal(startTime) && pi.Command != mysql.ComSleep {
+			sm.Kill(connID, true)
+		}
+	})
+}
+
 func killRemoteConn(ctx context.Context, sctx sessionctx.Context, connID *util.GlobalConnID, query bool) error {
 	if connID.ServerID == 0 {
 		return errors.New("Unexpected ZERO ServerID. Please file a bug to the TiDB Team")
@@ -1557,6 +1777,9 @@ func (e *SimpleExec) executeFlush(s *ast.FlushStmt) error {
 			return errors.New("FLUSH TABLES WITH READ LOCK is not supported.  Please use @@tidb_snapshot")
 		}
 	case ast.FlushPrivileges:
+		// The failed-login state is kept in memory, FLUSH PRIVILEGES releases the accounts
+		// locked by consecutive failed logins on this instance.
+		privileges.ResetFailedLogins()
 		dom := domain.GetDomain(e.ctx)
 		return dom.NotifyUpdatePrivilege()
 	case ast.FlushTiDBPlugin:
@@ -1567,7 +1790,8 @@ func (e *SimpleExec) executeFlush(s *ast.FlushStmt) error {
 				return err
 			}
 		}
-	case ast.FlushClientErrorsSummary:
+	case ast.FlushStatus, ast.FlushClientErrorsSummary:
+		// TiDB has no resettable status variables, so FLUSH STATUS resets the client error summaries.
 		errno.FlushStats()
 	}
 	return nil
@@ -1668,3 +1892,31 @@ func (e *SimpleExec) executeAdminReloadStatistics(s *ast.AdminStmt) error {
 	}
 	return domain.GetDomain(e.ctx).StatsHandle().ReloadExtendedStatistics()
 }
+
+// executeAdminReloadSystemTZ compares the system time zone of the host with the one stored in mysql.tidb.
+// With FORCE, the stored one is replaced by the host's, and all the tidb servers are notified to reload it.
+func (e *SimpleExec) executeAdminReloadSystemTZ(ctx context.Context, s *ast.AdminStmt) error {
+	stored, err := timeutil.GetSystemTZ()
+	if err != nil {
+		return err
+	}
+	detected := timeutil.InferSystemTZ()
+	if detected == stored {
+		return nil
+	}
+	if !s.Force {
+		return errors.Errorf("The system time zone of the host is '%s' while the stored one is '%s', use ADMIN RELOAD SYSTEM_TZ FORCE to replace it", detected, stored)
+	}
+	exec := e.ctx.(sqlexec.RestrictedSQLExecutor)
+	stmt, err := exec.ParseWithParams(ctx, `UPDATE %n.%n SET variable_value=%? WHERE variable_name=%?`, mysql.SystemDB, mysql.TiDBTable, detected, "system_tz")
+	if err != nil {
+		return err
+	}
+	if _, _, err = exec.ExecRestrictedStmt(ctx, stmt); err != nil {
+		return err
+	}
+	logutil.BgLogger().Warn("system time zone is replaced by ADMIN RELOAD SYSTEM_TZ FORCE",
+		zap.String("old", stored), zap.String("new", detected))
+	domain.GetDomain(e.ctx).NotifyUpdateSysVarCache()
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/pingcap/tidb/util/israce"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/timeutil"
 )
 
 func (s *testSuite3) TestCharsetDatabase(c *C) {
@@ -786,6 +787,16 @@ func (s *testSuite3) TestFlushTables(c *C) {
 
 }
 
+func (s *testSuite3) TestResetMaster(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+
+	_, err := tk.Exec("RESET MASTER")
+	c.Check(err, IsNil)
+	c.Assert(tk.Se.GetSessionVars().StmtCtx.WarningCount(), Equals, uint16(1))
+	tk.MustQuery("show warnings").Check(testutil.RowsWithSep("|", "Warning|8246|RESET MASTER is not applicable in TiDB; binlog position reset is a no-op"))
+	tk.MustQuery("select @@error_count").Check(testkit.Rows("0"))
+}
+
 func (s *testSuite3) TestUseDB(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	_, err := tk.Exec("USE test")
@@ -988,3 +999,31 @@ func (s *testSuite3) TestDropRoleAfterRevoke(c *C) {
 	tk.MustExec("revoke r1, r3 from root;")
 	tk.MustExec("drop role r1;")
 }
+
+func (s *testSerialSuite) TestAdminReloadSystemTZ(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	detected := timeutil.InferSystemTZ()
+	stored := "Pacific/Honolulu"
+	if detected == stored {
+		stored = "Asia/Tokyo"
+	}
+	tk.MustExec("update mysql.tidb set variable_value = ? where variable_name = 'system_tz'", stored)
+	domain.GetDomain(tk.Se).NotifyUpdateSysVarCache()
+	defer func() {
+		tk.MustExec("update mysql.tidb set variable_value = ? where variable_name = 'system_tz'", detected)
+		domain.GetDomain(tk.Se).NotifyUpdateSysVarCache()
+	}()
+	tk.MustQuery("select @@system_time_zone").Check(testkit.Rows(stored))
+
+	// The stored system time zone is only replaced with FORCE.
+	_, err := tk.Exec("admin reload system_tz")
+	c.Assert(err, ErrorMatches, ".*use ADMIN RELOAD SYSTEM_TZ FORCE to replace it")
+	tk.MustExec("admin reload system_tz force")
+	tk.MustQuery("select variable_value from mysql.tidb where variable_name = 'system_tz'").Check(testkit.Rows(detected))
+	tk.MustExec("admin reload system_tz")
+
+	// New sessions use the reloaded system time zone.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustQuery("select @@system_time_zone").Check(testkit.Rows(detected))
+	c.Assert(tk1.Se.GetSessionVars().Location().String(), Equals, detected)
+}
@@ -16,7 +16,9 @@ package executor
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -55,6 +57,9 @@ import (
 // ParseSlowLogBatchSize is the batch size of slow-log lines for a worker to parse, exported for testing.
 var ParseSlowLogBatchSize = 64
 
+// slowLogJSONPrefix is the prefix of a slow query written in the JSON format, which always starts with the time.
+const slowLogJSONPrefix = `{"` + variable.SlowLogTimeStr + `":`
+
 // slowQueryRetriever is used to read slow log data.
 type slowQueryRetriever struct {
 	table                 *model.TableInfo
@@ -313,6 +318,10 @@ func (e *slowQueryRetriever) getBatchLog(ctx context.Context, reader *bufio.Read
 			}
 			line = string(hack.String(lineByte))
 			log = append(log, line)
+			// A slow query in the JSON format takes a single line.
+			if strings.HasPrefix(line, slowLogJSONPrefix) {
+				break
+			}
 			if strings.HasSuffix(line, variable.SlowLogSQLSuffixStr) {
 				if strings.HasPrefix(line, "use") || strings.HasPrefix(line, variable.SlowLogRowPrefixStr) {
 					continue
@@ -364,6 +373,13 @@ func (e *slowQueryRetriever) getBatchLogForReversedScan(ctx context.Context, rea
 			return nil, err
 		}
 		line = string(hack.String(lineByte))
+		if !hasStartFlag && strings.HasPrefix(line, slowLogJSONPrefix) {
+			logs = append(logs, []string{line})
+			if scanPreviousFile {
+				break
+			}
+			continue
+		}
 		if !hasStartFlag && strings.HasPrefix(line, variable.SlowLogStartPrefixStr) {
 			hasStartFlag = true
 		}
@@ -521,6 +537,69 @@ func splitByColon(line string) (fields []string, values []string) {
 	return fields, values
 }
 
+// splitSlowLogLine splits a line of the slow log like "field: value field: value...", whose "# " prefix is trimmed.
+// The User@Host field is split into the User and Host fields, and the Cop_backoff fields are merged into Backoff_Detail.
+func splitSlowLogLine(line string) (fields []string, values []string) {
+	if strings.HasPrefix(line, variable.SlowLogPrevStmtPrefix) {
+		return []string{variable.SlowLogPrevStmt}, []string{line[len(variable.SlowLogPrevStmtPrefix):]}
+	}
+	if strings.HasPrefix(line, variable.SlowLogUserAndHostStr+variable.SlowLogSpaceMarkStr) {
+		value := line[len(variable.SlowLogUserAndHostStr+variable.SlowLogSpaceMarkStr):]
+		userAndHost := strings.SplitN(value, "@", 2)
+		if len(userAndHost) < 2 {
+			return nil, nil
+		}
+		return []string{variable.SlowLogUserStr, variable.SlowLogHostStr},
+			[]string{parseUserOrHostValue(userAndHost[0]), parseUserOrHostValue(userAndHost[1])}
+	}
+	if strings.HasPrefix(line, variable.SlowLogCopBackoffPrefix) {
+		return []string{variable.SlowLogBackoffDetail}, []string{line}
+	}
+	return splitByColon(line)
+}
+
+// formatSlowLogJSON converts a slow query in the text format produced by SessionVars.SlowLogFormat to a
+// single-line JSON object. The keys are the field names of the text format, and the values are kept as
+// strings so that the large integers like Txn_start_ts are not rounded by the JSON decoders.
+func formatSlowLogJSON(t time.Time, slowLog string) string {
+	keys := []string{variable.SlowLogTimeStr}
+	items := map[string]string{variable.SlowLogTimeStr: t.Format(logutil.SlowLogTimeFormat)}
+	var query []string
+	for _, line := range strings.Split(slowLog, "\n") {
+		if !strings.HasPrefix(line, variable.SlowLogRowPrefixStr) {
+			query = append(query, line)
+			continue
+		}
+		fields, values := splitSlowLogLine(line[len(variable.SlowLogRowPrefixStr):])
+		for i := range fields {
+			if value, ok := items[fields[i]]; ok {
+				items[fields[i]] = value + " " + values[i]
+				continue
+			}
+			keys = append(keys, fields[i])
+			items[fields[i]] = values[i]
+		}
+	}
+	keys = append(keys, variable.SlowLogQuerySQLStr)
+	items[variable.SlowLogQuerySQLStr] = strings.Join(query, "\n")
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Marshaling a string never fails.
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(items[key])
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
 func (e *slowQueryRetriever) parseLog(ctx context.Context, sctx sessionctx.Context, log []string, offset offset) (data [][]types.Datum, err error) {
 	start := time.Now()
 	defer func() {
@@ -549,6 +628,13 @@ func (e *slowQueryRetriever) parseLog(ctx context.Context, sctx sessionctx.Conte
 			return nil, ctx.Err()
 		}
 		fileLine := getLineIndex(offset, index)
+		if strings.HasPrefix(line, slowLogJSONPrefix) {
+			startFlag = false
+			if row := e.parseJSONLog(sctx, tz, line, fileLine); row != nil {
+				data = append(data, row)
+			}
+			continue
+		}
 		if !startFlag && strings.HasPrefix(line, variable.SlowLogStartPrefixStr) {
 			row = make([]types.Datum, len(e.outputCols))
 			user = ""
@@ -560,42 +646,20 @@ func (e *slowQueryRetriever) parseLog(ctx context.Context, sctx sessionctx.Conte
 		}
 		if startFlag {
 			if strings.HasPrefix(line, variable.SlowLogRowPrefixStr) {
-				line = line[len(variable.SlowLogRowPrefixStr):]
-				valid := true
-				if strings.HasPrefix(line, variable.SlowLogPrevStmtPrefix) {
-					valid = e.setColumnValue(sctx, row, tz, variable.SlowLogPrevStmt, line[len(variable.SlowLogPrevStmtPrefix):], e.checker, fileLine)
-				} else if strings.HasPrefix(line, variable.SlowLogUserAndHostStr+variable.SlowLogSpaceMarkStr) {
-					value := line[len(variable.SlowLogUserAndHostStr+variable.SlowLogSpaceMarkStr):]
-					fields := strings.SplitN(value, "@", 2)
-					if len(fields) < 2 {
-						continue
-					}
-					user = parseUserOrHostValue(fields[0])
-					if e.checker != nil && !e.checker.hasPrivilege(user) {
-						startFlag = false
-						continue
-					}
-					valid = e.setColumnValue(sctx, row, tz, variable.SlowLogUserStr, user, e.checker, fileLine)
-					if !valid {
-						startFlag = false
-						continue
-					}
-					host := parseUserOrHostValue(fields[1])
-					valid = e.setColumnValue(sctx, row, tz, variable.SlowLogHostStr, host, e.checker, fileLine)
-				} else if strings.HasPrefix(line, variable.SlowLogCopBackoffPrefix) {
-					valid = e.setColumnValue(sctx, row, tz, variable.SlowLogBackoffDetail, line, e.checker, fileLine)
-				} else {
-					fields, values := splitByColon(line)
-					for i := 0; i < len(fields); i++ {
-						valid := e.setColumnValue(sctx, row, tz, fields[i], values[i], e.checker, fileLine)
-						if !valid {
+				fields, values := splitSlowLogLine(line[len(variable.SlowLogRowPrefixStr):])
+				for i := 0; i < len(fields); i++ {
+					if fields[i] == variable.SlowLogUserStr {
+						user = values[i]
+						if e.checker != nil && !e.checker.hasPrivilege(user) {
 							startFlag = false
 							break
 						}
 					}
-				}
-				if !valid {
-					startFlag = false
+					valid := e.setColumnValue(sctx, row, tz, fields[i], values[i], e.checker, fileLine)
+					if !valid {
+						startFlag = false
+						break
+					}
 				}
 			} else if strings.HasSuffix(line, variable.SlowLogSQLSuffixStr) {
 				if strings.HasPrefix(line, "use") {
@@ -621,6 +685,34 @@ func (e *slowQueryRetriever) parseLog(ctx context.Context, sctx sessionctx.Conte
 	return data, nil
 }
 
+// parseJSONLog parses a slow query written in the JSON format, it returns nil if the slow query is filtered out.
+func (e *slowQueryRetriever) parseJSONLog(sctx sessionctx.Context, tz *time.Location, line string, fileLine int) []types.Datum {
+	items := make(map[string]string)
+	if err := json.Unmarshal(hack.Slice(line), &items); err != nil {
+		err = fmt.Errorf("Parse slow log at line %v failed, error is %v", fileLine, err)
+		sctx.GetSessionVars().StmtCtx.AppendWarning(err)
+		return nil
+	}
+	row := make([]types.Datum, len(e.outputCols))
+	// The time is checked first, so the slow queries out of the time range are skipped quickly.
+	if !e.setColumnValue(sctx, row, tz, variable.SlowLogTimeStr, items[variable.SlowLogTimeStr], e.checker, fileLine) {
+		return nil
+	}
+	if e.checker != nil && !e.checker.hasPrivilege(items[variable.SlowLogUserStr]) {
+		return nil
+	}
+	for field, value := range items {
+		if field == variable.SlowLogTimeStr {
+			continue
+		}
+		if !e.setColumnValue(sctx, row, tz, field, value, e.checker, fileLine) {
+			return nil
+		}
+	}
+	e.setDefaultValue(row)
+	return row
+}
+
 func (e *slowQueryRetriever) setColumnValue(sctx sessionctx.Context, row []types.Datum, tz *time.Location, field, value string, checker *slowLogChecker, lineNum int) bool {
 	factory := e.columnValueFactoryMap[field]
 	if factory == nil {
@@ -921,9 +1013,8 @@ func (e *slowQueryRetriever) getFileStartTime(ctx context.Context, file *os.File
 		if err != nil {
 			return t, err
 		}
-		line := string(lineByte)
-		if strings.HasPrefix(line, variable.SlowLogStartPrefixStr) {
-			return ParseTime(line[len(variable.SlowLogStartPrefixStr):])
+		if t, ok, err := parseSlowLogStartTime(string(lineByte)); ok {
+			return t, err
 		}
 		maxNum -= 1
 		if maxNum <= 0 {
@@ -936,6 +1027,28 @@ func (e *slowQueryRetriever) getFileStartTime(ctx context.Context, file *os.File
 	return t, errors.Errorf("malform slow query file %v", file.Name())
 }
 
+// parseSlowLogStartTime parses the time of the slow query if the line is the first line of a slow query
+// in either the text or the JSON format.
+func parseSlowLogStartTime(line string) (t time.Time, ok bool, err error) {
+	if strings.HasPrefix(line, variable.SlowLogStartPrefixStr) {
+		t, err = ParseTime(line[len(variable.SlowLogStartPrefixStr):])
+		return t, true, err
+	}
+	if strings.HasPrefix(line, slowLogJSONPrefix) {
+		value := line[len(slowLogJSONPrefix):]
+		if len(value) < 2 || value[0] != '"' {
+			return t, true, errors.Errorf("malform slow log time %v", line)
+		}
+		end := strings.IndexByte(value[1:], '"')
+		if end < 0 {
+			return t, true, errors.Errorf("malform slow log time %v", line)
+		}
+		t, err = ParseTime(value[1 : end+1])
+		return t, true, err
+	}
+	return t, false, nil
+}
+
 func (e *slowQueryRetriever) getRuntimeStats() execdetails.RuntimeStats {
 	return e.stats
 }
@@ -1003,8 +1116,8 @@ func (e *slowQueryRetriever) getFileEndTime(ctx context.Context, file *os.File)
 		}
 		endCursor -= int64(readBytes)
 		for i := len(lines) - 1; i >= 0; i-- {
-			if strings.HasPrefix(lines[i], variable.SlowLogStartPrefixStr) {
-				return ParseTime(lines[i][len(variable.SlowLogStartPrefixStr):])
+			if t, ok, err := parseSlowLogStartTime(lines[i]); ok {
+				return t, err
 			}
 		}
 		tried += len(lines)
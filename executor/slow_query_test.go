@@ -18,6 +18,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -238,6 +239,125 @@ select * from t;
 	c.Assert(warnings[0].Err.Error(), Equals, "Parse slow log at line 2, failed field is Succ, failed value is abc, error is strconv.ParseBool: parsing \"abc\": invalid syntax")
 }
 
+func (s *testExecSuite) TestParseSlowLogJSON(c *C) {
+	slowLogBody := `# Txn_start_ts: 405888132465033227
+# User@Host: root[root] @ localhost [127.0.0.1]
+# Exec_retry_time: 0.12 Exec_retry_count: 57
+# Query_time: 0.216905
+# Cop_time: 0.38 Process_time: 0.021 Request_count: 1 Total_keys: 637 Processed_keys: 436
+# Is_internal: true
+# Digest: 42a1c8aae6f133e934d4bf0147491709a8812ea05ff8819ec522780fe657b772
+# Stats: t1:1,t2:2
+# Cop_backoff_regionMiss_total_times: 200 Cop_backoff_regionMiss_total_time: 0.2
+# Cop_backoff_rpcPD_total_times: 200 Cop_backoff_rpcPD_total_time: 0.2
+# Mem_max: 70724
+# Succ: false
+# Plan_digest: 60e9378c746d9a2be1c791047e008967cf252eb6de9167ad3aa6098fa2d523f4
+# Prev_stmt: update t set i = 1;
+select * from t;`
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	c.Assert(err, IsNil)
+	t, err := ParseTime("2019-04-28T15:24:04.309074+08:00")
+	c.Assert(err, IsNil)
+	jsonLog := formatSlowLogJSON(t.In(loc), slowLogBody)
+	c.Assert(strings.HasPrefix(jsonLog, `{"Time":"2019-04-28T15:24:04.309074+08:00","Txn_start_ts":"405888132465033227","User":"root","Host":"localhost",`), IsTrue, Commentf("%s", jsonLog))
+	c.Assert(strings.Contains(jsonLog, "\n"), IsFalse)
+	items := make(map[string]string)
+	c.Assert(json.Unmarshal([]byte(jsonLog), &items), IsNil)
+	c.Assert(items["Digest"], Equals, "42a1c8aae6f133e934d4bf0147491709a8812ea05ff8819ec522780fe657b772")
+	c.Assert(items["Mem_max"], Equals, "70724")
+	c.Assert(items["Backoff_Detail"], Equals, "Cop_backoff_regionMiss_total_times: 200 Cop_backoff_regionMiss_total_time: 0.2 Cop_backoff_rpcPD_total_times: 200 Cop_backoff_rpcPD_total_time: 0.2")
+	c.Assert(items["Prev_stmt"], Equals, "update t set i = 1;")
+	c.Assert(items["Query"], Equals, "select * from t;")
+
+	// The JSON format is parsed to the same row as the text format.
+	ctx := mock.NewContext()
+	ctx.GetSessionVars().TimeZone = loc
+	textRows, err := parseSlowLog(ctx, bufio.NewReader(bytes.NewBufferString("# Time: 2019-04-28T15:24:04.309074+08:00\n"+slowLogBody)), 64)
+	c.Assert(err, IsNil)
+	c.Assert(textRows, HasLen, 1)
+	jsonRows, err := parseSlowLog(ctx, bufio.NewReader(bytes.NewBufferString(jsonLog+"\n"+jsonLog+"\n")), 64)
+	c.Assert(err, IsNil)
+	c.Assert(jsonRows, HasLen, 2)
+	for _, row := range jsonRows {
+		c.Assert(row, HasLen, len(textRows[0]))
+		for i := range row {
+			expected, err := textRows[0][i].ToString()
+			c.Assert(err, IsNil)
+			actual, err := row[i].ToString()
+			c.Assert(err, IsNil)
+			c.Assert(actual, Equals, expected, Commentf("column %d", i))
+		}
+	}
+	c.Assert(ctx.GetSessionVars().StmtCtx.GetWarnings(), HasLen, 0)
+
+	// A malformed JSON line is skipped with a warning.
+	rows, err := parseSlowLog(ctx, bufio.NewReader(bytes.NewBufferString(`{"Time":"2019-04-28T15:24:04.309074+08:00","Query":`+"\n")), 64)
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 0)
+	c.Assert(ctx.GetSessionVars().StmtCtx.GetWarnings(), HasLen, 1)
+}
+
+func (s *testExecSuite) TestSlowQueryRetrieverJSON(c *C) {
+	logData0 := `{"Time":"2020-02-15T18:00:01.000000+08:00","Query":"select 1;"}
+{"Time":"2020-02-15T19:00:05.000000+08:00","Query":"select 2;"}`
+	logData1 := `{"Time":"2020-02-16T18:00:01.000000+08:00","Query":"select 3;"}
+{"Time":"2020-02-16T18:30:00.000000+08:00","Query":"select\n4;"}`
+	fileName0 := "tidb-slow-2020-02-15T19-04-05.01.log"
+	fileName1 := "tidb-slow.log"
+	fileNames := []string{fileName0, fileName1}
+	prepareLogs(c, []string{logData0, logData1}, fileNames)
+	defer func() {
+		removeFiles(fileNames)
+	}()
+
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	c.Assert(err, IsNil)
+	sctx := mock.NewContext()
+	sctx.GetSessionVars().TimeZone = loc
+	sctx.GetSessionVars().SlowQueryFile = fileName1
+	for _, desc := range []bool{false, true} {
+		comment := Commentf("desc: %v", desc)
+		startTime, err := ParseTime("2020-02-15T19:00:00.000000+08:00")
+		c.Assert(err, IsNil)
+		endTime, err := ParseTime("2020-02-16T19:00:00.000000+08:00")
+		c.Assert(err, IsNil)
+		retriever, err := newSlowQueryRetriever()
+		c.Assert(err, IsNil)
+		retriever.extractor = &plannercore.SlowQueryExtractor{
+			Enable:     true,
+			Desc:       desc,
+			TimeRanges: []*plannercore.TimeRange{{StartTime: startTime, EndTime: endTime}},
+		}
+		c.Assert(retriever.initialize(context.Background(), sctx), IsNil)
+		c.Assert(retriever.files, HasLen, 2, comment)
+		reader := bufio.NewReader(retriever.files[0].file)
+		var logs [][]string
+		if desc {
+			logs, err = retriever.getBatchLogForReversedScan(context.Background(), reader, &offset{}, 64)
+		} else {
+			logs, err = retriever.getBatchLog(context.Background(), reader, &offset{}, 64)
+		}
+		c.Assert(err, IsNil)
+		c.Assert(logs, HasLen, 1, comment)
+		rows, err := retriever.parseLog(context.Background(), sctx, logs[0], offset{})
+		c.Assert(err, IsNil)
+		queries := make([]string, 0, len(rows))
+		for _, row := range rows {
+			queries = append(queries, row[len(row)-1].GetString())
+		}
+		if desc {
+			// The reversed scan reads the lines of the last file backwards.
+			c.Assert(logs[0], HasLen, 2, comment)
+			c.Assert(queries, DeepEquals, []string{"select\n4;", "select 3;"}, comment)
+		} else {
+			c.Assert(logs[0], HasLen, 4, comment)
+			c.Assert(queries, DeepEquals, []string{"select 2;", "select 3;", "select\n4;"}, comment)
+		}
+		c.Assert(retriever.close(), IsNil)
+	}
+}
+
 // It changes variable.MaxOfMaxAllowedPacket, so must be stayed in SerialSuite.
 func (s *testExecSerialSuite) TestParseSlowLogFileSerial(c *C) {
 	loc, err := time.LoadLocation("Asia/Shanghai")
@@ -375,7 +375,8 @@ func doSleep(secs float64, sessVars *variable.SessionVars) (isKilled bool) {
 	for {
 		select {
 		case <-ticker.C:
-			if atomic.CompareAndSwapUint32(&sessVars.Killed, 1, 0) {
+			// The soft kill is not reset, so that the rest of the statement stops as soon as possible.
+			if atomic.CompareAndSwapUint32(&sessVars.Killed, 1, 0) || sessVars.StmtCtx.SoftKilled.Load() {
 				timer.Stop()
 				return true
 			}
@@ -6728,10 +6728,20 @@ func TestCharsetErr(t *testing.T) {
 	tk.MustExec("use test")
 	tk.MustExec("create table charset_test(id int auto_increment primary key, c1 varchar(255) character set ascii)")
 	err := tk.ExecToErr("insert into charset_test(c1) values ('aaa\xEF\xBF\xBDabcdef')")
-	require.Error(t, err, "[table:1366]Incorrect string value '\\xEF\\xBF\\xBDabc...' for column 'c1'")
+	require.EqualError(t, err, "[table:1366]Incorrect string value: '\\xEF\\xBF\\xBDabc...' for column 'c1' at row 1")
 
 	err = tk.ExecToErr("insert into charset_test(c1) values ('aaa\xEF\xBF\xBD')")
-	require.Error(t, err, "[table:1366]Incorrect string value '\\xEF\\xBF\\xBD' for column 'c1'")
+	require.EqualError(t, err, "[table:1366]Incorrect string value: '\\xEF\\xBF\\xBD' for column 'c1' at row 1")
+
+	// The row of the invalid string is reported like MySQL.
+	err = tk.ExecToErr("insert into charset_test(c1) values ('abc'), ('a\xC3\x8Abc')")
+	require.EqualError(t, err, "[table:1366]Incorrect string value: '\\xC3\\x8Abc' for column 'c1' at row 2")
+
+	// The invalid characters are still replaced if they are not errors.
+	tk.MustExec("set @@sql_mode = ''")
+	tk.MustExec("insert into charset_test(c1) values ('a\xC3\x8Abc')")
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1366 Incorrect string value '\\xC3\\x8Abc' for column 'c1'"))
+	tk.MustQuery("select c1 from charset_test").Check(testkit.Rows("a?bc"))
 }
 
 func TestIssue25591(t *testing.T) {
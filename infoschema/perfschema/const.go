@@ -46,6 +46,7 @@ var perfSchemaTables = []string{
 	tablePDProfileAllocs,
 	tablePDProfileBlock,
 	tablePDProfileGoroutines,
+	tableSessionConnectAttrs,
 }
 
 // tableGlobalStatus contains the column name definitions for table global_status, same as MySQL.
@@ -544,3 +545,10 @@ const tablePDProfileGoroutines = "CREATE TABLE IF NOT EXISTS " + tableNamePDProf
 	"ID INT(8) NOT NULL," +
 	"STATE VARCHAR(16) NOT NULL," +
 	"LOCATION VARCHAR(512) NOT NULL);"
+
+// tableSessionConnectAttrs contains the column name definitions for table session_connect_attrs, same as MySQL.
+const tableSessionConnectAttrs = "CREATE TABLE IF NOT EXISTS performance_schema." + tableNameSessionConnectAttrs + " (" +
+	"PROCESSLIST_ID BIGINT(20) UNSIGNED NOT NULL," +
+	"ATTR_NAME VARCHAR(32) NOT NULL," +
+	"ATTR_VALUE VARCHAR(1024)," +
+	"ORDINAL_POSITION INT(11));"
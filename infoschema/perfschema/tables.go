@@ -28,7 +28,9 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/parser/terror"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
@@ -68,6 +70,7 @@ const (
 	tableNamePDProfileAllocs                 = "pd_profile_allocs"
 	tableNamePDProfileBlock                  = "pd_profile_block"
 	tableNamePDProfileGoroutines             = "pd_profile_goroutines"
+	tableNameSessionConnectAttrs             = "session_connect_attrs"
 )
 
 var tableIDMap = map[string]int64{
@@ -101,6 +104,7 @@ var tableIDMap = map[string]int64{
 	tableNamePDProfileAllocs:                 autoid.PerformanceSchemaDBID + 28,
 	tableNamePDProfileBlock:                  autoid.PerformanceSchemaDBID + 29,
 	tableNamePDProfileGoroutines:             autoid.PerformanceSchemaDBID + 30,
+	tableNameSessionConnectAttrs:             autoid.PerformanceSchemaDBID + 31,
 }
 
 // perfSchemaTable stands for the fake table all its data is in the memory.
@@ -247,6 +251,8 @@ func (vt *perfSchemaTable) getRows(ctx sessionctx.Context, cols []*table.Column)
 		fullRows, err = dataForRemoteProfile(ctx, "pd", "/pd/api/v1/debug/pprof/block", false)
 	case tableNamePDProfileGoroutines:
 		fullRows, err = dataForRemoteProfile(ctx, "pd", "/pd/api/v1/debug/pprof/goroutine?debug=2", true)
+	case tableNameSessionConnectAttrs:
+		fullRows = dataForSessionConnectAttrs(ctx)
 	}
 	if err != nil {
 		return
@@ -284,6 +290,40 @@ func (vt *perfSchemaTable) IterRecords(ctx sessionctx.Context, cols []*table.Col
 	return nil
 }
 
+func dataForSessionConnectAttrs(ctx sessionctx.Context) [][]types.Datum {
+	sm := ctx.GetSessionManager()
+	if sm == nil {
+		return nil
+	}
+	loginUser := ctx.GetSessionVars().User
+	hasProcessPriv := true
+	if pm := privilege.GetPrivilegeManager(ctx); pm != nil {
+		hasProcessPriv = pm.RequestVerification(ctx.GetSessionVars().ActiveRoles, "", "", "", mysql.ProcessPriv)
+	}
+	var rows [][]types.Datum
+	for _, pi := range sm.ShowProcessList() {
+		// Like the processlist, the attributes of other users are shown only with the PROCESS privilege.
+		if !hasProcessPriv && loginUser != nil && pi.User != loginUser.Username {
+			continue
+		}
+		// The client does not send the attributes in a specific order, so they are ordered by the names.
+		names := make([]string, 0, len(pi.ConnectionAttrs))
+		for name := range pi.ConnectionAttrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			rows = append(rows, types.MakeDatums(
+				pi.ID,                    // PROCESSLIST_ID
+				name,                     // ATTR_NAME
+				pi.ConnectionAttrs[name], // ATTR_VALUE
+				i,                        // ORDINAL_POSITION
+			))
+		}
+	}
+	return rows
+}
+
 func dataForRemoteProfile(ctx sessionctx.Context, nodeType, uri string, isGoroutine bool) ([][]types.Datum, error) {
 	var (
 		servers []infoschema.ServerInfo
@@ -106,7 +106,7 @@ const (
 	TableTiDBIndexes = "TIDB_INDEXES"
 	// TableTiDBHotRegions is the string constant of infoschema table
 	TableTiDBHotRegions = "TIDB_HOT_REGIONS"
-	// TableTiDBHotRegionsHistory is the string constant of infoschema table
+	// TableTiDBHotRegionsHistory is the string constant of infoschema table, it requires PD v5.3.0 or later.
 	TableTiDBHotRegionsHistory = "TIDB_HOT_REGIONS_HISTORY"
 	// TableTiKVStoreStatus is the string constant of infoschema table
 	TableTiKVStoreStatus = "TIKV_STORE_STATUS"
@@ -787,6 +787,10 @@ var tableProcesslistCols = []columnInfo{
 	{name: "MEM", tp: mysql.TypeLonglong, size: 21, flag: mysql.UnsignedFlag},
 	{name: "DISK", tp: mysql.TypeLonglong, size: 21, flag: mysql.UnsignedFlag},
 	{name: "TxnStart", tp: mysql.TypeVarchar, size: 64, flag: mysql.NotNullFlag, deflt: ""},
+	{name: "PLAN_DIGEST", tp: mysql.TypeVarchar, size: 64, deflt: ""},
+	{name: "TRANSPORT", tp: mysql.TypeVarchar, size: 16},
+	{name: "SSL_VERSION", tp: mysql.TypeVarchar, size: 16},
+	{name: "SSL_CIPHER", tp: mysql.TypeVarchar, size: 64},
 }
 
 var tableTiDBIndexesCols = []columnInfo{
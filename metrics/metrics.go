@@ -74,8 +74,18 @@ func RegisterMetrics() {
 	prometheus.MustRegister(BindTotalGauge)
 	prometheus.MustRegister(BindMemoryUsage)
 	prometheus.MustRegister(CampaignOwnerCounter)
-	prometheus.MustRegister(ConnGauge)
 	prometheus.MustRegister(DisconnectionCounter)
+	prometheus.MustRegister(WriteBufferMemoryGauge)
+	prometheus.MustRegister(WriteBufferEvictCounter)
+	prometheus.MustRegister(CharsetMismatchCounter)
+	prometheus.MustRegister(UserTrafficBytesCounter)
+	prometheus.MustRegister(UserCommandCounter)
+	prometheus.MustRegister(InsecureTransportRejectCounter)
+	prometheus.MustRegister(ConnectionTransportCounter)
+	prometheus.MustRegister(ConnectionLimitRejectCounter)
+	prometheus.MustRegister(IdleTransactionKillCounter)
+	prometheus.MustRegister(TLSHandshakeFailureCounter)
+	prometheus.MustRegister(ClientCertAuthCounter)
 	prometheus.MustRegister(PreparedStmtGauge)
 	prometheus.MustRegister(CriticalErrorCounter)
 	prometheus.MustRegister(DDLCounter)
@@ -93,6 +103,7 @@ func RegisterMetrics() {
 	prometheus.MustRegister(ExecutorCounter)
 	prometheus.MustRegister(GetTokenDurationHistogram)
 	prometheus.MustRegister(HandShakeErrorCounter)
+	prometheus.MustRegister(HandshakeFailureCounter)
 	prometheus.MustRegister(HandleJobHistogram)
 	prometheus.MustRegister(SignificantFeedbackCounter)
 	prometheus.MustRegister(FastAnalyzeHistogram)
@@ -107,10 +118,16 @@ func RegisterMetrics() {
 	prometheus.MustRegister(OwnerHandleSyncerHistogram)
 	prometheus.MustRegister(PanicCounter)
 	prometheus.MustRegister(PlanCacheCounter)
+	prometheus.MustRegister(PlanCacheMissCounter)
+	prometheus.MustRegister(PlanCacheEvictionCounter)
+	prometheus.MustRegister(PlanCacheMemoryUsage)
 	prometheus.MustRegister(PseudoEstimation)
 	prometheus.MustRegister(PacketIOHistogram)
 	prometheus.MustRegister(QueryDurationHistogram)
 	prometheus.MustRegister(QueryTotalCounter)
+	prometheus.MustRegister(QueryCacheCounter)
+	prometheus.MustRegister(QueryCacheMemoryUsage)
+	prometheus.MustRegister(AuditEventDroppedCounter)
 	prometheus.MustRegister(SchemaLeaseErrorCounter)
 	prometheus.MustRegister(ServerEventCounter)
 	prometheus.MustRegister(SessionExecuteCompileDuration)
@@ -157,7 +174,11 @@ func RegisterMetrics() {
 	prometheus.MustRegister(TopSQLIgnoredCounter)
 	prometheus.MustRegister(TopSQLReportDurationHistogram)
 	prometheus.MustRegister(TopSQLReportDataHistogram)
+	prometheus.MustRegister(TopSQLReceiverReportCounter)
 	prometheus.MustRegister(PDApiExecutionHistogram)
+	prometheus.MustRegister(StatusSQLRejectCounter)
+	prometheus.MustRegister(StatusAuthFailureCounter)
+	prometheus.MustRegister(StatusHostRejectCounter)
 
 	tikvmetrics.InitMetrics(TiDB, TiKVClient)
 	tikvmetrics.RegisterMetrics()
@@ -53,12 +53,92 @@ var (
 			Help:      "Counter of queries.",
 		}, []string{LblType, LblResult})
 
-	ConnGauge = prometheus.NewGauge(
+	WriteBufferMemoryGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: "tidb",
 			Subsystem: "server",
-			Name:      "connections",
-			Help:      "Number of connections.",
+			Name:      "write_buffer_bytes",
+			Help:      "Bytes held by the write buffers and the spooled cursor rows of all the connections.",
+		})
+
+	WriteBufferEvictCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "write_buffer_evict_total",
+			Help:      "Counter of connections forced to release their write buffers.",
+		}, []string{LblType})
+
+	UserTrafficBytesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "user_traffic_bytes_total",
+			Help:      "Counter of the packet bytes read from and written to the connections of each account.",
+		}, []string{LblUser, LblType})
+
+	UserCommandCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "user_command_total",
+			Help:      "Counter of the commands of each account by the command type.",
+		}, []string{LblUser, LblType})
+
+	CharsetMismatchCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "charset_mismatch_total",
+			Help:      "Counter of statements which are valid utf8 but invalid in the declared character_set_client.",
+		}, []string{LblType})
+
+	InsecureTransportRejectCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "insecure_transport_reject_total",
+			Help:      "Counter of plaintext connections rejected because require_secure_transport is ON.",
+		})
+
+	IdleTransactionKillCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "idle_transaction_kill_total",
+			Help:      "Counter of connections killed because the transaction is idle for longer than tidb_idle_transaction_timeout.",
+		})
+
+	ConnectionLimitRejectCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "connection_limit_reject_total",
+			Help:      "Counter of connections rejected because of the connection limits, by the reason and the network of the client IP.",
+		}, []string{LblReason, LblIPPrefix})
+
+	ConnectionTransportCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "connection_transport_total",
+			Help:      "Counter of established connections by the transport, which is tcp, tls or socket.",
+		}, []string{LblType})
+
+	TLSHandshakeFailureCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "tls_handshake_failure_total",
+			Help:      "Counter of failed TLS handshakes.",
+		})
+
+	ClientCertAuthCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "client_cert_auth_total",
+			Help:      "Counter of sessions established with a verified client certificate.",
 		})
 
 	DisconnectionCounter = prometheus.NewCounterVec(
@@ -94,6 +174,9 @@ var (
 
 	EventStart        = "start"
 	EventGracefulDown = "graceful_shutdown"
+	EventDrain        = "drain"
+	EventPause        = "pause"
+	EventResume       = "resume"
 	// Eventkill occurs when the server.Kill() function is called.
 	EventKill          = "kill"
 	EventClose         = "close"
@@ -129,6 +212,54 @@ var (
 			Help:      "Counter of query using plan cache.",
 		}, []string{LblType})
 
+	PlanCacheMissCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_miss_total",
+			Help:      "Counter of query which can use plan cache but does not find the plan in it.",
+		}, []string{LblType})
+
+	PlanCacheEvictionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_eviction_total",
+			Help:      "Counter of plans evicted from plan cache.",
+		}, []string{LblType})
+
+	PlanCacheMemoryUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_memory_usage",
+			Help:      "Estimated memory usage of plan cache in bytes.",
+		}, []string{LblType})
+
+	QueryCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "query_cache_total",
+			Help:      "Counter of the cacheable queries looked up in the query result cache and the evicted results.",
+		}, []string{LblType})
+
+	QueryCacheMemoryUsage = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "query_cache_memory_usage",
+			Help:      "Estimated memory usage of the query result cache in bytes.",
+		})
+
+	AuditEventDroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "audit_event_dropped_total",
+			Help:      "Counter of the audit events dropped because the queue of the audit hook is full.",
+		}, []string{LblType})
+
 	HandShakeErrorCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "tidb",
@@ -138,6 +269,14 @@ var (
 		},
 	)
 
+	HandshakeFailureCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "handshake_failure_total",
+			Help:      "Counter of the failed handshakes by the reason, which is tcp-accept, tls, auth-plugin-mismatch, bad-password, timeout, proxy-protocol-error or other.",
+		}, []string{LblType})
+
 	GetTokenDurationHistogram = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "tidb",
@@ -238,6 +377,30 @@ var (
 			Help:      "Bucketed histogram of all pd api execution time (s)",
 			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 20), // 1ms ~ 524s
 		}, []string{LblType})
+
+	StatusSQLRejectCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "status_sql_rejected_total",
+			Help:      "Counter of internal SQL of the status server rejected because the session pool is busy or the circuit breaker is open.",
+		}, []string{LblType})
+
+	StatusAuthFailureCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "status_auth_failure_total",
+			Help:      "Counter of requests to the status server rejected because of the invalid auth-token.",
+		})
+
+	StatusHostRejectCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "status_host_rejected_total",
+			Help:      "Counter of requests to the status server rejected because the client is not in allowed-hosts.",
+		})
 )
 
 // ExecuteErrorToLabel converts an execute error to label.
@@ -135,6 +135,7 @@ const (
 	LblReachMax    = "reach_max"
 	LblOK          = "ok"
 	LblError       = "error"
+	LblTimeout     = "timeout"
 	LblCommit      = "commit"
 	LblAbort       = "abort"
 	LblRollback    = "rollback"
@@ -156,4 +157,7 @@ const (
 	LblVersion     = "version"
 	LblHash        = "hash"
 	LblCTEType     = "cte_type"
+	LblUser        = "user"
+	LblReason      = "reason"
+	LblIPPrefix    = "ip_prefix"
 )
@@ -43,4 +43,12 @@ var (
 			Help:      "Bucket histogram of reporting records/sql/plan count to the top-sql agent.",
 			Buckets:   prometheus.ExponentialBuckets(1, 2, 20), // 1 ~ 524288
 		}, []string{LblType})
+
+	TopSQLReceiverReportCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "topsql",
+			Name:      "receiver_report_total",
+			Help:      "Counter of the reports sent, failed to send and dropped for each top-sql receiver.",
+		}, []string{LblAddress, LblResult})
 )
@@ -2605,6 +2605,7 @@ const (
 	ShowPlacementForTable
 	ShowPlacementForPartition
 	ShowPlacementLabels
+	ShowEngineStatus
 )
 
 const (
@@ -2637,6 +2638,7 @@ type ShowStmt struct {
 	Roles       []*auth.RoleIdentity // Used for show grants .. using
 	IfNotExists bool                 // Used for `show create database if not exists`
 	Extended    bool                 // Used for `show extended columns from ...`
+	Engine      string               // Used for `show engine ... status`
 
 	// GlobalScope is used by `show variables` and `show bindings`
 	GlobalScope bool
@@ -2646,6 +2648,8 @@ type ShowStmt struct {
 	ShowProfileTypes []int  // Used for `SHOW PROFILE` syntax
 	ShowProfileArgs  *int64 // Used for `SHOW PROFILE` syntax
 	ShowProfileLimit *Limit // Used for `SHOW PROFILE` syntax
+
+	Limit *Limit // Used for `SHOW WARNINGS` and `SHOW ERRORS`
 }
 
 // Restore implements Node interface.
@@ -2736,6 +2740,10 @@ func (n *ShowStmt) Restore(ctx *format.RestoreCtx) error {
 		}
 	case ShowMasterStatus:
 		ctx.WriteKeyWord("MASTER STATUS")
+	case ShowEngineStatus:
+		ctx.WriteKeyWord("ENGINE ")
+		ctx.WriteName(n.Engine)
+		ctx.WriteKeyWord(" STATUS")
 	case ShowProcessList:
 		restoreOptFull()
 		ctx.WriteKeyWord("PROCESSLIST")
@@ -2886,10 +2894,18 @@ func (n *ShowStmt) Restore(ctx *format.RestoreCtx) error {
 				}
 			}
 			restoreShowDatabaseNameOpt()
-		case ShowWarnings:
-			ctx.WriteKeyWord("WARNINGS")
-		case ShowErrors:
-			ctx.WriteKeyWord("ERRORS")
+		case ShowWarnings, ShowErrors:
+			if n.Tp == ShowWarnings {
+				ctx.WriteKeyWord("WARNINGS")
+			} else {
+				ctx.WriteKeyWord("ERRORS")
+			}
+			if n.Limit != nil {
+				ctx.WritePlain(" ")
+				if err := n.Limit.Restore(ctx); err != nil {
+					return errors.Annotate(err, "An error occurred while restore ShowStmt.Limit")
+				}
+			}
 		case ShowVariables:
 			restoreGlobalScope()
 			ctx.WriteKeyWord("VARIABLES")
@@ -2996,6 +3012,13 @@ func (n *ShowStmt) Accept(v Visitor) (Node, bool) {
 		}
 		n.Where = node.(ExprNode)
 	}
+	if n.Limit != nil {
+		node, ok := n.Limit.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Limit = node.(*Limit)
+	}
 	return v.Leave(n)
 }
 
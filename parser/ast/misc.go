@@ -51,6 +51,7 @@ var (
 	_ StmtNode = &CreateBindingStmt{}
 	_ StmtNode = &DropBindingStmt{}
 	_ StmtNode = &ShutdownStmt{}
+	_ StmtNode = &ResetMasterStmt{}
 	_ StmtNode = &RestartStmt{}
 	_ StmtNode = &RenameUserStmt{}
 	_ StmtNode = &HelpStmt{}
@@ -856,6 +857,9 @@ type KillStmt struct {
 	// So, "KILL TIDB" grammar is introduced, and it REQUIRES DIRECT client -> TiDB TOPOLOGY.
 	// TODO: The standard KILL grammar will be supported once we have global connectionID.
 	TiDBExtension bool
+	// WaitSeconds is set by "KILL [TIDB] QUERY connectionID WAIT seconds". The query is asked to stop
+	// with a partial result first, and is terminated only if it is still running after WaitSeconds.
+	WaitSeconds uint64
 }
 
 // Restore implements Node interface.
@@ -868,6 +872,10 @@ func (n *KillStmt) Restore(ctx *format.RestoreCtx) error {
 		ctx.WriteKeyWord(" QUERY")
 	}
 	ctx.WritePlainf(" %d", n.ConnectionID)
+	if n.WaitSeconds > 0 {
+		ctx.WriteKeyWord(" WAIT")
+		ctx.WritePlainf(" %d", n.WaitSeconds)
+	}
 	return nil
 }
 
@@ -1200,7 +1208,7 @@ func (n *UserSpec) EncodedPassword() (string, bool) {
 	opt := n.AuthOpt
 	if opt.ByAuthString {
 		switch opt.AuthPlugin {
-		case mysql.AuthCachingSha2Password:
+		case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
 			return auth.NewSha2Password(opt.AuthString), true
 		case mysql.AuthSocket:
 			return "", true
@@ -1216,7 +1224,7 @@ func (n *UserSpec) EncodedPassword() (string, bool) {
 
 	// Not a legal password string.
 	switch opt.AuthPlugin {
-	case mysql.AuthCachingSha2Password:
+	case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
 		if len(opt.HashString) != mysql.SHAPWDHashLen {
 			return "", false
 		}
@@ -1308,6 +1316,9 @@ const (
 	PasswordExpireInterval
 	Lock
 	Unlock
+	FailedLoginAttempts
+	PasswordLockTime
+	PasswordLockTimeUnbounded
 )
 
 type PasswordOrLockOption struct {
@@ -1331,6 +1342,14 @@ func (p *PasswordOrLockOption) Restore(ctx *format.RestoreCtx) error {
 		ctx.WriteKeyWord("ACCOUNT LOCK")
 	case Unlock:
 		ctx.WriteKeyWord("ACCOUNT UNLOCK")
+	case FailedLoginAttempts:
+		ctx.WriteKeyWord("FAILED_LOGIN_ATTEMPTS")
+		ctx.WritePlainf(" %d", p.Count)
+	case PasswordLockTime:
+		ctx.WriteKeyWord("PASSWORD_LOCK_TIME")
+		ctx.WritePlainf(" %d", p.Count)
+	case PasswordLockTimeUnbounded:
+		ctx.WriteKeyWord("PASSWORD_LOCK_TIME UNBOUNDED")
 	default:
 		return errors.Errorf("Unsupported PasswordOrLockOption.Type %d", p.Type)
 	}
@@ -1856,6 +1875,7 @@ const (
 	AdminShowTelemetry
 	AdminResetTelemetryID
 	AdminReloadStatistics
+	AdminReloadSystemTZ
 )
 
 // HandleRange represents a range where handle value >= Begin and < End.
@@ -1933,6 +1953,8 @@ type AdminStmt struct {
 	ShowSlow     *ShowSlow
 	Plugins      []string
 	Where        ExprNode
+	// Force is set by ADMIN RELOAD SYSTEM_TZ FORCE, which confirms to overwrite the stored system time zone.
+	Force bool
 }
 
 // Restore implements Node interface.
@@ -2070,6 +2092,11 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 		ctx.WriteKeyWord("RESET TELEMETRY_ID")
 	case AdminReloadStatistics:
 		ctx.WriteKeyWord("RELOAD STATS_EXTENDED")
+	case AdminReloadSystemTZ:
+		ctx.WriteKeyWord("RELOAD SYSTEM_TZ")
+		if n.Force {
+			ctx.WriteKeyWord(" FORCE")
+		}
 	default:
 		return errors.New("Unsupported AdminStmt type")
 	}
@@ -2569,6 +2596,28 @@ func (n *ShutdownStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// ResetMasterStmt is a statement to reset the binary logs of the server.
+// See https://dev.mysql.com/doc/refman/5.7/en/reset-master.html
+type ResetMasterStmt struct {
+	stmtNode
+}
+
+// Restore implements Node interface.
+func (n *ResetMasterStmt) Restore(ctx *format.RestoreCtx) error {
+	ctx.WriteKeyWord("RESET MASTER")
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *ResetMasterStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ResetMasterStmt)
+	return v.Leave(n)
+}
+
 // RestartStmt is a statement to restart the TiDB server.
 // See https://dev.mysql.com/doc/refman/8.0/en/restart.html
 type RestartStmt struct {
@@ -77,6 +77,7 @@ func TestMiscVisitorCover(t *testing.T) {
 		&ast.KillStmt{},
 		&ast.DropStatsStmt{Table: &ast.TableName{}},
 		&ast.ShutdownStmt{},
+		&ast.ResetMasterStmt{},
 	}
 
 	for _, v := range stmts {
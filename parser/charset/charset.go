@@ -27,6 +27,7 @@ import (
 var (
 	ErrUnknownCollation         = terror.ClassDDL.NewStd(mysql.ErrUnknownCollation)
 	ErrCollationCharsetMismatch = terror.ClassDDL.NewStd(mysql.ErrCollationCharsetMismatch)
+	ErrUnknownCharacterSet      = terror.ClassParser.NewStd(mysql.ErrUnknownCharacterSet)
 )
 
 // Charset is a charset.
@@ -214,6 +215,8 @@ const (
 
 	CollationGBKBin = "gbk_bin"
 
+	CollationGB18030Bin = "gb18030_bin"
+
 	CharsetARMSCII8 = "armscii8"
 	CharsetBig5     = "big5"
 	CharsetBinary   = "binary"
@@ -473,6 +476,8 @@ var collations = []*Collation{
 	{245, "utf8mb4", "utf8mb4_croatian_ci", false},
 	{246, "utf8mb4", "utf8mb4_unicode_520_ci", false},
 	{247, "utf8mb4", "utf8mb4_vietnamese_ci", false},
+	{248, "gb18030", "gb18030_chinese_ci", true},
+	{249, "gb18030", "gb18030_bin", false},
 	{255, "utf8mb4", "utf8mb4_0900_ai_ci", false},
 	{2048, "utf8mb4", "utf8mb4_zh_pinyin_tidb_as_cs", false},
 }
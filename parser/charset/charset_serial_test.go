@@ -14,7 +14,12 @@
 package charset
 
 import (
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 func TestValidCustomCharset(t *testing.T) {
@@ -34,3 +39,103 @@ func TestValidCustomCharset(t *testing.T) {
 		testValidCharset(t, tt.cs, tt.co, tt.succ)
 	}
 }
+
+func TestRegisterEncodingAlias(t *testing.T) {
+	e, _ := Lookup("cp936")
+	require.Nil(t, e)
+	require.NoError(t, RegisterEncodingAlias("CP936", "gbk"))
+	e, name := Lookup("cp936")
+	require.Equal(t, simplifiedchinese.GBK, e)
+	require.Equal(t, CharsetGBK, name)
+	// The alias is accepted by NewEncoding as gbk is.
+	require.Equal(t, GBKEncoding, NewEncoding("cp936"))
+	require.True(t, IsClientEncoding("cp936"))
+	// Registering the same alias again is fine.
+	require.NoError(t, RegisterEncodingAlias("cp936", "x-gbk"))
+
+	require.NoError(t, RegisterEncodingAlias("ansi", "windows-1252"))
+	e, name = Lookup("ansi")
+	require.Equal(t, charmap.Windows1252, e)
+	require.Equal(t, "windows-1252", name)
+	require.Equal(t, UTF8Encoding, NewEncoding("ansi"))
+	require.False(t, IsSupportedEncoding("ansi"))
+
+	// The existing labels are not overridden.
+	require.EqualError(t, RegisterEncodingAlias("cp936", "utf8"), "encoding label cp936 is already registered for gbk")
+	require.EqualError(t, RegisterEncodingAlias("utf8", "gbk"), "encoding label utf8 is already registered for utf-8")
+	require.EqualError(t, RegisterEncodingAlias("cp1252", "unknown"), "unknown encoding unknown")
+	e, _ = Lookup("cp1252")
+	require.NotNil(t, e)
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	validator := StringValidatorASCII{}
+	require.NoError(t, RegisterEncoding("Legacy1252", charmap.Windows1252, validator))
+	require.NoError(t, RegisterEncodingAlias("legacy", "legacy1252"))
+	for _, label := range []string{"legacy1252", "LEGACY"} {
+		e, name := Lookup(label)
+		require.Equal(t, charmap.Windows1252, e)
+		require.Equal(t, "legacy1252", name)
+		enc := NewEncoding(label)
+		require.Equal(t, "legacy1252", enc.Name())
+		require.Equal(t, 1, enc.MaxCharWidth())
+		require.False(t, enc.IsVariableWidth())
+		encoded, err := enc.EncodeString("é€")
+		require.NoError(t, err)
+		require.Equal(t, "\xe9\x80", encoded)
+		decoded, err := enc.DecodeString("\xe9\x80")
+		require.NoError(t, err)
+		require.Equal(t, "é€", decoded)
+	}
+	require.Equal(t, validator, RegisteredStringValidator("legacy1252"))
+	require.Nil(t, RegisteredStringValidator(CharsetGBK))
+
+	// The length of the characters of the multi-byte encodings is found by decoding them.
+	require.NoError(t, RegisterEncoding("legacy-gbk", simplifiedchinese.GBK, nil))
+	enc := NewEncoding("legacy-gbk")
+	require.Equal(t, 4, enc.MaxCharWidth())
+	require.True(t, enc.IsVariableWidth())
+	for _, tc := range []struct {
+		bs     string
+		length int
+	}{{"a", 1}, {"\xd6\xd0", 2}, {"\xd6\xd0a", 2}, {"\xd6", 1}, {"\xff\xff", 1}} {
+		require.Equal(t, tc.length, enc.CharLength([]byte(tc.bs)), tc.bs)
+	}
+	require.Nil(t, RegisteredStringValidator("legacy-gbk"))
+
+	// The existing encodings are not overridden.
+	require.EqualError(t, RegisterEncoding("gbk", charmap.Windows1252, nil), "encoding label gbk is already registered for gbk")
+	require.EqualError(t, RegisterEncoding("legacy", charmap.Windows1252, nil), "encoding label legacy is already registered for legacy1252")
+	require.Error(t, RegisterEncoding("", charmap.Windows1252, nil))
+
+	// The connections can use the encoding after it is marked as connectable.
+	require.False(t, IsClientEncoding("legacy1252"))
+	require.Error(t, SetClientConnectable("legacy1252"))
+	AddCharset(&Charset{"legacy1252", "legacy1252_bin", make(map[string]*Collation), "Legacy", 1})
+	defer RemoveCharset("legacy1252")
+	require.EqualError(t, SetClientConnectable("unknown"), "Unknown charset unknown")
+	require.NoError(t, SetClientConnectable("legacy1252"))
+	require.True(t, IsClientEncoding("legacy1252"))
+	require.True(t, IsClientEncoding("legacy"))
+}
+
+func TestRegisterEncodingConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	labels := []string{"concurrent-a", "concurrent-b", "concurrent-c", "concurrent-d"}
+	for _, label := range labels {
+		wg.Add(2)
+		go func(label string) {
+			defer wg.Done()
+			require.NoError(t, RegisterEncodingAlias(label, "gbk"))
+		}(label)
+		go func(label string) {
+			defer wg.Done()
+			NewEncoding(label)
+			Lookup(label)
+		}(label)
+	}
+	wg.Wait()
+	for _, label := range labels {
+		require.Equal(t, GBKEncoding, NewEncoding(label))
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -28,7 +29,8 @@ import (
 	"golang.org/x/text/transform"
 )
 
-var errInvalidCharacterString = terror.ClassParser.NewStd(mysql.ErrInvalidCharacterString)
+// ErrInvalidCharacterString is returned when a string is not valid in its charset.
+var ErrInvalidCharacterString = terror.ClassParser.NewStd(mysql.ErrInvalidCharacterString)
 
 type EncodingLabel string
 
@@ -48,6 +50,10 @@ type Encoding struct {
 	name        string
 	charLength  func([]byte) int
 	specialCase unicode.SpecialCase
+	// maxCharWidth is the max length of a character in bytes.
+	maxCharWidth int
+	// table converts the characters by lookups before falling back to the transformers of enc if it is not nil.
+	table *doubleByteTable
 }
 
 // enabled indicates whether the non-utf8 encoding is used.
@@ -65,12 +71,41 @@ func (e *Encoding) CharLength(bs []byte) int {
 	return e.charLength(bs)
 }
 
+// MaxCharWidth returns the max length of a character in bytes.
+func (e *Encoding) MaxCharWidth() int {
+	return e.maxCharWidth
+}
+
+// Collations returns the names of the collations of the encoding in order, including the ones added by
+// AddCollation. UTF8Encoding is shared by utf8 and utf8mb4, it returns the utf8mb4 collations.
+func (e *Encoding) Collations() []string {
+	names := make([]string, 0, 2)
+	for name, c := range collationsNameMap {
+		if c.CharsetName == e.name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsVariableWidth returns whether the characters are encoded in different numbers of bytes.
+// If it returns false, the number of characters of an encoded string equals its length in bytes.
+func (e *Encoding) IsVariableWidth() bool {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+	variable, ok := variableWidthEncodings[e.name]
+	return !ok || variable
+}
+
 // NewEncoding creates a new Encoding.
 func NewEncoding(label string) *Encoding {
 	if len(label) == 0 {
 		return UTF8Encoding
 	}
 
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
 	if e, exist := encodingMap[Format(label)]; exist {
 		return e
 	}
@@ -82,7 +117,7 @@ func (e *Encoding) Encode(dest, src []byte) ([]byte, error) {
 	if !e.enabled() {
 		return src, nil
 	}
-	return e.transform(e.enc.NewEncoder(), dest, src, false)
+	return e.transform(dest, src, false)
 }
 
 // EncodeString convert a string from utf-8 charset to a specific charset.
@@ -90,7 +125,7 @@ func (e *Encoding) EncodeString(src string) (string, error) {
 	if !e.enabled() {
 		return src, nil
 	}
-	bs, err := e.transform(e.enc.NewEncoder(), nil, Slice(src), false)
+	bs, err := e.transform(nil, Slice(src), false)
 	return string(bs), err
 }
 
@@ -101,7 +136,7 @@ func (e *Encoding) EncodeFirstChar(dest, src []byte) ([]byte, error) {
 	if !e.enabled() {
 		return src[:srcEnd], nil
 	}
-	return e.transform(e.enc.NewEncoder(), dest, src[:srcEnd], false)
+	return e.transform(dest, src[:srcEnd], false)
 }
 
 // EncodeInternal convert bytes from utf-8 charset to a specific charset, we actually do not do the real convert, just find the inconvertible character and use ? replace.
@@ -119,9 +154,17 @@ func (e *Encoding) EncodeInternal(dest, src []byte) []byte {
 	var srcOffset int
 
 	var buf [4]byte
-	transformer := e.enc.NewEncoder()
+	var transformer transform.Transformer
 	for srcOffset < len(src) {
-		length := UTF8Encoding.CharLength(src[srcOffset:])
+		length := mathutil.Min(UTF8Encoding.CharLength(src[srcOffset:]), len(src)-srcOffset)
+		if e.table != nil && e.table.encodableLen(src[srcOffset:srcOffset+length]) == length {
+			dest = append(dest, src[srcOffset:srcOffset+length]...)
+			srcOffset += length
+			continue
+		}
+		if transformer == nil {
+			transformer = e.enc.NewEncoder()
+		}
 		_, _, err := transformer.Transform(buf[:], src[srcOffset:srcOffset+length], true)
 		if err != nil {
 			dest = append(dest, byte('?'))
@@ -139,7 +182,7 @@ func (e *Encoding) Decode(dest, src []byte) ([]byte, error) {
 	if !e.enabled() {
 		return src, nil
 	}
-	return e.transform(e.enc.NewDecoder(), dest, src, true)
+	return e.transform(dest, src, true)
 }
 
 // DecodeString convert a string from a specific charset to utf-8 charset.
@@ -147,11 +190,13 @@ func (e *Encoding) DecodeString(src string) (string, error) {
 	if !e.enabled() {
 		return src, nil
 	}
-	bs, err := e.transform(e.enc.NewDecoder(), nil, Slice(src), true)
+	bs, err := e.transform(nil, Slice(src), true)
 	return string(bs), err
 }
 
-func (e *Encoding) transform(transformer transform.Transformer, dest, src []byte, isDecoding bool) ([]byte, error) {
+// transform converts src by the table of e, the characters not in it are converted by the transformer of enc,
+// which is only created if there is such a character.
+func (e *Encoding) transform(dest, src []byte, isDecoding bool) ([]byte, error) {
 	if len(dest) < len(src) {
 		dest = make([]byte, len(src)*2)
 	}
@@ -160,9 +205,22 @@ func (e *Encoding) transform(transformer transform.Transformer, dest, src []byte
 	}
 	var destOffset, srcOffset int
 	var encodingErr error
+	var transformer transform.Transformer
 	for {
+		if e.table != nil {
+			// The characters in the table are converted in batches, the next one is left to the transformer.
+			nDest, nSrc := e.table.convertPrefix(dest[destOffset:], src[srcOffset:], isDecoding)
+			destOffset += nDest
+			srcOffset += nSrc
+			if srcOffset >= len(src) {
+				return dest[:destOffset], encodingErr
+			}
+		}
 		srcNextLen := e.nextCharLenInSrc(src[srcOffset:], isDecoding)
 		srcEnd := mathutil.Min(srcOffset+srcNextLen, len(src))
+		if transformer == nil {
+			transformer = e.newTransformer(isDecoding)
+		}
 		nDest, nSrc, err := transformer.Transform(dest[destOffset:], src[srcOffset:srcEnd], false)
 		if err == transform.ErrShortDst {
 			dest = enlargeCapacity(dest)
@@ -182,6 +240,13 @@ func (e *Encoding) transform(transformer transform.Transformer, dest, src []byte
 	}
 }
 
+func (e *Encoding) newTransformer(isDecoding bool) transform.Transformer {
+	if isDecoding {
+		return e.enc.NewDecoder()
+	}
+	return e.enc.NewEncoder()
+}
+
 func (e *Encoding) nextCharLenInSrc(srcRest []byte, isDecoding bool) int {
 	if isDecoding {
 		if e.charLength != nil {
@@ -201,7 +266,7 @@ func enlargeCapacity(dest []byte) []byte {
 func (e *Encoding) generateErr(srcRest []byte, srcNextLen int) error {
 	cutEnd := mathutil.Min(srcNextLen, len(srcRest))
 	invalidBytes := fmt.Sprintf("%X", string(srcRest[:cutEnd]))
-	return errInvalidCharacterString.GenWithStackByArgs(e.name, invalidBytes)
+	return ErrInvalidCharacterString.GenWithStackByArgs(e.name, invalidBytes)
 }
 
 // replacementBytes are bytes for the replacement rune 0xfffd.
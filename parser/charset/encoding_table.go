@@ -14,11 +14,15 @@
 package charset
 
 import (
+	"encoding/binary"
+	"fmt"
 	"strings"
+	"sync"
 	go_unicode "unicode"
 	"unicode/utf8"
 
 	"github.com/cznic/mathutil"
+	"github.com/pingcap/errors"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/japanese"
@@ -26,27 +30,201 @@ import (
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/traditionalchinese"
 	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
 )
 
 var encodingMap = map[EncodingLabel]*Encoding{
 	CharsetUTF8MB4: UTF8Encoding,
 	CharsetUTF8:    UTF8Encoding,
 	CharsetGBK:     GBKEncoding,
+	CharsetGB18030: GB18030Encoding,
 	CharsetLatin1:  LatinEncoding,
 	CharsetBin:     BinaryEncoding,
 	CharsetASCII:   ASCIIEncoding,
 }
 
+// variableWidthEncodings records whether the characters of an encoding are encoded
+// in different numbers of bytes. It is keyed by the name of the encoding.
+var variableWidthEncodings = map[string]bool{
+	CharsetUTF8MB4: true,
+	CharsetGBK:     true,
+	CharsetGB18030: true,
+	CharsetLatin1:  false,
+	CharsetBin:     false,
+	CharsetASCII:   false,
+}
+
+// encodingsLock protects encodings, encodingMap, variableWidthEncodings, nonClientEncodings and
+// registeredValidators, which can be changed by the registrations at runtime.
+var encodingsLock sync.RWMutex
+
+// nonClientEncodings records the names of the encodings which can not be used by the connections,
+// the registered encodings are in it until SetClientConnectable is called.
+var nonClientEncodings = map[string]struct{}{}
+
+// registeredValidators are the validators of the registered encodings, keyed by the name of the encoding.
+var registeredValidators = map[string]StringValidator{}
+
+// IsSupportedEncoding checks whether the charset label is in the encoding table.
+// Matching is case-insensitive and ignores leading and trailing whitespace.
+func IsSupportedEncoding(label string) bool {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+	_, ok := encodingMap[Format(label)]
+	return ok
+}
+
+// IsClientEncoding checks whether the charset label is in the encoding table and can be used as
+// the charset of the connections, such as by SET NAMES.
+func IsClientEncoding(label string) bool {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+	e, ok := encodingMap[Format(label)]
+	if !ok {
+		return false
+	}
+	_, nonClient := nonClientEncodings[e.name]
+	return !nonClient
+}
+
+// RegisterEncodingAlias registers label as another label of the encoding of canonical, so that Lookup
+// returns the encoding for it. It is also accepted by NewEncoding if canonical is. It returns an error if
+// canonical is unknown, or label is already a label of another encoding.
+func RegisterEncodingAlias(label, canonical string) error {
+	label, canonical = string(Format(label)), string(Format(canonical))
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+	target, ok := encodings[canonical]
+	if !ok {
+		return errors.Errorf("unknown encoding %s", canonical)
+	}
+	if old, ok := encodings[label]; ok {
+		if old.name != target.name {
+			return errors.Errorf("encoding label %s is already registered for %s", label, old.name)
+		}
+		return nil
+	}
+	e, isEncoding := encodingMap[EncodingLabel(canonical)]
+	if old, ok := encodingMap[EncodingLabel(label)]; ok && (!isEncoding || old != e) {
+		return errors.Errorf("encoding label %s is already registered for %s", label, old.name)
+	}
+	encodings[label] = target
+	if isEncoding {
+		encodingMap[EncodingLabel(label)] = e
+	}
+	return nil
+}
+
+// RegisterEncoding registers a new encoding, which is returned by Lookup and NewEncoding for name. The
+// strings are checked by validator if it is not nil, see RegisteredStringValidator. The encoding can not
+// be used by the connections until SetClientConnectable is called. It returns an error if name is already
+// a label of an encoding.
+func RegisterEncoding(name string, e encoding.Encoding, validator StringValidator) error {
+	name = string(Format(name))
+	if name == "" || e == nil {
+		return errors.Errorf("invalid encoding %q", name)
+	}
+	enc := &Encoding{
+		enc:          e,
+		name:         name,
+		charLength:   charLengthByDecoding(e),
+		maxCharWidth: 4,
+	}
+	if _, singleByte := e.(*charmap.Charmap); singleByte {
+		enc.charLength, enc.maxCharWidth = func([]byte) int { return 1 }, 1
+	}
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+	if old, ok := encodings[name]; ok {
+		return errors.Errorf("encoding label %s is already registered for %s", name, old.name)
+	}
+	if old, ok := encodingMap[EncodingLabel(name)]; ok {
+		return errors.Errorf("encoding label %s is already registered for %s", name, old.name)
+	}
+	encodings[name] = struct {
+		e    encoding.Encoding
+		name string
+	}{e, name}
+	encodingMap[EncodingLabel(name)] = enc
+	variableWidthEncodings[name] = enc.maxCharWidth > 1
+	nonClientEncodings[name] = struct{}{}
+	if validator != nil {
+		registeredValidators[name] = validator
+	}
+	return nil
+}
+
+// SetClientConnectable allows the connections to use the encoding registered by RegisterEncoding, the charset
+// and its collations must be added by AddCharset and AddCollation before, so that SET NAMES can find them.
+func SetClientConnectable(name string) error {
+	name = string(Format(name))
+	if _, err := GetCharsetInfo(name); err != nil {
+		return err
+	}
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+	if e, ok := encodingMap[EncodingLabel(name)]; !ok || e.name != name {
+		return errors.Errorf("unknown encoding %s", name)
+	}
+	delete(nonClientEncodings, name)
+	return nil
+}
+
+// RegisteredStringValidator returns the validator registered with the encoding by RegisterEncoding,
+// it returns nil if there is none.
+func RegisteredStringValidator(name string) StringValidator {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+	return registeredValidators[string(Format(name))]
+}
+
+// charLengthByDecoding returns the function which finds the length of the next character by decoding it,
+// it is used by the registered encodings. The length is 1 if the bytes are not a valid character.
+func charLengthByDecoding(e encoding.Encoding) func([]byte) int {
+	return func(bs []byte) int {
+		decoder := e.NewDecoder()
+		var buf [utf8.UTFMax]byte
+		for n := 1; n <= len(bs) && n <= utf8.UTFMax; n++ {
+			decoder.Reset()
+			nDst, _, err := decoder.Transform(buf[:], bs[:n], true)
+			if err != nil {
+				continue
+			}
+			if r, w := utf8.DecodeRune(buf[:nDst]); w == nDst && r != utf8.RuneError {
+				return n
+			}
+		}
+		return 1
+	}
+}
+
 // Lookup returns the encoding with the specified label, and its canonical
 // name. It returns nil and the empty string if label is not one of the
 // standard encodings for HTML. Matching is case-insensitive and ignores
-// leading and trailing whitespace.
+// leading and trailing whitespace. The encoding modifiers are stripped as
+// LookupWithModifier does.
 func Lookup(label string) (e encoding.Encoding, name string) {
+	e, name, _ = LookupWithModifier(label)
+	return e, name
+}
+
+// LookupWithModifier is Lookup which also reports whether the label has an
+// encoding modifier of the libiconv convention, such as "utf-8//IGNORE" and
+// "gbk//TRANSLIT". The modifier is stripped before the lookup.
+// The modifier is only a hint for the callers, it doesn't change the
+// TruncateStrategy, which is chosen by the callers from the SQL mode.
+func LookupWithModifier(label string) (e encoding.Encoding, name string, hasModifier bool) {
 	label = strings.ToLower(strings.Trim(label, "\t\n\r\f "))
-	return lookup(Formatted(label))
+	if idx := strings.Index(label, "//"); idx >= 0 {
+		label, hasModifier = strings.TrimRight(label[:idx], "\t\n\r\f "), true
+	}
+	e, name = lookup(Formatted(label))
+	return e, name, hasModifier
 }
 
 func lookup(label EncodingLabel) (e encoding.Encoding, name string) {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
 	enc := encodings[string(label)]
 	return enc.e, enc.name
 }
@@ -236,7 +414,7 @@ var encodings = map[string]struct {
 	"gbk":                 {simplifiedchinese.GBK, "gbk"},
 	"iso-ir-58":           {simplifiedchinese.GBK, "gbk"},
 	"x-gbk":               {simplifiedchinese.GBK, "gbk"},
-	"gb18030":             {simplifiedchinese.GB18030, "gb18030"},
+	"gb18030":             {gb18030{}, "gb18030"},
 	"hz-gb-2312":          {simplifiedchinese.HZGB2312, "hz-gb-2312"},
 	"big5":                {traditionalchinese.Big5, "big5"},
 	"big5-hkscs":          {traditionalchinese.Big5, "big5"},
@@ -278,13 +456,17 @@ var encodings = map[string]struct {
 // TruncateStrategy indicates the way to handle the invalid strings in specific charset.
 //   - TruncateStrategyEmpty: returns an empty string.
 //   - TruncateStrategyTrim: returns the valid prefix part of string.
-//   - TruncateStrategyReplace: returns the whole string, but the invalid characters are replaced with '?'.
+//   - TruncateStrategyReplace: returns the whole string, but the invalid characters are replaced with '?',
+//     or with the ReplacementChar of StringValidatorUTF8 if it is set.
+//   - TruncateStrategyError: returns the whole string unchanged and an ErrInvalidCharacterString error,
+//     which reports the charset and the hex of the first invalid character.
 type TruncateStrategy int8
 
 const (
 	TruncateStrategyEmpty TruncateStrategy = iota
 	TruncateStrategyTrim
 	TruncateStrategyReplace
+	TruncateStrategyError
 )
 
 var _ StringValidator = StringValidatorASCII{}
@@ -293,8 +475,53 @@ var _ StringValidator = StringValidatorOther{}
 
 // StringValidator is used to check if a string is valid in the specific charset.
 type StringValidator interface {
+	// Validate returns the byte offset of the first invalid character, or -1 if the string is valid.
 	Validate(str string) (invalidPos int)
-	Truncate(str string, strategy TruncateStrategy) (result string, invalidPos int)
+	// ValidateWithPosition is like Validate, it also returns the index of the first invalid character counted
+	// in characters, which is the position shown to the users. Both are -1 if the string is valid.
+	ValidateWithPosition(str string) (invalidPos int, charPos int)
+	// Truncate handles the invalid characters of the string with the strategy. The err is only returned by
+	// TruncateStrategyError.
+	Truncate(str string, strategy TruncateStrategy) (result string, invalidPos int, err error)
+}
+
+// invalidCharacterError returns the error of the invalid character in the charset, the bytes of the character
+// are shown in hex like MySQL does.
+func invalidCharacterError(chs string, invalidChar string) error {
+	return ErrInvalidCharacterString.GenWithStackByArgs(chs, fmt.Sprintf("%X", invalidChar))
+}
+
+// charIndex returns the index of the character at the byte offset pos of the UTF-8 string, the characters
+// before pos must be valid UTF-8. It returns -1 if pos is -1.
+func charIndex(str string, pos int) int {
+	if pos < 0 {
+		return -1
+	}
+	return utf8.RuneCountInString(str[:pos])
+}
+
+// asciiMask has the highest bit of each byte set, a word has non-ASCII bytes if it has any of the bits set.
+const asciiMask = 0x8080808080808080
+
+// indexNonASCII returns the position of the first non-ASCII byte in the string, or -1 if there is none.
+// It checks 16 bytes at a time, which is faster than checking byte by byte or strings.IndexFunc,
+// the latter decodes the runes one by one.
+func indexNonASCII(str string) int {
+	b := Slice(str)
+	i := 0
+	for ; i+16 <= len(b); i += 16 {
+		w1 := binary.LittleEndian.Uint64(b[i:])
+		w2 := binary.LittleEndian.Uint64(b[i+8:])
+		if (w1|w2)&asciiMask != 0 {
+			break
+		}
+	}
+	for ; i < len(b); i++ {
+		if b[i] > go_unicode.MaxASCII {
+			return i
+		}
+	}
+	return -1
 }
 
 // StringValidatorASCII checks whether a string is valid ASCII string.
@@ -302,28 +529,32 @@ type StringValidatorASCII struct{}
 
 // Validate checks whether the string is valid in the given charset.
 func (s StringValidatorASCII) Validate(str string) int {
-	_, invalidPos := s.Truncate(str, TruncateStrategyEmpty)
+	_, invalidPos, _ := s.Truncate(str, TruncateStrategyEmpty)
 	return invalidPos
 }
 
+// ValidateWithPosition implements the interface StringValidator.
+func (s StringValidatorASCII) ValidateWithPosition(str string) (invalidPos int, charPos int) {
+	// All the characters before the invalid one are single-byte.
+	invalidPos = s.Validate(str)
+	return invalidPos, invalidPos
+}
+
 // Truncate implement the interface StringValidator.
-func (s StringValidatorASCII) Truncate(str string, strategy TruncateStrategy) (string, int) {
-	invalidPos := -1
-	for i := 0; i < len(str); i++ {
-		if str[i] > go_unicode.MaxASCII {
-			invalidPos = i
-			break
-		}
-	}
+func (s StringValidatorASCII) Truncate(str string, strategy TruncateStrategy) (string, int, error) {
+	invalidPos := indexNonASCII(str)
 	if invalidPos == -1 {
 		// Quick check passed.
-		return str, -1
+		return str, -1, nil
 	}
 	switch strategy {
 	case TruncateStrategyEmpty:
-		return "", invalidPos
+		return "", invalidPos, nil
 	case TruncateStrategyTrim:
-		return str[:invalidPos], invalidPos
+		return str[:invalidPos], invalidPos, nil
+	case TruncateStrategyError:
+		w := mathutil.Min(UTF8Encoding.CharLength(Slice(str)[invalidPos:]), len(str)-invalidPos)
+		return str, invalidPos, invalidCharacterError(CharsetASCII, str[invalidPos:invalidPos+w])
 	case TruncateStrategyReplace:
 		result := make([]byte, 0, len(str))
 		for i, w := 0, 0; i < len(str); i += w {
@@ -336,63 +567,116 @@ func (s StringValidatorASCII) Truncate(str string, strategy TruncateStrategy) (s
 			}
 			result = append(result, str[i:i+w]...)
 		}
-		return string(result), invalidPos
+		return string(result), invalidPos, nil
 	}
-	return str, -1
+	return str, -1, nil
 }
 
 // StringValidatorUTF8 checks whether a string is valid UTF8 string.
 type StringValidatorUTF8 struct {
 	IsUTF8MB4           bool // Distinguish between "utf8" and "utf8mb4"
 	CheckMB4ValueInUTF8 bool
+	// ReplacementChar replaces the invalid characters in TruncateStrategyReplace. It is '?' if it is not set,
+	// which is compatible with MySQL. Set it to utf8.RuneError to use the Unicode replacement character U+FFFD.
+	ReplacementChar rune
+	// RejectNonCharacters treats the Unicode noncharacters U+FDD0..U+FDEF and U+nFFFE, U+nFFFF of all the planes
+	// as invalid. The encoded surrogates U+D800..U+DFFF are always invalid like MySQL, as utf8.DecodeRuneInString
+	// doesn't accept them.
+	RejectNonCharacters bool
 }
 
 // Validate checks whether the string is valid in the given charset.
 func (s StringValidatorUTF8) Validate(str string) int {
-	_, invalidPos := s.Truncate(str, TruncateStrategyEmpty)
+	_, invalidPos, _ := s.Truncate(str, TruncateStrategyEmpty)
 	return invalidPos
 }
 
+// ValidateWithPosition implements the interface StringValidator.
+func (s StringValidatorUTF8) ValidateWithPosition(str string) (invalidPos int, charPos int) {
+	invalidPos = s.Validate(str)
+	return invalidPos, charIndex(str, invalidPos)
+}
+
 // Truncate implement the interface StringValidator.
-func (s StringValidatorUTF8) Truncate(str string, strategy TruncateStrategy) (string, int) {
-	if str == "" {
-		return str, -1
-	}
-	if s.IsUTF8MB4 && utf8.ValidString(str) {
+func (s StringValidatorUTF8) Truncate(str string, strategy TruncateStrategy) (string, int, error) {
+	invalidPos := s.indexInvalid(str)
+	if invalidPos == -1 {
 		// Quick check passed.
-		return str, -1
-	}
-	doMB4CharCheck := !s.IsUTF8MB4 && s.CheckMB4ValueInUTF8
-	var result []byte
-	if strategy == TruncateStrategyReplace {
-		result = make([]byte, 0, len(str))
+		return str, -1, nil
 	}
-	invalidPos := -1
-	for i, w := 0, 0; i < len(str); i += w {
-		var rv rune
-		rv, w = utf8.DecodeRuneInString(str[i:])
-		if (rv == utf8.RuneError && w == 1) || (w > 3 && doMB4CharCheck) {
-			if invalidPos == -1 {
-				invalidPos = i
-			}
-			switch strategy {
-			case TruncateStrategyEmpty:
-				return "", invalidPos
-			case TruncateStrategyTrim:
-				return str[:i], invalidPos
-			case TruncateStrategyReplace:
-				result = append(result, '?')
+	switch strategy {
+	case TruncateStrategyEmpty:
+		return "", invalidPos, nil
+	case TruncateStrategyTrim:
+		return str[:invalidPos], invalidPos, nil
+	case TruncateStrategyError:
+		_, w := utf8.DecodeRuneInString(str[invalidPos:])
+		return str, invalidPos, invalidCharacterError(s.charset(), str[invalidPos:invalidPos+w])
+	case TruncateStrategyReplace:
+		replacement := []byte{'?'}
+		if s.ReplacementChar != 0 {
+			replacement = []byte(string(s.ReplacementChar))
+		}
+		result := make([]byte, 0, len(str))
+		result = append(result, str[:invalidPos]...)
+		for i, w := invalidPos, 0; i < len(str); i += w {
+			var rv rune
+			rv, w = utf8.DecodeRuneInString(str[i:])
+			if s.isInvalidRune(rv, w) {
+				result = append(result, replacement...)
 				continue
 			}
-		}
-		if strategy == TruncateStrategyReplace {
 			result = append(result, str[i:i+w]...)
 		}
+		return string(result), invalidPos, nil
 	}
-	if strategy == TruncateStrategyReplace {
-		return string(result), invalidPos
+	return str, -1, nil
+}
+
+// indexInvalid returns the position of the first invalid character in the string, or -1 if there is none.
+func (s StringValidatorUTF8) indexInvalid(str string) int {
+	if (!s.CheckMB4ValueInUTF8 || s.IsUTF8MB4) && !s.RejectNonCharacters {
+		// utf8.ValidString is the fastest way to accept a valid string, the loop below is only needed to find
+		// the invalid position.
+		if utf8.ValidString(str) {
+			return -1
+		}
+	}
+	// The ASCII bytes are valid in both utf8 and utf8mb4, only the non-ASCII characters are decoded.
+	i := indexNonASCII(str)
+	if i == -1 {
+		return -1
 	}
-	return str, -1
+	for i < len(str) {
+		if str[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		rv, w := utf8.DecodeRuneInString(str[i:])
+		if s.isInvalidRune(rv, w) {
+			return i
+		}
+		i += w
+	}
+	return -1
+}
+
+// isInvalidRune checks the rune decoded from w bytes by utf8.DecodeRuneInString.
+func (s StringValidatorUTF8) isInvalidRune(rv rune, w int) bool {
+	return (rv == utf8.RuneError && w == 1) || (w > 3 && !s.IsUTF8MB4 && s.CheckMB4ValueInUTF8) ||
+		(s.RejectNonCharacters && isNonCharacter(rv))
+}
+
+// isNonCharacter checks whether the rune is one of the 66 Unicode noncharacters.
+func isNonCharacter(r rune) bool {
+	return (r >= 0xFDD0 && r <= 0xFDEF) || (r&0xFFFE == 0xFFFE && r <= utf8.MaxRune)
+}
+
+func (s StringValidatorUTF8) charset() string {
+	if s.IsUTF8MB4 {
+		return CharsetUTF8MB4
+	}
+	return CharsetUTF8
 }
 
 // StringValidatorOther checks whether a string is valid string in given charset.
@@ -402,40 +686,87 @@ type StringValidatorOther struct {
 
 // Validate checks whether the string is valid in the given charset.
 func (s StringValidatorOther) Validate(str string) int {
-	_, invalidPos := s.Truncate(str, TruncateStrategyEmpty)
+	_, invalidPos, _ := s.Truncate(str, TruncateStrategyEmpty)
 	return invalidPos
 }
 
+// ValidateWithPosition implements the interface StringValidator.
+func (s StringValidatorOther) ValidateWithPosition(str string) (invalidPos int, charPos int) {
+	// The string is UTF-8, the characters before the invalid one are valid UTF-8 as well.
+	invalidPos = s.Validate(str)
+	return invalidPos, charIndex(str, invalidPos)
+}
+
 // Truncate implement the interface StringValidator.
-func (s StringValidatorOther) Truncate(str string, strategy TruncateStrategy) (string, int) {
+func (s StringValidatorOther) Truncate(str string, strategy TruncateStrategy) (string, int, error) {
 	if str == "" {
-		return str, -1
+		return str, -1, nil
 	}
 	enc := NewEncoding(s.Charset)
 	if !enc.enabled() {
-		return str, -1
+		return str, -1, nil
 	}
 	var result []byte
 	if strategy == TruncateStrategyReplace {
 		result = make([]byte, 0, len(str))
 	}
 	var buf [4]byte
+	// batchBuf receives the encoded valid characters, which are checked in batches up to its size.
+	var batchBuf []byte
 	strBytes := Slice(str)
-	transformer := enc.enc.NewEncoder()
+	// transformer and batchBuf are only created for the characters not in the table of the encoding.
+	var transformer transform.Transformer
 	invalidPos := -1
+	// validUTF8End is the end of the valid UTF-8 characters since i.
+	validUTF8End := 0
+	if utf8.ValidString(str) {
+		validUTF8End = len(str)
+	}
 	for i, w := 0, 0; i < len(str); i += w {
+		if enc.table != nil {
+			if n := enc.table.encodableLen(strBytes[i:]); n > 0 {
+				if strategy == TruncateStrategyReplace {
+					result = append(result, strBytes[i:i+n]...)
+				}
+				w = n
+				continue
+			}
+		}
+		if transformer == nil {
+			transformer = enc.enc.NewEncoder()
+			batchBuf = make([]byte, mathutil.Min(len(str)*2, 4096)+len(buf))
+		}
+		if i >= validUTF8End {
+			validUTF8End = i + validUTF8Len(str[i:])
+		}
+		// Fast path: the characters are valid until the transformer fails. It only checks the valid UTF-8 ones,
+		// which are split into characters in the same way as the slow path, so the character at i is invalid if
+		// none of them is encoded.
+		n, err := validEncodingLen(transformer, batchBuf, strBytes[i:validUTF8End])
+		if n > 0 {
+			if strategy == TruncateStrategyReplace {
+				result = append(result, strBytes[i:i+n]...)
+			}
+			w = n
+			continue
+		}
 		w = UTF8Encoding.CharLength(strBytes[i:])
 		w = mathutil.Min(w, len(str)-i)
-		_, _, err := transformer.Transform(buf[:], strBytes[i:i+w], true)
+		if i >= validUTF8End {
+			// Slow path: the character at i is not valid UTF-8, check it by itself.
+			_, _, err = transformer.Transform(buf[:], strBytes[i:i+w], true)
+		}
 		if err != nil {
 			if invalidPos == -1 {
 				invalidPos = i
 			}
 			switch strategy {
 			case TruncateStrategyEmpty:
-				return "", invalidPos
+				return "", invalidPos, nil
 			case TruncateStrategyTrim:
-				return str[:i], invalidPos
+				return str[:i], invalidPos, nil
+			case TruncateStrategyError:
+				return str, invalidPos, invalidCharacterError(s.Charset, str[i:i+w])
 			case TruncateStrategyReplace:
 				result = append(result, '?')
 				continue
@@ -446,7 +777,37 @@ func (s StringValidatorOther) Truncate(str string, strategy TruncateStrategy) (s
 		}
 	}
 	if strategy == TruncateStrategyReplace {
-		return string(result), invalidPos
+		return string(result), invalidPos, nil
+	}
+	return str, -1, nil
+}
+
+// validUTF8Len returns the length of the valid UTF-8 prefix of the string.
+func validUTF8Len(str string) int {
+	for i := 0; i < len(str); {
+		if str[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, w := utf8.DecodeRuneInString(str[i:])
+		if r == utf8.RuneError && w == 1 {
+			return i
+		}
+		i += w
+	}
+	return len(str)
+}
+
+// validEncodingLen returns the length of the prefix of src which the transformer encodes without errors and
+// the error of the character after it, the encoded bytes are written to dst and dropped.
+func validEncodingLen(transformer transform.Transformer, dst, src []byte) (n int, err error) {
+	for n < len(src) {
+		var nSrc int
+		_, nSrc, err = transformer.Transform(dst, src[n:], true)
+		n += nSrc
+		if err != transform.ErrShortDst || nSrc == 0 {
+			break
+		}
 	}
-	return str, -1
+	return n, err
 }
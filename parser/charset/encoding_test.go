@@ -15,11 +15,14 @@ package charset_test
 
 import (
 	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 	"unicode/utf8"
 
 	"github.com/pingcap/tidb/parser/charset"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
@@ -89,6 +92,138 @@ func TestEncoding(t *testing.T) {
 	}
 }
 
+func TestGB18030Encoding(t *testing.T) {
+	t.Parallel()
+	enc := charset.NewEncoding(charset.CharsetGB18030)
+	require.Equal(t, charset.CharsetGB18030, enc.Name())
+
+	testCases := []struct {
+		utf8Str    string
+		gb18030Str string
+		charLens   []int
+	}{
+		{"abc", "abc", []int{1, 1, 1}},
+		{"一二三", "\xd2\xbb\xb6\xfe\xc8\xfd", []int{2, 2, 2}},
+		// The characters out of GBK are encoded in 4 bytes, including the ones outside the BMP.
+		{"€", "\xa2\xe3", []int{2}},
+		{"ḿ", "\xa8\xbc", []int{2}},
+		{"ẞ", "\x81\x35\xfe\x32", []int{4}},
+		{"\ue7c7", "\x81\x35\xf4\x37", []int{4}},
+		{"😀", "\x94\x39\xfc\x36", []int{4}},
+		{"𠂇a𪚥", "\x95\x32\x90\x31a\x98\x35\xee\x37", []int{4, 1, 4}},
+		// The characters mapped to the standard code points by GB18030-2022.
+		{"︐︙", "\xa6\xd9\xa6\xf3", []int{2, 2}},
+		{"龴龻", "\xfe\x59\xfe\xa0", []int{2, 2}},
+	}
+	for _, tc := range testCases {
+		encoded, err := enc.Encode(nil, []byte(tc.utf8Str))
+		require.NoError(t, err, tc.utf8Str)
+		require.Equal(t, tc.gb18030Str, string(encoded), tc.utf8Str)
+		decoded, err := enc.Decode(nil, encoded)
+		require.NoError(t, err, tc.utf8Str)
+		require.Equal(t, tc.utf8Str, string(decoded), tc.utf8Str)
+
+		var charLens []int
+		for rest := encoded; len(rest) > 0; rest = rest[enc.CharLength(rest):] {
+			charLens = append(charLens, enc.CharLength(rest))
+		}
+		require.Equal(t, tc.charLens, charLens, tc.utf8Str)
+	}
+
+	// The four-byte characters which the standard code points were mapped to by GB18030-2005 are still decoded.
+	decoded, err := enc.Decode(nil, []byte("\x84\x31\x82\x36\x82\x35\x90\x37"))
+	require.NoError(t, err)
+	require.Equal(t, "︐龴", string(decoded))
+
+	invalidCases := []struct {
+		gb18030Str string
+		result     string
+	}{
+		{"\x80", "?"},
+		{"a\xff", "a?"},
+		{"\x81\x30", "?"},
+		{"\xa1\x7f", "?"},
+	}
+	for _, tc := range invalidCases {
+		decoded, err = enc.Decode(nil, []byte(tc.gb18030Str))
+		require.Error(t, err, tc.gb18030Str)
+		require.Equal(t, tc.result, string(decoded), tc.gb18030Str)
+	}
+
+	// The lookup of the label returns the same encoding.
+	e, name := charset.Lookup("GB18030")
+	require.Equal(t, "gb18030", name)
+	decoded, _, err = transform.Bytes(e.NewDecoder(), []byte("\xa6\xd9\x94\x39\xfc\x36"))
+	require.NoError(t, err)
+	require.Equal(t, "︐😀", string(decoded))
+}
+
+func TestIsSupportedEncoding(t *testing.T) {
+	t.Parallel()
+	for _, label := range []string{"utf8mb4", "utf8", "gbk", "gb18030", "latin1", "binary", "ascii", "GBK", "Utf8MB4", " latin1 "} {
+		require.True(t, charset.IsSupportedEncoding(label), label)
+	}
+	for _, label := range []string{"", "boguscharsetname", "gb2312", "utf-8", "big5"} {
+		require.False(t, charset.IsSupportedEncoding(label), label)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		label       string
+		name        string
+		hasModifier bool
+	}{
+		{"gbk", "gbk", false},
+		{" UTF-8 ", "utf-8", false},
+		{"utf-8//ignore", "utf-8", true},
+		{"GBK//TRANSLIT", "gbk", true},
+		{"utf8mb4//TRANSLIT//IGNORE", "utf-8", true},
+		{"unknown//IGNORE", "", true},
+		{"unknown", "", false},
+	}
+	for _, tc := range testCases {
+		e, name, hasModifier := charset.LookupWithModifier(tc.label)
+		require.Equal(t, tc.name, name, tc.label)
+		require.Equal(t, tc.name != "", e != nil, tc.label)
+		require.Equal(t, tc.hasModifier, hasModifier, tc.label)
+		e, name = charset.Lookup(tc.label)
+		require.Equal(t, tc.name, name, tc.label)
+		require.Equal(t, tc.name != "", e != nil, tc.label)
+	}
+}
+
+func TestMaxCharWidth(t *testing.T) {
+	t.Parallel()
+	for label, width := range map[string]int{"utf8mb4": 4, "utf8": 4, "gbk": 2, "gb18030": 4, "latin1": 1, "binary": 1, "ascii": 1, "": 4} {
+		require.Equal(t, width, charset.NewEncoding(label).MaxCharWidth(), label)
+	}
+}
+
+func TestIsVariableWidth(t *testing.T) {
+	t.Parallel()
+	for label, variable := range map[string]bool{"utf8mb4": true, "utf8": true, "gbk": true, "gb18030": true, "latin1": false, "binary": false, "ascii": false, "": true} {
+		require.Equal(t, variable, charset.NewEncoding(label).IsVariableWidth(), label)
+	}
+}
+
+func TestEncodingCollations(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, []string{"ascii_bin", "ascii_general_ci"}, charset.NewEncoding("ascii").Collations())
+	require.Equal(t, []string{"gb18030_bin", "gb18030_chinese_ci"}, charset.NewEncoding("gb18030").Collations())
+	require.Equal(t, []string{"binary"}, charset.NewEncoding("binary").Collations())
+	collations := charset.NewEncoding("utf8").Collations()
+	require.Contains(t, collations, "utf8mb4_bin")
+	require.Contains(t, collations, "utf8mb4_zh_pinyin_tidb_as_cs")
+	require.NotContains(t, collations, "utf8_bin")
+	for _, name := range collations {
+		c, err := charset.GetCollationByName(name)
+		require.NoError(t, err)
+		require.Equal(t, charset.CharsetUTF8MB4, c.CharsetName)
+	}
+}
+
 func TestStringValidatorASCII(t *testing.T) {
 	v := charset.StringValidatorASCII{}
 	testCases := []struct {
@@ -105,16 +240,63 @@ func TestStringValidatorASCII(t *testing.T) {
 		{"中文", charset.TruncateStrategyEmpty, "", 0},
 		{"中文?qwert", charset.TruncateStrategyTrim, "", 0},
 		{"中文?qwert", charset.TruncateStrategyReplace, "???qwert", 0},
+		{"qwerty", charset.TruncateStrategyError, "qwerty", -1},
+		{"qwÊrty", charset.TruncateStrategyError, "qwÊrty", 2},
 	}
 	for _, tc := range testCases {
 		msg := fmt.Sprintf("%v", tc)
-		actual, invalidPos := v.Truncate(tc.str, tc.strategy)
+		actual, invalidPos, err := v.Truncate(tc.str, tc.strategy)
 		require.Equal(t, tc.expected, actual, msg)
 		require.Equal(t, tc.invalidPos, invalidPos, msg)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, msg)
 	}
 	require.Equal(t, -1, v.Validate("qwerty"))
 	require.Equal(t, 2, v.Validate("qwÊrty"))
 	require.Equal(t, 0, v.Validate("中文"))
+	// The non-ASCII byte is found at any position of the words checked at a time.
+	long := strings.Repeat("a", 20)
+	require.Equal(t, -1, v.Validate(long))
+	for i := 0; i < len(long); i++ {
+		str := long[:i] + "Ê" + long[i:]
+		require.Equal(t, i, v.Validate(str), str)
+		actual, _, _ := v.Truncate(str, charset.TruncateStrategyReplace)
+		require.Equal(t, long[:i]+"?"+long[i:], actual, str)
+	}
+}
+
+func checkTruncateErr(t *testing.T, strategy charset.TruncateStrategy, invalidPos int, err error, msg string) {
+	if strategy == charset.TruncateStrategyError && invalidPos >= 0 {
+		require.True(t, charset.ErrInvalidCharacterString.Equal(err), msg)
+	} else {
+		require.NoError(t, err, msg)
+	}
+}
+
+func TestTruncateStrategyError(t *testing.T) {
+	testCases := []struct {
+		v   charset.StringValidator
+		str string
+		err string
+	}{
+		{charset.StringValidatorASCII{}, "qwÊrty", "[parser:1300]Invalid ascii character string: 'C38A'"},
+		{charset.StringValidatorUTF8{IsUTF8MB4: true}, "中文\xff\xfe", "[parser:1300]Invalid utf8mb4 character string: 'FF'"},
+		{charset.StringValidatorUTF8{CheckMB4ValueInUTF8: true}, "a😂", "[parser:1300]Invalid utf8 character string: 'F09F9882'"},
+		{charset.StringValidatorOther{Charset: "gbk"}, "中文À", "[parser:1300]Invalid gbk character string: 'C380'"},
+	}
+	for _, tc := range testCases {
+		actual, _, err := tc.v.Truncate(tc.str, charset.TruncateStrategyError)
+		require.Equal(t, tc.str, actual)
+		require.EqualError(t, err, tc.err)
+	}
+}
+
+func BenchmarkStringValidatorASCIIValidate(b *testing.B) {
+	v := charset.StringValidatorASCII{}
+	str := strings.Repeat("abcdefghij", 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.Validate(str)
+	}
 }
 
 func TestStringValidatorUTF8(t *testing.T) {
@@ -136,13 +318,15 @@ func TestStringValidatorUTF8(t *testing.T) {
 		{oxfffefd, charset.TruncateStrategyReplace, "???", 0},
 		{"中文" + oxfffefd, charset.TruncateStrategyTrim, "中文", 6},
 		{"中文" + oxfffefd, charset.TruncateStrategyReplace, "中文???", 6},
+		{"中文" + oxfffefd, charset.TruncateStrategyError, "中文" + oxfffefd, 6},
 		{string(utf8.RuneError), charset.TruncateStrategyEmpty, "�", -1},
 	}
 	for _, tc := range testCases {
 		msg := fmt.Sprintf("%v", tc)
-		actual, invalidPos := v.Truncate(tc.str, tc.strategy)
+		actual, invalidPos, err := v.Truncate(tc.str, tc.strategy)
 		require.Equal(t, tc.expected, actual, msg)
 		require.Equal(t, tc.invalidPos, invalidPos, msg)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, msg)
 	}
 	// Test charset "utf8" with checking mb4 value.
 	v = charset.StringValidatorUTF8{IsUTF8MB4: false, CheckMB4ValueInUTF8: true}
@@ -163,13 +347,138 @@ func TestStringValidatorUTF8(t *testing.T) {
 		{oxfffefd, charset.TruncateStrategyReplace, "???", 0},
 		{"中文" + oxfffefd, charset.TruncateStrategyTrim, "中文", 6},
 		{"中文" + oxfffefd, charset.TruncateStrategyReplace, "中文???", 6},
+		{"中文" + oxfffefd, charset.TruncateStrategyError, "中文" + oxfffefd, 6},
 		{string(utf8.RuneError), charset.TruncateStrategyEmpty, "�", -1},
 	}
 	for _, tc := range testCases {
 		msg := fmt.Sprintf("%v", tc)
-		actual, invalidPos := v.Truncate(tc.str, tc.strategy)
+		actual, invalidPos, err := v.Truncate(tc.str, tc.strategy)
+		require.Equal(t, tc.expected, actual, msg)
+		require.Equal(t, tc.invalidPos, invalidPos, msg)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, msg)
+	}
+	// Test the Unicode replacement character.
+	v = charset.StringValidatorUTF8{IsUTF8MB4: false, CheckMB4ValueInUTF8: true, ReplacementChar: utf8.RuneError}
+	testCases = []struct {
+		str        string
+		strategy   charset.TruncateStrategy
+		expected   string
+		invalidPos int
+	}{
+		{"qwÊrty", charset.TruncateStrategyReplace, "qwÊrty", -1},
+		{"valid_str😂", charset.TruncateStrategyReplace, "valid_str\uFFFD", 9},
+		{"中文" + oxfffefd, charset.TruncateStrategyReplace, "中文\uFFFD\uFFFD\uFFFD", 6},
+		{"中文" + oxfffefd, charset.TruncateStrategyTrim, "中文", 6},
+	}
+	for _, tc := range testCases {
+		msg := fmt.Sprintf("%v", tc)
+		actual, invalidPos, err := v.Truncate(tc.str, tc.strategy)
 		require.Equal(t, tc.expected, actual, msg)
 		require.Equal(t, tc.invalidPos, invalidPos, msg)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, msg)
+	}
+}
+
+func TestStringValidatorUTF8NonCharacters(t *testing.T) {
+	// The encoded surrogate U+D800 is invalid even RejectNonCharacters is not set.
+	surrogate := "\xed\xa0\x80"
+	for _, v := range []charset.StringValidatorUTF8{{IsUTF8MB4: true}, {IsUTF8MB4: true, RejectNonCharacters: true}} {
+		require.Equal(t, 3, v.Validate("中"+surrogate))
+		actual, invalidPos, _ := v.Truncate("a"+surrogate+"b", charset.TruncateStrategyReplace)
+		require.Equal(t, "a???b", actual)
+		require.Equal(t, 1, invalidPos)
+	}
+
+	v := charset.StringValidatorUTF8{IsUTF8MB4: true, RejectNonCharacters: true}
+	for _, nonChar := range []string{"\uFDD0", "\uFDEF", "\uFFFE", "\uFFFF", "\U0001FFFE", "\U0010FFFF"} {
+		require.Equal(t, -1, charset.StringValidatorUTF8{IsUTF8MB4: true}.Validate(nonChar), "%q", nonChar)
+		require.Equal(t, 0, v.Validate(nonChar), "%q", nonChar)
+	}
+	for _, char := range []string{"\uFDCF", "\uFDF0", "\uFFFD", "\U0001FFFD", "😂"} {
+		require.Equal(t, -1, v.Validate(char), "%q", char)
+	}
+	testCases := []struct {
+		strategy charset.TruncateStrategy
+		expected string
+	}{
+		{charset.TruncateStrategyEmpty, ""},
+		{charset.TruncateStrategyTrim, "中文"},
+		{charset.TruncateStrategyReplace, "中文?a?"},
+		{charset.TruncateStrategyError, "中文\uFFFEa\U0010FFFF"},
+	}
+	for _, tc := range testCases {
+		actual, invalidPos, err := v.Truncate("中文\uFFFEa\U0010FFFF", tc.strategy)
+		require.Equal(t, tc.expected, actual)
+		require.Equal(t, 6, invalidPos)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, "")
+	}
+	_, _, err := v.Truncate("a\uFFFE", charset.TruncateStrategyError)
+	require.EqualError(t, err, "[parser:1300]Invalid utf8mb4 character string: 'EFBFBE'")
+}
+
+func TestStringValidatorUTF8InvalidPos(t *testing.T) {
+	validators := []charset.StringValidatorUTF8{
+		{IsUTF8MB4: true},
+		{CheckMB4ValueInUTF8: true},
+	}
+	// indexInvalidByRune decodes the string rune by rune, the validators must return the same position.
+	indexInvalidByRune := func(v charset.StringValidatorUTF8, str string) int {
+		for i, w := 0, 0; i < len(str); i += w {
+			var rv rune
+			rv, w = utf8.DecodeRuneInString(str[i:])
+			if (rv == utf8.RuneError && w == 1) || (w > 3 && !v.IsUTF8MB4) {
+				return i
+			}
+		}
+		return -1
+	}
+	long := strings.Repeat("a", 20)
+	// The invalid characters are put at any position of the words checked at a time, "\xe4\xb8" is the first
+	// two bytes of "中", the string can end in the middle of it.
+	for _, invalid := range []string{"\xff", "😂", "\xe4\xb8", "中\xe4\xb8"} {
+		for i := 0; i <= len(long); i++ {
+			for _, str := range []string{long[:i] + invalid + long[i:], long[:i] + invalid} {
+				for _, v := range validators {
+					expected := indexInvalidByRune(v, str)
+					require.Equal(t, expected, v.Validate(str), "%q %v", str, v)
+					actual, invalidPos, _ := v.Truncate(str, charset.TruncateStrategyTrim)
+					require.Equal(t, expected, invalidPos, "%q %v", str, v)
+					if expected >= 0 {
+						require.Equal(t, str[:expected], actual, "%q %v", str, v)
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkStringValidatorUTF8Validate(b *testing.B) {
+	validators := []struct {
+		name string
+		v    charset.StringValidatorUTF8
+	}{
+		{"utf8mb4", charset.StringValidatorUTF8{IsUTF8MB4: true}},
+		{"utf8", charset.StringValidatorUTF8{CheckMB4ValueInUTF8: true}},
+	}
+	ascii := strings.Repeat("abcdefghij", 1<<20/10)
+	inputs := []struct {
+		name string
+		str  string
+	}{
+		{"ascii", ascii},
+		// One in ten characters is not ASCII.
+		{"mixed", strings.Repeat("abcdefghi中", 1<<20/12)},
+		{"invalid-tail", ascii + "\xff"},
+	}
+	for _, v := range validators {
+		for _, in := range inputs {
+			b.Run(v.name+"/"+in.name, func(b *testing.B) {
+				b.SetBytes(int64(len(in.str)))
+				for i := 0; i < b.N; i++ {
+					v.v.Validate(in.str)
+				}
+			})
+		}
 	}
 }
 
@@ -189,11 +498,121 @@ func TestStringValidatorGBK(t *testing.T) {
 		{"中文À中文", charset.TruncateStrategyTrim, "中文", 6},
 		{"中文À中文", charset.TruncateStrategyReplace, "中文?中文", 6},
 		{"asdfÀ", charset.TruncateStrategyReplace, "asdf?", 4},
+		{"中文", charset.TruncateStrategyError, "中文", -1},
+		{"asdfÀ", charset.TruncateStrategyError, "asdfÀ", 4},
 	}
 	for _, tc := range testCases {
 		msg := fmt.Sprintf("%v", tc)
-		actual, invalidPos := v.Truncate(tc.str, tc.strategy)
+		actual, invalidPos, err := v.Truncate(tc.str, tc.strategy)
 		require.Equal(t, tc.expected, actual, msg)
 		require.Equal(t, tc.invalidPos, invalidPos, msg)
+		checkTruncateErr(t, tc.strategy, invalidPos, err, msg)
+	}
+}
+
+// truncateGBKByChar is how StringValidatorOther truncates the gbk strings by checking the characters one by one.
+func truncateGBKByChar(str string, strategy charset.TruncateStrategy) (string, int) {
+	var result []byte
+	transformer := simplifiedchinese.GBK.NewEncoder()
+	var buf [4]byte
+	invalidPos := -1
+	for i, w := 0, 0; i < len(str); i += w {
+		w = charset.UTF8Encoding.CharLength([]byte(str[i:]))
+		if w > len(str)-i {
+			w = len(str) - i
+		}
+		if _, _, err := transformer.Transform(buf[:], []byte(str[i:i+w]), true); err != nil {
+			if invalidPos == -1 {
+				invalidPos = i
+			}
+			switch strategy {
+			case charset.TruncateStrategyEmpty:
+				return "", invalidPos
+			case charset.TruncateStrategyTrim:
+				return str[:i], invalidPos
+			case charset.TruncateStrategyError:
+				return str, invalidPos
+			}
+			result = append(result, '?')
+			continue
+		}
+		result = append(result, str[i:i+w]...)
+	}
+	if strategy == charset.TruncateStrategyReplace {
+		return string(result), invalidPos
+	}
+	return str, -1
+}
+
+func TestStringValidatorValidateWithPosition(t *testing.T) {
+	gbk := charset.StringValidatorOther{Charset: "gbk"}
+	testCases := []struct {
+		v          charset.StringValidator
+		str        string
+		invalidPos int
+		charPos    int
+	}{
+		{charset.StringValidatorASCII{}, "qwerty", -1, -1},
+		{charset.StringValidatorASCII{}, "qwÊrty", 2, 2},
+		{charset.StringValidatorUTF8{IsUTF8MB4: true}, "中文", -1, -1},
+		{charset.StringValidatorUTF8{IsUTF8MB4: true}, "中文a\xff", 7, 3},
+		{charset.StringValidatorUTF8{CheckMB4ValueInUTF8: true}, "a中😂", 4, 2},
+		{gbk, "中文中文", -1, -1},
+		// The characters of 2 bytes in GBK are 3 bytes in UTF-8.
+		{gbk, "中文中文\xff", 12, 4},
+		{gbk, "中文中文À", 12, 4},
+		{gbk, "a中文À中文", 7, 3},
 	}
+	for _, tc := range testCases {
+		invalidPos, charPos := tc.v.ValidateWithPosition(tc.str)
+		require.Equal(t, tc.invalidPos, invalidPos, "%q", tc.str)
+		require.Equal(t, tc.charPos, charPos, "%q", tc.str)
+		require.Equal(t, tc.invalidPos, tc.v.Validate(tc.str), "%q", tc.str)
+	}
+}
+
+func TestStringValidatorGBKBatch(t *testing.T) {
+	t.Parallel()
+	v := charset.StringValidatorOther{Charset: "gbk"}
+	pieces := []string{"a", "qwerty", "中文", "À", "😂", "\xff", "\xe4a", "\xe4\xb8", "\xf0\x9f", "€", " "}
+	rnd := rand.New(rand.NewSource(0))
+	strategies := []charset.TruncateStrategy{charset.TruncateStrategyEmpty, charset.TruncateStrategyTrim,
+		charset.TruncateStrategyReplace, charset.TruncateStrategyError}
+	for i := 0; i < 1000; i++ {
+		var sb strings.Builder
+		for j := rnd.Intn(20); j > 0; j-- {
+			sb.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		// Make the valid strings long enough to be checked in several batches.
+		if i%100 == 0 {
+			sb.WriteString(strings.Repeat("中文abc", 2000))
+			sb.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		str := sb.String()
+		for _, strategy := range strategies {
+			expected, expectedPos := truncateGBKByChar(str, strategy)
+			actual, invalidPos, _ := v.Truncate(str, strategy)
+			require.Equal(t, expected, actual, "%q %v", str, strategy)
+			require.Equal(t, expectedPos, invalidPos, "%q %v", str, strategy)
+		}
+	}
+}
+
+func BenchmarkStringValidatorGBKTruncate(b *testing.B) {
+	v := charset.StringValidatorOther{Charset: "gbk"}
+	valid := strings.Repeat("中文abcdef", 1<<20/12)
+	// The characters out of GBK are replaced except one in ten.
+	invalid := strings.Repeat("ÀÀÀÀÀÀÀÀÀ中", 1<<20/21)
+	b.Run("valid", func(b *testing.B) {
+		b.SetBytes(int64(len(valid)))
+		for i := 0; i < b.N; i++ {
+			v.Truncate(valid, charset.TruncateStrategyReplace)
+		}
+	})
+	b.Run("mostly-invalid", func(b *testing.B) {
+		b.SetBytes(int64(len(invalid)))
+		for i := 0; i < b.N; i++ {
+			v.Truncate(invalid, charset.TruncateStrategyReplace)
+		}
+	})
 }
@@ -0,0 +1,165 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package charset
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// GB18030Encoding is the encoding of the gb18030 charset. A character is encoded in 1, 2 or 4 bytes,
+// all the Unicode code points including the ones outside the BMP can be encoded.
+var GB18030Encoding = &Encoding{
+	enc:          gb18030{},
+	name:         CharsetGB18030,
+	charLength:   gb18030CharLength,
+	specialCase:  nil,
+	maxCharWidth: 4,
+}
+
+func gb18030CharLength(bs []byte) int {
+	if len(bs) == 0 || bs[0] < 0x80 || bs[0] == 0x80 || bs[0] == 0xFF {
+		// A byte in the range 00–7F is a single byte that means the same thing as it does in ASCII,
+		// 80 and FF are not the first byte of any character.
+		return 1
+	}
+	if len(bs) > 1 && bs[1] >= 0x30 && bs[1] <= 0x39 {
+		// The second byte of a four-byte character is a digit.
+		return 4
+	}
+	return 2
+}
+
+// gb18030Mappings are the characters which simplifiedchinese.GB18030 does not map to the same code points as
+// GB18030-2022. The two-byte characters since A6D9 were mapped to the private use area before GB18030-2022,
+// the four-byte characters which their standard code points were mapped to are still decoded as before.
+var gb18030Mappings = []struct {
+	gb18030 string
+	r       rune
+}{
+	{"\xA2\xE3", 0x20AC}, {"\xA8\xBC", 0x1E3F}, {"\x81\x35\xF4\x37", 0xE7C7},
+	{"\xA6\xD9", 0xFE10}, {"\xA6\xDA", 0xFE12}, {"\xA6\xDB", 0xFE11}, {"\xA6\xDC", 0xFE13},
+	{"\xA6\xDD", 0xFE14}, {"\xA6\xDE", 0xFE15}, {"\xA6\xDF", 0xFE16}, {"\xA6\xEC", 0xFE17},
+	{"\xA6\xED", 0xFE18}, {"\xA6\xF3", 0xFE19}, {"\xFE\x59", 0x9FB4}, {"\xFE\x61", 0x9FB5},
+	{"\xFE\x66", 0x9FB6}, {"\xFE\x67", 0x9FB7}, {"\xFE\x6D", 0x9FB8}, {"\xFE\x7E", 0x9FB9},
+	{"\xFE\x90", 0x9FBA}, {"\xFE\xA0", 0x9FBB},
+}
+
+var (
+	gb18030DecodeTable = map[string]rune{
+		// simplifiedchinese.GB18030 decodes 80 as the euro sign like GBK does, but it is invalid in GB18030.
+		"\x80": utf8.RuneError,
+	}
+	gb18030EncodeTable = map[rune]string{}
+)
+
+func init() {
+	for _, m := range gb18030Mappings {
+		gb18030DecodeTable[m.gb18030] = m.r
+		gb18030EncodeTable[m.r] = m.gb18030
+	}
+}
+
+// gb18030 implements encoding.Encoding with the GB18030-2022 mappings.
+type gb18030 struct{}
+
+func (gb18030) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: &gb18030Decoder{dec: simplifiedchinese.GB18030.NewDecoder()}}
+}
+
+func (gb18030) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: &gb18030Encoder{enc: simplifiedchinese.GB18030.NewEncoder()}}
+}
+
+// gb18030Decoder decodes the characters in gb18030DecodeTable itself, the others are decoded by
+// simplifiedchinese.GB18030.
+type gb18030Decoder struct {
+	dec transform.Transformer
+}
+
+func (d *gb18030Decoder) Reset() {
+	d.dec.Reset()
+}
+
+func (d *gb18030Decoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	start := 0
+	for i := 0; i < len(src); {
+		size := gb18030CharLength(src[i:])
+		if i+size > len(src) {
+			break
+		}
+		r, ok := gb18030DecodeTable[string(src[i:i+size])]
+		if !ok {
+			i += size
+			continue
+		}
+		n, m, err := d.dec.Transform(dst[nDst:], src[start:i], true)
+		nDst, nSrc = nDst+n, nSrc+m
+		if err != nil {
+			return nDst, nSrc, err
+		}
+		if len(dst)-nDst < utf8.RuneLen(r) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += utf8.EncodeRune(dst[nDst:], r)
+		i += size
+		start, nSrc = i, i
+	}
+	n, m, err := d.dec.Transform(dst[nDst:], src[start:], atEOF)
+	return nDst + n, nSrc + m, err
+}
+
+// gb18030Encoder encodes the characters in gb18030EncodeTable itself, the others are encoded by
+// simplifiedchinese.GB18030.
+type gb18030Encoder struct {
+	enc transform.Transformer
+}
+
+func (e *gb18030Encoder) Reset() {
+	e.enc.Reset()
+}
+
+func (e *gb18030Encoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	start := 0
+	for i := 0; i < len(src); {
+		r, size := rune(src[i]), 1
+		if r >= utf8.RuneSelf {
+			if !utf8.FullRune(src[i:]) {
+				break
+			}
+			r, size = utf8.DecodeRune(src[i:])
+		}
+		gb, ok := gb18030EncodeTable[r]
+		if !ok {
+			i += size
+			continue
+		}
+		n, m, err := e.enc.Transform(dst[nDst:], src[start:i], true)
+		nDst, nSrc = nDst+n, nSrc+m
+		if err != nil {
+			return nDst, nSrc, err
+		}
+		if len(dst)-nDst < len(gb) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], gb)
+		i += size
+		start, nSrc = i, i
+	}
+	n, m, err := e.enc.Transform(dst[nDst:], src[start:], atEOF)
+	return nDst + n, nSrc + m, err
+}
@@ -13,17 +13,133 @@
 
 package charset
 
-import "golang.org/x/text/encoding/simplifiedchinese"
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
 
 var GBKEncoding = &Encoding{
-	enc:  simplifiedchinese.GBK,
-	name: CharsetGBK,
-	charLength: func(bs []byte) int {
-		if len(bs) == 0 || bs[0] < 0x80 {
-			// A byte in the range 00–7F is a single byte that means the same thing as it does in ASCII.
-			return 1
-		}
-		return 2
-	},
-	specialCase: GBKCase,
+	enc:          simplifiedchinese.GBK,
+	name:         CharsetGBK,
+	charLength:   gbkCharLength,
+	specialCase:  GBKCase,
+	maxCharWidth: 2,
+}
+
+func init() {
+	GBKEncoding.table = newDoubleByteTable(simplifiedchinese.GBK, isGBKLeadByte)
+}
+
+func gbkCharLength(bs []byte) int {
+	if len(bs) == 0 || bs[0] < 0x80 {
+		// A byte in the range 00–7F is a single byte that means the same thing as it does in ASCII.
+		return 1
+	}
+	return 2
+}
+
+func isGBKLeadByte(b byte) bool {
+	return b >= 0x81 && b <= 0xFE
+}
+
+// doubleByteTable converts the ASCII characters, the two-byte characters of an encoding and the runes in the BMP
+// by lookups. It is built from the transformers of the encoding, the characters not in it, such as the euro sign
+// which GBK encodes in one byte, are left to the transformers.
+type doubleByteTable struct {
+	// decode maps the two bytes of a character in big endian to its rune, 0 means the character is not in the table.
+	decode [1 << 16]uint16
+	// encode maps a rune to the two bytes of its character in big endian, 0 means the rune is not in the table.
+	encode [1 << 16]uint16
+}
+
+func newDoubleByteTable(e encoding.Encoding, isLeadByte func(byte) bool) *doubleByteTable {
+	t := &doubleByteTable{}
+	var src, dst [utf8.UTFMax]byte
+	decoder := e.NewDecoder()
+	for c := 0; c < len(t.decode); c++ {
+		if !isLeadByte(byte(c >> 8)) {
+			continue
+		}
+		src[0], src[1] = byte(c>>8), byte(c)
+		decoder.Reset()
+		nDst, nSrc, err := decoder.Transform(dst[:], src[:2], true)
+		if err != nil || nSrc != 2 {
+			continue
+		}
+		r, w := utf8.DecodeRune(dst[:nDst])
+		if w != nDst || r == utf8.RuneError || r < utf8.RuneSelf || r >= rune(len(t.encode)) {
+			continue
+		}
+		t.decode[c] = uint16(r)
+	}
+	encoder := e.NewEncoder()
+	for r := rune(utf8.RuneSelf); r < rune(len(t.encode)); r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+		n := utf8.EncodeRune(src[:], r)
+		encoder.Reset()
+		nDst, nSrc, err := encoder.Transform(dst[:], src[:n], true)
+		if err != nil || nSrc != n || nDst != 2 || !isLeadByte(dst[0]) {
+			continue
+		}
+		t.encode[r] = uint16(dst[0])<<8 | uint16(dst[1])
+	}
+	return t
+}
+
+// convertPrefix converts the characters at the start of src by the table until one is not in it or dst has no room
+// for utf8.UTFMax bytes, src is a UTF-8 string if it is encoding, or a string of the encoding if it is decoding.
+// It returns the number of bytes written to dst and read from src.
+func (t *doubleByteTable) convertPrefix(dst, src []byte, isDecoding bool) (nDst, nSrc int) {
+	for nSrc < len(src) && len(dst)-nDst >= utf8.UTFMax {
+		c := src[nSrc]
+		if c < utf8.RuneSelf {
+			dst[nDst] = c
+			nDst++
+			nSrc++
+			continue
+		}
+		if isDecoding {
+			if nSrc+1 >= len(src) {
+				break
+			}
+			r := t.decode[uint16(c)<<8|uint16(src[nSrc+1])]
+			if r == 0 {
+				break
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], rune(r))
+			nSrc += 2
+			continue
+		}
+		// An invalid UTF-8 byte is decoded as utf8.RuneError, which is not in the table.
+		r, w := utf8.DecodeRune(src[nSrc:])
+		if r >= rune(len(t.encode)) || t.encode[r] == 0 {
+			break
+		}
+		dst[nDst], dst[nDst+1] = byte(t.encode[r]>>8), byte(t.encode[r])
+		nDst += 2
+		nSrc += w
+	}
+	return nDst, nSrc
+}
+
+// encodableLen returns the length of the prefix of the UTF-8 string src which can be encoded by the table.
+func (t *doubleByteTable) encodableLen(src []byte) int {
+	n := 0
+	for n < len(src) {
+		if src[n] < utf8.RuneSelf {
+			n++
+			continue
+		}
+		// An invalid UTF-8 byte is decoded as utf8.RuneError, which is not in the table.
+		r, w := utf8.DecodeRune(src[n:])
+		if r >= rune(len(t.encode)) || t.encode[r] == 0 {
+			break
+		}
+		n += w
+	}
+	return n
 }
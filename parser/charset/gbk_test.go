@@ -0,0 +1,133 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package charset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// gbkEncodingByTransformer is GBKEncoding without the table, all the characters are converted by the transformers.
+func gbkEncodingByTransformer() *Encoding {
+	e := *GBKEncoding
+	e.table = nil
+	return &e
+}
+
+func requireSameConversion(t *testing.T, expected, actual []byte, expectedErr, actualErr error, msg string) {
+	require.Equal(t, expected, actual, msg)
+	if expectedErr == nil {
+		require.NoError(t, actualErr, msg)
+	} else {
+		require.EqualError(t, actualErr, expectedErr.Error(), msg)
+	}
+}
+
+func TestGBKTableDecode(t *testing.T) {
+	t.Parallel()
+	require.NotNil(t, GBKEncoding.table)
+	ref := gbkEncodingByTransformer()
+	var all []byte
+	for c := 0; c < 1<<16; c++ {
+		src := []byte{byte(c >> 8), byte(c)}
+		all = append(all, src...)
+		msg := fmt.Sprintf("%X", src)
+		expected, expectedErr := ref.Decode(nil, src)
+		actual, actualErr := GBKEncoding.Decode(nil, src)
+		requireSameConversion(t, expected, actual, expectedErr, actualErr, msg)
+
+		// The two-byte characters at the end or followed by a single-byte one.
+		expected, expectedErr = ref.Decode(nil, append(src, 'a', src[0]))
+		actual, actualErr = GBKEncoding.Decode(nil, append(src, 'a', src[0]))
+		requireSameConversion(t, expected, actual, expectedErr, actualErr, msg)
+	}
+	expected, expectedErr := ref.Decode(nil, all)
+	actual, actualErr := GBKEncoding.Decode(nil, all)
+	requireSameConversion(t, expected, actual, expectedErr, actualErr, "whole two-byte space")
+	expectedStr, expectedErr := ref.DecodeString(string(all))
+	actualStr, actualErr := GBKEncoding.DecodeString(string(all))
+	require.Equal(t, expectedStr, actualStr)
+	require.EqualError(t, actualErr, expectedErr.Error())
+}
+
+func TestGBKTableEncode(t *testing.T) {
+	t.Parallel()
+	ref := gbkEncodingByTransformer()
+	v := StringValidatorOther{Charset: CharsetGBK}
+	var all strings.Builder
+	for r := rune(0); r <= utf8.MaxRune; r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+		all.WriteRune(r)
+		if r >= 1<<16 && r%0x1000 != 0 {
+			// The runes outside the BMP are not in the table, only check some of them one by one.
+			continue
+		}
+		src := []byte(string(r))
+		msg := fmt.Sprintf("%U", r)
+		expected, expectedErr := ref.Encode(nil, src)
+		actual, actualErr := GBKEncoding.Encode(nil, src)
+		requireSameConversion(t, expected, actual, expectedErr, actualErr, msg)
+		require.Equal(t, ref.EncodeInternal(nil, src), GBKEncoding.EncodeInternal(nil, src), msg)
+		expected, expectedErr = ref.EncodeFirstChar(nil, append(src, src...))
+		actual, actualErr = GBKEncoding.EncodeFirstChar(nil, append(src, src...))
+		requireSameConversion(t, expected, actual, expectedErr, actualErr, msg)
+		if expectedErr == nil {
+			require.Equal(t, -1, v.Validate(string(src)), msg)
+		} else {
+			require.Equal(t, 0, v.Validate(string(src)), msg)
+		}
+	}
+	// The invalid UTF-8 bytes.
+	for b := 0x80; b <= 0xFF; b++ {
+		src := []byte{'a', byte(b), 'b'}
+		msg := fmt.Sprintf("%X", src)
+		expected, expectedErr := ref.Encode(nil, src)
+		actual, actualErr := GBKEncoding.Encode(nil, src)
+		requireSameConversion(t, expected, actual, expectedErr, actualErr, msg)
+		require.Equal(t, ref.EncodeInternal(nil, src), GBKEncoding.EncodeInternal(nil, src), msg)
+		require.Equal(t, 1, v.Validate(string(src)), msg)
+	}
+	expected, expectedErr := ref.EncodeString(all.String())
+	actual, actualErr := GBKEncoding.EncodeString(all.String())
+	require.Equal(t, expected, actual)
+	require.EqualError(t, actualErr, expectedErr.Error())
+	require.Equal(t, ref.EncodeInternal(nil, []byte(all.String())), GBKEncoding.EncodeInternal(nil, []byte(all.String())))
+}
+
+func BenchmarkGBKEncoding(b *testing.B) {
+	str := strings.Repeat("中文GBK编码", 64)
+	encoded, err := GBKEncoding.EncodeString(str)
+	require.NoError(b, err)
+	for _, e := range []struct {
+		name string
+		enc  *Encoding
+	}{{"table", GBKEncoding}, {"transformer", gbkEncodingByTransformer()}} {
+		b.Run(e.name+"/encode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = e.enc.EncodeString(str)
+			}
+		})
+		b.Run(e.name+"/decode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = e.enc.DecodeString(encoded)
+			}
+		})
+	}
+}
@@ -25,7 +25,8 @@ var (
 		charLength: func(bytes []byte) int {
 			return 1
 		},
-		specialCase: nil,
+		specialCase:  nil,
+		maxCharWidth: 1,
 	}
 
 	BinaryEncoding = &Encoding{
@@ -34,7 +35,8 @@ var (
 		charLength: func(bytes []byte) int {
 			return 1
 		},
-		specialCase: nil,
+		specialCase:  nil,
+		maxCharWidth: 1,
 	}
 
 	ASCIIEncoding = &Encoding{
@@ -43,6 +45,7 @@ var (
 		charLength: func(bytes []byte) int {
 			return 1
 		},
-		specialCase: nil,
+		specialCase:  nil,
+		maxCharWidth: 1,
 	}
 )
@@ -30,5 +30,6 @@ var UTF8Encoding = &Encoding{
 		}
 		return 4
 	},
-	specialCase: nil,
+	specialCase:  nil,
+	maxCharWidth: 4,
 }
@@ -329,6 +329,7 @@ var tokenMap = map[string]int{
 	"EXPR_PUSHDOWN_BLACKLIST":  exprPushdownBlacklist,
 	"EXTENDED":                 extended,
 	"EXTRACT":                  extract,
+	"FAILED_LOGIN_ATTEMPTS":    failedLoginAttempts,
 	"FALSE":                    falseKwd,
 	"FAULTS":                   faultsSym,
 	"FETCH":                    fetch,
@@ -526,6 +527,7 @@ var tokenMap = map[string]int{
 	"PARTITIONING":             partitioning,
 	"PARTITIONS":               partitions,
 	"PASSWORD":                 password,
+	"PASSWORD_LOCK_TIME":       passwordLockTime,
 	"PERCENT":                  percent,
 	"PER_DB":                   per_db,
 	"PER_TABLE":                per_table,
@@ -698,6 +700,7 @@ var tokenMap = map[string]int{
 	"SWITCHES":                 switchesSym,
 	"SYSTEM":                   system,
 	"SYSTEM_TIME":              systemTime,
+	"SYSTEM_TZ":                systemTZ,
 	"TARGET":                   target,
 	"TABLE_CHECKSUM":           tableChecksum,
 	"TABLE":                    tableKwd,
@@ -45,6 +45,9 @@ const (
 // Protocol Features
 const AuthSwitchRequest byte = 0xfe
 
+// AuthMoreData is the header of the packet carrying extra authentication data, such as the RSA public key.
+const AuthMoreData byte = 0x01
+
 // Server information.
 const (
 	ServerStatusInTrans            uint16 = 0x0001
@@ -155,6 +158,7 @@ const (
 	ClientPluginAuth
 	ClientConnectAtts
 	ClientPluginAuthLenencClientData
+	ClientCanHandleExpiredPasswords
 )
 
 // Cache type information.
@@ -166,7 +170,9 @@ const (
 const (
 	AuthNativePassword      = "mysql_native_password"
 	AuthCachingSha2Password = "caching_sha2_password"
+	AuthSha256Password      = "sha256_password"
 	AuthSocket              = "auth_socket"
+	AuthClearPassword       = "mysql_clear_password"
 )
 
 // MySQL database and tables.
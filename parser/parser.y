@@ -393,6 +393,7 @@ import (
 	expansion             "EXPANSION"
 	expire                "EXPIRE"
 	extended              "EXTENDED"
+	failedLoginAttempts   "FAILED_LOGIN_ATTEMPTS"
 	faultsSym             "FAULTS"
 	fields                "FIELDS"
 	file                  "FILE"
@@ -490,6 +491,7 @@ import (
 	partitioning          "PARTITIONING"
 	partitions            "PARTITIONS"
 	password              "PASSWORD"
+	passwordLockTime      "PASSWORD_LOCK_TIME"
 	percent               "PERCENT"
 	per_db                "PER_DB"
 	per_table             "PER_TABLE"
@@ -588,6 +590,7 @@ import (
 	switchesSym           "SWITCHES"
 	system                "SYSTEM"
 	systemTime            "SYSTEM_TIME"
+	systemTZ              "SYSTEM_TZ"
 	tableChecksum         "TABLE_CHECKSUM"
 	tables                "TABLES"
 	tablespace            "TABLESPACE"
@@ -929,6 +932,7 @@ import (
 	SetOprStmtWoutLimitOrderBy "Union/Except/Intersect select statement without limit and order by"
 	UseStmt                    "USE statement"
 	ShutdownStmt               "SHUTDOWN statement"
+	ResetMasterStmt            "RESET MASTER statement"
 	RestartStmt                "RESTART statement"
 	CreateViewSelectOpt        "Select/Union/Except/Intersect statement in CREATE VIEW ... AS SELECT"
 	BindableStmt               "Statement that can be created binding on"
@@ -5930,6 +5934,8 @@ UnReservedKeyword:
 |	"NEVER"
 |	"EXPIRE"
 |	"ACCOUNT"
+|	"FAILED_LOGIN_ATTEMPTS"
+|	"PASSWORD_LOCK_TIME"
 |	"INCREMENTAL"
 |	"CPU"
 |	"MEMORY"
@@ -5950,6 +5956,7 @@ UnReservedKeyword:
 |	"LIST"
 |	"NODEGROUP"
 |	"SYSTEM_TIME"
+|	"SYSTEM_TZ"
 |	"PARTIAL"
 |	"SIMPLE"
 |	"REMOVE"
@@ -8035,6 +8042,12 @@ ShutdownStmt:
 		$$ = &ast.ShutdownStmt{}
 	}
 
+ResetMasterStmt:
+	"RESET" "MASTER"
+	{
+		$$ = &ast.ResetMasterStmt{}
+	}
+
 RestartStmt:
 	"RESTART"
 	{
@@ -10136,6 +10149,19 @@ AdminStmt:
 			Tp: ast.AdminReloadStatistics,
 		}
 	}
+|	"ADMIN" "RELOAD" "SYSTEM_TZ"
+	{
+		$$ = &ast.AdminStmt{
+			Tp: ast.AdminReloadSystemTZ,
+		}
+	}
+|	"ADMIN" "RELOAD" "SYSTEM_TZ" "FORCE"
+	{
+		$$ = &ast.AdminStmt{
+			Tp:    ast.AdminReloadSystemTZ,
+			Force: true,
+		}
+	}
 |	"ADMIN" "SHOW" "TELEMETRY"
 	{
 		$$ = &ast.AdminStmt{
@@ -10333,6 +10359,13 @@ ShowStmt:
 			Tp: ast.ShowMasterStatus,
 		}
 	}
+|	"SHOW" "ENGINE" Identifier "STATUS"
+	{
+		$$ = &ast.ShowStmt{
+			Tp:     ast.ShowEngineStatus,
+			Engine: $3,
+		}
+	}
 |	"SHOW" OptFull "PROCESSLIST"
 	{
 		$$ = &ast.ShowStmt{
@@ -10560,13 +10593,21 @@ ShowTargetFilterable:
 			Extended: true,
 		}
 	}
-|	"WARNINGS"
+|	"WARNINGS" SelectStmtLimitOpt
 	{
-		$$ = &ast.ShowStmt{Tp: ast.ShowWarnings}
+		v := &ast.ShowStmt{Tp: ast.ShowWarnings}
+		if $2 != nil {
+			v.Limit = $2.(*ast.Limit)
+		}
+		$$ = v
 	}
-|	"ERRORS"
+|	"ERRORS" SelectStmtLimitOpt
 	{
-		$$ = &ast.ShowStmt{Tp: ast.ShowErrors}
+		v := &ast.ShowStmt{Tp: ast.ShowErrors}
+		if $2 != nil {
+			v.Limit = $2.(*ast.Limit)
+		}
+		$$ = v
 	}
 |	GlobalScope "VARIABLES"
 	{
@@ -10982,6 +11023,7 @@ Statement:
 |	LockTablesStmt
 |	ShutdownStmt
 |	RestartStmt
+|	ResetMasterStmt
 |	HelpStmt
 
 TraceableStmt:
@@ -12436,6 +12478,26 @@ PasswordOrLockOption:
 			Type: ast.PasswordExpireDefault,
 		}
 	}
+|	"FAILED_LOGIN_ATTEMPTS" Int64Num
+	{
+		$$ = &ast.PasswordOrLockOption{
+			Type:  ast.FailedLoginAttempts,
+			Count: $2.(int64),
+		}
+	}
+|	"PASSWORD_LOCK_TIME" Int64Num
+	{
+		$$ = &ast.PasswordOrLockOption{
+			Type:  ast.PasswordLockTime,
+			Count: $2.(int64),
+		}
+	}
+|	"PASSWORD_LOCK_TIME" "UNBOUNDED"
+	{
+		$$ = &ast.PasswordOrLockOption{
+			Type: ast.PasswordLockTimeUnbounded,
+		}
+	}
 
 PasswordExpire:
 	"PASSWORD" "EXPIRE" ClearPasswordExpireOptions
@@ -13319,6 +13381,15 @@ KillStmt:
 			TiDBExtension: $1.(bool),
 		}
 	}
+|	KillOrKillTiDB "QUERY" NUM "WAIT" NUM
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID:  getUint64FromNUM($3),
+			Query:         true,
+			TiDBExtension: $1.(bool),
+			WaitSeconds:   getUint64FromNUM($5),
+		}
+	}
 
 KillOrKillTiDB:
 	"KILL"
@@ -815,6 +815,8 @@ func TestDMLStmt(t *testing.T) {
 		// This case would be removed once TiDB PR to remove ADMIN RELOAD STATISTICS is merged.
 		{"admin reload statistics", true, "ADMIN RELOAD STATS_EXTENDED"},
 		{"admin reload stats_extended", true, "ADMIN RELOAD STATS_EXTENDED"},
+		{"admin reload system_tz", true, "ADMIN RELOAD SYSTEM_TZ"},
+		{"admin reload system_tz force", true, "ADMIN RELOAD SYSTEM_TZ FORCE"},
 
 		// for on duplicate key update
 		{"INSERT INTO t (a,b,c) VALUES (1,2,3),(4,5,6) ON DUPLICATE KEY UPDATE c=VALUES(a)+VALUES(b);", true, "INSERT INTO `t` (`a`,`b`,`c`) VALUES (1,2,3),(4,5,6) ON DUPLICATE KEY UPDATE `c`=VALUES(`a`)+VALUES(`b`)"},
@@ -1079,7 +1081,18 @@ func TestDBAStmt(t *testing.T) {
 		{`SHOW PROFILE CPU FOR QUERY 2`, true, "SHOW PROFILE CPU FOR QUERY 2"},
 		{`SHOW PROFILE CPU FOR QUERY 2 LIMIT 1,1`, true, "SHOW PROFILE CPU FOR QUERY 2 LIMIT 1,1"},
 		{`SHOW PROFILE CPU, MEMORY, BLOCK IO, CONTEXT SWITCHES, PAGE FAULTS, IPC, SWAPS, SOURCE FOR QUERY 1 limit 100`, true, "SHOW PROFILE CPU, MEMORY, BLOCK IO, CONTEXT SWITCHES, PAGE FAULTS, IPC, SWAPS, SOURCE FOR QUERY 1 LIMIT 100"},
+		{`SHOW WARNINGS`, true, "SHOW WARNINGS"},
+		{`SHOW WARNINGS LIMIT 10`, true, "SHOW WARNINGS LIMIT 10"},
+		{`SHOW WARNINGS LIMIT 5, 10`, true, "SHOW WARNINGS LIMIT 5,10"},
+		{`SHOW WARNINGS LIMIT 10 OFFSET 5`, true, "SHOW WARNINGS LIMIT 5,10"},
+		{`SHOW ERRORS`, true, "SHOW ERRORS"},
+		{`SHOW ERRORS LIMIT 1`, true, "SHOW ERRORS LIMIT 1"},
+		{`SHOW ERRORS LIMIT 1, 2`, true, "SHOW ERRORS LIMIT 1,2"},
+		{`SHOW WARNINGS LIMIT`, false, ""},
 		{`SHOW MASTER STATUS`, true, "SHOW MASTER STATUS"},
+		{`SHOW ENGINE INNODB STATUS`, true, "SHOW ENGINE `INNODB` STATUS"},
+		{`show engine innodb status`, true, "SHOW ENGINE `innodb` STATUS"},
+		{`SHOW ENGINE STATUS`, false, ""},
 		{`SHOW PRIVILEGES`, true, "SHOW PRIVILEGES"},
 		// for show character set
 		{"show character set;", true, "SHOW CHARSET"},
@@ -4152,6 +4165,9 @@ func TestPrivilege(t *testing.T) {
 		{"create user 'test@localhost' password expire never;", true, "CREATE USER `test@localhost`@`%` PASSWORD EXPIRE NEVER"},
 		{"create user 'test@localhost' password expire default;", true, "CREATE USER `test@localhost`@`%` PASSWORD EXPIRE DEFAULT"},
 		{"create user 'test@localhost' password expire interval 3 day;", true, "CREATE USER `test@localhost`@`%` PASSWORD EXPIRE INTERVAL 3 DAY"},
+		{"create user 'u1'@'%' failed_login_attempts 3 password_lock_time 2;", true, "CREATE USER `u1`@`%` FAILED_LOGIN_ATTEMPTS 3 PASSWORD_LOCK_TIME 2"},
+		{"create user 'u1'@'%' failed_login_attempts 3 password_lock_time unbounded;", true, "CREATE USER `u1`@`%` FAILED_LOGIN_ATTEMPTS 3 PASSWORD_LOCK_TIME UNBOUNDED"},
+		{"create user 'u1'@'%' password_lock_time -1;", false, ""},
 		{"CREATE USER 'sha_test'@'localhost' IDENTIFIED WITH 'caching_sha2_password' BY 'sha_test'", true, "CREATE USER `sha_test`@`localhost` IDENTIFIED WITH 'caching_sha2_password' BY 'sha_test'"},
 		{"CREATE USER 'sha_test3'@'localhost' IDENTIFIED WITH 'caching_sha2_password' AS 0x24412430303524255B03496C662C1055127B3B654A2F04207D01485276703644704B76303247474564416A516662346C5868646D32764C6B514F43585A473779565947514F34", true, "CREATE USER `sha_test3`@`localhost` IDENTIFIED WITH 'caching_sha2_password' AS '$A$005$%[\x03Ilf,\x10U\x12{;eJ/\x04 }\x01HRvp6DpKv02GGEdAjQfb4lXhdm2vLkQOCXZG7yVYGQO4'"},
 		{"CREATE USER 'sha_test4'@'localhost' IDENTIFIED WITH 'caching_sha2_password' AS '$A$005$%[\x03Ilf,\x10U\x12{;eJ/\x04 }\x01HRvp6DpKv02GGEdAjQfb4lXhdm2vLkQOCXZG7yVYGQO4'", true, "CREATE USER `sha_test4`@`localhost` IDENTIFIED WITH 'caching_sha2_password' AS '$A$005$%[\x03Ilf,\x10U\x12{;eJ/\x04 }\x01HRvp6DpKv02GGEdAjQfb4lXhdm2vLkQOCXZG7yVYGQO4'"},
@@ -4178,6 +4194,8 @@ func TestPrivilege(t *testing.T) {
 		{"alter user 'test@localhost' password expire never;", true, "ALTER USER `test@localhost`@`%` PASSWORD EXPIRE NEVER"},
 		{"alter user 'test@localhost' password expire default;", true, "ALTER USER `test@localhost`@`%` PASSWORD EXPIRE DEFAULT"},
 		{"alter user 'test@localhost' password expire interval 3 day;", true, "ALTER USER `test@localhost`@`%` PASSWORD EXPIRE INTERVAL 3 DAY"},
+		{"alter user 'u1'@'%' failed_login_attempts 0 password_lock_time 0;", true, "ALTER USER `u1`@`%` FAILED_LOGIN_ATTEMPTS 0 PASSWORD_LOCK_TIME 0"},
+		{"alter user 'u1'@'%' password_lock_time unbounded;", true, "ALTER USER `u1`@`%` PASSWORD_LOCK_TIME UNBOUNDED"},
 		{"ALTER USER 'ttt' REQUIRE X509;", true, "ALTER USER `ttt`@`%` REQUIRE X509"},
 		{"ALTER USER 'ttt' REQUIRE SSL;", true, "ALTER USER `ttt`@`%` REQUIRE SSL"},
 		{"ALTER USER 'ttt' REQUIRE NONE;", true, "ALTER USER `ttt`@`%` REQUIRE NONE"},
@@ -5038,10 +5056,17 @@ func TestSessionManage(t *testing.T) {
 		{"kill tidb 23123", true, "KILL TIDB 23123"},
 		{"kill tidb connection 23123", true, "KILL TIDB 23123"},
 		{"kill tidb query 23123", true, "KILL TIDB QUERY 23123"},
+		{"kill tidb query 23123 wait 5", true, "KILL TIDB QUERY 23123 WAIT 5"},
+		{"kill query 23123 wait 5", true, "KILL QUERY 23123 WAIT 5"},
+		{"kill tidb query 23123 wait", false, ""},
+		{"kill tidb 23123 wait 5", false, ""},
+		{"kill tidb connection 23123 wait 5", false, ""},
 		{"show processlist", true, "SHOW PROCESSLIST"},
 		{"show full processlist", true, "SHOW FULL PROCESSLIST"},
 		{"shutdown", true, "SHUTDOWN"},
 		{"restart", true, "RESTART"},
+		{"reset master", true, "RESET MASTER"},
+		{"reset", false, ""},
 	}
 	RunTest(t, table, false)
 }
@@ -177,6 +177,8 @@ type PSTMTPlanCacheValue struct {
 	OutPutNames       []*types.FieldName
 	TblInfo2UnionScan map[*model.TableInfo]bool
 	UserVarTypes      FieldSlice
+
+	stats *planCacheEntryStats
 }
 
 // NewPSTMTPlanCacheValue creates a SQLCacheValue.
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/config"
@@ -454,10 +455,13 @@ func (e *Execute) getPhysicalPlan(ctx context.Context, sctx sessionctx.Context,
 						planValid = false
 						// TODO we can inject UnionScan into cached plan to avoid invalidating it, though
 						// rebuilding the filters in UnionScan is pretty trivial.
-						sctx.PreparedPlanCache().Delete(cacheKey)
+						DeletePreparedPlanCache(sctx, cacheKey)
 						break
 					}
 				}
+				if planValid && dropStalePreparedPlanCache(sctx, cachedVal) {
+					break
+				}
 				if planValid {
 					err := e.rebuildRange(cachedVal.Plan)
 					if err != nil {
@@ -473,6 +477,9 @@ func (e *Execute) getPhysicalPlan(ctx context.Context, sctx sessionctx.Context,
 					} else {
 						planCacheCounter.Inc()
 					}
+					if cachedVal.stats != nil {
+						atomic.AddUint64(&cachedVal.stats.hits, 1)
+					}
 					e.names = cachedVal.OutPutNames
 					e.Plan = cachedVal.Plan
 					stmtCtx.SetPlanDigest(preparedStmt.NormalizedPlan, preparedStmt.PlanDigest)
@@ -484,6 +491,9 @@ func (e *Execute) getPhysicalPlan(ctx context.Context, sctx sessionctx.Context,
 	}
 
 REBUILD:
+	if prepared.UseCache {
+		planCacheMissCounter.Inc()
+	}
 	stmt := prepared.Stmt
 	p, names, err := OptimizeAstNode(ctx, sctx, stmt, is)
 	if err != nil {
@@ -506,6 +516,7 @@ REBUILD:
 		cached := NewPSTMTPlanCacheValue(p, names, stmtCtx.TblInfo2UnionScan, tps)
 		preparedStmt.NormalizedPlan, preparedStmt.PlanDigest = NormalizePlan(p)
 		stmtCtx.SetPlanDigest(preparedStmt.NormalizedPlan, preparedStmt.PlanDigest)
+		cached.stats = newPlanCacheEntryStats(cacheKey, preparedStmt)
 		if cacheVals, exists := sctx.PreparedPlanCache().Get(cacheKey); exists {
 			hitVal := false
 			for i, cacheVal := range cacheVals.([]*PSTMTPlanCacheValue) {
@@ -518,9 +529,9 @@ REBUILD:
 			if !hitVal {
 				cacheVals = append(cacheVals.([]*PSTMTPlanCacheValue), cached)
 			}
-			sctx.PreparedPlanCache().Put(cacheKey, cacheVals)
+			putPreparedPlanCache(sctx, cacheKey, cacheVals.([]*PSTMTPlanCacheValue))
 		} else {
-			sctx.PreparedPlanCache().Put(cacheKey, []*PSTMTPlanCacheValue{cached})
+			putPreparedPlanCache(sctx, cacheKey, []*PSTMTPlanCacheValue{cached})
 		}
 	}
 	err = e.setFoundInPlanCache(sctx, false)
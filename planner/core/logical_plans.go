@@ -1253,6 +1253,7 @@ type ShowContents struct {
 	Flag      int                  // Some flag parsed from sql, such as FULL.
 	User      *auth.UserIdentity   // Used for show grants.
 	Roles     []*auth.RoleIdentity // Used for show grants.
+	Engine    string               // Used for show engine status.
 
 	Full        bool
 	IfNotExists bool // Used for `show create database if not exists`.
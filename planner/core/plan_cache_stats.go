@@ -0,0 +1,244 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/util/kvcache"
+)
+
+var (
+	planCacheMissCounter     = metrics.PlanCacheMissCounter.WithLabelValues("prepare")
+	planCacheEvictionCounter = metrics.PlanCacheEvictionCounter.WithLabelValues("prepare")
+	planCacheMemoryUsage     = metrics.PlanCacheMemoryUsage.WithLabelValues("prepare")
+)
+
+// planCacheEntryStats is the statistics of a cached plan. It is referenced by the PSTMTPlanCacheValue,
+// so the hits can be counted without looking up the registry.
+type planCacheEntryStats struct {
+	sqlDigest     string
+	normalizedSQL string
+	schemaVersion int64
+	memoryUsage   int64
+	// epoch is the epoch of the registry when the plan is cached, the plan is stale if the
+	// prepared plan caches are cleared after it.
+	epoch uint64
+	hits  uint64
+}
+
+// planCacheRegistry tracks the plans in the prepared plan caches of all sessions. The caches are
+// not thread-safe, so they are only accessed by their sessions, and the registry keeps a copy of
+// the statistics for reporting them to the other goroutines.
+type planCacheRegistry struct {
+	sync.Mutex
+	epoch uint64
+	// caches maps the hash of the cache keys to the statistics of the values for each cache.
+	caches      map[*kvcache.SimpleLRUCache]map[string][]*planCacheEntryStats
+	memoryUsage int64
+}
+
+var globalPlanCacheRegistry = &planCacheRegistry{
+	caches: make(map[*kvcache.SimpleLRUCache]map[string][]*planCacheEntryStats),
+}
+
+func (r *planCacheRegistry) currentEpoch() uint64 {
+	return atomic.LoadUint64(&r.epoch)
+}
+
+func (r *planCacheRegistry) put(cache *kvcache.SimpleLRUCache, key kvcache.Key, values []*PSTMTPlanCacheValue) {
+	r.Lock()
+	defer r.Unlock()
+	entries := r.caches[cache]
+	if entries == nil {
+		entries = make(map[string][]*planCacheEntryStats)
+		r.caches[cache] = entries
+	}
+	hash := string(key.Hash())
+	r.releaseLocked(entries[hash])
+	stats := make([]*planCacheEntryStats, 0, len(values))
+	for _, value := range values {
+		if value.stats != nil && value.stats.epoch == r.epoch {
+			stats = append(stats, value.stats)
+			r.memoryUsage += value.stats.memoryUsage
+		}
+	}
+	entries[hash] = stats
+	planCacheMemoryUsage.Set(float64(r.memoryUsage))
+}
+
+func (r *planCacheRegistry) delete(cache *kvcache.SimpleLRUCache, key kvcache.Key) {
+	r.Lock()
+	defer r.Unlock()
+	entries := r.caches[cache]
+	if entries == nil {
+		return
+	}
+	hash := string(key.Hash())
+	r.releaseLocked(entries[hash])
+	delete(entries, hash)
+	planCacheMemoryUsage.Set(float64(r.memoryUsage))
+}
+
+func (r *planCacheRegistry) deleteCache(cache *kvcache.SimpleLRUCache) {
+	r.Lock()
+	defer r.Unlock()
+	for _, stats := range r.caches[cache] {
+		r.releaseLocked(stats)
+	}
+	delete(r.caches, cache)
+	planCacheMemoryUsage.Set(float64(r.memoryUsage))
+}
+
+func (r *planCacheRegistry) releaseLocked(stats []*planCacheEntryStats) {
+	for _, s := range stats {
+		r.memoryUsage -= s.memoryUsage
+	}
+}
+
+func (r *planCacheRegistry) clear() {
+	r.Lock()
+	defer r.Unlock()
+	atomic.AddUint64(&r.epoch, 1)
+	r.caches = make(map[*kvcache.SimpleLRUCache]map[string][]*planCacheEntryStats)
+	r.memoryUsage = 0
+	planCacheMemoryUsage.Set(0)
+}
+
+// PlanCacheStats is the statistics of the plans cached for a statement in the prepared plan caches
+// of all sessions. It only contains the normalized SQL, the parameters are never included.
+type PlanCacheStats struct {
+	SQLDigest     string `json:"sql_digest"`
+	NormalizedSQL string `json:"normalized_sql"`
+	SchemaVersion int64  `json:"schema_version"`
+	// Plans is the number of the cached plans, a statement may have several plans in a session
+	// because of the different parameter types, and a plan in each session.
+	Plans int `json:"plans"`
+	// MemoryUsage is estimated by the size of the normalized plans and the cache keys.
+	MemoryUsage int64  `json:"memory_usage"`
+	Hits        uint64 `json:"hits"`
+}
+
+// GetPreparedPlanCacheStats returns the statistics of the prepared plan caches of all sessions,
+// aggregated by the SQL digest and the schema version.
+func GetPreparedPlanCacheStats() []PlanCacheStats {
+	type statsKey struct {
+		sqlDigest     string
+		schemaVersion int64
+	}
+	aggregated := make(map[statsKey]*PlanCacheStats)
+	r := globalPlanCacheRegistry
+	r.Lock()
+	for _, entries := range r.caches {
+		for _, stats := range entries {
+			for _, s := range stats {
+				key := statsKey{s.sqlDigest, s.schemaVersion}
+				item := aggregated[key]
+				if item == nil {
+					item = &PlanCacheStats{
+						SQLDigest:     s.sqlDigest,
+						NormalizedSQL: s.normalizedSQL,
+						SchemaVersion: s.schemaVersion,
+					}
+					aggregated[key] = item
+				}
+				item.Plans++
+				item.MemoryUsage += s.memoryUsage
+				item.Hits += atomic.LoadUint64(&s.hits)
+			}
+		}
+	}
+	r.Unlock()
+
+	result := make([]PlanCacheStats, 0, len(aggregated))
+	for _, item := range aggregated {
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].MemoryUsage != result[j].MemoryUsage {
+			return result[i].MemoryUsage > result[j].MemoryUsage
+		}
+		if result[i].SQLDigest != result[j].SQLDigest {
+			return result[i].SQLDigest < result[j].SQLDigest
+		}
+		return result[i].SchemaVersion < result[j].SchemaVersion
+	})
+	return result
+}
+
+// ClearPreparedPlanCaches clears the prepared plan caches of all sessions. The caches are owned by
+// their sessions, so the plans are only marked as stale here, and each session drops its cached
+// plans when it finds a stale one.
+func ClearPreparedPlanCaches() {
+	globalPlanCacheRegistry.clear()
+}
+
+// NewPreparedPlanCache creates the prepared plan cache of a session, whose evictions are tracked.
+func NewPreparedPlanCache(capacity uint, guard float64, quota uint64) *kvcache.SimpleLRUCache {
+	cache := kvcache.NewSimpleLRUCache(capacity, guard, quota)
+	cache.SetOnEvict(func(key kvcache.Key, _ kvcache.Value) {
+		planCacheEvictionCounter.Inc()
+		globalPlanCacheRegistry.delete(cache, key)
+	})
+	return cache
+}
+
+// ReleasePreparedPlanCache stops tracking the prepared plan cache when the session is closed.
+func ReleasePreparedPlanCache(cache *kvcache.SimpleLRUCache) {
+	if cache != nil {
+		globalPlanCacheRegistry.deleteCache(cache)
+	}
+}
+
+// DeletePreparedPlanCache deletes the plans of the key from the prepared plan cache of the session.
+func DeletePreparedPlanCache(sctx sessionctx.Context, key kvcache.Key) {
+	cache := sctx.PreparedPlanCache()
+	cache.Delete(key)
+	globalPlanCacheRegistry.delete(cache, key)
+}
+
+func putPreparedPlanCache(sctx sessionctx.Context, key kvcache.Key, values []*PSTMTPlanCacheValue) {
+	cache := sctx.PreparedPlanCache()
+	cache.Put(key, values)
+	globalPlanCacheRegistry.put(cache, key, values)
+}
+
+// dropStalePreparedPlanCache drops all the plans of the session if they are cleared by ClearPreparedPlanCaches.
+func dropStalePreparedPlanCache(sctx sessionctx.Context, value *PSTMTPlanCacheValue) bool {
+	if value.stats == nil || value.stats.epoch == globalPlanCacheRegistry.currentEpoch() {
+		return false
+	}
+	cache := sctx.PreparedPlanCache()
+	cache.DeleteAll()
+	globalPlanCacheRegistry.deleteCache(cache)
+	return true
+}
+
+func newPlanCacheEntryStats(key kvcache.Key, stmt *CachedPrepareStmt) *planCacheEntryStats {
+	stats := &planCacheEntryStats{
+		normalizedSQL: stmt.NormalizedSQL,
+		schemaVersion: stmt.PreparedAst.SchemaVersion,
+		memoryUsage:   int64(len(key.Hash()) + len(stmt.NormalizedPlan)),
+		epoch:         globalPlanCacheRegistry.currentEpoch(),
+	}
+	if stmt.SQLDigest != nil {
+		stats.sqlDigest = stmt.SQLDigest.String()
+	}
+	return stats
+}
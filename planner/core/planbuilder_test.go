@@ -71,6 +71,7 @@ func (s *testPlanBuilderSuite) TestShow(c *C) {
 		ast.ShowCreateDatabase,
 		ast.ShowEvents,
 		ast.ShowMasterStatus,
+		ast.ShowEngineStatus,
 		ast.ShowBackups,
 		ast.ShowRestores,
 	}
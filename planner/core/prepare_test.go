@@ -16,6 +16,7 @@ package core_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -2005,6 +2006,94 @@ func (s *testPrepareSerialSuite) TestIssue30100(c *C) {
 	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
 }
 
+func (s *testPrepareSerialSuite) TestPreparedPlanCacheStats(c *C) {
+	defer testleak.AfterTest(c)()
+	store, dom, err := newStoreWithBootstrap()
+	c.Assert(err, IsNil)
+	tk := testkit.NewTestKit(c, store)
+	orgEnable := core.PreparedPlanCacheEnabled()
+	defer func() {
+		dom.Close()
+		err = store.Close()
+		c.Assert(err, IsNil)
+		core.SetPreparedPlanCache(orgEnable)
+	}()
+	core.SetPreparedPlanCache(true)
+	tk.Se, err = session.CreateSession4TestWithOpt(store, &session.Opt{
+		PreparedPlanCache: core.NewPreparedPlanCache(2, 0.1, math.MaxUint64),
+	})
+	c.Assert(err, IsNil)
+
+	statsOf := func(table string) []core.PlanCacheStats {
+		var result []core.PlanCacheStats
+		for _, stats := range core.GetPreparedPlanCacheStats() {
+			if strings.Contains(stats.NormalizedSQL, table) {
+				result = append(result, stats)
+			}
+		}
+		return result
+	}
+	readCounter := func(counter prometheus.Counter) float64 {
+		pb := &dto.Metric{}
+		c.Assert(counter.Write(pb), IsNil)
+		return pb.GetCounter().GetValue()
+	}
+	missCounter := metrics.PlanCacheMissCounter.WithLabelValues("prepare")
+	evictionCounter := metrics.PlanCacheEvictionCounter.WithLabelValues("prepare")
+
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t_stats_1, t_stats_2, t_stats_3")
+	tk.MustExec("create table t_stats_1(a int, b int)")
+	tk.MustExec("create table t_stats_2(a int, b int)")
+	tk.MustExec("create table t_stats_3(a int, b int)")
+	tk.MustExec(`prepare stmt1 from "select * from t_stats_1 where b > ?"`)
+	tk.MustExec("set @a=12345")
+	misses := readCounter(missCounter)
+	tk.MustQuery("execute stmt1 using @a").Check(testkit.Rows())
+	tk.MustQuery("execute stmt1 using @a").Check(testkit.Rows())
+	tk.MustExec("set @a=67890")
+	tk.MustQuery("execute stmt1 using @a").Check(testkit.Rows())
+	c.Assert(readCounter(missCounter), Equals, misses+1)
+
+	stats := statsOf("t_stats_1")
+	c.Assert(stats, HasLen, 1)
+	c.Assert(stats[0].NormalizedSQL, Equals, "select * from `t_stats_1` where `b` > ?")
+	c.Assert(stats[0].SQLDigest, Not(Equals), "")
+	c.Assert(stats[0].SchemaVersion, Equals, dom.InfoSchema().SchemaMetaVersion())
+	c.Assert(stats[0].Plans, Equals, 1)
+	c.Assert(stats[0].MemoryUsage > 0, IsTrue)
+	c.Assert(stats[0].Hits, Equals, uint64(2))
+	// The parameters are never included.
+	data, err := json.Marshal(stats)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Not(Matches), ".*(12345|67890).*")
+
+	// The capacity is 2, so stmt1 is evicted.
+	evictions := readCounter(evictionCounter)
+	tk.MustExec(`prepare stmt2 from "select * from t_stats_2 where b > ?"`)
+	tk.MustExec(`prepare stmt3 from "select * from t_stats_3 where b > ?"`)
+	tk.MustQuery("execute stmt2 using @a").Check(testkit.Rows())
+	tk.MustQuery("execute stmt3 using @a").Check(testkit.Rows())
+	c.Assert(readCounter(evictionCounter), Equals, evictions+1)
+	c.Assert(statsOf("t_stats_1"), HasLen, 0)
+	c.Assert(statsOf("t_stats_2"), HasLen, 1)
+	c.Assert(statsOf("t_stats_3"), HasLen, 1)
+
+	// The cleared plans are not used anymore.
+	core.ClearPreparedPlanCaches()
+	c.Assert(statsOf("t_stats_2"), HasLen, 0)
+	c.Assert(statsOf("t_stats_3"), HasLen, 0)
+	tk.MustQuery("execute stmt2 using @a").Check(testkit.Rows())
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("execute stmt2 using @a").Check(testkit.Rows())
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	c.Assert(statsOf("t_stats_2"), HasLen, 1)
+
+	// The plans are not tracked after the session is closed.
+	tk.Se.Close()
+	c.Assert(statsOf("t_stats_2"), HasLen, 0)
+}
+
 func (s *testPlanSerialSuite) TestPartitionTable(c *C) {
 	if israce.RaceEnabled {
 		c.Skip("exhaustive types test, skip race test")
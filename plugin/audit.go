@@ -97,3 +97,9 @@ type execStartTimeCtxKeyType struct{}
 
 // ExecStartTimeCtxKey indicates stmt start execution time.
 var ExecStartTimeCtxKey = execStartTimeCtxKeyType{}
+
+type queryTextCtxKeyType struct{}
+
+// QueryTextCtxKey indicates the text of the stmt, in which the bytes that are not valid UTF-8 are
+// hex-escaped unless log.keep-raw-binary-bytes is set.
+var QueryTextCtxKey = queryTextCtxKeyType{}
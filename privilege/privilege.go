@@ -62,6 +62,31 @@ type Manager interface {
 	// Requires exact match on user name and host name.
 	ConnectionVerification(user, host string, auth, salt []byte, tlsState *tls.ConnectionState) bool
 
+	// PluginConnectionVerification verifies user privilege for connection like ConnectionVerification,
+	// but the credentials of the account of an external auth plugin are verified by authenticate.
+	// Requires exact match on user name and host name.
+	PluginConnectionVerification(user, host string, authenticate func() bool, tlsState *tls.ConnectionState) bool
+
+	// IsPasswordExpired returns whether the password of the account is expired.
+	// Requires exact match on user name and host name.
+	IsPasswordExpired(user, host string) bool
+
+	// IsAccountLocked returns whether the account is locked by ACCOUNT LOCK.
+	// Requires exact match on user name and host name.
+	IsAccountLocked(user, host string) bool
+
+	// IsVerifiedAccountLocked returns whether the last ConnectionVerification rejected the account only
+	// because it is locked by ACCOUNT LOCK, after its credentials were verified.
+	IsVerifiedAccountLocked() bool
+
+	// GetMaxUserConnections returns the MAX_USER_CONNECTIONS of the account, 0 means unlimited.
+	// Requires exact match on user name and host name.
+	GetMaxUserConnections(user, host string) int64
+
+	// ProxyAs switches the privileges of the current user to the proxied account, if the
+	// current user has the PROXY privilege on it. Requires exact match on user name and host name.
+	ProxyAs(proxiedUser, proxiedHost string) bool
+
 	// GetAuthWithoutVerification uses to get auth name without verification.
 	// Requires exact match on user name and host name.
 	GetAuthWithoutVerification(user, host string) bool
@@ -58,6 +58,7 @@ const (
 	sqlLoadTablePrivTable   = "SELECT HIGH_PRIORITY Host,DB,User,Table_name,Grantor,Timestamp,Table_priv,Column_priv FROM mysql.tables_priv"
 	sqlLoadColumnsPrivTable = "SELECT HIGH_PRIORITY Host,DB,User,Table_name,Column_name,Timestamp,Column_priv FROM mysql.columns_priv"
 	sqlLoadDefaultRoles     = "SELECT HIGH_PRIORITY HOST, USER, DEFAULT_ROLE_HOST, DEFAULT_ROLE_USER FROM mysql.default_roles"
+	sqlLoadProxiesPrivTable = "SELECT HIGH_PRIORITY Host,User,Proxied_host,Proxied_user,With_grant FROM mysql.proxies_priv"
 	// list of privileges from mysql.Priv2UserCol
 	sqlLoadUserTable = `SELECT HIGH_PRIORITY Host,User,authentication_string,
 	Create_priv, Select_priv, Insert_priv, Update_priv, Delete_priv, Show_db_priv, Super_priv,
@@ -65,7 +66,7 @@ const (
 	References_priv,Alter_priv,Execute_priv,Index_priv,Create_view_priv,Show_view_priv,
 	Create_role_priv,Drop_role_priv,Create_tmp_table_priv,Lock_tables_priv,Create_routine_priv,
 	Alter_routine_priv,Event_priv,Shutdown_priv,Reload_priv,File_priv,Config_priv,Repl_client_priv,Repl_slave_priv,
-	account_locked,plugin FROM mysql.user`
+	account_locked,plugin%s FROM mysql.user`
 	sqlLoadGlobalGrantsTable = `SELECT HIGH_PRIORITY Host,User,Priv,With_Grant_Option FROM mysql.global_grants`
 )
 
@@ -97,8 +98,33 @@ type UserRecord struct {
 
 	AuthenticationString string
 	Privileges           mysql.PrivilegeType
-	AccountLocked        bool // A role record when this field is true
+	AccountLocked        bool // The account can't login, a role record is always locked
 	AuthPlugin           string
+	// FailedLoginAttempts and PasswordLockTimeDays are set by FAILED_LOGIN_ATTEMPTS and PASSWORD_LOCK_TIME,
+	// PasswordLockTimeDays is -1 for PASSWORD_LOCK_TIME UNBOUNDED.
+	FailedLoginAttempts  int64
+	PasswordLockTimeDays int64
+	// PasswordExpired is true if the password is expired by PASSWORD EXPIRE,
+	// the account can only change its password after login.
+	PasswordExpired bool
+	// PasswordLastChanged and PasswordLifetimeDays are used to expire the password by PASSWORD EXPIRE INTERVAL N DAY,
+	// PasswordLifetimeDays is 0 if the password never expires by time.
+	PasswordLastChanged  time.Time
+	PasswordLifetimeDays int64
+	// MaxUserConnections is the maximum number of the simultaneous connections of the account
+	// on an instance, 0 means unlimited.
+	MaxUserConnections int64
+}
+
+// isPasswordExpired returns whether the password is expired by PASSWORD EXPIRE or PASSWORD EXPIRE INTERVAL N DAY at now.
+func (record *UserRecord) isPasswordExpired(now time.Time) bool {
+	if record.PasswordExpired {
+		return true
+	}
+	if record.PasswordLifetimeDays == 0 || record.PasswordLastChanged.IsZero() {
+		return false
+	}
+	return !now.Before(record.PasswordLastChanged.AddDate(0, 0, int(record.PasswordLifetimeDays)))
 }
 
 // NewUserRecord return a UserRecord, only use for unit test.
@@ -125,6 +151,18 @@ type dynamicPrivRecord struct {
 	GrantOption   bool
 }
 
+// UserAttributes is the json format for User_attributes column in mysql.user.
+// The layout is compatible with MySQL.
+type UserAttributes struct {
+	PasswordLocking *PasswordLocking `json:"Password_locking,omitempty"`
+}
+
+// PasswordLocking is the failed-login tracking settings of an account.
+type PasswordLocking struct {
+	FailedLoginAttempts  int64 `json:"failed_login_attempts"`
+	PasswordLockTimeDays int64 `json:"password_lock_time_days"`
+}
+
 // SSLType is enum value for GlobalPrivValue.SSLType.
 // the value is compatible with MySQL storage json value.
 type SSLType int
@@ -224,6 +262,15 @@ type defaultRoleRecord struct {
 	DefaultRoleHost string
 }
 
+// proxyPrivRecord is used to cache mysql.proxies_priv
+type proxyPrivRecord struct {
+	baseRecord
+
+	ProxiedUser string
+	ProxiedHost string
+	WithGrant   bool
+}
+
 // roleGraphEdgesTable is used to cache relationship between and role.
 type roleGraphEdgesTable struct {
 	roleList map[string]*auth.RoleIdentity
@@ -267,6 +314,7 @@ type MySQLPrivilege struct {
 	ColumnsPriv   []columnsPrivRecord
 	DefaultRoles  []defaultRoleRecord
 	RoleGraph     map[string]roleGraphEdgesTable
+	ProxiesPriv   []proxyPrivRecord
 }
 
 // FindAllUserEffectiveRoles is used to find all effective roles grant to this user.
@@ -379,6 +427,15 @@ func (p *MySQLPrivilege) LoadAll(ctx sessionctx.Context) error {
 		}
 		logutil.BgLogger().Warn("mysql.role_edges missing")
 	}
+
+	err = p.LoadProxiesPrivTable(ctx)
+	if err != nil {
+		if !noSuchTable(err) {
+			logutil.BgLogger().Warn("load mysql.proxies_priv", zap.Error(err))
+			return errLoadPrivilege.FastGen("mysql.proxies_priv")
+		}
+		logutil.BgLogger().Warn("mysql.proxies_priv missing")
+	}
 	return nil
 }
 
@@ -392,6 +449,16 @@ func noSuchTable(err error) bool {
 	return false
 }
 
+func noSuchColumn(err error) bool {
+	e1 := errors.Cause(err)
+	if e2, ok := e1.(*terror.Error); ok {
+		if terror.ErrCode(e2.Code()) == terror.ErrCode(mysql.ErrBadField) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadRoleGraph loads the mysql.role_edges table from database.
 func (p *MySQLPrivilege) LoadRoleGraph(ctx sessionctx.Context) error {
 	p.RoleGraph = make(map[string]roleGraphEdgesTable)
@@ -404,7 +471,20 @@ func (p *MySQLPrivilege) LoadRoleGraph(ctx sessionctx.Context) error {
 
 // LoadUserTable loads the mysql.user table from database.
 func (p *MySQLPrivilege) LoadUserTable(ctx sessionctx.Context) error {
-	err := p.loadTable(ctx, sqlLoadUserTable, p.decodeUserTableRow)
+	var err error
+	// The mysql.user table may come from an older version without the newly added columns.
+	for _, columns := range []string{
+		",User_attributes,Password_expired,max_user_connections,Password_last_changed,Password_lifetime",
+		",User_attributes,Password_expired,max_user_connections",
+		",User_attributes,Password_expired",
+		",User_attributes",
+		"",
+	} {
+		err = p.loadTable(ctx, fmt.Sprintf(sqlLoadUserTable, columns), p.decodeUserTableRow)
+		if !noSuchColumn(err) {
+			break
+		}
+	}
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -564,6 +644,11 @@ func (p *MySQLPrivilege) LoadDefaultRoles(ctx sessionctx.Context) error {
 	return p.loadTable(ctx, sqlLoadDefaultRoles, p.decodeDefaultRoleTableRow)
 }
 
+// LoadProxiesPrivTable loads the mysql.proxies_priv table from database.
+func (p *MySQLPrivilege) LoadProxiesPrivTable(ctx sessionctx.Context) error {
+	return p.loadTable(ctx, sqlLoadProxiesPrivTable, p.decodeProxiesPrivTableRow)
+}
+
 func (p *MySQLPrivilege) loadTable(sctx sessionctx.Context, sql string,
 	decodeTableRow func(chunk.Row, []*ast.ResultField) error) error {
 	ctx := context.Background()
@@ -632,7 +717,8 @@ func (record *baseRecord) assignUserOrHost(row chunk.Row, i int, f *ast.ResultFi
 		record.User = row.GetString(i)
 	case "host":
 		record.Host = row.GetString(i)
-		record.patChars, record.patTypes = stringutil.CompilePatternBytes(record.Host, '\\')
+		// The client hosts are normalized by the server, so are the IP addresses of the accounts.
+		record.patChars, record.patTypes = stringutil.CompilePatternBytes(util.NormalizeIP(record.Host), '\\')
 		record.hostIPNet = parseHostIPNet(record.Host)
 	}
 }
@@ -647,12 +733,47 @@ func (p *MySQLPrivilege) decodeUserTableRow(row chunk.Row, fs []*ast.ResultField
 			if row.GetEnum(i).String() == "Y" {
 				value.AccountLocked = true
 			}
+		case f.ColumnAsName.L == "password_expired":
+			if row.GetEnum(i).String() == "Y" {
+				value.PasswordExpired = true
+			}
+		case f.ColumnAsName.L == "max_user_connections":
+			value.MaxUserConnections = int64(row.GetUint64(i))
+		case f.ColumnAsName.L == "password_last_changed":
+			if row.IsNull(i) {
+				continue
+			}
+			t, err := row.GetTime(i).GoTime(time.Local)
+			if err != nil {
+				logutil.BgLogger().Warn("the password last changed time is broken, ignore it",
+					zap.String("user", value.User), zap.String("host", value.Host), zap.Error(err))
+				continue
+			}
+			value.PasswordLastChanged = t
+		case f.ColumnAsName.L == "password_lifetime":
+			if !row.IsNull(i) {
+				value.PasswordLifetimeDays = int64(row.GetUint64(i))
+			}
 		case f.ColumnAsName.L == "plugin":
 			if row.GetString(i) != "" {
 				value.AuthPlugin = row.GetString(i)
 			} else {
 				value.AuthPlugin = mysql.AuthNativePassword
 			}
+		case f.ColumnAsName.L == "user_attributes":
+			if row.IsNull(i) {
+				continue
+			}
+			var attributes UserAttributes
+			if err := json.Unmarshal(hack.Slice(row.GetJSON(i).String()), &attributes); err != nil {
+				logutil.BgLogger().Warn("one user attributes data is broken, ignore it",
+					zap.String("user", value.User), zap.String("host", value.Host), zap.Error(err))
+				continue
+			}
+			if attributes.PasswordLocking != nil {
+				value.FailedLoginAttempts = attributes.PasswordLocking.FailedLoginAttempts
+				value.PasswordLockTimeDays = attributes.PasswordLocking.PasswordLockTimeDays
+			}
 		case f.Column.Tp == mysql.TypeEnum:
 			if row.GetEnum(i).String() != "Y" {
 				continue
@@ -812,6 +933,24 @@ func (p *MySQLPrivilege) decodeDefaultRoleTableRow(row chunk.Row, fs []*ast.Resu
 	return nil
 }
 
+func (p *MySQLPrivilege) decodeProxiesPrivTableRow(row chunk.Row, fs []*ast.ResultField) error {
+	var value proxyPrivRecord
+	for i, f := range fs {
+		switch {
+		case f.ColumnAsName.L == "proxied_host":
+			value.ProxiedHost = row.GetString(i)
+		case f.ColumnAsName.L == "proxied_user":
+			value.ProxiedUser = row.GetString(i)
+		case f.ColumnAsName.L == "with_grant":
+			value.WithGrant = row.GetEnum(i).String() == "Y"
+		default:
+			value.assignUserOrHost(row, i, f)
+		}
+	}
+	p.ProxiesPriv = append(p.ProxiesPriv, value)
+	return nil
+}
+
 func (p *MySQLPrivilege) decodeColumnsPrivTableRow(row chunk.Row, fs []*ast.ResultField) error {
 	var value columnsPrivRecord
 	for i, f := range fs {
@@ -1552,6 +1691,16 @@ func (p *MySQLPrivilege) getDefaultRoles(user, host string) []*auth.RoleIdentity
 	return ret
 }
 
+// canProxy returns true if the account user@host has the PROXY privilege on proxiedUser@proxiedHost.
+func (p *MySQLPrivilege) canProxy(user, host, proxiedUser, proxiedHost string) bool {
+	for _, r := range p.ProxiesPriv {
+		if r.fullyMatch(user, host) && r.ProxiedUser == proxiedUser && r.ProxiedHost == proxiedHost {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *MySQLPrivilege) getAllRoles(user, host string) []*auth.RoleIdentity {
 	key := user + "@" + host
 	edgeTable, ok := p.RoleGraph[key]
@@ -255,6 +255,15 @@ func TestHostMatch(t *testing.T) {
 	require.True(t, p.RequestVerification(activeRoles, "root", "172.0.0.1", "test", "", "", mysql.ShutdownPriv))
 	mustExec(t, se, `TRUNCATE TABLE mysql.user`)
 
+	// Host name can be IPv6 address, it matches the normalized client host.
+	mustExec(t, se, `INSERT INTO mysql.user (HOST, USER, authentication_string, Select_priv) VALUES ("0:0:0:0:0:0:0:1", "root", "", "Y")`)
+	p = privileges.MySQLPrivilege{}
+	err = p.LoadUserTable(se)
+	require.NoError(t, err)
+	require.True(t, p.RequestVerification(activeRoles, "root", "::1", "test", "", "", mysql.SelectPriv))
+	require.False(t, p.RequestVerification(activeRoles, "root", "::2", "test", "", "", mysql.SelectPriv))
+	mustExec(t, se, `TRUNCATE TABLE mysql.user`)
+
 	// Invalid host name, the user can be created, but cannot login.
 	cases := []string{
 		"127.0.0.0/24",
@@ -0,0 +1,49 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privileges
+
+import "sync"
+
+// externalAuthPlugins are the names of the authentication plugins registered by the server,
+// the accounts using them are authenticated by the plugins instead of the password.
+var externalAuthPlugins struct {
+	sync.RWMutex
+	names map[string]struct{}
+}
+
+// RegisterExternalAuthPlugin marks the authentication plugin as loaded, so that the accounts can be created with it.
+func RegisterExternalAuthPlugin(name string) {
+	externalAuthPlugins.Lock()
+	defer externalAuthPlugins.Unlock()
+	if externalAuthPlugins.names == nil {
+		externalAuthPlugins.names = make(map[string]struct{})
+	}
+	externalAuthPlugins.names[name] = struct{}{}
+}
+
+// UnregisterExternalAuthPlugin removes the authentication plugin registered by RegisterExternalAuthPlugin.
+func UnregisterExternalAuthPlugin(name string) {
+	externalAuthPlugins.Lock()
+	defer externalAuthPlugins.Unlock()
+	delete(externalAuthPlugins.names, name)
+}
+
+// IsExternalAuthPlugin returns whether the authentication plugin is registered by the server.
+func IsExternalAuthPlugin(name string) bool {
+	externalAuthPlugins.RLock()
+	defer externalAuthPlugins.RUnlock()
+	_, ok := externalAuthPlugins.names[name]
+	return ok
+}
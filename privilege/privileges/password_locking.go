@@ -0,0 +1,153 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privileges
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// passwordLockTimeUnbounded is the PasswordLockTimeDays of PASSWORD_LOCK_TIME UNBOUNDED.
+const passwordLockTimeUnbounded = -1
+
+// FailedLoginInfo is the failed-login state of an account.
+type FailedLoginInfo struct {
+	User           string `json:"user"`
+	Host           string `json:"host"`
+	FailedAttempts int64  `json:"failed_attempts"`
+	Locked         bool   `json:"locked"`
+	// LockTimeDays is -1 if the account is locked until it is unlocked explicitly.
+	LockTimeDays int64     `json:"lock_time_days"`
+	LockedAt     time.Time `json:"locked_at"`
+}
+
+// RemainingDays returns the remaining days of the lock, it returns -1 if the lock is unbounded.
+func (i *FailedLoginInfo) RemainingDays(now time.Time) int64 {
+	if i.LockTimeDays == passwordLockTimeUnbounded {
+		return passwordLockTimeUnbounded
+	}
+	remaining := i.LockedAt.Add(time.Duration(i.LockTimeDays) * 24 * time.Hour).Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+	return int64((remaining + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+func (i *FailedLoginInfo) expired(now time.Time) bool {
+	return i.Locked && i.RemainingDays(now) == 0
+}
+
+// failedLoginTracker tracks the consecutive failed logins of the accounts which set both
+// FAILED_LOGIN_ATTEMPTS and PASSWORD_LOCK_TIME. Like MySQL, the state is kept in the memory
+// of each instance, it is lost after restart.
+type failedLoginTracker struct {
+	sync.Mutex
+	accounts map[string]*FailedLoginInfo
+}
+
+var failedLogins = &failedLoginTracker{accounts: make(map[string]*FailedLoginInfo)}
+
+func failedLoginKey(user, host string) string {
+	return user + "@" + host
+}
+
+func passwordLockingEnabled(record *UserRecord) bool {
+	return record.FailedLoginAttempts > 0 && record.PasswordLockTimeDays != 0
+}
+
+// locked returns whether the account is locked now, an expired lock is released.
+func (t *failedLoginTracker) locked(user, host string, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+	key := failedLoginKey(user, host)
+	info, ok := t.accounts[key]
+	if !ok || !info.Locked {
+		return false
+	}
+	if info.expired(now) {
+		delete(t.accounts, key)
+		return false
+	}
+	return true
+}
+
+func (t *failedLoginTracker) onFailure(record *UserRecord, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	key := failedLoginKey(record.User, record.Host)
+	info, ok := t.accounts[key]
+	if !ok {
+		info = &FailedLoginInfo{User: record.User, Host: record.Host}
+		t.accounts[key] = info
+	}
+	info.FailedAttempts++
+	if info.FailedAttempts >= record.FailedLoginAttempts {
+		info.Locked = true
+		info.LockTimeDays = record.PasswordLockTimeDays
+		info.LockedAt = now
+	}
+}
+
+func (t *failedLoginTracker) onSuccess(user, host string) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.accounts, failedLoginKey(user, host))
+}
+
+// GetFailedLogin returns the failed-login state of the account.
+func GetFailedLogin(user, host string) (FailedLoginInfo, bool) {
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+	info, ok := failedLogins.accounts[failedLoginKey(user, host)]
+	if !ok {
+		return FailedLoginInfo{}, false
+	}
+	return *info, true
+}
+
+// GetFailedLogins returns the failed-login state of all the accounts that have failed to login.
+func GetFailedLogins() []FailedLoginInfo {
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+	now := time.Now()
+	infos := make([]FailedLoginInfo, 0, len(failedLogins.accounts))
+	for key, info := range failedLogins.accounts {
+		if info.expired(now) {
+			delete(failedLogins.accounts, key)
+			continue
+		}
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].User != infos[j].User {
+			return infos[i].User < infos[j].User
+		}
+		return infos[i].Host < infos[j].Host
+	})
+	return infos
+}
+
+// ResetFailedLogin clears the failed-login state of the account and releases its lock.
+func ResetFailedLogin(user, host string) {
+	failedLogins.onSuccess(user, host)
+}
+
+// ResetFailedLogins clears the failed-login state of all the accounts and releases their locks.
+func ResetFailedLogins() {
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+	failedLogins.accounts = make(map[string]*FailedLoginInfo)
+}
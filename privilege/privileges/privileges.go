@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/infoschema/perfschema"
@@ -62,6 +63,9 @@ var dynamicPrivLock sync.Mutex
 type UserPrivileges struct {
 	user string
 	host string
+	// verifiedAccountLocked is set by ConnectionVerification if the credentials are verified but the
+	// account is locked.
+	verifiedAccountLocked bool
 	*Handle
 }
 
@@ -202,15 +206,15 @@ func (p *UserPrivileges) isValidHash(record *UserRecord) bool {
 		return false
 	}
 
-	if record.AuthPlugin == mysql.AuthCachingSha2Password {
+	if record.AuthPlugin == mysql.AuthCachingSha2Password || record.AuthPlugin == mysql.AuthSha256Password {
 		if len(pwd) == mysql.SHAPWDHashLen {
 			return true
 		}
-		logutil.BgLogger().Error("user password from system DB not like a caching_sha2_password format", zap.String("user", record.User), zap.String("plugin", record.AuthPlugin), zap.Int("hash_length", len(pwd)))
+		logutil.BgLogger().Error("user password from system DB not like a sha2 password format", zap.String("user", record.User), zap.String("plugin", record.AuthPlugin), zap.Int("hash_length", len(pwd)))
 		return false
 	}
 
-	if record.AuthPlugin == mysql.AuthSocket {
+	if record.AuthPlugin == mysql.AuthSocket || IsExternalAuthPlugin(record.AuthPlugin) {
 		return true
 	}
 
@@ -246,8 +250,8 @@ func (p *UserPrivileges) GetAuthPlugin(user, host string) (string, error) {
 	}
 	// zero-length auth string means no password for native and caching_sha2 auth.
 	// but for auth_socket it means there should be a 1-to-1 mapping between the TiDB user
-	// and the OS user.
-	if record.AuthenticationString == "" && record.AuthPlugin != mysql.AuthSocket {
+	// and the OS user, and the external plugins authenticate the user by themselves.
+	if record.AuthenticationString == "" && record.AuthPlugin != mysql.AuthSocket && !IsExternalAuthPlugin(record.AuthPlugin) {
 		return "", nil
 	}
 	if p.isValidHash(record) {
@@ -269,6 +273,60 @@ func (p *UserPrivileges) MatchIdentity(user, host string, skipNameResolve bool)
 	return "", "", false
 }
 
+// IsPasswordExpired implements the Manager interface.
+func (p *UserPrivileges) IsPasswordExpired(user, host string) bool {
+	if SkipWithGrant {
+		return false
+	}
+	record := p.Handle.Get().connectionVerification(user, host)
+	return record != nil && record.isPasswordExpired(time.Now())
+}
+
+// IsAccountLocked implements the Manager interface.
+func (p *UserPrivileges) IsAccountLocked(user, host string) bool {
+	if SkipWithGrant {
+		return false
+	}
+	record := p.Handle.Get().connectionVerification(user, host)
+	return record != nil && record.AccountLocked
+}
+
+// IsVerifiedAccountLocked implements the Manager interface.
+func (p *UserPrivileges) IsVerifiedAccountLocked() bool {
+	return p.verifiedAccountLocked
+}
+
+// GetMaxUserConnections implements the Manager interface.
+func (p *UserPrivileges) GetMaxUserConnections(user, host string) int64 {
+	if SkipWithGrant {
+		return 0
+	}
+	record := p.Handle.Get().connectionVerification(user, host)
+	if record == nil {
+		return 0
+	}
+	return record.MaxUserConnections
+}
+
+// ProxyAs implements the Manager interface.
+func (p *UserPrivileges) ProxyAs(proxiedUser, proxiedHost string) bool {
+	if SkipWithGrant {
+		p.user = proxiedUser
+		p.host = proxiedHost
+		return true
+	}
+	mysqlPriv := p.Handle.Get()
+	if !mysqlPriv.canProxy(p.user, p.host, proxiedUser, proxiedHost) {
+		return false
+	}
+	if mysqlPriv.connectionVerification(proxiedUser, proxiedHost) == nil {
+		return false
+	}
+	p.user = proxiedUser
+	p.host = proxiedHost
+	return true
+}
+
 // GetAuthWithoutVerification implements the Manager interface.
 func (p *UserPrivileges) GetAuthWithoutVerification(user, host string) (success bool) {
 	if SkipWithGrant {
@@ -293,7 +351,28 @@ func (p *UserPrivileges) GetAuthWithoutVerification(user, host string) (success
 }
 
 // ConnectionVerification implements the Manager interface.
-func (p *UserPrivileges) ConnectionVerification(user, host string, authentication, salt []byte, tlsState *tls.ConnectionState) (success bool) {
+func (p *UserPrivileges) ConnectionVerification(user, host string, authentication, salt []byte, tlsState *tls.ConnectionState) bool {
+	return p.connectionVerification(user, host, tlsState, func(record *UserRecord) bool {
+		return p.verifyPassword(record, user, authentication, salt)
+	})
+}
+
+// PluginConnectionVerification implements the Manager interface.
+func (p *UserPrivileges) PluginConnectionVerification(user, host string, authenticate func() bool, tlsState *tls.ConnectionState) bool {
+	return p.connectionVerification(user, host, tlsState, func(record *UserRecord) bool {
+		if !IsExternalAuthPlugin(record.AuthPlugin) {
+			logutil.BgLogger().Warn("the account is not of an external auth plugin",
+				zap.String("user", user), zap.String("host", host), zap.String("plugin", record.AuthPlugin))
+			return false
+		}
+		return authenticate()
+	})
+}
+
+// connectionVerification checks the TLS requirements, the account lock and the failed logins of the account,
+// and verifies its credentials by verify.
+func (p *UserPrivileges) connectionVerification(user, host string, tlsState *tls.ConnectionState, verify func(record *UserRecord) bool) (success bool) {
+	p.verifiedAccountLocked = false
 	if SkipWithGrant {
 		p.user = user
 		p.host = host
@@ -319,31 +398,65 @@ func (p *UserPrivileges) ConnectionVerification(user, host string, authenticatio
 		}
 	}
 
-	// Login a locked account is not allowed.
-	locked := record.AccountLocked
-	if locked {
-		logutil.BgLogger().Error("try to login a locked account",
-			zap.String("user", user), zap.String("host", host))
-		success = false
+	// Login a locked account is not allowed. It is rejected after the credentials are verified, so that
+	// the clients without the right credentials can't tell whether an account is locked.
+	defer func() {
+		if success && record.AccountLocked {
+			logutil.BgLogger().Error("try to login a locked account",
+				zap.String("user", user), zap.String("host", host))
+			p.user, p.host = "", ""
+			p.verifiedAccountLocked = true
+			success = false
+		}
+	}()
+
+	if passwordLockingEnabled(record) {
+		now := time.Now()
+		if failedLogins.locked(record.User, record.Host, now) {
+			logutil.BgLogger().Warn("try to login an account locked by consecutive failed logins",
+				zap.String("user", user), zap.String("host", host))
+			return
+		}
+		defer func() {
+			if success {
+				failedLogins.onSuccess(record.User, record.Host)
+			} else {
+				failedLogins.onFailure(record, now)
+			}
+		}()
+	}
+
+	if !verify(record) {
 		return
 	}
+	p.user = user
+	p.host = record.Host
+	success = true
+	return
+}
 
+// verifyPassword verifies the authentication data sent by the client against the password of the account.
+func (p *UserPrivileges) verifyPassword(record *UserRecord, user string, authentication, salt []byte) bool {
 	pwd := record.AuthenticationString
 	if !p.isValidHash(record) {
-		return
+		return false
+	}
+	// The accounts of the external plugins are only authenticated by the plugins by PluginConnectionVerification.
+	// Their empty authentication_string must not be taken as an empty password.
+	if IsExternalAuthPlugin(record.AuthPlugin) {
+		logutil.BgLogger().Warn("the account of an external auth plugin can not be verified by password",
+			zap.String("user", user), zap.String("host", record.Host), zap.String("plugin", record.AuthPlugin))
+		return false
 	}
 
 	// empty password
 	if len(pwd) == 0 && len(authentication) == 0 {
-		p.user = user
-		p.host = record.Host
-		success = true
-		return
+		return true
 	}
 
 	if len(pwd) == 0 || len(authentication) == 0 {
 		if record.AuthPlugin != mysql.AuthSocket {
-			return
+			return false
 		}
 	}
 
@@ -351,37 +464,28 @@ func (p *UserPrivileges) ConnectionVerification(user, host string, authenticatio
 		hpwd, err := auth.DecodePassword(pwd)
 		if err != nil {
 			logutil.BgLogger().Error("decode password string failed", zap.Error(err))
-			return
+			return false
 		}
 
-		if !auth.CheckScrambledPassword(salt, hpwd, authentication) {
-			return
-		}
-	} else if record.AuthPlugin == mysql.AuthCachingSha2Password {
+		return auth.CheckScrambledPassword(salt, hpwd, authentication)
+	} else if record.AuthPlugin == mysql.AuthCachingSha2Password || record.AuthPlugin == mysql.AuthSha256Password {
 		authok, err := auth.CheckShaPassword([]byte(pwd), string(authentication))
 		if err != nil {
-			logutil.BgLogger().Error("Failed to check caching_sha2_password", zap.Error(err))
+			logutil.BgLogger().Error("Failed to check sha2 password", zap.String("plugin", record.AuthPlugin), zap.Error(err))
 		}
 
-		if !authok {
-			return
-		}
+		return authok
 	} else if record.AuthPlugin == mysql.AuthSocket {
 		if string(authentication) != user && string(authentication) != pwd {
 			logutil.BgLogger().Error("Failed socket auth", zap.String("user", user),
 				zap.String("socket_user", string(authentication)),
 				zap.String("authentication_string", pwd))
-			return
+			return false
 		}
-	} else {
-		logutil.BgLogger().Error("unknown authentication plugin", zap.String("user", user), zap.String("plugin", record.AuthPlugin))
-		return
+		return true
 	}
-
-	p.user = user
-	p.host = record.Host
-	success = true
-	return
+	logutil.BgLogger().Error("unknown authentication plugin", zap.String("user", user), zap.String("plugin", record.AuthPlugin))
+	return false
 }
 
 type checkResult int
@@ -525,7 +629,7 @@ func checkCertSAN(priv *globalPrivRecord, cert *x509.Certificate, sans map[util.
 			}
 		}
 		if !givenMatchOne {
-			logutil.BgLogger().Info("ssl check failure for subject", zap.String("user", priv.User), zap.String("host", priv.Host),
+			logutil.BgLogger().Info("ssl check failure for SAN", zap.String("user", priv.User), zap.String("host", priv.Host),
 				zap.String("require", priv.Priv.SAN), zap.Strings("given", given), zap.String("type", string(typ)))
 			r = false
 			return
@@ -25,6 +25,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/errno"
@@ -3031,3 +3032,101 @@ func TestIssue29823(t *testing.T) {
 	err = tk2.QueryToErr("show tables from test")
 	require.EqualError(t, err, "[executor:1044]Access denied for user 'u1'@'%' to database 'test'")
 }
+
+func TestFailedLoginLock(t *testing.T) {
+	t.Parallel()
+	store, clean := newStore(t)
+	defer clean()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("create user 'lock1'@'localhost' identified by 'abc' failed_login_attempts 2 password_lock_time 1")
+	tk.MustQuery("show create user 'lock1'@'localhost'").Check(testkit.Rows(
+		"CREATE USER 'lock1'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '*0D3CED9BEC10A777AEC23CCC353A8C08A633045E' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK FAILED_LOGIN_ATTEMPTS 2 PASSWORD_LOCK_TIME 1"))
+
+	salt := []byte{85, 92, 45, 22, 58, 79, 107, 6, 122, 125, 58, 80, 12, 90, 103, 32, 90, 10, 74, 82}
+	authentication := []byte{24, 180, 183, 225, 166, 6, 81, 102, 70, 248, 199, 143, 91, 204, 169, 9, 161, 171, 203, 33}
+	user := &auth.UserIdentity{Username: "lock1", Hostname: "localhost"}
+	se := testkit.NewTestKit(t, store).Session()
+
+	// A successful login resets the counter.
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.True(t, se.Auth(user, authentication, salt))
+	_, ok := privileges.GetFailedLogin("lock1", "localhost")
+	require.False(t, ok)
+
+	// The account is locked after consecutive failed logins, even the password is right.
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.False(t, se.Auth(user, authentication, salt))
+	info, ok := privileges.GetFailedLogin("lock1", "localhost")
+	require.True(t, ok)
+	require.True(t, info.Locked)
+	require.Equal(t, int64(2), info.FailedAttempts)
+	require.Equal(t, int64(1), info.RemainingDays(time.Now()))
+	require.Equal(t, int64(0), info.RemainingDays(time.Now().Add(25*time.Hour)))
+	require.Contains(t, privileges.GetFailedLogins(), info)
+
+	// FLUSH PRIVILEGES releases the lock.
+	tk.MustExec("flush privileges")
+	require.True(t, se.Auth(user, authentication, salt))
+
+	// ALTER USER changes the settings and resets the state.
+	tk.MustExec("alter user 'lock1'@'localhost' password_lock_time unbounded")
+	tk.MustQuery("select User_attributes from mysql.user where user = 'lock1'").Check(testkit.Rows(
+		`{"Password_locking": {"failed_login_attempts": 2, "password_lock_time_days": -1}}`))
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	info, ok = privileges.GetFailedLogin("lock1", "localhost")
+	require.True(t, ok)
+	require.Equal(t, int64(-1), info.RemainingDays(time.Now()))
+	tk.MustExec("alter user 'lock1'@'localhost' account unlock")
+	require.True(t, se.Auth(user, authentication, salt))
+
+	// The failed logins are not tracked when the options are zero.
+	tk.MustExec("alter user 'lock1'@'localhost' failed_login_attempts 0")
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.True(t, se.Auth(user, authentication, salt))
+	_, ok = privileges.GetFailedLogin("lock1", "localhost")
+	require.False(t, ok)
+
+	tk.MustGetErrMsg("create user 'lock2'@'localhost' failed_login_attempts 32768", "FAILED_LOGIN_ATTEMPTS must be between 0 and 32767")
+}
+
+func TestAccountLock(t *testing.T) {
+	t.Parallel()
+	store, clean := newStore(t)
+	defer clean()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("create user 'locked1'@'localhost' identified by 'abc' account lock")
+	tk.MustQuery("select Account_locked from mysql.user where user = 'locked1'").Check(testkit.Rows("Y"))
+
+	salt := []byte{85, 92, 45, 22, 58, 79, 107, 6, 122, 125, 58, 80, 12, 90, 103, 32, 90, 10, 74, 82}
+	authentication := []byte{24, 180, 183, 225, 166, 6, 81, 102, 70, 248, 199, 143, 91, 204, 169, 9, 161, 171, 203, 33}
+	user := &auth.UserIdentity{Username: "locked1", Hostname: "localhost"}
+	se := testkit.NewTestKit(t, store).Session()
+	pm := privilege.GetPrivilegeManager(se)
+
+	// The locked account can't login even the password is right.
+	require.True(t, pm.IsAccountLocked("locked1", "localhost"))
+	require.False(t, se.Auth(user, authentication, salt))
+	require.True(t, pm.IsVerifiedAccountLocked())
+	require.False(t, se.Auth(user, []byte("wrong"), salt))
+	require.False(t, pm.IsVerifiedAccountLocked())
+
+	tk.MustExec("alter user 'locked1'@'localhost' account unlock")
+	tk.MustQuery("select Account_locked from mysql.user where user = 'locked1'").Check(testkit.Rows("N"))
+	require.False(t, pm.IsAccountLocked("locked1", "localhost"))
+	require.True(t, se.Auth(user, authentication, salt))
+
+	// The other options don't change the state.
+	tk.MustExec("alter user 'locked1'@'localhost' account lock")
+	tk.MustExec("alter user 'locked1'@'localhost' password expire never")
+	require.True(t, pm.IsAccountLocked("locked1", "localhost"))
+	require.False(t, se.Auth(user, authentication, salt))
+
+	// The last option wins.
+	tk.MustExec("create user 'locked2'@'localhost' account lock account unlock")
+	tk.MustQuery("select Account_locked from mysql.user where user = 'locked2'").Check(testkit.Rows("N"))
+}
@@ -0,0 +1,285 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// auditEventQueueSize is the number of the audit events buffered for each hook.
+const auditEventQueueSize = 10000
+
+// auditHookDrainTimeout bounds the time to deliver the queued events when the server is closed.
+var auditHookDrainTimeout = 5 * time.Second
+
+// ConnectionEventType is the type of a ConnectionEvent.
+type ConnectionEventType string
+
+const (
+	// ConnectionEventConnect is sent after a client is authenticated.
+	ConnectionEventConnect ConnectionEventType = "connect"
+	// ConnectionEventDisconnect is sent after an authenticated connection is closed.
+	ConnectionEventDisconnect ConnectionEventType = "disconnect"
+	// ConnectionEventAuthFailure is sent when a client is rejected by the authentication.
+	ConnectionEventAuthFailure ConnectionEventType = "auth_failure"
+)
+
+// ConnectionEvent records who connected from where.
+type ConnectionEvent struct {
+	Time         time.Time           `json:"time"`
+	Type         ConnectionEventType `json:"type"`
+	ConnectionID uint64              `json:"connection_id"`
+	User         string              `json:"user"`
+	Host         string              `json:"host"`
+	Port         string              `json:"port,omitempty"`
+	DB           string              `json:"db,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// StatementEvent records a statement executed by a connection. The statement text is not recorded,
+// the statement is identified by the digest of its normalized text.
+type StatementEvent struct {
+	Time         time.Time `json:"time"`
+	ConnectionID uint64    `json:"connection_id"`
+	User         string    `json:"user"`
+	Host         string    `json:"host"`
+	DB           string    `json:"db,omitempty"`
+	Digest       string    `json:"digest"`
+	// Tables are the tables accessed by the statement, in the "db.table" format.
+	Tables       []string `json:"tables,omitempty"`
+	AffectedRows uint64   `json:"affected_rows"`
+	// SentRows is the number of the rows of the result set written to the client, the rows fetched
+	// later through a cursor are not counted.
+	SentRows  uint64  `json:"sent_rows"`
+	QueryTime float64 `json:"query_time"`
+	// Status is "ok" or "error".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditHook receives the audit events of the connections and the statements. The events are delivered
+// to each hook by a goroutine through a bounded queue, so a slow hook never blocks the connections, the
+// events are dropped and counted by tidb_server_audit_event_dropped_total if the queue is full.
+type AuditHook interface {
+	OnConnectionEvent(event *ConnectionEvent)
+	OnStatementEvent(event *StatementEvent)
+}
+
+// auditHookRunner delivers the events to an AuditHook.
+type auditHookRunner struct {
+	hook         AuditHook
+	queue        chan interface{}
+	drainTimeout time.Duration
+	stop         chan struct{}
+	once         sync.Once
+	wg           sync.WaitGroup
+}
+
+func newAuditHookRunner(hook AuditHook, queueSize int) *auditHookRunner {
+	r := &auditHookRunner{
+		hook:         hook,
+		queue:        make(chan interface{}, queueSize),
+		drainTimeout: auditHookDrainTimeout,
+		stop:         make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *auditHookRunner) run() {
+	defer r.wg.Done()
+	for {
+		select {
+		case event := <-r.queue:
+			r.deliver(event)
+		case <-r.stop:
+			r.drain()
+			return
+		}
+	}
+}
+
+// drain delivers the queued events until the queue is empty or the drain timeout elapses.
+func (r *auditHookRunner) drain() {
+	deadline := time.Now().Add(r.drainTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case event := <-r.queue:
+			r.deliver(event)
+		default:
+			return
+		}
+	}
+	if n := len(r.queue); n > 0 {
+		logutil.BgLogger().Warn("audit events are not delivered before the timeout", zap.Int("events", n))
+	}
+}
+
+func (r *auditHookRunner) deliver(event interface{}) {
+	defer func() {
+		if v := recover(); v != nil {
+			logutil.BgLogger().Error("audit hook panicked", zap.Any("recover", v), zap.Stack("stack"))
+		}
+	}()
+	switch e := event.(type) {
+	case *ConnectionEvent:
+		r.hook.OnConnectionEvent(e)
+	case *StatementEvent:
+		r.hook.OnStatementEvent(e)
+	}
+}
+
+func (r *auditHookRunner) send(event interface{}, typ string) {
+	select {
+	case r.queue <- event:
+	default:
+		metrics.AuditEventDroppedCounter.WithLabelValues(typ).Inc()
+	}
+}
+
+// close delivers the queued events and stops, it returns after auditHookDrainTimeout even if the hook
+// is still blocked. The events sent after it are not delivered.
+func (r *auditHookRunner) close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(r.drainTimeout):
+		logutil.BgLogger().Warn("audit hook is blocked, stop waiting for it")
+	}
+}
+
+// AddAuditHook registers an audit hook, it must be called before Run.
+func (s *Server) AddAuditHook(hook AuditHook) {
+	s.auditHooks = append(s.auditHooks, newAuditHookRunner(hook, auditEventQueueSize))
+}
+
+func (s *Server) closeAuditHooks() {
+	for _, r := range s.auditHooks {
+		r.close()
+	}
+}
+
+func (cc *clientConn) auditEnabled() bool {
+	return cc.server != nil && len(cc.server.auditHooks) > 0
+}
+
+func (cc *clientConn) auditConnection(typ ConnectionEventType, err error) {
+	if !cc.auditEnabled() {
+		return
+	}
+	event := &ConnectionEvent{
+		Time:         time.Now(),
+		Type:         typ,
+		ConnectionID: cc.connectionID,
+		User:         cc.user,
+		Host:         cc.peerHost,
+		Port:         cc.peerPort,
+		DB:           cc.dbname,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	for _, r := range cc.server.auditHooks {
+		r.send(event, "connection")
+	}
+}
+
+// auditStatement sends the event of the statement started at start, it must be called before the
+// statement context is reset by the next statement.
+func (cc *clientConn) auditStatement(start time.Time, err error) {
+	if !cc.auditEnabled() || cc.ctx == nil {
+		return
+	}
+	vars := cc.ctx.GetSessionVars()
+	sc := vars.StmtCtx
+	_, digest := sc.SQLDigest()
+	event := &StatementEvent{
+		Time:         start,
+		ConnectionID: cc.connectionID,
+		User:         cc.user,
+		Host:         cc.peerHost,
+		DB:           vars.CurrentDB,
+		Digest:       digest.String(),
+		AffectedRows: sc.AffectedRows(),
+		SentRows:     cc.sentRows,
+		QueryTime:    time.Since(start).Seconds(),
+		Status:       "ok",
+	}
+	for _, t := range sc.Tables {
+		event.Tables = append(event.Tables, t.DB+"."+t.Table)
+	}
+	if err != nil {
+		event.Status = "error"
+		event.Error = err.Error()
+	}
+	for _, r := range cc.server.auditHooks {
+		r.send(event, "statement")
+	}
+}
+
+// FileAuditHook is an AuditHook writing the events as JSON lines to a file, which is rotated in the same
+// way as the log file.
+type FileAuditHook struct {
+	writer zapcore.WriteSyncer
+}
+
+// NewFileAuditHook creates a FileAuditHook writing to filename, the rotation of the file is configured by
+// the MaxSize, MaxDays and MaxBackups of fileCfg.
+func NewFileAuditHook(filename string, fileCfg logutil.FileLogConfig) (*FileAuditHook, error) {
+	fileCfg.Filename = filename
+	_, prop, err := log.InitLogger(&log.Config{File: fileCfg.FileLogConfig})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &FileAuditHook{writer: prop.Syncer}, nil
+}
+
+// OnConnectionEvent implements the AuditHook interface.
+func (h *FileAuditHook) OnConnectionEvent(event *ConnectionEvent) {
+	h.write(event)
+}
+
+// OnStatementEvent implements the AuditHook interface.
+func (h *FileAuditHook) OnStatementEvent(event *StatementEvent) {
+	h.write(event)
+}
+
+func (h *FileAuditHook) write(event interface{}) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logutil.BgLogger().Warn("marshal audit event failed", zap.Error(err))
+		return
+	}
+	if _, err = h.writer.Write(append(data, '\n')); err != nil {
+		logutil.BgLogger().Warn("write audit event failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testAuditHook struct {
+	sync.Mutex
+	block      chan struct{}
+	conns      []*ConnectionEvent
+	statements []*StatementEvent
+}
+
+func (h *testAuditHook) OnConnectionEvent(event *ConnectionEvent) {
+	h.Lock()
+	defer h.Unlock()
+	h.conns = append(h.conns, event)
+}
+
+func (h *testAuditHook) OnStatementEvent(event *StatementEvent) {
+	if h.block != nil {
+		<-h.block
+	}
+	h.Lock()
+	defer h.Unlock()
+	h.statements = append(h.statements, event)
+}
+
+func (h *testAuditHook) numStatements() int {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.statements)
+}
+
+func TestAuditHookRunnerDropsEvents(t *testing.T) {
+	hook := &testAuditHook{block: make(chan struct{})}
+	r := newAuditHookRunner(hook, 2)
+	defer r.close()
+
+	// The hook is blocked by the first event, the next 2 events are queued and the others are dropped
+	// without blocking the sender.
+	for i := 0; i < 10; i++ {
+		r.send(&StatementEvent{Digest: "d"}, "statement")
+		if i == 0 {
+			require.Eventually(t, func() bool { return len(r.queue) == 0 }, time.Second, time.Millisecond)
+		}
+	}
+	close(hook.block)
+	require.Eventually(t, func() bool { return hook.numStatements() == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestAuditHookRunnerDrainsEvents(t *testing.T) {
+	// The queued events are delivered by close.
+	hook := &testAuditHook{block: make(chan struct{})}
+	r := newAuditHookRunner(hook, 10)
+	for i := 0; i < 5; i++ {
+		r.send(&StatementEvent{Digest: "d"}, "statement")
+	}
+	close(hook.block)
+	r.close()
+	require.Equal(t, 5, hook.numStatements())
+
+	// close returns after the timeout if the hook is blocked.
+	old := auditHookDrainTimeout
+	auditHookDrainTimeout = 50 * time.Millisecond
+	defer func() {
+		auditHookDrainTimeout = old
+	}()
+	hook = &testAuditHook{block: make(chan struct{})}
+	defer close(hook.block)
+	r = newAuditHookRunner(hook, 10)
+	r.send(&StatementEvent{Digest: "d"}, "statement")
+	r.send(&StatementEvent{Digest: "d"}, "statement")
+	start := time.Now()
+	r.close()
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, 0, hook.numStatements())
+}
+
+func TestAuditStatementEvents(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	hook := &testAuditHook{}
+	srv.AddAuditHook(hook)
+	defer srv.closeAuditHooks()
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+
+	cc := &clientConn{
+		connectionID: 1,
+		alloc:        arena.NewAllocator(1024),
+		chunkAlloc:   chunk.NewAllocator(),
+		collation:    mysql.DefaultCollationID,
+		peerHost:     "localhost",
+		pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+		server:       srv,
+		user:         "root",
+		capability:   defaultCapability,
+	}
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	cc.auditConnection(ConnectionEventConnect, nil)
+
+	require.NoError(t, cc.handleQuery(ctx, "use test"))
+	require.NoError(t, cc.handleQuery(ctx, "insert into t values (1), (2), (3)"))
+	require.NoError(t, cc.handleQuery(ctx, "select * from t where a > 1"))
+	require.Error(t, cc.handleQuery(ctx, "insert into t values (1)"))
+	require.Eventually(t, func() bool { return hook.numStatements() == 4 }, time.Second, 10*time.Millisecond)
+
+	hook.Lock()
+	defer hook.Unlock()
+	require.Len(t, hook.conns, 1)
+	require.Equal(t, ConnectionEventConnect, hook.conns[0].Type)
+	require.Equal(t, "root", hook.conns[0].User)
+	require.Equal(t, "localhost", hook.conns[0].Host)
+
+	insert, sel, dup := hook.statements[1], hook.statements[2], hook.statements[3]
+	require.Equal(t, "test", insert.DB)
+	require.Equal(t, []string{"test.t"}, insert.Tables)
+	require.Equal(t, uint64(3), insert.AffectedRows)
+	require.Equal(t, "ok", insert.Status)
+	require.NotEmpty(t, insert.Digest)
+	require.Equal(t, []string{"test.t"}, sel.Tables)
+	require.Equal(t, uint64(2), sel.SentRows)
+	require.Equal(t, "error", dup.Status)
+	require.Contains(t, dup.Error, "Duplicate entry")
+	require.NotEmpty(t, dup.Digest)
+}
+
+func TestFileAuditHook(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	hook, err := NewFileAuditHook(filename, logutil.FileLogConfig{FileLogConfig: log.FileLogConfig{MaxSize: 1}})
+	require.NoError(t, err)
+	hook.OnConnectionEvent(&ConnectionEvent{Type: ConnectionEventAuthFailure, User: "u", Host: "127.0.0.1", Error: "denied"})
+	hook.OnStatementEvent(&StatementEvent{User: "u", Digest: "abc", Tables: []string{"test.t"}, Status: "ok"})
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var conn ConnectionEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &conn))
+	require.Equal(t, ConnectionEventAuthFailure, conn.Type)
+	require.Equal(t, "denied", conn.Error)
+	var stmt StatementEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &stmt))
+	require.Equal(t, "abc", stmt.Digest)
+	require.Equal(t, []string{"test.t"}, stmt.Tables)
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/auth"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/privilege/privileges"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// AuthPlugin authenticates the accounts against an external service, such as LDAP or PAM.
+// An account uses the plugin if the plugin column of mysql.user is the name of the plugin.
+type AuthPlugin interface {
+	// Name returns the name of the plugin, it is also sent to the client in the auth-switch packet.
+	Name() string
+	// Authenticate returns nil if the user is authenticated. authData is the response of the client
+	// to the auth-switch packet, and salt is the one sent in it.
+	Authenticate(user, host string, authData []byte, salt []byte, tls bool) error
+}
+
+// AuthPluginWithClientPlugin is implemented by the AuthPlugin whose client side plugin differs from its name.
+// For example, an LDAP plugin asks the client to send the password by mysql_clear_password, which is only
+// permitted on TLS or unix socket connections.
+type AuthPluginWithClientPlugin interface {
+	AuthPlugin
+	// ClientPluginName returns the name of the client side plugin.
+	ClientPluginName() string
+}
+
+// RegisterAuthPlugin registers an external authentication plugin, it must be called before Run.
+func (s *Server) RegisterAuthPlugin(p AuthPlugin) error {
+	name := p.Name()
+	switch name {
+	case "", mysql.AuthNativePassword, mysql.AuthCachingSha2Password, mysql.AuthSha256Password, mysql.AuthSocket, mysql.AuthClearPassword:
+		return errors.Errorf("can not register the auth plugin '%s'", name)
+	}
+	s.rwlock.Lock()
+	defer s.rwlock.Unlock()
+	if _, ok := s.authPlugins[name]; ok {
+		return errors.Errorf("the auth plugin '%s' is already registered", name)
+	}
+	if s.authPlugins == nil {
+		s.authPlugins = make(map[string]AuthPlugin)
+	}
+	s.authPlugins[name] = p
+	privileges.RegisterExternalAuthPlugin(name)
+	return nil
+}
+
+func (s *Server) getAuthPlugin(name string) (AuthPlugin, bool) {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+	p, ok := s.authPlugins[name]
+	return p, ok
+}
+
+// clientPluginName returns the name of the client side plugin of p.
+func clientPluginName(p AuthPlugin) string {
+	if cp, ok := p.(AuthPluginWithClientPlugin); ok {
+		return cp.ClientPluginName()
+	}
+	return p.Name()
+}
+
+// switchToAuthPlugin asks the client to switch to the client side plugin of p, if the client hasn't used it.
+func (cc *clientConn) switchToAuthPlugin(ctx context.Context, resp *handshakeResponse41, p AuthPlugin, host, hasPassword string) ([]byte, error) {
+	clientPlugin := clientPluginName(p)
+	if clientPlugin == mysql.AuthClearPassword && !cc.isSecureTransport() {
+		logutil.Logger(ctx).Warn("mysql_clear_password requires a TLS or unix socket connection",
+			zap.String("plugin", p.Name()), zap.String("user", cc.user), zap.String("host", host))
+		return nil, errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	}
+	if resp.Capability&mysql.ClientPluginAuth == 0 {
+		return nil, errNotSupportedAuthMode
+	}
+	var authData []byte
+	if cc.authPlugin != clientPlugin || resp.AuthPlugin != clientPlugin {
+		var err error
+		if authData, err = cc.authSwitchRequest(ctx, clientPlugin); err != nil {
+			return nil, err
+		}
+	}
+	resp.AuthPlugin = p.Name()
+	return authData, nil
+}
+
+// authWithPlugin authenticates the user by the external plugin p.
+func (cc *clientConn) authWithPlugin(p AuthPlugin, host string, authData []byte, hasPassword string) error {
+	clientPlugin := clientPluginName(p)
+	if clientPlugin == mysql.AuthClearPassword {
+		if !cc.isSecureTransport() {
+			return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+		}
+		authData = bytes.TrimRight(authData, "\x00")
+	}
+	// The plugin sent by the client can't be trusted, it must be the one of the account.
+	identity, err := cc.ctx.MatchIdentity(cc.user, host)
+	if err != nil {
+		return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	}
+	if userPlugin, err := cc.ctx.AuthPluginForUser(identity); err != nil || userPlugin != p.Name() {
+		return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	}
+	// The TLS requirements, the account lock and the failed logins are checked like the password accounts,
+	// only the password is replaced by the plugin.
+	authenticate := func() bool {
+		if err := p.Authenticate(cc.user, host, authData, cc.salt, cc.tlsConn != nil); err != nil {
+			logutil.BgLogger().Warn("external authentication failed", zap.String("plugin", p.Name()),
+				zap.String("user", cc.user), zap.String("host", host), zap.Error(err))
+			return false
+		}
+		return true
+	}
+	if !cc.ctx.AuthWithPlugin(&auth.UserIdentity{Username: cc.user, Hostname: host}, authenticate) {
+		return cc.authFailedError(host, hasPassword)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser/charset"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/dbterror"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+var errCharsetMismatch = dbterror.ClassServer.NewStd(errno.ErrCharsetMismatch)
+
+// CharsetMismatchInfo is the number of the suspected charset mismatches of a user.
+type CharsetMismatchInfo struct {
+	User  string `json:"user"`
+	Count uint64 `json:"count"`
+}
+
+// maxCharsetMismatchUsers is the maximal number of the users tracked by charsetMismatchTracker, the
+// mismatches of the users seen after the limit is reached are counted under userMetricsLabelOther.
+const maxCharsetMismatchUsers = 100
+
+// charsetMismatchTracker counts the suspected charset mismatches per user on this instance.
+type charsetMismatchTracker struct {
+	sync.Mutex
+	users map[string]uint64
+}
+
+var charsetMismatches = &charsetMismatchTracker{users: make(map[string]uint64)}
+
+func (t *charsetMismatchTracker) inc(user string) {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.users[user]; !ok && len(t.users) >= maxCharsetMismatchUsers {
+		user = userMetricsLabelOther
+	}
+	t.users[user]++
+}
+
+// GetCharsetMismatches returns the number of the suspected charset mismatches of each user, sorted by the user.
+func GetCharsetMismatches() []CharsetMismatchInfo {
+	charsetMismatches.Lock()
+	defer charsetMismatches.Unlock()
+	infos := make([]CharsetMismatchInfo, 0, len(charsetMismatches.users))
+	for user, count := range charsetMismatches.users {
+		infos = append(infos, CharsetMismatchInfo{User: user, Count: count})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].User < infos[j].User })
+	return infos
+}
+
+// suspectCharsetMismatch returns true if sql is declared in the legacy charset chs, but it contains
+// multi-byte utf8 characters, and it is valid utf8 and invalid in chs. It usually means the client
+// sends utf8 but declares another charset, and the data is converted twice.
+func suspectCharsetMismatch(chs, sql string) bool {
+	enc := charset.NewEncoding(chs)
+	if enc == charset.UTF8Encoding || enc == charset.BinaryEncoding {
+		return false
+	}
+	multiByte := false
+	for i := 0; i < len(sql); i++ {
+		if sql[i] >= utf8.RuneSelf {
+			multiByte = true
+			break
+		}
+	}
+	if !multiByte || !utf8.ValidString(sql) {
+		return false
+	}
+	_, err := enc.DecodeString(sql)
+	return err != nil
+}
+
+// checkCharsetMismatch detects whether the statement is suspected to be sent in utf8 while
+// character_set_client is a legacy charset. It returns a warning the first time it is detected
+// on the connection, or an error if the statement should be rejected. It does nothing if the
+// detection is off.
+func (cc *clientConn) checkCharsetMismatch(ctx context.Context, sql string) (warn error, err error) {
+	action := config.GetGlobalConfig().CharsetMismatchCheck
+	if action == config.CharsetMismatchCheckOff || action == "" {
+		return nil, nil
+	}
+	chs, err := variable.GetSessionOrGlobalSystemVar(cc.ctx.GetSessionVars(), variable.CharacterSetClient)
+	if err != nil || !suspectCharsetMismatch(chs, sql) {
+		return nil, nil
+	}
+	cc.charsetMismatches++
+	charsetMismatches.inc(cc.user)
+	metrics.CharsetMismatchCounter.WithLabelValues(chs).Inc()
+	if action == config.CharsetMismatchCheckStrict {
+		return nil, errCharsetMismatch.GenWithStackByArgs(chs)
+	}
+	if cc.charsetMismatches > 1 {
+		return nil, nil
+	}
+	logutil.Logger(ctx).Warn("the statement is valid utf8 but invalid in character_set_client",
+		zap.String("charset", chs), zap.String("user", cc.user))
+	return errCharsetMismatch.FastGenByArgs(chs), nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"net"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/mysql"
+)
+
+const (
+	compressedHeaderSize = 7
+	// minCompressLength is the minimal payload length to be compressed, the shorter payloads are sent as is
+	// because the compression cannot make them shorter. It is the MIN_COMPRESS_LENGTH of MySQL.
+	minCompressLength = 50
+	// maxRetainedWriteBufSize is the maximal size of the write buffer kept after writing a packet.
+	maxRetainedWriteBufSize = 4 * defaultWriterSize
+)
+
+// compressedConn is a net.Conn that implements the compressed MySQL protocol negotiated by CLIENT_COMPRESS.
+// Each compressed packet has a 7-byte header which contains the length of the compressed payload, the
+// sequence and the length of the payload before compression, followed by the payload compressed by zlib.
+// The length before compression is 0 if the payload is not compressed. The payload is a part of the stream
+// of the ordinary packets, so an ordinary packet may be split into several compressed packets.
+type compressedConn struct {
+	net.Conn
+	// sequence is shared by reading and writing, and is reset with the sequence of the ordinary packets.
+	sequence uint8
+
+	// readBuf holds the decompressed data which is not read yet.
+	readBuf  []byte
+	zReader  io.ReadCloser
+	writeBuf bytes.Buffer
+	zWriter  *zlib.Writer
+}
+
+func newCompressedConn(conn net.Conn) *compressedConn {
+	return &compressedConn{
+		Conn:    conn,
+		zWriter: zlib.NewWriter(nil),
+	}
+}
+
+// Read reads the decompressed data.
+func (c *compressedConn) Read(b []byte) (int, error) {
+	for len(c.readBuf) == 0 {
+		if err := c.readCompressedPacket(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+func (c *compressedConn) readCompressedPacket() error {
+	var header [compressedHeaderSize]byte
+	if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+		return errors.Trace(err)
+	}
+	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	sequence := header[3]
+	uncompressedLength := int(uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16)
+	if sequence != c.sequence {
+		return errInvalidSequence.GenWithStack("invalid compressed sequence %d != %d", sequence, c.sequence)
+	}
+	c.sequence++
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.Conn, payload); err != nil {
+		return errors.Trace(err)
+	}
+	if uncompressedLength == 0 {
+		c.readBuf = payload
+		return nil
+	}
+
+	var err error
+	if c.zReader == nil {
+		c.zReader, err = zlib.NewReader(bytes.NewReader(payload))
+	} else {
+		err = c.zReader.(zlib.Resetter).Reset(bytes.NewReader(payload), nil)
+	}
+	if err != nil {
+		return errors.Trace(mysql.ErrMalformPacket)
+	}
+	data := make([]byte, uncompressedLength)
+	if _, err = io.ReadFull(c.zReader, data); err != nil {
+		return errors.Trace(mysql.ErrMalformPacket)
+	}
+	c.readBuf = data
+	return nil
+}
+
+// Write compresses the data and writes it in one or more compressed packets.
+func (c *compressedConn) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		size := len(data)
+		if size > mysql.MaxPayloadLen {
+			size = mysql.MaxPayloadLen
+		}
+		if err := c.writeCompressedPacket(data[:size]); err != nil {
+			return written, err
+		}
+		written += size
+		data = data[size:]
+	}
+	return written, nil
+}
+
+func (c *compressedConn) writeCompressedPacket(payload []byte) error {
+	c.writeBuf.Reset()
+	c.writeBuf.Write(make([]byte, compressedHeaderSize))
+	uncompressedLength := 0
+	if len(payload) >= minCompressLength {
+		c.zWriter.Reset(&c.writeBuf)
+		if _, err := c.zWriter.Write(payload); err != nil {
+			return errors.Trace(err)
+		}
+		if err := c.zWriter.Close(); err != nil {
+			return errors.Trace(err)
+		}
+		uncompressedLength = len(payload)
+	}
+	// Send the payload as is if it is not compressed or the compression does not make it shorter.
+	if uncompressedLength == 0 || c.writeBuf.Len()-compressedHeaderSize >= len(payload) {
+		c.writeBuf.Truncate(compressedHeaderSize)
+		c.writeBuf.Write(payload)
+		uncompressedLength = 0
+	}
+
+	data := c.writeBuf.Bytes()
+	length := len(data) - compressedHeaderSize
+	data[0] = byte(length)
+	data[1] = byte(length >> 8)
+	data[2] = byte(length >> 16)
+	data[3] = c.sequence
+	data[4] = byte(uncompressedLength)
+	data[5] = byte(uncompressedLength >> 8)
+	data[6] = byte(uncompressedLength >> 16)
+	if _, err := c.Conn.Write(data); err != nil {
+		return errors.Trace(err)
+	}
+	c.sequence++
+	if c.writeBuf.Cap() > maxRetainedWriteBufSize {
+		c.writeBuf = bytes.Buffer{}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackConn reads what is written to it.
+type loopbackConn struct {
+	bytesConn
+}
+
+func (c *loopbackConn) Write(b []byte) (n int, err error) {
+	return c.b.Write(b)
+}
+
+func TestCompressedConn(t *testing.T) {
+	t.Parallel()
+
+	conn := &loopbackConn{}
+	writer := newCompressedConn(conn)
+	short := []byte("select 1")
+	long := bytes.Repeat([]byte("select 1;"), 1000)
+	for _, data := range [][]byte{short, long} {
+		n, err := writer.Write(data)
+		require.NoError(t, err)
+		require.Equal(t, len(data), n)
+	}
+	raw := conn.b.Bytes()
+	// The short payload is sent as is.
+	require.Equal(t, []byte{byte(len(short)), 0, 0, 0, 0, 0, 0}, raw[:compressedHeaderSize])
+	require.Equal(t, short, raw[compressedHeaderSize:compressedHeaderSize+len(short)])
+	// The long payload is compressed.
+	header := raw[compressedHeaderSize+len(short):]
+	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	require.Less(t, length, len(long))
+	require.Equal(t, byte(1), header[3])
+	require.Equal(t, len(long), int(uint32(header[4])|uint32(header[5])<<8|uint32(header[6])<<16))
+	require.Len(t, header, compressedHeaderSize+length)
+
+	reader := newCompressedConn(conn)
+	data, err := io.ReadAll(io.LimitReader(reader, int64(len(short)+len(long))))
+	require.NoError(t, err)
+	require.Equal(t, append(append([]byte{}, short...), long...), data)
+
+	// The payload longer than the max payload length is split.
+	huge := make([]byte, mysql.MaxPayloadLen+10)
+	writer.sequence = 0
+	_, err = writer.Write(huge)
+	require.NoError(t, err)
+	require.Equal(t, uint8(2), writer.sequence)
+	reader.sequence = 0
+	data, err = io.ReadAll(io.LimitReader(reader, int64(len(huge))))
+	require.NoError(t, err)
+	require.Equal(t, huge, data)
+
+	// The sequence is checked.
+	_, err = writer.Write(short)
+	require.NoError(t, err)
+	reader.sequence = 0
+	_, err = reader.Read(make([]byte, 1))
+	require.True(t, errInvalidSequence.Equal(err))
+}
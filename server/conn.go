@@ -72,6 +72,7 @@ import (
 	plannercore "github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/plugin"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
@@ -145,6 +146,7 @@ var (
 
 	disconnectNormal            = metrics.DisconnectionCounter.WithLabelValues(metrics.LblOK)
 	disconnectByClientWithError = metrics.DisconnectionCounter.WithLabelValues(metrics.LblError)
+	disconnectByTimeout         = metrics.DisconnectionCounter.WithLabelValues(metrics.LblTimeout)
 	disconnectErrorUndetermined = metrics.DisconnectionCounter.WithLabelValues("undetermined")
 
 	connIdleDurationHistogramNotInTxn = metrics.ConnIdleDurationHistogram.WithLabelValues("0")
@@ -181,7 +183,7 @@ type clientConn struct {
 	chunkAlloc    chunk.Allocator
 	lastPacket    []byte            // latest sql query string, currently used for logging error.
 	ctx           *TiDBContext      // an interface to execute sql statements.
-	attrs         map[string]string // attributes parsed from client handshake response, not used for now.
+	attrs         map[string]string // attributes parsed from client handshake response.
 	peerHost      string            // peer host
 	peerPort      string            // peer port
 	status        int32             // dispatching/reading/shutdown/waitshutdown
@@ -192,6 +194,30 @@ type clientConn struct {
 	isUnixSocket  bool              // connection is Unix Socket file
 	rsEncoder     *resultEncoder    // rsEncoder is used to encode the string result to different charsets.
 	socketCredUID uint32            // UID from the other end of the Unix Socket
+	// tlsHandshakeFailed is set if the TLS handshake of the connection failed.
+	tlsHandshakeFailed bool
+	// passwordExpired is true if the password of the account is expired, the connection is in the sandbox mode
+	// and only the statements to change the password are allowed.
+	passwordExpired bool
+	// charsetMismatches is the number of the statements suspected to be sent in utf8 while
+	// character_set_client is a legacy charset.
+	charsetMismatches uint64
+	// userConnKey is the account the connection is counted for MAX_USER_CONNECTIONS.
+	userConnKey string
+	// userMetrics is the metrics of the account, it is nil before the handshake is finished.
+	userMetrics *userMetrics
+	// limitHost is the client IP the connection is counted for tidb_max_connections_per_ip,
+	// it is empty for the unix socket.
+	limitHost string
+	// lastActivity is the unix nano time of the last successful packet read or the start of the current idle
+	// period, it is accessed atomically by the idle watcher.
+	lastActivity int64
+	// waitTimeout is the timeout in seconds of the current idle period, which is wait_timeout, or
+	// tidb_idle_transaction_timeout if the connection is in a transaction. It is accessed atomically.
+	waitTimeout int64
+	// sentRows is the number of the rows of the result set written for the current statement, it is
+	// only counted for the audit hooks.
+	sentRows uint64
 	// mu is used for cancelling the execution of current transaction.
 	mu struct {
 		sync.RWMutex
@@ -247,10 +273,22 @@ func (cc *clientConn) authSwitchRequest(ctx context.Context, plugin string) ([]b
 	return resp, nil
 }
 
+// setDeadline sets the read and write deadline of the connection, the zero value clears the deadline.
+func (cc *clientConn) setDeadline(deadline time.Time) error {
+	cc.pkt.deadline = deadline
+	return cc.bufReadConn.SetDeadline(deadline)
+}
+
 // handshake works like TCP handshake, but in a higher level, it first writes initial packet to client,
 // during handshake, client and server negotiate compatible features and do authentication.
 // After handshake, client can send sql query to server.
 func (cc *clientConn) handshake(ctx context.Context) error {
+	// A client which is slow or never finishes the handshake can't hold the connection forever.
+	if timeout := config.GetGlobalConfig().Performance.HandshakeTimeoutSeconds; timeout > 0 {
+		if err := cc.setDeadline(time.Now().Add(time.Duration(timeout) * time.Second)); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	if err := cc.writeInitialHandshake(ctx); err != nil {
 		if errors.Cause(err) == io.EOF {
 			logutil.Logger(ctx).Debug("Could not send handshake due to connection has be closed by client-side")
@@ -266,6 +304,13 @@ func (cc *clientConn) handshake(ctx context.Context) error {
 		}
 		return err
 	}
+	if err := cc.setDeadline(time.Time{}); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := cc.initWaitTimeout(); err != nil {
+		logutil.Logger(ctx).Warn("init wait_timeout failed", zap.Error(err))
+	}
 
 	// MySQL supports an "init_connect" query, which can be run on initial connection.
 	// The query must return a non-error or the client is disconnected.
@@ -287,7 +332,7 @@ func (cc *clientConn) handshake(ctx context.Context) error {
 	}
 
 	err := cc.writePacket(data)
-	cc.pkt.sequence = 0
+	cc.pkt.resetSequence()
 	if err != nil {
 		err = errors.SuspendStack(err)
 		logutil.Logger(ctx).Debug("write response to client failed", zap.Error(err))
@@ -300,19 +345,31 @@ func (cc *clientConn) handshake(ctx context.Context) error {
 		logutil.Logger(ctx).Debug("flush response to client failed", zap.Error(err))
 		return err
 	}
-	return err
+	// Like MySQL, the packets after the OK packet of the handshake are compressed if the client asks for it.
+	if cc.capability&mysql.ClientCompress > 0 {
+		cc.enableCompression()
+	}
+
+	vars := cc.ctx.GetSessionVars()
+	metrics.ConnectionTransportCounter.WithLabelValues(vars.ConnectionTransport).Inc()
+	if vars.TLSConnectionState != nil && len(vars.TLSConnectionState.VerifiedChains) > 0 {
+		metrics.ClientCertAuthCounter.Inc()
+	}
+	return nil
 }
 
 func (cc *clientConn) Close() error {
 	cc.server.rwlock.Lock()
 	delete(cc.server.clients, cc.connectionID)
-	connections := len(cc.server.clients)
+	cc.server.releaseUserConnLocked(cc)
 	cc.server.rwlock.Unlock()
-	return closeConn(cc, connections)
+	return closeConn(cc)
 }
 
-func closeConn(cc *clientConn, connections int) error {
-	metrics.ConnGauge.Set(float64(connections))
+func closeConn(cc *clientConn) error {
+	if cc.pkt != nil {
+		cc.pkt.writeBuffer.unregister()
+	}
 	if cc.bufReadConn != nil {
 		err := cc.bufReadConn.Close()
 		terror.Log(err)
@@ -325,7 +382,8 @@ func closeConn(cc *clientConn, connections int) error {
 
 func (cc *clientConn) closeWithoutLock() error {
 	delete(cc.server.clients, cc.connectionID)
-	return closeConn(cc, len(cc.server.clients))
+	cc.server.releaseUserConnLocked(cc)
+	return closeConn(cc)
 }
 
 // writeInitialHandshake sends server version, connection ID, server capability, collation, server status
@@ -391,7 +449,11 @@ func (cc *clientConn) writeInitialHandshake(ctx context.Context) error {
 }
 
 func (cc *clientConn) readPacket() ([]byte, error) {
-	return cc.pkt.readPacket()
+	data, err := cc.pkt.readPacket()
+	if err == nil {
+		atomic.StoreInt64(&cc.lastActivity, time.Now().UnixNano())
+	}
+	return data, err
 }
 
 func (cc *clientConn) writePacket(data []byte) error {
@@ -418,6 +480,28 @@ func (cc *clientConn) getSessionVarsWaitTimeout(ctx context.Context) uint64 {
 	return waitTimeout
 }
 
+// initWaitTimeout initializes the session wait_timeout from interactive_timeout if the client is interactive,
+// which is the same as MySQL. It must be called after the capability is negotiated.
+func (cc *clientConn) initWaitTimeout() error {
+	if cc.capability&mysql.ClientInteractive == 0 {
+		return nil
+	}
+	vars := cc.ctx.GetSessionVars()
+	interactiveTimeout, exists := vars.GetSystemVar(variable.InteractiveTimeout)
+	if !exists {
+		return nil
+	}
+	return vars.SetSystemVar(variable.WaitTimeout, interactiveTimeout)
+}
+
+// getIdleTransactionTimeout returns tidb_idle_transaction_timeout if the connection is in a transaction, otherwise 0.
+func (cc *clientConn) getIdleTransactionTimeout() uint64 {
+	if cc.ctx.Status()&mysql.ServerStatusInTrans == 0 {
+		return 0
+	}
+	return uint64(cc.ctx.GetSessionVars().IdleTransactionTimeout)
+}
+
 type handshakeResponse41 struct {
 	Capability uint32
 	Collation  uint8
@@ -663,10 +747,12 @@ func (cc *clientConn) readOptionalSSLRequestAndHandshakeResponse(ctx context.Con
 				return err
 			}
 		}
-	} else if config.GetGlobalConfig().Security.RequireSecureTransport {
-		err := errSecureTransportRequired.FastGenByArgs()
-		terror.Log(err)
-		return err
+	}
+	// The unix socket is considered secure, so only the plaintext TCP connections are rejected.
+	if !cc.isSecureTransport() && config.GetGlobalConfig().Security.RequireSecureTransport {
+		metrics.InsecureTransportRejectCounter.Inc()
+		logutil.Logger(ctx).Info("reject the connection using insecure transport", zap.String("host", cc.peerHost))
+		return errSecureTransportRequired.FastGenByArgs()
 	}
 
 	// Read the remaining part of the packet.
@@ -685,29 +771,47 @@ func (cc *clientConn) readOptionalSSLRequestAndHandshakeResponse(ctx context.Con
 	cc.dbname = resp.DBName
 	cc.collation = resp.Collation
 	cc.attrs = resp.Attrs
+	// The session is usually opened before the handshake response is read.
+	if cc.ctx != nil {
+		cc.ctx.GetSessionVars().ConnectionAttrs = cc.attrs
+	}
 
 	err = cc.handleAuthPlugin(ctx, &resp)
 	if err != nil {
 		return err
 	}
+	if err = cc.readPluginAuthData(ctx, &resp); err != nil {
+		return err
+	}
 
+	err = cc.openSessionAndDoAuth(resp.Auth, resp.AuthPlugin)
+	if err != nil {
+		logutil.Logger(ctx).Warn("open new session or authentication failure", zap.Error(err))
+	}
+	return err
+}
+
+// readPluginAuthData reads the extra rounds of the auth plugin chosen by handleAuthPlugin.
+func (cc *clientConn) readPluginAuthData(ctx context.Context, resp *handshakeResponse41) (err error) {
 	switch resp.AuthPlugin {
 	case mysql.AuthCachingSha2Password:
 		resp.Auth, err = cc.authSha(ctx)
 		if err != nil {
 			return err
 		}
+	case mysql.AuthSha256Password:
+		resp.Auth, err = cc.authSha256(ctx, resp.Auth)
+		if err != nil {
+			return err
+		}
 	case mysql.AuthNativePassword:
 	case mysql.AuthSocket:
 	default:
-		return errors.New("Unknown auth plugin")
-	}
-
-	err = cc.openSessionAndDoAuth(resp.Auth, resp.AuthPlugin)
-	if err != nil {
-		logutil.Logger(ctx).Warn("open new session or authentication failure", zap.Error(err))
+		if _, ok := cc.server.getAuthPlugin(resp.AuthPlugin); !ok {
+			return errors.New("Unknown auth plugin")
+		}
 	}
-	return err
+	return nil
 }
 
 func (cc *clientConn) handleAuthPlugin(ctx context.Context, resp *handshakeResponse41) error {
@@ -723,10 +827,13 @@ func (cc *clientConn) handleAuthPlugin(ctx context.Context, resp *handshakeRespo
 
 		switch resp.AuthPlugin {
 		case mysql.AuthCachingSha2Password:
+		case mysql.AuthSha256Password:
 		case mysql.AuthNativePassword:
 		case mysql.AuthSocket:
 		default:
-			logutil.Logger(ctx).Warn("Unknown Auth Plugin", zap.String("plugin", resp.AuthPlugin))
+			if _, ok := cc.server.getAuthPlugin(resp.AuthPlugin); !ok {
+				logutil.Logger(ctx).Warn("Unknown Auth Plugin", zap.String("plugin", resp.AuthPlugin))
+			}
 		}
 	} else {
 		// MySQL 5.1 and older clients don't support authentication plugins.
@@ -745,7 +852,7 @@ func (cc *clientConn) authSha(ctx context.Context) ([]byte, error) {
 
 	const (
 		ShaCommand       = 1
-		RequestRsaPubKey = 2 // Not supported yet, only TLS is supported as secure channel.
+		RequestRsaPubKey = 2
 		FastAuthOk       = 3
 		FastAuthFail     = 4
 	)
@@ -768,9 +875,84 @@ func (cc *clientConn) authSha(ctx context.Context) ([]byte, error) {
 		logutil.Logger(ctx).Error("authSha packet read failed", zap.Error(err))
 		return nil, err
 	}
+	// Over insecure transport the client asks for the RSA public key and sends the encrypted password.
+	if !cc.isSecureTransport() && len(data) == 1 && data[0] == RequestRsaPubKey {
+		return cc.authRSA(ctx)
+	}
 	return bytes.Trim(data, "\x00"), nil
 }
 
+// authSha256 implements the sha256_password specific part of the protocol. The client sends the password
+// in clear text over TLS or unix socket, otherwise the password is encrypted by the server's RSA public key.
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_authentication_methods_sha256_password_authentication.html
+func (cc *clientConn) authSha256(ctx context.Context, authData []byte) ([]byte, error) {
+	const RequestRsaPubKey = 1
+
+	if len(authData) == 0 || (len(authData) == 1 && authData[0] == 0) {
+		// Empty password.
+		return nil, nil
+	}
+	if cc.isSecureTransport() {
+		return bytes.TrimRight(authData, "\x00"), nil
+	}
+	if len(authData) == 1 && authData[0] == RequestRsaPubKey {
+		return cc.authRSA(ctx)
+	}
+	// The client already has the public key and sends the encrypted password directly.
+	keyPair, err := cc.server.getRSAKeyPair()
+	if err != nil {
+		logutil.Logger(ctx).Error("get RSA key pair failed", zap.Error(err))
+		return nil, err
+	}
+	return cc.decryptRSAPassword(ctx, keyPair, authData)
+}
+
+// authRSA sends the RSA public key to the client and decrypts the password sent back with it.
+// The key pair is captured before it is sent, so that rotating the key in the middle of the
+// exchange doesn't break the authentication.
+func (cc *clientConn) authRSA(ctx context.Context) ([]byte, error) {
+	keyPair, err := cc.server.getRSAKeyPair()
+	if err != nil {
+		logutil.Logger(ctx).Error("get RSA key pair failed", zap.Error(err))
+		return nil, err
+	}
+	data := make([]byte, 4, 5+len(keyPair.publicKeyPEM))
+	data = append(data, mysql.AuthMoreData)
+	data = append(data, keyPair.publicKeyPEM...)
+	if err = cc.writePacket(data); err != nil {
+		logutil.Logger(ctx).Error("RSA public key packet write failed", zap.Error(err))
+		return nil, err
+	}
+	if err = cc.flush(ctx); err != nil {
+		logutil.Logger(ctx).Error("RSA public key packet flush failed", zap.Error(err))
+		return nil, err
+	}
+	encrypted, err := cc.readPacket()
+	if err != nil {
+		logutil.Logger(ctx).Error("RSA encrypted password packet read failed", zap.Error(err))
+		return nil, err
+	}
+	return cc.decryptRSAPassword(ctx, keyPair, encrypted)
+}
+
+func (cc *clientConn) decryptRSAPassword(ctx context.Context, keyPair *rsaKeyPair, encrypted []byte) ([]byte, error) {
+	host, _, err := cc.PeerHost("YES")
+	if err != nil {
+		return nil, err
+	}
+	password, err := keyPair.decryptPassword(encrypted, cc.salt)
+	if err != nil {
+		logutil.Logger(ctx).Warn("decrypt RSA encrypted password failed", zap.String("user", cc.user), zap.Error(err))
+		return nil, errAccessDenied.FastGenByArgs(cc.user, host, "YES")
+	}
+	return password, nil
+}
+
+// isSecureTransport returns whether the password can be sent in clear text.
+func (cc *clientConn) isSecureTransport() bool {
+	return cc.tlsConn != nil || cc.isUnixSocket
+}
+
 func (cc *clientConn) SessionStatusToString() string {
 	status := cc.ctx.Status()
 	inTxn, autoCommit := 0, 0
@@ -792,15 +974,11 @@ func (cc *clientConn) openSession() error {
 		tlsStatePtr = &tlsState
 	}
 	var err error
-	cc.ctx, err = cc.server.driver.OpenCtx(cc.connectionID, cc.capability, cc.collation, cc.dbname, tlsStatePtr)
-	if err != nil {
-		return err
-	}
-
-	err = cc.server.checkConnectionCount()
+	cc.ctx, err = cc.server.driver.OpenCtxWithAttributes(cc.connectionID, cc.capability, cc.collation, cc.dbname, cc.attrs, tlsStatePtr)
 	if err != nil {
 		return err
 	}
+	cc.ctx.GetSessionVars().ConnectionTransport = tidbutil.ConnectionTransport(tlsStatePtr, cc.isUnixSocket)
 	return nil
 }
 
@@ -826,8 +1004,22 @@ func (cc *clientConn) openSessionAndDoAuth(authData []byte, authPlugin string) e
 		return errAccessDeniedNoPassword.FastGenByArgs(cc.user, host)
 	}
 
-	if !cc.ctx.Auth(&auth.UserIdentity{Username: cc.user, Hostname: host}, authData, cc.salt) {
-		return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	if p, ok := cc.server.getAuthPlugin(authPlugin); ok {
+		if err := cc.authWithPlugin(p, host, authData, hasPassword); err != nil {
+			return err
+		}
+	} else if !cc.ctx.Auth(&auth.UserIdentity{Username: cc.user, Hostname: host}, authData, cc.salt) {
+		return cc.authFailedError(host, hasPassword)
+	}
+	cc.passwordExpired = cc.isPasswordExpired()
+	if cc.passwordExpired && cc.capability&mysql.ClientCanHandleExpiredPasswords == 0 {
+		return errMustChangePasswordLogin
+	}
+	if err := cc.proxyAs(host, hasPassword); err != nil {
+		return err
+	}
+	if err := cc.acquireUserConn(); err != nil {
+		return err
 	}
 	cc.ctx.SetPort(port)
 	if cc.dbname != "" {
@@ -840,6 +1032,53 @@ func (cc *clientConn) openSessionAndDoAuth(authData []byte, authPlugin string) e
 	return nil
 }
 
+// isPasswordExpired returns whether the password of the logged-in account is expired.
+func (cc *clientConn) isPasswordExpired() bool {
+	user := cc.ctx.GetSessionVars().User
+	pm := privilege.GetPrivilegeManager(cc.ctx.Session)
+	return user != nil && pm != nil && pm.IsPasswordExpired(user.AuthUsername, user.AuthHostname)
+}
+
+// allowedWithExpiredPassword returns whether the statement can be executed in the sandbox mode,
+// which is entered when the password is expired. Like MySQL, only the password can be changed.
+func allowedWithExpiredPassword(stmt ast.StmtNode) bool {
+	switch stmt.(type) {
+	case *ast.SetPwdStmt, *ast.AlterUserStmt, *ast.SetStmt:
+		return true
+	}
+	return false
+}
+
+// authFailedError returns the error reported to the client when the authentication fails.
+func (cc *clientConn) authFailedError(host, hasPassword string) error {
+	// The lock is only reported to the clients with the right credentials.
+	if pm := privilege.GetPrivilegeManager(cc.ctx.Session); pm != nil && pm.IsVerifiedAccountLocked() {
+		return errAccountLocked.FastGenByArgs(cc.user, host)
+	}
+	if err := cc.checkAccountBlocked(host); err != nil {
+		return err
+	}
+	return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+}
+
+// checkAccountBlocked returns an error if the account is temporarily locked
+// because of too many consecutive failed logins.
+func (cc *clientConn) checkAccountBlocked(host string) error {
+	authUser, err := cc.ctx.MatchIdentity(cc.user, host)
+	if err != nil {
+		return nil
+	}
+	info, ok := privileges.GetFailedLogin(authUser.Username, authUser.Hostname)
+	if !ok || !info.Locked {
+		return nil
+	}
+	lockDays, remainingDays := "unlimited", "unlimited"
+	if remaining := info.RemainingDays(time.Now()); remaining >= 0 {
+		lockDays, remainingDays = strconv.FormatInt(info.LockTimeDays, 10), strconv.FormatInt(remaining, 10)
+	}
+	return errAccountBlocked.FastGenByArgs(cc.user, host, lockDays, remainingDays, info.FailedAttempts)
+}
+
 // Check if the Authentication Plugin of the server, client and user configuration matches
 func (cc *clientConn) checkAuthPlugin(ctx context.Context, resp *handshakeResponse41) ([]byte, error) {
 	// Open a context unless this was done before.
@@ -875,15 +1114,22 @@ func (cc *clientConn) checkAuthPlugin(ctx context.Context, resp *handshakeRespon
 	})
 	if userplugin == mysql.AuthSocket {
 		if !cc.isUnixSocket {
-			return nil, errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+			logutil.Logger(ctx).Warn("auth_socket plugin requires a unix socket connection",
+				zap.String("user", cc.user), zap.String("host", host))
+			return nil, errAccessDeniedNoPassword.FastGenByArgs(cc.user, host)
 		}
 		resp.AuthPlugin = mysql.AuthSocket
 		user, err := user.LookupId(fmt.Sprint(cc.socketCredUID))
 		if err != nil {
-			return nil, err
+			logutil.Logger(ctx).Warn("Failed to look up the OS user of the unix socket peer",
+				zap.String("user", cc.user), zap.Uint32("uid", cc.socketCredUID), zap.Error(err))
+			return nil, errAccessDeniedNoPassword.FastGenByArgs(cc.user, host)
 		}
 		return []byte(user.Username), nil
 	}
+	if p, ok := cc.server.getAuthPlugin(userplugin); ok {
+		return cc.switchToAuthPlugin(ctx, resp, p, host, hasPassword)
+	}
 	if len(userplugin) == 0 {
 		// No user plugin set, assuming MySQL Native Password
 		// This happens if the account doesn't exist or if the account doesn't have
@@ -938,6 +1184,7 @@ func (cc *clientConn) PeerHost(hasPassword string) (host, port string, err error
 		err = errAccessDenied.GenWithStackByArgs(cc.user, addr, hasPassword)
 		return
 	}
+	host = tidbutil.NormalizeIP(host)
 	cc.peerHost = host
 	cc.peerPort = port
 	return
@@ -1031,12 +1278,28 @@ func (cc *clientConn) Run(ctx context.Context) {
 		}
 	}()
 
+	idleWatcherDone := make(chan struct{})
+	defer close(idleWatcherDone)
+	go cc.watchIdleTimeout(ctx, idleWatcherDone)
+
 	// Usually, client connection status changes between [dispatching] <=> [reading].
 	// When some event happens, server may notify this client connection by setting
 	// the status to special values, for example: kill or graceful shutdown.
 	// The client connection would detect the events when it fails to change status
 	// by CAS operation, it would then take some actions accordingly.
 	for {
+		// close connection when idle time is more than wait_timeout, which is initialized from
+		// interactive_timeout for interactive clients. The deadline is only set while reading the
+		// next command, so long-running statements are not affected.
+		waitTimeout := cc.getSessionVarsWaitTimeout(ctx)
+		// kill connection when its transaction is idle for longer than tidb_idle_transaction_timeout
+		idleTxnTimeout := cc.getIdleTransactionTimeout()
+		killIdleTxn := idleTxnTimeout > 0 && (waitTimeout == 0 || idleTxnTimeout < waitTimeout)
+		if killIdleTxn {
+			waitTimeout = idleTxnTimeout
+		}
+		atomic.StoreInt64(&cc.waitTimeout, int64(waitTimeout))
+		atomic.StoreInt64(&cc.lastActivity, time.Now().UnixNano())
 		if !atomic.CompareAndSwapInt32(&cc.status, connStatusDispatching, connStatusReading) ||
 			// The judge below will not be hit by all means,
 			// But keep it stayed as a reminder and for the code reference for connStatusWaitShutdown.
@@ -1045,20 +1308,35 @@ func (cc *clientConn) Run(ctx context.Context) {
 		}
 
 		cc.alloc.Reset()
-		// close connection when idle time is more than wait_timeout
-		waitTimeout := cc.getSessionVarsWaitTimeout(ctx)
 		cc.pkt.setReadTimeout(time.Duration(waitTimeout) * time.Second)
 		start := time.Now()
 		data, err := cc.readPacket()
 		if err != nil {
 			if terror.ErrorNotEqual(err, io.EOF) {
-				if netErr, isNetErr := errors.Cause(err).(net.Error); isNetErr && netErr.Timeout() {
+				if netErr, isNetErr := errors.Cause(err).(net.Error); isNetErr && netErr.Timeout() && killIdleTxn {
+					logutil.Logger(ctx).Warn("transaction is idle for longer than tidb_idle_transaction_timeout, kill this connection and roll back the transaction",
+						zap.Duration("idle", time.Since(start)),
+						zap.Uint64("idleTransactionTimeout", idleTxnTimeout),
+						zap.Uint64("txnStartTS", cc.ctx.GetSessionVars().TxnCtx.StartTS),
+					)
+					metrics.IdleTransactionKillCounter.Inc()
+					if err1 := cc.writeError(ctx, executor.ErrQueryInterrupted); err1 != nil {
+						logutil.Logger(ctx).Debug("write query interrupted error to client failed", zap.Error(err1))
+					}
+					disconnectByTimeout.Inc()
+					return
+				} else if isNetErr && netErr.Timeout() {
 					idleTime := time.Since(start)
 					logutil.Logger(ctx).Info("read packet timeout, close this connection",
 						zap.Duration("idle", idleTime),
 						zap.Uint64("waitTimeout", waitTimeout),
 						zap.Error(err),
 					)
+					if err1 := cc.writeError(ctx, errNetRead.FastGenByArgs()); err1 != nil {
+						logutil.Logger(ctx).Debug("write wait_timeout error to client failed", zap.Error(err1))
+					}
+					disconnectByTimeout.Inc()
+					return
 				} else {
 					errStack := errors.ErrorStack(err)
 					if !strings.Contains(errStack, "use of closed network connection") {
@@ -1078,6 +1356,7 @@ func (cc *clientConn) Run(ctx context.Context) {
 		startTime := time.Now()
 		err = cc.dispatch(ctx, data)
 		cc.chunkAlloc.Reset()
+		cc.countUserCommand(data[0])
 		if err != nil {
 			cc.audit(plugin.Error) // tell the plugin API there was a dispatch error
 			if terror.ErrorEqual(err, io.EOF) {
@@ -1113,7 +1392,43 @@ func (cc *clientConn) Run(ctx context.Context) {
 			terror.Log(err1)
 		}
 		cc.addMetrics(data[0], startTime, err)
-		cc.pkt.sequence = 0
+		cc.pkt.resetSequence()
+	}
+}
+
+// idleCheckInterval is the interval of checking whether the connection is idle for longer than the timeout.
+var idleCheckInterval = time.Second
+
+// watchIdleTimeout closes the connection if it has been waiting for the next command for longer than
+// wait_timeout or tidb_idle_transaction_timeout, even if the client keeps it alive by sending a packet slowly,
+// since the read deadline of packetIO is extended on every header and payload read. The blocked read is
+// interrupted by resetting the read deadline, so that clientConn.Run writes the error to the client and
+// closes the connection.
+func (cc *clientConn) watchIdleTimeout(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if atomic.LoadInt32(&cc.status) != connStatusReading {
+			continue
+		}
+		waitTimeout := time.Duration(atomic.LoadInt64(&cc.waitTimeout)) * time.Second
+		if waitTimeout <= 0 {
+			continue
+		}
+		idleTime := time.Since(time.Unix(0, atomic.LoadInt64(&cc.lastActivity)))
+		if idleTime < waitTimeout {
+			continue
+		}
+		logutil.Logger(ctx).Debug("connection is idle for longer than the timeout",
+			zap.Duration("idle", idleTime), zap.Duration("waitTimeout", waitTimeout))
+		if err := cc.bufReadConn.SetReadDeadline(time.Now()); err != nil {
+			logutil.Logger(ctx).Debug("interrupt the idle connection failed", zap.Error(err))
+		}
 	}
 }
 
@@ -1143,9 +1458,9 @@ func errStrForLog(err error, enableRedactLog bool) string {
 	}
 	if kv.ErrKeyExists.Equal(err) || parser.ErrParse.Equal(err) || infoschema.ErrTableNotExists.Equal(err) {
 		// Do not log stack for duplicated entry error.
-		return err.Error()
+		return logutil.EscapeBinaryBytes(err.Error())
 	}
-	return errors.ErrorStack(err)
+	return logutil.EscapeBinaryBytes(errors.ErrorStack(err))
 }
 
 func (cc *clientConn) addMetrics(cmd byte, startTime time.Time, err error) {
@@ -1299,6 +1614,14 @@ func (cc *clientConn) dispatch(ctx context.Context, data []byte) error {
 		cc.ctx.SetProcessInfo("use "+dataStr, t, cmd, 0)
 	}
 
+	if cc.passwordExpired {
+		switch cmd {
+		case mysql.ComSleep, mysql.ComQuit, mysql.ComPing, mysql.ComQuery, mysql.ComChangeUser:
+		default:
+			return errMustChangePassword
+		}
+	}
+
 	switch cmd {
 	case mysql.ComSleep:
 		// TODO: According to mysql document, this command is supposed to be used only internally.
@@ -1540,10 +1863,11 @@ func insertDataWithCommit(ctx context.Context, prevData,
 }
 
 // processStream process input stream from network
-func processStream(ctx context.Context, cc *clientConn, loadDataInfo *executor.LoadDataInfo, wg *sync.WaitGroup) {
-	var err error
+func processStream(ctx context.Context, cc *clientConn, loadDataInfo *executor.LoadDataInfo) (err error) {
 	var shouldBreak bool
 	var prevData, curData []byte
+	var received int64
+	maxFileSize := cc.getMaxLoadDataFileSize()
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -1556,7 +1880,6 @@ func processStream(ctx context.Context, cc *clientConn, loadDataInfo *executor.L
 		} else {
 			loadDataInfo.CloseTaskQueue()
 		}
-		wg.Done()
 	}()
 	for {
 		curData, err = cc.readPacket()
@@ -1573,6 +1896,13 @@ func processStream(ctx context.Context, cc *clientConn, loadDataInfo *executor.L
 				break
 			}
 		}
+		received += int64(len(curData))
+		if maxFileSize > 0 && received > maxFileSize {
+			logutil.Logger(ctx).Warn("the file of LOAD DATA LOCAL INFILE is too large",
+				zap.Int64("received", received), zap.Int64("max-load-data-file-size", maxFileSize))
+			err = errNetPacketTooLarge.FastGenByArgs()
+			break
+		}
 		select {
 		case <-loadDataInfo.QuitCh:
 			err = errors.New("processStream forced to quit")
@@ -1598,6 +1928,14 @@ func processStream(ctx context.Context, cc *clientConn, loadDataInfo *executor.L
 		logutil.Logger(ctx).Error("load data process stream error", zap.Error(err))
 		return
 	}
+	return
+}
+
+func (cc *clientConn) getMaxLoadDataFileSize() int64 {
+	if cc.server == nil || cc.server.cfg == nil {
+		return 0
+	}
+	return cc.server.cfg.Security.MaxLoadDataFileSize
 }
 
 // handleLoadData does the additional work after processing the 'load data' query.
@@ -1630,9 +1968,16 @@ func (cc *clientConn) handleLoadData(ctx context.Context, loadDataInfo *executor
 	// processStream process input data, enqueue commit task
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
-	go processStream(ctx, cc, loadDataInfo, wg)
+	var streamErr error
+	go func() {
+		defer wg.Done()
+		streamErr = processStream(ctx, cc, loadDataInfo)
+	}()
 	err = loadDataInfo.CommitWork(ctx)
 	wg.Wait()
+	if errNetPacketTooLarge.Equal(streamErr) {
+		err = streamErr
+	}
 	if err != nil {
 		if !loadDataInfo.Drained {
 			logutil.Logger(ctx).Info("not drained yet, try reading left data from client connection")
@@ -1748,6 +2093,7 @@ func (cc *clientConn) audit(eventType plugin.GeneralEvent) {
 		if audit.OnGeneralEvent != nil {
 			cmd := mysql.Command2Str[byte(atomic.LoadUint32(&cc.ctx.GetSessionVars().CommandValue))]
 			ctx := context.WithValue(context.Background(), plugin.ExecStartTimeCtxKey, cc.ctx.GetSessionVars().StartTime)
+			ctx = context.WithValue(ctx, plugin.QueryTextCtxKey, logutil.EscapeBinaryBytes(cc.ctx.GetSessionVars().StmtCtx.OriginalSQL))
 			audit.OnGeneralEvent(ctx, cc.ctx.GetSessionVars(), eventType, cmd)
 		}
 		return nil
@@ -1763,6 +2109,10 @@ func (cc *clientConn) audit(eventType plugin.GeneralEvent) {
 // Query `load stats` does not return result either.
 func (cc *clientConn) handleQuery(ctx context.Context, sql string) (err error) {
 	defer trace.StartRegion(ctx, "handleQuery").End()
+	mismatchWarn, err := cc.checkCharsetMismatch(ctx, sql)
+	if err != nil {
+		return err
+	}
 	sc := cc.ctx.GetSessionVars().StmtCtx
 	prevWarns := sc.GetWarnings()
 	stmts, err := cc.ctx.Parse(ctx, sql)
@@ -1774,8 +2124,24 @@ func (cc *clientConn) handleQuery(ctx context.Context, sql string) (err error) {
 		return cc.writeOK(ctx)
 	}
 
+	if cc.passwordExpired {
+		for _, stmt := range stmts {
+			if !allowedWithExpiredPassword(stmt) {
+				return errMustChangePassword
+			}
+		}
+		defer func() {
+			if err == nil {
+				cc.passwordExpired = cc.isPasswordExpired()
+			}
+		}()
+	}
+
 	warns := sc.GetWarnings()
 	parserWarns := warns[len(prevWarns):]
+	if mismatchWarn != nil {
+		parserWarns = append(parserWarns, stmtctx.SQLWarn{Level: stmtctx.WarnLevelWarning, Err: mismatchWarn})
+	}
 
 	var pointPlans []plannercore.Plan
 	if len(stmts) > 1 {
@@ -1942,11 +2308,29 @@ func (cc *clientConn) prefetchPointPlanKeys(ctx context.Context, stmts []ast.Stm
 
 // The first return value indicates whether the call of handleStmt has no side effect and can be retried.
 // Currently, the first return value is used to fall back to TiKV when TiFlash is down.
-func (cc *clientConn) handleStmt(ctx context.Context, stmt ast.StmtNode, warns []stmtctx.SQLWarn, lastStmt bool) (bool, error) {
+func (cc *clientConn) handleStmt(ctx context.Context, stmt ast.StmtNode, warns []stmtctx.SQLWarn, lastStmt bool) (_ bool, err error) {
+	defer cc.countStatement()
+	if cc.auditEnabled() {
+		cc.sentRows = 0
+		start := time.Now()
+		defer func() {
+			cc.auditStatement(start, err)
+		}()
+	}
 	ctx = context.WithValue(ctx, execdetails.StmtExecDetailKey, &execdetails.StmtExecDetails{})
 	ctx = context.WithValue(ctx, util.ExecDetailsKey, &util.ExecDetails{})
 	reg := trace.StartRegion(ctx, "ExecuteStmt")
 	cc.audit(plugin.Starting)
+	queryCacheKey, cacheable := "", false
+	if cc.server != nil && cc.server.queryCache != nil {
+		queryCacheKey, cacheable = cc.queryCacheKey(stmt)
+	}
+	if cacheable {
+		if entry := cc.server.queryCache.get(queryCacheKey); entry != nil {
+			reg.End()
+			return cc.writeCachedResultset(ctx, stmt, entry, warns, lastStmt)
+		}
+	}
 	rs, err := cc.ctx.ExecuteStmt(ctx, stmt)
 	reg.End()
 	// The session tracker detachment from global tracker is solved in the `rs.Close` in most cases.
@@ -1969,9 +2353,19 @@ func (cc *clientConn) handleStmt(ctx context.Context, stmt ast.StmtNode, warns [
 		if connStatus := atomic.LoadInt32(&cc.status); connStatus == connStatusShutdown {
 			return false, executor.ErrQueryInterrupted
 		}
+		var recorder *queryCacheRecorder
+		if cacheable {
+			recorder = newQueryCacheRecorder(rs, queryCacheKey, cc.ctx.GetSessionVars().StmtCtx.Tables, cc.server.queryCache.capacity)
+			rs = recorder
+		}
 		if retryable, err := cc.writeResultset(ctx, rs, false, status, 0); err != nil {
 			return retryable, err
 		}
+		if recorder != nil {
+			if entry := recorder.finish(); entry != nil {
+				cc.server.queryCache.put(entry)
+			}
+		}
 		return false, nil
 	}
 
@@ -1988,6 +2382,21 @@ func (cc *clientConn) handleStmt(ctx context.Context, stmt ast.StmtNode, warns [
 	return false, nil
 }
 
+// writeCachedResultset writes the result of a statement from the query result cache. The statement
+// is not executed, so it is not recorded in the slow log or the statement summary.
+func (cc *clientConn) writeCachedResultset(ctx context.Context, stmt ast.StmtNode, entry *queryCacheEntry, warns []stmtctx.SQLWarn, lastStmt bool) (bool, error) {
+	// Reset the statement context so that the warnings of the previous statement are not reported.
+	vars := cc.ctx.GetSessionVars()
+	vars.StmtCtx = &stmtctx.StatementContext{OriginalSQL: stmt.Text(), Tables: entry.tables}
+	status := cc.ctx.Status()
+	if lastStmt {
+		vars.StmtCtx.AppendWarnings(warns)
+	} else {
+		status |= mysql.ServerMoreResultsExists
+	}
+	return cc.writeResultset(ctx, &queryCacheResultSet{entry: entry}, false, status, 0)
+}
+
 func (cc *clientConn) handleQuerySpecial(ctx context.Context, status uint16) (bool, error) {
 	handled := false
 	loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
@@ -2177,9 +2586,15 @@ func (cc *clientConn) writeChunks(ctx context.Context, rs ResultSet, binary bool
 			}
 		}
 		reg.End()
+		cc.sentRows += uint64(rowCount)
 		if stmtDetail != nil {
 			stmtDetail.WriteSQLRespDuration += time.Since(start)
 		}
+		// The query is killed with a grace period, so the rows written so far are returned as the result.
+		if stmtCtx := cc.ctx.GetSessionVars().StmtCtx; stmtCtx.SoftKilled.Load() {
+			stmtCtx.AppendWarning(executor.ErrQueryInterrupted)
+			break
+		}
 	}
 	return false, cc.writeEOF(serverStatus)
 }
@@ -2190,6 +2605,7 @@ func (cc *clientConn) writeChunks(ctx context.Context, rs ResultSet, binary bool
 // fetchSize, the desired number of rows to be fetched each time when client uses cursor.
 func (cc *clientConn) writeChunksWithFetchSize(ctx context.Context, rs ResultSet, serverStatus uint16, fetchSize int) error {
 	fetchedRows := rs.GetFetchedRows()
+	prevFetched := len(fetchedRows)
 	// if fetchedRows is not enough, getting data from recordSet.
 	req := rs.NewChunk(nil)
 	for len(fetchedRows) < fetchSize {
@@ -2217,10 +2633,19 @@ func (cc *clientConn) writeChunksWithFetchSize(ctx context.Context, rs ResultSet
 	if len(fetchedRows) == 0 {
 		serverStatus &^= mysql.ServerStatusCursorExists
 		serverStatus |= mysql.ServerStatusLastRowSend
+		cc.pkt.writeBuffer.releaseSpool(rs)
 		terror.Call(rs.Close)
 		return cc.writeEOF(serverStatus)
 	}
 
+	// account the memory of the rows held by the cursor, the statement is aborted if the write
+	// buffers of all the connections exceed the quota and this connection holds the most.
+	spooledBytes := cc.pkt.writeBuffer.spooledBytes(rs) + spoolRowBytes(fetchedRows[prevFetched:])
+	if err := cc.pkt.writeBuffer.spool(rs, spooledBytes); err != nil {
+		terror.Call(rs.Close)
+		return err
+	}
+
 	// construct the rows sent to the client according to fetchSize.
 	var curRows []chunk.Row
 	if fetchSize < len(fetchedRows) {
@@ -2231,6 +2656,10 @@ func (cc *clientConn) writeChunksWithFetchSize(ctx context.Context, rs ResultSet
 		fetchedRows = fetchedRows[:0]
 	}
 	rs.StoreFetchedRows(fetchedRows)
+	if err := cc.pkt.writeBuffer.spool(rs, spooledBytes-spoolRowBytes(curRows)); err != nil {
+		terror.Call(rs.Close)
+		return err
+	}
 
 	data := cc.alloc.AllocWithLen(4, 1024)
 	var stmtDetail *execdetails.StmtExecDetails
@@ -2269,10 +2698,19 @@ func (cc *clientConn) setConn(conn net.Conn) {
 	}
 }
 
+// enableCompression switches the connection to the compressed protocol.
+func (cc *clientConn) enableCompression() {
+	conn := newCompressedConn(cc.bufReadConn)
+	cc.setConn(conn)
+	cc.pkt.compressedConn = conn
+}
+
 func (cc *clientConn) upgradeToTLS(tlsConfig *tls.Config) error {
 	// Important: read from buffered reader instead of the original net.Conn because it may contain data we need.
 	tlsConn := tls.Server(cc.bufReadConn, tlsConfig)
 	if err := tlsConn.Handshake(); err != nil {
+		metrics.TLSHandshakeFailureCounter.Inc()
+		cc.tlsHandshakeFailed = true
 		return err
 	}
 	cc.setConn(tlsConn)
@@ -2293,13 +2731,29 @@ func (cc *clientConn) handleChangeUser(ctx context.Context, data []byte) error {
 	}
 	pass := data[:passLen]
 	data = data[passLen:]
-	dbName, _ := parseNullTermString(data)
+	dbName, data := parseNullTermString(data)
 	cc.dbname = string(hack.String(dbName))
+	// The client plugin follows the 2 bytes of the character set, it is mysql_native_password if it is not sent.
+	authPlugin := mysql.AuthNativePassword
+	if cc.capability&mysql.ClientPluginAuth > 0 && len(data) > 2 {
+		if name, _ := parseNullTermString(data[2:]); len(name) > 0 {
+			authPlugin = string(hack.String(name))
+		}
+	}
 
 	if err := cc.ctx.Close(); err != nil {
 		logutil.Logger(ctx).Debug("close old context failed", zap.Error(err))
 	}
-	if err := cc.openSessionAndDoAuth(pass, ""); err != nil {
+	// The plugin of the new account is resolved like the handshake does, the client is asked to switch to it
+	// if needed, so that the accounts of the external plugins are always authenticated by the plugins.
+	resp := &handshakeResponse41{Capability: cc.capability, User: cc.user, Auth: pass, AuthPlugin: authPlugin}
+	if err := cc.handleAuthPlugin(ctx, resp); err != nil {
+		return err
+	}
+	if err := cc.readPluginAuthData(ctx, resp); err != nil {
+		return err
+	}
+	if err := cc.openSessionAndDoAuth(resp.Auth, resp.AuthPlugin); err != nil {
 		return err
 	}
 	return cc.handleCommonConnectionReset(ctx)
@@ -2316,13 +2770,16 @@ func (cc *clientConn) handleResetConnection(ctx context.Context) error {
 		tlsState := cc.tlsConn.ConnectionState()
 		tlsStatePtr = &tlsState
 	}
-	cc.ctx, err = cc.server.driver.OpenCtx(cc.connectionID, cc.capability, cc.collation, cc.dbname, tlsStatePtr)
+	cc.ctx, err = cc.server.driver.OpenCtxWithAttributes(cc.connectionID, cc.capability, cc.collation, cc.dbname, cc.attrs, tlsStatePtr)
 	if err != nil {
 		return err
 	}
 	if !cc.ctx.AuthWithoutVerification(user) {
 		return errors.New("Could not reset connection")
 	}
+	if err := cc.proxyAs(cc.peerHost, "YES"); err != nil {
+		return err
+	}
 	if cc.dbname != "" { // Restore the current DB
 		err = cc.useDB(context.Background(), cc.dbname)
 		if err != nil {
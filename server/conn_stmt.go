@@ -195,6 +195,7 @@ func (cc *clientConn) handleStmtExecute(ctx context.Context, data []byte) (err e
 		}
 
 		err = parseExecArgs(cc.ctx.GetSessionVars().StmtCtx, args, stmt.BoundParams(), nullBitmaps, stmt.GetParamsType(), paramValues)
+		cc.pkt.writeBuffer.releaseSpool(stmt.GetResultSet())
 		stmt.Reset()
 		if err != nil {
 			return errors.Annotate(err, cc.preparedStmt2String(stmtID))
@@ -221,7 +222,15 @@ func (cc *clientConn) handleStmtExecute(ctx context.Context, data []byte) (err e
 
 // The first return value indicates whether the call of executePreparedStmtAndWriteResult has no side effect and can be retried.
 // Currently the first return value is used to fallback to TiKV when TiFlash is down.
-func (cc *clientConn) executePreparedStmtAndWriteResult(ctx context.Context, stmt PreparedStatement, args []types.Datum, useCursor bool) (bool, error) {
+func (cc *clientConn) executePreparedStmtAndWriteResult(ctx context.Context, stmt PreparedStatement, args []types.Datum, useCursor bool) (_ bool, err error) {
+	defer cc.countStatement()
+	if cc.auditEnabled() {
+		cc.sentRows = 0
+		start := time.Now()
+		defer func() {
+			cc.auditStatement(start, err)
+		}()
+	}
 	rs, err := stmt.Execute(ctx, args)
 	if err != nil {
 		return true, errors.Annotate(err, cc.preparedStmt2String(uint32(stmt.ID())))
@@ -236,6 +245,7 @@ func (cc *clientConn) executePreparedStmtAndWriteResult(ctx context.Context, stm
 	if useCursor {
 		cc.initResultEncoder(ctx)
 		defer cc.rsEncoder.clean()
+		cc.pkt.writeBuffer.releaseSpool(stmt.GetResultSet())
 		stmt.StoreResultSet(rs)
 		err = cc.writeColumnInfo(rs.Columns(), mysql.ServerStatusCursorExists)
 		if err != nil {
@@ -617,6 +627,7 @@ func (cc *clientConn) handleStmtClose(data []byte) (err error) {
 	stmtID := int(binary.LittleEndian.Uint32(data[0:4]))
 	stmt := cc.ctx.GetStatement(stmtID)
 	if stmt != nil {
+		cc.pkt.writeBuffer.releaseSpool(stmt.GetResultSet())
 		return stmt.Close()
 	}
 	return
@@ -650,6 +661,7 @@ func (cc *clientConn) handleStmtReset(ctx context.Context, data []byte) (err err
 		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
 			strconv.Itoa(stmtID), "stmt_reset")
 	}
+	cc.pkt.writeBuffer.releaseSpool(stmt.GetResultSet())
 	stmt.Reset()
 	stmt.StoreResultSet(nil)
 	return cc.writeOK(ctx)
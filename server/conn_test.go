@@ -18,17 +18,35 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
 	"encoding/binary"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"math"
+	"net"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/domain"
+	"github.com/pingcap/tidb/errno"
 	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/metrics"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -38,6 +56,9 @@ import (
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/util/arena"
 	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/collate"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 	tikverr "github.com/tikv/client-go/v2/error"
 	"github.com/tikv/client-go/v2/testutils"
@@ -172,6 +193,48 @@ func TestAuthSwitchRequest(t *testing.T) {
 	require.Equal(t, "caching_sha2_password", resp.AuthPlugin)
 }
 
+func TestHandshakeTimeout(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	defer config.RestoreFunc()()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Performance.HandshakeTimeoutSeconds = 1
+	})
+
+	serverSide, clientSide := net.Pipe()
+	defer func() {
+		require.NoError(t, clientSide.Close())
+	}()
+	cc := &clientConn{
+		connectionID: 1,
+		alloc:        arena.NewAllocator(1024),
+		chunkAlloc:   chunk.NewAllocator(),
+		collation:    mysql.DefaultCollationID,
+		peerHost:     "localhost",
+		server:       srv,
+		salt:         make([]byte, 20),
+	}
+	cc.setConn(serverSide)
+	// The client reads the initial handshake packet, then only sends the first byte of its response.
+	go func() {
+		cli := newPacketIO(newBufferedReadConn(clientSide))
+		if _, err := cli.readPacket(); err == nil {
+			_, _ = clientSide.Write([]byte{0x01})
+		}
+	}()
+	start := time.Now()
+	err = cc.handshake(context.Background())
+	require.Error(t, err)
+	require.Equal(t, handshakeFailureTimeout, cc.handshakeFailureReason(err))
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
 func TestInitialHandshake(t *testing.T) {
 	t.Parallel()
 
@@ -545,6 +608,199 @@ func TestGetSessionVarsWaitTimeout(t *testing.T) {
 	require.Equal(t, uint64(variable.DefWaitTimeout), cc.getSessionVarsWaitTimeout(context.Background()))
 }
 
+func TestInitWaitTimeout(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	newConn := func(capability uint32) *clientConn {
+		se, err := session.CreateSession4Test(store)
+		require.NoError(t, err)
+		require.NoError(t, se.GetSessionVars().SetSystemVar(variable.WaitTimeout, "100"))
+		require.NoError(t, se.GetSessionVars().SetSystemVar(variable.InteractiveTimeout, "200"))
+		return &clientConn{
+			connectionID: 1,
+			capability:   capability,
+			ctx: &TiDBContext{
+				Session: se,
+				stmts:   make(map[int]*TiDBStatement),
+			},
+		}
+	}
+
+	cc := newConn(defaultCapability &^ mysql.ClientInteractive)
+	require.NoError(t, cc.initWaitTimeout())
+	require.Equal(t, uint64(100), cc.getSessionVarsWaitTimeout(context.Background()))
+
+	// The wait_timeout of an interactive client is initialized from interactive_timeout.
+	cc = newConn(defaultCapability)
+	require.NoError(t, cc.initWaitTimeout())
+	require.Equal(t, uint64(200), cc.getSessionVarsWaitTimeout(context.Background()))
+}
+
+func TestIdleConnectionWaitTimeout(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	origInterval := idleCheckInterval
+	idleCheckInterval = 50 * time.Millisecond
+	defer func() {
+		idleCheckInterval = origInterval
+	}()
+
+	se, err := session.CreateSession4Test(store)
+	require.NoError(t, err)
+	require.NoError(t, se.GetSessionVars().SetSystemVar(variable.WaitTimeout, "1"))
+	srvSide, cliSide := net.Pipe()
+	defer cliSide.Close()
+	bufReadConn := newBufferedReadConn(srvSide)
+	srv := &Server{
+		capability: defaultCapability,
+		clients:    make(map[uint64]*clientConn),
+	}
+	cc := &clientConn{
+		connectionID: 1,
+		server:       srv,
+		ctx: &TiDBContext{
+			Session: se,
+			stmts:   make(map[int]*TiDBStatement),
+		},
+		alloc:       arena.NewAllocator(1024),
+		chunkAlloc:  chunk.NewAllocator(),
+		collation:   mysql.DefaultCollationID,
+		capability:  defaultCapability,
+		status:      connStatusDispatching,
+		bufReadConn: bufReadConn,
+		pkt:         newPacketIO(bufReadConn),
+	}
+	srv.clients[cc.connectionID] = cc
+	timeoutCount := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, disconnectByTimeout.Write(m))
+		return m.GetCounter().GetValue()
+	}
+	before := timeoutCount()
+
+	done := make(chan struct{})
+	go func() {
+		cc.Run(context.Background())
+		close(done)
+	}()
+
+	// The client sleeps longer than wait_timeout without sending anything.
+	start := time.Now()
+	var header [4]byte
+	_, err = io.ReadFull(cliSide, header[:])
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	data := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err = io.ReadFull(cliSide, data)
+	require.NoError(t, err)
+	require.Equal(t, byte(mysql.ErrHeader), data[0])
+	require.Equal(t, uint16(errno.ErrNetRead), binary.LittleEndian.Uint16(data[1:3]))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the idle connection is not closed")
+	}
+	_, err = cliSide.Read(header[:])
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, srv.ConnectionCount())
+	require.Equal(t, before+1, timeoutCount())
+}
+
+func TestIdleTransactionTimeout(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	origInterval := idleCheckInterval
+	idleCheckInterval = 50 * time.Millisecond
+	defer func() {
+		idleCheckInterval = origInterval
+	}()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	killed := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.IdleTransactionKillCounter.Write(m))
+		return m.GetCounter().GetValue()
+	}
+
+	runConn := func(inTxn bool) (net.Conn, chan struct{}) {
+		se, err := session.CreateSession4Test(store)
+		require.NoError(t, err)
+		require.NoError(t, se.GetSessionVars().SetSystemVar(variable.TiDBIdleTransactionTimeout, "1"))
+		if inTxn {
+			_, err = se.Execute(context.Background(), "begin")
+			require.NoError(t, err)
+			_, err = se.Execute(context.Background(), "insert into test.t values (1)")
+			require.NoError(t, err)
+		}
+		srvSide, cliSide := net.Pipe()
+		bufReadConn := newBufferedReadConn(srvSide)
+		cc := &clientConn{
+			connectionID: 1,
+			server: &Server{
+				capability: defaultCapability,
+				clients:    make(map[uint64]*clientConn),
+			},
+			ctx: &TiDBContext{
+				Session: se,
+				stmts:   make(map[int]*TiDBStatement),
+			},
+			alloc:       arena.NewAllocator(1024),
+			chunkAlloc:  chunk.NewAllocator(),
+			collation:   mysql.DefaultCollationID,
+			capability:  defaultCapability,
+			status:      connStatusDispatching,
+			bufReadConn: bufReadConn,
+			pkt:         newPacketIO(bufReadConn),
+		}
+		done := make(chan struct{})
+		go func() {
+			cc.Run(context.Background())
+			close(done)
+		}()
+		return cliSide, done
+	}
+
+	// The connection that is idle outside a transaction is kept.
+	before := killed()
+	cliSide, done := runConn(false)
+	require.NoError(t, cliSide.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var header [4]byte
+	_, err := cliSide.Read(header[:])
+	require.True(t, err.(net.Error).Timeout(), "%v", err)
+	require.NoError(t, cliSide.Close())
+	<-done
+	require.Equal(t, before, killed())
+
+	// The connection that is idle in a transaction is killed.
+	cliSide, done = runConn(true)
+	defer cliSide.Close()
+	start := time.Now()
+	_, err = io.ReadFull(cliSide, header[:])
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	data := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err = io.ReadFull(cliSide, data)
+	require.NoError(t, err)
+	require.Equal(t, byte(mysql.ErrHeader), data[0])
+	require.Equal(t, uint16(errno.ErrQueryInterrupted), binary.LittleEndian.Uint16(data[1:3]))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the connection with an idle transaction is not killed")
+	}
+	_, err = cliSide.Read(header[:])
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, before+1, killed())
+	// The transaction is rolled back.
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("0"))
+}
+
 func mapIdentical(m1, m2 map[string]string) bool {
 	return mapBelong(m1, m2) && mapBelong(m2, m1)
 }
@@ -908,6 +1164,71 @@ func TestShowErrors(t *testing.T) {
 	tk.MustQuery("show errors").Check(testkit.Rows("Error 1051 Unknown table 'test.idontexist'"))
 }
 
+func TestCharsetMismatch(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+	cc := &clientConn{
+		alloc:      arena.NewAllocator(1024),
+		chunkAlloc: chunk.NewAllocator(),
+		pkt: &packetIO{
+			bufWriter: bufio.NewWriter(bytes.NewBuffer(nil)),
+		},
+		user: "mismatch_user",
+	}
+	ctx := context.Background()
+	tk := testkit.NewTestKit(t, store)
+	cc.ctx = &TiDBContext{Session: tk.Session(), stmts: make(map[int]*TiDBStatement)}
+	defer config.RestoreFunc()()
+	collate.SetCharsetFeatEnabledForTest(true)
+	defer collate.SetCharsetFeatEnabledForTest(false)
+
+	// "中" in utf8 is E4 B8 AD, AD 20 is not a gbk character.
+	utf8SQL := "select 1 /* 中 */"
+	require.False(t, suspectCharsetMismatch("utf8mb4", utf8SQL))
+	require.False(t, suspectCharsetMismatch("gbk", "select 'a'"))
+	require.False(t, suspectCharsetMismatch("gbk", "select '\xd6\xd0'"))
+	require.False(t, suspectCharsetMismatch("latin1", utf8SQL))
+	require.True(t, suspectCharsetMismatch("gbk", utf8SQL))
+
+	require.NoError(t, cc.handleQuery(ctx, "set names gbk"))
+	// The detection is off by default.
+	require.NoError(t, cc.handleQuery(ctx, utf8SQL))
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	require.Equal(t, uint64(0), cc.charsetMismatches)
+
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.CharsetMismatchCheck = config.CharsetMismatchCheckWarn
+	})
+	require.NoError(t, cc.handleQuery(ctx, utf8SQL))
+	tk.MustQuery("show warnings").Check(testkit.Rows(
+		"Warning 8245 The statement is valid utf8 but invalid gbk, character_set_client may not match the encoding of the client"))
+	// The warning is only reported once per connection.
+	require.NoError(t, cc.handleQuery(ctx, utf8SQL))
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	require.Equal(t, uint64(2), cc.charsetMismatches)
+
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.CharsetMismatchCheck = config.CharsetMismatchCheckStrict
+	})
+	err := cc.handleQuery(ctx, utf8SQL)
+	require.True(t, errCharsetMismatch.Equal(err))
+	require.NoError(t, cc.handleQuery(ctx, "select 'a'"))
+	require.Equal(t, uint64(3), cc.charsetMismatches)
+
+	require.Contains(t, GetCharsetMismatches(), CharsetMismatchInfo{User: "mismatch_user", Count: 3})
+
+	// The users beyond maxCharsetMismatchUsers are counted under userMetricsLabelOther.
+	tracker := &charsetMismatchTracker{users: make(map[string]uint64)}
+	for i := 0; i < maxCharsetMismatchUsers; i++ {
+		tracker.inc(strconv.Itoa(i))
+	}
+	tracker.inc("0")
+	tracker.inc("new_user")
+	require.Len(t, tracker.users, maxCharsetMismatchUsers+1)
+	require.Equal(t, uint64(2), tracker.users["0"])
+	require.Equal(t, uint64(1), tracker.users[userMetricsLabelOther])
+}
+
 func TestHandleAuthPlugin(t *testing.T) {
 	store, clean := testkit.CreateMockStore(t)
 	defer clean()
@@ -1169,3 +1490,759 @@ func TestAuthPlugin2(t *testing.T) {
 	require.NoError(t, err)
 
 }
+
+func TestAuthSocket(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	osUser, err := user.Current()
+	require.NoError(t, err)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec(fmt.Sprintf("CREATE USER 'usocket'@'%%' IDENTIFIED WITH 'auth_socket' AS '%s'", osUser.Username))
+	defer tk.MustExec("DROP USER 'usocket'@'%'")
+
+	newConn := func(isUnixSocket bool, uid uint32) *clientConn {
+		return &clientConn{
+			connectionID:  1,
+			alloc:         arena.NewAllocator(1024),
+			chunkAlloc:    chunk.NewAllocator(),
+			collation:     mysql.DefaultCollationID,
+			peerHost:      "localhost",
+			pkt:           &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:        srv,
+			user:          "usocket",
+			isUnixSocket:  isUnixSocket,
+			socketCredUID: uid,
+		}
+	}
+	resp := handshakeResponse41{
+		Capability: mysql.ClientProtocol41 | mysql.ClientPluginAuth,
+		AuthPlugin: mysql.AuthNativePassword,
+	}
+
+	// auth_socket requires a unix socket connection.
+	cc := newConn(false, 0)
+	_, err = cc.checkAuthPlugin(ctx, &resp)
+	require.True(t, errAccessDeniedNoPassword.Equal(err))
+
+	// The OS user of the peer matches the authentication string.
+	uid, err := strconv.ParseUint(osUser.Uid, 10, 32)
+	require.NoError(t, err)
+	cc = newConn(true, uint32(uid))
+	authData, err := cc.checkAuthPlugin(ctx, &resp)
+	require.NoError(t, err)
+	require.Equal(t, mysql.AuthSocket, resp.AuthPlugin)
+	require.Equal(t, []byte(osUser.Username), authData)
+	require.NoError(t, cc.openSessionAndDoAuth(authData, resp.AuthPlugin))
+
+	// The uid of the peer can't be resolved to an OS user.
+	resp.AuthPlugin = mysql.AuthNativePassword
+	cc = newConn(true, math.MaxUint32-1)
+	_, err = cc.checkAuthPlugin(ctx, &resp)
+	require.True(t, errAccessDeniedNoPassword.Equal(err))
+}
+
+func TestAccountLockedLogin(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'ulocked'@'%' ACCOUNT LOCK")
+	defer tk.MustExec("DROP USER 'ulocked'@'%'")
+
+	newConn := func() *clientConn {
+		return &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "ulocked",
+			capability:   defaultCapability,
+		}
+	}
+
+	err = newConn().openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errAccountLocked.Equal(err))
+	require.EqualError(t, err, "[server:3118]Access denied for user 'ulocked'@'localhost'. Account is locked.")
+
+	// The lock is not reported without the right credentials.
+	err = newConn().openSessionAndDoAuth([]byte("wrong"), mysql.AuthNativePassword)
+	require.True(t, errAccessDenied.Equal(err))
+
+	tk.MustExec("ALTER USER 'ulocked'@'%' ACCOUNT UNLOCK")
+	require.NoError(t, newConn().openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+}
+
+func TestPasswordExpiredSandbox(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'uexpired'@'%' PASSWORD EXPIRE")
+	defer tk.MustExec("DROP USER 'uexpired'@'%'")
+	tk.MustQuery("SELECT Password_expired FROM mysql.user WHERE User = 'uexpired'").Check(testkit.Rows("Y"))
+
+	newConn := func(capability uint32) *clientConn {
+		return &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "uexpired",
+			capability:   capability,
+		}
+	}
+
+	// The client can't handle expired passwords, it is rejected.
+	cc := newConn(defaultCapability &^ mysql.ClientCanHandleExpiredPasswords)
+	err = cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errMustChangePasswordLogin.Equal(err))
+
+	// The client can handle expired passwords, it enters the sandbox mode.
+	cc = newConn(defaultCapability)
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.True(t, cc.passwordExpired)
+	err = cc.handleQuery(ctx, "select 1")
+	require.True(t, errMustChangePassword.Equal(err))
+	err = cc.dispatch(ctx, append([]byte{mysql.ComStmtPrepare}, "select 1"...))
+	require.True(t, errMustChangePassword.Equal(err))
+	require.NoError(t, cc.handleQuery(ctx, "set @a = 1"))
+	require.True(t, cc.passwordExpired)
+
+	// The sandbox mode ends once the password is changed.
+	require.NoError(t, cc.handleQuery(ctx, "set password = 'pwd'"))
+	require.False(t, cc.passwordExpired)
+	require.NoError(t, cc.handleQuery(ctx, "select 1"))
+	tk.MustQuery("SELECT Password_expired FROM mysql.user WHERE User = 'uexpired'").Check(testkit.Rows("N"))
+
+	tk.MustExec("ALTER USER 'uexpired'@'%' PASSWORD EXPIRE")
+	tk.MustQuery("SELECT Password_expired FROM mysql.user WHERE User = 'uexpired'").Check(testkit.Rows("Y"))
+	tk.MustExec("ALTER USER 'uexpired'@'%' IDENTIFIED BY 'pwd2'")
+	tk.MustQuery("SELECT Password_expired FROM mysql.user WHERE User = 'uexpired'").Check(testkit.Rows("N"))
+}
+
+func TestPasswordExpireInterval(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'uinterval'@'%' PASSWORD EXPIRE INTERVAL 10 DAY")
+	defer tk.MustExec("DROP USER 'uinterval'@'%'")
+	tk.MustQuery("SELECT Password_expired, Password_lifetime, Password_last_changed IS NOT NULL FROM mysql.user WHERE User = 'uinterval'").Check(testkit.Rows("N 10 1"))
+	_, err = tk.Exec("ALTER USER 'uinterval'@'%' PASSWORD EXPIRE INTERVAL 0 DAY")
+	require.EqualError(t, err, "PASSWORD EXPIRE INTERVAL must be between 1 and 65535")
+
+	newConn := func(capability uint32) *clientConn {
+		return &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "uinterval",
+			capability:   capability,
+		}
+	}
+
+	// The password is not expired yet.
+	cc := newConn(defaultCapability &^ mysql.ClientCanHandleExpiredPasswords)
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.False(t, cc.passwordExpired)
+
+	// The password is expired after the lifetime.
+	tk.MustExec("UPDATE mysql.user SET Password_last_changed = DATE_SUB(NOW(), INTERVAL 11 DAY) WHERE User = 'uinterval'")
+	tk.MustExec("FLUSH PRIVILEGES")
+	cc = newConn(defaultCapability &^ mysql.ClientCanHandleExpiredPasswords)
+	err = cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errMustChangePasswordLogin.Equal(err))
+	cc = newConn(defaultCapability)
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.True(t, cc.passwordExpired)
+	err = cc.handleQuery(ctx, "select 1")
+	require.True(t, errMustChangePassword.Equal(err))
+
+	// Changing the password restarts the lifetime.
+	require.NoError(t, cc.handleQuery(ctx, "ALTER USER USER() IDENTIFIED BY ''"))
+	require.False(t, cc.passwordExpired)
+	require.NoError(t, cc.handleQuery(ctx, "select 1"))
+
+	// The password never expires by time with PASSWORD EXPIRE NEVER.
+	tk.MustExec("UPDATE mysql.user SET Password_last_changed = DATE_SUB(NOW(), INTERVAL 11 DAY) WHERE User = 'uinterval'")
+	tk.MustExec("ALTER USER 'uinterval'@'%' PASSWORD EXPIRE NEVER")
+	tk.MustQuery("SELECT Password_lifetime FROM mysql.user WHERE User = 'uinterval'").Check(testkit.Rows("0"))
+	cc = newConn(defaultCapability &^ mysql.ClientCanHandleExpiredPasswords)
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.False(t, cc.passwordExpired)
+	tk.MustExec("ALTER USER 'uinterval'@'%' PASSWORD EXPIRE DEFAULT")
+	tk.MustQuery("SELECT Password_lifetime FROM mysql.user WHERE User = 'uinterval'").Check(testkit.Rows("<nil>"))
+}
+
+func TestFlushStatusResetsClientErrors(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("FLUSH STATUS")
+	tk.MustExec("CREATE USER 'uflush'@'%' IDENTIFIED BY 'secret'")
+	defer tk.MustExec("DROP USER 'uflush'@'%'")
+
+	// Fail the authentication twice, the errors are counted when they are written to the client.
+	for i := 0; i < 2; i++ {
+		cc := &clientConn{
+			connectionID: uint64(i + 1),
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "uflush",
+			capability:   defaultCapability,
+		}
+		err = cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+		require.True(t, errAccessDenied.Equal(err))
+		require.NoError(t, cc.writeError(ctx, err))
+	}
+	tk.MustQuery("SELECT error_number, error_count FROM information_schema.client_errors_summary_by_user WHERE user = 'uflush'").Check(testkit.Rows("1045 2"))
+	tk.MustQuery("SELECT error_count FROM information_schema.client_errors_summary_global WHERE error_number = 1045").Check(testkit.Rows("2"))
+
+	tk.MustExec("FLUSH STATUS")
+	tk.MustQuery("SELECT * FROM information_schema.client_errors_summary_by_user").Check(testkit.Rows())
+	tk.MustQuery("SELECT * FROM information_schema.client_errors_summary_by_host").Check(testkit.Rows())
+	tk.MustQuery("SELECT * FROM information_schema.client_errors_summary_global").Check(testkit.Rows())
+}
+
+func TestMaxUserConnections(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'ulimited'@'%' WITH MAX_USER_CONNECTIONS 2")
+	defer tk.MustExec("DROP USER 'ulimited'@'%'")
+	tk.MustQuery("SELECT max_user_connections FROM mysql.user WHERE User = 'ulimited'").Check(testkit.Rows("2"))
+
+	connID := uint64(0)
+	newConn := func() *clientConn {
+		connID++
+		return &clientConn{
+			connectionID: connID,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "ulimited",
+			capability:   defaultCapability,
+		}
+	}
+
+	cc1, cc2, cc3 := newConn(), newConn(), newConn()
+	require.NoError(t, cc1.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.NoError(t, cc2.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	err = cc3.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errTooManyUserConnections.Equal(err))
+	require.NoError(t, cc3.Close())
+	require.Equal(t, 2, srv.userConnCount("ulimited", "%"))
+
+	// The count is released when the connection is closed.
+	require.NoError(t, cc1.Close())
+	require.Equal(t, 1, srv.userConnCount("ulimited", "%"))
+	cc3 = newConn()
+	require.NoError(t, cc3.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.Equal(t, 2, srv.userConnCount("ulimited", "%"))
+
+	// 0 means unlimited.
+	tk.MustExec("ALTER USER 'ulimited'@'%' WITH MAX_USER_CONNECTIONS 0")
+	cc4 := newConn()
+	require.NoError(t, cc4.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.Equal(t, 3, srv.userConnCount("ulimited", "%"))
+
+	for _, cc := range []*clientConn{cc2, cc3, cc4} {
+		require.NoError(t, cc.Close())
+	}
+	require.Equal(t, 0, srv.userConnCount("ulimited", "%"))
+}
+
+func TestUserMetrics(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'umetrics'@'%', 'umetrics2'@'%'")
+	defer tk.MustExec("DROP USER 'umetrics'@'%', 'umetrics2'@'%'")
+
+	connID := uint64(0)
+	newConn := func(user string) *clientConn {
+		connID++
+		return &clientConn{
+			connectionID: connID,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         user,
+			capability:   defaultCapability,
+		}
+	}
+	counter := func(c prometheus.Counter) float64 {
+		m := &dto.Metric{}
+		require.NoError(t, c.Write(m))
+		return m.GetCounter().GetValue()
+	}
+
+	cc1, cc2 := newConn("umetrics"), newConn("umetrics")
+	require.NoError(t, cc1.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.NoError(t, cc2.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.Equal(t, []UserConnInfo{{User: "umetrics@%", Count: 2}}, srv.UserConnections())
+
+	// The bytes of the packets are counted by the packetIO and flushed after each command.
+	ctx := context.Background()
+	query := append([]byte{mysql.ComQuery}, "select 1"...)
+	atomic.AddUint64(&cc1.pkt.readBytes, uint64(len(query)))
+	require.NoError(t, cc1.dispatch(ctx, query))
+	require.NotZero(t, atomic.LoadUint64(&cc1.pkt.writtenBytes))
+	cc1.countUserCommand(query[0])
+	require.Zero(t, atomic.LoadUint64(&cc1.pkt.writtenBytes))
+	require.Equal(t, float64(len(query)), counter(metrics.UserTrafficBytesCounter.WithLabelValues("umetrics@%", "read")))
+	require.NotZero(t, counter(metrics.UserTrafficBytesCounter.WithLabelValues("umetrics@%", "write")))
+	require.Equal(t, float64(1), counter(metrics.UserCommandCounter.WithLabelValues("umetrics@%", "Query")))
+	cc2.countUserCommand(mysql.ComPing)
+	cc2.countUserCommand(mysql.ComPing)
+	require.Equal(t, float64(2), counter(metrics.UserCommandCounter.WithLabelValues("umetrics@%", "Ping")))
+
+	// The counters are kept after the connections are closed.
+	require.NoError(t, cc1.Close())
+	require.NoError(t, cc2.Close())
+	require.Empty(t, srv.UserConnections())
+	require.Equal(t, float64(1), counter(metrics.UserCommandCounter.WithLabelValues("umetrics@%", "Query")))
+
+	// The accounts beyond maxUserMetricsLabels are counted under userMetricsLabelOther.
+	srv.rwlock.Lock()
+	for i := len(srv.userMetrics); i < maxUserMetricsLabels; i++ {
+		srv.userMetrics[strconv.Itoa(i)] = nil
+	}
+	srv.rwlock.Unlock()
+	cc3, cc4 := newConn("umetrics"), newConn("umetrics2")
+	require.NoError(t, cc3.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.NoError(t, cc4.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	require.Equal(t, "umetrics@%", cc3.userMetrics.label)
+	require.Equal(t, userMetricsLabelOther, cc4.userMetrics.label)
+	require.Equal(t, []UserConnInfo{{User: "umetrics2@%", Count: 1}, {User: "umetrics@%", Count: 1}}, srv.UserConnections())
+	require.NoError(t, cc3.Close())
+	require.NoError(t, cc4.Close())
+}
+
+func TestProxyUser(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'proxy'@'%', 'app'@'%'")
+	tk.MustExec("GRANT SELECT ON test.* TO 'app'@'%'")
+
+	newConn := func() *clientConn {
+		return &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+			server:       srv,
+			user:         "proxy",
+			attrs:        map[string]string{proxiedUserAttr: "app"},
+			capability:   defaultCapability,
+		}
+	}
+
+	// The PROXY privilege is required.
+	cc := newConn()
+	err = cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errAccessDenied.Equal(err))
+	require.NoError(t, cc.Close())
+
+	tk.MustExec("GRANT PROXY ON 'app'@'%' TO 'proxy'@'%'")
+	tk.MustQuery("SELECT Host, User, Proxied_host, Proxied_user, With_grant FROM mysql.proxies_priv").Check(testkit.Rows("% proxy % app N"))
+	cc = newConn()
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	pm := privilege.GetPrivilegeManager(cc.ctx.Session)
+	require.True(t, pm.RequestVerification(nil, "test", "", "", mysql.SelectPriv))
+	require.False(t, pm.RequestVerification(nil, "test", "", "", mysql.InsertPriv))
+	// current_user() still reports the proxy account.
+	require.Equal(t, "proxy@%", cc.ctx.GetSessionVars().User.String())
+	require.Equal(t, "app@%", cc.ctx.GetSessionVars().ProxiedUser.String())
+	cc.ctx.SetProcessInfo("", time.Now(), mysql.ComSleep, 0)
+	require.Equal(t, "app@%", cc.ctx.ShowProcess().ProxyUser)
+	require.NoError(t, cc.Close())
+
+	// DROP USER removes the PROXY privilege.
+	tk.MustExec("DROP USER 'app'@'%'")
+	tk.MustQuery("SELECT COUNT(*) FROM mysql.proxies_priv").Check(testkit.Rows("0"))
+	tk.MustExec("DROP USER 'proxy'@'%'")
+}
+
+func encryptRSAPassword(t *testing.T, publicKeyPEM []byte, password string, salt []byte) []byte {
+	block, _ := pem.Decode(publicKeyPEM)
+	require.NotNil(t, block)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= salt[i%len(salt)]
+	}
+	encrypted, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub.(*rsa.PublicKey), plain, nil)
+	require.NoError(t, err)
+	return encrypted
+}
+
+func TestAuthSha256PasswordRSA(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Path = t.TempDir()
+	cfg.Security.RSAKeySize = 1024
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("CREATE USER 'usha256'@'%' IDENTIFIED WITH 'sha256_password' BY 'pwd'")
+	defer tk.MustExec("DROP USER 'usha256'@'%'")
+
+	salt := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10, 0x11, 0x12, 0x13, 0x14}
+	newConn := func() (*clientConn, *packetIO) {
+		serverSide, clientSide := net.Pipe()
+		cc := &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			server:       srv,
+			salt:         salt,
+			user:         "usha256",
+		}
+		cc.setConn(serverSide)
+		return cc, newPacketIO(newBufferedReadConn(clientSide))
+	}
+	// requestPublicKey plays the client side: it reads the public key and replies with the encrypted password.
+	requestPublicKey := func(cli *packetIO, password string, beforeReply func()) chan []byte {
+		keyCh := make(chan []byte, 1)
+		go func() {
+			data, err := cli.readPacket()
+			require.NoError(t, err)
+			require.Equal(t, mysql.AuthMoreData, data[0])
+			keyCh <- data[1:]
+			if beforeReply != nil {
+				beforeReply()
+			}
+			encrypted := encryptRSAPassword(t, data[1:], password, salt)
+			require.NoError(t, cli.writePacket(append(make([]byte, 4), encrypted...)))
+			require.NoError(t, cli.flush())
+		}()
+		return keyCh
+	}
+
+	// Right password.
+	cc, cli := newConn()
+	keyCh := requestPublicKey(cli, "pwd", nil)
+	authData, err := cc.authSha256(ctx, []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, []byte("pwd"), authData)
+	require.NoError(t, cc.openSessionAndDoAuth(authData, mysql.AuthSha256Password))
+	publicKey := <-keyCh
+	// The generated key pair is kept under the data directory.
+	require.FileExists(t, filepath.Join(cfg.Path, autoRSAPrivateKeyFile))
+	require.FileExists(t, filepath.Join(cfg.Path, autoRSAPublicKeyFile))
+
+	// Wrong password.
+	cc, cli = newConn()
+	requestPublicKey(cli, "wrong", nil)
+	authData, err = cc.authSha256(ctx, []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, []byte("wrong"), authData)
+	err = cc.openSessionAndDoAuth(authData, mysql.AuthSha256Password)
+	require.True(t, errAccessDenied.Equal(err))
+
+	// The key is rotated after the public key is sent, the connection keeps using the old key.
+	cc, cli = newConn()
+	requestPublicKey(cli, "pwd", func() {
+		require.NoError(t, srv.ReloadRSAKeyPair())
+	})
+	authData, err = cc.authSha256(ctx, []byte{1})
+	require.NoError(t, err)
+	require.NoError(t, cc.openSessionAndDoAuth(authData, mysql.AuthSha256Password))
+
+	// A client which caches the rotated public key can't authenticate any more.
+	cc, _ = newConn()
+	_, err = cc.authSha256(ctx, encryptRSAPassword(t, publicKey, "pwd", salt))
+	require.True(t, errAccessDenied.Equal(err))
+
+	// Password is sent in clear text over secure transport.
+	cc, _ = newConn()
+	cc.isUnixSocket = true
+	authData, err = cc.authSha256(ctx, []byte("pwd\x00"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pwd"), authData)
+}
+
+type mockLDAPAuthPlugin struct {
+	password string
+}
+
+func (p *mockLDAPAuthPlugin) Name() string {
+	return "authentication_ldap_simple"
+}
+
+func (p *mockLDAPAuthPlugin) ClientPluginName() string {
+	return mysql.AuthClearPassword
+}
+
+func (p *mockLDAPAuthPlugin) Authenticate(user, host string, authData []byte, salt []byte, tls bool) error {
+	if string(authData) != p.password {
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
+
+func TestExternalAuthPlugin(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	plugin := &mockLDAPAuthPlugin{password: "secret"}
+	require.NoError(t, srv.RegisterAuthPlugin(plugin))
+	defer privileges.UnregisterExternalAuthPlugin(plugin.Name())
+	require.Error(t, srv.RegisterAuthPlugin(plugin))
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustGetErrCode("CREATE USER 'uunknown'@'%' IDENTIFIED WITH 'authentication_pam'", errno.ErrPluginIsNotLoaded)
+	tk.MustExec("CREATE USER 'uldap'@'%' IDENTIFIED WITH 'authentication_ldap_simple'")
+	defer tk.MustExec("DROP USER 'uldap'@'%'")
+	tk.MustExec("CREATE USER 'unative'@'%' IDENTIFIED BY 'secret'")
+	defer tk.MustExec("DROP USER 'unative'@'%'")
+
+	newConn := func(user string, isUnixSocket bool) (*clientConn, *packetIO) {
+		serverSide, clientSide := net.Pipe()
+		cc := &clientConn{
+			connectionID: 1,
+			alloc:        arena.NewAllocator(1024),
+			chunkAlloc:   chunk.NewAllocator(),
+			collation:    mysql.DefaultCollationID,
+			peerHost:     "localhost",
+			server:       srv,
+			salt:         []byte{0x01, 0x02, 0x03, 0x04},
+			user:         user,
+			authPlugin:   mysql.AuthNativePassword,
+			isUnixSocket: isUnixSocket,
+		}
+		cc.setConn(serverSide)
+		return cc, newPacketIO(newBufferedReadConn(clientSide))
+	}
+	// switchAuth plays the client side: it reads the auth-switch packet and replies with the password in clear text.
+	switchAuth := func(cli *packetIO, password string) chan string {
+		pluginCh := make(chan string, 1)
+		go func() {
+			data, err := cli.readPacket()
+			require.NoError(t, err)
+			require.Equal(t, mysql.AuthSwitchRequest, data[0])
+			pluginCh <- string(data[1:bytes.IndexByte(data, 0)])
+			require.NoError(t, cli.writePacket(append(make([]byte, 4), append([]byte(password), 0)...)))
+			require.NoError(t, cli.flush())
+		}()
+		return pluginCh
+	}
+	newResp := func() *handshakeResponse41 {
+		return &handshakeResponse41{
+			Capability: mysql.ClientProtocol41 | mysql.ClientPluginAuth,
+			AuthPlugin: mysql.AuthNativePassword,
+			Auth:       []byte{0x01},
+		}
+	}
+
+	// mysql_clear_password is only permitted on TLS or unix socket connections.
+	cc, _ := newConn("uldap", false)
+	_, err = cc.checkAuthPlugin(ctx, newResp())
+	require.True(t, errAccessDenied.Equal(err))
+
+	// The right password.
+	cc, cli := newConn("uldap", true)
+	pluginCh := switchAuth(cli, "secret")
+	resp := newResp()
+	authData, err := cc.checkAuthPlugin(ctx, resp)
+	require.NoError(t, err)
+	require.Equal(t, mysql.AuthClearPassword, <-pluginCh)
+	require.Equal(t, plugin.Name(), resp.AuthPlugin)
+	require.NoError(t, cc.openSessionAndDoAuth(authData, resp.AuthPlugin))
+	require.Equal(t, "uldap", cc.ctx.GetSessionVars().User.AuthUsername)
+
+	// The wrong password.
+	cc, cli = newConn("uldap", true)
+	switchAuth(cli, "wrong")
+	resp = newResp()
+	authData, err = cc.checkAuthPlugin(ctx, resp)
+	require.NoError(t, err)
+	err = cc.openSessionAndDoAuth(authData, resp.AuthPlugin)
+	require.True(t, errAccessDenied.Equal(err))
+
+	// The locked account is rejected after the plugin authenticates it.
+	tk.MustExec("ALTER USER 'uldap'@'%' ACCOUNT LOCK")
+	cc, cli = newConn("uldap", true)
+	switchAuth(cli, "wrong")
+	resp = newResp()
+	authData, err = cc.checkAuthPlugin(ctx, resp)
+	require.NoError(t, err)
+	err = cc.openSessionAndDoAuth(authData, resp.AuthPlugin)
+	require.True(t, errAccessDenied.Equal(err))
+	cc, cli = newConn("uldap", true)
+	switchAuth(cli, "secret")
+	resp = newResp()
+	authData, err = cc.checkAuthPlugin(ctx, resp)
+	require.NoError(t, err)
+	err = cc.openSessionAndDoAuth(authData, resp.AuthPlugin)
+	require.True(t, errAccountLocked.Equal(err))
+	tk.MustExec("ALTER USER 'uldap'@'%' ACCOUNT UNLOCK")
+
+	login := func(password string) error {
+		cc, cli := newConn("uldap", true)
+		switchAuth(cli, password)
+		resp := newResp()
+		authData, err := cc.checkAuthPlugin(ctx, resp)
+		require.NoError(t, err)
+		return cc.openSessionAndDoAuth(authData, resp.AuthPlugin)
+	}
+	// The TLS requirements of the account are checked like the password accounts.
+	tk.MustExec("ALTER USER 'uldap'@'%' REQUIRE SSL")
+	require.True(t, errAccessDenied.Equal(login("secret")))
+	tk.MustExec("ALTER USER 'uldap'@'%' REQUIRE NONE")
+	require.NoError(t, login("secret"))
+
+	// The consecutive failed logins lock the account like the password accounts.
+	tk.MustExec("ALTER USER 'uldap'@'%' FAILED_LOGIN_ATTEMPTS 1 PASSWORD_LOCK_TIME 1")
+	defer privileges.ResetFailedLogin("uldap", "%")
+	require.True(t, errAccountBlocked.Equal(login("wrong")))
+	require.True(t, errAccountBlocked.Equal(login("secret")))
+	privileges.ResetFailedLogin("uldap", "%")
+	require.NoError(t, login("secret"))
+	tk.MustExec("ALTER USER 'uldap'@'%' FAILED_LOGIN_ATTEMPTS 0 PASSWORD_LOCK_TIME 0")
+
+	// The plugin claimed by the client must be the one of the account.
+	cc, _ = newConn("unative", true)
+	err = cc.openSessionAndDoAuth([]byte("secret\x00"), plugin.Name())
+	require.True(t, errAccessDenied.Equal(err))
+
+	// The empty authentication_string of the account is not an empty password.
+	cc, _ = newConn("uldap", true)
+	err = cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword)
+	require.True(t, errAccessDenied.Equal(err))
+
+	// COM_CHANGE_USER to the account with an empty password is authenticated by the plugin as well.
+	tk.MustExec("CREATE USER 'unopass'@'%'")
+	defer tk.MustExec("DROP USER 'unopass'@'%'")
+	cc, cli = newConn("unopass", true)
+	cc.capability = mysql.ClientProtocol41 | mysql.ClientPluginAuth
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	pluginCh = switchAuth(cli, "")
+	data := append([]byte("uldap\x00"), 0)
+	data = append(data, 0, mysql.DefaultCollationID, 0)
+	data = append(data, mysql.AuthNativePassword+"\x00"...)
+	err = cc.handleChangeUser(ctx, data)
+	require.Equal(t, mysql.AuthClearPassword, <-pluginCh)
+	require.True(t, errAccessDenied.Equal(err))
+}
+
+// longFormAddrConn reports the remote address in the long form, like some clients and proxies do.
+type longFormAddrConn struct {
+	net.Conn
+}
+
+type longFormAddr struct{}
+
+func (longFormAddr) Network() string { return "tcp" }
+func (longFormAddr) String() string  { return "[0:0:0:0:0:0:0:1]:4000" }
+
+func (c longFormAddrConn) RemoteAddr() net.Addr { return longFormAddr{} }
+
+func TestPeerHostIPv6(t *testing.T) {
+	t.Parallel()
+	srvSide, cliSide := net.Pipe()
+	defer cliSide.Close()
+	defer srvSide.Close()
+	cc := &clientConn{bufReadConn: newBufferedReadConn(longFormAddrConn{srvSide})}
+	host, port, err := cc.PeerHost("")
+	require.NoError(t, err)
+	require.Equal(t, "::1", host)
+	require.Equal(t, "4000", port)
+}
@@ -26,6 +26,8 @@ import (
 type IDriver interface {
 	// OpenCtx opens an IContext with connection id, client capability, collation, dbname and optionally the tls state.
 	OpenCtx(connID uint64, capability uint32, collation uint8, dbname string, tlsState *tls.ConnectionState) (*TiDBContext, error)
+	// OpenCtxWithAttributes is the same as OpenCtx, but also stores the connection attributes sent by the client in the session.
+	OpenCtxWithAttributes(connID uint64, capability uint32, collation uint8, dbname string, attrs map[string]string, tlsState *tls.ConnectionState) (*TiDBContext, error)
 }
 
 // PreparedStatement is the interface to use a prepared statement.
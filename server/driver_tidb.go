@@ -46,6 +46,9 @@ func NewTiDBDriver(store kv.Storage) *TiDBDriver {
 	return driver
 }
 
+// maxErrorSQLLen is the max length of the statement text annotated to the errors of ExecuteStmt.
+const maxErrorSQLLen = 1024
+
 // TiDBContext implements QueryCtx.
 type TiDBContext struct {
 	session.Session
@@ -164,7 +167,7 @@ func (ts *TiDBStatement) Close() error {
 			if !ok {
 				return errors.Errorf("invalid CachedPrepareStmt type")
 			}
-			ts.ctx.PreparedPlanCache().Delete(core.NewPSTMTPlanCacheKey(
+			core.DeletePreparedPlanCache(ts.ctx, core.NewPSTMTPlanCacheKey(
 				ts.ctx.GetSessionVars(), ts.id, preparedObj.PreparedAst.SchemaVersion))
 		}
 		ts.ctx.GetSessionVars().RemovePreparedStmt(ts.id)
@@ -180,6 +183,11 @@ func (ts *TiDBStatement) Close() error {
 
 // OpenCtx implements IDriver.
 func (qd *TiDBDriver) OpenCtx(connID uint64, capability uint32, collation uint8, dbname string, tlsState *tls.ConnectionState) (*TiDBContext, error) {
+	return qd.OpenCtxWithAttributes(connID, capability, collation, dbname, nil, tlsState)
+}
+
+// OpenCtxWithAttributes implements IDriver.
+func (qd *TiDBDriver) OpenCtxWithAttributes(connID uint64, capability uint32, collation uint8, dbname string, attrs map[string]string, tlsState *tls.ConnectionState) (*TiDBContext, error) {
 	se, err := session.CreateSession(qd.store)
 	if err != nil {
 		return nil, err
@@ -191,6 +199,7 @@ func (qd *TiDBDriver) OpenCtx(connID uint64, capability uint32, collation uint8,
 	}
 	se.SetClientCapability(capability)
 	se.SetConnectionID(connID)
+	se.GetSessionVars().ConnectionAttrs = attrs
 	tc := &TiDBContext{
 		Session:   se,
 		currentDB: dbname,
@@ -219,7 +228,7 @@ func (tc *TiDBContext) ExecuteStmt(ctx context.Context, stmt ast.StmtNode) (Resu
 	rs, err := tc.Session.ExecuteStmt(ctx, stmt)
 	if err != nil {
 		tc.Session.GetSessionVars().StmtCtx.AppendError(err)
-		return nil, err
+		return nil, annotateStmtErr(err, stmt.Text(), tc.GetSessionVars().EnableRedactLog)
 	}
 	if rs == nil {
 		return nil, nil
@@ -229,6 +238,20 @@ func (tc *TiDBContext) ExecuteStmt(ctx context.Context, stmt ast.StmtNode) (Resu
 	}, nil
 }
 
+// annotateStmtErr annotates the error with the statement which causes it, so that the logs of the
+// error include the statement. The statement is truncated to maxErrorSQLLen bytes, and it is not
+// annotated if the log redaction is enabled. The error sent to the client is not changed because it
+// is built from the cause.
+func annotateStmtErr(err error, sql string, redact bool) error {
+	if redact || sql == "" {
+		return err
+	}
+	if len(sql) > maxErrorSQLLen {
+		sql = sql[:maxErrorSQLLen] + "..."
+	}
+	return errors.Annotatef(err, "executing %q", sql)
+}
+
 // Close implements QueryCtx Close method.
 func (tc *TiDBContext) Close() error {
 	// close PreparedStatement associated with this connection
@@ -398,9 +421,10 @@ func convertColumnInfo(fld *ast.ResultField) (ci *ColumnInfo) {
 		// * utf8mb4, the multiple is 4
 		// We used to check non-string types to avoid the truncation problem in some MySQL
 		// client such as Navicat. Now we only allow string type enter this branch.
+		// The charset info is preferred since utf8 shares the same encoding with utf8mb4.
 		charsetDesc, err := charset.GetCharsetInfo(fld.Column.Charset)
 		if err != nil {
-			ci.ColumnLength *= 4
+			ci.ColumnLength *= uint32(charset.NewEncoding(fld.Column.Charset).MaxCharWidth())
 		} else {
 			ci.ColumnLength *= uint32(charsetDesc.Maxlen)
 		}
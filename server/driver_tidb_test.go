@@ -15,8 +15,10 @@
 package server
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/charset"
 	"github.com/pingcap/tidb/parser/model"
@@ -112,3 +114,19 @@ func TestConvertColumnInfo(t *testing.T) {
 	colInfo = convertColumnInfo(&resultField)
 	require.Equal(t, uint32(4), colInfo.ColumnLength)
 }
+
+func TestAnnotateStmtErr(t *testing.T) {
+	err := annotateStmtErr(kv.ErrKeyExists.FastGenByArgs("1", "PRIMARY"), "insert into t values (1)", false)
+	require.True(t, kv.ErrKeyExists.Equal(err))
+	require.Equal(t, `executing "insert into t values (1)": [kv:1062]Duplicate entry '1' for key 'PRIMARY'`, err.Error())
+
+	// The long statement is truncated.
+	err = annotateStmtErr(kv.ErrKeyExists.FastGenByArgs("1", "PRIMARY"), strings.Repeat("x", 2000), false)
+	require.True(t, kv.ErrKeyExists.Equal(err))
+	require.Contains(t, err.Error(), strings.Repeat("x", maxErrorSQLLen)+`..."`)
+	require.NotContains(t, err.Error(), strings.Repeat("x", maxErrorSQLLen+1))
+
+	// The statement is not annotated if the log redaction is enabled.
+	err = annotateStmtErr(kv.ErrKeyExists.FastGenByArgs("1", "PRIMARY"), "insert into t values (1)", true)
+	require.Equal(t, "[kv:1062]Duplicate entry '1' for key 'PRIMARY'", err.Error())
+}
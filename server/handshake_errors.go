@@ -0,0 +1,127 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/metrics"
+)
+
+// handshakeErrorHistorySize is the number of the latest handshake failures kept for /debug/handshake-errors.
+const handshakeErrorHistorySize = 100
+
+// The reasons of the handshake failures, they are the labels of tidb_server_handshake_failure_total.
+const (
+	handshakeFailureTCPAccept          = "tcp-accept"
+	handshakeFailureTLS                = "tls"
+	handshakeFailureAuthPluginMismatch = "auth-plugin-mismatch"
+	handshakeFailureBadPassword        = "bad-password"
+	handshakeFailureTimeout            = "timeout"
+	handshakeFailureProxyProtocol      = "proxy-protocol-error"
+	handshakeFailureOther              = "other"
+)
+
+// handshakeError is a handshake failure shown by /debug/handshake-errors.
+type handshakeError struct {
+	Time     time.Time `json:"time"`
+	RemoteIP string    `json:"remote_ip,omitempty"`
+	Reason   string    `json:"reason"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// handshakeErrorHistory is a ring buffer of the latest handshake failures.
+type handshakeErrorHistory struct {
+	mu     sync.Mutex
+	errors []handshakeError
+	// next is the position the next failure is written to once the buffer is full.
+	next int
+	size int
+}
+
+func newHandshakeErrorHistory(size int) *handshakeErrorHistory {
+	return &handshakeErrorHistory{errors: make([]handshakeError, 0, size), size: size}
+}
+
+func (h *handshakeErrorHistory) add(e handshakeError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.errors) < h.size {
+		h.errors = append(h.errors, e)
+		return
+	}
+	h.errors[h.next] = e
+	h.next = (h.next + 1) % h.size
+}
+
+// list returns the failures from the oldest to the latest.
+func (h *handshakeErrorHistory) list() []handshakeError {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := make([]handshakeError, 0, len(h.errors))
+	result = append(result, h.errors[h.next:]...)
+	return append(result, h.errors[:h.next]...)
+}
+
+// recordHandshakeFailure counts a handshake failure by its reason and keeps it in the history.
+func (s *Server) recordHandshakeFailure(reason, remoteIP string, err error) {
+	metrics.HandshakeFailureCounter.WithLabelValues(reason).Inc()
+	e := handshakeError{Time: time.Now(), RemoteIP: remoteIP, Reason: reason}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.handshakeErrors.add(e)
+}
+
+// handshakeFailureReason returns the reason of the error returned by clientConn.handshake.
+func (cc *clientConn) handshakeFailureReason(err error) string {
+	if cc.tlsHandshakeFailed {
+		return handshakeFailureTLS
+	}
+	switch {
+	case errAccessDenied.Equal(err), errAccessDeniedNoPassword.Equal(err), errMustChangePasswordLogin.Equal(err):
+		return handshakeFailureBadPassword
+	case errNotSupportedAuthMode.Equal(err):
+		return handshakeFailureAuthPluginMismatch
+	case errSecureTransportRequired.Equal(err):
+		return handshakeFailureTLS
+	}
+	if netErr, ok := errors.Cause(err).(net.Error); ok && netErr.Timeout() {
+		return handshakeFailureTimeout
+	}
+	return handshakeFailureOther
+}
+
+// remoteIP returns the IP of the client, it is empty for the unix socket connections.
+func (cc *clientConn) remoteIP() string {
+	if cc.isUnixSocket || cc.bufReadConn == nil {
+		return ""
+	}
+	addr := cc.bufReadConn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// handleHandshakeErrors returns the latest handshake failures.
+func (s *Server) handleHandshakeErrors(w http.ResponseWriter, req *http.Request) {
+	writeData(w, s.handshakeErrors.list())
+}
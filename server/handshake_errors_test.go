@@ -0,0 +1,77 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeErrorHistory(t *testing.T) {
+	h := newHandshakeErrorHistory(3)
+	require.Empty(t, h.list())
+	for _, ip := range []string{"1", "2"} {
+		h.add(handshakeError{RemoteIP: ip})
+	}
+	require.Equal(t, []handshakeError{{RemoteIP: "1"}, {RemoteIP: "2"}}, h.list())
+	for _, ip := range []string{"3", "4", "5"} {
+		h.add(handshakeError{RemoteIP: ip})
+	}
+	require.Equal(t, []handshakeError{{RemoteIP: "3"}, {RemoteIP: "4"}, {RemoteIP: "5"}}, h.list())
+}
+
+func TestHandshakeFailureReason(t *testing.T) {
+	cc := &clientConn{}
+	testCases := []struct {
+		err    error
+		reason string
+	}{
+		{errAccessDenied.FastGenByArgs("u", "h", "YES"), handshakeFailureBadPassword},
+		{errAccessDeniedNoPassword.FastGenByArgs("u", "h"), handshakeFailureBadPassword},
+		{errNotSupportedAuthMode, handshakeFailureAuthPluginMismatch},
+		{errSecureTransportRequired.FastGenByArgs(), handshakeFailureTLS},
+		{errors.Trace(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), handshakeFailureTimeout},
+		{errors.New("EOF"), handshakeFailureOther},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.reason, cc.handshakeFailureReason(tc.err), tc.err.Error())
+	}
+	cc.tlsHandshakeFailed = true
+	require.Equal(t, handshakeFailureTLS, cc.handshakeFailureReason(errors.New("EOF")))
+}
+
+func TestHandleHandshakeErrors(t *testing.T) {
+	s := &Server{handshakeErrors: newHandshakeErrorHistory(handshakeErrorHistorySize)}
+	s.recordHandshakeFailure(handshakeFailureProxyProtocol, "", errors.New("invalid header"))
+	s.recordHandshakeFailure(handshakeFailureBadPassword, "10.0.1.5", nil)
+
+	w := httptest.NewRecorder()
+	s.handleHandshakeErrors(w, httptest.NewRequest("GET", "/debug/handshake-errors", nil))
+	var errs []handshakeError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, handshakeFailureProxyProtocol, errs[0].Reason)
+	require.Equal(t, "invalid header", errs[0].Error)
+	require.Empty(t, errs[0].RemoteIP)
+	require.Equal(t, handshakeFailureBadPassword, errs[1].Reason)
+	require.Equal(t, "10.0.1.5", errs[1].RemoteIP)
+	require.Empty(t, errs[1].Error)
+}
@@ -47,7 +47,6 @@ import (
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/terror"
 	"github.com/pingcap/tidb/session"
-	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -65,7 +64,6 @@ import (
 	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/pdapi"
-	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/tikv/client-go/v2/tikv"
 	"go.uber.org/zap"
 )
@@ -336,6 +334,7 @@ type schemaHandler struct {
 // schemaStorageHandler is the handler for list database or table schemas.
 type schemaStorageHandler struct {
 	*tikvHandlerTool
+	statusSQL *statusSQLLane
 }
 
 type dbTableHandler struct {
@@ -649,6 +648,12 @@ func (h settingsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				return
 			}
 		}
+		if generalLogTarget := req.Form.Get("tidb_general_log_target"); generalLogTarget != "" {
+			if err := logutil.SetGeneralLogTarget(generalLogTarget); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
 		if asyncCommit := req.Form.Get("tidb_enable_async_commit"); asyncCommit != "" {
 			s, err := session.CreateSession(h.Store)
 			if err != nil {
@@ -949,13 +954,6 @@ type schemaTableStorage struct {
 }
 
 func getSchemaTablesStorageInfo(h *schemaStorageHandler, schema *model.CIStr, table *model.CIStr) (messages []*schemaTableStorage, err error) {
-	var s session.Session
-	if s, err = session.CreateSession(h.Store); err != nil {
-		return
-	}
-	defer s.Close()
-
-	ctx := s.(sessionctx.Context)
 	condition := make([]string, 0)
 	params := make([]interface{}, 0)
 
@@ -968,24 +966,29 @@ func getSchemaTablesStorageInfo(h *schemaStorageHandler, schema *model.CIStr, ta
 		params = append(params, table.O)
 	}
 
-	sql := `select TABLE_SCHEMA,TABLE_NAME,TABLE_ROWS,AVG_ROW_LENGTH,DATA_LENGTH,MAX_DATA_LENGTH,INDEX_LENGTH,DATA_FREE from INFORMATION_SCHEMA.TABLES`
+	sql := statusSQLComment + `select TABLE_SCHEMA,TABLE_NAME,TABLE_ROWS,AVG_ROW_LENGTH,DATA_LENGTH,MAX_DATA_LENGTH,INDEX_LENGTH,DATA_FREE from INFORMATION_SCHEMA.TABLES`
 	if len(condition) > 0 {
 		sql += ` WHERE ` + strings.Join(condition, ` AND `)
 	}
-	var results sqlexec.RecordSet
-	if results, err = ctx.(sqlexec.SQLExecutor).ExecuteInternal(context.TODO(), sql, params...); err != nil {
-		logutil.BgLogger().Error(`ExecuteInternal`, zap.Error(err))
-	} else if results != nil {
+	err = h.statusSQL.withSession(func(ctx context.Context, s session.Session) error {
+		results, err := s.ExecuteInternal(ctx, sql, params...)
+		if err != nil {
+			logutil.BgLogger().Error(`ExecuteInternal`, zap.Error(err))
+			return err
+		}
+		if results == nil {
+			return nil
+		}
 		messages = make([]*schemaTableStorage, 0)
 		defer terror.Call(results.Close)
 		for {
 			req := results.NewChunk(nil)
-			if err = results.Next(context.TODO(), req); err != nil {
-				break
+			if err = results.Next(ctx, req); err != nil {
+				return err
 			}
 
 			if req.NumRows() == 0 {
-				break
+				return nil
 			}
 
 			for i := 0; i < req.NumRows(); i++ {
@@ -1001,7 +1004,7 @@ func getSchemaTablesStorageInfo(h *schemaStorageHandler, schema *model.CIStr, ta
 				})
 			}
 		}
-	}
+	})
 	return
 }
 
@@ -1046,7 +1049,7 @@ func (h schemaStorageHandler) ServeHTTP(w http.ResponseWriter, req *http.Request
 	}
 
 	if results, e := getSchemaTablesStorageInfo(&h, dbName, tableName); e != nil {
-		writeError(w, e)
+		writeStatusSQLError(w, e)
 	} else {
 		if isSingle {
 			writeData(w, results[0])
@@ -40,6 +40,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/util/deadlockhistory"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/versioninfo"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -56,6 +57,7 @@ func TestPostSettings(t *testing.T) {
 	form := make(url.Values)
 	form.Set("log_level", "error")
 	form.Set("tidb_general_log", "1")
+	form.Set("tidb_general_log_target", "stderr")
 	form.Set("tidb_enable_async_commit", "1")
 	form.Set("tidb_enable_1pc", "1")
 	resp, err := ts.formStatus("/settings", form)
@@ -65,6 +67,7 @@ func TestPostSettings(t *testing.T) {
 	require.Equal(t, zap.ErrorLevel, log.GetLevel())
 	require.Equal(t, "error", config.GetGlobalConfig().Log.Level)
 	require.True(t, variable.ProcessGeneralLog.Load())
+	require.Equal(t, logutil.GeneralLogTargetStderr, logutil.GetGeneralLogTarget())
 	val, err := variable.GetGlobalSystemVar(se.GetSessionVars(), variable.TiDBEnableAsyncCommit)
 	require.NoError(t, err)
 	require.Equal(t, variable.On, val)
@@ -75,6 +78,7 @@ func TestPostSettings(t *testing.T) {
 	form = make(url.Values)
 	form.Set("log_level", "fatal")
 	form.Set("tidb_general_log", "0")
+	form.Set("tidb_general_log_target", "off")
 	form.Set("tidb_enable_async_commit", "0")
 	form.Set("tidb_enable_1pc", "0")
 	resp, err = ts.formStatus("/settings", form)
@@ -82,6 +86,7 @@ func TestPostSettings(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 	require.NoError(t, resp.Body.Close())
 	require.False(t, variable.ProcessGeneralLog.Load())
+	require.Equal(t, logutil.GeneralLogTargetOff, logutil.GetGeneralLogTarget())
 	require.Equal(t, zap.FatalLevel, log.GetLevel())
 	require.Equal(t, "fatal", config.GetGlobalConfig().Log.Level)
 	val, err = variable.GetGlobalSystemVar(se.GetSessionVars(), variable.TiDBEnableAsyncCommit)
@@ -188,6 +193,79 @@ func TestPostSettings(t *testing.T) {
 	config.GetGlobalConfig().CheckMb4ValueInUTF8 = true
 }
 
+func TestLogLevel(t *testing.T) {
+	ts := createBasicHTTPHandlerTestSuite()
+	ts.startServer(t)
+	defer ts.stopServer(t)
+
+	originLevel := log.GetLevel()
+	originConfLevel := config.GetGlobalConfig().Log.Level
+	originThreshold := atomic.LoadUint64(&config.GetGlobalConfig().Log.SlowThreshold)
+	originGeneralLog := variable.ProcessGeneralLog.Load()
+	defer func() {
+		log.SetLevel(originLevel)
+		config.UpdateGlobal(func(conf *config.Config) {
+			conf.Log.Level = originConfLevel
+		})
+		atomic.StoreUint64(&config.GetGlobalConfig().Log.SlowThreshold, originThreshold)
+		variable.ProcessGeneralLog.Store(originGeneralLog)
+	}()
+
+	putLogLevel := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, ts.statusURL("/log-level"), strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp *http.Response) logSettings {
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var st logSettings
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+		require.NoError(t, resp.Body.Close())
+		return st
+	}
+
+	resp, err := ts.fetchStatus("/log-level")
+	require.NoError(t, err)
+	st := decode(resp)
+	require.Equal(t, originLevel.String(), st.Level)
+	require.Equal(t, originThreshold, st.SlowThreshold)
+	require.Equal(t, originGeneralLog, st.GeneralLog)
+
+	st = decode(putLogLevel(`{"level": "warn", "slow_threshold": 100, "general_log": true}`))
+	require.Equal(t, logSettings{Level: "warn", SlowThreshold: 100, GeneralLog: true, GeneralLogTarget: "OFF"}, st)
+	require.Equal(t, zap.WarnLevel, log.GetLevel())
+	require.Equal(t, "warn", config.GetGlobalConfig().Log.Level)
+	require.Equal(t, uint64(100), atomic.LoadUint64(&config.GetGlobalConfig().Log.SlowThreshold))
+	require.True(t, variable.ProcessGeneralLog.Load())
+
+	// The missing fields are left unchanged.
+	st = decode(putLogLevel(`{"general_log": false}`))
+	require.Equal(t, logSettings{Level: "warn", SlowThreshold: 100, GeneralLog: false, GeneralLogTarget: "OFF"}, st)
+	st = decode(putLogLevel(`{"general_log_target": "stderr"}`))
+	require.Equal(t, "STDERR", st.GeneralLogTarget)
+	require.Equal(t, logutil.GeneralLogTargetStderr, logutil.GetGeneralLogTarget())
+	st = decode(putLogLevel(`{"general_log_target": "off"}`))
+	require.Equal(t, "OFF", st.GeneralLogTarget)
+
+	// A bad request changes nothing.
+	for _, body := range []string{`{"level": "unknown", "general_log": true}`, `{"slow_threshold": -1}`, `{"general_log_target": "unknown"}`, `not json`} {
+		resp = putLogLevel(body)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	}
+	resp, err = ts.fetchStatus("/log-level")
+	require.NoError(t, err)
+	require.Equal(t, logSettings{Level: "warn", SlowThreshold: 100, GeneralLog: false, GeneralLogTarget: "OFF"}, decode(resp))
+
+	resp, err = ts.postStatus("/log-level", "application/json", strings.NewReader(`{"level": "info"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, zap.WarnLevel, log.GetLevel())
+}
+
 func TestAllServerInfo(t *testing.T) {
 	ts := createBasicHTTPHandlerTestSuite()
 	ts.startServer(t)
@@ -15,6 +15,7 @@
 package server
 
 import (
+	"archive/zip"
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
@@ -26,8 +27,10 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"net/http/httputil"
+	"net/http/httptest"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -51,6 +54,7 @@ import (
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/deadlockhistory"
 	"github.com/pingcap/tidb/util/rowcodec"
+	"github.com/pingcap/tidb/util/topsql/reporter"
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/client-go/v2/tikv"
 	"go.uber.org/zap"
@@ -1031,20 +1035,65 @@ func TestDebugZip(t *testing.T) {
 	ts := createBasicHTTPHandlerTestSuite()
 	ts.startServer(t)
 	defer ts.stopServer(t)
-	resp, err := ts.fetchStatus("/debug/zip?seconds=1")
+	resp, err := ts.fetchStatus("/debug/zip?seconds=1&slow_log_entries=10")
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
-	b, err := httputil.DumpResponse(resp, true)
+	b, err := io.ReadAll(resp.Body)
 	require.NoError(t, err)
-	require.Greater(t, len(b), 0)
 	require.NoError(t, resp.Body.Close())
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		files[f.Name] = string(content)
+	}
+	for _, name := range []string{"goroutine", "heap", "mutex", "profile", "config", "version", "processlist", "cluster_info", "slow_query"} {
+		require.Contains(t, files, name)
+	}
+	require.True(t, strings.HasPrefix(files["cluster_info"], "TYPE\tINSTANCE\t"), files["cluster_info"])
+	require.True(t, strings.HasPrefix(files["slow_query"], "Time\t"), files["slow_query"])
+
+	// Only one zip is collected at a time.
+	atomic.StoreInt32(&ts.server.debugZipRunning, 1)
+	resp, err = ts.fetchStatus("/debug/zip?seconds=1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+	atomic.StoreInt32(&ts.server.debugZipRunning, 0)
+}
+
+func TestRedactConfig(t *testing.T) {
+	t.Parallel()
+	m := map[string]interface{}{
+		"token-limit": float64(1000),
+		"security": map[string]interface{}{
+			"sha256-password-private-key-path": "/path/to/key.pem",
+			"auth-token":                       "abc",
+			"db-password":                      "123",
+			"empty-password":                   "",
+		},
+	}
+	redactConfigItems(m)
+	require.Equal(t, map[string]interface{}{
+		"token-limit": float64(1000),
+		"security": map[string]interface{}{
+			"sha256-password-private-key-path": "/path/to/key.pem",
+			"auth-token":                       "******",
+			"db-password":                      "******",
+			"empty-password":                   "",
+		},
+	}, m)
 }
 
 func TestCheckCN(t *testing.T) {
 	t.Parallel()
-	s := &Server{cfg: &config.Config{Security: config.Security{ClusterVerifyCN: []string{"a ", "b", "c"}}}}
 	tlsConfig := &tls.Config{}
-	s.setCNChecker(tlsConfig)
+	setCNChecker(tlsConfig, []string{"a ", "b", "c"})
 	require.NotNil(t, tlsConfig.VerifyPeerCertificate)
 	err := tlsConfig.VerifyPeerCertificate(nil, [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "a"}}}})
 	require.NoError(t, err)
@@ -1052,6 +1101,21 @@ func TestCheckCN(t *testing.T) {
 	require.NoError(t, err)
 	err = tlsConfig.VerifyPeerCertificate(nil, [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "d"}}}})
 	require.Error(t, err)
+
+	m := newCNMatcher([]string{"tidb-client-*", " *.tidb.svc", "*pd*", "tikv-?"})
+	for cn, match := range map[string]bool{
+		"tidb-client-":        true,
+		"tidb-client-1":       true,
+		"tidb-client":         false,
+		"tidb-0.tidb.svc":     true,
+		"tidb-0.tidb.svc.com": false,
+		"basic-pd-0":          true,
+		"tikv-?":              true,
+		"tikv-1":              false,
+	} {
+		require.Equal(t, match, m.match(cn), cn)
+	}
+	require.True(t, newCNMatcher([]string{"*"}).match("any"))
 }
 
 func TestDDLHookHandler(t *testing.T) {
@@ -1083,3 +1147,22 @@ func TestDDLHookHandler(t *testing.T) {
 	require.Equal(t, "\"success!\"", string(body))
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 }
+
+func TestHandleTopSQL(t *testing.T) {
+	s := &Server{}
+	for _, query := range []string{"window=abc", "window=-1s", "window=10m", "limit=0", "limit=abc"} {
+		w := httptest.NewRecorder()
+		s.handleTopSQL(w, httptest.NewRequest("GET", "/top-sql?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w := httptest.NewRecorder()
+	s.handleTopSQL(w, httptest.NewRequest("POST", "/top-sql", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.handleTopSQL(w, httptest.NewRequest("GET", "/top-sql?window=60s&limit=20", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats []reporter.TopSQLStat
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+}
@@ -18,34 +18,50 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"net/url"
 	"runtime"
 	rpprof "runtime/pprof"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/fn"
 	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/parser/terror"
+	plannercore "github.com/pingcap/tidb/planner/core"
+	"github.com/pingcap/tidb/privilege/privileges"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/printer"
+	"github.com/pingcap/tidb/util/timeutil"
+	"github.com/pingcap/tidb/util/topsql"
+	"github.com/pingcap/tidb/util/topsql/reporter"
 	"github.com/pingcap/tidb/util/topsql/tracecpu"
 	"github.com/pingcap/tidb/util/versioninfo"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -78,30 +94,114 @@ func sleepWithCtx(ctx context.Context, d time.Duration) {
 	}
 }
 
+// defaultDebugZipSlowLogEntries is the default number of the recent slow log entries in the debug zip.
+const defaultDebugZipSlowLogEntries = 100
+
+// redactedConfigJSON marshals the config and masks the string items which may hold credentials,
+// except the paths of the files, so that the result can be attached to bug reports.
+func redactedConfigJSON(conf *config.Config) ([]byte, error) {
+	js, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(js, &m); err != nil {
+		return nil, errors.Trace(err)
+	}
+	redactConfigItems(m)
+	return json.MarshalIndent(m, "", " ")
+}
+
+func redactConfigItems(m map[string]interface{}) {
+	for k, v := range m {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			redactConfigItems(val)
+		case string:
+			name := strings.ToLower(k)
+			if val != "" && !strings.HasSuffix(name, "path") &&
+				(strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")) {
+				m[k] = "******"
+			}
+		}
+	}
+}
+
+// writeDebugZipSQL writes the result of the SQL to the file of the debug zip as tab-separated values.
+// The error is written to the file instead if the SQL fails, so that the other files are still collected.
+func writeDebugZipSQL(zw *zip.Writer, statusSQL *statusSQLLane, name, sql string, args ...interface{}) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var buf bytes.Buffer
+	err = statusSQL.withSession(func(ctx context.Context, se session.Session) error {
+		rs, err := se.ExecuteInternal(ctx, statusSQLComment+sql, args...)
+		if err != nil {
+			return err
+		}
+		fields := rs.Fields()
+		rows, err := session.ResultSetToStringSlice(ctx, se, rs)
+		if err != nil {
+			terror.Call(rs.Close)
+			return err
+		}
+		names := make([]string, 0, len(fields))
+		for _, field := range fields {
+			names = append(names, field.Column.Name.O)
+		}
+		buf.WriteString(strings.Join(names, "\t"))
+		buf.WriteByte('\n')
+		for _, row := range rows {
+			buf.WriteString(strings.Join(row, "\t"))
+			buf.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		buf.Reset()
+		fmt.Fprintf(&buf, "Execute %q fail: %v\n", sql, err)
+	}
+	_, err = fw.Write(buf.Bytes())
+	return errors.Trace(err)
+}
+
 func (s *Server) listenStatusHTTPServer() error {
-	s.statusAddr = fmt.Sprintf("%s:%d", s.cfg.Status.StatusHost, s.cfg.Status.StatusPort)
-	if s.cfg.Status.StatusPort == 0 && !runInGoTest {
-		s.statusAddr = fmt.Sprintf("%s:%d", s.cfg.Status.StatusHost, defaultStatusPort)
+	// The status server binds on its own host, so that it can be exposed on a
+	// different interface from the MySQL port. It falls back to the MySQL host.
+	statusHost := s.cfg.Status.StatusHost
+	if statusHost == "" {
+		statusHost = s.cfg.Host
 	}
+	statusPort := s.cfg.Status.StatusPort
+	if statusPort == 0 && !runInGoTest {
+		statusPort = defaultStatusPort
+	}
+	s.statusAddr = net.JoinHostPort(statusHost, strconv.FormatUint(uint64(statusPort), 10))
 
 	logutil.BgLogger().Info("for status and metrics report", zap.String("listening on addr", s.statusAddr))
-	clusterSecurity := s.cfg.Security.ClusterSecurity()
-	tlsConfig, err := clusterSecurity.ToTLSConfig()
+	tlsConfig, err := s.loadStatusTLSConfig()
 	if err != nil {
 		logutil.BgLogger().Error("invalid TLS config", zap.Error(err))
 		return errors.Trace(err)
 	}
-	tlsConfig = s.setCNChecker(tlsConfig)
 
 	if tlsConfig != nil {
+		atomic.StorePointer(&s.statusTLSConfig, unsafe.Pointer(tlsConfig))
+		// The config is got for every handshake, so that the reloaded certificates are used by ReloadTLS.
+		listenerConfig := &tls.Config{
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return s.getStatusTLSConfig(), nil
+			},
+		}
 		// we need to manage TLS here for cmux to distinguish between HTTP and gRPC.
-		s.statusListener, err = tls.Listen("tcp", s.statusAddr, tlsConfig)
+		s.statusListener, err = tls.Listen("tcp", s.statusAddr, listenerConfig)
 	} else {
 		s.statusListener, err = net.Listen("tcp", s.statusAddr)
 	}
 	if err != nil {
 		logutil.BgLogger().Info("listen failed", zap.Error(err))
-		return errors.Trace(err)
+		return errors.Trace(newListenerError(s.statusAddr, err))
 	} else if runInGoTest && s.cfg.Status.StatusPort == 0 {
 		s.statusAddr = s.statusListener.Addr().String()
 		s.cfg.Status.StatusPort = uint(s.statusListener.Addr().(*net.TCPAddr).Port)
@@ -109,6 +209,44 @@ func (s *Server) listenStatusHTTPServer() error {
 	return nil
 }
 
+// loadStatusTLSConfig loads the TLS config of the status server from the status.tls.* files, or the
+// cluster-ssl-* files if status.tls is not set.
+// The certificate is loaded once instead of for every handshake, so that it is kept if the files
+// are changed to be invalid.
+func (s *Server) loadStatusTLSConfig() (*tls.Config, error) {
+	statusSecurity := s.cfg.StatusSecurity()
+	tlsConfig, err := statusSecurity.ToTLSConfig()
+	if err != nil || tlsConfig == nil {
+		return nil, err
+	}
+	if tlsConfig.GetCertificate != nil {
+		cert, err := tlsConfig.GetCertificate(nil)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+		tlsConfig.GetCertificate = nil
+	}
+	return setCNChecker(tlsConfig, statusSecurity.ClusterVerifyCN), nil
+}
+
+func (s *Server) getStatusTLSConfig() *tls.Config {
+	return (*tls.Config)(atomic.LoadPointer(&s.statusTLSConfig))
+}
+
+// handleTLSReload reloads the TLS certificates of the MySQL protocol server and the status server.
+func (s *Server) handleTLSReload(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, errors.Errorf("This api only support POST method."))
+		return
+	}
+	if err := s.ReloadTLS(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeData(w, "success!")
+}
+
 // Ballast try to reduce the GC frequency by using Ballast Object
 type Ballast struct {
 	ballast     []byte
@@ -192,14 +330,53 @@ func (b *Ballast) GenHTTPHandler() func(w http.ResponseWriter, r *http.Request)
 
 func (s *Server) startHTTPServer() {
 	router := mux.NewRouter()
+	statusSQL := s.newStatusSQLLane()
 
 	router.HandleFunc("/status", s.handleStatus).Name("Status")
+	// HTTP path for the readiness probes, which also checks the schema and the storage.
+	router.HandleFunc("/health", s.handleHealth).Name("Health")
+	// HTTP path for draining the server before shutdown.
+	router.HandleFunc("/drain", s.handleDrain).Name("Drain")
+	router.HandleFunc("/drain/cancel", s.handleDrainCancel).Name("DrainCancel")
+	router.HandleFunc("/pause", s.handlePause).Name("Pause")
+	router.HandleFunc("/resume", s.handleResume).Name("Resume")
+	// HTTP paths for listing and killing the client connections without a SQL connection, and for the
+	// information of the accounts. They are only served to the authenticated clients of the status server.
+	if s.statusClientsAuthenticated() {
+		router.HandleFunc("/connections", s.handleConnections).Name("Connections")
+		router.HandleFunc("/connections/{id}/kill", s.handleConnectionKill).Name("ConnectionKill")
+		// HTTP path for get the accounts that have consecutive failed logins on this instance.
+		router.Handle("/failed-logins", fn.Wrap(func() ([]privileges.FailedLoginInfo, error) {
+			return privileges.GetFailedLogins(), nil
+		})).Name("FailedLogins")
+		// HTTP path for get the number of the connections of each account on this instance.
+		router.Handle("/user-connections", fn.Wrap(func() ([]UserConnInfo, error) {
+			return s.UserConnections(), nil
+		})).Name("UserConnections")
+		// HTTP path for get the number of the statements suspected to be sent in utf8 while character_set_client
+		// is a legacy charset, per user on this instance.
+		router.Handle("/charset-mismatches", fn.Wrap(func() ([]CharsetMismatchInfo, error) {
+			return GetCharsetMismatches(), nil
+		})).Name("CharsetMismatches")
+	} else {
+		logutil.BgLogger().Warn("the /connections and the account APIs of the status server are disabled, " +
+			"set status.auth-token or the TLS of the status server with verify-cn to enable them")
+	}
+	// HTTP path for reading and changing the log level, the slow log threshold and the general log.
+	router.HandleFunc("/log-level", s.handleLogLevel).Name("LogLevel")
+	// HTTP path for the prepared plan caches of all sessions.
+	router.HandleFunc("/plan-cache", s.handlePlanCache).Name("PlanCache")
+	router.HandleFunc("/plan-cache/clear", s.handlePlanCacheClear).Name("PlanCacheClear")
+	// HTTP path for the top SQLs and plans by the CPU time in the recent Top SQL reports.
+	router.HandleFunc("/top-sql", s.handleTopSQL).Name("TopSQL")
+	// HTTP path for the RSA public key used by sha256_password.
+	router.HandleFunc("/rsa-public-key", s.handleRSAPublicKey).Name("RSAPublicKey")
 	// HTTP path for prometheus.
 	router.Handle("/metrics", promhttp.Handler()).Name("Metrics")
 
 	// HTTP path for dump statistics.
 	router.Handle("/stats/dump/{db}/{table}", s.newStatsHandler()).Name("StatsDump")
-	router.Handle("/stats/dump/{db}/{table}/{snapshot}", s.newStatsHistoryHandler()).Name("StatsHistoryDump")
+	router.Handle("/stats/dump/{db}/{table}/{snapshot}", s.newStatsHistoryHandler(statusSQL)).Name("StatsHistoryDump")
 
 	router.Handle("/plan_replayer/dump/{filename}", s.newPlanReplayerHandler()).Name("PlanReplayerDump")
 
@@ -207,6 +384,8 @@ func (s *Server) startHTTPServer() {
 
 	tikvHandlerTool := s.newTikvHandlerTool()
 	router.Handle("/settings", settingsHandler{tikvHandlerTool}).Name("Settings")
+	// HTTP path for reloading the TLS certificates without restarting the server.
+	router.HandleFunc("/settings/tls-reload", s.handleTLSReload).Name("TLSReload")
 	router.Handle("/binlog/recover", binlogRecover{}).Name("BinlogRecover")
 
 	router.Handle("/schema", schemaHandler{tikvHandlerTool}).Name("Schema")
@@ -214,9 +393,9 @@ func (s *Server) startHTTPServer() {
 	router.Handle("/schema/{db}/{table}", schemaHandler{tikvHandlerTool})
 	router.Handle("/tables/{colID}/{colTp}/{colFlag}/{colLen}", valueHandler{})
 
-	router.Handle("/schema_storage", schemaStorageHandler{tikvHandlerTool}).Name("Schema Storage")
-	router.Handle("/schema_storage/{db}", schemaStorageHandler{tikvHandlerTool})
-	router.Handle("/schema_storage/{db}/{table}", schemaStorageHandler{tikvHandlerTool})
+	router.Handle("/schema_storage", schemaStorageHandler{tikvHandlerTool, statusSQL}).Name("Schema Storage")
+	router.Handle("/schema_storage/{db}", schemaStorageHandler{tikvHandlerTool, statusSQL})
+	router.Handle("/schema_storage/{db}/{table}", schemaStorageHandler{tikvHandlerTool, statusSQL})
 
 	router.Handle("/ddl/history", ddlHistoryJobHandler{tikvHandlerTool}).Name("DDL_History")
 	router.Handle("/ddl/owner/resign", ddlResignOwnerHandler{tikvHandlerTool.Store.(kv.Storage)}).Name("DDL_Owner_Resign")
@@ -226,6 +405,16 @@ func (s *Server) startHTTPServer() {
 		return config.GetGlobalConfig(), nil
 	}))
 
+	// HTTP path for get the system time zone stored in mysql.tidb and the one detected from the host.
+	router.Handle("/system-tz", fn.Wrap(func() (systemTZInfo, error) {
+		stored, err := timeutil.GetSystemTZ()
+		if err != nil {
+			return systemTZInfo{}, err
+		}
+		detected := timeutil.InferSystemTZ()
+		return systemTZInfo{Stored: stored, Detected: detected, Mismatch: stored != detected}, nil
+	})).Name("SystemTZ")
+
 	// HTTP path for get server info.
 	router.Handle("/info", serverInfoHandler{tikvHandlerTool}).Name("Info")
 	router.Handle("/info/all", allServerInfoHandler{tikvHandlerTool}).Name("InfoALL")
@@ -290,6 +479,7 @@ func (s *Server) startHTTPServer() {
 		}
 	}
 	serverMux.HandleFunc("/debug/ballast-object-sz", ballast.GenHTTPHandler())
+	serverMux.HandleFunc("/debug/handshake-errors", s.handleHandshakeErrors)
 
 	serverMux.HandleFunc("/debug/gogc", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -317,6 +507,16 @@ func (s *Server) startHTTPServer() {
 	})
 
 	serverMux.HandleFunc("/debug/zip", func(w http.ResponseWriter, r *http.Request) {
+		// Collecting a bundle takes seconds of CPU profiling, so only one bundle is collected at a time.
+		if !atomic.CompareAndSwapInt32(&s.debugZipRunning, 0, 1) {
+			serveError(w, http.StatusTooManyRequests, "Another debug zip is being collected, please retry later")
+			return
+		}
+		defer atomic.StoreInt32(&s.debugZipRunning, 0)
+		slowLogEntries, err := strconv.Atoi(r.FormValue("slow_log_entries"))
+		if err != nil || slowLogEntries <= 0 {
+			slowLogEntries = defaultDebugZipSlowLogEntries
+		}
 		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tidb_debug"`+time.Now().Format("20060102150405")+".zip"))
 
 		// dump goroutine/heap/mutex
@@ -377,7 +577,7 @@ func (s *Server) startHTTPServer() {
 			serveError(w, http.StatusInternalServerError, fmt.Sprintf("Create zipped %s fail: %v", "config", err))
 			return
 		}
-		js, err := json.MarshalIndent(config.GetGlobalConfig(), "", " ")
+		js, err := redactedConfigJSON(config.GetGlobalConfig())
 		if err != nil {
 			serveError(w, http.StatusInternalServerError, fmt.Sprintf("get config info fail%v", err))
 			return
@@ -394,6 +594,22 @@ func (s *Server) startHTTPServer() {
 		_, err = fw.Write([]byte(printer.GetTiDBInfo()))
 		terror.Log(err)
 
+		// dump processlist
+		fw, err = zw.Create("processlist")
+		if err != nil {
+			serveError(w, http.StatusInternalServerError, fmt.Sprintf("Create zipped %s fail: %v", "processlist", err))
+			return
+		}
+		js, err = json.MarshalIndent(s.connectionInfos(), "", " ")
+		terror.Log(err)
+		_, err = fw.Write(js)
+		terror.Log(err)
+
+		// dump cluster info and the recent slow log
+		terror.Log(writeDebugZipSQL(zw, statusSQL, "cluster_info", "select * from information_schema.cluster_info"))
+		terror.Log(writeDebugZipSQL(zw, statusSQL, "slow_query",
+			"select * from information_schema.slow_query order by time desc limit %?", slowLogEntries))
+
 		err = zw.Close()
 		terror.Log(err)
 	})
@@ -451,7 +667,7 @@ func (s *Server) startStatusServerAndRPCServer(serverMux *http.ServeMux) {
 	httpL := m.Match(cmux.HTTP1Fast())
 	grpcL := m.Match(cmux.Any())
 
-	s.statusServer = &http.Server{Addr: s.statusAddr, Handler: CorsHandler{handler: serverMux, cfg: s.cfg}}
+	s.statusServer = &http.Server{Addr: s.statusAddr, Handler: newStatusHostFilter(CorsHandler{handler: statusAuthHandler{handler: serverMux, cfg: s.cfg}, cfg: s.cfg}, s.cfg)}
 	s.grpcServer = NewRPCServer(s.cfg, s.dom, s)
 	service.RegisterChannelzServiceToServer(s.grpcServer)
 
@@ -471,48 +687,146 @@ func (s *Server) startStatusServerAndRPCServer(serverMux *http.ServeMux) {
 	}
 }
 
-func (s *Server) setCNChecker(tlsConfig *tls.Config) *tls.Config {
-	if tlsConfig != nil && len(s.cfg.Security.ClusterVerifyCN) != 0 {
-		checkCN := make(map[string]struct{})
-		for _, cn := range s.cfg.Security.ClusterVerifyCN {
-			cn = strings.TrimSpace(cn)
-			checkCN[cn] = struct{}{}
+// cnMatcher matches the Common Names against the patterns of cluster-verify-cn. A pattern may
+// start or end with "*" to match any characters, e.g. "tidb-client-*" or "*.tidb.svc".
+// "?" and "*" in the middle are not supported, they are matched literally.
+type cnMatcher struct {
+	exact    map[string]struct{}
+	prefixes []string
+	suffixes []string
+	contains []string
+	any      bool
+}
+
+func newCNMatcher(patterns []string) *cnMatcher {
+	m := &cnMatcher{exact: make(map[string]struct{})}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		leading, trailing := strings.HasPrefix(pattern, "*"), strings.HasSuffix(pattern, "*")
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "*"), "*")
+		switch {
+		case (leading || trailing) && len(pattern) == 0:
+			m.any = true
+		case leading && trailing:
+			m.contains = append(m.contains, pattern)
+		case trailing:
+			m.prefixes = append(m.prefixes, pattern)
+		case leading:
+			m.suffixes = append(m.suffixes, pattern)
+		default:
+			m.exact[pattern] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (m *cnMatcher) match(cn string) bool {
+	if _, ok := m.exact[cn]; ok || m.any {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(cn, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(cn, suffix) {
+			return true
 		}
+	}
+	for _, sub := range m.contains {
+		if strings.Contains(cn, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func setCNChecker(tlsConfig *tls.Config, verifyCN []string) *tls.Config {
+	if tlsConfig != nil && len(verifyCN) != 0 {
+		checkCN := newCNMatcher(verifyCN)
 		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 			for _, chain := range verifiedChains {
-				if len(chain) != 0 {
-					if _, match := checkCN[chain[0].Subject.CommonName]; match {
-						return nil
-					}
+				if len(chain) != 0 && checkCN.match(chain[0].Subject.CommonName) {
+					return nil
 				}
 			}
-			return errors.Errorf("client certificate authentication failed. The Common Name from the client certificate was not found in the configuration cluster-verify-cn with value: %s", s.cfg.Security.ClusterVerifyCN)
+			return errors.Errorf("client certificate authentication failed. The Common Name from the client certificate was not found in the configuration cluster-verify-cn or status.tls.verify-cn with value: %s", verifyCN)
 		}
 		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 	return tlsConfig
 }
 
+// systemTZInfo is the system time zone stored in mysql.tidb and the one detected from the host.
+type systemTZInfo struct {
+	Stored   string `json:"stored"`
+	Detected string `json:"detected"`
+	Mismatch bool   `json:"mismatch"`
+}
+
 // status of TiDB.
 type status struct {
-	Connections int    `json:"connections"`
-	Version     string `json:"version"`
-	GitHash     string `json:"git_hash"`
+	Connections            int    `json:"connections"`
+	Version                string `json:"version"`
+	GitHash                string `json:"git_hash"`
+	RequireSecureTransport bool   `json:"require_secure_transport"`
+	// State is one of accepting, paused, draining and stopping.
+	State     string    `json:"state"`
+	StartTime time.Time `json:"start_time"`
+	// Uptime is the seconds elapsed since the server is created.
+	Uptime    int64  `json:"uptime"`
+	BuildTime string `json:"build_time"`
+	// Bootstrapped is true if the bootstrap is done, and BootstrapVersion is the version of the
+	// system tables it upgrades to.
+	Bootstrapped     bool   `json:"bootstrapped"`
+	BootstrapVersion int64  `json:"bootstrap_version"`
+	MaxConnections   uint32 `json:"max_connections"`
+	TLS              struct {
+		MySQL  bool `json:"mysql"`
+		Status bool `json:"status"`
+	} `json:"tls"`
+	// Config is the effective config with the credentials masked, it is returned only if verbose is true.
+	Config json.RawMessage `json:"config,omitempty"`
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	// If the server is in the process of shutting down, return a non-200 status.
-	// It is important not to return status{} as acquiring the s.ConnectionCount()
-	// acquires a lock that may already be held by the shutdown process.
-	if s.inShutdownMode {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	cfg := config.GetGlobalConfig()
 	st := status{
-		Connections: s.ConnectionCount(),
-		Version:     mysql.ServerVersion,
-		GitHash:     versioninfo.TiDBGitHash,
+		Version:                mysql.ServerVersion,
+		GitHash:                versioninfo.TiDBGitHash,
+		RequireSecureTransport: cfg.Security.RequireSecureTransport,
+		State:                  s.State(),
+		StartTime:              s.startTime,
+		Uptime:                 int64(time.Since(s.startTime).Seconds()),
+		BuildTime:              versioninfo.TiDBBuildTS,
+		Bootstrapped:           s.dom != nil,
+		MaxConnections:         cfg.MaxServerConnections,
+	}
+	if st.Bootstrapped {
+		st.BootstrapVersion = session.GetBootstrapVersion()
+	}
+	st.TLS.MySQL = atomic.LoadPointer(&s.tlsConfig) != nil
+	st.TLS.Status = s.getStatusTLSConfig() != nil
+	if verbose, _ := strconv.ParseBool(req.FormValue("verbose")); verbose {
+		js, err := redactedConfigJSON(cfg)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		st.Config = js
+	}
+	// The draining and paused servers are still alive, so that the liveness probes don't restart them.
+	// The readiness probes should use /health, which fails unless the server is accepting connections.
+	code := http.StatusOK
+	if st.State == serverStateStopping {
+		// If the server is in the process of shutting down, return a non-200 status.
+		// It is important not to call s.ConnectionCount() as it acquires a lock that
+		// may already be held by the shutdown process.
+		code = http.StatusInternalServerError
+	} else {
+		st.Connections = s.ConnectionCount()
 	}
 	js, err := json.Marshal(st)
 	if err != nil {
@@ -520,6 +834,519 @@ func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 		logutil.BgLogger().Error("encode json failed", zap.Error(err))
 		return
 	}
+	w.WriteHeader(code)
+	_, err = w.Write(js)
+	terror.Log(errors.Trace(err))
+}
+
+// The components checked by the /health API.
+const (
+	healthComponentServer    = "server"
+	healthComponentBootstrap = "bootstrap"
+	healthComponentStore     = "store"
+	healthComponentSchema    = "schema"
+)
+
+// healthProbeTimeout is the timeout of reading the storage in the /health API.
+var healthProbeTimeout = 3 * time.Second
+
+// healthProbeKey is read from the storage by the /health API. It usually does not exist.
+var healthProbeKey = kv.Key("tidb_health_probe")
+
+// health is the response of the /health API.
+type health struct {
+	Healthy bool `json:"healthy"`
+	// Component is the first failing component, which is one of server, bootstrap, store and schema.
+	Component string `json:"component,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// checkHealth checks whether the server is accepting connections, the bootstrap is done, the storage
+// can be read and the schema is loaded within the lease.
+func (s *Server) checkHealth(ctx context.Context) health {
+	if state := s.State(); state != serverStateAccepting {
+		return health{Component: healthComponentServer, Message: "the server is " + state}
+	}
+	dom := s.dom
+	if dom == nil {
+		return health{Component: healthComponentBootstrap, Message: "the bootstrap is not done"}
+	}
+	store := dom.Store()
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+		_, err = store.GetSnapshot(ver).Get(ctx, healthProbeKey)
+		cancel()
+		if kv.IsErrNotFound(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return health{Component: healthComponentStore, Message: err.Error()}
+	}
+	if _, result := dom.SchemaValidator.Check(ver.Ver, dom.InfoSchema().SchemaMetaVersion(), nil); result != domain.ResultSucc {
+		return health{Component: healthComponentSchema, Message: "the schema is not loaded within the lease"}
+	}
+	return health{Healthy: true}
+}
+
+// handleHealth returns 200 if the server is healthy, or 503 with the failing component otherwise.
+// Unlike /status, it fails if the server cannot serve the queries though it is up.
+func (s *Server) handleHealth(w http.ResponseWriter, req *http.Request) {
+	h := s.checkHealth(req.Context())
+	js, err := json.Marshal(h)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if h.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	_, err = w.Write(js)
 	terror.Log(errors.Trace(err))
 }
+
+// handleDrain stops accepting new connections and waits until the existing connections are idle
+// or graceful-drain-timeout is reached. The server is shut down by the caller after that.
+func (s *Server) handleDrain(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPauseRequest(w, req) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), time.Duration(s.cfg.GracefulDrainTimeout)*time.Second)
+	defer cancel()
+	if err := s.Drain(ctx); err != nil {
+		if err == errDrainCanceled {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+		_, err = w.Write([]byte(err.Error()))
+		terror.Log(errors.Trace(err))
+		return
+	}
+	writeData(w, "success!")
+}
+
+// handleDrainCancel cancels the draining and makes the server accept new connections again.
+func (s *Server) handleDrainCancel(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPauseRequest(w, req) {
+		return
+	}
+	if !s.CancelDrain() {
+		writeError(w, errors.Errorf("The server is not draining."))
+		return
+	}
+	writeData(w, "success!")
+}
+
+// handlePause makes the server reject new connections with ER_SERVER_SHUTDOWN.
+func (s *Server) handlePause(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPauseRequest(w, req) {
+		return
+	}
+	if err := s.Pause(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeData(w, "success!")
+}
+
+// handleResume makes the paused server accept new connections again.
+func (s *Server) handleResume(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPauseRequest(w, req) {
+		return
+	}
+	if err := s.Resume(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeData(w, "success!")
+}
+
+// connectionInfo is a client connection returned by the /connections API.
+type connectionInfo struct {
+	ID      uint64 `json:"id"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	DB      string `json:"db"`
+	Command string `json:"command"`
+	// Time is the seconds elapsed since the current command starts.
+	Time        float64 `json:"time"`
+	InTxn       bool    `json:"in_txn"`
+	TxnStartTS  uint64  `json:"txn_start_ts"`
+	Info        string  `json:"info"`
+	Digest      string  `json:"digest"`
+	MemoryUsage int64   `json:"mem"`
+	DiskUsage   int64   `json:"disk"`
+	Transport   string  `json:"transport"`
+	TLSVersion  string  `json:"tls_version,omitempty"`
+	TLSCipher   string  `json:"tls_cipher,omitempty"`
+}
+
+// statusClientsAuthenticated returns whether the clients of the status server are authenticated, by
+// status.auth-token or by the client certificates checked against verify-cn.
+func (s *Server) statusClientsAuthenticated() bool {
+	if s.cfg.Status.AuthToken != "" {
+		return true
+	}
+	statusSecurity := s.cfg.StatusSecurity()
+	return statusSecurity.ClusterSSLCA != "" && len(statusSecurity.ClusterVerifyCN) > 0
+}
+
+// handleConnections returns the client connections of this server ordered by the id.
+func (s *Server) handleConnections(w http.ResponseWriter, req *http.Request) {
+	writeData(w, s.connectionInfos())
+}
+
+// connectionInfos returns the client connections of this server ordered by the id.
+func (s *Server) connectionInfos() []connectionInfo {
+	pl := s.ShowProcessList()
+	conns := make([]connectionInfo, 0, len(pl))
+	for _, pi := range pl {
+		conn := connectionInfo{
+			ID:         pi.ID,
+			User:       pi.User,
+			Host:       pi.Host,
+			DB:         pi.DB,
+			Command:    mysql.Command2Str[pi.Command],
+			Time:       time.Since(pi.Time).Seconds(),
+			InTxn:      pi.State&mysql.ServerStatusInTrans > 0,
+			TxnStartTS: pi.CurTxnStartTS,
+			Info:       redactStatementText(pi.Info, pi.RedactSQL),
+			Digest:     pi.Digest,
+			Transport:  pi.Transport,
+		}
+		if pi.Port != "" {
+			conn.Host = net.JoinHostPort(pi.Host, pi.Port)
+		}
+		if pi.StmtCtx != nil {
+			if pi.StmtCtx.MemTracker != nil {
+				conn.MemoryUsage = pi.StmtCtx.MemTracker.BytesConsumed()
+			}
+			if pi.StmtCtx.DiskTracker != nil {
+				conn.DiskUsage = pi.StmtCtx.DiskTracker.BytesConsumed()
+			}
+		}
+		if pi.TLSState != nil {
+			conn.TLSVersion = util.TLSVersion2String(pi.TLSState.Version)
+			conn.TLSCipher = util.TLSCipher2String(pi.TLSState.CipherSuite)
+		}
+		conns = append(conns, conn)
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].ID < conns[j].ID
+	})
+	return conns
+}
+
+// redactStatementText returns the statement text shown by the /connections API. The literals are
+// hidden if tidb_redact_log is enabled, and the passwords are always hidden.
+func redactStatementText(sql string, redact bool) string {
+	if sql == "" {
+		return ""
+	}
+	if redact || errors.RedactLogEnabled.Load() {
+		return parser.Normalize(sql)
+	}
+	stmts, _, err := parser.New().ParseSQL(sql)
+	if err != nil {
+		// It can't be told whether the statement has a password.
+		return parser.Normalize(sql)
+	}
+	for _, stmt := range stmts {
+		if ss, ok := stmt.(ast.SensitiveStmtNode); ok {
+			if len(stmts) > 1 {
+				return parser.Normalize(sql)
+			}
+			return ss.SecureText()
+		}
+	}
+	return sql
+}
+
+// handleConnectionKill kills the connection, or only its running statement if query_only is true.
+func (s *Server) handleConnectionKill(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, errors.Errorf("This api only support POST method."))
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		writeError(w, errors.Errorf("Invalid connection id: %s", mux.Vars(req)["id"]))
+		return
+	}
+	queryOnly := false
+	if v := req.FormValue("query_only"); v != "" {
+		if queryOnly, err = strconv.ParseBool(v); err != nil {
+			writeError(w, errors.Errorf("Invalid query_only: %s", v))
+			return
+		}
+	}
+	s.rwlock.RLock()
+	_, ok := s.clients[id]
+	s.rwlock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, err = w.Write([]byte(fmt.Sprintf("Connection %d is not found.", id)))
+		terror.Log(errors.Trace(err))
+		return
+	}
+	s.Kill(id, queryOnly)
+	writeData(w, "success!")
+}
+
+// logSettings is the request and the response of the /log-level API.
+type logSettings struct {
+	Level string `json:"level"`
+	// SlowThreshold is the slow log threshold in milliseconds.
+	SlowThreshold uint64 `json:"slow_threshold"`
+	GeneralLog    bool   `json:"general_log"`
+	// GeneralLogTarget is the target of the structured general query log, see tidb_general_log_target.
+	GeneralLogTarget string `json:"general_log_target"`
+}
+
+func currentLogSettings() logSettings {
+	return logSettings{
+		Level:            logutil.GetLevel(),
+		SlowThreshold:    atomic.LoadUint64(&config.GetGlobalConfig().Log.SlowThreshold),
+		GeneralLog:       variable.ProcessGeneralLog.Load(),
+		GeneralLogTarget: logutil.GetGeneralLogTarget(),
+	}
+}
+
+// handleLogLevel returns the log settings on GET, and changes them on PUT. The fields missing in the
+// PUT body are left unchanged. The changes are kept in memory and are lost after restart.
+func (s *Server) handleLogLevel(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		st := currentLogSettings()
+		if err := json.NewDecoder(req.Body).Decode(&st); err != nil {
+			writeError(w, errors.Errorf("Invalid request body: %v", err))
+			return
+		}
+		// Validate the level before changing anything so that a bad request changes nothing.
+		var level zap.AtomicLevel
+		if err := level.UnmarshalText([]byte(st.Level)); err != nil {
+			writeError(w, errors.Errorf("Invalid log level: %s", st.Level))
+			return
+		}
+		if st.SlowThreshold > math.MaxInt64 {
+			writeError(w, errors.Errorf("Invalid slow threshold: %d", st.SlowThreshold))
+			return
+		}
+		if err := logutil.SetGeneralLogTarget(st.GeneralLogTarget); err != nil {
+			writeError(w, err)
+			return
+		}
+		terror.Log(logutil.SetLevel(st.Level))
+		config.UpdateGlobal(func(conf *config.Config) {
+			conf.Log.Level = level.String()
+		})
+		atomic.StoreUint64(&config.GetGlobalConfig().Log.SlowThreshold, st.SlowThreshold)
+		variable.ProcessGeneralLog.Store(st.GeneralLog)
+		logutil.BgLogger().Info("log settings changed by the status API",
+			zap.String("level", level.String()),
+			zap.Uint64("slow-threshold", st.SlowThreshold),
+			zap.Bool("general-log", st.GeneralLog),
+			zap.String("general-log-target", st.GeneralLogTarget))
+	default:
+		writeError(w, errors.Errorf("This api only support GET and PUT method."))
+		return
+	}
+	writeData(w, currentLogSettings())
+}
+
+// handlePlanCache returns the plans in the prepared plan caches of all sessions, aggregated by the
+// SQL digest and the schema version.
+func (s *Server) handlePlanCache(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, errors.Errorf("This api only support GET method."))
+		return
+	}
+	writeData(w, plannercore.GetPreparedPlanCacheStats())
+}
+
+// handlePlanCacheClear clears the prepared plan caches of all sessions.
+func (s *Server) handlePlanCacheClear(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, errors.Errorf("This api only support POST method."))
+		return
+	}
+	plannercore.ClearPreparedPlanCaches()
+	logutil.BgLogger().Info("prepared plan caches are cleared by the status API")
+	writeData(w, "success!")
+}
+
+// handleTopSQL returns the SQLs and plans consuming the most CPU time in the last window, which is kept in
+// memory by the Top SQL reporter whether a receiver is configured or not.
+func (s *Server) handleTopSQL(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, errors.Errorf("This api only support GET method."))
+		return
+	}
+	window, limit := time.Minute, 20
+	if v := req.FormValue("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > reporter.MaxWindow {
+			writeError(w, errors.Errorf("invalid window %s, it should be a positive duration no longer than %s", v, reporter.MaxWindow))
+			return
+		}
+		window = d
+	}
+	if v := req.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, errors.Errorf("invalid limit %s, it should be a positive integer", v))
+			return
+		}
+		limit = n
+	}
+	writeData(w, topsql.GetTopStats(window, limit))
+}
+
+// checkPauseRequest checks the method and the pause-token of the /pause, /resume, /drain and /drain/cancel
+// requests, which take the server out of service, and writes the error response if the check fails.
+func (s *Server) checkPauseRequest(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodPost {
+		writeError(w, errors.Errorf("This api only support POST method."))
+		return false
+	}
+	token := s.cfg.Status.PauseToken
+	if token == "" {
+		w.WriteHeader(http.StatusForbidden)
+		_, err := w.Write([]byte("This api is disabled because pause-token is not set."))
+		terror.Log(errors.Trace(err))
+		return false
+	}
+	if !hasBearerToken(req, token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, err := w.Write([]byte("Invalid token."))
+		terror.Log(errors.Trace(err))
+		return false
+	}
+	return true
+}
+
+// hasBearerToken checks whether the request has "Authorization: Bearer <token>".
+func hasBearerToken(req *http.Request, token string) bool {
+	auth := req.Header.Get("Authorization")
+	return strings.HasPrefix(auth, "Bearer ") &&
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
+
+// statusAuthHandler requires the auth-token of the status server if it is set.
+// The paths in auth-allowlist are not checked, and neither are /pause, /resume, /drain and /drain/cancel
+// if pause-token is set because they check it by themselves.
+type statusAuthHandler struct {
+	handler http.Handler
+	cfg     *config.Config
+}
+
+func (h statusAuthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := h.cfg.Status
+	if status.AuthToken == "" || isStatusAuthExempt(status, req.URL.Path) || hasBearerToken(req, status.AuthToken) {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+	metrics.StatusAuthFailureCounter.Inc()
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, err := w.Write([]byte("Invalid token."))
+	terror.Log(errors.Trace(err))
+}
+
+func isStatusAuthExempt(status config.Status, path string) bool {
+	if status.PauseToken != "" {
+		switch path {
+		case "/pause", "/resume", "/drain", "/drain/cancel":
+			return true
+		}
+	}
+	for _, allowed := range status.AuthAllowlist {
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// statusHostFilter rejects the requests to the status server from the hosts not in allowed-hosts.
+type statusHostFilter struct {
+	handler        http.Handler
+	allowedHosts   []*net.IPNet
+	trustedProxies []*net.IPNet
+}
+
+// newStatusHostFilter returns the handler itself if all hosts are allowed. If allowed-hosts is
+// invalid, all the requests are rejected.
+func newStatusHostFilter(handler http.Handler, cfg *config.Config) http.Handler {
+	if len(cfg.Status.AllowedHosts) == 0 {
+		return handler
+	}
+	allowedHosts, err := config.ParseHosts(cfg.Status.AllowedHosts)
+	if err != nil {
+		logutil.BgLogger().Error("invalid status.allowed-hosts, all the requests to the status server are rejected", zap.Error(err))
+	}
+	trustedProxies, err := config.ParseHosts(cfg.Status.TrustedProxies)
+	if err != nil {
+		logutil.BgLogger().Error("invalid status.trusted-proxies, X-Forwarded-For is not trusted", zap.Error(err))
+	}
+	return statusHostFilter{
+		handler:        handler,
+		allowedHosts:   allowedHosts,
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (h statusHostFilter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ip := h.clientIP(req)
+	if ip != nil && containsIP(h.allowedHosts, ip) {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+	metrics.StatusHostRejectCounter.Inc()
+	logutil.BgLogger().Warn("reject the request to the status server from the host not in allowed-hosts",
+		zap.String("remoteAddr", req.RemoteAddr),
+		zap.Strings("forwardedFor", req.Header.Values("X-Forwarded-For")),
+		zap.String("path", req.URL.Path))
+	w.WriteHeader(http.StatusForbidden)
+	_, err := w.Write([]byte("Forbidden."))
+	terror.Log(errors.Trace(err))
+}
+
+// clientIP returns the address of the client, or nil if it is unknown. X-Forwarded-For is only used
+// if the request comes from a trusted proxy.
+func (h statusHostFilter) clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	forwardedFor := req.Header.Values("X-Forwarded-For")
+	if ip == nil || len(forwardedFor) == 0 || !containsIP(h.trustedProxies, ip) {
+		return ip
+	}
+	// Each proxy appends the address of its peer, so the client is the last address which is not a
+	// trusted proxy. The addresses before it may be forged by the client.
+	addrs := strings.Split(strings.Join(forwardedFor, ","), ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(addrs[i]))
+		if ip == nil || !containsIP(h.trustedProxies, ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ListenerError is returned by NewServer and Server.Run when the server fails to listen on or accept
+// connections from an address, so that the callers can tell a port conflict from the other failures
+// without matching the error message.
+type ListenerError struct {
+	// Addr is the address or the socket file the server listens on.
+	Addr string
+	Err  error
+}
+
+func newListenerError(addr string, err error) *ListenerError {
+	return &ListenerError{Addr: addr, Err: err}
+}
+
+// Error implements the error interface, it returns the message of the original error.
+func (e *ListenerError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+// IsAddrInUse returns whether the address is already in use.
+func (e *ListenerError) IsAddrInUse() bool {
+	return errors.Is(e.Err, syscall.EADDRINUSE)
+}
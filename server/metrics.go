@@ -0,0 +1,56 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var (
+	// connGaugeServer is the server whose connection count is reported by connGauge.
+	connGaugeServer       atomic.Value
+	registerConnGaugeOnce sync.Once
+
+	// connGauge is tidb_server_connections. It reads Server.ConnectionCount when it is collected,
+	// so it never drifts from the connections the server actually holds.
+	connGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "connections",
+			Help:      "Number of connections.",
+		}, func() float64 {
+			s, ok := connGaugeServer.Load().(*Server)
+			if !ok || s == nil {
+				return 0
+			}
+			return float64(s.ConnectionCount())
+		})
+)
+
+// registerConnGauge makes connGauge report the connection count of s.
+func registerConnGauge(s *Server) {
+	connGaugeServer.Store(s)
+	registerConnGaugeOnce.Do(func() {
+		if err := prometheus.Register(connGauge); err != nil {
+			logutil.BgLogger().Warn("register connection gauge failed", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnGauge(t *testing.T) {
+	s := &Server{clients: make(map[uint64]*clientConn)}
+	registerConnGauge(s)
+	defer connGaugeServer.Store((*Server)(nil))
+
+	connections := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, connGauge.Write(m))
+		return m.GetGauge().GetValue()
+	}
+	require.Equal(t, float64(0), connections())
+
+	s.clients[1] = &clientConn{}
+	s.clients[2] = &clientConn{}
+	require.Equal(t, 2, s.ConnectionCount())
+	require.Equal(t, float64(2), connections())
+
+	delete(s.clients, 1)
+	require.Equal(t, float64(1), connections())
+}
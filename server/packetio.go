@@ -38,6 +38,7 @@ package server
 import (
 	"bufio"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -55,10 +56,22 @@ var (
 
 // packetIO is a helper to read and write data in packet format.
 type packetIO struct {
+	// readBytes and writtenBytes are the bytes of the packets since the last flushUserMetrics,
+	// they are accessed atomically.
+	readBytes    uint64
+	writtenBytes uint64
+
 	bufReadConn *bufferedReadConn
 	bufWriter   *bufio.Writer
 	sequence    uint8
 	readTimeout time.Duration
+	// deadline is the read deadline when readTimeout is 0, it is set during the handshake.
+	deadline time.Time
+
+	// writeBuffer accounts the memory held by bufWriter, it may be nil.
+	writeBuffer *connWriteBuffer
+	// compressedConn is not nil if the packets are sent through the compressed protocol.
+	compressedConn *compressedConn
 }
 
 func newPacketIO(bufReadConn *bufferedReadConn) *packetIO {
@@ -72,6 +85,14 @@ func (p *packetIO) setBufferedReadConn(bufReadConn *bufferedReadConn) {
 	p.bufWriter = bufio.NewWriterSize(bufReadConn, defaultWriterSize)
 }
 
+// resetSequence resets the sequence at the beginning of a command.
+func (p *packetIO) resetSequence() {
+	p.sequence = 0
+	if p.compressedConn != nil {
+		p.compressedConn.sequence = 0
+	}
+}
+
 func (p *packetIO) setReadTimeout(timeout time.Duration) {
 	p.readTimeout = timeout
 }
@@ -110,7 +131,7 @@ func (p *packetIO) readOnePacket() ([]byte, error) {
 
 func (p *packetIO) readPacket() ([]byte, error) {
 	if p.readTimeout == 0 {
-		if err := p.bufReadConn.SetReadDeadline(time.Time{}); err != nil {
+		if err := p.bufReadConn.SetReadDeadline(p.deadline); err != nil {
 			return nil, errors.Trace(err)
 		}
 	}
@@ -121,6 +142,7 @@ func (p *packetIO) readPacket() ([]byte, error) {
 
 	if len(data) < mysql.MaxPayloadLen {
 		readPacketBytes.Observe(float64(len(data)))
+		atomic.AddUint64(&p.readBytes, uint64(len(data)))
 		return data, nil
 	}
 
@@ -139,6 +161,7 @@ func (p *packetIO) readPacket() ([]byte, error) {
 	}
 
 	readPacketBytes.Observe(float64(len(data)))
+	atomic.AddUint64(&p.readBytes, uint64(len(data)))
 	return data, nil
 }
 
@@ -146,6 +169,7 @@ func (p *packetIO) readPacket() ([]byte, error) {
 func (p *packetIO) writePacket(data []byte) error {
 	length := len(data) - 4
 	writePacketBytes.Observe(float64(len(data)))
+	atomic.AddUint64(&p.writtenBytes, uint64(len(data)))
 
 	for length >= mysql.MaxPayloadLen {
 		data[0] = 0xff
@@ -177,12 +201,19 @@ func (p *packetIO) writePacket(data []byte) error {
 		return errors.Trace(mysql.ErrBadConn)
 	} else {
 		p.sequence++
-		return nil
 	}
+	p.writeBuffer.setBuffered(p.bufWriter.Buffered())
+	// The connection is picked to release its memory, flush the buffer synchronously.
+	if p.writeBuffer.isVictim() {
+		writeBufferEvictFlush.Inc()
+		return p.flush()
+	}
+	return nil
 }
 
 func (p *packetIO) flush() error {
 	err := p.bufWriter.Flush()
+	p.writeBuffer.setBuffered(p.bufWriter.Buffered())
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -0,0 +1,50 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// proxiedUserAttr is the connection attribute with which a proxy asks the connection
+// to act as another user, like the proxy users of MySQL.
+const proxiedUserAttr = "tidb_proxied_user"
+
+// proxyAs switches the effective privileges of the session to the user requested by the
+// tidb_proxied_user connection attribute. The logged-in account must have the PROXY privilege
+// on the proxied account, which is matched by the user name and the client host. current_user()
+// still reports the logged-in account.
+func (cc *clientConn) proxyAs(host, hasPassword string) error {
+	proxied := cc.attrs[proxiedUserAttr]
+	if proxied == "" {
+		return nil
+	}
+	identity, err := cc.ctx.MatchIdentity(proxied, host)
+	if err != nil {
+		return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	}
+	pm := privilege.GetPrivilegeManager(cc.ctx.Session)
+	if pm == nil || !pm.ProxyAs(identity.Username, identity.Hostname) {
+		logutil.BgLogger().Warn("proxy user denied", zap.Uint64("conn", cc.connectionID),
+			zap.String("user", cc.user), zap.Stringer("proxied", identity))
+		return errAccessDenied.FastGenByArgs(cc.user, host, hasPassword)
+	}
+	sessionVars := cc.ctx.GetSessionVars()
+	sessionVars.ProxiedUser = identity
+	sessionVars.ActiveRoles = pm.GetDefaultRoles(identity.Username, identity.Hostname)
+	return nil
+}
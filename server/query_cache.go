@@ -0,0 +1,347 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/chunk"
+)
+
+var (
+	queryCacheHitCounter   = metrics.QueryCacheCounter.WithLabelValues("hit")
+	queryCacheMissCounter  = metrics.QueryCacheCounter.WithLabelValues("miss")
+	queryCacheEvictCounter = metrics.QueryCacheCounter.WithLabelValues("evict")
+)
+
+// queryCacheSessionVars are the session variables that change the results of the same statement,
+// they are a part of the key of the query result cache. The variables only changing the plans, like
+// the tidb_opt_* ones, are not included because the cached statements return the same rows anyway.
+var queryCacheSessionVars = []string{
+	variable.SQLModeVar,
+	variable.TimeZone,
+	variable.CharacterSetResults,
+	variable.CollationConnection,
+	variable.GroupConcatMaxLen,
+	variable.DefaultWeekFormat,
+	variable.BlockEncryptionMode,
+	variable.SQLSelectLimit,
+	variable.MaxAllowedPacket,
+	variable.WindowingUseHighPrecision,
+	variable.TiDBEnableOrderedResultMode,
+}
+
+// queryCacheEntry is the result of a statement cached in the query result cache.
+type queryCacheEntry struct {
+	key     string
+	columns []*ColumnInfo
+	// template is an empty chunk with the field types of the result.
+	template *chunk.Chunk
+	chunks   []*chunk.Chunk
+	size     int64
+	// tables are the tables accessed by the statement, they are restored to the statement context on a hit.
+	tables []stmtctx.TableEntry
+}
+
+// queryResultCache is an LRU cache of the results of the read-only statements, which is bounded by
+// the memory size of the results. It is shared by all the connections of the server.
+type queryResultCache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	lru      *list.List
+	entries  map[string]*list.Element
+}
+
+func newQueryResultCache(capacity int64) *queryResultCache {
+	return &queryResultCache{
+		capacity: capacity,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *queryResultCache) get(key string) *queryCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		queryCacheMissCounter.Inc()
+		return nil
+	}
+	queryCacheHitCounter.Inc()
+	c.lru.MoveToFront(element)
+	return element.Value.(*queryCacheEntry)
+}
+
+func (c *queryResultCache) put(entry *queryCacheEntry) {
+	if entry.size > c.capacity {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[entry.key]; ok {
+		c.removeLocked(element)
+	}
+	for c.size+entry.size > c.capacity {
+		c.removeLocked(c.lru.Back())
+		queryCacheEvictCounter.Inc()
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += entry.size
+	metrics.QueryCacheMemoryUsage.Set(float64(c.size))
+}
+
+func (c *queryResultCache) removeLocked(element *list.Element) {
+	entry := c.lru.Remove(element).(*queryCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// clear drops all the cached results, it is called when the schema or the privileges change.
+func (c *queryResultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.size = 0
+	metrics.QueryCacheMemoryUsage.Set(0)
+}
+
+// queryCacheKey returns the key of stmt in the query result cache, it returns false if the result of
+// stmt can't be cached. Only the statements reading a fixed snapshot, that is the statements executed
+// with tidb_snapshot or in a read-only explicit transaction, are cached, because the other statements
+// read the latest data with a new timestamp each time.
+func (cc *clientConn) queryCacheKey(stmt ast.StmtNode) (string, bool) {
+	switch x := stmt.(type) {
+	case *ast.SelectStmt:
+		if x.SelectIntoOpt != nil {
+			return "", false
+		}
+	case *ast.SetOprStmt:
+	default:
+		return "", false
+	}
+	sessVars := cc.ctx.GetSessionVars()
+	if sessVars.User == nil {
+		return "", false
+	}
+	snapshotTS := sessVars.SnapshotTS
+	if snapshotTS == 0 {
+		if !sessVars.InTxn() || sessVars.IsIsolation(ast.ReadCommitted) {
+			return "", false
+		}
+		txn, err := cc.ctx.Txn(false)
+		if err != nil || !txn.Valid() || !txn.IsReadOnly() {
+			return "", false
+		}
+		snapshotTS = txn.StartTS()
+	}
+	is, ok := cc.ctx.GetInfoSchema().(infoschema.InfoSchema)
+	if !ok {
+		return "", false
+	}
+	checker := queryCacheChecker{is: is, currentDB: sessVars.CurrentDB, cacheable: true}
+	stmt.Accept(&checker)
+	if !checker.cacheable {
+		return "", false
+	}
+
+	// The digest normalizes the literals, so the text is a part of the key as well.
+	sql := stmt.Text()
+	_, digest := parser.NormalizeDigest(sql)
+	var key strings.Builder
+	key.WriteString(digest.String())
+	key.WriteByte(0)
+	key.WriteString(strconv.FormatInt(is.SchemaMetaVersion(), 10))
+	key.WriteByte(0)
+	key.WriteString(strconv.FormatUint(snapshotTS, 10))
+	key.WriteByte(0)
+	key.WriteString(sessVars.User.AuthUsername)
+	key.WriteByte('@')
+	key.WriteString(sessVars.User.AuthHostname)
+	for _, role := range sessVars.ActiveRoles {
+		key.WriteByte(0)
+		key.WriteString(role.String())
+	}
+	key.WriteByte(0)
+	key.WriteString(sessVars.CurrentDB)
+	for _, name := range queryCacheSessionVars {
+		value, _ := sessVars.GetSystemVar(name)
+		key.WriteByte(0)
+		key.WriteString(value)
+	}
+	key.WriteByte(0)
+	key.WriteString(sql)
+	return key.String(), true
+}
+
+// queryCacheChecker checks whether the result of a statement only depends on the snapshot it reads.
+type queryCacheChecker struct {
+	is        infoschema.InfoSchema
+	currentDB string
+	cacheable bool
+}
+
+// Enter implements the ast.Visitor interface.
+func (c *queryCacheChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.VariableExpr, ast.ParamMarkerExpr, *ast.TableSample:
+		c.cacheable = false
+	case *ast.SelectStmt:
+		if x.LockInfo != nil && x.LockInfo.LockType != ast.SelectLockNone {
+			c.cacheable = false
+		}
+	case *ast.FuncCallExpr:
+		if _, ok := expression.IllegalFunctions4GeneratedColumns[x.FnName.L]; ok {
+			c.cacheable = false
+		} else if _, ok := expression.DeferredFunctions[x.FnName.L]; ok {
+			c.cacheable = false
+		}
+		switch x.FnName.L {
+		case ast.NextVal, ast.LastVal, ast.SetVal, ast.TiDBIsDDLOwner:
+			c.cacheable = false
+		}
+	case *ast.TableName:
+		c.cacheable = c.cacheable && c.isCacheableTable(x)
+	}
+	return in, !c.cacheable
+}
+
+// isCacheableTable returns whether the data of the table is read from the snapshot. The views are not
+// cached because their definitions may be non-deterministic, and the names which are not tables, such as
+// the CTEs, are not cached for simplicity.
+func (c *queryCacheChecker) isCacheableTable(name *ast.TableName) bool {
+	if name.AsOf != nil {
+		return false
+	}
+	schema := name.Schema
+	if schema.L == "" {
+		schema = model.NewCIStr(c.currentDB)
+	}
+	if util.IsMemDB(schema.L) {
+		return false
+	}
+	tbl, err := c.is.TableByName(schema, name.Name)
+	if err != nil {
+		return false
+	}
+	meta := tbl.Meta()
+	return !meta.IsView() && !meta.IsSequence() && meta.TempTableType == model.TempTableNone
+}
+
+// Leave implements the ast.Visitor interface.
+func (c *queryCacheChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, c.cacheable
+}
+
+// queryCacheRecorder records the result of a statement for the query result cache while it is written
+// to the client. It gives up recording once the result is larger than the cache.
+type queryCacheRecorder struct {
+	ResultSet
+	entry  *queryCacheEntry
+	tables []stmtctx.TableEntry
+	limit  int64
+}
+
+func newQueryCacheRecorder(rs ResultSet, key string, tables []stmtctx.TableEntry, limit int64) *queryCacheRecorder {
+	return &queryCacheRecorder{
+		ResultSet: rs,
+		entry:     &queryCacheEntry{key: key, template: rs.NewChunk(nil), size: int64(len(key))},
+		tables:    append([]stmtctx.TableEntry(nil), tables...),
+		limit:     limit,
+	}
+}
+
+// Next implements the ResultSet interface.
+func (r *queryCacheRecorder) Next(ctx context.Context, req *chunk.Chunk) error {
+	if err := r.ResultSet.Next(ctx, req); err != nil {
+		r.entry = nil
+		return err
+	}
+	if r.entry == nil || req.NumRows() == 0 {
+		return nil
+	}
+	chk := req.CopyConstructSel()
+	r.entry.chunks = append(r.entry.chunks, chk)
+	r.entry.size += chk.MemoryUsage()
+	if r.entry.size > r.limit {
+		r.entry = nil
+	}
+	return nil
+}
+
+// finish returns the recorded entry, it returns nil if the result is not recorded completely.
+func (r *queryCacheRecorder) finish() *queryCacheEntry {
+	if r.entry == nil {
+		return nil
+	}
+	r.entry.columns = r.Columns()
+	r.entry.tables = r.tables
+	return r.entry
+}
+
+// queryCacheResultSet replays a cached result.
+type queryCacheResultSet struct {
+	entry *queryCacheEntry
+	next  int
+}
+
+// Columns implements the ResultSet interface.
+func (rs *queryCacheResultSet) Columns() []*ColumnInfo {
+	return rs.entry.columns
+}
+
+// NewChunk implements the ResultSet interface.
+func (rs *queryCacheResultSet) NewChunk(chunk.Allocator) *chunk.Chunk {
+	return rs.entry.template.CopyConstruct()
+}
+
+// Next implements the ResultSet interface.
+func (rs *queryCacheResultSet) Next(_ context.Context, req *chunk.Chunk) error {
+	req.Reset()
+	if rs.next < len(rs.entry.chunks) {
+		chk := rs.entry.chunks[rs.next]
+		req.Append(chk, 0, chk.NumRows())
+		rs.next++
+	}
+	return nil
+}
+
+// StoreFetchedRows implements the ResultSet interface.
+func (rs *queryCacheResultSet) StoreFetchedRows([]chunk.Row) {}
+
+// GetFetchedRows implements the ResultSet interface.
+func (rs *queryCacheResultSet) GetFetchedRows() []chunk.Row {
+	return nil
+}
+
+// Close implements the ResultSet interface.
+func (rs *queryCacheResultSet) Close() error {
+	return nil
+}
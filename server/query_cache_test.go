@@ -0,0 +1,152 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryResultCacheLRU(t *testing.T) {
+	cache := newQueryResultCache(100)
+	cache.put(&queryCacheEntry{key: "a", size: 40})
+	cache.put(&queryCacheEntry{key: "b", size: 40})
+	require.NotNil(t, cache.get("a"))
+
+	// "b" is the least recently used entry, so it is evicted.
+	cache.put(&queryCacheEntry{key: "c", size: 40})
+	require.Nil(t, cache.get("b"))
+	require.NotNil(t, cache.get("a"))
+	require.NotNil(t, cache.get("c"))
+	require.Equal(t, int64(80), cache.size)
+
+	// The entries larger than the cache are not cached.
+	cache.put(&queryCacheEntry{key: "d", size: 101})
+	require.Nil(t, cache.get("d"))
+	require.Equal(t, 2, cache.lru.Len())
+
+	// Putting an existing key replaces the old entry.
+	cache.put(&queryCacheEntry{key: "a", size: 10})
+	require.Equal(t, int64(50), cache.size)
+
+	cache.clear()
+	require.Nil(t, cache.get("a"))
+	require.Nil(t, cache.get("c"))
+	require.Equal(t, int64(0), cache.size)
+}
+
+func TestQueryResultCache(t *testing.T) {
+	store, dom, clean := testkit.CreateMockStoreAndDomain(t)
+	defer clean()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Performance.QueryCacheSizeBytes = 1 << 20
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	srv.SetDomain(dom)
+	ctx := context.Background()
+
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec(`INSERT INTO mysql.tidb VALUES ('tikv_gc_safe_point', '20060102-15:04:05 -0700', '')
+	ON DUPLICATE KEY UPDATE variable_value = '20060102-15:04:05 -0700'`)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b varchar(10))")
+	tk.MustExec("insert into t values (1, 'a'), (2, 'b')")
+
+	out := new(bytes.Buffer)
+	cc := &clientConn{
+		connectionID: 1,
+		alloc:        arena.NewAllocator(1024),
+		chunkAlloc:   chunk.NewAllocator(),
+		collation:    mysql.DefaultCollationID,
+		peerHost:     "localhost",
+		pkt:          &packetIO{bufWriter: bufio.NewWriter(out)},
+		server:       srv,
+		user:         "root",
+		capability:   defaultCapability,
+	}
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+	query := func(sql string) []byte {
+		out.Reset()
+		cc.pkt.sequence = 0
+		require.NoError(t, cc.handleQuery(ctx, sql))
+		require.NoError(t, cc.flush(ctx))
+		return append([]byte(nil), out.Bytes()...)
+	}
+
+	// The statements out of a transaction read the latest data, they are not cached.
+	query("use test")
+	query("select * from t")
+	require.Equal(t, 0, srv.queryCache.lru.Len())
+
+	// The statements in a read-only transaction are cached.
+	query("begin")
+	query("select * from t where a > 0")
+	require.Equal(t, 1, srv.queryCache.lru.Len())
+	miss := query("select * from t")
+	require.Equal(t, 2, srv.queryCache.lru.Len())
+	hit := query("select * from t")
+	require.Equal(t, 2, srv.queryCache.lru.Len())
+	require.Equal(t, miss, hit)
+
+	// The session variables changing the results are a part of the key.
+	query("set @@sql_select_limit = 1")
+	limited := query("select * from t")
+	require.Equal(t, 3, srv.queryCache.lru.Len())
+	require.NotEqual(t, hit, limited)
+	query("set @@sql_select_limit = default")
+	require.Equal(t, hit, query("select * from t"))
+	require.Equal(t, 3, srv.queryCache.lru.Len())
+
+	// The non-deterministic statements are not cached.
+	query("select a, rand() from t")
+	query("select now(), b from t")
+	query("select @@tidb_current_ts")
+	query("select a from t for update")
+	require.Equal(t, 3, srv.queryCache.lru.Len())
+	query("commit")
+
+	// The cache is cleared after a newer schema is loaded.
+	tk.MustExec("alter table t add column c int")
+	require.NoError(t, dom.Reload())
+	require.Equal(t, 0, srv.queryCache.lru.Len())
+
+	// The statements in a transaction that has written data are not cached.
+	query("begin")
+	query("insert into t values (3, 'c', 3)")
+	query("select * from t")
+	query("rollback")
+	require.Equal(t, 0, srv.queryCache.lru.Len())
+
+	// The statements reading a snapshot are cached, and the cache is cleared after the privileges are reloaded.
+	query("set @@tidb_snapshot = '" + time.Now().Format("2006-01-02 15:04:05.999999") + "'")
+	query("select * from t")
+	query("select * from t")
+	query("set @@tidb_snapshot = ''")
+	require.Equal(t, 1, srv.queryCache.lru.Len())
+	tk.MustExec("flush privileges")
+	require.Equal(t, 0, srv.queryCache.lru.Len())
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+const (
+	autoRSAPrivateKeyFile = "private_key.pem"
+	autoRSAPublicKeyFile  = "public_key.pem"
+)
+
+// rsaKeyPair is the RSA key pair used to protect the password sent by sha256_password and
+// caching_sha2_password clients when the connection is neither TLS nor unix socket.
+type rsaKeyPair struct {
+	privateKey   *rsa.PrivateKey
+	publicKeyPEM []byte
+}
+
+func newRSAKeyPair(privateKey *rsa.PrivateKey) (*rsaKeyPair, error) {
+	pubBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &rsaKeyPair{
+		privateKey:   privateKey,
+		publicKeyPEM: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}),
+	}, nil
+}
+
+// fingerprint returns the hex encoded SHA-256 digest of the DER encoded public key.
+func (k *rsaKeyPair) fingerprint() string {
+	block, _ := pem.Decode(k.publicKeyPEM)
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// decryptPassword decrypts the password encrypted by the client with the public key.
+// The client XORs the null-terminated password with the salt before encrypting it.
+func (k *rsaKeyPair) decryptPassword(data, salt []byte) ([]byte, error) {
+	plain, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, k.privateKey, data, nil) // #nosec G401
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(salt) > 0 {
+		for i := range plain {
+			plain[i] ^= salt[i%len(salt)]
+		}
+	}
+	if len(plain) > 0 && plain[len(plain)-1] == 0 {
+		plain = plain[:len(plain)-1]
+	}
+	return plain, nil
+}
+
+func loadRSAKeyPair(privateKeyPath string) (*rsaKeyPair, error) {
+	privPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	block, _ := pem.Decode(privPEM)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in RSA private key file %s", privateKeyPath)
+	}
+	var privateKey *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		var key interface{}
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if privateKey, ok = key.(*rsa.PrivateKey); !ok {
+				err = errors.Errorf("private key in %s is not a RSA key", privateKeyPath)
+			}
+		}
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newRSAKeyPair(privateKey)
+}
+
+func createRSAKeyPair(privateKeyPath, publicKeyPath string, keySize int) (*rsaKeyPair, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	keyPair, err := newRSAKeyPair(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	if err = os.WriteFile(privateKeyPath, privPEM, 0600); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = os.WriteFile(publicKeyPath, keyPair.publicKeyPEM, 0644); err != nil {
+		return nil, errors.Trace(err)
+	}
+	logutil.BgLogger().Info("RSA key pair for sha256_password created", zap.String("private key", privateKeyPath),
+		zap.String("public key", publicKeyPath), zap.Int("rsaKeySize", keySize))
+	return keyPair, nil
+}
+
+// initRSAKeyPair loads the configured RSA key pair. When no key is configured, the key pair
+// previously generated under the data directory is loaded or a new one is created.
+func (s *Server) initRSAKeyPair(regenerate bool) (*rsaKeyPair, error) {
+	if privPath := s.cfg.Security.SHA256PasswordPrivateKeyPath; privPath != "" {
+		return loadRSAKeyPair(privPath)
+	}
+	dataDir := s.cfg.DataDir()
+	privPath := filepath.Join(dataDir, autoRSAPrivateKeyFile)
+	pubPath := filepath.Join(dataDir, autoRSAPublicKeyFile)
+	if !regenerate {
+		if _, err := os.Stat(privPath); err == nil {
+			return loadRSAKeyPair(privPath)
+		}
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return createRSAKeyPair(privPath, pubPath, s.cfg.Security.RSAKeySize)
+}
+
+// getRSAKeyPair returns the current RSA key pair, the key pair is created on first use
+// if it is not configured.
+func (s *Server) getRSAKeyPair() (*rsaKeyPair, error) {
+	if keyPair := (*rsaKeyPair)(atomic.LoadPointer(&s.rsaKeyPair)); keyPair != nil {
+		return keyPair, nil
+	}
+	s.rsaKeyMu.Lock()
+	defer s.rsaKeyMu.Unlock()
+	if keyPair := (*rsaKeyPair)(atomic.LoadPointer(&s.rsaKeyPair)); keyPair != nil {
+		return keyPair, nil
+	}
+	keyPair, err := s.initRSAKeyPair(false)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StorePointer(&s.rsaKeyPair, unsafe.Pointer(keyPair))
+	return keyPair, nil
+}
+
+// ReloadRSAKeyPair reloads the RSA key pair used by sha256_password from the configured path,
+// or generates a new one if no path is configured. The old key pair is kept on failure.
+// Connections that have already received the old public key continue to use the old key.
+func (s *Server) ReloadRSAKeyPair() error {
+	s.rsaKeyMu.Lock()
+	defer s.rsaKeyMu.Unlock()
+	keyPair, err := s.initRSAKeyPair(true)
+	if err != nil {
+		logutil.BgLogger().Warn("reload RSA key pair failed, keep using the old one", zap.Error(err))
+		return err
+	}
+	atomic.StorePointer(&s.rsaKeyPair, unsafe.Pointer(keyPair))
+	logutil.BgLogger().Info("RSA key pair for sha256_password reloaded", zap.String("fingerprint", keyPair.fingerprint()))
+	return nil
+}
+
+// rsaPublicKey is the response of the RSA public key http handler.
+type rsaPublicKey struct {
+	Fingerprint string `json:"fingerprint"`
+	PublicKey   string `json:"public_key"`
+}
+
+func (s *Server) handleRSAPublicKey(w http.ResponseWriter, req *http.Request) {
+	keyPair, err := s.getRSAKeyPair()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeData(w, rsaPublicKey{
+		Fingerprint: keyPair.fingerprint(),
+		PublicKey:   string(keyPair.publicKeyPEM),
+	})
+}
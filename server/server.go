@@ -41,6 +41,7 @@ import (
 	_ "net/http/pprof" // #nosec G108
 	"os"
 	"os/user"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -96,11 +97,18 @@ var (
 	errNotAllowedCommand       = dbterror.ClassServer.NewStd(errno.ErrNotAllowedCommand)
 	errAccessDenied            = dbterror.ClassServer.NewStd(errno.ErrAccessDenied)
 	errAccessDeniedNoPassword  = dbterror.ClassServer.NewStd(errno.ErrAccessDeniedNoPassword)
+	errAccountBlocked          = dbterror.ClassServer.NewStd(errno.ErrUserAccessDeniedForUserAccountBlockedByPasswordLock)
+	errAccountLocked           = dbterror.ClassServer.NewStd(errno.ErrAccountHasBeenLocked)
 	errConCount                = dbterror.ClassServer.NewStd(errno.ErrConCount)
 	errSecureTransportRequired = dbterror.ClassServer.NewStd(errno.ErrSecureTransportRequired)
 	errMultiStatementDisabled  = dbterror.ClassServer.NewStd(errno.ErrMultiStatementDisabled)
 	errNewAbortingConnection   = dbterror.ClassServer.NewStd(errno.ErrNewAbortingConnection)
 	errNotSupportedAuthMode    = dbterror.ClassServer.NewStd(errno.ErrNotSupportedAuthMode)
+	errNetPacketTooLarge       = dbterror.ClassServer.NewStd(errno.ErrNetPacketTooLarge)
+	errNetRead                 = dbterror.ClassServer.NewStd(errno.ErrNetRead)
+	errMustChangePassword      = dbterror.ClassServer.NewStd(errno.ErrMustChangePassword)
+	errMustChangePasswordLogin = dbterror.ClassServer.NewStd(errno.ErrMustChangePasswordLogin)
+	errServerShutdown          = dbterror.ClassServer.NewStd(errno.ErrServerShutdown)
 )
 
 // DefaultCapability is the capability of the server when it is created using the default configuration.
@@ -109,12 +117,16 @@ const defaultCapability = mysql.ClientLongPassword | mysql.ClientLongFlag |
 	mysql.ClientConnectWithDB | mysql.ClientProtocol41 |
 	mysql.ClientTransactions | mysql.ClientSecureConnection | mysql.ClientFoundRows |
 	mysql.ClientMultiStatements | mysql.ClientMultiResults | mysql.ClientLocalFiles |
-	mysql.ClientConnectAtts | mysql.ClientPluginAuth | mysql.ClientInteractive
+	mysql.ClientConnectAtts | mysql.ClientPluginAuth | mysql.ClientInteractive |
+	mysql.ClientCanHandleExpiredPasswords | mysql.ClientCompress
 
 // Server is the MySQL protocol server
 type Server struct {
 	cfg               *config.Config
 	tlsConfig         unsafe.Pointer // *tls.Config
+	statusTLSConfig   unsafe.Pointer // *tls.Config
+	rsaKeyPair        unsafe.Pointer // *rsaKeyPair
+	rsaKeyMu          sync.Mutex
 	driver            IDriver
 	listener          net.Listener
 	socket            net.Listener
@@ -124,14 +136,66 @@ type Server struct {
 	capability        uint32
 	dom               *domain.Domain
 	globalConnID      util.GlobalConnID
+	authPlugins       map[string]AuthPlugin
+	userConns         map[string]int // the number of the connections of each account.
+	// userMetrics and otherUserMetrics are the metrics of the accounts, see userMetricsLocked.
+	userMetrics      map[string]*userMetrics
+	otherUserMetrics *userMetrics
 
 	statusAddr     string
 	statusListener net.Listener
 	statusServer   *http.Server
 	grpcServer     *grpc.Server
 	inShutdownMode bool
+	startTime      time.Time
+
+	// draining is set when the server stops accepting new connections before shutdown.
+	draining int32
+	drainMu  sync.Mutex
+	// drainCanceled is closed by CancelDrain to wake up the pending Drain calls.
+	drainCanceled chan struct{}
+	// paused is set by Pause to reject new connections with ER_SERVER_SHUTDOWN until Resume is called.
+	paused int32
+	// debugZipRunning is set while a /debug/zip bundle is being collected.
+	debugZipRunning int32
+
+	// connLimitMu protects acceptedConns and hostConns, which count the accepted connections for
+	// tidb_max_server_connections and tidb_max_connections_per_ip.
+	connLimitMu   sync.Mutex
+	acceptedConns int
+	hostConns     map[string]int
+
+	// queryCache is the result cache of the read-only queries, it is nil if query-cache-size-bytes is 0.
+	queryCache *queryResultCache
+	// auditHooks are registered by AddAuditHook before Run.
+	auditHooks []*auditHookRunner
+	// handshakeErrors are the latest handshake failures shown by /debug/handshake-errors.
+	handshakeErrors *handshakeErrorHistory
+	// statementCounter is nil if quota-plugin.statement-counter-endpoint is empty.
+	statementCounter *statementCounter
 }
 
+// The reasons of rejecting connections by the connection limits.
+const (
+	connLimitMaxServerConnections = "max_server_connections"
+	connLimitMaxConnectionsPerIP  = "max_connections_per_ip"
+)
+
+// The states of the server reported by the status API.
+const (
+	serverStateAccepting = "accepting"
+	serverStateDraining  = "draining"
+	serverStateStopping  = "stopping"
+	serverStatePaused    = "paused"
+)
+
+var (
+	errDrainCanceled = errors.New("draining is canceled")
+	errPaused        = errors.New("the server is already paused")
+	errNotPaused     = errors.New("the server is not paused")
+	errStopping      = errors.New("the server is stopping")
+)
+
 // ConnectionCount gets current connection count.
 func (s *Server) ConnectionCount() int {
 	s.rwlock.RLock()
@@ -157,6 +221,9 @@ func (s *Server) releaseToken(token *Token) {
 // SetDomain use to set the server domain.
 func (s *Server) SetDomain(dom *domain.Domain) {
 	s.dom = dom
+	if s.queryCache != nil {
+		dom.AddInvalidationListener(s.queryCache.clear)
+	}
 }
 
 // InitGlobalConnID initialize global connection id.
@@ -180,29 +247,45 @@ func (s *Server) newConn(conn net.Conn) *clientConn {
 		}
 	}
 	cc.setConn(conn)
+	cc.pkt.writeBuffer = connWriteBuffers.register(cc.connectionID)
 	cc.salt = fastrand.Buf(20)
 	return cc
 }
 
 // NewServer creates a new Server.
 func NewServer(cfg *config.Config, driver IDriver) (*Server, error) {
+	if cfg.Performance.MaxChunkSize < config.MaxChunkSizeLowerBound || cfg.Performance.MaxChunkSize > config.MaxChunkSizeUpperBound {
+		return nil, errors.Errorf("invalid max-chunk-size %d, it should be in the range [%d, %d]",
+			cfg.Performance.MaxChunkSize, config.MaxChunkSizeLowerBound, config.MaxChunkSizeUpperBound)
+	}
 	s := &Server{
 		cfg:               cfg,
 		driver:            driver,
 		concurrentLimiter: NewTokenLimiter(cfg.TokenLimit),
 		clients:           make(map[uint64]*clientConn),
+		hostConns:         make(map[string]int),
 		globalConnID:      util.GlobalConnID{ServerID: 0, Is64bits: true},
+		startTime:         time.Now(),
+		handshakeErrors:   newHandshakeErrorHistory(handshakeErrorHistorySize),
+	}
+	if cfg.Performance.QueryCacheSizeBytes > 0 {
+		s.queryCache = newQueryResultCache(int64(cfg.Performance.QueryCacheSizeBytes))
+	}
+	if cfg.QuotaPlugin.StatementCounterEndpoint != "" {
+		s.statementCounter = newStatementCounter(cfg.QuotaPlugin.StatementCounterEndpoint)
 	}
 	s.capability = defaultCapability
 	setTxnScope()
 	setSystemTimeZoneVariable()
+	registerConnGauge(s)
+	connWriteBuffers.setLimit(int64(float64(cfg.Performance.ServerMemoryQuota) * cfg.Performance.ServerWriteBufferQuotaRatio))
 
 	tlsConfig, autoReload, err := util.LoadTLSCertificates(
 		s.cfg.Security.SSLCA, s.cfg.Security.SSLKey, s.cfg.Security.SSLCert,
 		s.cfg.Security.AutoTLS, s.cfg.Security.RSAKeySize)
 
 	// Automatically reload auto-generated certificates.
-	// The certificates are re-created every 30 days and are valid for 90 days.
+	// The certificates are checked every 30 days and re-created when they are about to expire.
 	if autoReload {
 		go func() {
 			for range time.Tick(time.Hour * 24 * 30) { // 30 days
@@ -226,7 +309,8 @@ func NewServer(cfg *config.Config, driver IDriver) (*Server, error) {
 		atomic.StorePointer(&s.tlsConfig, unsafe.Pointer(tlsConfig))
 		logutil.BgLogger().Info("mysql protocol server secure connection is enabled",
 			zap.Bool("client verification enabled", len(variable.GetSysVar("ssl_ca").Value) > 0))
-	} else if cfg.Security.RequireSecureTransport {
+	} else if cfg.Security.RequireSecureTransport && cfg.Socket == "" {
+		// The unix socket is the only secure transport without TLS.
 		return nil, errSecureTransportRequired.FastGenByArgs()
 	}
 
@@ -234,14 +318,21 @@ func NewServer(cfg *config.Config, driver IDriver) (*Server, error) {
 		s.capability |= mysql.ClientSSL
 	}
 
+	if s.cfg.Security.SHA256PasswordPrivateKeyPath != "" {
+		if _, err = s.getRSAKeyPair(); err != nil {
+			logutil.BgLogger().Error("load RSA key pair for sha256_password failed", zap.Error(err))
+			return nil, errors.Trace(err)
+		}
+	}
+
 	if s.cfg.Host != "" && (s.cfg.Port != 0 || runInGoTest) {
-		addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+		addr := net.JoinHostPort(s.cfg.Host, strconv.FormatUint(uint64(s.cfg.Port), 10))
 		tcpProto := "tcp"
 		if s.cfg.EnableTCP4Only {
 			tcpProto = "tcp4"
 		}
 		if s.listener, err = net.Listen(tcpProto, addr); err != nil {
-			return nil, errors.Trace(err)
+			return nil, errors.Trace(newListenerError(addr, err))
 		}
 		logutil.BgLogger().Info("server is running MySQL protocol", zap.String("addr", addr))
 		if runInGoTest && s.cfg.Port == 0 {
@@ -255,7 +346,16 @@ func NewServer(cfg *config.Config, driver IDriver) (*Server, error) {
 		}
 
 		if s.socket, err = net.Listen("unix", s.cfg.Socket); err != nil {
-			return nil, errors.Trace(err)
+			return nil, errors.Trace(newListenerError(s.cfg.Socket, err))
+		}
+		if s.cfg.SocketPermissions != "" {
+			mode, err := config.ParseSocketPermissions(s.cfg.SocketPermissions)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err = os.Chmod(s.cfg.Socket, mode); err != nil {
+				return nil, errors.Trace(err)
+			}
 		}
 		logutil.BgLogger().Info("server is running MySQL protocol", zap.String("socket", s.cfg.Socket))
 	}
@@ -351,7 +451,7 @@ func (s *Server) reportConfig() {
 	metrics.ConfigStatus.WithLabelValues("max-server-connections").Set(float64(s.cfg.MaxServerConnections))
 }
 
-// Run runs the server.
+// Run runs the server. The errors of accepting the connections are returned as *ListenerError.
 func (s *Server) Run() error {
 	metrics.ServerEventCounter.WithLabelValues(metrics.EventStart).Inc()
 	s.reportConfig()
@@ -385,7 +485,7 @@ func (s *Server) startNetworkListener(listener net.Listener, isUnixSocket bool,
 					if s.inShutdownMode {
 						errChan <- nil
 					} else {
-						errChan <- err
+						errChan <- newListenerError(listener.Addr().String(), err)
 					}
 					return
 				}
@@ -394,15 +494,29 @@ func (s *Server) startNetworkListener(listener net.Listener, isUnixSocket bool,
 			// If we got PROXY protocol error, we should continue to accept.
 			if proxyprotocol.IsProxyProtocolError(err) {
 				logutil.BgLogger().Error("PROXY protocol failed", zap.Error(err))
+				s.recordHandshakeFailure(handshakeFailureProxyProtocol, "", err)
 				continue
 			}
 
 			logutil.BgLogger().Error("accept failed", zap.Error(err))
-			errChan <- err
+			s.recordHandshakeFailure(handshakeFailureTCPAccept, "", err)
+			errChan <- newListenerError(listener.Addr().String(), err)
 			return
 		}
 
+		// The listener is kept open while draining so that the draining can be canceled.
+		if s.isDraining() {
+			logutil.BgLogger().Info("reject connection because the server is draining", zap.Stringer("remoteAddr", conn.RemoteAddr()))
+			terror.Log(conn.Close())
+			continue
+		}
+
 		clientConn := s.newConn(conn)
+		if s.isPaused() {
+			logutil.BgLogger().Info("reject connection because the server is paused", zap.Stringer("remoteAddr", conn.RemoteAddr()))
+			s.rejectConn(clientConn, errServerShutdown)
+			continue
+		}
 		if isUnixSocket {
 			uc, ok := conn.(*net.UnixConn)
 			if !ok {
@@ -448,6 +562,23 @@ func (s *Server) startNetworkListener(listener net.Listener, isUnixSocket bool,
 			continue
 		}
 
+		// Check the limits at last, so that the connections rejected for other reasons are not counted.
+		host := ""
+		if !isUnixSocket {
+			host, _, err = net.SplitHostPort(conn.RemoteAddr().String())
+			if err != nil {
+				host = conn.RemoteAddr().String()
+			}
+		}
+		if reason := s.acquireConnLimit(host); reason != "" {
+			logutil.BgLogger().Warn("reject connection because of the connection limit",
+				zap.String("reason", reason), zap.Stringer("remoteAddr", conn.RemoteAddr()))
+			metrics.ConnectionLimitRejectCounter.WithLabelValues(reason, ipPrefix(host)).Inc()
+			s.rejectConn(clientConn, errConCount)
+			continue
+		}
+		clientConn.limitHost = host
+
 		go s.onConn(clientConn)
 	}
 }
@@ -491,11 +622,16 @@ func (s *Server) Close() {
 		s.grpcServer.Stop()
 		s.grpcServer = nil
 	}
+	s.closeAuditHooks()
+	if s.statementCounter != nil {
+		s.statementCounter.close()
+	}
 	metrics.ServerEventCounter.WithLabelValues(metrics.EventClose).Inc()
 }
 
 // onConn runs in its own goroutine, handles queries from this connection.
 func (s *Server) onConn(conn *clientConn) {
+	defer s.releaseConnLimit(conn.limitHost)
 	ctx := logutil.WithConnID(context.Background(), conn.connectionID)
 	if err := conn.handshake(ctx); err != nil {
 		if plugin.IsEnable(plugin.Audit) && conn.ctx != nil {
@@ -510,9 +646,13 @@ func (s *Server) onConn(conn *clientConn) {
 			})
 			terror.Log(err)
 		}
+		if errAccessDenied.Equal(err) || errAccessDeniedNoPassword.Equal(err) || errMustChangePasswordLogin.Equal(err) {
+			conn.auditConnection(ConnectionEventAuthFailure, err)
+		}
 		// Some keep alive services will send request to TiDB and disconnect immediately.
 		// So we only record metrics.
 		metrics.HandShakeErrorCounter.Inc()
+		s.recordHandshakeFailure(conn.handshakeFailureReason(err), conn.remoteIP(), err)
 		terror.Log(errors.Trace(err))
 		terror.Log(errors.Trace(conn.Close()))
 		return
@@ -525,9 +665,7 @@ func (s *Server) onConn(conn *clientConn) {
 	}()
 	s.rwlock.Lock()
 	s.clients[conn.connectionID] = conn
-	connections := len(s.clients)
 	s.rwlock.Unlock()
-	metrics.ConnGauge.Set(float64(connections))
 
 	sessionVars := conn.ctx.GetSessionVars()
 	if plugin.IsEnable(plugin.Audit) {
@@ -544,8 +682,10 @@ func (s *Server) onConn(conn *clientConn) {
 		return
 	}
 
+	conn.auditConnection(ConnectionEventConnect, nil)
 	connectedTime := time.Now()
 	conn.Run(ctx)
+	conn.auditConnection(ConnectionEventDisconnect, nil)
 
 	err = plugin.ForeachPlugin(plugin.Audit, func(p *plugin.Plugin) error {
 		// Audit plugin may be disabled before a conn is created, leading no connectionInfo in sessionVars.
@@ -593,22 +733,57 @@ func (cc *clientConn) connectInfo() *variable.ConnectionInfo {
 	return connInfo
 }
 
-func (s *Server) checkConnectionCount() error {
-	// When the value of MaxServerConnections is 0, the number of connections is unlimited.
-	if int(s.cfg.MaxServerConnections) == 0 {
-		return nil
+// acquireConnLimit counts an accepted connection from host, which is empty for the unix socket, against
+// tidb_max_server_connections and tidb_max_connections_per_ip. It returns the reason if the connection
+// should be rejected, otherwise the connection must be released by releaseConnLimit once it is closed.
+func (s *Server) acquireConnLimit(host string) string {
+	cfg := config.GetGlobalConfig()
+	s.connLimitMu.Lock()
+	defer s.connLimitMu.Unlock()
+	// When the limit is 0, the number of connections is unlimited.
+	if cfg.MaxServerConnections > 0 && s.acceptedConns >= int(cfg.MaxServerConnections) {
+		return connLimitMaxServerConnections
+	}
+	if host != "" && cfg.MaxConnectionsPerIP > 0 && s.hostConns[host] >= int(cfg.MaxConnectionsPerIP) {
+		return connLimitMaxConnectionsPerIP
 	}
+	s.acceptedConns++
+	if host != "" {
+		s.hostConns[host]++
+	}
+	return ""
+}
 
-	s.rwlock.RLock()
-	conns := len(s.clients)
-	s.rwlock.RUnlock()
+func (s *Server) releaseConnLimit(host string) {
+	s.connLimitMu.Lock()
+	defer s.connLimitMu.Unlock()
+	s.acceptedConns--
+	if host == "" {
+		return
+	}
+	if s.hostConns[host] <= 1 {
+		delete(s.hostConns, host)
+	} else {
+		s.hostConns[host]--
+	}
+}
 
-	if conns >= int(s.cfg.MaxServerConnections) {
-		logutil.BgLogger().Error("too many connections",
-			zap.Uint32("max connections", s.cfg.MaxServerConnections), zap.Error(errConCount))
-		return errConCount
+// ipPrefix returns the /24 network of an IPv4 address or the /64 network of an IPv6 address,
+// which keeps the cardinality of the metric labels low.
+func ipPrefix(host string) string {
+	if host == "" {
+		return "socket"
 	}
-	return nil
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "unknown"
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		network := net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+		return network.String()
+	}
+	network := net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}
+	return network.String()
 }
 
 // ShowProcessList implements the SessionManager interface.
@@ -683,6 +858,40 @@ func (s *Server) getTLSConfig() *tls.Config {
 	return (*tls.Config)(atomic.LoadPointer(&s.tlsConfig))
 }
 
+// ReloadTLS re-reads the certificates of the MySQL protocol server and the status server from the
+// configured files, so that the rotated certificates are used without restarting the server. The new
+// handshakes use the new certificates, the established connections are not affected. If any of the
+// files are invalid, an error is returned and the old certificates are kept.
+func (s *Server) ReloadTLS() error {
+	var tlsConfig, statusTLSConfig *tls.Config
+	if s.getTLSConfig() != nil {
+		var err error
+		tlsConfig, _, err = util.LoadTLSCertificates(
+			s.cfg.Security.SSLCA, s.cfg.Security.SSLKey, s.cfg.Security.SSLCert,
+			s.cfg.Security.AutoTLS, s.cfg.Security.RSAKeySize)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if s.getStatusTLSConfig() != nil {
+		var err error
+		statusTLSConfig, err = s.loadStatusTLSConfig()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	// Swap the configs only after all of them are loaded successfully.
+	if tlsConfig != nil {
+		s.UpdateTLSConfig(tlsConfig)
+	}
+	if statusTLSConfig != nil {
+		atomic.StorePointer(&s.statusTLSConfig, unsafe.Pointer(statusTLSConfig))
+	}
+	logutil.BgLogger().Info("TLS certificates reloaded",
+		zap.Bool("mysql", tlsConfig != nil), zap.Bool("status", statusTLSConfig != nil))
+	return nil
+}
+
 func killConn(conn *clientConn) {
 	sessVars := conn.ctx.GetSessionVars()
 	atomic.StoreUint32(&sessVars.Killed, 1)
@@ -754,6 +963,120 @@ func (s *Server) GracefulDown(ctx context.Context, done chan struct{}) {
 	close(done)
 }
 
+// State returns whether the server is accepting connections, draining or stopping.
+func (s *Server) State() string {
+	if s.inShutdownMode {
+		return serverStateStopping
+	}
+	if s.isDraining() {
+		return serverStateDraining
+	}
+	if s.isPaused() {
+		return serverStatePaused
+	}
+	return serverStateAccepting
+}
+
+func (s *Server) isDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// Drain stops accepting new connections and waits until all the existing connections are idle,
+// which means they are neither executing a statement nor in a transaction. The existing connections
+// keep being served. It returns an error if ctx is done or the draining is canceled by CancelDrain.
+func (s *Server) Drain(ctx context.Context) error {
+	s.drainMu.Lock()
+	if s.drainCanceled == nil {
+		logutil.BgLogger().Info("[server] start draining, new connections will be rejected")
+		metrics.ServerEventCounter.WithLabelValues(metrics.EventDrain).Inc()
+		s.drainCanceled = make(chan struct{})
+		atomic.StoreInt32(&s.draining, 1)
+	}
+	canceled := s.drainCanceled
+	s.drainMu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		count := s.busyConnectionCount()
+		if count == 0 {
+			logutil.BgLogger().Info("[server] all connections are idle, draining is done")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			logutil.BgLogger().Warn("[server] draining timeout", zap.Int("busy conn count", count))
+			return ctx.Err()
+		case <-canceled:
+			return errDrainCanceled
+		case <-ticker.C:
+		}
+	}
+}
+
+// CancelDrain makes the server accept new connections again. It returns false if the server is not draining.
+func (s *Server) CancelDrain() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	if s.drainCanceled == nil {
+		return false
+	}
+	logutil.BgLogger().Info("[server] draining is canceled, accept new connections again")
+	close(s.drainCanceled)
+	s.drainCanceled = nil
+	atomic.StoreInt32(&s.draining, 0)
+	return true
+}
+
+func (s *Server) isPaused() bool {
+	return atomic.LoadInt32(&s.paused) == 1
+}
+
+// Pause makes the server reject new connections with ER_SERVER_SHUTDOWN, while the existing
+// connections keep being served. It is used to move clients to other servers during rolling upgrades.
+func (s *Server) Pause() error {
+	if s.inShutdownMode {
+		return errStopping
+	}
+	if !atomic.CompareAndSwapInt32(&s.paused, 0, 1) {
+		return errPaused
+	}
+	logutil.BgLogger().Info("[server] paused, new connections will be rejected")
+	metrics.ServerEventCounter.WithLabelValues(metrics.EventPause).Inc()
+	return nil
+}
+
+// Resume makes the paused server accept new connections again.
+func (s *Server) Resume() error {
+	if !atomic.CompareAndSwapInt32(&s.paused, 1, 0) {
+		return errNotPaused
+	}
+	logutil.BgLogger().Info("[server] resumed, accept new connections again")
+	metrics.ServerEventCounter.WithLabelValues(metrics.EventResume).Inc()
+	return nil
+}
+
+// rejectConn sends err to the client in place of the initial handshake and closes the connection.
+func (s *Server) rejectConn(cc *clientConn, err error) {
+	if err := cc.writeError(context.Background(), err); err == nil {
+		terror.Log(cc.flush(context.Background()))
+	}
+	terror.Log(closeConn(cc))
+}
+
+// busyConnectionCount returns the number of connections that are executing a statement or in a transaction.
+func (s *Server) busyConnectionCount() int {
+	count := 0
+	s.rwlock.RLock()
+	for _, cc := range s.clients {
+		if atomic.LoadInt32(&cc.status) != connStatusReading || (cc.ctx.Status()&mysql.ServerStatusInTrans) > 0 {
+			count++
+		}
+	}
+	s.rwlock.RUnlock()
+	return count
+}
+
 func (s *Server) kickIdleConnection() {
 	var conns []*clientConn
 	s.rwlock.RLock()
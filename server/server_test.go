@@ -35,6 +35,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/errno"
 	"github.com/pingcap/tidb/kv"
 	tmysql "github.com/pingcap/tidb/parser/mysql"
@@ -1587,6 +1588,35 @@ func (cli *testServerClient) runTestAuth(t *testing.T) {
 	}, func(dbt *testkit.DBTestKit) {
 		dbt.MustExec(`USE information_schema;`)
 	})
+
+	// Test the account is locked after consecutive failed logins.
+	cli.runTests(t, nil, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec(`CREATE USER 'authlock'@'%' IDENTIFIED BY '123' FAILED_LOGIN_ATTEMPTS 1 PASSWORD_LOCK_TIME 1;`)
+		dbt.MustExec(`GRANT ALL on test.* to 'authlock'@'%'`)
+	})
+	for _, passwd := range []string{"456", "123"} {
+		db, err = sql.Open("mysql", cli.getDSN(func(config *mysql.Config) {
+			config.User = "authlock"
+			config.Passwd = passwd
+		}))
+		require.NoError(t, err)
+		err = db.Ping()
+		require.Error(t, err)
+		require.NoError(t, db.Close())
+	}
+	mErr, ok := err.(*mysql.MySQLError)
+	require.True(t, ok)
+	require.Equal(t, uint16(errno.ErrUserAccessDeniedForUserAccountBlockedByPasswordLock), mErr.Number)
+	require.Contains(t, mErr.Message, "Account is blocked for 1 day(s) (1 day(s) remaining) due to 1 consecutive failed logins.")
+	cli.runTests(t, nil, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec(`FLUSH PRIVILEGES;`)
+	})
+	cli.runTests(t, func(config *mysql.Config) {
+		config.User = "authlock"
+		config.Passwd = "123"
+	}, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec(`USE information_schema;`)
+	})
 }
 
 func (cli *testServerClient) runTestIssue3662(t *testing.T) {
@@ -1694,6 +1724,21 @@ func (cli *testServerClient) runTestStatusAPI(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, tmysql.ServerVersion, data.Version)
 	require.Equal(t, versioninfo.TiDBGitHash, data.GitHash)
+	require.Equal(t, versioninfo.TiDBBuildTS, data.BuildTime)
+	require.False(t, data.StartTime.IsZero())
+	require.GreaterOrEqual(t, data.Uptime, int64(0))
+	require.Equal(t, config.GetGlobalConfig().MaxServerConnections, data.MaxConnections)
+	require.Nil(t, data.Config)
+
+	// The config is returned only if verbose is true.
+	resp, err = cli.fetchStatus("/status?verbose=true")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var verbose status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&verbose))
+	var conf map[string]interface{}
+	require.NoError(t, json.Unmarshal(verbose.Config, &conf))
+	require.Contains(t, conf, "port")
 }
 
 // The golang sql driver (and most drivers) should have multi-statement
@@ -19,17 +19,22 @@ import (
 
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/timeutil"
 	"go.uber.org/zap"
 )
 
 var (
 	serverNotAfter  = "Ssl_server_not_after"
 	serverNotBefore = "Ssl_server_not_before"
+	// systemTZDetected is the system time zone of the host, it may differ from @@system_time_zone,
+	// which is the one stored in mysql.tidb when the cluster is bootstrapped.
+	systemTZDetected = "System_time_zone_detected"
 )
 
 var defaultStatus = map[string]*variable.StatusVal{
-	serverNotAfter:  {Scope: variable.ScopeGlobal | variable.ScopeSession, Value: ""},
-	serverNotBefore: {Scope: variable.ScopeGlobal | variable.ScopeSession, Value: ""},
+	serverNotAfter:   {Scope: variable.ScopeGlobal | variable.ScopeSession, Value: ""},
+	serverNotBefore:  {Scope: variable.ScopeGlobal | variable.ScopeSession, Value: ""},
+	systemTZDetected: {Scope: variable.ScopeGlobal | variable.ScopeSession, Value: ""},
 }
 
 // GetScope gets the status variables scope.
@@ -44,6 +49,7 @@ func (s *Server) Stats(vars *variable.SessionVars) (map[string]interface{}, erro
 	for name, v := range defaultStatus {
 		m[name] = v.Value
 	}
+	m[systemTZDetected] = timeutil.InferSystemTZ()
 
 	tlsConfig := s.getTLSConfig()
 	if tlsConfig != nil {
@@ -0,0 +1,155 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/terror"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+var (
+	// statementCounterInterval is the interval between two reports to the statement counter endpoint.
+	statementCounterInterval = time.Second
+	// statementCounterTimeout is the timeout of posting a count to the statement counter endpoint.
+	statementCounterTimeout = 5 * time.Second
+)
+
+// statementCount is the payload posted to the statement counter endpoint.
+type statementCount struct {
+	User  string `json:"user"`
+	Count uint64 `json:"count"`
+}
+
+// statementCounter meters the statements executed by each user for QuotaPlugin.StatementCounterEndpoint.
+// The counts are accumulated locally and reported by a background goroutine every statementCounterInterval,
+// so there is at most one report in flight. The counts failed to be reported are added back and reported
+// with the next ones.
+type statementCounter struct {
+	endpoint string
+	client   *http.Client
+
+	mu     sync.Mutex
+	counts map[string]uint64
+
+	exit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newStatementCounter(endpoint string) *statementCounter {
+	c := &statementCounter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: statementCounterTimeout},
+		counts:   make(map[string]uint64),
+		exit:     make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.run()
+	return c
+}
+
+func (c *statementCounter) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(statementCounterInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.exit:
+			c.flush()
+			return
+		}
+	}
+}
+
+// add counts a statement executed by the user.
+func (c *statementCounter) add(user string) {
+	c.mu.Lock()
+	c.counts[user]++
+	c.mu.Unlock()
+}
+
+// flush reports the counts accumulated since the last flush, and adds back the ones failed to be reported.
+func (c *statementCounter) flush() {
+	c.mu.Lock()
+	counts := c.counts
+	c.counts = make(map[string]uint64, len(counts))
+	c.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	failed := c.report(counts)
+	if len(failed) == 0 {
+		return
+	}
+	c.mu.Lock()
+	for user, count := range failed {
+		c.counts[user] += count
+	}
+	c.mu.Unlock()
+}
+
+// report posts the count of each user, and returns the counts failed to be reported.
+func (c *statementCounter) report(counts map[string]uint64) (failed map[string]uint64) {
+	for user, count := range counts {
+		data, err := json.Marshal(&statementCount{User: user, Count: count})
+		if err != nil {
+			logutil.BgLogger().Warn("marshal statement count failed", zap.Error(err))
+			continue
+		}
+		resp, err := c.client.Post(c.endpoint, "application/json", bytes.NewReader(data))
+		if err == nil {
+			terror.Log(resp.Body.Close())
+			if resp.StatusCode/100 != 2 {
+				err = errors.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			logutil.BgLogger().Warn("report statement count failed", zap.String("user", user),
+				zap.Uint64("count", count), zap.Error(err))
+			if failed == nil {
+				failed = make(map[string]uint64)
+			}
+			failed[user] = count
+		}
+	}
+	return failed
+}
+
+// close stops the background goroutine after it reports the counts not reported yet. It can be called
+// more than once.
+func (c *statementCounter) close() {
+	c.closeOnce.Do(func() {
+		close(c.exit)
+	})
+	c.wg.Wait()
+}
+
+// countStatement meters the statement executed by the connection if the statement counter is enabled.
+func (cc *clientConn) countStatement() {
+	if cc.server == nil || cc.server.statementCounter == nil {
+		return
+	}
+	cc.server.statementCounter.add(cc.user)
+}
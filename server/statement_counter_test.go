@@ -0,0 +1,193 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/stretchr/testify/require"
+)
+
+// statementCounterEndpoint captures the counts posted to it, it fails the posts while failing is set.
+type statementCounterEndpoint struct {
+	sync.Mutex
+	*httptest.Server
+	counts  []statementCount
+	failing bool
+}
+
+func newStatementCounterEndpoint(t *testing.T) *statementCounterEndpoint {
+	e := &statementCounterEndpoint{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var count statementCount
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&count))
+		e.Lock()
+		defer e.Unlock()
+		if e.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		e.counts = append(e.counts, count)
+	}))
+	return e
+}
+
+func (e *statementCounterEndpoint) setFailing(failing bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.failing = failing
+}
+
+// posted returns the number of the posts and the total counts of each user.
+func (e *statementCounterEndpoint) posted() (int, map[string]uint64) {
+	e.Lock()
+	defer e.Unlock()
+	total := make(map[string]uint64)
+	for _, c := range e.counts {
+		total[c.User] += c.Count
+	}
+	return len(e.counts), total
+}
+
+func setStatementCounterInterval(interval time.Duration) func() {
+	old := statementCounterInterval
+	statementCounterInterval = interval
+	return func() {
+		statementCounterInterval = old
+	}
+}
+
+func TestStatementCounter(t *testing.T) {
+	endpoint := newStatementCounterEndpoint(t)
+	defer endpoint.Close()
+	defer setStatementCounterInterval(time.Hour)()
+
+	c := newStatementCounter(endpoint.URL)
+	// The counts are batched until the next flush, which reports the count of each user in one post.
+	for i := 0; i < 10; i++ {
+		c.add("u1")
+		c.add("u2")
+	}
+	n, _ := endpoint.posted()
+	require.Equal(t, 0, n)
+	c.flush()
+	n, total := endpoint.posted()
+	require.Equal(t, 2, n)
+	require.Equal(t, map[string]uint64{"u1": 10, "u2": 10}, total)
+
+	// The counts failed to be reported are reported by the next flush.
+	endpoint.setFailing(true)
+	c.add("u1")
+	c.flush()
+	c.add("u1")
+	c.add("u3")
+	c.flush()
+	n, _ = endpoint.posted()
+	require.Equal(t, 2, n)
+	endpoint.setFailing(false)
+	c.flush()
+	n, total = endpoint.posted()
+	require.Equal(t, 4, n)
+	require.Equal(t, map[string]uint64{"u1": 12, "u2": 10, "u3": 1}, total)
+
+	// The counts not reported yet are reported by close.
+	c.add("u3")
+	c.close()
+	n, total = endpoint.posted()
+	require.Equal(t, 5, n)
+	require.Equal(t, map[string]uint64{"u1": 12, "u2": 10, "u3": 2}, total)
+}
+
+func TestStatementCounterTicker(t *testing.T) {
+	endpoint := newStatementCounterEndpoint(t)
+	defer endpoint.Close()
+	defer setStatementCounterInterval(10 * time.Millisecond)()
+
+	// The counts are reported by the background goroutine.
+	c := newStatementCounter(endpoint.URL)
+	defer c.close()
+	c.add("u1")
+	require.Eventually(t, func() bool {
+		n, _ := endpoint.posted()
+		return n == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStatementCounterEndpointFailure(t *testing.T) {
+	defer setStatementCounterInterval(time.Hour)()
+
+	// The failures are logged without blocking the statements.
+	c := newStatementCounter("http://127.0.0.1:0")
+	c.add("u1")
+	c.flush()
+	c.mu.Lock()
+	require.Equal(t, map[string]uint64{"u1": 1}, c.counts)
+	c.mu.Unlock()
+	c.close()
+}
+
+func TestStatementCounterConn(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+	endpoint := newStatementCounterEndpoint(t)
+	defer endpoint.Close()
+	defer setStatementCounterInterval(time.Hour)()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.QuotaPlugin.StatementCounterEndpoint = endpoint.URL
+	srv, err := NewServer(cfg, NewTiDBDriver(store))
+	require.NoError(t, err)
+	require.NotNil(t, srv.statementCounter)
+	ctx := context.Background()
+
+	cc := &clientConn{
+		connectionID: 1,
+		alloc:        arena.NewAllocator(1024),
+		chunkAlloc:   chunk.NewAllocator(),
+		collation:    mysql.DefaultCollationID,
+		peerHost:     "localhost",
+		pkt:          &packetIO{bufWriter: bufio.NewWriter(bytes.NewBuffer(nil))},
+		server:       srv,
+		user:         "root",
+		capability:   defaultCapability,
+	}
+	require.NoError(t, cc.openSessionAndDoAuth(nil, mysql.AuthNativePassword))
+
+	// The failed statements are counted as well.
+	require.NoError(t, cc.handleQuery(ctx, "use test"))
+	require.NoError(t, cc.handleQuery(ctx, "create table t (a int primary key)"))
+	require.NoError(t, cc.handleQuery(ctx, "insert into t values (1)"))
+	require.Error(t, cc.handleQuery(ctx, "insert into t values (1)"))
+	srv.statementCounter.close()
+	n, total := endpoint.posted()
+	require.Equal(t, 1, n)
+	require.Equal(t, map[string]uint64{"root": 4}, total)
+}
@@ -15,6 +15,7 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -68,10 +69,11 @@ func (sh StatsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 // StatsHistoryHandler is the handler for dumping statistics.
 type StatsHistoryHandler struct {
-	do *domain.Domain
+	do        *domain.Domain
+	statusSQL *statusSQLLane
 }
 
-func (s *Server) newStatsHistoryHandler() *StatsHistoryHandler {
+func (s *Server) newStatsHistoryHandler(statusSQL *statusSQLLane) *StatsHistoryHandler {
 	store, ok := s.driver.(*TiDBDriver)
 	if !ok {
 		panic("Illegal driver")
@@ -81,35 +83,29 @@ func (s *Server) newStatsHistoryHandler() *StatsHistoryHandler {
 	if err != nil {
 		panic("Failed to get domain")
 	}
-	return &StatsHistoryHandler{do}
+	return &StatsHistoryHandler{do, statusSQL}
 }
 
 func (sh StatsHistoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	params := mux.Vars(req)
-	se, err := session.CreateSession(sh.do.Store())
-	if err != nil {
-		writeError(w, err)
-		return
-	}
-	defer se.Close()
-
-	se.GetSessionVars().StmtCtx.TimeZone = time.Local
-	t, err := types.ParseTime(se.GetSessionVars().StmtCtx, params[pSnapshot], mysql.TypeTimestamp, 6)
-	if err != nil {
-		writeError(w, err)
-		return
-	}
-	t1, err := t.GoTime(time.Local)
-	if err != nil {
-		writeError(w, err)
-		return
-	}
-	snapshot := oracle.GoTimeToTS(t1)
-	err = gcutil.ValidateSnapshot(se, snapshot)
+	var snapshot uint64
+	err := sh.statusSQL.withSession(func(_ context.Context, se session.Session) error {
+		se.GetSessionVars().StmtCtx.TimeZone = time.Local
+		t, err := types.ParseTime(se.GetSessionVars().StmtCtx, params[pSnapshot], mysql.TypeTimestamp, 6)
+		if err != nil {
+			return err
+		}
+		t1, err := t.GoTime(time.Local)
+		if err != nil {
+			return err
+		}
+		snapshot = oracle.GoTimeToTS(t1)
+		return gcutil.ValidateSnapshot(se, snapshot)
+	})
 	if err != nil {
-		writeError(w, err)
+		writeStatusSQLError(w, err)
 		return
 	}
 
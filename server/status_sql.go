@@ -0,0 +1,198 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser/terror"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// statusSQLComment is prepended to the internal SQL run by the status server, so that it can be
+// recognized in the process list and logs. The SQL is executed as restricted SQL, which is excluded
+// from the statement summary and reported as internal to TopSQL by default.
+const statusSQLComment = "/* status-port */ "
+
+var (
+	// statusSQLPoolSize is the max number of internal SQL the status server runs at the same time.
+	statusSQLPoolSize = 4
+	// statusSQLTimeout is the timeout of the internal SQL run by the status server.
+	statusSQLTimeout = 30 * time.Second
+	// statusSQLBreakerThreshold is the number of consecutive timeouts that opens the circuit breaker.
+	statusSQLBreakerThreshold = 3
+	// statusSQLBreakerCooldown is how long the circuit breaker stays open.
+	statusSQLBreakerCooldown = 10 * time.Second
+)
+
+var (
+	errStatusSQLBusy        = errors.New("too many internal SQL running on the status server, please retry later")
+	errStatusSQLCircuitOpen = errors.New("internal SQL on the status server keeps timing out, please retry later")
+)
+
+// statusSQLLane runs the internal SQL of status handlers with its own bounded session pool,
+// so that the handlers fail fast instead of piling up behind work owned by the same instance.
+type statusSQLLane struct {
+	store  kv.Storage
+	tokens chan struct{}
+	idle   chan session.Session
+
+	mu        sync.Mutex
+	timeouts  int
+	openUntil time.Time
+}
+
+func (s *Server) newStatusSQLLane() *statusSQLLane {
+	store, ok := s.driver.(*TiDBDriver)
+	if !ok {
+		panic("Illegal driver")
+	}
+	return newStatusSQLLane(store.store, statusSQLPoolSize)
+}
+
+func newStatusSQLLane(store kv.Storage, size int) *statusSQLLane {
+	return &statusSQLLane{
+		store:  store,
+		tokens: make(chan struct{}, size),
+		idle:   make(chan session.Session, size),
+	}
+}
+
+// withSession runs fn with a pooled session. It never waits for a free session: it returns
+// errStatusSQLBusy when the pool is exhausted and errStatusSQLCircuitOpen when the breaker is open.
+// The context passed to fn is canceled after statusSQLTimeout.
+func (l *statusSQLLane) withSession(fn func(ctx context.Context, se session.Session) error) error {
+	if l.isOpen() {
+		metrics.StatusSQLRejectCounter.WithLabelValues("circuit_open").Inc()
+		return errStatusSQLCircuitOpen
+	}
+	select {
+	case l.tokens <- struct{}{}:
+	default:
+		metrics.StatusSQLRejectCounter.WithLabelValues("busy").Inc()
+		return errStatusSQLBusy
+	}
+	defer func() { <-l.tokens }()
+
+	se, err := l.get()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusSQLTimeout)
+	defer cancel()
+	err = fn(ctx, se)
+	timeout := ctx.Err() == context.DeadlineExceeded
+	l.record(timeout)
+	if timeout {
+		// The session may still be held by the timed out statement, don't reuse it.
+		se.Close()
+		if err == nil {
+			err = errors.Trace(ctx.Err())
+		}
+		return err
+	}
+	if err != nil {
+		// The failed statement may leave the session in any state, don't reuse it.
+		se.Close()
+		return err
+	}
+	l.reset(ctx, se)
+	l.put(se)
+	return nil
+}
+
+func (l *statusSQLLane) get() (session.Session, error) {
+	select {
+	case se := <-l.idle:
+		return se, nil
+	default:
+	}
+	se, err := session.CreateSession(l.store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	se.GetSessionVars().MaxExecutionTime = uint64(statusSQLTimeout.Milliseconds())
+	return se, nil
+}
+
+// reset clears the state left by the previous handler, so that it doesn't leak into the next one
+// that picks the session from the pool.
+func (l *statusSQLLane) reset(ctx context.Context, se session.Session) {
+	vars := se.GetSessionVars()
+	if vars.InTxn() {
+		se.RollbackTxn(ctx)
+	}
+	vars.CurrentDB = ""
+	vars.SnapshotTS = 0
+	vars.SnapshotInfoschema = nil
+	vars.UsersLock.Lock()
+	vars.Users = make(map[string]types.Datum)
+	vars.UserVarTypes = make(map[string]*types.FieldType)
+	vars.UsersLock.Unlock()
+	vars.MaxExecutionTime = uint64(statusSQLTimeout.Milliseconds())
+}
+
+func (l *statusSQLLane) put(se session.Session) {
+	select {
+	case l.idle <- se:
+	default:
+		se.Close()
+	}
+}
+
+func (l *statusSQLLane) isOpen() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.openUntil)
+}
+
+func (l *statusSQLLane) record(timeout bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !timeout {
+		l.timeouts = 0
+		return
+	}
+	l.timeouts++
+	if l.timeouts >= statusSQLBreakerThreshold {
+		l.timeouts = 0
+		l.openUntil = time.Now().Add(statusSQLBreakerCooldown)
+		logutil.BgLogger().Warn("internal SQL of the status server keeps timing out, reject new requests for a while",
+			zap.Duration("cooldown", statusSQLBreakerCooldown))
+	}
+}
+
+// writeStatusSQLError writes 503 for the errors caused by the status SQL lane being unavailable.
+func writeStatusSQLError(w http.ResponseWriter, err error) {
+	cause := errors.Cause(err)
+	if cause != errStatusSQLBusy && cause != errStatusSQLCircuitOpen {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(statusSQLBreakerCooldown.Seconds())))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, err = w.Write([]byte(err.Error()))
+	terror.Log(errors.Trace(err))
+}
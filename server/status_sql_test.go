@@ -0,0 +1,123 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusSQLLane(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	originTimeout, originCooldown := statusSQLTimeout, statusSQLBreakerCooldown
+	statusSQLTimeout, statusSQLBreakerCooldown = 50*time.Millisecond, 200*time.Millisecond
+	defer func() {
+		statusSQLTimeout, statusSQLBreakerCooldown = originTimeout, originCooldown
+	}()
+
+	lane := newStatusSQLLane(store, 1)
+	err := lane.withSession(func(ctx context.Context, se session.Session) error {
+		rs, err := se.ExecuteInternal(ctx, statusSQLComment+"select 1")
+		require.NoError(t, err)
+		return rs.Close()
+	})
+	require.NoError(t, err)
+
+	// The lane rejects instead of queueing when all the sessions are in use.
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- lane.withSession(func(ctx context.Context, se session.Session) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	err = lane.withSession(func(ctx context.Context, se session.Session) error { return nil })
+	require.Equal(t, errStatusSQLBusy, err)
+	close(release)
+	require.NoError(t, <-done)
+
+	// Consecutive timeouts open the circuit breaker.
+	for i := 0; i < statusSQLBreakerThreshold; i++ {
+		err = lane.withSession(func(ctx context.Context, se session.Session) error {
+			<-ctx.Done()
+			return nil
+		})
+		require.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	}
+	err = lane.withSession(func(ctx context.Context, se session.Session) error { return nil })
+	require.Equal(t, errStatusSQLCircuitOpen, err)
+
+	w := httptest.NewRecorder()
+	writeStatusSQLError(w, err)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// The breaker is closed after the cooldown.
+	time.Sleep(statusSQLBreakerCooldown)
+	err = lane.withSession(func(ctx context.Context, se session.Session) error { return nil })
+	require.NoError(t, err)
+}
+
+func TestStatusSQLLaneReset(t *testing.T) {
+	store, clean := testkit.CreateMockStore(t)
+	defer clean()
+
+	lane := newStatusSQLLane(store, 1)
+	var first session.Session
+	err := lane.withSession(func(ctx context.Context, se session.Session) error {
+		first = se
+		for _, sql := range []string{"use mysql", "set @a = 1", "begin"} {
+			_, err := se.ExecuteInternal(ctx, statusSQLComment+sql)
+			require.NoError(t, err)
+		}
+		require.True(t, se.GetSessionVars().InTxn())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The session is reused without the state left by the previous handler.
+	err = lane.withSession(func(ctx context.Context, se session.Session) error {
+		require.Same(t, first, se)
+		vars := se.GetSessionVars()
+		require.False(t, vars.InTxn())
+		require.Empty(t, vars.CurrentDB)
+		require.Empty(t, vars.Users)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The session is closed instead of being reused after an error.
+	errFailed := errors.New("failed")
+	err = lane.withSession(func(ctx context.Context, se session.Session) error { return errFailed })
+	require.Equal(t, errFailed, err)
+	err = lane.withSession(func(ctx context.Context, se session.Session) error {
+		require.NotSame(t, first, se)
+		return nil
+	})
+	require.NoError(t, err)
+}
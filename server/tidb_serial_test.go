@@ -18,8 +18,17 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -27,11 +36,15 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/metrics"
 	tmysql "github.com/pingcap/tidb/parser/mysql"
+	plannercore "github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/collate"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -96,6 +109,32 @@ func TestLoadDataListPartition(t *testing.T) {
 	ts.runTestLoadDataForListColumnPartition2(t)
 }
 
+func TestLoadDataMaxFileSize(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	ts.server.cfg.Security.MaxLoadDataFileSize = 16
+	path := filepath.Join(t.TempDir(), "load_data_max_file_size.csv")
+	ts.runTestsOnNewDB(t, func(config *mysql.Config) {
+		config.AllowAllFiles = true
+	}, "LoadDataMaxFileSize", func(dbt *testkit.DBTestKit) {
+		dbt.MustExec("create table t (a int, b int)")
+
+		require.NoError(t, os.WriteFile(path, []byte("1,1\n2,2\n"), 0644))
+		dbt.MustExec(fmt.Sprintf("load data local infile '%s' into table t fields terminated by ','", path))
+		rows := dbt.MustQuery("select count(*) from t")
+		ts.checkRows(t, rows, "2")
+
+		// The file exceeds the limit, nothing is loaded and the connection is still usable.
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("3,3\n", 10)), 0644))
+		_, err := dbt.GetDB().Exec(fmt.Sprintf("load data local infile '%s' into table t fields terminated by ','", path))
+		require.Error(t, err)
+		require.Equal(t, uint16(tmysql.ErrNetPacketTooLarge), err.(*mysql.MySQLError).Number)
+		rows = dbt.MustQuery("select count(*) from t")
+		ts.checkRows(t, rows, "2")
+	})
+}
+
 func TestTLSAuto(t *testing.T) {
 	ts, cleanup := createTidbTestSuite(t)
 	defer cleanup()
@@ -200,6 +239,138 @@ func TestTLSBasic(t *testing.T) {
 	server.Close()
 }
 
+func TestTLSECDSA(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caCertFile, caKeyFile := filepath.Join(dir, "ca-cert.pem"), filepath.Join(dir, "ca-key.pem")
+	serverCertFile, serverKeyFile := filepath.Join(dir, "server-cert.pem"), filepath.Join(dir, "server-key.pem")
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client-cert.pem"), filepath.Join(dir, "client-key.pem")
+	caCert, caKey, err := generateCertWithKeyType(CertKeyECDSAP384, 0, "TiDB CA", nil, nil, caKeyFile, caCertFile)
+	require.NoError(t, err)
+	_, _, err = generateCertWithKeyType(CertKeyECDSAP256, 1, "tidb-server", caCert, caKey, serverKeyFile, serverCertFile)
+	require.NoError(t, err)
+	_, _, err = generateCertWithKeyType(CertKeyECDSAP256, 2, "SQL Client Certificate", caCert, caKey, clientKeyFile, clientCertFile)
+	require.NoError(t, err)
+
+	// The client only offers TLS 1.2 to check the ECDSA cipher suite is negotiated.
+	caPEM, err := os.ReadFile(caCertFile)
+	require.NoError(t, err)
+	rootCertPool := x509.NewCertPool()
+	require.True(t, rootCertPool.AppendCertsFromPEM(caPEM))
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	require.NoError(t, mysql.RegisterTLSConfig("client-certificate-ecdsa", &tls.Config{
+		RootCAs:      rootCertPool,
+		Certificates: []tls.Certificate{clientCert},
+		ServerName:   "tidb-server",
+		MaxVersion:   tls.VersionTLS12,
+	}))
+	defer mysql.DeregisterTLSConfig("client-certificate-ecdsa")
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.ReportStatus = false
+	cfg.Security = config.Security{
+		SSLCA:   caCertFile,
+		SSLCert: serverCertFile,
+		SSLKey:  serverKeyFile,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	cli.runTests(t, func(config *mysql.Config) {
+		config.TLSConfig = "client-certificate-ecdsa"
+	}, func(dbt *testkit.DBTestKit) {
+		rows := dbt.MustQuery("show status like 'Ssl_cipher'")
+		require.True(t, rows.Next())
+		var name, cipher string
+		require.NoError(t, rows.Scan(&name, &cipher))
+		require.NoError(t, rows.Close())
+		require.True(t, strings.HasPrefix(cipher, "ECDHE-ECDSA-"), cipher)
+		dbt.MustExec("select 1")
+	})
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caCertFile, caKeyFile := filepath.Join(dir, "ca-cert.pem"), filepath.Join(dir, "ca-key.pem")
+	serverCertFile, serverKeyFile := filepath.Join(dir, "server-cert.pem"), filepath.Join(dir, "server-key.pem")
+	caCert, caKey, err := generateCert(0, "TiDB CA", nil, nil, caKeyFile, caCertFile)
+	require.NoError(t, err)
+	_, _, err = generateCert(1, "tidb-server", caCert, caKey, serverKeyFile, serverCertFile)
+	require.NoError(t, err)
+
+	caPEM, err := os.ReadFile(caCertFile)
+	require.NoError(t, err)
+	rootCertPool := x509.NewCertPool()
+	require.True(t, rootCertPool.AppendCertsFromPEM(caPEM))
+	require.NoError(t, mysql.RegisterTLSConfig("tls-1.1", &tls.Config{
+		RootCAs:    rootCertPool,
+		ServerName: "tidb-server",
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS11,
+	}))
+	defer mysql.DeregisterTLSConfig("tls-1.1")
+	require.NoError(t, mysql.RegisterTLSConfig("tls-1.2", &tls.Config{
+		RootCAs:    rootCertPool,
+		ServerName: "tidb-server",
+		MaxVersion: tls.VersionTLS12,
+	}))
+	defer mysql.DeregisterTLSConfig("tls-1.2")
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.ReportStatus = false
+	// The empty tls-version enforces TLS 1.2 as the minimum.
+	cfg.Security = config.Security{
+		SSLCA:   caCertFile,
+		SSLCert: serverCertFile,
+		SSLKey:  serverKeyFile,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	db, err := sql.Open("mysql", cli.getDSN(func(config *mysql.Config) {
+		config.TLSConfig = "tls-1.1"
+	}))
+	require.NoError(t, err)
+	_, err = db.Exec("select 1")
+	require.Error(t, err, "TLS 1.1 should be rejected")
+	require.NoError(t, db.Close())
+
+	cli.runTests(t, func(config *mysql.Config) {
+		config.TLSConfig = "tls-1.2"
+	}, func(dbt *testkit.DBTestKit) {
+		rows := dbt.MustQuery("show status like 'Ssl_version'")
+		require.True(t, rows.Next())
+		var name, version string
+		require.NoError(t, rows.Scan(&name, &version))
+		require.NoError(t, rows.Close())
+		require.Equal(t, "TLSv1.2", version)
+	})
+}
+
 func TestTLSVerify(t *testing.T) {
 	ts, cleanup := createTidbTestSuite(t)
 	defer cleanup()
@@ -495,3 +666,526 @@ func TestReloadTLS(t *testing.T) {
 	require.Truef(t, util.IsTLSExpiredError(err), "real error is %+v", err)
 	server.Close()
 }
+
+func TestReloadTLSCertificates(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caCertFile, caKeyFile := filepath.Join(dir, "ca-cert.pem"), filepath.Join(dir, "ca-key.pem")
+	certFile, keyFile := filepath.Join(dir, "server-cert.pem"), filepath.Join(dir, "server-key.pem")
+	caCert, caKey, err := generateCert(0, "TiDB CA", nil, nil, caKeyFile, caCertFile)
+	require.NoError(t, err)
+	_, _, err = generateCert(1, "tidb-server", caCert, caKey, keyFile, certFile)
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	cfg.Security = config.Security{
+		SSLCA:          caCertFile,
+		SSLCert:        certFile,
+		SSLKey:         keyFile,
+		ClusterSSLCA:   caCertFile,
+		ClusterSSLCert: certFile,
+		ClusterSSLKey:  keyFile,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, server.statusListener.Close())
+		server.Close()
+	}()
+
+	checkSerialNumber := func(expected int64) {
+		for _, tlsConfig := range []*tls.Config{server.getTLSConfig(), server.getStatusTLSConfig()} {
+			cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+			require.NoError(t, err)
+			require.Equal(t, expected, cert.SerialNumber.Int64())
+		}
+	}
+	checkSerialNumber(1)
+
+	// The rotated certificates are used after reloading.
+	_, _, err = generateCert(2, "tidb-server", caCert, caKey, keyFile, certFile)
+	require.NoError(t, err)
+	require.NoError(t, server.ReloadTLS())
+	checkSerialNumber(2)
+
+	// The old certificates are kept if the new files are invalid.
+	require.NoError(t, os.WriteFile(certFile, []byte("invalid"), 0600))
+	require.Error(t, server.ReloadTLS())
+	checkSerialNumber(2)
+
+	// Reload by the status API.
+	_, _, err = generateCert(3, "tidb-server", caCert, caKey, keyFile, certFile)
+	require.NoError(t, err)
+	resp := httptest.NewRecorder()
+	server.handleTLSReload(resp, httptest.NewRequest(http.MethodGet, "/settings/tls-reload", nil))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	checkSerialNumber(2)
+	resp = httptest.NewRecorder()
+	server.handleTLSReload(resp, httptest.NewRequest(http.MethodPost, "/settings/tls-reload", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	checkSerialNumber(3)
+}
+
+func TestRequireSecureTransport(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caCert, caKey, err := generateCert(0, "TiDB CA", nil, nil, filepath.Join(dir, "ca-key.pem"), filepath.Join(dir, "ca-cert.pem"))
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "server-cert.pem"), filepath.Join(dir, "server-key.pem")
+	_, _, err = generateCert(1, "tidb-server", caCert, caKey, keyFile, certFile)
+	require.NoError(t, err)
+	socketFile := filepath.Join(dir, "tidbtest.sock")
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = cli.port
+	cfg.Socket = socketFile
+	cfg.Status.ReportStatus = false
+	cfg.Security = config.Security{
+		SSLCert: certFile,
+		SSLKey:  keyFile,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+	defer variable.SetRequireSecureTransport(false)
+
+	tlsOverrider := func(config *mysql.Config) {
+		config.TLSConfig = "skip-verify"
+	}
+	socketOverrider := func(config *mysql.Config) {
+		config.Net = "unix"
+		config.Addr = socketFile
+	}
+	rejected := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.InsecureTransportRejectCounter.Write(m))
+		return m.GetCounter().GetValue()
+	}
+	statusRequireSecureTransport := func() bool {
+		resp := httptest.NewRecorder()
+		server.handleStatus(resp, httptest.NewRequest(http.MethodGet, "/status", nil))
+		var st status
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &st))
+		return st.RequireSecureTransport
+	}
+
+	// The plaintext connection established before enabling require_secure_transport keeps working.
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	require.False(t, statusRequireSecureTransport())
+
+	cli.runTests(t, tlsOverrider, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec("SET GLOBAL require_secure_transport = ON")
+	})
+	require.True(t, statusRequireSecureTransport())
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	// New plaintext connections are rejected, but TLS and unix socket connections are allowed.
+	before := rejected()
+	err = cli.runTestTLSConnection(t, nil)
+	require.Error(t, err)
+	mysqlErr, ok := errors.Cause(err).(*mysql.MySQLError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, uint16(errno.ErrSecureTransportRequired), mysqlErr.Number)
+	require.Equal(t, before+1, rejected())
+	require.NoError(t, cli.runTestTLSConnection(t, tlsOverrider))
+	require.NoError(t, cli.runTestTLSConnection(t, socketOverrider))
+	require.Equal(t, before+1, rejected())
+
+	cli.runTests(t, socketOverrider, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec("SET GLOBAL require_secure_transport = OFF")
+	})
+	require.False(t, statusRequireSecureTransport())
+	require.NoError(t, cli.runTestTLSConnection(t, nil))
+}
+
+func TestConnectionTransport(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caCert, caKey, err := generateCert(0, "TiDB CA", nil, nil, filepath.Join(dir, "ca-key.pem"), filepath.Join(dir, "ca-cert.pem"))
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "server-cert.pem"), filepath.Join(dir, "server-key.pem")
+	_, _, err = generateCert(1, "tidb-server", caCert, caKey, keyFile, certFile)
+	require.NoError(t, err)
+	socketFile := filepath.Join(dir, "tidbtest.sock")
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = cli.port
+	cfg.Socket = socketFile
+	cfg.Status.ReportStatus = false
+	cfg.Security = config.Security{
+		SSLCert: certFile,
+		SSLKey:  keyFile,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	transportCount := func(transport string) float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.ConnectionTransportCounter.WithLabelValues(transport).Write(m))
+		return m.GetCounter().GetValue()
+	}
+	handshakeFailures := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.TLSHandshakeFailureCounter.Write(m))
+		return m.GetCounter().GetValue()
+	}
+	checkProcessList := func(overrider configOverrider, transport string, withTLS bool) {
+		before := transportCount(transport)
+		cli.runTests(t, overrider, func(dbt *testkit.DBTestKit) {
+			rows := dbt.MustQuery("select transport, ssl_version, ssl_cipher from information_schema.processlist where id = connection_id()")
+			require.True(t, rows.Next())
+			var tp, version, cipher sql.NullString
+			require.NoError(t, rows.Scan(&tp, &version, &cipher))
+			require.False(t, rows.Next())
+			require.NoError(t, rows.Close())
+			require.Equal(t, transport, tp.String)
+			require.Equal(t, withTLS, version.Valid)
+			require.Equal(t, withTLS, cipher.Valid)
+			if withTLS {
+				require.True(t, strings.HasPrefix(version.String, "TLSv1."), version.String)
+				require.NotEmpty(t, cipher.String)
+			}
+		})
+		require.Equal(t, before+1, transportCount(transport))
+	}
+
+	checkProcessList(nil, "tcp", false)
+	checkProcessList(func(config *mysql.Config) {
+		config.TLSConfig = "skip-verify"
+	}, "tls", true)
+	checkProcessList(func(config *mysql.Config) {
+		config.Net = "unix"
+		config.Addr = socketFile
+	}, "socket", false)
+
+	// A client that does not trust the server certificate aborts the TLS handshake.
+	before := handshakeFailures()
+	err = cli.runTestTLSConnection(t, func(config *mysql.Config) {
+		config.TLSConfig = "true"
+	})
+	require.Error(t, err)
+	require.Eventually(t, func() bool {
+		return handshakeFailures() == before+1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConnectionLimit(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+	defer func() {
+		variable.SetMaxServerConnections(0)
+		variable.SetMaxConnectionsPerIP(0)
+	}()
+
+	dir := t.TempDir()
+	socketFile := filepath.Join(dir, "tidbtest.sock")
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Socket = socketFile
+	cfg.Status.ReportStatus = false
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	rejectCount := func(reason, prefix string) float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.ConnectionLimitRejectCounter.WithLabelValues(reason, prefix).Write(m))
+		return m.GetCounter().GetValue()
+	}
+	connect := func(overrider configOverrider) error {
+		db, err := sql.Open("mysql", cli.getDSN(overrider))
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, db.Close())
+		}()
+		return db.Ping()
+	}
+	useSocket := func(config *mysql.Config) {
+		config.Net = "unix"
+		config.Addr = socketFile
+	}
+	checkRejected := func(err error) {
+		require.Error(t, err)
+		require.Contains(t, err.Error(), fmt.Sprintf("Error %d", errno.ErrConCount))
+	}
+
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	// The limits are changed at runtime by the system variables.
+	_, err = conn.ExecContext(context.Background(), "set global tidb_max_connections_per_ip = 1")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), config.GetGlobalConfig().MaxConnectionsPerIP)
+	before := rejectCount(connLimitMaxConnectionsPerIP, "127.0.0.0/24")
+	checkRejected(connect(nil))
+	require.Equal(t, before+1, rejectCount(connLimitMaxConnectionsPerIP, "127.0.0.0/24"))
+	// The unix socket is not limited by tidb_max_connections_per_ip.
+	require.NoError(t, connect(useSocket))
+
+	_, err = conn.ExecContext(context.Background(), "set global tidb_max_connections_per_ip = 0")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(context.Background(), "set global tidb_max_server_connections = 1")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), config.GetGlobalConfig().MaxServerConnections)
+	before = rejectCount(connLimitMaxServerConnections, "socket")
+	checkRejected(connect(useSocket))
+	require.Equal(t, before+1, rejectCount(connLimitMaxServerConnections, "socket"))
+
+	// The closed connections are released once the server notices they are closed.
+	_, err = conn.ExecContext(context.Background(), "set global tidb_max_server_connections = 2")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.Eventually(t, func() bool {
+			return connect(nil) == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestIPPrefix(t *testing.T) {
+	require.Equal(t, "socket", ipPrefix(""))
+	require.Equal(t, "unknown", ipPrefix("localhost"))
+	require.Equal(t, "192.168.1.0/24", ipPrefix("192.168.1.23"))
+	require.Equal(t, "2001:db8:1:2::/64", ipPrefix("2001:db8:1:2:3:4:5:6"))
+}
+
+func TestStatusAuth(t *testing.T) {
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	cfg.Status.AuthToken = "status-secret"
+	cfg.Status.PauseToken = "pause-secret"
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	server.SetDomain(ts.domain)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	requestWithToken := func(method, path, token string) (int, string) {
+		req, err := http.NewRequest(method, cli.statusURL(path), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode, string(body)
+	}
+	failures := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.StatusAuthFailureCounter.Write(m))
+		return m.GetCounter().GetValue()
+	}
+
+	before := failures()
+	code, _ := requestWithToken(http.MethodGet, "/status", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = requestWithToken(http.MethodGet, "/status", "wrong")
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.Equal(t, before+2, failures())
+	code, _ = requestWithToken(http.MethodGet, "/status", "status-secret")
+	require.Equal(t, http.StatusOK, code)
+
+	// The paths in auth-allowlist are not checked.
+	code, _ = requestWithToken(http.MethodGet, "/health", "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = requestWithToken(http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusOK, code)
+	server.cfg.Status.AuthAllowlist = []string{"/health"}
+	code, _ = requestWithToken(http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// /pause and /resume are protected by pause-token instead.
+	code, _ = requestWithToken(http.MethodPost, "/pause", "status-secret")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = requestWithToken(http.MethodPost, "/pause", "pause-secret")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = requestWithToken(http.MethodPost, "/resume", "pause-secret")
+	require.Equal(t, http.StatusOK, code)
+
+	// The token is not exposed by /settings.
+	code, body := requestWithToken(http.MethodGet, "/settings", "status-secret")
+	require.Equal(t, http.StatusOK, code)
+	require.NotContains(t, body, "status-secret")
+	require.NotContains(t, body, "pause-secret")
+}
+
+func TestStatusAllowedHosts(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	cfg := newTestConfig()
+	_, filtered := newStatusHostFilter(ok, cfg).(statusHostFilter)
+	require.False(t, filtered)
+
+	cfg.Status.AllowedHosts = []string{"127.0.0.1", "10.0.0.0/8"}
+	cfg.Status.TrustedProxies = []string{"192.168.1.0/24"}
+	handler := newStatusHostFilter(ok, cfg)
+	serve := func(remoteAddr string, forwardedFor ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = remoteAddr
+		for _, addr := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", addr)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	rejected := func() float64 {
+		m := &dto.Metric{}
+		require.NoError(t, metrics.StatusHostRejectCounter.Write(m))
+		return m.GetCounter().GetValue()
+	}
+
+	before := rejected()
+	require.Equal(t, http.StatusOK, serve("127.0.0.1:4000"))
+	require.Equal(t, http.StatusOK, serve("10.1.2.3:4000"))
+	require.Equal(t, http.StatusForbidden, serve("172.16.0.1:4000"))
+	require.Equal(t, before+1, rejected())
+
+	// X-Forwarded-For is ignored if the peer is not a trusted proxy.
+	require.Equal(t, http.StatusForbidden, serve("172.16.0.1:4000", "10.1.2.3"))
+	require.Equal(t, http.StatusOK, serve("10.1.2.3:4000", "172.16.0.1"))
+	// The client is the last address which is not a trusted proxy, the forged addresses before it are ignored.
+	require.Equal(t, http.StatusOK, serve("192.168.1.1:4000", "10.1.2.3"))
+	require.Equal(t, http.StatusOK, serve("192.168.1.1:4000", "172.16.0.1, 10.1.2.3, 192.168.1.2"))
+	require.Equal(t, http.StatusOK, serve("192.168.1.1:4000", "172.16.0.1", "10.1.2.3"))
+	require.Equal(t, http.StatusForbidden, serve("192.168.1.1:4000", "10.1.2.3, 172.16.0.1"))
+	require.Equal(t, http.StatusForbidden, serve("192.168.1.1:4000", "unknown"))
+	// A trusted proxy itself is not allowed unless it is in allowed-hosts.
+	require.Equal(t, http.StatusForbidden, serve("192.168.1.1:4000"))
+
+	// All the requests are rejected if allowed-hosts is invalid.
+	cfg.Status.AllowedHosts = []string{"localhost"}
+	handler = newStatusHostFilter(ok, cfg)
+	require.Equal(t, http.StatusForbidden, serve("127.0.0.1:4000"))
+}
+
+func TestPlanCacheAPI(t *testing.T) {
+	orgEnable := plannercore.PreparedPlanCacheEnabled()
+	plannercore.SetPreparedPlanCache(true)
+	defer plannercore.SetPreparedPlanCache(orgEnable)
+
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	planCacheOf := func(table string) []plannercore.PlanCacheStats {
+		resp, err := cli.fetchStatus("/plan-cache")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var stats []plannercore.PlanCacheStats
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		require.NoError(t, resp.Body.Close())
+		var result []plannercore.PlanCacheStats
+		for _, s := range stats {
+			if strings.Contains(s.NormalizedSQL, table) {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	cli.runTests(t, nil, func(dbt *testkit.DBTestKit) {
+		dbt.MustExec("create table t_plan_cache(a int, b int)")
+		stmt, err := dbt.GetDB().Prepare("select * from t_plan_cache where b > ?")
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			rows, err := stmt.Query(123456)
+			require.NoError(t, err)
+			require.NoError(t, rows.Close())
+		}
+
+		stats := planCacheOf("t_plan_cache")
+		require.Len(t, stats, 1)
+		require.Equal(t, "select * from `t_plan_cache` where `b` > ?", stats[0].NormalizedSQL)
+		require.Equal(t, 1, stats[0].Plans)
+		require.Equal(t, uint64(2), stats[0].Hits)
+
+		resp, err := cli.fetchStatus("/plan-cache/clear")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+		resp, err = cli.postStatus("/plan-cache/clear", "application/x-www-form-urlencoded", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+		require.Len(t, planCacheOf("t_plan_cache"), 0)
+		require.NoError(t, stmt.Close())
+	})
+}
@@ -19,18 +19,24 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -46,6 +52,7 @@ import (
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/store/mockstore"
 	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/plancodec"
@@ -249,6 +256,59 @@ func TestStatusPort(t *testing.T) {
 	server, err := NewServer(cfg, ts.tidbdrv)
 	require.Error(t, err)
 	require.Nil(t, server)
+	listenerErr, ok := errors.Cause(err).(*ListenerError)
+	require.True(t, ok)
+	require.True(t, listenerErr.IsAddrInUse())
+}
+
+func TestStatusHost(t *testing.T) {
+	t.Parallel()
+	// Find a non-loopback address to act as an external client.
+	var externalIP string
+	addrs, err := net.InterfaceAddrs()
+	require.NoError(t, err)
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			externalIP = ipNet.IP.String()
+			break
+		}
+	}
+	if externalIP == "" {
+		t.Skip("no non-loopback address is available")
+	}
+
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.ReportStatus = true
+	cfg.Status.StatusHost = "0.0.0.0"
+	cfg.Status.StatusPort = 0
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	port := getPortFromTCPAddr(server.listener.Addr())
+	statusPort := getPortFromTCPAddr(server.statusListener.Addr())
+
+	// The MySQL port is only reachable through the loopback interface.
+	_, err = net.DialTimeout("tcp", net.JoinHostPort(externalIP, strconv.Itoa(int(port))), time.Second)
+	require.Error(t, err)
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))), time.Second)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// The status port accepts connections from other interfaces.
+	resp, err := http.Get(fmt.Sprintf("http://%s/status", net.JoinHostPort(externalIP, strconv.Itoa(int(statusPort)))))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
 }
 
 func TestStatusAPIWithTLS(t *testing.T) {
@@ -276,6 +336,8 @@ func TestStatusAPIWithTLS(t *testing.T) {
 	cfg.Security.ClusterSSLCA = "/tmp/ca-cert-2.pem"
 	cfg.Security.ClusterSSLCert = "/tmp/server-cert-2.pem"
 	cfg.Security.ClusterSSLKey = "/tmp/server-key-2.pem"
+	cfg.Security.SSLCert = "/tmp/server-cert-2.pem"
+	cfg.Security.SSLKey = "/tmp/server-key-2.pem"
 	server, err := NewServer(cfg, ts.tidbdrv)
 	require.NoError(t, err)
 	cli.port = getPortFromTCPAddr(server.listener.Addr())
@@ -294,6 +356,20 @@ func TestStatusAPIWithTLS(t *testing.T) {
 	_, err = cli.fetchStatus("/status") // nolint: bodyclose
 	require.Error(t, err)
 
+	// The MySQL protocol connections report whether they use TLS.
+	cli.runTests(t, func(config *mysql.Config) {
+		config.TLSConfig = "skip-verify"
+	}, func(dbt *testkit.DBTestKit) {
+		rows := dbt.MustQuery("select @@tidb_connection_type")
+		cli.checkRows(t, rows, "TLS")
+		require.NoError(t, rows.Close())
+	})
+	cli.runTests(t, nil, func(dbt *testkit.DBTestKit) {
+		rows := dbt.MustQuery("select @@tidb_connection_type")
+		cli.checkRows(t, rows, "TCP")
+		require.NoError(t, rows.Close())
+	})
+
 	server.Close()
 }
 
@@ -360,6 +436,134 @@ func TestStatusAPIWithTLSCNCheck(t *testing.T) {
 	require.Nil(t, resp.Body.Close())
 }
 
+func TestStatusAPIWithStatusTLS(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	clusterCACert, clusterCAKey, err := generateCert(0, "TiDB Cluster CA", nil, nil, path("cluster-ca-key.pem"), path("cluster-ca-cert.pem"))
+	require.NoError(t, err)
+	_, _, err = generateCert(1, "tidb-cluster", clusterCACert, clusterCAKey, path("cluster-key.pem"), path("cluster-cert.pem"))
+	require.NoError(t, err)
+	_, _, err = generateCert(2, "tidb-client-1", clusterCACert, clusterCAKey, path("client1-key.pem"), path("client1-cert.pem"))
+	require.NoError(t, err)
+	statusCACert, statusCAKey, err := generateCert(0, "TiDB Status CA", nil, nil, path("status-ca-key.pem"), path("status-ca-cert.pem"))
+	require.NoError(t, err)
+	_, _, err = generateCert(1, "tidb-status", statusCACert, statusCAKey, path("status-key.pem"), path("status-cert.pem"))
+	require.NoError(t, err)
+	_, _, err = generateCert(2, "tidb-client-2", statusCACert, statusCAKey, path("client2-key.pem"), path("client2-cert.pem"))
+	require.NoError(t, err)
+	_, _, err = generateCert(3, "tidb-client-3", statusCACert, statusCAKey, path("client3-key.pem"), path("client3-cert.pem"))
+	require.NoError(t, err)
+
+	startServer := func(statusTLS config.StatusTLS) (*Server, *testServerClient) {
+		cli := newTestServerClient()
+		cli.statusScheme = "https"
+		cfg := newTestConfig()
+		cfg.Port = cli.port
+		cfg.Status.StatusPort = cli.statusPort
+		cfg.Security.ClusterSSLCA = path("cluster-ca-cert.pem")
+		cfg.Security.ClusterSSLCert = path("cluster-cert.pem")
+		cfg.Security.ClusterSSLKey = path("cluster-key.pem")
+		cfg.Status.TLS = statusTLS
+		server, err := NewServer(cfg, ts.tidbdrv)
+		require.NoError(t, err)
+		cli.port = getPortFromTCPAddr(server.listener.Addr())
+		cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+		go func() {
+			err := server.Run()
+			require.NoError(t, err)
+		}()
+		return server, cli
+	}
+	// The status server falls back to the cluster TLS configurations.
+	clusterServer, clusterCli := startServer(config.StatusTLS{})
+	defer clusterServer.Close()
+	// The status TLS configurations override the cluster ones.
+	statusServer, statusCli := startServer(config.StatusTLS{
+		CA:       path("status-ca-cert.pem"),
+		Cert:     path("status-cert.pem"),
+		Key:      path("status-key.pem"),
+		VerifyCN: []string{"tidb-client-2"},
+	})
+	defer statusServer.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	checkServerCN := func(hc *http.Client, cli *testServerClient, cn string) {
+		resp, err := hc.Get(cli.statusURL("/status"))
+		require.NoError(t, err)
+		require.Equal(t, cn, resp.TLS.PeerCertificates[0].Subject.CommonName)
+		require.NoError(t, resp.Body.Close())
+	}
+	hc1 := newTLSHttpClient(t, path("cluster-ca-cert.pem"), path("client1-cert.pem"), path("client1-key.pem"))
+	hc2 := newTLSHttpClient(t, path("status-ca-cert.pem"), path("client2-cert.pem"), path("client2-key.pem"))
+	hc3 := newTLSHttpClient(t, path("status-ca-cert.pem"), path("client3-cert.pem"), path("client3-key.pem"))
+	checkServerCN(hc1, clusterCli, "tidb-cluster")
+	checkServerCN(hc2, statusCli, "tidb-status")
+
+	// The client certificate signed by the cluster CA is not accepted by the status server with its own CA.
+	_, err = hc1.Get(statusCli.statusURL("/status")) // nolint: bodyclose
+	require.Error(t, err)
+	// The status server checks the Common Name by status.tls.verify-cn.
+	_, err = hc3.Get(statusCli.statusURL("/status")) // nolint: bodyclose
+	require.Error(t, err)
+}
+
+func TestStatusAPIWithTLSCNWildcard(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca-cert.pem")
+	serverKeyPath := filepath.Join(dir, "server-key.pem")
+	serverCertPath := filepath.Join(dir, "server-cert.pem")
+	caCert, caKey, err := generateCert(0, "TiDB CA CN WILDCARD", nil, nil, filepath.Join(dir, "ca-key.pem"), caPath)
+	require.NoError(t, err)
+	_, _, err = generateCert(1, "tidb-server-cn-wildcard", caCert, caKey, serverKeyPath, serverCertPath)
+	require.NoError(t, err)
+
+	cli := newTestServerClient()
+	cli.statusScheme = "https"
+	cfg := newTestConfig()
+	cfg.Port = cli.port
+	cfg.Status.StatusPort = cli.statusPort
+	cfg.Security.ClusterSSLCA = caPath
+	cfg.Security.ClusterSSLCert = serverCertPath
+	cfg.Security.ClusterSSLKey = serverKeyPath
+	cfg.Security.ClusterVerifyCN = []string{"tidb-client-*", "*.tidb.svc"}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	defer server.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	for i, cn := range []string{"tidb-client-1", "tidb-client-2", "tidb-0.tidb.svc", "tidb-client", "tidb-0.tidb.svc.local"} {
+		keyPath := filepath.Join(dir, fmt.Sprintf("client-key-%d.pem", i))
+		certPath := filepath.Join(dir, fmt.Sprintf("client-cert-%d.pem", i))
+		_, _, err = generateCert(i+2, cn, caCert, caKey, keyPath, certPath)
+		require.NoError(t, err)
+		hc := newTLSHttpClient(t, caPath, certPath, keyPath)
+		resp, err := hc.Get(cli.statusURL("/status"))
+		if i < 3 {
+			require.NoError(t, err, cn)
+			require.Nil(t, resp.Body.Close())
+		} else {
+			require.Error(t, err, cn)
+		}
+	}
+}
+
 func newTLSHttpClient(t *testing.T, caFile, certFile, keyFile string) *http.Client {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	require.NoError(t, err)
@@ -422,6 +626,65 @@ func TestSocketForwarding(t *testing.T) {
 	}, "SocketRegression")
 }
 
+func TestProxyProtocol(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = cli.port
+	cfg.Status.ReportStatus = false
+	cfg.ProxyProtocol.Networks = "127.0.0.1/32"
+
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	// The v2 header of a TCP over IPv4 connection from 192.168.1.20:5678 to 127.0.0.1:4000.
+	v2Header := []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A, 0x21, 0x11, 0x00, 0x0C,
+		192, 168, 1, 20, 127, 0, 0, 1, 0x16, 0x2E, 0x0F, 0xA0}
+	headers := map[string][]byte{
+		"proxyv1": []byte("PROXY TCP4 192.168.1.10 127.0.0.1 5678 4000\r\n"),
+		"proxyv2": v2Header,
+	}
+	expected := map[string]string{
+		"proxyv1": "root@192.168.1.10",
+		"proxyv2": "root@192.168.1.20",
+	}
+	for network, header := range headers {
+		header := header
+		mysql.RegisterDialContext(network, func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = conn.Write(header); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		})
+		cli.runTests(t, func(config *mysql.Config) {
+			config.Net = network
+		}, func(dbt *testkit.DBTestKit) {
+			rows := dbt.MustQuery("select user()")
+			require.True(t, rows.Next())
+			var user string
+			require.NoError(t, rows.Scan(&user))
+			require.Equal(t, expected[network], user)
+			require.NoError(t, rows.Close())
+		})
+	}
+}
+
 func TestSocket(t *testing.T) {
 	t.Parallel()
 	osTempDir := os.TempDir()
@@ -462,6 +725,45 @@ func TestSocket(t *testing.T) {
 	cli.runTestRegression(t, confFunc, "SocketRegression")
 }
 
+func TestSocketPermissions(t *testing.T) {
+	t.Parallel()
+	tempDir, err := os.MkdirTemp(os.TempDir(), "tidb-test.*.socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cfg := newTestConfig()
+	cfg.Socket = filepath.Join(tempDir, "tidbtest.sock")
+	cfg.SocketPermissions = "0700"
+	cfg.Port = 0
+	cfg.Status.ReportStatus = false
+
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	defer server.Close()
+	info, err := os.Stat(cfg.Socket)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestInvalidMaxChunkSize(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	for _, size := range []uint{0, 31, 65537} {
+		cfg := newTestConfig()
+		cfg.Port = 0
+		cfg.Status.ReportStatus = false
+		cfg.Performance.MaxChunkSize = size
+		_, err := NewServer(cfg, ts.tidbdrv)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid max-chunk-size")
+	}
+}
+
 func TestSocketAndIp(t *testing.T) {
 	t.Parallel()
 	osTempDir := os.TempDir()
@@ -683,6 +985,9 @@ func TestOnlySocket(t *testing.T) {
 			rows := dbt.MustQuery("select user()")
 			cli.checkRows(t, rows, "root@localhost")
 			require.NoError(t, rows.Close())
+			rows = dbt.MustQuery("select @@tidb_connection_type")
+			cli.checkRows(t, rows, "UNIX")
+			require.NoError(t, rows.Close())
 			rows = dbt.MustQuery("show grants")
 			cli.checkRows(t, rows, "GRANT ALL PRIVILEGES ON *.* TO 'root'@'%' WITH GRANT OPTION")
 			require.NoError(t, rows.Close())
@@ -794,11 +1099,65 @@ func TestOnlySocket(t *testing.T) {
 // generateCert generates a private key and a certificate in PEM format based on parameters.
 // If parentCert and parentCertKey is specified, the new certificate will be signed by the parentCert.
 // Otherwise, the new certificate will be self-signed and is a CA.
-func generateCert(sn int, commonName string, parentCert *x509.Certificate, parentCertKey *rsa.PrivateKey, outKeyFile string, outCertFile string, opts ...func(c *x509.Certificate)) (*x509.Certificate, *rsa.PrivateKey, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 528)
+// CertKeyType is the type of the private key generated by generateCertWithKeyType.
+type CertKeyType int
+
+// The key types supported by generateCertWithKeyType.
+const (
+	CertKeyRSA CertKeyType = iota
+	CertKeyECDSAP256
+	CertKeyECDSAP384
+)
+
+// String implements fmt.Stringer.
+func (tp CertKeyType) String() string {
+	switch tp {
+	case CertKeyECDSAP256:
+		return "ECDSA-P256"
+	case CertKeyECDSAP384:
+		return "ECDSA-P384"
+	default:
+		return "RSA"
+	}
+}
+
+// generateKey generates a private key of the type and returns it with its PEM block.
+func (tp CertKeyType) generateKey() (crypto.Signer, *pem.Block, error) {
+	var curve elliptic.Curve
+	switch tp {
+	case CertKeyECDSAP256:
+		curve = elliptic.P256()
+	case CertKeyECDSAP384:
+		curve = elliptic.P384()
+	default:
+		privateKey, err := rsa.GenerateKey(rand.Reader, 528)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		return privateKey, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}, nil
+	}
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
+	return privateKey, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+}
+
+// generateCert generates a certificate with a RSA key, the certificate is self-signed if parentCert or parentCertKey is nil.
+func generateCert(sn int, commonName string, parentCert *x509.Certificate, parentCertKey crypto.Signer, outKeyFile string, outCertFile string, opts ...func(c *x509.Certificate)) (*x509.Certificate, crypto.Signer, error) {
+	return generateCertWithKeyType(CertKeyRSA, sn, commonName, parentCert, parentCertKey, outKeyFile, outCertFile, opts...)
+}
+
+// generateCertWithKeyType is the same as generateCert, but generates the key of keyType.
+func generateCertWithKeyType(keyType CertKeyType, sn int, commonName string, parentCert *x509.Certificate, parentCertKey crypto.Signer, outKeyFile string, outCertFile string, opts ...func(c *x509.Certificate)) (*x509.Certificate, crypto.Signer, error) {
+	privateKey, keyBlock, err := keyType.generateKey()
+	if err != nil {
+		return nil, nil, err
+	}
 	notBefore := time.Now().Add(-10 * time.Minute).UTC()
 	notAfter := notBefore.Add(1 * time.Hour).UTC()
 
@@ -808,16 +1167,20 @@ func generateCert(sn int, commonName string, parentCert *x509.Certificate, paren
 		DNSNames:              []string{commonName},
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
 	}
+	// Only RSA keys are used for the key exchange.
+	if keyType == CertKeyRSA {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
 	for _, opt := range opts {
 		opt(&template)
 	}
 
 	var parent *x509.Certificate
-	var priv *rsa.PrivateKey
+	var priv crypto.Signer
 
 	if parentCert == nil || parentCertKey == nil {
 		template.IsCA = true
@@ -829,7 +1192,7 @@ func generateCert(sn int, commonName string, parentCert *x509.Certificate, paren
 		priv = parentCertKey
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, parent, &privateKey.PublicKey, priv)
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, parent, privateKey.Public(), priv)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -856,7 +1219,7 @@ func generateCert(sn int, commonName string, parentCert *x509.Certificate, paren
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
-	err = pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	err = pem.Encode(keyOut, keyBlock)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -982,17 +1345,35 @@ func TestCreateTableFlen(t *testing.T) {
 	require.Equal(t, 22, int(cols[1].ColumnLength))
 }
 
+// Execute executes a single statement. It returns an error if sql is not exactly one statement, so that
+// the test fails with the message instead of panicking.
 func Execute(ctx context.Context, qc *TiDBContext, sql string) (ResultSet, error) {
 	stmts, err := qc.Parse(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
 	if len(stmts) != 1 {
-		panic("wrong input for Execute: " + sql)
+		return nil, errors.Errorf("wrong input for Execute, expect 1 statement but got %d: %s", len(stmts), sql)
 	}
 	return qc.ExecuteStmt(ctx, stmts[0])
 }
 
+func TestExecuteWrongInput(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	qctx, err := ts.tidbdrv.OpenCtx(uint64(0), 0, uint8(tmysql.DefaultCollationID), "test", nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = Execute(ctx, qctx, "select 1; select 2")
+	require.EqualError(t, err, "wrong input for Execute, expect 1 statement but got 2: select 1; select 2")
+	_, err = Execute(ctx, qctx, "")
+	require.EqualError(t, err, "wrong input for Execute, expect 1 statement but got 0: ")
+	_, err = Execute(ctx, qctx, "select 1")
+	require.NoError(t, err)
+}
+
 func TestShowTablesFlen(t *testing.T) {
 	t.Parallel()
 	ts, cleanup := createTidbTestSuite(t)
@@ -1018,6 +1399,35 @@ func TestShowTablesFlen(t *testing.T) {
 	require.Equal(t, 26*tmysql.MaxBytesOfCharacter, int(cols[0].ColumnLength))
 }
 
+func TestConnectionAttributes(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	attrs := map[string]string{"_client_name": "libmysql", "_os": "Linux", "program_name": "mysql"}
+	qctx, err := ts.tidbdrv.OpenCtxWithAttributes(uint64(1), 0, uint8(tmysql.DefaultCollationID), "test", attrs, nil)
+	require.NoError(t, err)
+	require.Equal(t, attrs, qctx.GetSessionVars().ConnectionAttrs)
+	qctx.SetSessionManager(&Server{
+		clients: map[uint64]*clientConn{1: {connectionID: 1, ctx: qctx}},
+	})
+
+	ctx := context.Background()
+	rs, err := Execute(ctx, qctx, "select processlist_id, attr_name, attr_value, ordinal_position from performance_schema.session_connect_attrs")
+	require.NoError(t, err)
+	req := rs.NewChunk(nil)
+	require.NoError(t, rs.Next(ctx, req))
+	require.Equal(t, 3, req.NumRows())
+	for i, name := range []string{"_client_name", "_os", "program_name"} {
+		row := req.GetRow(i)
+		require.Equal(t, uint64(1), row.GetUint64(0))
+		require.Equal(t, name, row.GetString(1))
+		require.Equal(t, attrs[name], row.GetString(2))
+		require.Equal(t, int64(i), row.GetInt64(3))
+	}
+	require.NoError(t, rs.Close())
+}
+
 func checkColNames(t *testing.T, columns []*ColumnInfo, names ...string) {
 	for i, name := range names {
 		require.Equal(t, name, columns[i].Name)
@@ -1108,6 +1518,96 @@ func TestFieldList(t *testing.T) {
 	require.Equal(t, columnAsName, cols[0].Name)
 }
 
+func TestShowColumnsParity(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	ts.runTestsOnNewDB(t, nil, "ShowColumnsParity", func(dbt *testkit.DBTestKit) {
+		dbt.MustExec(`create table t (
+			c_bit bit(10),
+			c_int_d int,
+			c_bigint_d bigint,
+			c_float_d float,
+			c_double_d double,
+			c_decimal decimal(6, 3),
+			c_datetime datetime(2),
+			c_time time(3),
+			c_date date,
+			c_timestamp timestamp(4) DEFAULT CURRENT_TIMESTAMP(4),
+			c_char char(20),
+			c_varchar varchar(20),
+			c_text_d text,
+			c_binary binary(20),
+			c_blob_d blob,
+			c_set set('a', 'b', 'c'),
+			c_enum enum('a', 'b', 'c'),
+			c_json JSON,
+			c_year year,
+			c_int_uz int(5) unsigned zerofill,
+			c_ts_on_update timestamp DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			c_dt_on_update datetime(3) DEFAULT '2021-01-01 00:00:00' ON UPDATE CURRENT_TIMESTAMP(3),
+			c_virtual int AS (c_int_d + 1) VIRTUAL,
+			c_stored int AS (c_int_d + 1) STORED
+		)`)
+		expected := []string{
+			"c_bit|bit(10)|<nil>|",
+			"c_int_d|int(11)|<nil>|",
+			"c_bigint_d|bigint(20)|<nil>|",
+			"c_float_d|float|<nil>|",
+			"c_double_d|double|<nil>|",
+			"c_decimal|decimal(6,3)|<nil>|",
+			"c_datetime|datetime(2)|<nil>|",
+			"c_time|time(3)|<nil>|",
+			"c_date|date|<nil>|",
+			"c_timestamp|timestamp(4)|<nil>|",
+			"c_char|char(20)|utf8mb4_bin|",
+			"c_varchar|varchar(20)|utf8mb4_bin|",
+			"c_text_d|text|utf8mb4_bin|",
+			"c_binary|binary(20)|<nil>|",
+			"c_blob_d|blob|<nil>|",
+			"c_set|set('a','b','c')|utf8mb4_bin|",
+			"c_enum|enum('a','b','c')|utf8mb4_bin|",
+			"c_json|json|<nil>|",
+			"c_year|year(4)|<nil>|",
+			"c_int_uz|int(5) unsigned zerofill|<nil>|",
+			"c_ts_on_update|timestamp|<nil>|DEFAULT_GENERATED on update CURRENT_TIMESTAMP",
+			"c_dt_on_update|datetime(3)|<nil>|on update CURRENT_TIMESTAMP(3)",
+			"c_virtual|int(11)|<nil>|VIRTUAL GENERATED",
+			"c_stored|int(11)|<nil>|STORED GENERATED",
+		}
+		// Field, Type, Collation and Extra of SHOW FULL COLUMNS.
+		require.Equal(t, expected, showColumnsFields(t, dbt.MustQuery("show full columns from t"), 0, 1, 2, 6))
+		require.Equal(t, expected, showColumnsFields(t, dbt.MustQuery("select column_name, column_type, collation_name, extra "+
+			"from information_schema.columns where table_schema = 'ShowColumnsParity' and table_name = 't' order by ordinal_position"), 0, 1, 2, 3))
+	})
+}
+
+func showColumnsFields(t *testing.T, rows *sql.Rows, idx ...int) []string {
+	cols, err := rows.Columns()
+	require.NoError(t, err)
+	var result []string
+	for rows.Next() {
+		raw := make([]sql.NullString, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range raw {
+			dest[i] = &raw[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		fields := make([]string, 0, len(idx))
+		for _, i := range idx {
+			if raw[i].Valid {
+				fields = append(fields, raw[i].String)
+			} else {
+				fields = append(fields, "<nil>")
+			}
+		}
+		result = append(result, strings.Join(fields, "|"))
+	}
+	require.NoError(t, rows.Close())
+	return result
+}
+
 func TestClientErrors(t *testing.T) {
 	t.Parallel()
 	ts, cleanup := createTidbTestSuite(t)
@@ -1264,6 +1764,444 @@ func TestGracefulShutdown(t *testing.T) {
 	require.Regexp(t, "connect: connection refused$", err.Error())
 }
 
+func TestDrain(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.GracefulDrainTimeout = 10
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	cfg.Status.PauseToken = "secret"
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	checkStatus := func(code int, state string) {
+		resp, err := cli.fetchStatus("/status")
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+		require.Equal(t, code, resp.StatusCode)
+		var st status
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+		require.Equal(t, state, st.State)
+	}
+	canConnect := func() bool {
+		db, err := sql.Open("mysql", cli.getDSN())
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, db.Close())
+		}()
+		return db.Ping() == nil
+	}
+	postWithToken := func(method, path, token string) int {
+		req, err := http.NewRequest(method, cli.statusURL(path), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+	postStatus := func(path string) int {
+		return postWithToken(http.MethodPost, path, "secret")
+	}
+	checkStatus(http.StatusOK, serverStateAccepting)
+
+	// Draining requires the pause-token and POST.
+	require.Equal(t, http.StatusUnauthorized, postWithToken(http.MethodPost, "/drain", ""))
+	require.Equal(t, http.StatusUnauthorized, postWithToken(http.MethodPost, "/drain/cancel", "wrong"))
+	require.Equal(t, http.StatusBadRequest, postWithToken(http.MethodGet, "/drain", "secret"))
+	checkStatus(http.StatusOK, serverStateAccepting)
+
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+	_, err = conn.ExecContext(context.Background(), "begin")
+	require.NoError(t, err)
+
+	// The connection in a transaction is not idle, so draining times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, server.Drain(ctx), context.DeadlineExceeded)
+	// The draining server is still alive for the liveness probes.
+	checkStatus(http.StatusOK, serverStateDraining)
+	require.False(t, canConnect())
+	// The existing connection keeps being served.
+	_, err = conn.ExecContext(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	// Canceling wakes up the pending drain request.
+	codeCh := make(chan int, 1)
+	go func() {
+		codeCh <- postStatus("/drain")
+	}()
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, http.StatusOK, postStatus("/drain/cancel"))
+	require.Equal(t, http.StatusConflict, <-codeCh)
+	checkStatus(http.StatusOK, serverStateAccepting)
+	require.True(t, canConnect())
+	require.Equal(t, http.StatusBadRequest, postStatus("/drain/cancel"))
+
+	// Draining is done once the transaction finishes.
+	go func() {
+		codeCh <- postStatus("/drain")
+	}()
+	time.Sleep(200 * time.Millisecond)
+	_, err = conn.ExecContext(context.Background(), "commit")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, <-codeCh)
+	checkStatus(http.StatusOK, serverStateDraining)
+	require.Equal(t, http.StatusOK, postStatus("/drain/cancel"))
+	checkStatus(http.StatusOK, serverStateAccepting)
+}
+
+func TestPause(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	cfg.Status.PauseToken = "secret"
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	postWithToken := func(path, token string) int {
+		req, err := http.NewRequest(http.MethodPost, cli.statusURL(path), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+	connect := func() error {
+		db, err := sql.Open("mysql", cli.getDSN())
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, db.Close())
+		}()
+		return db.Ping()
+	}
+
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	require.Equal(t, http.StatusUnauthorized, postWithToken("/pause", ""))
+	require.Equal(t, http.StatusUnauthorized, postWithToken("/pause", "wrong"))
+	require.NoError(t, connect())
+
+	require.Equal(t, http.StatusOK, postWithToken("/pause", "secret"))
+	require.Equal(t, http.StatusBadRequest, postWithToken("/pause", "secret"))
+	resp, err := cli.fetchStatus("/status")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var st status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, serverStatePaused, st.State)
+
+	// New connections are rejected with ER_SERVER_SHUTDOWN.
+	err = connect()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1053")
+	// The existing connection keeps being served.
+	_, err = conn.ExecContext(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusUnauthorized, postWithToken("/resume", "wrong"))
+	require.Equal(t, http.StatusOK, postWithToken("/resume", "secret"))
+	require.Equal(t, http.StatusBadRequest, postWithToken("/resume", "secret"))
+	require.NoError(t, connect())
+
+	// The APIs are disabled without pause-token.
+	server.cfg.Status.PauseToken = ""
+	require.Equal(t, http.StatusForbidden, postWithToken("/pause", "secret"))
+}
+
+func TestConnectionsAPI(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	cfg.Status.AuthToken = "secret"
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+	var connID uint64
+	require.NoError(t, conn.QueryRowContext(context.Background(), "select connection_id()").Scan(&connID))
+
+	doWithToken := func(method, path string) *http.Response {
+		req, err := http.NewRequest(method, cli.statusURL(path), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp, err := cli.fetchStatus("/connections")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	fetchConn := func() *connectionInfo {
+		resp := doWithToken(http.MethodGet, "/connections")
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var conns []connectionInfo
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&conns))
+		for i := range conns {
+			if conns[i].ID == connID {
+				return &conns[i]
+			}
+		}
+		return nil
+	}
+	kill := func(id string, queryOnly bool) int {
+		resp := doWithToken(http.MethodPost, fmt.Sprintf("/connections/%s/kill?query_only=%v", id, queryOnly))
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+
+	info := fetchConn()
+	require.NotNil(t, info)
+	require.Equal(t, "root", info.User)
+	require.Equal(t, "test", info.DB)
+	require.Equal(t, "tcp", info.Transport)
+	require.Empty(t, info.TLSVersion)
+
+	// Killing the running statement keeps the connection.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := conn.ExecContext(context.Background(), "select sleep(10)")
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool {
+		info := fetchConn()
+		return info != nil && info.Info == "select sleep(10)"
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, http.StatusOK, kill(strconv.FormatUint(connID, 10), true))
+	select {
+	case <-errCh:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the statement is not killed")
+	}
+	_, err = conn.ExecContext(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusBadRequest, kill("abc", false))
+	require.Equal(t, http.StatusNotFound, kill(strconv.FormatUint(connID+1000, 10), false))
+
+	// Killing the connection closes it.
+	require.Equal(t, http.StatusOK, kill(strconv.FormatUint(connID, 10), false))
+	require.Eventually(t, func() bool {
+		return fetchConn() == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestConnectionsAPIAuthentication(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	// The APIs are not served to the clients which are not authenticated.
+	require.False(t, server.statusClientsAuthenticated())
+	for _, path := range []string{"/connections", "/failed-logins", "/user-connections", "/charset-mismatches"} {
+		resp, err := cli.fetchStatus(path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+	resp, err := cli.postStatus("/connections/1/kill", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// The client certificates are checked against verify-cn.
+	server.cfg.Status.TLS.CA = "ca.pem"
+	require.False(t, server.statusClientsAuthenticated())
+	server.cfg.Status.TLS.VerifyCN = []string{"tidb-client"}
+	require.True(t, server.statusClientsAuthenticated())
+}
+
+func TestRedactStatementText(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "", redactStatementText("", false))
+	require.Equal(t, "select sleep(10)", redactStatementText("select sleep(10)", false))
+	require.Equal(t, "select `sleep` ( ? )", redactStatementText("select sleep(10)", true))
+	require.Equal(t, "alter user {root@% password = ***}", redactStatementText("alter user root identified by 'pass'", false))
+	require.NotContains(t, redactStatementText("create user u identified by 'p4ss'", false), "p4ss")
+	require.NotContains(t, redactStatementText("set password = 'p4ss'; select 1", false), "p4ss")
+	// The statements which can't be parsed may have passwords as well.
+	require.Equal(t, "alter user `root` identified by ? `bogus`", redactStatementText("alter user root identified by 'pass' bogus", false))
+}
+
+func TestKillQueryWithGracePeriod(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.ReportStatus = false
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	db, err := sql.Open("mysql", cli.getDSN())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+	var connID uint64
+	require.NoError(t, conn.QueryRowContext(context.Background(), "select connection_id()").Scan(&connID))
+	waitRunning := func(sql string) {
+		require.Eventually(t, func() bool {
+			info, ok := server.GetProcessInfo(connID)
+			return ok && info.Info == sql
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// SLEEP stops at the soft kill and the statement returns its result.
+	type result struct {
+		value int
+		err   error
+	}
+	resultCh := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		var r result
+		r.err = conn.QueryRowContext(context.Background(), "select sleep(10)").Scan(&r.value)
+		resultCh <- r
+	}()
+	waitRunning("select sleep(10)")
+	_, err = db.Exec(fmt.Sprintf("kill tidb query %d wait 1", connID))
+	require.NoError(t, err)
+	r := <-resultCh
+	require.NoError(t, r.err)
+	require.Equal(t, 1, r.value)
+	require.Less(t, time.Since(start), 10*time.Second)
+
+	// The query which does not stop at the soft kill is killed after the grace period.
+	_, err = conn.ExecContext(context.Background(), "set @@cte_max_recursion_depth = 1000000000")
+	require.NoError(t, err)
+	longQuery := "with recursive c(n) as (select 1 union all select n + 1 from c where n < 1000000000) select count(*) from c"
+	errCh := make(chan error, 1)
+	go func() {
+		var count int
+		errCh <- conn.QueryRowContext(context.Background(), longQuery).Scan(&count)
+	}()
+	waitRunning(longQuery)
+	start = time.Now()
+	_, err = db.Exec(fmt.Sprintf("kill tidb query %d wait 1", connID))
+	require.NoError(t, err)
+	select {
+	case err = <-errCh:
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Query execution was interrupted")
+		require.GreaterOrEqual(t, time.Since(start), time.Second)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "the query is not killed after the grace period")
+	}
+	_, err = conn.ExecContext(context.Background(), "select 1")
+	require.NoError(t, err)
+}
+
 func TestPessimisticInsertSelectForUpdate(t *testing.T) {
 	t.Parallel()
 	ts, cleanup := createTidbTestSuite(t)
@@ -1290,6 +2228,74 @@ func TestPessimisticInsertSelectForUpdate(t *testing.T) {
 	require.Nil(t, rs) // should be no delay
 }
 
+func TestRollbackTxnReleasesPessimisticLock(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	openCtx := func(connID uint64) *TiDBContext {
+		qctx, err := ts.tidbdrv.OpenCtx(connID, 0, uint8(tmysql.DefaultCollationID), "test", nil)
+		require.NoError(t, err)
+		_, err = Execute(ctx, qctx, "use test")
+		require.NoError(t, err)
+		_, err = Execute(ctx, qctx, "set @@innodb_lock_wait_timeout = 1")
+		require.NoError(t, err)
+		return qctx
+	}
+	drain := func(rs ResultSet) {
+		req := rs.NewChunk(nil)
+		for {
+			require.NoError(t, rs.Next(ctx, req))
+			if req.NumRows() == 0 {
+				break
+			}
+		}
+		require.NoError(t, rs.Close())
+	}
+	lockRow := func(qctx *TiDBContext) error {
+		_, err := Execute(ctx, qctx, "begin pessimistic")
+		require.NoError(t, err)
+		rs, err := Execute(ctx, qctx, "select * from t_rollback where id = 1 for update")
+		if err != nil {
+			return err
+		}
+		req := rs.NewChunk(nil)
+		err = rs.Next(ctx, req)
+		require.NoError(t, rs.Close())
+		return err
+	}
+
+	qctx1 := openCtx(1)
+	defer qctx1.Close()
+	qctx2 := openCtx(2)
+	defer qctx2.Close()
+	_, err := Execute(ctx, qctx1, "create table t_rollback (id int primary key, v int)")
+	require.NoError(t, err)
+	_, err = Execute(ctx, qctx1, "insert into t_rollback values (1, 1)")
+	require.NoError(t, err)
+
+	// The prepared statement locks the row in a pessimistic transaction.
+	_, err = Execute(ctx, qctx1, "begin pessimistic")
+	require.NoError(t, err)
+	stmt, _, _, err := qctx1.Prepare("select * from t_rollback where id = ? for update")
+	require.NoError(t, err)
+	rs, err := stmt.Execute(ctx, []types.Datum{types.NewIntDatum(1)})
+	require.NoError(t, err)
+	drain(rs)
+	err = lockRow(qctx2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Lock wait timeout")
+	qctx2.RollbackTxn(ctx)
+
+	// The lock is released by rolling back through the driver.
+	qctx1.RollbackTxn(ctx)
+	require.False(t, qctx1.GetSessionVars().InTxn())
+	require.NoError(t, lockRow(qctx2))
+	qctx2.RollbackTxn(ctx)
+	require.NoError(t, stmt.Close())
+}
+
 type collectorWrapper struct {
 	reporter.TopSQLReporter
 }
@@ -1393,6 +2399,8 @@ func TestTopSQLCPUProfile(t *testing.T) {
 	// Check result of test case 1.
 	for _, ca := range cases1 {
 		checkFn(ca.sql, ca.planRegexp)
+		// The finished executions are observed along with the CPU time.
+		require.Greaterf(t, collector.GetExecStatsBySQL(ca.sql).ExecCount, uint64(0), "sql: %v", ca.sql)
 		ca.cancel()
 	}
 
@@ -1568,7 +2576,7 @@ func TestTopSQLAgent(t *testing.T) {
 	dbt.MustExec("set @@global.tidb_top_sql_report_interval_seconds=2;")
 	dbt.MustExec("set @@global.tidb_top_sql_max_statement_count=5;")
 
-	r := reporter.NewRemoteTopSQLReporter(reporter.NewGRPCReportClient(plancodec.DecodeNormalizedPlan))
+	r := reporter.NewRemoteTopSQLReporter(plancodec.DecodeNormalizedPlan)
 	tracecpu.GlobalSQLCPUProfiler.SetCollector(&collectorWrapper{r})
 
 	// TODO: change to ensure that the right sql statements are reported, not just counts
@@ -1796,3 +2804,160 @@ func TestLocalhostClientMapping(t *testing.T) {
 	err = dbSocket.Ping()
 	require.Errorf(t, err, "Connection successful without matching host for unix domain socket!")
 }
+
+func TestIPv6ClientHost(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not supported")
+	}
+	require.NoError(t, ln.Close())
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Host = "::1"
+	cfg.Port = cli.port
+	cfg.Status.ReportStatus = false
+
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	defer server.Close()
+
+	for _, addr := range []string{"[::1]", "[0:0:0:0:0:0:0:1]"} {
+		cli.runTests(t, func(config *mysql.Config) {
+			config.Addr = fmt.Sprintf("%s:%d", addr, cli.port)
+		}, func(dbt *testkit.DBTestKit) {
+			rows := dbt.MustQuery("select user()")
+			cli.checkRows(t, rows, "root@::1")
+			require.NoError(t, rows.Close())
+		})
+	}
+}
+
+func TestCompressedProtocol(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", ts.port))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+	brc := newBufferedReadConn(conn)
+	pkt := newPacketIO(brc)
+	data, err := pkt.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, byte(10), data[0])
+	// The lower 2 bytes of the capability follow the server version, the connection id and the salt.
+	pos := bytes.IndexByte(data, 0) + 1 + 4 + 8 + 1
+	require.NotZero(t, (uint32(data[pos])|uint32(data[pos+1])<<8)&tmysql.ClientCompress)
+
+	// Send the handshake response of root without password, and read the OK packet which is not compressed.
+	data = make([]byte, 4, 64)
+	data = dumpUint32(data, tmysql.ClientProtocol41|tmysql.ClientSecureConnection|tmysql.ClientPluginAuth|tmysql.ClientCompress)
+	data = dumpUint32(data, tmysql.MaxPayloadLen)
+	data = append(data, tmysql.DefaultCollationID)
+	data = append(data, make([]byte, 23)...)
+	data = append(data, "root"...)
+	data = append(data, 0, 0)
+	data = append(data, tmysql.AuthNativePassword...)
+	data = append(data, 0)
+	require.NoError(t, pkt.writePacket(data))
+	require.NoError(t, pkt.flush())
+	data, err = pkt.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, tmysql.OKHeader, data[0])
+
+	c := newCompressedConn(brc)
+	pkt.setBufferedReadConn(newBufferedReadConn(c))
+	pkt.compressedConn = c
+	for i := 0; i < 2; i++ {
+		pkt.resetSequence()
+		data = append(make([]byte, 4), tmysql.ComQuery)
+		data = append(data, "select repeat('a', 1000)"...)
+		require.NoError(t, pkt.writePacket(data))
+		require.NoError(t, pkt.flush())
+
+		// The column count, the column definition, EOF, the row and EOF.
+		var packets [][]byte
+		for len(packets) < 5 {
+			data, err = pkt.readPacket()
+			require.NoError(t, err)
+			packets = append(packets, data)
+		}
+		require.Equal(t, []byte{1}, packets[0])
+		require.Equal(t, tmysql.EOFHeader, packets[2][0])
+		require.Equal(t, dumpLengthEncodedString(nil, bytes.Repeat([]byte{'a'}, 1000)), packets[3])
+		require.Equal(t, tmysql.EOFHeader, packets[4][0])
+	}
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	ts, cleanup := createTidbTestSuite(t)
+	defer cleanup()
+
+	cli := newTestServerClient()
+	cfg := newTestConfig()
+	cfg.Port = 0
+	cfg.Status.StatusPort = 0
+	cfg.Status.ReportStatus = true
+	server, err := NewServer(cfg, ts.tidbdrv)
+	require.NoError(t, err)
+	// The bootstrap is not done before the domain is set.
+	require.Equal(t, health{Component: healthComponentBootstrap, Message: "the bootstrap is not done"}, server.checkHealth(context.Background()))
+	server.SetDomain(ts.domain)
+	cli.port = getPortFromTCPAddr(server.listener.Addr())
+	cli.statusPort = getPortFromTCPAddr(server.statusListener.Addr())
+	go func() {
+		err := server.Run()
+		require.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	checkHealth := func(code int, component string) {
+		resp, err := cli.fetchStatus("/health")
+		require.NoError(t, err)
+		require.Equal(t, code, resp.StatusCode)
+		var h health
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&h))
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, code == http.StatusOK, h.Healthy)
+		require.Equal(t, component, h.Component)
+	}
+	checkHealth(http.StatusOK, "")
+	resp, err := cli.fetchStatus("/status")
+	require.NoError(t, err)
+	var st status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+	require.NoError(t, resp.Body.Close())
+	require.True(t, st.Bootstrapped)
+	require.Equal(t, session.GetBootstrapVersion(), st.BootstrapVersion)
+
+	// The schema is not fresh if the schema validator is stopped, e.g. it cannot reload the schema.
+	ts.domain.SchemaValidator.Stop()
+	checkHealth(http.StatusServiceUnavailable, healthComponentSchema)
+	ts.domain.SchemaValidator.Restart()
+	checkHealth(http.StatusOK, "")
+
+	require.NoError(t, server.Pause())
+	checkHealth(http.StatusServiceUnavailable, healthComponentServer)
+	require.NoError(t, server.Resume())
+	checkHealth(http.StatusOK, "")
+
+	// It fails before the MySQL listener is closed during the shutdown.
+	server.startShutdown()
+	checkHealth(http.StatusServiceUnavailable, healthComponentServer)
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", cli.port))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+}
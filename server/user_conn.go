@@ -0,0 +1,193 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/util/dbterror"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var errTooManyUserConnections = dbterror.ClassServer.NewStd(errno.ErrTooManyUserConnections)
+
+const (
+	// maxUserMetricsLabels is the maximal number of the accounts labelled in the per-account metrics, the
+	// accounts logged in after the limit is reached are counted under userMetricsLabelOther.
+	maxUserMetricsLabels  = 100
+	userMetricsLabelOther = "other"
+)
+
+// acquireUserConn counts the connection for the logged-in account. It returns errTooManyUserConnections
+// if the account already has MAX_USER_CONNECTIONS connections on this instance. The connection counted
+// for the previous account, by COM_CHANGE_USER, is released first.
+func (cc *clientConn) acquireUserConn() error {
+	user := cc.ctx.GetSessionVars().User
+	if user == nil {
+		return nil
+	}
+	var limit int64
+	if pm := privilege.GetPrivilegeManager(cc.ctx.Session); pm != nil {
+		limit = pm.GetMaxUserConnections(user.AuthUsername, user.AuthHostname)
+	}
+	key := user.AuthUsername + "@" + user.AuthHostname
+
+	s := cc.server
+	s.rwlock.Lock()
+	defer s.rwlock.Unlock()
+	s.releaseUserConnLocked(cc)
+	if limit > 0 && int64(s.userConns[key]) >= limit {
+		logutil.BgLogger().Warn("too many connections of the account", zap.String("user", key),
+			zap.Int64("max user connections", limit), zap.Uint64("conn", cc.connectionID))
+		return errTooManyUserConnections.GenWithStackByArgs(user.AuthUsername)
+	}
+	if s.userConns == nil {
+		s.userConns = make(map[string]int)
+	}
+	s.userConns[key]++
+	cc.userConnKey = key
+	cc.userMetrics = s.userMetricsLocked(key)
+	// The bytes of the handshake are counted for the account.
+	cc.flushUserMetrics()
+	return nil
+}
+
+// releaseUserConnLocked releases the connection counted for its account, s.rwlock must be held.
+func (s *Server) releaseUserConnLocked(cc *clientConn) {
+	key := cc.userConnKey
+	if key == "" {
+		return
+	}
+	// cc.userMetrics is kept since the connection may be closed by another goroutine while counting.
+	cc.flushUserMetrics()
+	cc.userConnKey = ""
+	s.userConns[key]--
+	if s.userConns[key] <= 0 {
+		delete(s.userConns, key)
+	}
+}
+
+// userMetrics is the metrics of an account, whose label values are resolved once since they are
+// updated for every command.
+type userMetrics struct {
+	label        string
+	readBytes    prometheus.Counter
+	writtenBytes prometheus.Counter
+	// commands maps the command types to the counters, which are resolved on the first use.
+	commands sync.Map
+}
+
+func newUserMetrics(label string) *userMetrics {
+	return &userMetrics{
+		label:        label,
+		readBytes:    metrics.UserTrafficBytesCounter.WithLabelValues(label, "read"),
+		writtenBytes: metrics.UserTrafficBytesCounter.WithLabelValues(label, "write"),
+	}
+}
+
+func (m *userMetrics) commandCounter(cmd byte) prometheus.Counter {
+	if counter, ok := m.commands.Load(cmd); ok {
+		return counter.(prometheus.Counter)
+	}
+	name, ok := mysql.Command2Str[cmd]
+	if !ok {
+		name = strconv.Itoa(int(cmd))
+	}
+	counter, _ := m.commands.LoadOrStore(cmd, metrics.UserCommandCounter.WithLabelValues(m.label, name))
+	return counter.(prometheus.Counter)
+}
+
+// userMetricsLocked returns the metrics of the account, s.rwlock must be held. The labels are kept
+// after the connections of the account are closed, so that the counters are not reset.
+func (s *Server) userMetricsLocked(key string) *userMetrics {
+	if m, ok := s.userMetrics[key]; ok {
+		return m
+	}
+	if len(s.userMetrics) >= maxUserMetricsLabels {
+		if s.otherUserMetrics == nil {
+			s.otherUserMetrics = newUserMetrics(userMetricsLabelOther)
+		}
+		return s.otherUserMetrics
+	}
+	if s.userMetrics == nil {
+		s.userMetrics = make(map[string]*userMetrics)
+	}
+	m := newUserMetrics(key)
+	s.userMetrics[key] = m
+	return m
+}
+
+// countUserCommand counts the dispatched command and the bytes of its packets for the account.
+func (cc *clientConn) countUserCommand(cmd byte) {
+	if cc.userMetrics == nil {
+		return
+	}
+	cc.userMetrics.commandCounter(cmd).Inc()
+	cc.flushUserMetrics()
+}
+
+// flushUserMetrics adds the bytes of the packets counted by the packetIO since the last flush to the
+// metrics of the account.
+func (cc *clientConn) flushUserMetrics() {
+	if cc.pkt == nil {
+		return
+	}
+	read := atomic.SwapUint64(&cc.pkt.readBytes, 0)
+	written := atomic.SwapUint64(&cc.pkt.writtenBytes, 0)
+	if cc.userMetrics == nil {
+		return
+	}
+	if read > 0 {
+		cc.userMetrics.readBytes.Add(float64(read))
+	}
+	if written > 0 {
+		cc.userMetrics.writtenBytes.Add(float64(written))
+	}
+}
+
+// UserConnInfo is the number of the connections of an account on this instance.
+type UserConnInfo struct {
+	User  string `json:"user"`
+	Count int    `json:"count"`
+}
+
+// UserConnections returns the number of the connections of each account that is connected to this
+// instance, sorted by the account.
+func (s *Server) UserConnections() []UserConnInfo {
+	s.rwlock.RLock()
+	infos := make([]UserConnInfo, 0, len(s.userConns))
+	for key, count := range s.userConns {
+		infos = append(infos, UserConnInfo{User: key, Count: count})
+	}
+	s.rwlock.RUnlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].User < infos[j].User })
+	return infos
+}
+
+// userConnCount returns the number of the connections of the account on this instance.
+func (s *Server) userConnCount(user, host string) int {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+	return s.userConns[user+"@"+host]
+}
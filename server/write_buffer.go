@@ -0,0 +1,265 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/dbterror"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+var errWriteBufferQuotaExceeded = dbterror.ClassServer.NewStd(errno.ErrWriteBufferQuotaExceeded)
+
+var (
+	writeBufferEvictFlush = metrics.WriteBufferEvictCounter.WithLabelValues("flush")
+	writeBufferEvictAbort = metrics.WriteBufferEvictCounter.WithLabelValues("abort")
+)
+
+// connWriteBuffers tracks the write buffers of all the connections of this instance.
+var connWriteBuffers = newWriteBufferTracker()
+
+// writeBufferTracker accounts the memory held by the write buffers and the spooled cursor rows of
+// all the connections. When the total exceeds the limit, the connections holding the most memory
+// are picked as victims, one by one, until the rest fits in the limit. A victim flushes its write
+// buffer synchronously on its next write, and aborts the statement if it still holds spooled rows.
+// The writes only update the counters atomically, the lock is taken to pick the victims, which is
+// done again only after the total grows by another writeBufferPickStep of the limit.
+type writeBufferTracker struct {
+	// total, limit and nextPick are accessed atomically.
+	total int64
+	limit int64 // 0 means unlimited.
+	// nextPick is the total from which the victims are picked again, it is reset when the total
+	// falls back under the limit.
+	nextPick int64
+
+	mu      sync.Mutex
+	buffers map[*connWriteBuffer]struct{}
+}
+
+// writeBufferPickStep is the fraction of the limit the total grows by before the victims are picked again.
+const writeBufferPickStep = 64
+
+func newWriteBufferTracker() *writeBufferTracker {
+	return &writeBufferTracker{buffers: make(map[*connWriteBuffer]struct{})}
+}
+
+// setLimit sets the limit of the total memory, 0 means unlimited. The connections registered while
+// the memory is unlimited are not tracked.
+func (t *writeBufferTracker) setLimit(limit int64) {
+	atomic.StoreInt64(&t.limit, limit)
+}
+
+// register tracks the memory held by the connection, it returns nil if the memory is unlimited.
+func (t *writeBufferTracker) register(connID uint64) *connWriteBuffer {
+	if atomic.LoadInt64(&t.limit) <= 0 {
+		return nil
+	}
+	b := &connWriteBuffer{tracker: t, connID: connID, spooled: make(map[ResultSet]int64)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buffers[b] = struct{}{}
+	return b
+}
+
+// totalBytes returns the memory held by all the connections.
+func (t *writeBufferTracker) totalBytes() int64 {
+	return atomic.LoadInt64(&t.total)
+}
+
+// add updates the total memory, it returns whether the victims need to be picked.
+func (t *writeBufferTracker) add(delta int64) bool {
+	if delta == 0 {
+		return false
+	}
+	total := atomic.AddInt64(&t.total, delta)
+	metrics.WriteBufferMemoryGauge.Add(float64(delta))
+	limit := atomic.LoadInt64(&t.limit)
+	if limit <= 0 {
+		return false
+	}
+	if delta < 0 {
+		if total <= limit && atomic.LoadInt64(&t.nextPick) != 0 {
+			atomic.StoreInt64(&t.nextPick, 0)
+		}
+		return false
+	}
+	return total > limit && total >= atomic.LoadInt64(&t.nextPick)
+}
+
+// pickVictimsLocked marks the connections holding the most memory as victims until the memory held
+// by the others fits in the limit. The memory of the existing victims is regarded as released, so a
+// burst of writes doesn't evict more connections than needed. Ties are broken by the connection ID.
+func (t *writeBufferTracker) pickVictimsLocked() {
+	total, limit := atomic.LoadInt64(&t.total), atomic.LoadInt64(&t.limit)
+	// Another connection may have picked the victims for the same growth.
+	if limit <= 0 || total <= limit || total < atomic.LoadInt64(&t.nextPick) {
+		return
+	}
+	atomic.StoreInt64(&t.nextPick, total+limit/writeBufferPickStep+1)
+	type candidate struct {
+		b    *connWriteBuffer
+		held int64
+	}
+	remaining := total
+	candidates := make([]candidate, 0, len(t.buffers))
+	for b := range t.buffers {
+		held := b.held()
+		if b.isVictim() {
+			remaining -= held
+			continue
+		}
+		if held > 0 {
+			candidates = append(candidates, candidate{b, held})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].held != candidates[j].held {
+			return candidates[i].held > candidates[j].held
+		}
+		return candidates[i].b.connID < candidates[j].b.connID
+	})
+	for _, c := range candidates {
+		if remaining <= limit {
+			break
+		}
+		atomic.StoreUint32(&c.b.victim, 1)
+		remaining -= c.held
+		logutil.BgLogger().Warn("the write buffers of all the connections exceed the quota, evict the largest one",
+			zap.Uint64("conn", c.b.connID), zap.Int64("held", c.held), zap.Int64("total", total), zap.Int64("quota", limit))
+	}
+}
+
+// connWriteBuffer is the memory held by the write buffer and the spooled cursor rows of a connection.
+// The methods are called by the goroutine of the connection, and they are no-op on a nil receiver.
+type connWriteBuffer struct {
+	tracker *writeBufferTracker
+	connID  uint64
+	// victim is set when the connection is picked to release its memory.
+	victim uint32
+
+	// buffered and spoolTotal are accessed atomically, they are read by the other connections
+	// picking the victims.
+	buffered   int64
+	spoolTotal int64
+	// spooled is protected by tracker.mu.
+	spooled map[ResultSet]int64
+}
+
+func (b *connWriteBuffer) held() int64 {
+	return atomic.LoadInt64(&b.buffered) + atomic.LoadInt64(&b.spoolTotal)
+}
+
+func (b *connWriteBuffer) isVictim() bool {
+	return b != nil && atomic.LoadUint32(&b.victim) == 1
+}
+
+// setBuffered updates the bytes held by the write buffer.
+func (b *connWriteBuffer) setBuffered(buffered int) {
+	if b == nil {
+		return
+	}
+	old := atomic.SwapInt64(&b.buffered, int64(buffered))
+	if b.tracker.add(int64(buffered) - old) {
+		b.tracker.mu.Lock()
+		b.tracker.pickVictimsLocked()
+		b.tracker.mu.Unlock()
+	}
+	if buffered == 0 && atomic.LoadInt64(&b.spoolTotal) == 0 && b.isVictim() {
+		atomic.StoreUint32(&b.victim, 0)
+	}
+}
+
+// spooledBytes returns the bytes of the rows spooled for the cursor of rs.
+func (b *connWriteBuffer) spooledBytes(rs ResultSet) int64 {
+	if b == nil {
+		return 0
+	}
+	b.tracker.mu.Lock()
+	defer b.tracker.mu.Unlock()
+	return b.spooled[rs]
+}
+
+// spool updates the bytes of the rows spooled for the cursor of rs. If the connection is a victim,
+// the spooled rows of rs are released and errWriteBufferQuotaExceeded is returned.
+func (b *connWriteBuffer) spool(rs ResultSet, bytes int64) error {
+	if b == nil {
+		return nil
+	}
+	b.tracker.mu.Lock()
+	defer b.tracker.mu.Unlock()
+	b.setSpooledLocked(rs, bytes)
+	if atomic.LoadUint32(&b.victim) == 0 || bytes == 0 {
+		return nil
+	}
+	held, limit := b.held(), atomic.LoadInt64(&b.tracker.limit)
+	b.setSpooledLocked(rs, 0)
+	if b.held() == 0 {
+		atomic.StoreUint32(&b.victim, 0)
+	}
+	writeBufferEvictAbort.Inc()
+	return errWriteBufferQuotaExceeded.GenWithStackByArgs(b.connID, held, limit)
+}
+
+// releaseSpool releases the rows spooled for the cursor of rs.
+func (b *connWriteBuffer) releaseSpool(rs ResultSet) {
+	if b == nil || rs == nil {
+		return
+	}
+	b.tracker.mu.Lock()
+	defer b.tracker.mu.Unlock()
+	b.setSpooledLocked(rs, 0)
+}
+
+func (b *connWriteBuffer) setSpooledLocked(rs ResultSet, bytes int64) {
+	delta := bytes - b.spooled[rs]
+	if bytes == 0 {
+		delete(b.spooled, rs)
+	} else {
+		b.spooled[rs] = bytes
+	}
+	atomic.AddInt64(&b.spoolTotal, delta)
+	if b.tracker.add(delta) {
+		b.tracker.pickVictimsLocked()
+	}
+}
+
+// unregister releases all the memory held by the connection.
+func (b *connWriteBuffer) unregister() {
+	if b == nil {
+		return
+	}
+	b.tracker.mu.Lock()
+	defer b.tracker.mu.Unlock()
+	b.spooled = make(map[ResultSet]int64)
+	delete(b.tracker.buffers, b)
+	b.tracker.add(-atomic.SwapInt64(&b.buffered, 0) - atomic.SwapInt64(&b.spoolTotal, 0))
+}
+
+// spoolRowBytes returns the bytes of the rows spooled for a cursor.
+func spoolRowBytes(rows []chunk.Row) (bytes int64) {
+	for _, row := range rows {
+		for i := 0; i < row.Len(); i++ {
+			bytes += int64(len(row.GetRaw(i)))
+		}
+	}
+	return bytes
+}
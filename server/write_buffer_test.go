@@ -0,0 +1,179 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBufferVictims(t *testing.T) {
+	t.Parallel()
+
+	tracker := newWriteBufferTracker()
+	tracker.setLimit(500)
+	b1, b2, b3, b4 := tracker.register(1), tracker.register(2), tracker.register(3), tracker.register(4)
+	rs1, rs2, rs3, rs4 := &tidbResultSet{}, &tidbResultSet{}, &tidbResultSet{}, &tidbResultSet{}
+	require.NoError(t, b1.spool(rs1, 100))
+	require.NoError(t, b2.spool(rs2, 300))
+	require.NoError(t, b3.spool(rs3, 200))
+	require.Equal(t, int64(600), tracker.totalBytes())
+	// The largest connection is the only victim.
+	require.False(t, b1.isVictim())
+	require.True(t, b2.isVictim())
+	require.False(t, b3.isVictim())
+
+	// A new burst doesn't pick more victims when the memory held by the others fits in the limit.
+	require.NoError(t, b4.spool(rs4, 150))
+	require.False(t, b3.isVictim())
+	require.False(t, b4.isVictim())
+
+	err := b2.spool(rs2, 350)
+	require.True(t, errWriteBufferQuotaExceeded.Equal(err))
+	require.False(t, b2.isVictim())
+	require.Equal(t, int64(450), tracker.totalBytes())
+
+	// Ties are broken by the connection ID.
+	b1.setBuffered(100)
+	require.Equal(t, int64(550), tracker.totalBytes())
+	require.True(t, b1.isVictim())
+	require.False(t, b3.isVictim())
+
+	for _, b := range []*connWriteBuffer{b1, b2, b3, b4} {
+		b.unregister()
+	}
+	require.Equal(t, int64(0), tracker.totalBytes())
+
+	// A nil buffer is no-op.
+	var nilBuffer *connWriteBuffer
+	require.NoError(t, nilBuffer.spool(rs1, 100))
+	nilBuffer.setBuffered(100)
+	nilBuffer.releaseSpool(rs1)
+	nilBuffer.unregister()
+}
+
+func TestWriteBufferPickStep(t *testing.T) {
+	t.Parallel()
+
+	// The connections are not tracked if the memory is unlimited.
+	tracker := newWriteBufferTracker()
+	require.Nil(t, tracker.register(1))
+
+	tracker.setLimit(640)
+	b1, b2 := tracker.register(1), tracker.register(2)
+	b1.setBuffered(600)
+	b2.setBuffered(100)
+	require.True(t, b1.isVictim())
+	require.False(t, b2.isVictim())
+	require.Equal(t, int64(711), tracker.nextPick)
+
+	// The victims are not picked again until the total grows by a step of the limit.
+	b2.setBuffered(105)
+	require.Equal(t, int64(711), tracker.nextPick)
+	b2.setBuffered(120)
+	require.Equal(t, int64(731), tracker.nextPick)
+
+	// The step is reset when the total falls back under the limit.
+	b1.setBuffered(0)
+	require.False(t, b1.isVictim())
+	require.Equal(t, int64(0), tracker.nextPick)
+	b1.setBuffered(530)
+	require.Equal(t, int64(650), tracker.totalBytes())
+	require.True(t, b1.isVictim())
+	b1.unregister()
+	b2.unregister()
+	require.Equal(t, int64(0), tracker.totalBytes())
+}
+
+func TestWriteBufferFlushVictim(t *testing.T) {
+	t.Parallel()
+
+	tracker := newWriteBufferTracker()
+	tracker.setLimit(10)
+	var outBuffer bytes.Buffer
+	pkt := &packetIO{bufWriter: bufio.NewWriter(&outBuffer), writeBuffer: tracker.register(1)}
+	require.NoError(t, pkt.writePacket([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03}))
+	require.Equal(t, int64(7), tracker.totalBytes())
+	require.Equal(t, 0, outBuffer.Len())
+
+	// The victim flushes its buffer synchronously.
+	require.NoError(t, pkt.writePacket([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03}))
+	require.Equal(t, 14, outBuffer.Len())
+	require.Equal(t, int64(0), tracker.totalBytes())
+	require.False(t, pkt.writeBuffer.isVictim())
+
+	require.NoError(t, pkt.writePacket([]byte{0x00, 0x00, 0x00, 0x00, 0x01}))
+	require.Equal(t, int64(5), tracker.totalBytes())
+	require.NoError(t, pkt.flush())
+	require.Equal(t, int64(0), tracker.totalBytes())
+	pkt.writeBuffer.unregister()
+}
+
+func TestWriteBufferConcurrentStreams(t *testing.T) {
+	t.Parallel()
+
+	const (
+		conns     = 32
+		chunkSize = 1024
+		limit     = 2 * conns * chunkSize
+	)
+	tracker := newWriteBufferTracker()
+	tracker.setLimit(limit)
+
+	var wg, streamed sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, conns)
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		streamed.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := tracker.register(uint64(i + 1))
+			defer b.unregister()
+			rs := &tidbResultSet{}
+			<-start
+			// Connection i streams a result of (i+1) chunks and keeps all of them in the spool
+			// until all the connections finish streaming.
+			for n := 1; n <= i+1; n++ {
+				if errs[i] = b.spool(rs, int64(n*chunkSize)); errs[i] != nil {
+					break
+				}
+			}
+			streamed.Done()
+			streamed.Wait()
+			b.releaseSpool(rs)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	aborted := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		aborted++
+		require.True(t, errWriteBufferQuotaExceeded.Equal(err))
+		// A connection is only picked when it holds at least limit/conns, the small ones never abort.
+		require.GreaterOrEqual(t, (i+1)*chunkSize, limit/conns)
+	}
+	require.Greater(t, aborted, 0)
+	require.Less(t, aborted, conns)
+	require.Equal(t, int64(0), tracker.totalBytes())
+}
@@ -91,6 +91,11 @@ const (
 		Create_Tablespace_Priv  ENUM('N','Y') NOT NULL DEFAULT 'N',
 		Repl_slave_priv	    	ENUM('N','Y') NOT NULL DEFAULT 'N',
 		Repl_client_priv		ENUM('N','Y') NOT NULL DEFAULT 'N',
+		User_attributes			JSON,
+		Password_expired		ENUM('N','Y') NOT NULL DEFAULT 'N',
+		max_user_connections	INT UNSIGNED NOT NULL DEFAULT 0,
+		Password_last_changed	TIMESTAMP DEFAULT CURRENT_TIMESTAMP(),
+		Password_lifetime		SMALLINT UNSIGNED DEFAULT NULL,
 		PRIMARY KEY (Host, User));`
 	// CreateGlobalPrivTable is the SQL statement creates Global scope privilege table in system db.
 	CreateGlobalPrivTable = "CREATE TABLE IF NOT EXISTS mysql.global_priv (" +
@@ -365,6 +370,17 @@ const (
 		oldReadLease bigint(20) NOT NULL DEFAULT 0,
 		PRIMARY KEY (tid)
 	);`
+	// CreateProxiesPrivTable stores the accounts which are allowed to act as other accounts.
+	CreateProxiesPrivTable = `CREATE TABLE IF NOT EXISTS mysql.proxies_priv (
+		Host 			CHAR(255) NOT NULL DEFAULT '',
+		User 			CHAR(32) NOT NULL DEFAULT '',
+		Proxied_host 	CHAR(255) NOT NULL DEFAULT '',
+		Proxied_user 	CHAR(32) NOT NULL DEFAULT '',
+		With_grant 		ENUM('N','Y') NOT NULL DEFAULT 'N',
+		Grantor 		VARCHAR(288) NOT NULL DEFAULT '',
+		Timestamp 		TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		PRIMARY KEY (Host, User, Proxied_host, Proxied_user)
+	);`
 )
 
 // bootstrap initiates system DB for a store.
@@ -538,11 +554,26 @@ const (
 	version78 = 78
 	// version79 adds the mysql.table_cache_meta table
 	version79 = 79
+	// version80 adds the User_attributes column to mysql.user
+	version80 = 80
+	// version81 adds the Password_expired column to mysql.user
+	version81 = 81
+	// version82 adds the max_user_connections column to mysql.user
+	version82 = 82
+	// version83 adds the mysql.proxies_priv table
+	version83 = 83
+	// version84 adds the Password_last_changed and Password_lifetime columns to mysql.user
+	version84 = 84
 )
 
 // currentBootstrapVersion is defined as a variable, so we can modify its value for testing.
 // please make sure this is the largest version
-var currentBootstrapVersion int64 = version79
+var currentBootstrapVersion int64 = version84
+
+// GetBootstrapVersion returns the version of the system tables that BootstrapSession upgrades to.
+func GetBootstrapVersion() int64 {
+	return currentBootstrapVersion
+}
 
 var (
 	bootstrapVersion = []func(Session, int64){
@@ -625,6 +656,11 @@ var (
 		upgradeToVer77,
 		upgradeToVer78,
 		upgradeToVer79,
+		upgradeToVer80,
+		upgradeToVer81,
+		upgradeToVer82,
+		upgradeToVer83,
+		upgradeToVer84,
 	}
 )
 
@@ -1630,6 +1666,43 @@ func upgradeToVer79(s Session, ver int64) {
 	doReentrantDDL(s, CreateTableCacheMetaTable)
 }
 
+func upgradeToVer80(s Session, ver int64) {
+	if ver >= version80 {
+		return
+	}
+	doReentrantDDL(s, "ALTER TABLE mysql.user ADD COLUMN `User_attributes` JSON AFTER `Repl_client_priv`", infoschema.ErrColumnExists)
+}
+
+func upgradeToVer81(s Session, ver int64) {
+	if ver >= version81 {
+		return
+	}
+	doReentrantDDL(s, "ALTER TABLE mysql.user ADD COLUMN `Password_expired` ENUM('N','Y') NOT NULL DEFAULT 'N' AFTER `User_attributes`", infoschema.ErrColumnExists)
+}
+
+func upgradeToVer82(s Session, ver int64) {
+	if ver >= version82 {
+		return
+	}
+	doReentrantDDL(s, "ALTER TABLE mysql.user ADD COLUMN `max_user_connections` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `Password_expired`", infoschema.ErrColumnExists)
+}
+
+func upgradeToVer83(s Session, ver int64) {
+	if ver >= version83 {
+		return
+	}
+	doReentrantDDL(s, CreateProxiesPrivTable)
+}
+
+func upgradeToVer84(s Session, ver int64) {
+	if ver >= version84 {
+		return
+	}
+	// The existing passwords are regarded as changed at the upgrade.
+	doReentrantDDL(s, "ALTER TABLE mysql.user ADD COLUMN `Password_last_changed` TIMESTAMP DEFAULT CURRENT_TIMESTAMP() AFTER `max_user_connections`", infoschema.ErrColumnExists)
+	doReentrantDDL(s, "ALTER TABLE mysql.user ADD COLUMN `Password_lifetime` SMALLINT UNSIGNED DEFAULT NULL AFTER `Password_last_changed`", infoschema.ErrColumnExists)
+}
+
 func writeOOMAction(s Session) {
 	comment := "oom-action is `log` by default in v3.0.x, `cancel` by default in v4.0.11+"
 	mustExecute(s, `INSERT HIGH_PRIORITY INTO %n.%n VALUES (%?, %?, %?) ON DUPLICATE KEY UPDATE VARIABLE_VALUE= %?`,
@@ -1714,6 +1787,8 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateColumnStatsUsageTable)
 	// Create table_cache_meta table.
 	mustExecute(s, CreateTableCacheMetaTable)
+	// Create proxies_priv table.
+	mustExecute(s, CreateProxiesPrivTable)
 }
 
 // doDMLWorks executes DML statements in bootstrap stage.
@@ -1730,10 +1805,10 @@ func doDMLWorks(s Session) {
 			logutil.BgLogger().Fatal("failed to read current user. unable to secure bootstrap.", zap.Error(err))
 		}
 		mustExecute(s, `INSERT HIGH_PRIORITY INTO mysql.user VALUES
-		("localhost", "root", %?, "auth_socket", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "N", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`, u.Username)
+		("localhost", "root", %?, "auth_socket", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "N", "Y", "Y", "Y", "Y", "Y", "Y", "Y", null, "N", 0, CURRENT_TIMESTAMP(), null)`, u.Username)
 	} else {
 		mustExecute(s, `INSERT HIGH_PRIORITY INTO mysql.user VALUES
-		("%", "root", "", "mysql_native_password", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "N", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "mysql_native_password", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "N", "Y", "Y", "Y", "Y", "Y", "Y", "Y", null, "N", 0, CURRENT_TIMESTAMP(), null)`)
 	}
 
 	// Init global system variables table.
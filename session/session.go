@@ -144,6 +144,9 @@ type Session interface {
 	Close()
 	Auth(user *auth.UserIdentity, auth []byte, salt []byte) bool
 	AuthWithoutVerification(user *auth.UserIdentity) bool
+	// AuthWithPlugin is like Auth, but the credentials of the account of an external auth plugin are
+	// verified by authenticate.
+	AuthWithPlugin(user *auth.UserIdentity, authenticate func() bool) bool
 	AuthPluginForUser(user *auth.UserIdentity) (string, error)
 	MatchIdentity(username, remoteHost string) (*auth.UserIdentity, error)
 	ShowProcess() *util.ProcessInfo
@@ -318,7 +321,7 @@ func (s *session) cleanRetryInfo() {
 			if i > 0 && preparedAst != nil {
 				plannercore.SetPstmtIDSchemaVersion(cacheKey, stmtID, preparedAst.SchemaVersion, s.sessionVars.IsolationReadEngines)
 			}
-			s.PreparedPlanCache().Delete(cacheKey)
+			plannercore.DeletePreparedPlanCache(s, cacheKey)
 		}
 		s.sessionVars.RemovePreparedStmt(stmtID)
 	}
@@ -1190,6 +1193,20 @@ func (s *session) ParseSQL(ctx context.Context, sql string, params ...parser.Par
 	return res, warn, err
 }
 
+// tablesInUse returns the tables used by the running statement and the current transaction.
+func (s *session) tablesInUse(command byte) []stmtctx.TableEntry {
+	var tables []stmtctx.TableEntry
+	if command != mysql.ComSleep {
+		tables = append(tables, s.sessionVars.StmtCtx.Tables...)
+	}
+	if s.sessionVars.InTxn() {
+		for table := range s.sessionVars.TxnCtx.AccessedTables {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
 func (s *session) SetProcessInfo(sql string, t time.Time, command byte, maxExecutionTime uint64) {
 	// If command == mysql.ComSleep, it means the SQL execution is finished. The processinfo is reset to SLEEP.
 	// If the SQL finished and the session is not in transaction, the current start timestamp need to reset to 0.
@@ -1226,6 +1243,10 @@ func (s *session) SetProcessInfo(sql string, t time.Time, command byte, maxExecu
 		StatsInfo:        plannercore.GetStatsInfo,
 		MaxExecutionTime: maxExecutionTime,
 		RedactSQL:        s.sessionVars.EnableRedactLog,
+		Transport:        s.sessionVars.ConnectionTransport,
+		TLSState:         s.sessionVars.TLSConnectionState,
+		ConnectionAttrs:  s.sessionVars.ConnectionAttrs,
+		TablesInUse:      s.tablesInUse(command),
 	}
 	oldPi := s.ShowProcess()
 	if p == nil {
@@ -1243,6 +1264,17 @@ func (s *session) SetProcessInfo(sql string, t time.Time, command byte, maxExecu
 	}
 	_, digest := s.sessionVars.StmtCtx.SQLDigest()
 	pi.Digest = digest.String()
+	if p != nil {
+		normalizedPlan, planDigest := s.sessionVars.StmtCtx.GetPlanDigest()
+		if planDigest == nil {
+			normalizedPlan, planDigest = plannercore.NormalizePlan(p)
+			// Cache the plan digest so that the slow log and statement summary can reuse it.
+			if p == s.currentPlan {
+				s.sessionVars.StmtCtx.SetPlanDigest(normalizedPlan, planDigest)
+			}
+		}
+		pi.PlanDigest = planDigest.String()
+	}
 	// DO NOT reset the currentPlan to nil until this query finishes execution, otherwise reentrant calls
 	// of SetProcessInfo would override Plan and PlanExplainRows to nil.
 	if command == mysql.ComSleep {
@@ -1252,6 +1284,9 @@ func (s *session) SetProcessInfo(sql string, t time.Time, command byte, maxExecu
 		pi.User = s.sessionVars.User.Username
 		pi.Host = s.sessionVars.User.Hostname
 	}
+	if s.sessionVars.ProxiedUser != nil {
+		pi.ProxyUser = s.sessionVars.ProxiedUser.String()
+	}
 	s.processInfo.Store(&pi)
 }
 
@@ -2204,6 +2239,7 @@ func (s *session) Close() {
 	if s.sessionVars != nil {
 		s.sessionVars.WithdrawAllPreparedStmt()
 	}
+	plannercore.ReleasePreparedPlanCache(s.preparedPlanCache)
 	s.ClearDiskFullOpt()
 }
 
@@ -2259,6 +2295,23 @@ func (s *session) MatchIdentity(username, remoteHost string) (*auth.UserIdentity
 	return nil, fmt.Errorf("could not find matching user in MatchIdentity: %s, %s", username, remoteHost)
 }
 
+// AuthWithPlugin implements the Session interface.
+func (s *session) AuthWithPlugin(user *auth.UserIdentity, authenticate func() bool) bool {
+	pm := privilege.GetPrivilegeManager(s)
+	authUser, err := s.MatchIdentity(user.Username, user.Hostname)
+	if err != nil {
+		return false
+	}
+	if pm.PluginConnectionVerification(authUser.Username, authUser.Hostname, authenticate, s.sessionVars.TLSConnectionState) {
+		user.AuthUsername = authUser.Username
+		user.AuthHostname = authUser.Hostname
+		s.sessionVars.User = user
+		s.sessionVars.ActiveRoles = pm.GetDefaultRoles(user.AuthUsername, user.AuthHostname)
+		return true
+	}
+	return false
+}
+
 // AuthWithoutVerification is required by the ResetConnection RPC
 func (s *session) AuthWithoutVerification(user *auth.UserIdentity) bool {
 	pm := privilege.GetPrivilegeManager(s)
@@ -2428,6 +2481,11 @@ func BootstrapSession(store kv.Storage) (*domain.Domain, error) {
 		return nil, err
 	}
 	timeutil.SetSystemTZ(tz)
+	if detected := timeutil.InferSystemTZ(); detected != tz {
+		logutil.BgLogger().Warn("the system time zone of the host differs from the one stored in mysql.tidb, "+
+			"TiDB keeps using the stored one, run ADMIN RELOAD SYSTEM_TZ FORCE to switch to the host's time zone",
+			zap.String("stored", tz), zap.String("detected", detected))
+	}
 
 	// get the flag from `mysql`.`tidb` which indicating if new collations are enabled.
 	newCollationEnabled, err := loadCollationParameter(se)
@@ -2592,7 +2650,7 @@ func createSessionWithOpt(store kv.Storage, opt *Opt) (*session, error) {
 		if opt != nil && opt.PreparedPlanCache != nil {
 			s.preparedPlanCache = opt.PreparedPlanCache
 		} else {
-			s.preparedPlanCache = kvcache.NewSimpleLRUCache(plannercore.PreparedPlanCacheCapacity,
+			s.preparedPlanCache = plannercore.NewPreparedPlanCache(plannercore.PreparedPlanCacheCapacity,
 				plannercore.PreparedPlanCacheMemoryGuardRatio, plannercore.PreparedPlanCacheMaxMemory.Load())
 		}
 	}
@@ -2622,7 +2680,7 @@ func CreateSessionWithDomain(store kv.Storage, dom *domain.Domain) (*session, er
 		builtinFunctionUsage: make(telemetry.BuiltinFunctionsUsage),
 	}
 	if plannercore.PreparedPlanCacheEnabled() {
-		s.preparedPlanCache = kvcache.NewSimpleLRUCache(plannercore.PreparedPlanCacheCapacity,
+		s.preparedPlanCache = plannercore.NewPreparedPlanCache(plannercore.PreparedPlanCacheCapacity,
 			plannercore.PreparedPlanCacheMemoryGuardRatio, plannercore.PreparedPlanCacheMaxMemory.Load())
 	}
 	s.mu.values = make(map[fmt.Stringer]interface{})
@@ -2836,7 +2894,7 @@ func logStmt(execStmt *executor.ExecStmt, s *session) {
 	case *ast.CreateUserStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.SetPwdStmt, *ast.GrantStmt,
 		*ast.RevokeStmt, *ast.AlterTableStmt, *ast.CreateDatabaseStmt, *ast.CreateIndexStmt, *ast.CreateTableStmt,
 		*ast.DropDatabaseStmt, *ast.DropIndexStmt, *ast.DropTableStmt, *ast.RenameTableStmt, *ast.TruncateTableStmt,
-		*ast.RenameUserStmt:
+		*ast.RenameUserStmt, *ast.GrantProxyStmt:
 		user := vars.User
 		schemaVersion := s.GetInfoSchema().SchemaMetaVersion()
 		if ss, ok := execStmt.StmtNode.(ast.SensitiveStmtNode); ok {
@@ -2860,18 +2918,37 @@ func logStmt(execStmt *executor.ExecStmt, s *session) {
 
 func logGeneralQuery(execStmt *executor.ExecStmt, s *session, isPrepared bool) {
 	vars := s.GetSessionVars()
-	if variable.ProcessGeneralLog.Load() && !vars.InRestrictedSQL {
-		var query string
-		if isPrepared {
-			query = execStmt.OriginText()
-		} else {
-			query = execStmt.GetTextToLog()
-		}
+	if vars.InRestrictedSQL {
+		return
+	}
+	processGeneralLog := variable.ProcessGeneralLog.Load()
+	// The general query logger is nil if it is disabled, so the query is not formatted if both logs are disabled.
+	generalQueryLogger := logutil.GeneralQueryLogger()
+	if !processGeneralLog && generalQueryLogger == nil {
+		return
+	}
+	var query string
+	if isPrepared {
+		query = execStmt.OriginText()
+	} else {
+		query = execStmt.GetTextToLog()
+	}
 
-		query = executor.QueryReplacer.Replace(query)
-		if !vars.EnableRedactLog {
-			query += vars.PreparedParams.String()
-		}
+	query = executor.QueryReplacer.Replace(query)
+	if !vars.EnableRedactLog {
+		query += vars.PreparedParams.String()
+	}
+	query = logutil.EscapeBinaryBytes(query)
+	if generalQueryLogger != nil {
+		_, digest := vars.StmtCtx.SQLDigest()
+		generalQueryLogger.Info("GENERAL_LOG",
+			zap.Uint64("conn", vars.ConnectionID),
+			zap.Stringer("user", vars.User),
+			zap.String("digest", digest.String()),
+			zap.String("current_db", vars.CurrentDB),
+			zap.String("sql", query))
+	}
+	if processGeneralLog {
 		logutil.BgLogger().Info("GENERAL_LOG",
 			zap.Uint64("conn", vars.ConnectionID),
 			zap.Stringer("user", vars.User),
@@ -16,6 +16,8 @@ package session
 
 import (
 	"context"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 
@@ -23,6 +25,7 @@ import (
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -104,3 +107,53 @@ func TestKeysNeedLock(t *testing.T) {
 	require.True(t, flag.HasPresumeKeyNotExists())
 	require.True(t, keyNeedToLock(indexKey, deleteVal, flag))
 }
+
+func TestGeneralLogTarget(t *testing.T) {
+	store, dom := createStoreAndBootstrap(t)
+	defer func() { require.NoError(t, store.Close()) }()
+	defer dom.Close()
+
+	se, err := createSession(store)
+	require.NoError(t, err)
+	ctx := context.Background()
+	mustExec := func(sql string) {
+		rs, err := se.Execute(ctx, sql)
+		require.NoError(t, err)
+		for _, r := range rs {
+			require.NoError(t, r.Close())
+		}
+	}
+
+	// The STDERR target writes to the os.Stderr when it is set, redirect it to a file.
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	require.NoError(t, err)
+	origStderr := os.Stderr
+	os.Stderr = stderr
+	mustExec("set tidb_general_log_target = STDERR")
+	os.Stderr = origStderr
+	defer func() {
+		require.NoError(t, logutil.SetGeneralLogTarget(logutil.GeneralLogTargetOff))
+	}()
+
+	mustExec("select 12345")
+	// The literals are redacted with tidb_redact_log.
+	mustExec("set tidb_redact_log = 1")
+	mustExec("select 67890")
+	mustExec("set tidb_redact_log = 0")
+	mustExec("set tidb_general_log_target = OFF")
+	mustExec("select 24680")
+	require.NoError(t, stderr.Close())
+
+	data, err := os.ReadFile(stderr.Name())
+	require.NoError(t, err)
+	content := string(data)
+	require.Contains(t, content, `"message":"GENERAL_LOG"`)
+	require.Contains(t, content, `"sql":"select 12345"`)
+	require.Contains(t, content, `"sql":"select ?"`)
+	require.NotContains(t, content, "67890")
+	require.NotContains(t, content, "24680")
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		require.Contains(t, line, `"digest":`)
+		require.Contains(t, line, `"conn":`)
+	}
+}
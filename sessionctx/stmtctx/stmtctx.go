@@ -153,6 +153,9 @@ type StatementContext struct {
 		normalized string
 		digest     *parser.Digest
 	}
+	// SoftKilled is set by "KILL TIDB QUERY connectionID WAIT seconds", the statement should stop
+	// as soon as possible and return the partial result.
+	SoftKilled atomic2.Bool
 	// planNormalized use for cache the normalized plan, avoid duplicate builds.
 	planNormalized        string
 	planDigest            *parser.Digest
@@ -0,0 +1,84 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variable
+
+import (
+	"container/list"
+	"strings"
+
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+)
+
+// maxOpenTables is the maximal number of the recently accessed tables kept by a session.
+const maxOpenTables = 128
+
+// openTableCache is a LRU list of the tables recently accessed by a session, it is shown by SHOW OPEN TABLES.
+// TiDB has no table cache like MySQL, so the recently accessed tables are regarded as the open ones.
+type openTableCache struct {
+	// tables is ordered from the most recently accessed table to the least recently accessed one.
+	tables   *list.List
+	elements map[stmtctx.TableEntry]*list.Element
+}
+
+func (c *openTableCache) add(table stmtctx.TableEntry) {
+	if c.tables == nil {
+		c.tables = list.New()
+		c.elements = make(map[stmtctx.TableEntry]*list.Element)
+	}
+	if element, ok := c.elements[table]; ok {
+		c.tables.MoveToFront(element)
+		return
+	}
+	c.elements[table] = c.tables.PushFront(table)
+	if c.tables.Len() > maxOpenTables {
+		oldest := c.tables.Back()
+		c.tables.Remove(oldest)
+		delete(c.elements, oldest.Value.(stmtctx.TableEntry))
+	}
+}
+
+func (c *openTableCache) list() []stmtctx.TableEntry {
+	if c.tables == nil {
+		return nil
+	}
+	tables := make([]stmtctx.TableEntry, 0, c.tables.Len())
+	for element := c.tables.Front(); element != nil; element = element.Next() {
+		tables = append(tables, element.Value.(stmtctx.TableEntry))
+	}
+	return tables
+}
+
+// AddOpenTables records the tables accessed by a statement, they are also recorded in the transaction context
+// if the session is in a transaction.
+func (s *SessionVars) AddOpenTables(tables []stmtctx.TableEntry) {
+	for _, table := range tables {
+		if table.DB == "" || table.Table == "" {
+			continue
+		}
+		table = stmtctx.TableEntry{DB: strings.ToLower(table.DB), Table: strings.ToLower(table.Table)}
+		s.openTables.add(table)
+		if s.InTxn() {
+			if s.TxnCtx.AccessedTables == nil {
+				s.TxnCtx.AccessedTables = make(map[stmtctx.TableEntry]struct{})
+			}
+			s.TxnCtx.AccessedTables[table] = struct{}{}
+		}
+	}
+}
+
+// OpenTables returns the tables recently accessed by the session, from the most recently accessed one.
+func (s *SessionVars) OpenTables() []stmtctx.TableEntry {
+	return s.openTables.list()
+}
@@ -180,6 +180,10 @@ type TransactionContext struct {
 	// TemporaryTables is used to store transaction-specific information for global temporary tables.
 	// It can also be stored in sessionCtx with local temporary tables, but it's easier to clean this data after transaction ends.
 	TemporaryTables map[int64]tableutil.TempTable
+
+	// AccessedTables are the tables accessed by the statements of the transaction, SHOW OPEN TABLES
+	// regards them as in use until the transaction ends.
+	AccessedTables map[stmtctx.TableEntry]struct{}
 }
 
 // GetShard returns the shard prefix for the next `count` rowids.
@@ -496,6 +500,20 @@ type SessionVars struct {
 	// TLSConnectionState is the TLS connection state (nil if not using TLS).
 	TLSConnectionState *tls.ConnectionState
 
+	// ConnectionTransport is how the client connects to the server, such as util.TransportSocket.
+	// It is empty if the session is not created for a client connection.
+	ConnectionTransport string
+
+	// ConnectionAttrs are the connection attributes sent by the client in the handshake, such as _client_name.
+	ConnectionAttrs map[string]string
+
+	// openTables are the tables recently accessed by the session, see AddOpenTables.
+	openTables openTableCache
+
+	// OutfileBytesWritten is the bytes written by the running or the last SELECT INTO OUTFILE of the session,
+	// it is accessed atomically.
+	OutfileBytesWritten int64
+
 	// ConnectionID is the connection id of the current session.
 	ConnectionID uint64
 
@@ -508,6 +526,10 @@ type SessionVars struct {
 	// User is the user identity with which the session login.
 	User *auth.UserIdentity
 
+	// ProxiedUser is the account whose privileges the session acts with through the PROXY
+	// privilege of User, it is nil if the session is not proxied.
+	ProxiedUser *auth.UserIdentity
+
 	// Port is the port of the connected socket
 	Port string
 
@@ -939,6 +961,9 @@ type SessionVars struct {
 	// RegardNULLAsPoint if regard NULL as Point
 	RegardNULLAsPoint bool
 
+	// IdleTransactionTimeout is the max seconds that an open transaction waits for the next statement, 0 means no limit.
+	IdleTransactionTimeout int
+
 	// LocalTemporaryTables is *infoschema.LocalTemporaryTables, use interface to avoid circle dependency.
 	// It's nil if there is no local temporary table.
 	LocalTemporaryTables interface{}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pingcap/tidb/util"
 )
@@ -121,19 +122,13 @@ var tlsCiphers = []uint16{
 
 var tlsSupportedCiphers string
 
-// Taken from https://github.com/openssl/openssl/blob/c784a838e0947fcca761ee62def7d077dc06d37f/include/openssl/ssl.h#L141 .
-var tlsVersionString = map[uint16]string{
-	tls.VersionTLS10: "TLSv1",
-	tls.VersionTLS11: "TLSv1.1",
-	tls.VersionTLS12: "TLSv1.2",
-	tls.VersionTLS13: "TLSv1.3",
-}
-
 var defaultStatus = map[string]*StatusVal{
 	"Ssl_cipher":      {ScopeGlobal | ScopeSession, ""},
 	"Ssl_cipher_list": {ScopeGlobal | ScopeSession, ""},
 	"Ssl_verify_mode": {ScopeGlobal | ScopeSession, 0},
 	"Ssl_version":     {ScopeGlobal | ScopeSession, ""},
+
+	"Outfile_bytes_written": {ScopeSession, 0},
 }
 
 type defaultStatusStat struct {
@@ -151,12 +146,15 @@ func (s defaultStatusStat) Stats(vars *SessionVars) (map[string]interface{}, err
 	}
 
 	// `vars` may be nil in unit tests.
+	if vars != nil {
+		statusVars["Outfile_bytes_written"] = atomic.LoadInt64(&vars.OutfileBytesWritten)
+	}
 	if vars != nil && vars.TLSConnectionState != nil {
 		statusVars["Ssl_cipher"] = util.TLSCipher2String(vars.TLSConnectionState.CipherSuite)
 		statusVars["Ssl_cipher_list"] = tlsSupportedCiphers
 		// tls.VerifyClientCertIfGiven == SSL_VERIFY_PEER | SSL_VERIFY_CLIENT_ONCE
 		statusVars["Ssl_verify_mode"] = 0x01 | 0x04
-		if tlsVersion, tlsVersionKnown := tlsVersionString[vars.TLSConnectionState.Version]; tlsVersionKnown {
+		if tlsVersion := util.TLSVersion2String(vars.TLSConnectionState.Version); tlsVersion != "" {
 			statusVars["Ssl_version"] = tlsVersion
 		} else {
 			statusVars["Ssl_version"] = "unknown_tls_version"
@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/collate"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/stmtsummary"
@@ -522,7 +523,27 @@ var defaultSysVars = []*SysVar{
 		}
 		return string(info), nil
 	}},
-	{Scope: ScopeGlobal | ScopeSession, Name: TiDBMaxChunkSize, Value: strconv.Itoa(DefMaxChunkSize), Type: TypeUnsigned, MinValue: maxChunkSizeLowerBound, MaxValue: math.MaxInt32, SetSession: func(s *SessionVars, val string) error {
+	{Scope: ScopeSession, Name: TiDBConnectionType, Value: "", ReadOnly: true, skipInit: true, GetSession: func(s *SessionVars) (string, error) {
+		switch s.ConnectionTransport {
+		case util.TransportSocket:
+			return "UNIX", nil
+		case util.TransportTLS:
+			return "TLS", nil
+		case util.TransportTCP:
+			return "TCP", nil
+		}
+		return "", nil
+	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBMaxChunkSize, Value: strconv.Itoa(DefMaxChunkSize), Type: TypeUnsigned, MinValue: maxChunkSizeLowerBound, MaxValue: maxChunkSizeUpperBound, Validation: func(vars *SessionVars, normalizedValue string, originalValue string, scope ScopeFlag) (string, error) {
+		// Reject the out of range values rather than truncating them, a large chunk size may cause OOM.
+		if strings.EqualFold(originalValue, "DEFAULT") {
+			return normalizedValue, nil
+		}
+		if val, err := strconv.ParseInt(originalValue, 10, 64); err != nil || val < maxChunkSizeLowerBound || val > maxChunkSizeUpperBound {
+			return normalizedValue, ErrWrongValueForVar.GenWithStackByArgs(TiDBMaxChunkSize, originalValue)
+		}
+		return normalizedValue, nil
+	}, SetSession: func(s *SessionVars, val string) error {
 		s.MaxChunkSize = tidbOptPositiveInt32(val, DefMaxChunkSize)
 		return nil
 	}},
@@ -778,6 +799,11 @@ var defaultSysVars = []*SysVar{
 	}, GetSession: func(s *SessionVars) (string, error) {
 		return BoolToOnOff(ProcessGeneralLog.Load()), nil
 	}},
+	{Scope: ScopeSession, Name: TiDBGeneralLogTarget, Value: logutil.GeneralLogTargetOff, Type: TypeEnum, PossibleValues: []string{logutil.GeneralLogTargetOff, logutil.GeneralLogTargetFile, logutil.GeneralLogTargetStderr}, skipInit: true, SetSession: func(s *SessionVars, val string) error {
+		return logutil.SetGeneralLogTarget(val)
+	}, GetSession: func(s *SessionVars) (string, error) {
+		return logutil.GetGeneralLogTarget(), nil
+	}},
 	{Scope: ScopeSession, Name: TiDBLogFileMaxDays, Value: strconv.Itoa(config.GetGlobalConfig().Log.File.MaxDays), Type: TypeInt, MinValue: 0, MaxValue: math.MaxInt32, skipInit: true, SetSession: func(s *SessionVars, val string) error {
 		maxAge, err := strconv.ParseInt(val, 10, 32)
 		if err != nil {
@@ -1296,7 +1322,59 @@ var defaultSysVars = []*SysVar{
 		TopSQLVariable.ReportIntervalSeconds.Store(val)
 		return nil
 	}},
+	{Scope: ScopeGlobal, Name: TiDBTopSQLEvictionPolicy, Value: DefTiDBTopSQLEvictionPolicy, Type: TypeEnum, Hidden: true, PossibleValues: []string{TopSQLEvictionPolicyLRU, TopSQLEvictionPolicyLFU}, GetGlobal: func(s *SessionVars) (string, error) {
+		return TopSQLVariable.EvictionPolicy.Load(), nil
+	}, SetGlobal: func(vars *SessionVars, s string) error {
+		TopSQLVariable.EvictionPolicy.Store(s)
+		return nil
+	}},
+	{Scope: ScopeGlobal, Name: TiDBTopSQLTopNMetric, Value: DefTiDBTopSQLTopNMetric, Type: TypeEnum, Hidden: true, PossibleValues: []string{TopSQLTopNMetricCPU, TopSQLTopNMetricLatency}, GetGlobal: func(s *SessionVars) (string, error) {
+		return TopSQLVariable.TopNMetric.Load(), nil
+	}, SetGlobal: func(vars *SessionVars, s string) error {
+		TopSQLVariable.TopNMetric.Store(s)
+		return nil
+	}},
+	{Scope: ScopeGlobal, Name: TiDBTopSQLMaxPendingReports, Value: strconv.Itoa(DefTiDBTopSQLMaxPendingReports), Type: TypeInt, Hidden: true, MinValue: 1, MaxValue: 1000, GetGlobal: func(s *SessionVars) (string, error) {
+		return strconv.FormatInt(TopSQLVariable.MaxPendingReports.Load(), 10), nil
+	}, SetGlobal: func(vars *SessionVars, s string) error {
+		val, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		TopSQLVariable.MaxPendingReports.Store(val)
+		return nil
+	}},
+	{Scope: ScopeGlobal, Name: TiDBTopSQLIncludeInternal, Value: BoolToOnOff(DefTiDBTopSQLIncludeInternal), Type: TypeBool, Hidden: true, GetGlobal: func(s *SessionVars) (string, error) {
+		return BoolToOnOff(TopSQLVariable.IncludeInternal.Load()), nil
+	}, SetGlobal: func(vars *SessionVars, s string) error {
+		TopSQLVariable.IncludeInternal.Store(TiDBOptOn(s))
+		return nil
+	}},
 	{Scope: ScopeGlobal, Name: SkipNameResolve, Value: Off, Type: TypeBool},
+	{Scope: ScopeGlobal, Name: RequireSecureTransport, Value: BoolToOnOff(config.GetGlobalConfig().Security.RequireSecureTransport), Type: TypeBool, GetGlobal: func(s *SessionVars) (string, error) {
+		return BoolToOnOff(config.GetGlobalConfig().Security.RequireSecureTransport), nil
+	}, Validation: func(vars *SessionVars, normalizedValue string, originalValue string, scope ScopeFlag) (string, error) {
+		// Requiring a secure transport locks out all the new connections if the instance has none.
+		if TiDBOptOn(normalizedValue) && GetSysVar("have_ssl").Value != "YES" && config.GetGlobalConfig().Socket == "" {
+			return normalizedValue, errNoSecureTransports.GenWithStackByArgs()
+		}
+		return normalizedValue, nil
+	}, SetGlobal: func(s *SessionVars, val string) error {
+		SetRequireSecureTransport(TiDBOptOn(val))
+		return nil
+	}},
+	{Scope: ScopeGlobal, Name: TiDBMaxServerConnections, Value: strconv.FormatUint(uint64(config.GetGlobalConfig().MaxServerConnections), 10), Type: TypeUnsigned, MinValue: 0, MaxValue: math.MaxUint32, GetGlobal: func(s *SessionVars) (string, error) {
+		return strconv.FormatUint(uint64(config.GetGlobalConfig().MaxServerConnections), 10), nil
+	}, SetGlobal: func(s *SessionVars, val string) error {
+		SetMaxServerConnections(uint32(tidbOptInt64(val, 0)))
+		return nil
+	}},
+	{Scope: ScopeGlobal, Name: TiDBMaxConnectionsPerIP, Value: strconv.FormatUint(uint64(config.GetGlobalConfig().MaxConnectionsPerIP), 10), Type: TypeUnsigned, MinValue: 0, MaxValue: math.MaxUint32, GetGlobal: func(s *SessionVars) (string, error) {
+		return strconv.FormatUint(uint64(config.GetGlobalConfig().MaxConnectionsPerIP), 10), nil
+	}, SetGlobal: func(s *SessionVars, val string) error {
+		SetMaxConnectionsPerIP(uint32(tidbOptInt64(val, 0)))
+		return nil
+	}},
 	{Scope: ScopeGlobal, Name: DefaultAuthPlugin, Value: mysql.AuthNativePassword, Type: TypeEnum, PossibleValues: []string{mysql.AuthNativePassword, mysql.AuthCachingSha2Password}},
 	{Scope: ScopeGlobal | ScopeSession, Name: TiDBEnableOrderedResultMode, Value: BoolToOnOff(DefTiDBEnableOrderedResultMode), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
 		s.EnableStableResultMode = TiDBOptOn(val)
@@ -1310,6 +1388,10 @@ var defaultSysVars = []*SysVar{
 		s.RegardNULLAsPoint = TiDBOptOn(val)
 		return nil
 	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBIdleTransactionTimeout, Value: strconv.Itoa(DefTiDBIdleTransactionTimeout), Type: TypeUnsigned, MinValue: 0, MaxValue: secondsPerYear, SetSession: func(s *SessionVars, val string) error {
+		s.IdleTransactionTimeout = tidbOptPositiveInt32(val, DefTiDBIdleTransactionTimeout)
+		return nil
+	}},
 
 	{Scope: ScopeNone, Name: "version_compile_os", Value: runtime.GOOS},
 	{Scope: ScopeNone, Name: "version_compile_machine", Value: runtime.GOARCH},
@@ -1422,6 +1504,8 @@ const (
 	MaxConnections = "max_connections"
 	// SkipNameResolve is the name for 'skip_name_resolve' system variable.
 	SkipNameResolve = "skip_name_resolve"
+	// RequireSecureTransport is the name for 'require_secure_transport' system variable.
+	RequireSecureTransport = "require_secure_transport"
 	// ForeignKeyChecks is the name for 'foreign_key_checks' system variable.
 	ForeignKeyChecks = "foreign_key_checks"
 	// PlacementChecks is the name for 'placement_checks' system variable.
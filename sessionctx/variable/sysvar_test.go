@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/parser/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -561,6 +562,14 @@ func TestInstanceScopedVars(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, string(info), val)
 
+	val, err = GetSessionOrGlobalSystemVar(vars, TiDBConnectionType)
+	require.NoError(t, err)
+	require.Equal(t, "", val)
+	vars.ConnectionTransport = util.TransportSocket
+	val, err = GetSessionOrGlobalSystemVar(vars, TiDBConnectionType)
+	require.NoError(t, err)
+	require.Equal(t, "UNIX", val)
+
 	val, err = GetSessionOrGlobalSystemVar(vars, TiDBGeneralLog)
 	require.NoError(t, err)
 	require.Equal(t, BoolToOnOff(ProcessGeneralLog.Load()), val)
@@ -699,6 +708,28 @@ func TestSecureAuth(t *testing.T) {
 	require.Equal(t, "ON", val)
 }
 
+func TestRequireSecureTransport(t *testing.T) {
+	defer config.RestoreFunc()()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Socket = ""
+	})
+	sv := GetSysVar(RequireSecureTransport)
+	vars := NewSessionVars()
+	_, err := sv.Validate(vars, "ON", ScopeGlobal)
+	require.EqualError(t, err, "[variable:3161]No secure transports (SSL or Shared Memory) are configured, unable to set --require_secure_transport=ON.")
+	val, err := sv.Validate(vars, "OFF", ScopeGlobal)
+	require.NoError(t, err)
+	require.Equal(t, Off, val)
+
+	// The unix socket is a secure transport.
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Socket = "/tmp/tidb.sock"
+	})
+	val, err = sv.Validate(vars, "ON", ScopeGlobal)
+	require.NoError(t, err)
+	require.Equal(t, On, val)
+}
+
 func TestValidateWithRelaxedValidation(t *testing.T) {
 	sv := GetSysVar(SecureAuth)
 	vars := NewSessionVars()
@@ -85,6 +85,10 @@ const (
 	// TiDBLastTxnInfo is used to get the last query info within the current session.
 	TiDBLastQueryInfo = "tidb_last_query_info"
 
+	// TiDBConnectionType is used to get how the client of the current session connects to the server,
+	// one of "TCP", "UNIX" and "TLS". It is read-only.
+	TiDBConnectionType = "tidb_connection_type"
+
 	// tidb_config is a read-only variable that shows the config of the current server.
 	TiDBConfig = "tidb_config"
 
@@ -121,6 +125,10 @@ const (
 	// tidb_general_log is used to log every query in the server in info level.
 	TiDBGeneralLog = "tidb_general_log"
 
+	// tidb_general_log_target is used to write every query in the server to a dedicated structured log,
+	// which is OFF, FILE or STDERR.
+	TiDBGeneralLogTarget = "tidb_general_log_target"
+
 	// tidb_general_log is used to log every query in the server in info level.
 	TiDBLogFileMaxDays = "tidb_log_file_max_days"
 
@@ -583,6 +591,18 @@ const (
 
 	// TiDBTopSQLReportIntervalSeconds indicates the top SQL report interval seconds.
 	TiDBTopSQLReportIntervalSeconds = "tidb_top_sql_report_interval_seconds"
+
+	// TiDBTopSQLEvictionPolicy indicates the eviction policy of the registered SQL cache of top SQL.
+	TiDBTopSQLEvictionPolicy = "tidb_top_sql_eviction_policy"
+
+	// TiDBTopSQLTopNMetric indicates the metric the top N statements of top SQL are selected by.
+	TiDBTopSQLTopNMetric = "tidb_top_sql_top_n_metric"
+
+	// TiDBTopSQLMaxPendingReports indicates the max number of the top SQL reports kept for each receiver while it is failing.
+	TiDBTopSQLMaxPendingReports = "tidb_top_sql_max_pending_reports"
+
+	// TiDBTopSQLIncludeInternal indicates whether the internal SQLs are collected and reported by top SQL.
+	TiDBTopSQLIncludeInternal = "tidb_top_sql_include_internal"
 	// TiDBEnableGlobalTemporaryTable indicates whether to enable global temporary table
 	TiDBEnableGlobalTemporaryTable = "tidb_enable_global_temporary_table"
 	// TiDBEnableLocalTxn indicates whether to enable Local Txn.
@@ -603,6 +623,10 @@ const (
 
 	// TiDBTmpTableMaxSize indicates the max memory size of temporary tables.
 	TiDBTmpTableMaxSize = "tidb_tmp_table_max_size"
+
+	// TiDBIdleTransactionTimeout is the max seconds that an open transaction waits for the next statement.
+	// The connection is killed and the transaction is rolled back when it is exceeded. 0 means no limit.
+	TiDBIdleTransactionTimeout = "tidb_idle_transaction_timeout"
 )
 
 // TiDB vars that have only global scope
@@ -620,6 +644,12 @@ const (
 	TiDBGCScanLockMode = "tidb_gc_scan_lock_mode"
 	// TiDBEnableEnhancedSecurity restricts SUPER users from certain operations.
 	TiDBEnableEnhancedSecurity = "tidb_enable_enhanced_security"
+	// TiDBMaxServerConnections is the maximum number of simultaneous client connections of a TiDB server.
+	// It overrides the max-server-connections configuration. 0 means no limit.
+	TiDBMaxServerConnections = "tidb_max_server_connections"
+	// TiDBMaxConnectionsPerIP is the maximum number of simultaneous client connections from the same IP.
+	// It overrides the max-connections-per-ip configuration. 0 means no limit.
+	TiDBMaxConnectionsPerIP = "tidb_max_connections_per_ip"
 )
 
 // TiDB intentional limits
@@ -768,6 +798,10 @@ const (
 	DefTiDBTopSQLMaxStatementCount        = 200
 	DefTiDBTopSQLMaxCollect               = 5000
 	DefTiDBTopSQLReportIntervalSeconds    = 60
+	DefTiDBTopSQLEvictionPolicy           = TopSQLEvictionPolicyLFU
+	DefTiDBTopSQLTopNMetric               = TopSQLTopNMetricCPU
+	DefTiDBTopSQLMaxPendingReports        = 10
+	DefTiDBTopSQLIncludeInternal          = true
 	DefTiDBTmpTableMaxSize                = 64 << 20 // 64MB.
 	DefTiDBEnableLocalTxn                 = false
 	DefTiDBTSOClientBatchMaxWaitTime      = 0.0 // 0ms
@@ -775,6 +809,7 @@ const (
 	DefTiDBEnableOrderedResultMode        = false
 	DefTiDBEnablePseudoForOutdatedStats   = true
 	DefTiDBRegardNULLAsPoint              = true
+	DefTiDBIdleTransactionTimeout         = 0
 	DefEnablePlacementCheck               = true
 	DefTimestamp                          = "0"
 )
@@ -807,6 +842,10 @@ var (
 		MaxStatementCount:     atomic.NewInt64(DefTiDBTopSQLMaxStatementCount),
 		MaxCollect:            atomic.NewInt64(DefTiDBTopSQLMaxCollect),
 		ReportIntervalSeconds: atomic.NewInt64(DefTiDBTopSQLReportIntervalSeconds),
+		EvictionPolicy:        atomic.NewString(DefTiDBTopSQLEvictionPolicy),
+		TopNMetric:            atomic.NewString(DefTiDBTopSQLTopNMetric),
+		MaxPendingReports:     atomic.NewInt64(DefTiDBTopSQLMaxPendingReports),
+		IncludeInternal:       atomic.NewBool(DefTiDBTopSQLIncludeInternal),
 	}
 	EnableLocalTxn          = atomic.NewBool(DefTiDBEnableLocalTxn)
 	MaxTSOBatchWaitInterval = atomic.NewFloat64(DefTiDBTSOClientBatchMaxWaitTime)
@@ -826,9 +865,34 @@ type TopSQL struct {
 	MaxCollect *atomic.Int64
 	// The report data interval of top-sql.
 	ReportIntervalSeconds *atomic.Int64
+	// The eviction policy of the registered SQL cache, which is TopSQLEvictionPolicyLRU or TopSQLEvictionPolicyLFU.
+	EvictionPolicy *atomic.String
+	// The metric the top N statements are selected by, which is TopSQLTopNMetricCPU or TopSQLTopNMetricLatency.
+	TopNMetric *atomic.String
+	// The max number of the reports kept for each receiver while it is failing, the oldest ones are dropped first.
+	MaxPendingReports *atomic.Int64
+	// Whether the internal SQLs, such as the ones of the statistics and DDL jobs, are collected and reported.
+	IncludeInternal *atomic.Bool
 }
 
-// TopSQLEnabled uses to check whether enabled the top SQL feature.
+const (
+	// TopSQLEvictionPolicyLRU evicts the least recently registered SQL from the registered SQL cache.
+	TopSQLEvictionPolicyLRU = "lru"
+	// TopSQLEvictionPolicyLFU evicts the SQL with the least cumulative CPU time from the registered SQL cache.
+	TopSQLEvictionPolicyLFU = "lfu"
+
+	// TopSQLTopNMetricCPU selects the top N statements by the CPU time.
+	TopSQLTopNMetricCPU = "cpu"
+	// TopSQLTopNMetricLatency selects the top N statements by the total execution time.
+	TopSQLTopNMetricLatency = "latency"
+)
+
+// TopSQLEnabled uses to check whether enabled the top SQL feature, the data is sent to the receiver address,
+// or written to the report directory if the receiver address is empty.
 func TopSQLEnabled() bool {
-	return TopSQLVariable.Enable.Load() && config.GetGlobalConfig().TopSQL.ReceiverAddress != ""
+	if !TopSQLVariable.Enable.Load() {
+		return false
+	}
+	cfg := config.GetGlobalConfig().TopSQL
+	return cfg.ReceiverAddress != "" || cfg.ReportDir != ""
 }
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/charset"
 	"github.com/pingcap/tidb/parser/mysql"
@@ -88,6 +89,39 @@ func GetMaxDeltaSchemaCount() int64 {
 	return atomic.LoadInt64(&maxDeltaSchemaCount)
 }
 
+// SetRequireSecureTransport sets whether the connections must use TLS or unix socket. It only affects
+// the connections established afterwards.
+func SetRequireSecureTransport(require bool) {
+	if config.GetGlobalConfig().Security.RequireSecureTransport == require {
+		return
+	}
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security.RequireSecureTransport = require
+	})
+}
+
+// SetMaxServerConnections sets the maximum number of simultaneous client connections. It only affects
+// the connections accepted afterwards.
+func SetMaxServerConnections(max uint32) {
+	if config.GetGlobalConfig().MaxServerConnections == max {
+		return
+	}
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.MaxServerConnections = max
+	})
+}
+
+// SetMaxConnectionsPerIP sets the maximum number of simultaneous client connections from the same IP.
+// It only affects the connections accepted afterwards.
+func SetMaxConnectionsPerIP(max uint32) {
+	if config.GetGlobalConfig().MaxConnectionsPerIP == max {
+		return
+	}
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.MaxConnectionsPerIP = max
+	})
+}
+
 // BoolToOnOff returns the string representation of a bool, i.e. "ON/OFF"
 func BoolToOnOff(b bool) string {
 	if b {
@@ -270,7 +304,9 @@ const (
 	// initChunkSizeUpperBound indicates upper bound value of tidb_init_chunk_size.
 	initChunkSizeUpperBound = 32
 	// maxChunkSizeLowerBound indicates lower bound value of tidb_max_chunk_size.
-	maxChunkSizeLowerBound = 32
+	maxChunkSizeLowerBound = config.MaxChunkSizeLowerBound
+	// maxChunkSizeUpperBound indicates upper bound value of tidb_max_chunk_size.
+	maxChunkSizeUpperBound = config.MaxChunkSizeUpperBound
 )
 
 // appendDeprecationWarning adds a warning that the item is deprecated.
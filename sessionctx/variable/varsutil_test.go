@@ -239,7 +239,8 @@ func TestVarsutil(t *testing.T) {
 	require.Equal(t, 32, v.InitChunkSize)
 	require.Equal(t, 1024, v.MaxChunkSize)
 	err = SetSessionSystemVar(v, TiDBMaxChunkSize, "2")
-	require.NoError(t, err) // converts to min value
+	require.Error(t, err) // out of range
+	require.Equal(t, 1024, v.MaxChunkSize)
 	err = SetSessionSystemVar(v, TiDBInitChunkSize, "1024")
 	require.NoError(t, err) // converts to max value
 
@@ -569,7 +570,12 @@ func TestValidate(t *testing.T) {
 		{TiDBInitChunkSize, "a", true},
 		{TiDBInitChunkSize, "-1", false},
 		{TiDBMaxChunkSize, "a", true},
-		{TiDBMaxChunkSize, "-1", false},
+		{TiDBMaxChunkSize, "-1", true},
+		{TiDBMaxChunkSize, "31", true},
+		{TiDBMaxChunkSize, "32", false},
+		{TiDBMaxChunkSize, "65536", false},
+		{TiDBMaxChunkSize, "65537", true},
+		{TiDBMaxChunkSize, "DEFAULT", false},
 		{TiDBOptJoinReorderThreshold, "a", true},
 		{TiDBOptJoinReorderThreshold, "-1", false},
 		{TiDBReplicaRead, "invalid", true},
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/expression"
@@ -170,8 +171,9 @@ func truncateTrailingSpaces(v *types.Datum) {
 	v.SetString(str, v.Collation())
 }
 
-func handleWrongCharsetValue(ctx sessionctx.Context, col *model.ColumnInfo, str string, i int) error {
-	sc := ctx.GetSessionVars().StmtCtx
+// wrongCharsetValueSnippet returns the bytes since the invalid character at position i like MySQL, the ones
+// out of ASCII are hex-escaped.
+func wrongCharsetValueSnippet(str string, i int) string {
 	var strval strings.Builder
 	for j := 0; j < 6; j++ {
 		if len(str) > (i + j) {
@@ -185,9 +187,18 @@ func handleWrongCharsetValue(ctx sessionctx.Context, col *model.ColumnInfo, str
 	if len(str) > i+6 {
 		strval.WriteString(`...`)
 	}
-	// TODO: Add 'at row %d'
-	err := ErrTruncatedWrongValueForField.FastGen("Incorrect string value '%s' for column '%s'", strval.String(), col.Name)
-	logutil.BgLogger().Error("incorrect string value", zap.Uint64("conn", ctx.GetSessionVars().ConnectionID), zap.Error(err))
+	return strval.String()
+}
+
+// handleWrongCharsetValue handles the invalid character at the byte offset i of the string, which is the
+// charPos-th character.
+func handleWrongCharsetValue(ctx sessionctx.Context, col *model.ColumnInfo, str string, i int, charPos int) error {
+	sc := ctx.GetSessionVars().StmtCtx
+	// The row is not known here, WrongCharsetValueErr adds it for the inserted rows.
+	err := ErrTruncatedWrongValueForField.FastGen("Incorrect string value '%s' for column '%s'", wrongCharsetValueSnippet(str, i), col.Name)
+	// The position is 1-based and counted in characters like MySQL.
+	logutil.BgLogger().Error("incorrect string value", zap.Uint64("conn", ctx.GetSessionVars().ConnectionID),
+		zap.Int("position", charPos+1), zap.Error(err))
 	err = sc.HandleTruncate(err)
 	return err
 }
@@ -328,15 +339,20 @@ func CastValue(ctx sessionctx.Context, val types.Datum, col *model.ColumnInfo, r
 		truncateTrailingSpaces(&casted)
 	}
 
-	if v := makeStringValidator(ctx, col); v != nil {
+	if v := makeStringValidator(ctx, col, val.Collation()); v != nil {
 		str := casted.GetString()
 		strategy := charset.TruncateStrategyReplace
 		if val.Collation() == charset.CollationBin {
 			strategy = charset.TruncateStrategyTrim
 		}
-		if newStr, invalidPos := v.Truncate(str, strategy); invalidPos >= 0 {
+		if !forceIgnoreTruncate && !sc.IgnoreTruncate && !sc.TruncateAsWarning {
+			// The invalid string fails the statement, so there is no need to build the truncated one.
+			strategy = charset.TruncateStrategyError
+		}
+		if newStr, invalidPos, _ := v.Truncate(str, strategy); invalidPos >= 0 {
 			casted = types.NewStringDatum(newStr)
-			err = handleWrongCharsetValue(ctx, col, str, invalidPos)
+			_, charPos := v.ValidateWithPosition(str)
+			err = handleWrongCharsetValue(ctx, col, str, invalidPos, charPos)
 		}
 	}
 	if forceIgnoreTruncate {
@@ -345,7 +361,25 @@ func CastValue(ctx sessionctx.Context, val types.Datum, col *model.ColumnInfo, r
 	return casted, err
 }
 
-func makeStringValidator(ctx sessionctx.Context, col *model.ColumnInfo) charset.StringValidator {
+// WrongCharsetValueErr returns the error of the string written to the column at the row in the same way as MySQL,
+// e.g. "Incorrect string value: '\xF0\x9F\x98\x82' for column 'a' at row 1". It returns nil if the string is
+// valid in the charset of the column. CastValue reports the invalid strings without the row, which is known by
+// the callers only.
+func WrongCharsetValueErr(ctx sessionctx.Context, col *model.ColumnInfo, str string, srcCollation string, rowIdx int) error {
+	v := makeStringValidator(ctx, col, srcCollation)
+	if v == nil {
+		return nil
+	}
+	_, invalidPos, err := v.Truncate(str, charset.TruncateStrategyError)
+	if err == nil {
+		return nil
+	}
+	return ErrTruncatedWrongValueForField.GenWithStackByArgs("string", wrongCharsetValueSnippet(str, invalidPos), col.Name, rowIdx)
+}
+
+// makeStringValidator returns the validator of the strings written to the column, srcCollation is the
+// collation of the written value.
+func makeStringValidator(ctx sessionctx.Context, col *model.ColumnInfo, srcCollation string) charset.StringValidator {
 	switch col.Charset {
 	case charset.CharsetASCII:
 		if ctx.GetSessionVars().SkipASCIICheck {
@@ -357,19 +391,38 @@ func makeStringValidator(ctx sessionctx.Context, col *model.ColumnInfo) charset.
 			return nil
 		}
 		needCheckMB4 := config.GetGlobalConfig().CheckMb4ValueInUTF8
-		return charset.StringValidatorUTF8{IsUTF8MB4: false, CheckMB4ValueInUTF8: needCheckMB4}
+		return charset.StringValidatorUTF8{IsUTF8MB4: false, CheckMB4ValueInUTF8: needCheckMB4, ReplacementChar: unicodeReplacementChar(srcCollation)}
 	case charset.CharsetUTF8MB4:
 		if ctx.GetSessionVars().SkipUTF8Check {
 			return nil
 		}
-		return charset.StringValidatorUTF8{IsUTF8MB4: true}
+		return charset.StringValidatorUTF8{IsUTF8MB4: true, ReplacementChar: unicodeReplacementChar(srcCollation)}
 	case charset.CharsetLatin1, charset.CharsetBinary:
 		return nil
 	default:
+		if v := charset.RegisteredStringValidator(col.Charset); v != nil {
+			return v
+		}
 		return charset.StringValidatorOther{Charset: col.Charset}
 	}
 }
 
+// unicodeReplacementChar returns the character which replaces the invalid characters when the value in
+// srcCollation is written to a utf8 or utf8mb4 column. The Unicode replacement character U+FFFD is used if
+// the value is also in a Unicode charset, as recommended by W3C for transcoding. Otherwise, it returns 0
+// and the default '?' is used.
+func unicodeReplacementChar(srcCollation string) rune {
+	coll, err := charset.GetCollationByName(srcCollation)
+	if err != nil {
+		return 0
+	}
+	switch coll.CharsetName {
+	case charset.CharsetUTF8, charset.CharsetUTF8MB4:
+		return utf8.RuneError
+	}
+	return 0
+}
+
 // ColDesc describes column information like MySQL desc and show columns do.
 type ColDesc struct {
 	Field string
@@ -425,7 +478,11 @@ func NewColDesc(col *Column) *ColDesc {
 	} else if mysql.HasOnUpdateNowFlag(col.Flag) {
 		// in order to match the rules of mysql 8.0.16 version
 		// see https://github.com/pingcap/tidb/issues/10337
-		extra = "DEFAULT_GENERATED on update CURRENT_TIMESTAMP" + OptionalFsp(&col.FieldType)
+		// MySQL only reports DEFAULT_GENERATED when the default value is CURRENT_TIMESTAMP.
+		extra = "on update CURRENT_TIMESTAMP" + OptionalFsp(&col.FieldType)
+		if defaultValStr, ok := defaultValue.(string); ok && strings.HasPrefix(strings.ToUpper(defaultValStr), strings.ToUpper(ast.CurrentTimestamp)) {
+			extra = "DEFAULT_GENERATED " + extra
+		}
 	} else if col.IsGenerated() {
 		if col.GeneratedStored {
 			extra = "STORED GENERATED"
@@ -449,6 +506,11 @@ func NewColDesc(col *Column) *ColDesc {
 	if !field_types.HasCharset(&col.ColumnInfo.FieldType) {
 		desc.Charset = nil
 		desc.Collation = nil
+	} else if col.Collate == "" {
+		// Columns created by old versions may have no collation, report the default one of the charset.
+		if collation, err := charset.GetDefaultCollation(col.Charset); err == nil {
+			desc.Collation = collation
+		}
 	}
 	return desc
 }
@@ -149,7 +149,11 @@ func TestDesc(t *testing.T) {
 	NewColDesc(col)
 	col.Flag = mysql.UniqueKeyFlag | mysql.OnUpdateNowFlag
 	desc := NewColDesc(col)
+	require.Equal(t, "on update CURRENT_TIMESTAMP", desc.Extra)
+	require.NoError(t, col.SetDefaultValue("CURRENT_TIMESTAMP"))
+	desc = NewColDesc(col)
 	require.Equal(t, "DEFAULT_GENERATED on update CURRENT_TIMESTAMP", desc.Extra)
+	require.NoError(t, col.SetDefaultValue(nil))
 	col.Flag = 0
 	col.GeneratedExprString = "test"
 	col.GeneratedStored = true
@@ -310,6 +314,15 @@ func TestCastValue(t *testing.T) {
 	colInfoS.Charset = charset.CharsetASCII
 	_, err = CastValue(ctx, types.NewDatum([]byte{0x32, 0xf0}), &colInfoS, false, true)
 	require.NoError(t, err)
+
+	// The Unicode replacement character is used when converting between Unicode charsets.
+	colInfoS.Charset = mysql.UTF8Charset
+	val, err = CastValue(ctx, types.NewCollationStringDatum("a\U0001F600", charset.CollationUTF8MB4), &colInfoS, false, true)
+	require.NoError(t, err)
+	require.Equal(t, "a\uFFFD", val.GetString())
+	val, err = CastValue(ctx, types.NewCollationStringDatum("a\xff", charset.CollationLatin1), &colInfoS, false, true)
+	require.NoError(t, err)
+	require.Equal(t, "a?", val.GetString())
 }
 
 func TestGetDefaultValue(t *testing.T) {
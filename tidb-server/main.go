@@ -88,6 +88,7 @@ const (
 	nmPort                   = "P"
 	nmCors                   = "cors"
 	nmSocket                 = "socket"
+	nmSocketMode             = "socket-mode"
 	nmEnableBinlog           = "enable-binlog"
 	nmRunDDL                 = "run-ddl"
 	nmLogLevel               = "L"
@@ -128,6 +129,7 @@ var (
 	port             = flag.String(nmPort, "4000", "tidb server port")
 	cors             = flag.String(nmCors, "", "tidb server allow cors origin")
 	socket           = flag.String(nmSocket, "/tmp/tidb-{Port}.sock", "The socket file to use for connection.")
+	socketMode       = flag.String(nmSocketMode, "0777", "The file mode of the socket file in octal, in [0000, 0777].")
 	enableBinlog     = flagBoolean(nmEnableBinlog, false, "enable generate binlog")
 	runDDL           = flagBoolean(nmRunDDL, true, "run ddl worker on this tidb-server")
 	ddlLease         = flag.String(nmDdlLease, "45s", "schema lease duration, very dangerous to change only if you know what you do")
@@ -433,6 +435,9 @@ func overrideConfig(cfg *config.Config) {
 	if actualFlags[nmSocket] {
 		cfg.Socket = *socket
 	}
+	if actualFlags[nmSocketMode] {
+		cfg.SocketPermissions = *socketMode
+	}
 	if actualFlags[nmEnableBinlog] {
 		cfg.Binlog.Enable = *enableBinlog
 	}
@@ -575,11 +580,15 @@ func setGlobalVars() {
 	variable.SetSysVar(variable.TiDBForcePriority, mysql.Priority2Str[priority])
 	variable.SetSysVar(variable.TiDBOptDistinctAggPushDown, variable.BoolToOnOff(cfg.Performance.DistinctAggPushDown))
 	variable.SetSysVar(variable.TiDBMemQuotaQuery, strconv.FormatInt(cfg.MemQuotaQuery, 10))
+	variable.SetSysVar(variable.TiDBMaxChunkSize, strconv.FormatUint(uint64(cfg.Performance.MaxChunkSize), 10))
 	variable.SetSysVar(variable.LowerCaseTableNames, strconv.Itoa(cfg.LowerCaseTableNames))
 	variable.SetSysVar(variable.LogBin, variable.BoolToOnOff(cfg.Binlog.Enable))
 	variable.SetSysVar(variable.Port, fmt.Sprintf("%d", cfg.Port))
 	cfg.Socket = strings.Replace(cfg.Socket, "{Port}", fmt.Sprintf("%d", cfg.Port), 1)
 	variable.SetSysVar(variable.Socket, cfg.Socket)
+	variable.SetSysVar(variable.RequireSecureTransport, variable.BoolToOnOff(cfg.Security.RequireSecureTransport))
+	variable.SetSysVar(variable.TiDBMaxServerConnections, strconv.FormatUint(uint64(cfg.MaxServerConnections), 10))
+	variable.SetSysVar(variable.TiDBMaxConnectionsPerIP, strconv.FormatUint(uint64(cfg.MaxConnectionsPerIP), 10))
 	variable.SetSysVar(variable.DataDir, cfg.Path)
 	variable.SetSysVar(variable.TiDBSlowQueryFile, cfg.Log.SlowQueryFile)
 	variable.SetSysVar(variable.TiDBIsolationReadEngines, strings.Join(cfg.IsolationRead.Engines, ","))
@@ -659,6 +668,14 @@ func createServer(storage kv.Storage, dom *domain.Domain) *server.Server {
 		closeDomainAndStorage(storage, dom)
 		log.Fatal("failed to create the server", zap.Error(err), zap.Stack("stack"))
 	}
+	if cfg.Log.AuditLogFile != "" {
+		hook, err := server.NewFileAuditHook(cfg.Log.AuditLogFile, cfg.Log.File)
+		if err != nil {
+			closeDomainAndStorage(storage, dom)
+			log.Fatal("failed to create the audit log", zap.Error(err))
+		}
+		svr.AddAuditHook(hook)
+	}
 	svr.SetDomain(dom)
 	svr.InitGlobalConnID(dom.ServerID)
 	go dom.ExpensiveQueryHandle().SetSessionManager(svr).Run()
@@ -110,6 +110,40 @@ func TestUTF8CollatorKey(t *testing.T) {
 	testKeyTable(t, collations, tests)
 }
 
+func TestGB18030Collator(t *testing.T) {
+	SetCharsetFeatEnabledForTest(true)
+	defer SetCharsetFeatEnabledForTest(false)
+	collations := []string{"gbk_chinese_ci", "gb18030_bin", "gb18030_chinese_ci"}
+	compareTests := []compareTable{
+		{"a", "A", []int{0, 1, 0}},
+		{"a", "a ", []int{0, 0, 0}},
+		{"啊", "吧", []int{-1, -1, -1}},
+		{"中文", "汉字", []int{1, 1, 1}},
+		// The characters out of GBK are sorted after the ones in GBK.
+		{"😜", "😃", []int{0, 1, 1}},
+		{"😃", "啊", []int{-1, -1, 1}},
+		{"ä", "Ä", []int{0, 1, 0}},
+		// ü is in GBK but Ü is not.
+		{"ü", "Ü", []int{1, 1, 0}},
+		{"︐", "︙", []int{0, -1, -1}},
+	}
+	testCompareTable(t, collations, compareTests)
+
+	keyTests := []keyTable{
+		{"a", [][]byte{{0x41}, {0x61}, {0x41}}},
+		{"中文 ", [][]byte{{0xD3, 0x21, 0xC1, 0xAD}, {0xD6, 0xD0, 0xCE, 0xC4}, {0xD3, 0x21, 0xC1, 0xAD}}},
+		{"a😃", [][]byte{{0x41, 0x3F}, {0x61, 0x94, 0x39, 0xFC, 0x39}, {0x41, 0xFF, 0x01, 0xF6, 0x03}}},
+		{"︐", [][]byte{{0x3F}, {0xA6, 0xD9}, {0xFF, 0x00, 0xFE, 0x10}}},
+	}
+	testKeyTable(t, collations, keyTests)
+
+	collator := GetCollator("gb18030_chinese_ci")
+	pattern := collator.Pattern()
+	pattern.Compile("%Ä_", '\\')
+	require.True(t, pattern.DoMatch("aä😃"))
+	require.False(t, pattern.DoMatch("aa😃"))
+}
+
 func TestSetNewCollateEnabled(t *testing.T) {
 	defer SetNewCollationEnabledForTest(false)
 
@@ -179,4 +213,6 @@ func TestGetCollator(t *testing.T) {
 	defer SetCharsetFeatEnabledForTest(false)
 	require.IsType(t, &gbkBinCollator{}, GetCollator("gbk_bin"))
 	require.IsType(t, &gbkBinCollator{}, GetCollatorByID(87))
+	require.IsType(t, &gbkBinCollator{}, GetCollator("gb18030_bin"))
+	require.IsType(t, &gb18030ChineseCICollator{}, GetCollatorByID(248))
 }
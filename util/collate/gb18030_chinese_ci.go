@@ -0,0 +1,117 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collate
+
+import (
+	"unicode"
+
+	"github.com/pingcap/tidb/util/stringutil"
+)
+
+// gb18030ChineseCICollator is collator for gb18030_chinese_ci. The characters in GBK are sorted in the same
+// way as gbk_chinese_ci, the others are sorted after them by their upper case.
+type gb18030ChineseCICollator struct {
+}
+
+// Compare implements Collator interface.
+func (g *gb18030ChineseCICollator) Compare(a, b string) int {
+	a = truncateTailingSpace(a)
+	b = truncateTailingSpace(b)
+
+	r1, r2 := rune(0), rune(0)
+	ai, bi := 0, 0
+	for ai < len(a) && bi < len(b) {
+		r1, ai = decodeRune(a, ai)
+		r2, bi = decodeRune(b, bi)
+
+		k1, k2 := gb18030ChineseCISortKey(r1), gb18030ChineseCISortKey(r2)
+		if k1 != k2 {
+			if k1 < k2 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return sign((len(a) - ai) - (len(b) - bi))
+}
+
+// Key implements Collator interface.
+func (g *gb18030ChineseCICollator) Key(str string) []byte {
+	str = truncateTailingSpace(str)
+	buf := make([]byte, 0, len(str)*2)
+	i := 0
+	r := rune(0)
+	for i < len(str) {
+		r, i = decodeRune(str, i)
+		u32 := gb18030ChineseCISortKey(r)
+		if u32 > 0xFFFF {
+			buf = append(buf, byte(u32>>24), byte(u32>>16))
+		}
+		if u32 > 0xFF {
+			buf = append(buf, byte(u32>>8))
+		}
+		buf = append(buf, byte(u32))
+	}
+	return buf
+}
+
+// Pattern implements Collator interface.
+func (g *gb18030ChineseCICollator) Pattern() WildcardPattern {
+	return &gb18030ChineseCIPattern{}
+}
+
+type gb18030ChineseCIPattern struct {
+	patChars []rune
+	patTypes []byte
+}
+
+// Compile implements WildcardPattern interface.
+func (p *gb18030ChineseCIPattern) Compile(patternStr string, escape byte) {
+	p.patChars, p.patTypes = stringutil.CompilePatternInner(patternStr, escape)
+}
+
+// DoMatch implements WildcardPattern interface.
+func (p *gb18030ChineseCIPattern) DoMatch(str string) bool {
+	return stringutil.DoMatchInner(str, p.patChars, p.patTypes, func(a, b rune) bool {
+		return gb18030ChineseCISortKey(a) == gb18030ChineseCISortKey(b)
+	})
+}
+
+// gb18030ChineseCISortKey returns the weight of a character. The characters out of GBK have the weights of their
+// upper or lower case in GBK if any, so that they are compared case-insensitively with the ones in GBK. The weights
+// of the characters in GBK are at most 0xFFFF, the ones of the others are 0xFF000000 plus their upper case code
+// points, which are in the same order as their four-byte encodings, so the keys are still ordered when they are
+// compared byte by byte.
+func gb18030ChineseCISortKey(r rune) uint32 {
+	if key, ok := gbkSortKey(r); ok {
+		return key
+	}
+	upper := unicode.ToUpper(r)
+	if key, ok := gbkSortKey(upper); ok {
+		return key
+	}
+	if key, ok := gbkSortKey(unicode.ToLower(r)); ok {
+		return key
+	}
+	return 0xFF000000 | uint32(upper)
+}
+
+func gbkSortKey(r rune) (uint32, bool) {
+	if r > 0xFFFF {
+		return 0, false
+	}
+	key := gbkChineseCISortKeyTable[r]
+	return uint32(key), key != 0x3F || r == 0x3F
+}
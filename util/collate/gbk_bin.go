@@ -21,7 +21,7 @@ import (
 	"golang.org/x/text/encoding"
 )
 
-// gbkBinCollator is collator for gbk_bin.
+// gbkBinCollator is collator for gbk_bin and gb18030_bin, it compares the characters by their encoded bytes.
 type gbkBinCollator struct {
 	e *encoding.Encoder
 }
@@ -0,0 +1,159 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// GeneralLogTargetOff disables the general query log.
+	GeneralLogTargetOff = "OFF"
+	// GeneralLogTargetFile writes the general query log to log.general-log-file.
+	GeneralLogTargetFile = "FILE"
+	// GeneralLogTargetStderr writes the general query log to the standard error.
+	GeneralLogTargetStderr = "STDERR"
+
+	// generalLogRateLimit is the maximal number of the statements written to the general query log
+	// each second, the others are dropped and counted.
+	generalLogRateLimit = 10000
+)
+
+// generalLog is the dedicated logger of the general query log set by tidb_general_log_target.
+var generalLog struct {
+	// enabled is 1 if the target is not OFF, it is checked without locking so that the statements
+	// are not formatted when the log is disabled.
+	enabled int32
+
+	sync.Mutex
+	target string
+	// fileCfg is the config of the file written by the FILE target, it is set by InitLogger.
+	fileCfg log.FileLogConfig
+	// fileSyncer is the writer of the file, it is created at the first time the target is set to FILE.
+	fileSyncer zapcore.WriteSyncer
+	logger     *zap.Logger
+	// second is the unix second the count and the dropped statements are counted in.
+	second  int64
+	count   int
+	dropped int
+}
+
+func init() {
+	generalLog.target = GeneralLogTargetOff
+}
+
+func initGeneralLogger(cfg *LogConfig) error {
+	generalLog.Lock()
+	defer generalLog.Unlock()
+	generalLog.fileCfg = cfg.File
+	generalLog.fileCfg.Filename = cfg.GeneralLogFile
+	generalLog.fileSyncer = nil
+	if generalLog.target == GeneralLogTargetOff {
+		return nil
+	}
+	return setGeneralLogTargetLocked(generalLog.target)
+}
+
+// GetGeneralLogTarget returns the target of the general query log.
+func GetGeneralLogTarget() string {
+	generalLog.Lock()
+	defer generalLog.Unlock()
+	return generalLog.target
+}
+
+// SetGeneralLogTarget changes the target of the general query log, target is one of OFF, FILE and STDERR.
+func SetGeneralLogTarget(target string) error {
+	generalLog.Lock()
+	defer generalLog.Unlock()
+	return setGeneralLogTargetLocked(strings.ToUpper(target))
+}
+
+func setGeneralLogTargetLocked(target string) error {
+	var syncer zapcore.WriteSyncer
+	switch target {
+	case GeneralLogTargetOff:
+	case GeneralLogTargetFile:
+		if generalLog.fileSyncer == nil {
+			if generalLog.fileCfg.Filename == "" {
+				return errors.New("general log file is not configured")
+			}
+			_, prop, err := log.InitLogger(&log.Config{File: generalLog.fileCfg})
+			if err != nil {
+				return errors.Trace(err)
+			}
+			generalLog.fileSyncer = prop.Syncer
+		}
+		syncer = generalLog.fileSyncer
+	case GeneralLogTargetStderr:
+		syncer = zapcore.Lock(os.Stderr)
+	default:
+		return errors.Errorf("unknown general log target %s", target)
+	}
+	generalLog.target = target
+	generalLog.logger = nil
+	if syncer != nil {
+		encoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			TimeKey:        "time",
+			LevelKey:       "level",
+			MessageKey:     "message",
+			EncodeLevel:    zapcore.CapitalLevelEncoder,
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeTime:     zapcore.ISO8601TimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		})
+		generalLog.logger = zap.New(zapcore.NewCore(encoder, syncer, zapcore.InfoLevel))
+		atomic.StoreInt32(&generalLog.enabled, 1)
+	} else {
+		atomic.StoreInt32(&generalLog.enabled, 0)
+	}
+	return nil
+}
+
+// GeneralQueryLogger returns the logger of the general query log. It returns nil if the log is disabled or
+// more than generalLogRateLimit statements are logged in the current second, so the caller should build
+// the fields only if the logger is not nil.
+func GeneralQueryLogger() *zap.Logger {
+	if atomic.LoadInt32(&generalLog.enabled) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	generalLog.Lock()
+	defer generalLog.Unlock()
+	if generalLog.logger == nil {
+		return nil
+	}
+	if now != generalLog.second {
+		if generalLog.dropped > 0 {
+			generalLog.logger.Warn("GENERAL_LOG_DROPPED", zap.Int("dropped", generalLog.dropped),
+				zap.Int64("second", generalLog.second))
+		}
+		generalLog.second, generalLog.count, generalLog.dropped = now, 0, 0
+	}
+	if generalLog.count >= generalLogRateLimit {
+		generalLog.dropped++
+		return nil
+	}
+	generalLog.count++
+	return generalLog.logger
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGeneralQueryLogger(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "general.log")
+	conf := NewLogConfig("info", DefaultLogFormat, "", EmptyFileLogConfig, false)
+	conf.GeneralLogFile = filename
+	require.NoError(t, initGeneralLogger(conf))
+	defer func() {
+		require.NoError(t, SetGeneralLogTarget(GeneralLogTargetOff))
+	}()
+
+	require.Equal(t, GeneralLogTargetOff, GetGeneralLogTarget())
+	require.Nil(t, GeneralQueryLogger())
+	require.Error(t, SetGeneralLogTarget("unknown"))
+	require.Equal(t, GeneralLogTargetOff, GetGeneralLogTarget())
+
+	require.NoError(t, SetGeneralLogTarget("file"))
+	require.Equal(t, GeneralLogTargetFile, GetGeneralLogTarget())
+	lg := GeneralQueryLogger()
+	require.NotNil(t, lg)
+	lg.Info("GENERAL_LOG", zap.Uint64("conn", 1), zap.String("digest", "abc"), zap.String("sql", "select ?"))
+	require.NoError(t, lg.Sync())
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(string(data))), &entry))
+	require.Equal(t, "GENERAL_LOG", entry["message"])
+	require.Equal(t, "INFO", entry["level"])
+	require.Equal(t, float64(1), entry["conn"])
+	require.Equal(t, "abc", entry["digest"])
+	require.Equal(t, "select ?", entry["sql"])
+
+	// The statements over the rate limit of the current second are dropped.
+	now := time.Now().Unix()
+	generalLog.Lock()
+	generalLog.second, generalLog.count, generalLog.dropped = now, generalLogRateLimit, 0
+	generalLog.Unlock()
+	if GeneralQueryLogger() == nil {
+		generalLog.Lock()
+		require.Equal(t, 1, generalLog.dropped)
+		generalLog.Unlock()
+	}
+
+	require.NoError(t, SetGeneralLogTarget(GeneralLogTargetOff))
+	require.Nil(t, GeneralQueryLogger())
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"runtime/trace"
+	"sync/atomic"
 	"time"
 
 	gzap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
@@ -27,6 +28,7 @@ import (
 	tlog "github.com/opentracing/opentracing-go/log"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/util/stringutil"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -68,6 +70,13 @@ type LogConfig struct {
 
 	// SlowQueryFile filename, default to File log config on empty.
 	SlowQueryFile string
+
+	// GeneralLogFile is the file written by the general query log when tidb_general_log_target is FILE.
+	GeneralLogFile string
+
+	// KeepRawBinaryBytes keeps the bytes which are not valid UTF-8 in the statement text written to
+	// the logs and warnings, instead of hex-escaping them.
+	KeepRawBinaryBytes bool
 }
 
 // NewLogConfig creates a LogConfig.
@@ -97,6 +106,28 @@ const (
 // SlowQueryLogger is used to log slow query, InitLogger will modify it according to config file.
 var SlowQueryLogger = log.L()
 
+// keepRawBinaryBytes is 1 if the bytes which are not valid UTF-8 are kept in the logs.
+var keepRawBinaryBytes uint32
+
+// SetKeepRawBinaryBytes sets whether to keep the bytes which are not valid UTF-8 in the logs.
+func SetKeepRawBinaryBytes(keep bool) {
+	var v uint32
+	if keep {
+		v = 1
+	}
+	atomic.StoreUint32(&keepRawBinaryBytes, v)
+}
+
+// EscapeBinaryBytes hex-escapes the bytes which are not valid UTF-8 in the statement text written to
+// the logs and warnings, so that it doesn't break the log collectors. The raw bytes are kept if
+// log.keep-raw-binary-bytes is set.
+func EscapeBinaryBytes(s string) string {
+	if atomic.LoadUint32(&keepRawBinaryBytes) == 1 {
+		return s
+	}
+	return stringutil.EscapeInvalidUTF8(s)
+}
+
 // InitLogger initializes a logger with cfg.
 func InitLogger(cfg *LogConfig) error {
 	gl, props, err := log.InitLogger(&cfg.Config, zap.AddStacktrace(zapcore.FatalLevel))
@@ -104,6 +135,7 @@ func InitLogger(cfg *LogConfig) error {
 		return errors.Trace(err)
 	}
 	log.ReplaceGlobals(gl, props)
+	SetKeepRawBinaryBytes(cfg.KeepRawBinaryBytes)
 
 	// init dedicated logger for slow query log
 	SlowQueryLogger, _, err = newSlowQueryLogger(cfg)
@@ -116,7 +148,7 @@ func InitLogger(cfg *LogConfig) error {
 		return errors.Trace(err)
 	}
 
-	return nil
+	return initGeneralLogger(cfg)
 }
 
 func initGRPCLogger(cfg *LogConfig) (*zap.Logger, *log.ZapProperties, error) {
@@ -177,6 +209,11 @@ func SetLevel(level string) error {
 	return nil
 }
 
+// GetLevel returns the current level of the global logger.
+func GetLevel() string {
+	return log.GetLevel().String()
+}
+
 type ctxLogKeyType struct{}
 
 var ctxLogKey = ctxLogKeyType{}
@@ -156,3 +156,13 @@ func TestGlobalLoggerReplace(t *testing.T) {
 	err = os.Remove(fileCfg.Filename)
 	require.NoError(t, err)
 }
+
+func TestEscapeBinaryBytes(t *testing.T) {
+	defer SetKeepRawBinaryBytes(false)
+	require.Equal(t, `a\xFFb`, EscapeBinaryBytes("a\xffb"))
+
+	conf := NewLogConfig("info", DefaultLogFormat, "", EmptyFileLogConfig, false)
+	conf.KeepRawBinaryBytes = true
+	require.NoError(t, InitLogger(conf))
+	require.Equal(t, "a\xffb", EscapeBinaryBytes("a\xffb"))
+}
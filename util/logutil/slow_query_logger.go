@@ -16,6 +16,7 @@ package logutil
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -62,6 +63,11 @@ type slowLogEncoder struct{}
 
 func (e *slowLogEncoder) EncodeEntry(entry zapcore.Entry, _ []zapcore.Field) (*buffer.Buffer, error) {
 	b := _pool.Get()
+	// The slow log in the JSON format carries the time itself.
+	if strings.HasPrefix(entry.Message, "{") {
+		fmt.Fprintf(b, "%s\n", entry.Message)
+		return b, nil
+	}
 	fmt.Fprintf(b, "# Time: %s\n", entry.Time.Format(SlowLogTimeFormat))
 	fmt.Fprintf(b, "%s\n", entry.Message)
 	return b, nil
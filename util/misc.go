@@ -394,6 +394,19 @@ func TLSCipher2String(n uint16) string {
 	return s
 }
 
+// Taken from https://github.com/openssl/openssl/blob/c784a838e0947fcca761ee62def7d077dc06d37f/include/openssl/ssl.h#L141 .
+var tlsVersionString = map[uint16]string{
+	tls.VersionTLS10: "TLSv1",
+	tls.VersionTLS11: "TLSv1.1",
+	tls.VersionTLS12: "TLSv1.2",
+	tls.VersionTLS13: "TLSv1.3",
+}
+
+// TLSVersion2String convert tls version to string, it returns an empty string for the unknown versions.
+func TLSVersion2String(version uint16) string {
+	return tlsVersionString[version]
+}
+
 // ColumnsToProto converts a slice of model.ColumnInfo to a slice of tipb.ColumnInfo.
 func ColumnsToProto(columns []*model.ColumnInfo, pkIsHandle bool) []*tipb.ColumnInfo {
 	cols := make([]*tipb.ColumnInfo, 0, len(columns))
@@ -455,13 +468,24 @@ func LoadTLSCertificates(ca, key, cert string, autoTLS bool, rsaKeySize int) (tl
 			return
 		}
 		autoReload = true
-		tempStoragePath := config.GetGlobalConfig().TempStoragePath
-		cert = filepath.Join(tempStoragePath, "/cert.pem")
-		key = filepath.Join(tempStoragePath, "/key.pem")
-		err = createTLSCertificates(cert, key, rsaKeySize)
-		if err != nil {
-			logutil.BgLogger().Warn("TLS Certificate creation failed", zap.Error(err))
-			return
+		certDir := config.GetGlobalConfig().Security.AutoTLSPath
+		if certDir == "" {
+			certDir = filepath.Join(config.GetGlobalConfig().DataDir(), autoTLSDir)
+		}
+		cert = filepath.Join(certDir, autoTLSCertFile)
+		key = filepath.Join(certDir, autoTLSKeyFile)
+		if autoTLSCertificatesValid(cert, key) {
+			logutil.BgLogger().Info("Reuse automatically created TLS Certificates", zap.String("cert", cert), zap.String("key", key))
+		} else {
+			if err = os.MkdirAll(certDir, 0700); err != nil {
+				logutil.BgLogger().Warn("TLS Certificate creation failed", zap.Error(err))
+				return
+			}
+			err = createTLSCertificates(cert, key, rsaKeySize)
+			if err != nil {
+				logutil.BgLogger().Warn("TLS Certificate creation failed", zap.Error(err))
+				return
+			}
 		}
 	}
 
@@ -474,22 +498,15 @@ func LoadTLSCertificates(ca, key, cert string, autoTLS bool, rsaKeySize int) (tl
 	}
 
 	requireTLS := config.GetGlobalConfig().Security.RequireSecureTransport
-	var minTLSVersion uint16 = tls.VersionTLS11
-	switch tlsver := config.GetGlobalConfig().Security.MinTLSVersion; tlsver {
-	case "TLSv1.0":
-		minTLSVersion = tls.VersionTLS10
-	case "TLSv1.1":
-		minTLSVersion = tls.VersionTLS11
-	case "TLSv1.2":
-		minTLSVersion = tls.VersionTLS12
-	case "TLSv1.3":
-		minTLSVersion = tls.VersionTLS13
-	case "":
-	default:
+	tlsver := config.GetGlobalConfig().Security.MinTLSVersion
+	minTLSVersion, parseErr := config.ParseTLSVersion(tlsver)
+	if parseErr != nil {
+		// The config is validated when it is loaded, so it can only happen in the tests.
 		logutil.BgLogger().Warn(
 			"Invalid TLS version, using default instead",
 			zap.String("tls-version", tlsver),
 		)
+		minTLSVersion = tls.VersionTLS12
 	}
 	if minTLSVersion < tls.VersionTLS12 {
 		logutil.BgLogger().Warn(
@@ -613,13 +630,50 @@ func GetLocalIP() string {
 	return ""
 }
 
+// NormalizeIP returns the shortest form of an IP address, such as "::1" for "0:0:0:0:0:0:0:1" and "1.2.3.4" for
+// "::ffff:1.2.3.4", so that the same address is always shown and matched in the same way. The host is returned
+// as is if it is not an IP address.
+func NormalizeIP(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return host
+}
+
 // QueryStrForLog trim the query if the query length more than 4096
 func QueryStrForLog(query string) string {
 	const size = 4096
 	if len(query) > size {
-		return query[:size] + fmt.Sprintf("(len: %d)", len(query))
+		return logutil.EscapeBinaryBytes(query[:size]) + fmt.Sprintf("(len: %d)", len(query))
 	}
-	return query
+	return logutil.EscapeBinaryBytes(query)
+}
+
+const (
+	autoTLSCertFile = "cert.pem"
+	autoTLSKeyFile  = "key.pem"
+	// autoTLSCertValidity is the validity of the automatically created certificates.
+	autoTLSCertValidity = 10 * 365 * 24 * time.Hour
+	// autoTLSRenewBefore is how long before expiration the automatically created certificates are re-created,
+	// it is longer than the 30 days interval of checking them.
+	autoTLSRenewBefore = 60 * 24 * time.Hour
+	// autoTLSDir is the directory under the data directory of the automatically created certificates.
+	autoTLSDir = "auto-tls"
+)
+
+// autoTLSCertificatesValid checks whether the automatically created certificates in the paths can be reused,
+// that is, they exist and do not expire soon.
+func autoTLSCertificatesValid(certpath string, keypath string) bool {
+	tlsCert, err := tls.LoadX509KeyPair(certpath, keypath)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	return now.After(cert.NotBefore) && now.Add(autoTLSRenewBefore).Before(cert.NotAfter)
 }
 
 func createTLSCertificates(certpath string, keypath string, rsaKeySize int) error {
@@ -628,22 +682,30 @@ func createTLSCertificates(certpath string, keypath string, rsaKeySize int) erro
 		return err
 	}
 
-	certValidity := 90 * 24 * time.Hour // 90 days
-	notBefore := time.Now()
+	certValidity := autoTLSCertValidity
+	notBefore := time.Now().Add(-time.Hour) // Tolerate the clock skew of clients.
 	notAfter := notBefore.Add(certValidity)
 	hostname, err := os.Hostname()
 	if err != nil {
 		return err
 	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
 
 	template := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName: "TiDB_Server_Auto_Generated_Server_Certificate",
 		},
-		SerialNumber: big.NewInt(1),
-		NotBefore:    notBefore,
-		NotAfter:     notAfter,
-		DNSNames:     []string{hostname},
+		SerialNumber:          serialNumber,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{hostname, "localhost"},
+		IPAddresses:           getHostIPs(),
 	}
 
 	// DER: Distinguished Encoding Rules, this is the ASN.1 encoding rule of the certificate.
@@ -685,3 +747,19 @@ func createTLSCertificates(certpath string, keypath string, rsaKeySize int) erro
 		zap.Duration("validity", certValidity), zap.Int("rsaKeySize", rsaKeySize))
 	return nil
 }
+
+// getHostIPs returns the IPs of the host, including the loopback ones, for the SANs of certificates.
+func getHostIPs() []net.IP {
+	var ips []net.IP
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		logutil.BgLogger().Warn("get the IPs of the host failed", zap.Error(err))
+		return []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	}
+	for _, address := range addrs {
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsUnspecified() {
+			ips = append(ips, ipnet.IP)
+		}
+	}
+	return ips
+}
@@ -16,11 +16,18 @@ package util
 
 import (
 	"bytes"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
@@ -145,7 +152,7 @@ func TestBasicFuncProcessInfo(t *testing.T) {
 	row := pi.ToRowForShow(false)
 	row2 := pi.ToRowForShow(true)
 	assert.Equal(t, row2, row)
-	assert.Len(t, row, 8)
+	assert.Len(t, row, 9)
 	assert.Equal(t, pi.ID, row[0])
 	assert.Equal(t, pi.User, row[1])
 	assert.Equal(t, pi.Host, row[2])
@@ -154,9 +161,13 @@ func TestBasicFuncProcessInfo(t *testing.T) {
 	assert.Equal(t, uint64(0), row[5])
 	assert.Equal(t, "in transaction; autocommit", row[6])
 	assert.Equal(t, "test", row[7])
+	assert.Nil(t, row[8])
+
+	pi.ProxyUser = "proxied@%"
+	assert.Equal(t, "proxied@%", pi.ToRowForShow(false)[8])
 
 	row3 := pi.ToRow(time.UTC)
-	assert.Equal(t, row, row3[:8])
+	assert.Equal(t, row[:8], row3[:8])
 	assert.Equal(t, int64(0), row3[9])
 }
 
@@ -204,3 +215,66 @@ func TestComposeURL(t *testing.T) {
 	assert.Equal(t, ComposeURL("http://server.example.com", ""), "http://server.example.com")
 	assert.Equal(t, ComposeURL("https://server.example.com", ""), "https://server.example.com")
 }
+
+func TestNormalizeIP(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "::1", NormalizeIP("::1"))
+	assert.Equal(t, "::1", NormalizeIP("0:0:0:0:0:0:0:1"))
+	assert.Equal(t, "fe80::1", NormalizeIP("FE80:0000:0000:0000:0000:0000:0000:0001"))
+	assert.Equal(t, "1.2.3.4", NormalizeIP("::ffff:1.2.3.4"))
+	assert.Equal(t, "127.0.0.1", NormalizeIP("127.0.0.1"))
+	assert.Equal(t, "localhost", NormalizeIP("localhost"))
+	assert.Equal(t, "%", NormalizeIP("%"))
+	assert.Equal(t, "fe80::1%eth0", NormalizeIP("fe80::1%eth0"))
+}
+
+func TestAutoTLSCertificates(t *testing.T) {
+	dir := t.TempDir()
+	restore := config.RestoreFunc()
+	defer restore()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security.AutoTLSPath = dir
+	})
+
+	serialNumber := func(tlsConfig *tls.Config) *big.Int {
+		cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+		assert.NoError(t, err)
+		return cert.SerialNumber
+	}
+
+	// The certificates are created with multi-year validity and the host IPs.
+	tlsConfig, autoReload, err := LoadTLSCertificates("", "", "", true, 2048)
+	assert.NoError(t, err)
+	assert.True(t, autoReload)
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, cert.PublicKey.(*rsa.PublicKey).N.BitLen())
+	assert.True(t, cert.NotAfter.After(time.Now().Add(5*365*24*time.Hour)))
+	assert.Contains(t, cert.DNSNames, "localhost")
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
+	assert.FileExists(t, filepath.Join(dir, autoTLSCertFile))
+	assert.FileExists(t, filepath.Join(dir, autoTLSKeyFile))
+
+	// The persisted certificates are reused.
+	tlsConfig, _, err = LoadTLSCertificates("", "", "", true, 528)
+	assert.NoError(t, err)
+	assert.Equal(t, cert.SerialNumber, serialNumber(tlsConfig))
+
+	// The missing certificates are re-created.
+	assert.NoError(t, os.Remove(filepath.Join(dir, autoTLSCertFile)))
+	tlsConfig, _, err = LoadTLSCertificates("", "", "", true, 528)
+	assert.NoError(t, err)
+	assert.NotEqual(t, cert.SerialNumber, serialNumber(tlsConfig))
+
+	// The certificates are created under the data directory by default.
+	dataDir := t.TempDir()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security.AutoTLSPath = ""
+		conf.Store = "unistore"
+		conf.Path = dataDir
+	})
+	_, _, err = LoadTLSCertificates("", "", "", true, 528)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dataDir, autoTLSDir, autoTLSCertFile))
+	assert.FileExists(t, filepath.Join(dataDir, autoTLSDir, autoTLSKeyFile))
+}
@@ -16,8 +16,9 @@ package pdapi
 
 // The following constants are the APIs of PD server.
 const (
-	HotRead    = "/pd/api/v1/hotspot/regions/read"
-	HotWrite   = "/pd/api/v1/hotspot/regions/write"
+	HotRead  = "/pd/api/v1/hotspot/regions/read"
+	HotWrite = "/pd/api/v1/hotspot/regions/write"
+	// HotHistory is only supported by PD v5.3.0 and later, which store the history hot regions.
 	HotHistory = "/pd/api/v1/hotspot/regions/history"
 	Regions    = "/pd/api/v1/regions"
 	RegionByID = "/pd/api/v1/region/id/"
@@ -33,10 +33,12 @@ import (
 type ProcessInfo struct {
 	ID               uint64
 	User             string
+	ProxyUser        string
 	Host             string
 	Port             string
 	DB               string
 	Digest           string
+	PlanDigest       string
 	Plan             interface{}
 	PlanExplainRows  [][]string
 	RuntimeStatsColl *execdetails.RuntimeStatsColl
@@ -53,10 +55,46 @@ type ProcessInfo struct {
 	Command                   byte
 	ExceedExpensiveTimeThresh bool
 	RedactSQL                 bool
+
+	// Transport is how the client connects to the server, see ConnectionTransport.
+	Transport string
+	// TLSState is the TLS connection state captured after the handshake, nil if not using TLS.
+	TLSState *tls.ConnectionState
+	// ConnectionAttrs are the connection attributes sent by the client in the handshake.
+	ConnectionAttrs map[string]string
+	// TablesInUse are the tables used by the running statement and the current transaction.
+	TablesInUse []stmtctx.TableEntry
+}
+
+// The transports of the client connections.
+const (
+	TransportTCP    = "tcp"
+	TransportTLS    = "tls"
+	TransportSocket = "socket"
+)
+
+// ConnectionTransport returns the transport of a client connection.
+func ConnectionTransport(tlsState *tls.ConnectionState, isUnixSocket bool) string {
+	if isUnixSocket {
+		return TransportSocket
+	}
+	if tlsState != nil {
+		return TransportTLS
+	}
+	return TransportTCP
 }
 
 // ToRowForShow returns []interface{} for the row data of "SHOW [FULL] PROCESSLIST".
 func (pi *ProcessInfo) ToRowForShow(full bool) []interface{} {
+	var proxyUser interface{}
+	if len(pi.ProxyUser) > 0 {
+		proxyUser = pi.ProxyUser
+	}
+	return append(pi.toBaseRow(full), proxyUser)
+}
+
+// toBaseRow returns the columns shared by "SHOW [FULL] PROCESSLIST" and INFORMATION_SCHEMA.PROCESSLIST.
+func (pi *ProcessInfo) toBaseRow(full bool) []interface{} {
 	var info interface{}
 	if len(pi.Info) > 0 {
 		if full {
@@ -109,7 +147,16 @@ func (pi *ProcessInfo) ToRow(tz *time.Location) []interface{} {
 			diskConsumed = pi.StmtCtx.DiskTracker.BytesConsumed()
 		}
 	}
-	return append(pi.ToRowForShow(true), pi.Digest, bytesConsumed, diskConsumed, pi.txnStartTs(tz))
+	var transport, sslVersion, sslCipher interface{}
+	if len(pi.Transport) > 0 {
+		transport = pi.Transport
+	}
+	if pi.TLSState != nil {
+		sslVersion = TLSVersion2String(pi.TLSState.Version)
+		sslCipher = TLSCipher2String(pi.TLSState.CipherSuite)
+	}
+	return append(pi.toBaseRow(true), pi.Digest, bytesConsumed, diskConsumed, pi.txnStartTs(tz), pi.PlanDigest,
+		transport, sslVersion, sslCipher)
 }
 
 // ascServerStatus is a slice of all defined server status in ascending order.
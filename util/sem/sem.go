@@ -143,6 +143,7 @@ func IsInvisibleSysVar(varNameInLower string) bool {
 		variable.TiDBExpensiveQueryTimeThreshold,
 		variable.TiDBForcePriority,
 		variable.TiDBGeneralLog,
+		variable.TiDBGeneralLogTarget,
 		variable.TiDBMetricSchemaRangeDuration,
 		variable.TiDBMetricSchemaStep,
 		variable.TiDBOptWriteRowID,
@@ -364,3 +364,23 @@ func GetTailSpaceCount(str string) int64 {
 	}
 	return int64(len(str) - length)
 }
+
+// EscapeInvalidUTF8 hex-escapes the bytes which are not valid UTF-8 in s, e.g. "a\xffb" is returned as `a\xFFb`.
+// The valid text is left untouched.
+func EscapeInvalidUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 8)
+	for len(s) > 0 {
+		r, w := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && w == 1 {
+			fmt.Fprintf(&sb, `\x%02X`, s[0])
+		} else {
+			sb.WriteString(s[:w])
+		}
+		s = s[w:]
+	}
+	return sb.String()
+}
@@ -268,3 +268,25 @@ func BenchmarkBuildStringFromLabels(b *testing.B) {
 		})
 	}
 }
+
+func TestEscapeInvalidUTF8(t *testing.T) {
+	t.Parallel()
+	tbl := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"select 1", "select 1"},
+		{"select '中文'", "select '中文'"},
+		{"select 'a\xffb'", `select 'a\xFFb'`},
+		{"\xff\xfe", `\xFF\xFE`},
+		{"中\xe4\xb8", `中\xE4\xB8`},
+		{"\xe4\xb8中\x80", `\xE4\xB8中\x80`},
+		{"\ufffd", "\ufffd"},
+		{"a\xed\xa0\x80b", `a\xED\xA0\x80b`},
+		{"\xc0\xaf", `\xC0\xAF`},
+	}
+	for _, v := range tbl {
+		require.Equalf(t, v.expected, EscapeInvalidUTF8(v.input), "source %v", v)
+	}
+}
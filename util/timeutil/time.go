@@ -71,7 +71,7 @@ func inferOneStepLinkForPath(path string) (string, error) {
 }
 
 // InferSystemTZ reads system timezone from `TZ`, the path of the soft link of `/etc/localtime`. If both of them are failed, system timezone will be set to `UTC`.
-// It is exported because we need to use it during bootstrap stage. And it should be only used at that stage.
+// It is exported because it is used to initialize and verify the system timezone stored in mysql.tidb.
 func InferSystemTZ() string {
 	// consult $TZ to find the time zone to use.
 	// no $TZ means use the system default /etc/localtime.
@@ -143,6 +143,13 @@ func SetSystemTZ(name string) {
 	})
 }
 
+// UpdateSystemTZ replaces systemTZ, it is used when the system timezone stored in mysql.tidb is reloaded.
+// Unlike SetSystemTZ, it takes effect even if systemTZ has been set before.
+func UpdateSystemTZ(name string) {
+	setSysTZOnce.Do(func() {})
+	systemTZ.Store(name)
+}
+
 // GetSystemTZ gets the value of systemTZ, an error is returned if systemTZ is not properly set.
 func GetSystemTZ() (string, error) {
 	systemTZ := systemTZ.Load()
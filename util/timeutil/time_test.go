@@ -86,3 +86,17 @@ func TestInferOneStepLinkForPath(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEqual(t, -1, strings.Index(link3, link1.Name()))
 }
+
+func TestUpdateSystemTZ(t *testing.T) {
+	origin := systemTZ.Load()
+	defer systemTZ.Store(origin)
+
+	SetSystemTZ("Asia/Shanghai")
+	UpdateSystemTZ("America/New_York")
+	require.Equal(t, "America/New_York", SystemLocation().String())
+	// SetSystemTZ doesn't overwrite the reloaded value.
+	SetSystemTZ("Asia/Shanghai")
+	tz, err := GetSystemTZ()
+	require.NoError(t, err)
+	require.Equal(t, "America/New_York", tz)
+}
@@ -20,11 +20,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tipb/go-tipb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
 // ReportClient send data to the target server.
@@ -61,21 +63,23 @@ func (r *GRPCReportClient) Send(ctx context.Context, targetRPCAddr string, data
 		return err
 	}
 
+	// The SQL and plan metas are deduplicated by the reporter, the compression only applies to the messages sent.
+	opts := callOptions(config.GetGlobalConfig().TopSQL.Compression)
 	var wg sync.WaitGroup
 	errCh := make(chan error, 3)
 	wg.Add(3)
 
 	go func() {
 		defer wg.Done()
-		errCh <- r.sendBatchSQLMeta(ctx, data.normalizedSQLMap)
+		errCh <- r.sendBatchSQLMeta(ctx, data.normalizedSQLMap, opts...)
 	}()
 	go func() {
 		defer wg.Done()
-		errCh <- r.sendBatchPlanMeta(ctx, data.normalizedPlanMap)
+		errCh <- r.sendBatchPlanMeta(ctx, data.normalizedPlanMap, opts...)
 	}()
 	go func() {
 		defer wg.Done()
-		errCh <- r.sendBatchCPUTimeRecord(ctx, data.collectedData)
+		errCh <- r.sendBatchCPUTimeRecord(ctx, data.collectedData, opts...)
 	}()
 	wg.Wait()
 	close(errCh)
@@ -87,6 +91,14 @@ func (r *GRPCReportClient) Send(ctx context.Context, targetRPCAddr string, data
 	return nil
 }
 
+// callOptions returns the call options of the reports compressed by the compression.
+func callOptions(compression string) []grpc.CallOption {
+	if compression == config.TopSQLCompressionGzip {
+		return []grpc.CallOption{grpc.UseCompressor(gzip.Name)}
+	}
+	return nil
+}
+
 // Close uses to close grpc connection.
 func (r *GRPCReportClient) Close() {
 	if r.conn == nil {
@@ -100,13 +112,13 @@ func (r *GRPCReportClient) Close() {
 }
 
 // sendBatchCPUTimeRecord sends a batch of TopSQL records by stream.
-func (r *GRPCReportClient) sendBatchCPUTimeRecord(ctx context.Context, records []*dataPoints) error {
+func (r *GRPCReportClient) sendBatchCPUTimeRecord(ctx context.Context, records []*dataPoints, opts ...grpc.CallOption) error {
 	if len(records) == 0 {
 		return nil
 	}
 	start := time.Now()
 	client := tipb.NewTopSQLAgentClient(r.conn)
-	stream, err := client.ReportCPUTimeRecords(ctx)
+	stream, err := client.ReportCPUTimeRecords(ctx, opts...)
 	if err != nil {
 		return err
 	}
@@ -133,10 +145,10 @@ func (r *GRPCReportClient) sendBatchCPUTimeRecord(ctx context.Context, records [
 }
 
 // sendBatchSQLMeta sends a batch of SQL metas by stream.
-func (r *GRPCReportClient) sendBatchSQLMeta(ctx context.Context, sqlMap *sync.Map) error {
+func (r *GRPCReportClient) sendBatchSQLMeta(ctx context.Context, sqlMap *sync.Map, opts ...grpc.CallOption) error {
 	start := time.Now()
 	client := tipb.NewTopSQLAgentClient(r.conn)
-	stream, err := client.ReportSQLMeta(ctx)
+	stream, err := client.ReportSQLMeta(ctx, opts...)
 	if err != nil {
 		return err
 	}
@@ -169,10 +181,10 @@ func (r *GRPCReportClient) sendBatchSQLMeta(ctx context.Context, sqlMap *sync.Ma
 }
 
 // sendBatchPlanMeta sends a batch of SQL metas by stream.
-func (r *GRPCReportClient) sendBatchPlanMeta(ctx context.Context, planMap *sync.Map) error {
+func (r *GRPCReportClient) sendBatchPlanMeta(ctx context.Context, planMap *sync.Map, opts ...grpc.CallOption) error {
 	start := time.Now()
 	client := tipb.NewTopSQLAgentClient(r.conn)
-	stream, err := client.ReportPlanMeta(ctx)
+	stream, err := client.ReportPlanMeta(ctx, opts...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,118 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/util/topsql/reporter/mock"
+	"github.com/pingcap/tipb/go-tipb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+func TestGRPCReportClientCompression(t *testing.T) {
+	agentServer, err := mock.StartMockAgentServer()
+	require.NoError(t, err)
+	defer agentServer.Stop()
+	defer config.RestoreFunc()()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.Compression = config.TopSQLCompressionGzip
+	})
+
+	sqlMap, planMap := &sync.Map{}, &sync.Map{}
+	sqlMap.Store("sqlDigest1", SQLMeta{normalizedSQL: "select * from t"})
+	planMap.Store("planDigest1", "plan1")
+	data := reportData{
+		collectedData: []*dataPoints{
+			{SQLDigest: []byte("sqlDigest1"), PlanDigest: []byte("planDigest1"), TimestampList: []uint64{1}, CPUTimeMsList: []uint32{10}},
+			{SQLDigest: []byte("sqlDigest2"), TimestampList: []uint64{1}, CPUTimeMsList: []uint32{20}},
+		},
+		normalizedSQLMap:  sqlMap,
+		normalizedPlanMap: planMap,
+	}
+	client := NewGRPCReportClient(mockPlanBinaryDecoderFunc)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, client.Send(ctx, agentServer.Address(), data))
+
+	agentServer.WaitCollectCnt(1, 10*time.Second)
+	require.Len(t, agentServer.GetLatestRecords(), 2)
+	meta, ok := agentServer.GetSQLMetaByDigestBlocking([]byte("sqlDigest1"), time.Second)
+	require.True(t, ok)
+	require.Equal(t, "select * from t", meta.NormalizedSql)
+	plan, ok := agentServer.GetPlanMetaByDigestBlocking([]byte("planDigest1"), time.Second)
+	require.True(t, ok)
+	require.Equal(t, "plan1", plan)
+}
+
+// BenchmarkReportCompression reports the size of a synthetic report of 5000 digests, with and without the gzip
+// compression of each gRPC message.
+func BenchmarkReportCompression(b *testing.B) {
+	type marshaler interface {
+		Marshal() ([]byte, error)
+	}
+	var messages []marshaler
+	for i := 0; i < 5000; i++ {
+		sqlDigest := sha256.Sum256([]byte(fmt.Sprintf("sql%d", i)))
+		planDigest := sha256.Sum256([]byte(fmt.Sprintf("plan%d", i)))
+		record := &tipb.CPUTimeRecord{SqlDigest: sqlDigest[:], PlanDigest: planDigest[:]}
+		for ts := uint64(0); ts < 60; ts++ {
+			record.RecordListTimestampSec = append(record.RecordListTimestampSec, 1630000000+ts)
+			record.RecordListCpuTimeMs = append(record.RecordListCpuTimeMs, uint32((i+int(ts))%100))
+		}
+		messages = append(messages,
+			record,
+			&tipb.SQLMeta{SqlDigest: sqlDigest[:], NormalizedSql: fmt.Sprintf("select `a` , `b` , `c` from `db%d` . `t%d` where `id` = ? and `c` in ( ... )", i%10, i)},
+			&tipb.PlanMeta{PlanDigest: planDigest[:], NormalizedPlan: fmt.Sprintf("\tProjection\troot\t\tdb%d.t%d.a\n\t└─IndexLookUp\troot\t\t\n\t  ├─IndexRangeScan\tcop[tikv]\ttable:t%d, index:idx(id)\trange:[?,?]\n\t  └─TableRowIDScan\tcop[tikv]\ttable:t%d\tkeep order:false", i%10, i, i, i)},
+		)
+	}
+	compressor := encoding.GetCompressor(gzip.Name)
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		rawSize, compressedSize := 0, 0
+		for _, msg := range messages {
+			data, err := msg.Marshal()
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf.Reset()
+			w, err := compressor.Compress(&buf)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err = w.Write(data); err != nil {
+				b.Fatal(err)
+			}
+			if err = w.Close(); err != nil {
+				b.Fatal(err)
+			}
+			rawSize += len(data)
+			compressedSize += buf.Len()
+		}
+		b.ReportMetric(float64(rawSize), "raw-bytes/report")
+		b.ReportMetric(float64(compressedSize), "gzip-bytes/report")
+	}
+}
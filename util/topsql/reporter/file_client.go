@@ -0,0 +1,174 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// reportFileName is the name of the file the reports are written to in the report directory.
+	reportFileName = "top_sql.log"
+	// reportFileMaxSize is the maximum size in MB of the report file before it is rotated.
+	reportFileMaxSize = 64
+	// reportFileMaxBackups is the maximum number of the rotated report files kept.
+	reportFileMaxBackups = 5
+)
+
+// fileReport is a report written to the report file as a JSON line.
+type fileReport struct {
+	Time      time.Time           `json:"time"`
+	Records   []fileCPUTimeRecord `json:"records,omitempty"`
+	SQLMetas  []fileSQLMeta       `json:"sql_metas,omitempty"`
+	PlanMetas []filePlanMeta      `json:"plan_metas,omitempty"`
+}
+
+type fileCPUTimeRecord struct {
+	// SQLDigest and PlanDigest are empty for the record aggregating all the records out of Top N.
+	SQLDigest  string   `json:"sql_digest"`
+	PlanDigest string   `json:"plan_digest,omitempty"`
+	Timestamps []uint64 `json:"timestamps"`
+	CPUTimeMs  []uint32 `json:"cpu_time_ms"`
+	// Executions, TotalDurationNs and MaxDurationNs are the executions finished in the report interval.
+	Executions      uint64 `json:"executions"`
+	TotalDurationNs uint64 `json:"total_duration_ns"`
+	MaxDurationNs   uint64 `json:"max_duration_ns"`
+	IsInternal      bool   `json:"is_internal,omitempty"`
+}
+
+type fileSQLMeta struct {
+	SQLDigest     string `json:"sql_digest"`
+	NormalizedSQL string `json:"normalized_sql"`
+	IsInternal    bool   `json:"is_internal,omitempty"`
+}
+
+type filePlanMeta struct {
+	PlanDigest     string `json:"plan_digest"`
+	NormalizedPlan string `json:"normalized_plan"`
+}
+
+// FileReportClient writes the reports as JSON lines to a file in a local directory, the file is rotated
+// once it is larger than 64MB.
+type FileReportClient struct {
+	mu sync.Mutex
+	// writers are the writers of the report files keyed by the directory, a writer is kept after the
+	// directory is changed because the rotated file can't be closed.
+	writers map[string]zapcore.WriteSyncer
+	// calling decodePlan this can take a while, so should not block critical paths
+	decodePlan planBinaryDecodeFunc
+}
+
+// NewFileReportClient returns a new FileReportClient.
+func NewFileReportClient(decodePlan planBinaryDecodeFunc) *FileReportClient {
+	return &FileReportClient{
+		writers:    make(map[string]zapcore.WriteSyncer),
+		decodePlan: decodePlan,
+	}
+}
+
+var _ ReportClient = &FileReportClient{}
+
+// Send implements the ReportClient interface, dir is the directory of the report file.
+func (r *FileReportClient) Send(_ context.Context, dir string, data reportData) error {
+	if dir == "" {
+		return nil
+	}
+	report := fileReport{Time: time.Now()}
+	for _, record := range data.collectedData {
+		report.Records = append(report.Records, fileCPUTimeRecord{
+			SQLDigest:  hex.EncodeToString(record.SQLDigest),
+			PlanDigest: hex.EncodeToString(record.PlanDigest),
+			Timestamps: record.TimestampList,
+			CPUTimeMs:  record.CPUTimeMsList,
+
+			Executions:      record.ExecCount,
+			TotalDurationNs: record.DurationSumNs,
+			MaxDurationNs:   record.DurationMaxNs,
+			IsInternal:      record.IsInternal,
+		})
+	}
+	data.normalizedSQLMap.Range(func(key, value interface{}) bool {
+		meta := value.(SQLMeta)
+		report.SQLMetas = append(report.SQLMetas, fileSQLMeta{
+			SQLDigest:     hex.EncodeToString([]byte(key.(string))),
+			NormalizedSQL: meta.normalizedSQL,
+			IsInternal:    meta.isInternal,
+		})
+		return true
+	})
+	data.normalizedPlanMap.Range(func(key, value interface{}) bool {
+		planDecoded, errDecode := r.decodePlan(value.(string))
+		if errDecode != nil {
+			logutil.BgLogger().Warn("[top-sql] decode plan failed", zap.Error(errDecode))
+			return true
+		}
+		report.PlanMetas = append(report.PlanMetas, filePlanMeta{
+			PlanDigest:     hex.EncodeToString([]byte(key.(string))),
+			NormalizedPlan: planDecoded,
+		})
+		return true
+	})
+	line, err := json.Marshal(report)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	writer, err := r.getWriter(dir)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(line, '\n'))
+	return errors.Trace(err)
+}
+
+func (r *FileReportClient) getWriter(dir string) (zapcore.WriteSyncer, error) {
+	if writer, ok := r.writers[dir]; ok {
+		return writer, nil
+	}
+	_, prop, err := log.InitLogger(&log.Config{File: log.FileLogConfig{
+		Filename:   filepath.Join(dir, reportFileName),
+		MaxSize:    reportFileMaxSize,
+		MaxBackups: reportFileMaxBackups,
+	}})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r.writers[dir] = prop.Syncer
+	return prop.Syncer, nil
+}
+
+// Close implements the ReportClient interface.
+func (r *FileReportClient) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, writer := range r.writers {
+		if err := writer.Sync(); err != nil {
+			logutil.BgLogger().Warn("[top-sql] sync the report file failed", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/util/topsql/reporter/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func readReports(t *testing.T, filename string) []fileReport {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	var reports []fileReport
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var report fileReport
+		require.NoError(t, json.Unmarshal([]byte(line), &report))
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func TestReportToFile(t *testing.T) {
+	agentServer, err := mock.StartMockAgentServer()
+	require.NoError(t, err)
+	defer agentServer.Stop()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, reportFileName)
+	tsr := setupRemoteTopSQLReporter(5, 1, "")
+	defer tsr.Close()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.ReportDir = dir
+	})
+	defer config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.ReportDir = ""
+	})
+
+	// The reports are written to the file while the receiver address is empty, the records out of
+	// Top N are evicted into the others record in the same way.
+	populateCache(tsr, 0, 10, 1)
+	require.Eventually(t, func() bool { return len(readReports(t, filename)) > 0 }, 5*time.Second, 100*time.Millisecond)
+	report := readReports(t, filename)[0]
+	require.Len(t, report.Records, 6)
+	others := 0
+	for _, record := range report.Records {
+		if record.SQLDigest == "" {
+			others++
+			continue
+		}
+		digest, err := hex.DecodeString(record.SQLDigest)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(digest), "sqlDigest"))
+		require.Len(t, record.CPUTimeMs, 1)
+		require.Equal(t, []uint64{1}, record.Timestamps)
+	}
+	require.Equal(t, 1, others)
+	sqlMetas := make(map[string]string)
+	for _, meta := range report.SQLMetas {
+		sqlMetas[meta.SQLDigest] = meta.NormalizedSQL
+	}
+	require.Equal(t, "sqlNormalized10", sqlMetas[hex.EncodeToString([]byte("sqlDigest10"))])
+	planMetas := make(map[string]string)
+	for _, meta := range report.PlanMetas {
+		planMetas[meta.PlanDigest] = meta.NormalizedPlan
+	}
+	require.Equal(t, "planNormalized10", planMetas[hex.EncodeToString([]byte("planDigest10"))])
+
+	// The reports are sent to the receiver once its address is set.
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.ReceiverAddress = agentServer.Address()
+	})
+	defer config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.ReceiverAddress = ""
+	})
+	reports := len(readReports(t, filename))
+	populateCache(tsr, 0, 10, 2)
+	agentServer.WaitCollectCnt(1, 5*time.Second)
+	require.Len(t, agentServer.GetLatestRecords(), 6)
+	require.Len(t, readReports(t, filename), reports)
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// receiverRetryBaseDelay and receiverRetryMaxDelay bound the exponential backoff of retrying the reports
+// failed to send.
+const (
+	receiverRetryBaseDelay = time.Second
+	receiverRetryMaxDelay  = time.Minute
+)
+
+// parseReceiverAddresses splits the comma-separated receiver addresses, the empty and duplicated ones
+// are ignored.
+func parseReceiverAddresses(addresses string) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	for _, addr := range strings.Split(addresses, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		result = append(result, addr)
+	}
+	return result
+}
+
+// receiver sends the reports to a receiver address with its own client and goroutine, so that a slow
+// receiver does not block the others. The reports failed to send are kept in memory and retried with
+// an exponential backoff, at most tidb_top_sql_max_pending_reports ones are kept and the oldest ones are
+// dropped first. The retried reports keep their original timestamps.
+type receiver struct {
+	addr   string
+	client ReportClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	dataCh chan reportData
+	wg     sync.WaitGroup
+
+	sentCounter    prometheus.Counter
+	failedCounter  prometheus.Counter
+	droppedCounter prometheus.Counter
+}
+
+func newReceiver(ctx context.Context, addr string, client ReportClient) *receiver {
+	r := &receiver{
+		addr:           addr,
+		client:         client,
+		dataCh:         make(chan reportData, 1),
+		sentCounter:    metrics.TopSQLReceiverReportCounter.WithLabelValues(addr, "sent"),
+		failedCounter:  metrics.TopSQLReceiverReportCounter.WithLabelValues(addr, "failed"),
+		droppedCounter: metrics.TopSQLReceiverReportCounter.WithLabelValues(addr, "dropped"),
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// send queues the report without blocking, the report is dropped if the receiver is still busy sending
+// the previous one.
+func (r *receiver) send(data reportData) {
+	select {
+	case r.dataCh <- data:
+	default:
+		r.droppedCounter.Inc()
+	}
+}
+
+func (r *receiver) run() {
+	defer r.wg.Done()
+	defer util.Recover("top-sql", "receiver", nil, false)
+
+	var pending []reportData
+	var retryCh <-chan time.Time
+	backoff := time.Duration(0)
+	for {
+		select {
+		case data := <-r.dataCh:
+			pending = r.appendPending(pending, data)
+			if retryCh != nil {
+				// Wait for the backoff, the new report is sent along with the failed ones.
+				continue
+			}
+		case <-retryCh:
+			retryCh = nil
+		case <-r.ctx.Done():
+			return
+		}
+		pending = r.sendPending(pending)
+		if len(pending) == 0 {
+			backoff = 0
+			continue
+		}
+		backoff *= 2
+		if backoff < receiverRetryBaseDelay {
+			backoff = receiverRetryBaseDelay
+		} else if backoff > receiverRetryMaxDelay {
+			backoff = receiverRetryMaxDelay
+		}
+		retryCh = time.After(backoff)
+	}
+}
+
+// appendPending appends the report to the pending ones and drops the oldest ones if there are too many.
+func (r *receiver) appendPending(pending []reportData, data reportData) []reportData {
+	pending = append(pending, data)
+	if n := int64(len(pending)) - variable.TopSQLVariable.MaxPendingReports.Load(); n > 0 {
+		r.droppedCounter.Add(float64(n))
+		for i := int64(0); i < n; i++ {
+			pending[i] = reportData{}
+		}
+		pending = pending[n:]
+	}
+	return pending
+}
+
+// sendPending sends the pending reports from the oldest one until it fails, it returns the reports which
+// are not sent.
+func (r *receiver) sendPending(pending []reportData) []reportData {
+	for len(pending) > 0 && r.ctx.Err() == nil {
+		if err := r.doSend(pending[0]); err != nil {
+			logutil.BgLogger().Warn("[top-sql] client failed to send data", zap.String("receiver", r.addr),
+				zap.Int("pending", len(pending)), zap.Error(err))
+			r.failedCounter.Inc()
+			return pending
+		}
+		r.sentCounter.Inc()
+		pending[0] = reportData{}
+		pending = pending[1:]
+	}
+	return pending
+}
+
+func (r *receiver) doSend(data reportData) error {
+	ctx, cancel := context.WithTimeout(r.ctx, getReportTimeout())
+	defer cancel()
+	start := time.Now()
+	err := r.client.Send(ctx, r.addr, data)
+	if err != nil {
+		reportAllDurationFailedHistogram.Observe(time.Since(start).Seconds())
+	} else {
+		reportAllDurationSuccHistogram.Observe(time.Since(start).Seconds())
+	}
+	return err
+}
+
+// close stops sending and closes the client, the pending reports are dropped.
+func (r *receiver) close() {
+	r.cancel()
+	r.wg.Wait()
+	r.client.Close()
+}
+
+func getReportTimeout() time.Duration {
+	timeout := reportTimeout
+	failpoint.Inject("resetTimeoutForTest", func(val failpoint.Value) {
+		if val.(bool) {
+			interval := time.Duration(variable.TopSQLVariable.ReportIntervalSeconds.Load()) * time.Second
+			if interval < timeout {
+				timeout = interval
+			}
+		}
+	})
+	return timeout
+}
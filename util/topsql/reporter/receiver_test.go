@@ -0,0 +1,108 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/stretchr/testify/require"
+)
+
+// failingReportClient records the timestamps of the reports sent, it fails while failing is set.
+type failingReportClient struct {
+	sync.Mutex
+	failing bool
+	sent    []uint64
+}
+
+func (c *failingReportClient) Send(_ context.Context, _ string, data reportData) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.failing {
+		return errors.New("receiver is unavailable")
+	}
+	c.sent = append(c.sent, data.collectedData[0].TimestampList[0])
+	return nil
+}
+
+func (c *failingReportClient) Close() {}
+
+func (c *failingReportClient) setFailing(failing bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.failing = failing
+}
+
+func (c *failingReportClient) getSent() []uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return append([]uint64(nil), c.sent...)
+}
+
+func newTestReport(ts uint64) reportData {
+	return reportData{collectedData: []*dataPoints{{TimestampList: []uint64{ts}, CPUTimeMsList: []uint32{1}}}}
+}
+
+func TestReceiverPendingReports(t *testing.T) {
+	defer variable.TopSQLVariable.MaxPendingReports.Store(variable.DefTiDBTopSQLMaxPendingReports)
+	variable.TopSQLVariable.MaxPendingReports.Store(2)
+	client := &failingReportClient{failing: true}
+	r := &receiver{
+		addr:           "test",
+		client:         client,
+		sentCounter:    metrics.TopSQLReceiverReportCounter.WithLabelValues("test", "sent"),
+		failedCounter:  metrics.TopSQLReceiverReportCounter.WithLabelValues("test", "failed"),
+		droppedCounter: metrics.TopSQLReceiverReportCounter.WithLabelValues("test", "dropped"),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	defer r.cancel()
+
+	// The failed reports are kept, the oldest ones are dropped first.
+	var pending []reportData
+	for ts := uint64(1); ts <= 3; ts++ {
+		pending = r.appendPending(pending, newTestReport(ts))
+		pending = r.sendPending(pending)
+	}
+	require.Len(t, pending, 2)
+	require.Empty(t, client.getSent())
+
+	// The kept reports are sent in order with their original timestamps once the receiver recovers.
+	client.setFailing(false)
+	pending = r.appendPending(pending, newTestReport(4))
+	pending = r.sendPending(pending)
+	require.Empty(t, pending)
+	require.Equal(t, []uint64{3, 4}, client.getSent())
+}
+
+func TestReceiverRetry(t *testing.T) {
+	client := &failingReportClient{failing: true}
+	r := newReceiver(context.Background(), "test", client)
+	defer r.close()
+
+	// The failed report is retried with the backoff without waiting for the next report.
+	r.send(newTestReport(1))
+	time.Sleep(100 * time.Millisecond)
+	client.setFailing(false)
+	require.Eventually(t, func() bool {
+		return len(client.getSent()) == 1
+	}, 5*time.Second, 100*time.Millisecond)
+	require.Equal(t, []uint64{1}, client.getSent())
+}
@@ -16,17 +16,19 @@ package reporter
 
 import (
 	"bytes"
+	"container/heap"
 	"context"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/pingcap/failpoint"
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/metrics"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/topsql/tracecpu"
 	"github.com/wangjohn/quickselect"
@@ -50,22 +52,65 @@ type TopSQLReporter interface {
 	tracecpu.Collector
 	RegisterSQL(sqlDigest []byte, normalizedSQL string, isInternal bool)
 	RegisterPlan(planDigest []byte, normalizedPlan string)
+	// ObserveExecution records an execution of the SQL and plan which has finished in duration.
+	ObserveExecution(sqlDigest, planDigest []byte, isInternal bool, duration time.Duration)
+	// CacheStats returns the hit, miss and eviction counts of the registered SQL cache.
+	CacheStats() (hits, misses, evictions int64)
 	Close()
 }
 
 type cpuData struct {
-	timestamp uint64
+	timestamp time.Time
 	records   []tracecpu.SQLCPUTimeRecord
 }
 
 // dataPoints represents the cumulative SQL plan CPU time in current minute window
 // dataPoints do not guarantee the TimestampList is sorted by timestamp when there is a time jump backward.
 type dataPoints struct {
-	SQLDigest      []byte
-	PlanDigest     []byte
+	SQLDigest  []byte
+	PlanDigest []byte
+	// TimestampList is in Unix nanoseconds when collecting, and is converted to the buckets of
+	// tidb_top_sql_precision_seconds in Unix seconds by bucketByPrecision when reporting.
 	TimestampList  []uint64
 	CPUTimeMsList  []uint32
 	CPUTimeMsTotal uint64
+	// ExecCount, DurationSumNs and DurationMaxNs are the executions finished in the report interval.
+	ExecCount     uint64
+	DurationSumNs uint64
+	DurationMaxNs uint64
+	// IsInternal is true if the SQL is executed internally, such as by the statistics and DDL jobs.
+	IsInternal bool
+}
+
+// addExecStats merges the execution stats of other into d.
+func (d *dataPoints) addExecStats(other *dataPoints) {
+	d.ExecCount += other.ExecCount
+	d.DurationSumNs += other.DurationSumNs
+	if other.DurationMaxNs > d.DurationMaxNs {
+		d.DurationMaxNs = other.DurationMaxNs
+	}
+}
+
+// bucketByPrecision converts the timestamps in nanoseconds to the Unix seconds aligned to the precision,
+// and sums up the CPU time in the same bucket. The dataPoints must be sorted by timestamp.
+func (d *dataPoints) bucketByPrecision(precision uint64) {
+	if precision == 0 {
+		precision = 1
+	}
+	n := 0
+	for i, ts := range d.TimestampList {
+		sec := ts / uint64(time.Second)
+		sec -= sec % precision
+		if n > 0 && d.TimestampList[n-1] == sec {
+			d.CPUTimeMsList[n-1] += d.CPUTimeMsList[i]
+			continue
+		}
+		d.TimestampList[n] = sec
+		d.CPUTimeMsList[n] = d.CPUTimeMsList[i]
+		n++
+	}
+	d.TimestampList = d.TimestampList[:n]
+	d.CPUTimeMsList = d.CPUTimeMsList[:n]
 }
 
 func (d *dataPoints) isInvalid() bool {
@@ -99,6 +144,20 @@ func (t dataPointsOrderByCPUTime) Swap(i, j int) {
 	t[i], t[j] = t[j], t[i]
 }
 
+type dataPointsOrderByLatency []*dataPoints
+
+func (t dataPointsOrderByLatency) Len() int {
+	return len(t)
+}
+
+func (t dataPointsOrderByLatency) Less(i, j int) bool {
+	// We need find the kth largest value, so here should use >
+	return t[i].DurationSumNs > t[j].DurationSumNs
+}
+func (t dataPointsOrderByLatency) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
 type sqlCPUTimeRecordSlice []tracecpu.SQLCPUTimeRecord
 
 func (t sqlCPUTimeRecordSlice) Len() int {
@@ -118,19 +177,44 @@ type planBinaryDecodeFunc func(string) (string, error)
 // RemoteTopSQLReporter implements a TopSQL reporter that sends data to a remote agent
 // This should be called periodically to collect TopSQL resource usage metrics
 type RemoteTopSQLReporter struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	client ReportClient
+	ctx        context.Context
+	cancel     context.CancelFunc
+	decodePlan planBinaryDecodeFunc
+	// receivers are the senders of the reports keyed by the receiver address, they are started and
+	// stopped by the report worker according to the comma-separated receiver-address.
+	receiversMu sync.Mutex
+	receivers   map[string]*receiver
+	// fileClient writes the reports to the report directory when the receiver address is empty.
+	fileClient ReportClient
+	// window keeps the reports of the last MaxWindow in memory for TopStats.
+	window *topSQLWindow
 
 	// normalizedSQLMap is an map, whose keys are SQL digest strings and values are SQLMeta.
 	normalizedSQLMap atomic.Value // sync.Map
 	sqlMapLength     atomic2.Int64
 
+	// sqlCache keeps the SQL meta of the registered SQL digests across report cycles, whose capacity is
+	// `tidb_top_sql_max_statement_count * 2` and eviction policy is `tidb_top_sql_eviction_policy`.
+	// The SQL meta is collected in every report cycle the SQL digest is registered in; the cache only
+	// supplies the SQL meta of the records whose SQL digests are not registered in the report cycle.
+	sqlCacheMu        sync.Mutex
+	sqlCache          registeredSQLCache
+	sqlCacheCapacity  uint
+	sqlCachePolicy    string
+	sqlCacheHits      atomic2.Int64
+	sqlCacheMisses    atomic2.Int64
+	sqlCacheEvictions atomic2.Int64
+
 	// normalizedPlanMap is an map, whose keys are plan digest strings and values are normalized plans **in binary**.
 	// The normalized plans in binary can be decoded to string using the `planBinaryDecoder`.
 	normalizedPlanMap atomic.Value // sync.Map
 	planMapLength     atomic2.Int64
 
+	// execStats is a map, whose keys are the SQL digest + plan digest and values are the execution stats
+	// observed in the current report interval.
+	execStatsMu sync.Mutex
+	execStats   map[string]*dataPoints
+
 	collectCPUDataChan      chan cpuData
 	reportCollectedDataChan chan collectedData
 }
@@ -145,17 +229,22 @@ type SQLMeta struct {
 //
 // planBinaryDecoder is a decoding function which will be called asynchronously to decode the plan binary to string
 // MaxStatementsNum is the maximum SQL and plan number, which will restrict the memory usage of the internal LFU cache
-func NewRemoteTopSQLReporter(client ReportClient) *RemoteTopSQLReporter {
+func NewRemoteTopSQLReporter(decodePlan planBinaryDecodeFunc) *RemoteTopSQLReporter {
 	ctx, cancel := context.WithCancel(context.Background())
 	tsr := &RemoteTopSQLReporter{
 		ctx:                     ctx,
 		cancel:                  cancel,
-		client:                  client,
+		decodePlan:              decodePlan,
+		receivers:               make(map[string]*receiver),
+		fileClient:              NewFileReportClient(decodePlan),
+		window:                  newTopSQLWindow(),
+		execStats:               make(map[string]*dataPoints),
 		collectCPUDataChan:      make(chan cpuData, 1),
 		reportCollectedDataChan: make(chan collectedData, 1),
 	}
 	tsr.normalizedSQLMap.Store(&sync.Map{})
 	tsr.normalizedPlanMap.Store(&sync.Map{})
+	tsr.resetSQLCache(sqlCacheCapacity(), variable.TopSQLVariable.EvictionPolicy.Load())
 
 	go tsr.collectWorker()
 	go tsr.reportWorker()
@@ -188,19 +277,272 @@ var (
 // This function should be thread-safe, which means parallelly calling it in several goroutines should be fine.
 // It should also return immediately, and do any CPU-intensive job asynchronously.
 func (tsr *RemoteTopSQLReporter) RegisterSQL(sqlDigest []byte, normalizedSQL string, isInternal bool) {
-	if tsr.sqlMapLength.Load() >= variable.TopSQLVariable.MaxCollect.Load() {
-		ignoreExceedSQLCounter.Inc()
+	if isInternal && !variable.TopSQLVariable.IncludeInternal.Load() {
 		return
 	}
 	m := tsr.normalizedSQLMap.Load().(*sync.Map)
 	key := string(sqlDigest)
-	_, loaded := m.LoadOrStore(key, SQLMeta{
+	if _, ok := m.Load(key); ok {
+		// The SQL meta has been collected in the current report cycle.
+		return
+	}
+	if tsr.sqlMapLength.Load() >= variable.TopSQLVariable.MaxCollect.Load() {
+		ignoreExceedSQLCounter.Inc()
+		return
+	}
+	meta := SQLMeta{
 		normalizedSQL: normalizedSQL,
 		isInternal:    isInternal,
+	}
+	if _, loaded := m.LoadOrStore(key, meta); loaded {
+		return
+	}
+	tsr.sqlMapLength.Add(1)
+
+	tsr.sqlCacheMu.Lock()
+	defer tsr.sqlCacheMu.Unlock()
+	tsr.adjustSQLCacheLocked()
+	if _, ok := tsr.sqlCache.get(sqlDigest); ok {
+		tsr.sqlCacheHits.Inc()
+	} else {
+		tsr.sqlCacheMisses.Inc()
+	}
+	tsr.sqlCache.put(sqlDigest, meta)
+}
+
+// adjustSQLCacheLocked rebuilds or resizes the registered SQL cache if `tidb_top_sql_eviction_policy` or
+// `tidb_top_sql_max_statement_count` has been changed. The caller should hold sqlCacheMu.
+func (tsr *RemoteTopSQLReporter) adjustSQLCacheLocked() {
+	capacity, policy := sqlCacheCapacity(), variable.TopSQLVariable.EvictionPolicy.Load()
+	if policy != tsr.sqlCachePolicy {
+		tsr.sqlCacheEvictions.Add(int64(tsr.sqlCache.size()))
+		tsr.resetSQLCache(capacity, policy)
+	} else if capacity != tsr.sqlCacheCapacity {
+		size := tsr.sqlCache.size()
+		if err := tsr.sqlCache.setCapacity(capacity); err != nil {
+			logutil.BgLogger().Warn("[top-sql] failed to resize the SQL cache", zap.Error(err))
+		} else {
+			tsr.sqlCacheCapacity = capacity
+			// setCapacity does not call the eviction callback.
+			tsr.sqlCacheEvictions.Add(int64(size - tsr.sqlCache.size()))
+		}
+	}
+}
+
+// fillSQLMetaLocked adds the cached SQL meta of the records whose SQL digests are not registered in the
+// report cycle of sqlMap, such as the statements running across report cycles. The caller should hold sqlCacheMu.
+func (tsr *RemoteTopSQLReporter) fillSQLMetaLocked(sqlMap *sync.Map, records ...map[string]*dataPoints) {
+	for _, m := range records {
+		for _, record := range m {
+			if len(record.SQLDigest) == 0 {
+				continue
+			}
+			key := string(record.SQLDigest)
+			if _, ok := sqlMap.Load(key); ok {
+				continue
+			}
+			if meta, ok := tsr.sqlCache.get(record.SQLDigest); ok {
+				sqlMap.Store(key, meta)
+			}
+		}
+	}
+}
+
+// CacheStats returns the hit, miss and eviction counts of the registered SQL cache.
+func (tsr *RemoteTopSQLReporter) CacheStats() (hits, misses, evictions int64) {
+	return tsr.sqlCacheHits.Load(), tsr.sqlCacheMisses.Load(), tsr.sqlCacheEvictions.Load()
+}
+
+// resetSQLCache replaces the registered SQL cache with an empty one. The caller should hold sqlCacheMu.
+func (tsr *RemoteTopSQLReporter) resetSQLCache(capacity uint, policy string) {
+	onEvict := func() {
+		tsr.sqlCacheEvictions.Inc()
+	}
+	if policy == variable.TopSQLEvictionPolicyLRU {
+		tsr.sqlCache = newLRUSQLCache(capacity, onEvict)
+	} else {
+		tsr.sqlCache = newLFUSQLCache(capacity, onEvict)
+	}
+	tsr.sqlCacheCapacity = capacity
+	tsr.sqlCachePolicy = policy
+}
+
+// registeredSQLCache is the cache of the SQL meta of the registered SQL digests. It is not thread-safe.
+type registeredSQLCache interface {
+	// get returns the SQL meta of the SQL digest if it is cached.
+	get(sqlDigest []byte) (SQLMeta, bool)
+	// put caches the SQL meta of the SQL digest, and evicts one if the cache is full.
+	put(sqlDigest []byte, meta SQLMeta)
+	// addCPUTime accumulates the CPU time of the SQL digest if it is cached.
+	addCPUTime(sqlDigest []byte, cpuTimeMs uint32)
+	size() int
+	// setCapacity resizes the cache without calling the eviction callback.
+	setCapacity(capacity uint) error
+}
+
+// sqlDigestKey is the key of a SQL digest in the registered SQL cache.
+type sqlDigestKey []byte
+
+// Hash implements the kvcache.Key interface.
+func (k sqlDigestKey) Hash() []byte {
+	return k
+}
+
+// lruSQLCache evicts the least recently registered SQL digest.
+type lruSQLCache struct {
+	cache *kvcache.SimpleLRUCache
+}
+
+func newLRUSQLCache(capacity uint, onEvict func()) *lruSQLCache {
+	cache := kvcache.NewSimpleLRUCache(capacity, 0, 0)
+	cache.SetOnEvict(func(kvcache.Key, kvcache.Value) {
+		onEvict()
 	})
-	if !loaded {
-		tsr.sqlMapLength.Add(1)
+	return &lruSQLCache{cache: cache}
+}
+
+func (c *lruSQLCache) get(sqlDigest []byte) (SQLMeta, bool) {
+	value, ok := c.cache.Get(sqlDigestKey(sqlDigest))
+	if !ok {
+		return SQLMeta{}, false
+	}
+	return value.(SQLMeta), true
+}
+
+func (c *lruSQLCache) put(sqlDigest []byte, meta SQLMeta) {
+	c.cache.Put(sqlDigestKey(sqlDigest), meta)
+}
+
+func (c *lruSQLCache) addCPUTime([]byte, uint32) {}
+
+func (c *lruSQLCache) size() int {
+	return c.cache.Size()
+}
+
+func (c *lruSQLCache) setCapacity(capacity uint) error {
+	return c.cache.SetCapacity(capacity)
+}
+
+// lfuSQLCache evicts the SQL digest with the least cumulative CPU time, so that a burst of the SQLs which
+// are executed recently but rarely does not evict the frequent ones, whose SQL meta keeps being supplied to
+// the later reports. The earliest registered SQL digest is evicted if several ones have the same CPU time.
+type lfuSQLCache struct {
+	capacity uint
+	items    map[string]*lfuSQLCacheItem
+	heap     lfuSQLCacheHeap
+	// seq is the order of the registered SQL digests.
+	seq     uint64
+	onEvict func()
+}
+
+type lfuSQLCacheItem struct {
+	sqlDigest string
+	meta      SQLMeta
+	cpuTimeMs uint64
+	seq       uint64
+	index     int
+}
+
+// lfuSQLCacheHeap is a min-heap of the cached items ordered by the CPU time.
+type lfuSQLCacheHeap []*lfuSQLCacheItem
+
+func (h lfuSQLCacheHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuSQLCacheHeap) Less(i, j int) bool {
+	if h[i].cpuTimeMs != h[j].cpuTimeMs {
+		return h[i].cpuTimeMs < h[j].cpuTimeMs
 	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuSQLCacheHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuSQLCacheHeap) Push(x interface{}) {
+	item := x.(*lfuSQLCacheItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuSQLCacheHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+func newLFUSQLCache(capacity uint, onEvict func()) *lfuSQLCache {
+	return &lfuSQLCache{
+		capacity: capacity,
+		items:    make(map[string]*lfuSQLCacheItem),
+		onEvict:  onEvict,
+	}
+}
+
+func (c *lfuSQLCache) get(sqlDigest []byte) (SQLMeta, bool) {
+	item, ok := c.items[string(sqlDigest)]
+	if !ok {
+		return SQLMeta{}, false
+	}
+	return item.meta, true
+}
+
+func (c *lfuSQLCache) put(sqlDigest []byte, meta SQLMeta) {
+	if item, ok := c.items[string(sqlDigest)]; ok {
+		item.meta = meta
+		return
+	}
+	if uint(len(c.items)) >= c.capacity {
+		c.evict()
+		c.onEvict()
+	}
+	c.seq++
+	item := &lfuSQLCacheItem{sqlDigest: string(sqlDigest), meta: meta, seq: c.seq}
+	c.items[item.sqlDigest] = item
+	heap.Push(&c.heap, item)
+}
+
+func (c *lfuSQLCache) addCPUTime(sqlDigest []byte, cpuTimeMs uint32) {
+	if item, ok := c.items[string(sqlDigest)]; ok && cpuTimeMs > 0 {
+		item.cpuTimeMs += uint64(cpuTimeMs)
+		heap.Fix(&c.heap, item.index)
+	}
+}
+
+func (c *lfuSQLCache) evict() {
+	item := heap.Pop(&c.heap).(*lfuSQLCacheItem)
+	delete(c.items, item.sqlDigest)
+}
+
+func (c *lfuSQLCache) size() int {
+	return len(c.items)
+}
+
+func (c *lfuSQLCache) setCapacity(capacity uint) error {
+	if capacity < 1 {
+		return errors.New("capacity of LFU cache should be at least 1")
+	}
+	c.capacity = capacity
+	for uint(len(c.items)) > capacity {
+		c.evict()
+	}
+	return nil
+}
+
+// sqlCacheCapacity returns the capacity of the registered SQL cache.
+func sqlCacheCapacity() uint {
+	capacity := variable.TopSQLVariable.MaxStatementCount.Load() * 2
+	if capacity < 1 {
+		capacity = 1
+	}
+	return uint(capacity)
 }
 
 // RegisterPlan is like RegisterSQL, but for normalized plan strings.
@@ -218,9 +560,44 @@ func (tsr *RemoteTopSQLReporter) RegisterPlan(planDigest []byte, normalizedBinar
 	}
 }
 
+// ObserveExecution records an execution of the SQL and plan which has finished in duration. The executions
+// of the new SQL and plan are aggregated into "others" once tidb_top_sql_max_collect ones are observed.
+// This function is thread-safe and efficient.
+func (tsr *RemoteTopSQLReporter) ObserveExecution(sqlDigest, planDigest []byte, isInternal bool, duration time.Duration) {
+	if len(sqlDigest) == 0 || (isInternal && !variable.TopSQLVariable.IncludeInternal.Load()) {
+		return
+	}
+	if duration < 0 {
+		duration = 0
+	}
+	key := string(sqlDigest) + string(planDigest)
+	tsr.execStatsMu.Lock()
+	defer tsr.execStatsMu.Unlock()
+	entry, ok := tsr.execStats[key]
+	if !ok {
+		if int64(len(tsr.execStats)) >= variable.TopSQLVariable.MaxCollect.Load() {
+			key = keyOthers
+			entry, ok = tsr.execStats[key]
+			if !ok {
+				entry = &dataPoints{}
+			}
+		} else {
+			entry = &dataPoints{SQLDigest: sqlDigest, PlanDigest: planDigest, IsInternal: isInternal}
+		}
+		tsr.execStats[key] = entry
+	}
+	entry.addExecStats(&dataPoints{ExecCount: 1, DurationSumNs: uint64(duration), DurationMaxNs: uint64(duration)})
+}
+
+// TopStats returns the top limit SQLs and plans ordered by the CPU time in the reports of the last window,
+// which is at most MaxWindow. The data of the current report interval is not included.
+func (tsr *RemoteTopSQLReporter) TopStats(window time.Duration, limit int) []TopSQLStat {
+	return tsr.window.query(time.Now(), window, limit, tsr.decodePlan)
+}
+
 // Collect receives CPU time records for processing. WARN: It will drop the records if the processing is not in time.
 // This function is thread-safe and efficient.
-func (tsr *RemoteTopSQLReporter) Collect(timestamp uint64, records []tracecpu.SQLCPUTimeRecord) {
+func (tsr *RemoteTopSQLReporter) Collect(timestamp time.Time, records []tracecpu.SQLCPUTimeRecord) {
 	if len(records) == 0 {
 		return
 	}
@@ -238,7 +615,14 @@ func (tsr *RemoteTopSQLReporter) Collect(timestamp uint64, records []tracecpu.SQ
 // Close uses to close and release the reporter resource.
 func (tsr *RemoteTopSQLReporter) Close() {
 	tsr.cancel()
-	tsr.client.Close()
+	tsr.receiversMu.Lock()
+	receivers := tsr.receivers
+	tsr.receivers = make(map[string]*receiver)
+	tsr.receiversMu.Unlock()
+	for _, r := range receivers {
+		r.close()
+	}
+	tsr.fileClient.Close()
 }
 
 func addEvictedCPUTime(collectTarget map[string]*dataPoints, timestamp uint64, totalCPUTimeMs uint32) {
@@ -267,7 +651,11 @@ func addEvictedIntoSortedDataPoints(others *dataPoints, evict *dataPoints) *data
 	if others == nil {
 		others = &dataPoints{}
 	}
-	if evict == nil || len(evict.TimestampList) == 0 {
+	if evict == nil {
+		return others
+	}
+	others.addExecStats(evict)
+	if len(evict.TimestampList) == 0 {
 		return others
 	}
 	if evict.isInvalid() {
@@ -365,7 +753,11 @@ func getTopNDataPoints(records []*dataPoints) (topN, shouldEvict []*dataPoints)
 	if len(records) <= maxStmt {
 		return records, nil
 	}
-	if err := quickselect.QuickSelect(dataPointsOrderByCPUTime(records), maxStmt); err != nil {
+	var data quickselect.Interface = dataPointsOrderByCPUTime(records)
+	if variable.TopSQLVariable.TopNMetric.Load() == variable.TopSQLTopNMetricLatency {
+		data = dataPointsOrderByLatency(records)
+	}
+	if err := quickselect.QuickSelect(data, maxStmt); err != nil {
 		//	skip eviction
 		return records, nil
 	}
@@ -375,15 +767,37 @@ func getTopNDataPoints(records []*dataPoints) (topN, shouldEvict []*dataPoints)
 // doCollect collects top N records of each round into collectTarget, and evict the data that is not in top N.
 // All the evicted record will be summary into the collectedData.others.
 func (tsr *RemoteTopSQLReporter) doCollect(
-	collectTarget map[string]*dataPoints, timestamp uint64, records []tracecpu.SQLCPUTimeRecord) {
+	collectTarget map[string]*dataPoints, ts time.Time, records []tracecpu.SQLCPUTimeRecord) {
 	defer util.Recover("top-sql", "doCollect", nil, false)
+	if ts.Before(time.Unix(0, 0)) {
+		ts = time.Unix(0, 0)
+	}
+	timestamp := uint64(ts.UnixNano())
+
+	if !variable.TopSQLVariable.IncludeInternal.Load() {
+		n := 0
+		for _, record := range records {
+			if !record.IsInternal {
+				records[n] = record
+				n++
+			}
+		}
+		records = records[:n]
+	}
+
+	// Accumulate the CPU time of the registered SQLs for the LFU eviction.
+	tsr.sqlCacheMu.Lock()
+	for _, record := range records {
+		tsr.sqlCache.addCPUTime(record.SQLDigest, record.CPUTimeMs)
+	}
+	tsr.sqlCacheMu.Unlock()
 
 	// Get top N records of each round records.
 	var evicted []tracecpu.SQLCPUTimeRecord
 	records, evicted = getTopNRecords(records)
 
 	keyBuf := bytes.NewBuffer(make([]byte, 0, 64))
-	listCapacity := int(variable.TopSQLVariable.ReportIntervalSeconds.Load()/variable.TopSQLVariable.PrecisionSeconds.Load() + 1)
+	listCapacity := int(time.Duration(variable.TopSQLVariable.ReportIntervalSeconds.Load())*time.Second/tracecpu.ProfileInterval + 1)
 	if listCapacity < 1 {
 		listCapacity = 1
 	}
@@ -395,6 +809,7 @@ func (tsr *RemoteTopSQLReporter) doCollect(
 			entry = &dataPoints{
 				SQLDigest:     record.SQLDigest,
 				PlanDigest:    record.PlanDigest,
+				IsInternal:    record.IsInternal,
 				CPUTimeMsList: make([]uint32, 1, listCapacity),
 				TimestampList: make([]uint64, 1, listCapacity),
 			}
@@ -431,9 +846,17 @@ func (tsr *RemoteTopSQLReporter) takeDataAndSendToReportChan(collectedDataPtr *m
 	// Reset data for next report.
 	*collectedDataPtr = make(map[string]*dataPoints)
 	tsr.normalizedSQLMap.Store(&sync.Map{})
-	tsr.normalizedPlanMap.Store(&sync.Map{})
 	tsr.sqlMapLength.Store(0)
+	tsr.normalizedPlanMap.Store(&sync.Map{})
 	tsr.planMapLength.Store(0)
+	tsr.execStatsMu.Lock()
+	data.execStats = tsr.execStats
+	tsr.execStats = make(map[string]*dataPoints)
+	tsr.execStatsMu.Unlock()
+
+	tsr.sqlCacheMu.Lock()
+	tsr.fillSQLMetaLocked(data.normalizedSQLMap, data.records, data.execStats)
+	tsr.sqlCacheMu.Unlock()
 
 	// Send to report channel. When channel is full, data will be dropped.
 	select {
@@ -445,7 +868,9 @@ func (tsr *RemoteTopSQLReporter) takeDataAndSendToReportChan(collectedDataPtr *m
 }
 
 type collectedData struct {
-	records           map[string]*dataPoints
+	records map[string]*dataPoints
+	// execStats are the execution stats keyed in the same way as records.
+	execStats         map[string]*dataPoints
 	normalizedSQLMap  *sync.Map
 	normalizedPlanMap *sync.Map
 }
@@ -499,6 +924,16 @@ func (tsr *RemoteTopSQLReporter) reportWorker() {
 // getReportData gets reportData from the collectedData.
 // This function will calculate the topN collected records and the `others` record which aggregation all records that is out of Top N.
 func (tsr *RemoteTopSQLReporter) getReportData(collected collectedData) reportData {
+	// Merge the execution stats into the CPU time records of the same SQL and plan.
+	for key, stats := range collected.execStats {
+		entry, ok := collected.records[key]
+		if !ok {
+			entry = &dataPoints{SQLDigest: stats.SQLDigest, PlanDigest: stats.PlanDigest, IsInternal: stats.IsInternal}
+			collected.records[key] = entry
+		}
+		entry.addExecStats(stats)
+	}
+
 	// Fetch TopN dataPoints.
 	others := collected.records[keyOthers]
 	delete(collected.records, keyOthers)
@@ -519,11 +954,21 @@ func (tsr *RemoteTopSQLReporter) getReportData(collected collectedData) reportDa
 		others = addEvictedIntoSortedDataPoints(others, evict)
 	}
 
-	// append others which summarize all evicted item's cpu-time.
-	if others != nil && others.CPUTimeMsTotal > 0 {
+	// append others which summarize all evicted item's cpu-time and executions.
+	if others != nil && (others.CPUTimeMsTotal > 0 || others.ExecCount > 0) {
 		records = append(records, others)
 	}
 
+	// The precision is read when reporting, so that the change takes effect on the next report.
+	precision := uint64(variable.TopSQLVariable.PrecisionSeconds.Load())
+	for _, record := range records {
+		if record != others {
+			// Sort the dataPoints by timestamp to fix the affect of time jump backward.
+			sort.Sort(record)
+		}
+		record.bucketByPrecision(precision)
+	}
+
 	return reportData{
 		collectedData:     records,
 		normalizedSQLMap:  collected.normalizedSQLMap,
@@ -534,23 +979,24 @@ func (tsr *RemoteTopSQLReporter) getReportData(collected collectedData) reportDa
 func (tsr *RemoteTopSQLReporter) doReport(data reportData) {
 	defer util.Recover("top-sql", "doReport", nil, false)
 
+	// The receivers are updated even if there is no data, so that the removed ones are stopped in time.
+	cfg := config.GetGlobalConfig().TopSQL
+	receivers := tsr.updateReceivers(parseReceiverAddresses(cfg.ReceiverAddress))
+	tsr.window.add(time.Now(), data)
 	if !data.hasData() {
 		return
 	}
+	for _, r := range receivers {
+		r.send(data)
+	}
 
-	agentAddr := config.GetGlobalConfig().TopSQL.ReceiverAddress
-	timeout := reportTimeout
-	failpoint.Inject("resetTimeoutForTest", func(val failpoint.Value) {
-		if val.(bool) {
-			interval := time.Duration(variable.TopSQLVariable.ReportIntervalSeconds.Load()) * time.Second
-			if interval < timeout {
-				timeout = interval
-			}
-		}
-	})
-	ctx, cancel := context.WithTimeout(tsr.ctx, timeout)
+	// The reports are written to the report directory only if the receiver address is empty.
+	if len(receivers) > 0 || cfg.ReportDir == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(tsr.ctx, getReportTimeout())
 	start := time.Now()
-	err := tsr.client.Send(ctx, agentAddr, data)
+	err := tsr.fileClient.Send(ctx, cfg.ReportDir, data)
 	if err != nil {
 		logutil.BgLogger().Warn("[top-sql] client failed to send data", zap.Error(err))
 		reportAllDurationFailedHistogram.Observe(time.Since(start).Seconds())
@@ -559,3 +1005,39 @@ func (tsr *RemoteTopSQLReporter) doReport(data reportData) {
 	}
 	cancel()
 }
+
+// updateReceivers starts the receivers of the new addresses and stops the ones not in addrs, it returns
+// the receivers of addrs.
+func (tsr *RemoteTopSQLReporter) updateReceivers(addrs []string) []*receiver {
+	tsr.receiversMu.Lock()
+	if tsr.ctx.Err() != nil {
+		tsr.receiversMu.Unlock()
+		return nil
+	}
+	result := make([]*receiver, 0, len(addrs))
+	current := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		r, ok := tsr.receivers[addr]
+		if !ok {
+			r = newReceiver(tsr.ctx, addr, NewGRPCReportClient(tsr.decodePlan))
+			tsr.receivers[addr] = r
+			logutil.BgLogger().Info("[top-sql] start reporting to the receiver", zap.String("receiver", addr))
+		}
+		current[addr] = struct{}{}
+		result = append(result, r)
+	}
+	var removed []*receiver
+	for addr, r := range tsr.receivers {
+		if _, ok := current[addr]; !ok {
+			delete(tsr.receivers, addr)
+			removed = append(removed, r)
+		}
+	}
+	tsr.receiversMu.Unlock()
+
+	for _, r := range removed {
+		logutil.BgLogger().Info("[top-sql] stop reporting to the receiver", zap.String("receiver", r.addr))
+		r.close()
+	}
+	return result
+}
@@ -15,9 +15,11 @@
 package reporter
 
 import (
+	"bytes"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -55,7 +57,7 @@ func populateCache(tsr *RemoteTopSQLReporter, begin, end int, timestamp uint64)
 			CPUTimeMs:  uint32(i + 1),
 		})
 	}
-	tsr.Collect(timestamp, records)
+	tsr.Collect(time.Unix(int64(timestamp), 0), records)
 	// sleep a while for the asynchronous collect
 	time.Sleep(100 * time.Millisecond)
 }
@@ -72,8 +74,7 @@ func setupRemoteTopSQLReporter(maxStatementsNum, interval int, addr string) *Rem
 		conf.TopSQL.ReceiverAddress = addr
 	})
 
-	rc := NewGRPCReportClient(mockPlanBinaryDecoderFunc)
-	ts := NewRemoteTopSQLReporter(rc)
+	ts := NewRemoteTopSQLReporter(mockPlanBinaryDecoderFunc)
 	return ts
 }
 
@@ -183,7 +184,7 @@ func newSQLCPUTimeRecord(tsr *RemoteTopSQLReporter, sqlID int, cpuTimeMs uint32)
 }
 
 func collectAndWait(tsr *RemoteTopSQLReporter, timestamp uint64, records []tracecpu.SQLCPUTimeRecord) {
-	tsr.Collect(timestamp, records)
+	tsr.Collect(time.Unix(int64(timestamp), 0), records)
 	time.Sleep(time.Millisecond * 100)
 }
 
@@ -305,12 +306,210 @@ func TestCollectCapacity(t *testing.T) {
 
 	variable.TopSQLVariable.MaxStatementCount.Store(5000)
 	collectedData := make(map[string]*dataPoints)
-	tsr.doCollect(collectedData, 1, genRecord(20000))
+	tsr.doCollect(collectedData, time.Unix(1, 0), genRecord(20000))
 	require.Equal(t, 5001, len(collectedData))
 	require.Equal(t, int64(20000), tsr.sqlMapLength.Load())
 	require.Equal(t, int64(20000), tsr.planMapLength.Load())
 }
 
+func TestRegisterSQLCacheEviction(t *testing.T) {
+	variable.TopSQLVariable.EvictionPolicy.Store(variable.TopSQLEvictionPolicyLRU)
+	defer variable.TopSQLVariable.EvictionPolicy.Store(variable.DefTiDBTopSQLEvictionPolicy)
+	// The capacity of the registered SQL cache is 2 * 2 = 4.
+	tsr := setupRemoteTopSQLReporter(2, 60, "")
+	defer tsr.Close()
+
+	registerSQL := func(ids ...int) {
+		for _, id := range ids {
+			key := []byte("sqlDigest" + strconv.Itoa(id))
+			value := "sqlNormalized" + strconv.Itoa(id)
+			tsr.RegisterSQL(key, value, false)
+		}
+	}
+	// takeSQLMetas returns the SQL metas reported with the CPU time of the SQL digests, and starts the
+	// next report cycle.
+	takeSQLMetas := func(ids ...int) map[string]string {
+		collectedData := make(map[string]*dataPoints)
+		for _, id := range ids {
+			collectedData[strconv.Itoa(id)] = &dataPoints{SQLDigest: []byte("sqlDigest" + strconv.Itoa(id))}
+		}
+		sqlMap := tsr.normalizedSQLMap.Load().(*sync.Map)
+		tsr.takeDataAndSendToReportChan(&collectedData)
+		metas := make(map[string]string)
+		sqlMap.Range(func(key, value interface{}) bool {
+			metas[key.(string)] = value.(SQLMeta).normalizedSQL
+			return true
+		})
+		return metas
+	}
+	checkStats := func(hits, misses, evictions int64) {
+		h, m, e := tsr.CacheStats()
+		require.Equal(t, hits, h)
+		require.Equal(t, misses, m)
+		require.Equal(t, evictions, e)
+	}
+
+	// The SQL digests registered again in the same report cycle do not reach the cache.
+	registerSQL(1, 2, 3, 4, 1, 2)
+	checkStats(0, 4, 0)
+	metas := takeSQLMetas()
+	require.Len(t, metas, 4)
+
+	// The cached SQLs are still reported in the next report cycle they are registered in.
+	registerSQL(1, 2, 3, 4)
+	checkStats(4, 4, 0)
+	metas = takeSQLMetas()
+	require.Len(t, metas, 4)
+	require.Equal(t, "sqlNormalized1", metas["sqlDigest1"])
+
+	// sqlDigest5 and sqlDigest6 evict the least recently used sqlDigest1 and sqlDigest2.
+	registerSQL(5, 6)
+	checkStats(4, 6, 2)
+	// The evicted sqlDigest1 evicts sqlDigest3, while sqlDigest4 is still cached.
+	registerSQL(1, 4)
+	checkStats(5, 7, 3)
+	metas = takeSQLMetas()
+	require.Len(t, metas, 4)
+	require.Equal(t, "sqlNormalized5", metas["sqlDigest5"])
+	require.Equal(t, "sqlNormalized6", metas["sqlDigest6"])
+	require.Equal(t, "sqlNormalized1", metas["sqlDigest1"])
+
+	// The SQL meta of the records not registered in the report cycle is supplied by the cache,
+	// except for the evicted sqlDigest3.
+	metas = takeSQLMetas(3, 4)
+	require.Equal(t, map[string]string{"sqlDigest4": "sqlNormalized4"}, metas)
+
+	// Shrinking tidb_top_sql_max_statement_count evicts the least recently used SQLs.
+	variable.TopSQLVariable.MaxStatementCount.Store(1)
+	registerSQL(4)
+	checkStats(6, 7, 5)
+}
+
+func TestRegisterSQLCacheLFUEviction(t *testing.T) {
+	require.Equal(t, variable.TopSQLEvictionPolicyLFU, variable.TopSQLVariable.EvictionPolicy.Load())
+	// The capacity of the registered SQL cache is 2 * 2 = 4.
+	tsr := setupRemoteTopSQLReporter(2, 60, "")
+	defer tsr.Close()
+
+	// registerSQL registers the SQLs in a new report cycle, so that they are looked up in the cache.
+	registerSQL := func(ids ...int) {
+		tsr.takeDataAndSendToReportChan(&map[string]*dataPoints{})
+		for _, id := range ids {
+			key := []byte("sqlDigest" + strconv.Itoa(id))
+			value := "sqlNormalized" + strconv.Itoa(id)
+			tsr.RegisterSQL(key, value, false)
+		}
+	}
+	collect := func(cpuTimeMs map[int]uint32) {
+		records := make([]tracecpu.SQLCPUTimeRecord, 0, len(cpuTimeMs))
+		for id, ms := range cpuTimeMs {
+			records = append(records, tracecpu.SQLCPUTimeRecord{
+				SQLDigest:  []byte("sqlDigest" + strconv.Itoa(id)),
+				PlanDigest: []byte("planDigest" + strconv.Itoa(id)),
+				CPUTimeMs:  ms,
+			})
+		}
+		tsr.doCollect(make(map[string]*dataPoints), time.Unix(1, 0), records)
+	}
+	checkStats := func(hits, misses, evictions int64) {
+		h, m, e := tsr.CacheStats()
+		require.Equal(t, hits, h)
+		require.Equal(t, misses, m)
+		require.Equal(t, evictions, e)
+	}
+
+	registerSQL(1, 2, 3, 4)
+	collect(map[int]uint32{1: 100, 2: 50, 3: 10})
+	collect(map[int]uint32{1: 100, 3: 10})
+	// sqlDigest5 evicts sqlDigest4, which has no CPU time although it is registered most recently.
+	registerSQL(5)
+	checkStats(0, 5, 1)
+	registerSQL(1, 2, 3, 5)
+	checkStats(4, 5, 1)
+	// The SQLs without CPU time evict each other, the earliest registered one first.
+	registerSQL(4, 6)
+	checkStats(4, 7, 3)
+	registerSQL(1, 2, 3, 6)
+	checkStats(8, 7, 3)
+
+	// Once sqlDigest6 becomes the most frequent, sqlDigest7 evicts sqlDigest3 which has the least CPU time.
+	collect(map[int]uint32{6: 1000})
+	registerSQL(7)
+	checkStats(8, 8, 4)
+	registerSQL(1, 2, 6)
+	checkStats(11, 8, 4)
+	registerSQL(3)
+	checkStats(11, 9, 5)
+
+	// Shrinking tidb_top_sql_max_statement_count keeps the SQLs with the most CPU time.
+	variable.TopSQLVariable.MaxStatementCount.Store(1)
+	registerSQL(6, 1)
+	checkStats(13, 9, 7)
+	variable.TopSQLVariable.MaxStatementCount.Store(2)
+
+	// Changing tidb_top_sql_eviction_policy rebuilds the cache.
+	variable.TopSQLVariable.EvictionPolicy.Store(variable.TopSQLEvictionPolicyLRU)
+	defer variable.TopSQLVariable.EvictionPolicy.Store(variable.DefTiDBTopSQLEvictionPolicy)
+	registerSQL(1)
+	checkStats(13, 10, 9)
+	require.IsType(t, &lruSQLCache{}, tsr.sqlCache)
+}
+
+func TestRegisterSQLCacheLFUSQLMeta(t *testing.T) {
+	require.Equal(t, variable.TopSQLEvictionPolicyLFU, variable.TopSQLVariable.EvictionPolicy.Load())
+	// The capacity of the registered SQL cache is 2 * 2 = 4.
+	tsr := setupRemoteTopSQLReporter(2, 60, "")
+	defer tsr.Close()
+
+	registerSQL := func(ids ...int) {
+		for _, id := range ids {
+			key := []byte("sqlDigest" + strconv.Itoa(id))
+			value := "sqlNormalized" + strconv.Itoa(id)
+			tsr.RegisterSQL(key, value, false)
+		}
+	}
+	// report collects the CPU time of the SQL digests, and returns the reported SQL metas.
+	report := func(ids ...int) map[string]string {
+		records := make([]tracecpu.SQLCPUTimeRecord, 0, len(ids))
+		for _, id := range ids {
+			records = append(records, tracecpu.SQLCPUTimeRecord{
+				SQLDigest:  []byte("sqlDigest" + strconv.Itoa(id)),
+				PlanDigest: []byte("planDigest" + strconv.Itoa(id)),
+				CPUTimeMs:  100,
+			})
+		}
+		collectedData := make(map[string]*dataPoints)
+		tsr.doCollect(collectedData, time.Unix(1, 0), records)
+		sqlMap := tsr.normalizedSQLMap.Load().(*sync.Map)
+		tsr.takeDataAndSendToReportChan(&collectedData)
+		metas := make(map[string]string)
+		sqlMap.Range(func(key, value interface{}) bool {
+			metas[key.(string)] = value.(SQLMeta).normalizedSQL
+			return true
+		})
+		return metas
+	}
+
+	registerSQL(1, 2, 3, 4)
+	require.Len(t, report(1), 4)
+	// The frequent sqlDigest1 is registered and reported again.
+	registerSQL(1)
+	require.Equal(t, map[string]string{"sqlDigest1": "sqlNormalized1"}, report(1))
+
+	// A burst of new SQLs evicts the rare ones, but the SQL meta of the frequent sqlDigest1 is still
+	// reported with its CPU time in the later report cycles, although it is not registered again.
+	for i := 5; i < 10; i++ {
+		registerSQL(i)
+		metas := report(1, 2)
+		require.Equal(t, "sqlNormalized1", metas["sqlDigest1"])
+		require.Equal(t, "sqlNormalized"+strconv.Itoa(i), metas["sqlDigest"+strconv.Itoa(i)])
+	}
+	metas := report(1, 2, 3, 4)
+	require.Equal(t, map[string]string{"sqlDigest1": "sqlNormalized1"}, metas)
+	_, _, evictions := tsr.CacheStats()
+	require.Equal(t, int64(5), evictions)
+}
+
 func TestCollectOthers(t *testing.T) {
 	collectTarget := make(map[string]*dataPoints)
 	addEvictedCPUTime(collectTarget, 1, 10)
@@ -391,6 +590,133 @@ func TestDataPoints(t *testing.T) {
 	require.False(t, d.isInvalid())
 	require.Nil(t, d.CPUTimeMsList)
 	require.Nil(t, d.TimestampList)
+
+	// test for bucketing the timestamps in nanoseconds by the precision.
+	ns := func(sec, nsec int64) uint64 {
+		return uint64(time.Unix(sec, nsec).UnixNano())
+	}
+	d = &dataPoints{}
+	d.TimestampList = []uint64{ns(10, 1), ns(10, 999999999), ns(11, 0), ns(12, 500), ns(13, 0), ns(15, 0)}
+	d.CPUTimeMsList = []uint32{1, 2, 3, 4, 5, 6}
+	d.bucketByPrecision(1)
+	require.Equal(t, []uint64{10, 11, 12, 13, 15}, d.TimestampList)
+	require.Equal(t, []uint32{3, 3, 4, 5, 6}, d.CPUTimeMsList)
+	d.TimestampList = []uint64{ns(10, 1), ns(10, 999999999), ns(11, 0), ns(12, 500), ns(13, 0), ns(15, 0)}
+	d.CPUTimeMsList = []uint32{1, 2, 3, 4, 5, 6}
+	d.bucketByPrecision(2)
+	require.Equal(t, []uint64{10, 12, 14}, d.TimestampList)
+	require.Equal(t, []uint32{6, 9, 6}, d.CPUTimeMsList)
+}
+
+func TestExecStats(t *testing.T) {
+	tsr := setupRemoteTopSQLReporter(2, 60, "")
+	defer tsr.Close()
+	defer variable.TopSQLVariable.TopNMetric.Store(variable.DefTiDBTopSQLTopNMetric)
+
+	digest := func(i int) ([]byte, []byte) {
+		return []byte("sqlDigest" + strconv.Itoa(i)), []byte("planDigest" + strconv.Itoa(i))
+	}
+	collect := func() reportData {
+		// The SQL 1 uses the most CPU time, while the SQL 3 is the slowest.
+		records := make(map[string]*dataPoints)
+		tsr.doCollect(records, time.Unix(1, 0), []tracecpu.SQLCPUTimeRecord{
+			{SQLDigest: []byte("sqlDigest1"), PlanDigest: []byte("planDigest1"), CPUTimeMs: 30},
+			{SQLDigest: []byte("sqlDigest2"), PlanDigest: []byte("planDigest2"), CPUTimeMs: 20},
+		})
+		for i, durations := range [][]time.Duration{{time.Millisecond}, {2 * time.Millisecond, 4 * time.Millisecond}, {time.Second}} {
+			sqlDigest, planDigest := digest(i + 1)
+			for _, d := range durations {
+				tsr.ObserveExecution(sqlDigest, planDigest, false, d)
+			}
+		}
+		tsr.execStatsMu.Lock()
+		execStats := tsr.execStats
+		tsr.execStats = make(map[string]*dataPoints)
+		tsr.execStatsMu.Unlock()
+		return tsr.getReportData(collectedData{
+			records:           records,
+			execStats:         execStats,
+			normalizedSQLMap:  &sync.Map{},
+			normalizedPlanMap: &sync.Map{},
+		})
+	}
+	find := func(data reportData, sqlDigest []byte) *dataPoints {
+		for _, record := range data.collectedData {
+			if bytes.Equal(record.SQLDigest, sqlDigest) {
+				return record
+			}
+		}
+		return nil
+	}
+
+	data := collect()
+	require.Len(t, data.collectedData, 3)
+	sql1, sql2 := find(data, []byte("sqlDigest1")), find(data, []byte("sqlDigest2"))
+	require.NotNil(t, sql1)
+	require.Equal(t, uint64(30), sql1.CPUTimeMsTotal)
+	require.Equal(t, uint64(1), sql1.ExecCount)
+	require.NotNil(t, sql2)
+	require.Equal(t, uint64(2), sql2.ExecCount)
+	require.Equal(t, uint64(6*time.Millisecond), sql2.DurationSumNs)
+	require.Equal(t, uint64(4*time.Millisecond), sql2.DurationMaxNs)
+	// The SQL 3 has no CPU time, it is evicted into others.
+	others := find(data, nil)
+	require.NotNil(t, others)
+	require.Equal(t, uint64(1), others.ExecCount)
+	require.Equal(t, uint64(time.Second), others.DurationSumNs)
+
+	variable.TopSQLVariable.TopNMetric.Store(variable.TopSQLTopNMetricLatency)
+	data = collect()
+	require.Len(t, data.collectedData, 3)
+	require.NotNil(t, find(data, []byte("sqlDigest3")))
+	require.NotNil(t, find(data, []byte("sqlDigest2")))
+	others = find(data, nil)
+	require.NotNil(t, others)
+	require.Equal(t, uint64(30), others.CPUTimeMsTotal)
+	require.Equal(t, uint64(1), others.ExecCount)
+	require.Equal(t, uint64(time.Millisecond), others.DurationSumNs)
+}
+
+func TestMultipleReceivers(t *testing.T) {
+	agentServer1, err := mock.StartMockAgentServer()
+	require.NoError(t, err)
+	defer agentServer1.Stop()
+	agentServer2, err := mock.StartMockAgentServer()
+	require.NoError(t, err)
+	defer agentServer2.Stop()
+
+	tsr := setupRemoteTopSQLReporter(maxSQLNum, 1, agentServer1.Address()+", "+agentServer2.Address())
+	defer tsr.Close()
+	numReceivers := func() int {
+		tsr.receiversMu.Lock()
+		defer tsr.receiversMu.Unlock()
+		return len(tsr.receivers)
+	}
+
+	// A hanging receiver does not block the other one.
+	agentServer2.HangFromNow(5 * time.Second)
+	populateCache(tsr, 0, 10, 1)
+	agentServer1.WaitCollectCnt(1, 3*time.Second)
+	require.Len(t, agentServer1.GetLatestRecords(), 10)
+	require.Equal(t, 2, numReceivers())
+	agentServer2.WaitCollectCnt(1, 10*time.Second)
+	require.Len(t, agentServer2.GetLatestRecords(), 10)
+
+	// The receiver removed at runtime is stopped.
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.TopSQL.ReceiverAddress = agentServer1.Address()
+	})
+	require.Eventually(t, func() bool { return numReceivers() == 1 }, 5*time.Second, 100*time.Millisecond)
+	populateCache(tsr, 10, 15, 2)
+	agentServer1.WaitCollectCnt(1, 3*time.Second)
+	require.Len(t, agentServer1.GetLatestRecords(), 5)
+	require.Empty(t, agentServer2.GetLatestRecords())
+}
+
+func TestParseReceiverAddresses(t *testing.T) {
+	require.Nil(t, parseReceiverAddresses(""))
+	require.Equal(t, []string{"a:1"}, parseReceiverAddresses("a:1"))
+	require.Equal(t, []string{"a:1", "b:2"}, parseReceiverAddresses(" a:1, ,b:2,a:1,"))
 }
 
 func TestCollectInternal(t *testing.T) {
@@ -428,6 +754,46 @@ func TestCollectInternal(t *testing.T) {
 	}
 }
 
+func TestExcludeInternal(t *testing.T) {
+	tsr := setupRemoteTopSQLReporter(10, 60, "")
+	defer tsr.Close()
+	defer variable.TopSQLVariable.IncludeInternal.Store(variable.DefTiDBTopSQLIncludeInternal)
+	variable.TopSQLVariable.IncludeInternal.Store(false)
+
+	// The internal SQLs are neither registered, collected nor observed.
+	tsr.RegisterSQL([]byte("sqlDigest1"), "select * from t", false)
+	tsr.RegisterSQL([]byte("sqlDigest2"), "select * from mysql.stats_meta", true)
+	records := make(map[string]*dataPoints)
+	tsr.doCollect(records, time.Unix(1, 0), []tracecpu.SQLCPUTimeRecord{
+		{SQLDigest: []byte("sqlDigest1"), PlanDigest: []byte("planDigest1"), CPUTimeMs: 10},
+		{SQLDigest: []byte("sqlDigest2"), PlanDigest: []byte("planDigest2"), CPUTimeMs: 20, IsInternal: true},
+	})
+	tsr.ObserveExecution([]byte("sqlDigest1"), []byte("planDigest1"), false, time.Millisecond)
+	tsr.ObserveExecution([]byte("sqlDigest2"), []byte("planDigest2"), true, time.Millisecond)
+	require.Len(t, records, 1)
+	require.Len(t, tsr.execStats, 1)
+	_, ok := tsr.normalizedSQLMap.Load().(*sync.Map).Load("sqlDigest2")
+	require.False(t, ok)
+
+	// The internal SQLs are marked once they are included.
+	variable.TopSQLVariable.IncludeInternal.Store(true)
+	tsr.doCollect(records, time.Unix(2, 0), []tracecpu.SQLCPUTimeRecord{
+		{SQLDigest: []byte("sqlDigest2"), PlanDigest: []byte("planDigest2"), CPUTimeMs: 20, IsInternal: true},
+	})
+	tsr.ObserveExecution([]byte("sqlDigest2"), []byte("planDigest2"), true, time.Millisecond)
+	data := tsr.getReportData(collectedData{
+		records:           records,
+		execStats:         tsr.execStats,
+		normalizedSQLMap:  &sync.Map{},
+		normalizedPlanMap: &sync.Map{},
+	})
+	require.Len(t, data.collectedData, 2)
+	for _, record := range data.collectedData {
+		require.Equal(t, string(record.SQLDigest) == "sqlDigest2", record.IsInternal)
+		require.Equal(t, uint64(1), record.ExecCount)
+	}
+}
+
 func BenchmarkTopSQL_CollectAndIncrementFrequency(b *testing.B) {
 	tsr := initializeCache(maxSQLNum, 120, ":23333")
 	for i := 0; i < b.N; i++ {
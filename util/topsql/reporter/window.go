@@ -0,0 +1,203 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxWindow is the longest time range of the reports kept in memory.
+	MaxWindow = 5 * time.Minute
+	// maxWindowReports bounds the reports kept in memory when the report interval is short.
+	maxWindowReports = 300
+)
+
+// TopSQLStat is the CPU time and the executions of a SQL and plan aggregated in a time window.
+type TopSQLStat struct {
+	SQLDigest     string `json:"sql_digest"`
+	PlanDigest    string `json:"plan_digest"`
+	NormalizedSQL string `json:"normalized_sql"`
+	Plan          string `json:"plan"`
+	CPUTimeMs     uint64 `json:"cpu_time_ms"`
+	ExecCount     uint64 `json:"exec_count"`
+	IsInternal    bool   `json:"is_internal"`
+}
+
+type windowRecord struct {
+	sqlDigest  string
+	planDigest string
+	cpuTimeMs  uint64
+	execCount  uint64
+	isInternal bool
+}
+
+type windowReport struct {
+	timestamp time.Time
+	records   []windowRecord
+}
+
+// windowText is a normalized SQL or binary plan, lastSeen is the last time it is registered or referenced
+// by a report.
+type windowText struct {
+	text     string
+	lastSeen time.Time
+}
+
+// topSQLWindow keeps the Top N records of the reports in the last MaxWindow, so that the top consumers can
+// be queried without a receiver. The normalized SQLs and plans are kept only while they are referenced by
+// the reports in the window, since a report only carries the ones registered in its report cycle.
+type topSQLWindow struct {
+	sync.Mutex
+	reports []windowReport
+	sqls    map[string]windowText
+	plans   map[string]windowText
+}
+
+func newTopSQLWindow() *topSQLWindow {
+	return &topSQLWindow{
+		sqls:  make(map[string]windowText),
+		plans: make(map[string]windowText),
+	}
+}
+
+// add adds the report at now, the aggregation of the records out of Top N is not kept.
+func (w *topSQLWindow) add(now time.Time, data reportData) {
+	report := windowReport{timestamp: now}
+	for _, record := range data.collectedData {
+		if len(record.SQLDigest) == 0 {
+			continue
+		}
+		report.records = append(report.records, windowRecord{
+			sqlDigest:  string(record.SQLDigest),
+			planDigest: string(record.PlanDigest),
+			cpuTimeMs:  record.CPUTimeMsTotal,
+			execCount:  record.ExecCount,
+			isInternal: record.IsInternal,
+		})
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if data.normalizedSQLMap != nil {
+		data.normalizedSQLMap.Range(func(key, value interface{}) bool {
+			w.sqls[key.(string)] = windowText{text: value.(SQLMeta).normalizedSQL, lastSeen: now}
+			return true
+		})
+	}
+	if data.normalizedPlanMap != nil {
+		data.normalizedPlanMap.Range(func(key, value interface{}) bool {
+			w.plans[key.(string)] = windowText{text: value.(string), lastSeen: now}
+			return true
+		})
+	}
+	for _, record := range report.records {
+		touchWindowText(w.sqls, record.sqlDigest, now)
+		touchWindowText(w.plans, record.planDigest, now)
+	}
+	if len(report.records) > 0 {
+		w.reports = append(w.reports, report)
+	}
+	w.expireLocked(now)
+}
+
+func touchWindowText(texts map[string]windowText, key string, now time.Time) {
+	if text, ok := texts[key]; ok {
+		text.lastSeen = now
+		texts[key] = text
+	}
+}
+
+// expireLocked drops the reports, SQLs and plans out of MaxWindow. The caller should hold the lock.
+func (w *topSQLWindow) expireLocked(now time.Time) {
+	deadline := now.Add(-MaxWindow)
+	n := 0
+	for n < len(w.reports) && (w.reports[n].timestamp.Before(deadline) || len(w.reports)-n > maxWindowReports) {
+		w.reports[n] = windowReport{}
+		n++
+	}
+	w.reports = w.reports[n:]
+	for key, text := range w.sqls {
+		if text.lastSeen.Before(deadline) {
+			delete(w.sqls, key)
+		}
+	}
+	for key, text := range w.plans {
+		if text.lastSeen.Before(deadline) {
+			delete(w.plans, key)
+		}
+	}
+}
+
+// query returns the top limit SQLs and plans ordered by the CPU time in the reports of the last window.
+func (w *topSQLWindow) query(now time.Time, window time.Duration, limit int, decodePlan planBinaryDecodeFunc) []TopSQLStat {
+	w.Lock()
+	w.expireLocked(now)
+	deadline := now.Add(-window)
+	aggregated := make(map[string]*TopSQLStat)
+	var stats []*TopSQLStat
+	binaryPlans := make(map[*TopSQLStat]string)
+	for _, report := range w.reports {
+		if report.timestamp.Before(deadline) {
+			continue
+		}
+		for _, record := range report.records {
+			key := record.sqlDigest + record.planDigest
+			stat, ok := aggregated[key]
+			if !ok {
+				stat = &TopSQLStat{
+					SQLDigest:     hex.EncodeToString([]byte(record.sqlDigest)),
+					PlanDigest:    hex.EncodeToString([]byte(record.planDigest)),
+					NormalizedSQL: w.sqls[record.sqlDigest].text,
+				}
+				binaryPlans[stat] = w.plans[record.planDigest].text
+				aggregated[key] = stat
+				stats = append(stats, stat)
+			}
+			stat.CPUTimeMs += record.cpuTimeMs
+			stat.ExecCount += record.execCount
+			stat.IsInternal = stat.IsInternal || record.isInternal
+		}
+	}
+	w.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].CPUTimeMs != stats[j].CPUTimeMs {
+			return stats[i].CPUTimeMs > stats[j].CPUTimeMs
+		}
+		return stats[i].ExecCount > stats[j].ExecCount
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	result := make([]TopSQLStat, 0, len(stats))
+	for _, stat := range stats {
+		if binaryPlan := binaryPlans[stat]; binaryPlan != "" && decodePlan != nil {
+			plan, err := decodePlan(binaryPlan)
+			if err != nil {
+				logutil.BgLogger().Warn("[top-sql] decode plan failed", zap.String("plan-digest", stat.PlanDigest), zap.Error(err))
+			}
+			stat.Plan = plan
+		}
+		result = append(result, *stat)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newWindowTestReport(records []*dataPoints, sqls, plans map[string]string) reportData {
+	sqlMap, planMap := &sync.Map{}, &sync.Map{}
+	for digest, sql := range sqls {
+		sqlMap.Store(digest, SQLMeta{normalizedSQL: sql})
+	}
+	for digest, plan := range plans {
+		planMap.Store(digest, plan)
+	}
+	return reportData{collectedData: records, normalizedSQLMap: sqlMap, normalizedPlanMap: planMap}
+}
+
+func TestTopSQLWindow(t *testing.T) {
+	w := newTopSQLWindow()
+	decodePlan := func(plan string) (string, error) { return "decoded " + plan, nil }
+	now := time.Now()
+
+	// The SQLs are registered only once, the later reports reference them by the digests.
+	w.add(now.Add(-2*time.Minute), newWindowTestReport([]*dataPoints{
+		{SQLDigest: []byte("sql1"), PlanDigest: []byte("plan1"), CPUTimeMsTotal: 100, ExecCount: 1},
+		{SQLDigest: []byte("sql2"), CPUTimeMsTotal: 50, ExecCount: 5},
+		{CPUTimeMsTotal: 1000},
+	}, map[string]string{"sql1": "select ?", "sql2": "insert ?"}, map[string]string{"plan1": "p1"}))
+	w.add(now.Add(-30*time.Second), newWindowTestReport([]*dataPoints{
+		{SQLDigest: []byte("sql2"), CPUTimeMsTotal: 20, ExecCount: 2},
+	}, nil, nil))
+
+	stats := w.query(now, time.Minute, 10, decodePlan)
+	require.Equal(t, []TopSQLStat{
+		{SQLDigest: hex.EncodeToString([]byte("sql2")), NormalizedSQL: "insert ?", CPUTimeMs: 20, ExecCount: 2},
+	}, stats)
+
+	stats = w.query(now, MaxWindow, 10, decodePlan)
+	require.Len(t, stats, 2)
+	require.Equal(t, TopSQLStat{
+		SQLDigest:     hex.EncodeToString([]byte("sql1")),
+		PlanDigest:    hex.EncodeToString([]byte("plan1")),
+		NormalizedSQL: "select ?",
+		Plan:          "decoded p1",
+		CPUTimeMs:     100,
+		ExecCount:     1,
+	}, stats[0])
+	require.Equal(t, uint64(70), stats[1].CPUTimeMs)
+	require.Equal(t, uint64(7), stats[1].ExecCount)
+	require.Len(t, w.query(now, MaxWindow, 1, decodePlan), 1)
+
+	// The reports and the SQLs are dropped once they are out of the window.
+	w.add(now.Add(MaxWindow-time.Minute), reportData{})
+	require.Len(t, w.reports, 1)
+	require.Len(t, w.sqls, 1)
+	require.Empty(t, w.plans)
+	w.add(now.Add(MaxWindow), reportData{})
+	require.Empty(t, w.reports)
+	require.Empty(t, w.sqls)
+	require.Empty(t, w.query(now.Add(MaxWindow), MaxWindow, 10, decodePlan))
+}
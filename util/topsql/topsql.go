@@ -20,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/util/logutil"
@@ -34,18 +35,32 @@ const (
 	MaxSQLTextSize = 4 * 1024
 	// MaxBinaryPlanSize exports for testing.
 	MaxBinaryPlanSize = 2 * 1024
+
+	// SpanTagSQLDigest and SpanTagPlanDigest are the tags of the SQL and plan digests attached to the active
+	// tracing span of the statement.
+	SpanTagSQLDigest  = "db.sql.digest"
+	SpanTagPlanDigest = "db.plan.digest"
 )
 
 var globalTopSQLReport reporter.TopSQLReporter
 
 // SetupTopSQL sets up the top-sql worker.
 func SetupTopSQL() {
-	rc := reporter.NewGRPCReportClient(plancodec.DecodeNormalizedPlan)
-	globalTopSQLReport = reporter.NewRemoteTopSQLReporter(rc)
+	globalTopSQLReport = reporter.NewRemoteTopSQLReporter(plancodec.DecodeNormalizedPlan)
 	tracecpu.GlobalSQLCPUProfiler.SetCollector(globalTopSQLReport)
 	tracecpu.GlobalSQLCPUProfiler.Run()
 }
 
+// GetTopStats returns the top limit SQLs and plans ordered by the CPU time in the last window, it returns nil
+// if Top SQL is not set up.
+func GetTopStats(window time.Duration, limit int) []reporter.TopSQLStat {
+	r, ok := globalTopSQLReport.(*reporter.RemoteTopSQLReporter)
+	if !ok {
+		return nil
+	}
+	return r.TopStats(window, limit)
+}
+
 // Close uses to close and release the top sql resource.
 func Close() {
 	if globalTopSQLReport != nil {
@@ -63,8 +78,9 @@ func AttachSQLInfo(ctx context.Context, normalizedSQL string, sqlDigest *parser.
 	if planDigest != nil {
 		planDigestBytes = planDigest.Bytes()
 	}
-	ctx = tracecpu.CtxWithDigest(ctx, sqlDigestBytes, planDigestBytes)
+	ctx = tracecpu.CtxWithDigest(ctx, sqlDigestBytes, planDigestBytes, isInternal)
 	pprof.SetGoroutineLabels(ctx)
+	attachDigestToSpan(ctx, sqlDigest, planDigest)
 
 	if len(normalizedPlan) == 0 || len(planDigestBytes) == 0 {
 		// If plan digest is '', indicate it is the first time to attach the SQL info, since it only know the sql digest.
@@ -106,6 +122,40 @@ func AttachSQLInfo(ctx context.Context, normalizedSQL string, sqlDigest *parser.
 	return ctx
 }
 
+// attachDigestToSpan tags the active tracing span with the SQL and plan digests, so that the spans exported by
+// the tracer can be associated with the Top SQL records. It does nothing if there is no active span.
+func attachDigestToSpan(ctx context.Context, sqlDigest, planDigest *parser.Digest) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	span.SetTag(SpanTagSQLDigest, sqlDigest.String())
+	if planDigest != nil && len(planDigest.Bytes()) > 0 {
+		span.SetTag(SpanTagPlanDigest, planDigest.String())
+	}
+}
+
+// ObserveStmtFinished records the execution of a finished statement, which is reported along with the CPU time
+// of the same SQL and plan.
+func ObserveStmtFinished(sqlDigest, planDigest *parser.Digest, isInternal bool, duration time.Duration) {
+	if sqlDigest == nil || len(sqlDigest.Bytes()) == 0 {
+		return
+	}
+	c := tracecpu.GlobalSQLCPUProfiler.GetCollector()
+	if c == nil {
+		return
+	}
+	topc, ok := c.(reporter.TopSQLReporter)
+	if !ok {
+		return
+	}
+	var planDigestBytes []byte
+	if planDigest != nil {
+		planDigestBytes = planDigest.Bytes()
+	}
+	topc.ObserveExecution(sqlDigest.Bytes(), planDigestBytes, isInternal, duration)
+}
+
 func linkSQLTextWithDigest(sqlDigest []byte, normalizedSQL string, isInternal bool) {
 	if len(normalizedSQL) > MaxSQLTextSize {
 		normalizedSQL = normalizedSQL[:MaxSQLTextSize]
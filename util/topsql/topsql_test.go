@@ -17,10 +17,13 @@ package topsql_test
 import (
 	"bytes"
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/pprof/profile"
+	basictracer "github.com/opentracing/basictracer-go"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -29,6 +32,7 @@ import (
 	mockServer "github.com/pingcap/tidb/util/topsql/reporter/mock"
 	"github.com/pingcap/tidb/util/topsql/tracecpu"
 	"github.com/pingcap/tidb/util/topsql/tracecpu/mock"
+	"github.com/pingcap/tidb/util/tracing"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,27 +44,38 @@ func TestTopSQLCPUProfile(t *testing.T) {
 	collector := mock.NewTopSQLCollector()
 	tracecpu.GlobalSQLCPUProfiler.SetCollector(&collectorWrapper{collector})
 	reqs := []struct {
-		sql  string
-		plan string
+		sql      string
+		plan     string
+		internal bool
 	}{
-		{"select * from t where a=?", "point-get"},
-		{"select * from t where a>?", "table-scan"},
-		{"insert into t values (?)", ""},
+		{"select * from t where a=?", "point-get", false},
+		{"select * from t where a>?", "table-scan", false},
+		{"insert into t values (?)", "", false},
+		{"select * from mysql.stats_meta", "table-scan", true},
 	}
 
+	// Wait for the executions to finish and their CPU time to be collected, so that the internal SQL is not
+	// collected by the other tests.
+	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	defer func() {
+		cancel()
+		wg.Wait()
+		collector.WaitCollectCnt(2)
+	}()
 	for _, req := range reqs {
-		go func(sql, plan string) {
+		wg.Add(1)
+		go func(sql, plan string, internal bool) {
+			defer wg.Done()
 			for {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					mockExecuteSQL(sql, plan)
+					mockExecuteSQL(sql, plan, internal)
 				}
 			}
-		}(req.sql, req.plan)
+		}(req.sql, req.plan, req.internal)
 	}
 
 	// test for StartCPUProfile.
@@ -80,6 +95,10 @@ func TestTopSQLCPUProfile(t *testing.T) {
 		plan := collector.GetPlan(stats[0].PlanDigest)
 		require.Equal(t, req.sql, sql)
 		require.Equal(t, req.plan, plan)
+		require.Equal(t, req.internal, stats[0].IsInternal)
+	}
+	for _, stats := range collector.GetSQLStatsByInternal(true) {
+		require.Equal(t, "select * from mysql.stats_meta", collector.GetSQL(stats.SQLDigest))
 	}
 }
 
@@ -115,8 +134,7 @@ func TestTopSQLReporter(t *testing.T) {
 		conf.TopSQL.ReceiverAddress = server.Address()
 	})
 
-	client := reporter.NewGRPCReportClient(mockPlanBinaryDecoderFunc)
-	report := reporter.NewRemoteTopSQLReporter(client)
+	report := reporter.NewRemoteTopSQLReporter(mockPlanBinaryDecoderFunc)
 	defer report.Close()
 
 	tracecpu.GlobalSQLCPUProfiler.SetCollector(&collectorWrapper{report})
@@ -145,7 +163,7 @@ func TestTopSQLReporter(t *testing.T) {
 				case <-ctx.Done():
 					return
 				default:
-					mockExecuteSQL(sql, plan)
+					mockExecuteSQL(sql, plan, false)
 				}
 			}
 		}(req.sql, req.plan)
@@ -213,13 +231,13 @@ func setTopSQLEnable(enabled bool) {
 	variable.TopSQLVariable.Enable.Store(enabled)
 }
 
-func mockExecuteSQL(sql, plan string) {
+func mockExecuteSQL(sql, plan string, isInternal bool) {
 	ctx := context.Background()
 	sqlDigest := mock.GenSQLDigest(sql)
-	topsql.AttachSQLInfo(ctx, sql, sqlDigest, "", nil, false)
+	topsql.AttachSQLInfo(ctx, sql, sqlDigest, "", nil, isInternal)
 	mockExecute(time.Millisecond * 100)
 	planDigest := genDigest(plan)
-	topsql.AttachSQLInfo(ctx, sql, sqlDigest, plan, planDigest, false)
+	topsql.AttachSQLInfo(ctx, sql, sqlDigest, plan, planDigest, isInternal)
 	mockExecute(time.Millisecond * 300)
 }
 
@@ -248,3 +266,23 @@ func genStr(n int) string {
 	}
 	return string(buf)
 }
+
+func TestAttachDigestToSpan(t *testing.T) {
+	sqlDigest := mock.GenSQLDigest("select * from t")
+	planDigest := genDigest("plan")
+
+	// It does nothing if there is no active span.
+	topsql.AttachSQLInfo(context.Background(), "select * from t", sqlDigest, "", nil, false)
+
+	var spans []basictracer.RawSpan
+	span := basictracer.New(tracing.CallbackRecorder(func(sp basictracer.RawSpan) {
+		spans = append(spans, sp)
+	})).StartSpan("test")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	topsql.AttachSQLInfo(ctx, "select * from t", sqlDigest, "", nil, false)
+	topsql.AttachSQLInfo(ctx, "select * from t", sqlDigest, "plan", planDigest, false)
+	span.Finish()
+	require.Len(t, spans, 1)
+	require.Equal(t, sqlDigest.String(), spans[0].Tags[topsql.SpanTagSQLDigest])
+	require.Equal(t, planDigest.String(), spans[0].Tags[topsql.SpanTagPlanDigest])
+}
@@ -37,19 +37,25 @@ type TopSQLCollector struct {
 	// (sql + plan_digest) -> sql stats
 	sqlStatsMap map[string]*tracecpu.SQLCPUTimeRecord
 	collectCnt  atomic.Int64
+	// (sql + plan_digest) -> execution stats
+	execStatsMap map[string]*ExecStats
+	// registered SQL cache stats, the mock never evicts.
+	sqlHits   int64
+	sqlMisses int64
 }
 
 // NewTopSQLCollector uses for testing.
 func NewTopSQLCollector() *TopSQLCollector {
 	return &TopSQLCollector{
-		sqlMap:      make(map[string]string),
-		planMap:     make(map[string]string),
-		sqlStatsMap: make(map[string]*tracecpu.SQLCPUTimeRecord),
+		sqlMap:       make(map[string]string),
+		planMap:      make(map[string]string),
+		sqlStatsMap:  make(map[string]*tracecpu.SQLCPUTimeRecord),
+		execStatsMap: make(map[string]*ExecStats),
 	}
 }
 
 // Collect uses for testing.
-func (c *TopSQLCollector) Collect(ts uint64, stats []tracecpu.SQLCPUTimeRecord) {
+func (c *TopSQLCollector) Collect(ts time.Time, stats []tracecpu.SQLCPUTimeRecord) {
 	defer c.collectCnt.Inc()
 	if len(stats) == 0 {
 		return
@@ -63,6 +69,7 @@ func (c *TopSQLCollector) Collect(ts uint64, stats []tracecpu.SQLCPUTimeRecord)
 			stats = &tracecpu.SQLCPUTimeRecord{
 				SQLDigest:  stmt.SQLDigest,
 				PlanDigest: stmt.PlanDigest,
+				IsInternal: stmt.IsInternal,
 			}
 			c.sqlStatsMap[hash] = stats
 		}
@@ -111,6 +118,20 @@ func (c *TopSQLCollector) GetSQLStatsBySQL(sql string, planIsNotNull bool) []*tr
 	return stats
 }
 
+// GetSQLStatsByInternal uses for testing, it returns the stats of the internal SQLs if isInternal is true,
+// otherwise the stats of the user SQLs.
+func (c *TopSQLCollector) GetSQLStatsByInternal(isInternal bool) []*tracecpu.SQLCPUTimeRecord {
+	var stats []*tracecpu.SQLCPUTimeRecord
+	c.Lock()
+	for _, stmt := range c.sqlStatsMap {
+		if stmt.IsInternal == isInternal {
+			stats = append(stats, stmt)
+		}
+	}
+	c.Unlock()
+	return stats
+}
+
 // GetSQL uses for testing.
 func (c *TopSQLCollector) GetSQL(sqlDigest []byte) string {
 	c.Lock()
@@ -134,9 +155,18 @@ func (c *TopSQLCollector) RegisterSQL(sqlDigest []byte, normalizedSQL string, is
 	_, ok := c.sqlMap[digestStr]
 	if !ok {
 		c.sqlMap[digestStr] = normalizedSQL
+		c.sqlMisses++
+	} else {
+		c.sqlHits++
 	}
 	c.Unlock()
+}
 
+// CacheStats uses for testing.
+func (c *TopSQLCollector) CacheStats() (hits, misses, evictions int64) {
+	c.Lock()
+	defer c.Unlock()
+	return c.sqlHits, c.sqlMisses, 0
 }
 
 // RegisterPlan uses for testing.
@@ -150,6 +180,51 @@ func (c *TopSQLCollector) RegisterPlan(planDigest []byte, normalizedPlan string)
 	c.Unlock()
 }
 
+// ExecStats is the execution stats of a SQL and plan observed by the TopSQLCollector.
+type ExecStats struct {
+	ExecCount     uint64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+	IsInternal    bool
+}
+
+// ObserveExecution uses for testing.
+func (c *TopSQLCollector) ObserveExecution(sqlDigest, planDigest []byte, isInternal bool, duration time.Duration) {
+	key := string(sqlDigest) + string(planDigest)
+	c.Lock()
+	defer c.Unlock()
+	stats, ok := c.execStatsMap[key]
+	if !ok {
+		stats = &ExecStats{IsInternal: isInternal}
+		c.execStatsMap[key] = stats
+	}
+	stats.ExecCount++
+	stats.TotalDuration += duration
+	if duration > stats.MaxDuration {
+		stats.MaxDuration = duration
+	}
+}
+
+// GetExecStatsBySQL uses for testing, it returns the execution stats of the SQL summed up by all its plans.
+func (c *TopSQLCollector) GetExecStatsBySQL(sql string) ExecStats {
+	var result ExecStats
+	sqlDigest := GenSQLDigest(sql).Bytes()
+	c.Lock()
+	defer c.Unlock()
+	for key, stats := range c.execStatsMap {
+		if !bytes.HasPrefix([]byte(key), sqlDigest) {
+			continue
+		}
+		result.ExecCount += stats.ExecCount
+		result.TotalDuration += stats.TotalDuration
+		result.IsInternal = result.IsInternal || stats.IsInternal
+		if stats.MaxDuration > result.MaxDuration {
+			result.MaxDuration = stats.MaxDuration
+		}
+	}
+	return result
+}
+
 // WaitCollectCnt uses for testing.
 func (c *TopSQLCollector) WaitCollectCnt(count int64) {
 	timeout := time.After(time.Second * 10)
@@ -39,6 +39,8 @@ const (
 	labelSQL        = "sql"
 	labelSQLDigest  = "sql_digest"
 	labelPlanDigest = "plan_digest"
+	// labelInternal is set to "true" for the internal SQLs.
+	labelInternal = "internal"
 )
 
 // GlobalSQLCPUProfiler is the global SQL stats profiler.
@@ -47,11 +49,11 @@ var GlobalSQLCPUProfiler = newSQLCPUProfiler()
 // Collector uses to collect SQL execution cpu time.
 type Collector interface {
 	// Collect uses to collect the SQL execution cpu time.
-	// ts is a Unix time, unit is second.
-	Collect(ts uint64, stats []SQLCPUTimeRecord)
+	// ts is the end of the profiling window of the stats, which lasts ProfileInterval.
+	Collect(ts time.Time, stats []SQLCPUTimeRecord)
 }
 
-// SQLCPUTimeRecord represents a single record of how much cpu time a sql plan consumes in one second.
+// SQLCPUTimeRecord represents a single record of how much cpu time a sql plan consumes in one profiling window.
 //
 // PlanDigest can be empty, because:
 // 1. some sql statements has no plan, like `COMMIT`
@@ -60,6 +62,8 @@ type SQLCPUTimeRecord struct {
 	SQLDigest  []byte
 	PlanDigest []byte
 	CPUTimeMs  uint32
+	// IsInternal is true if the SQL is executed internally, such as by the statistics and DDL jobs.
+	IsInternal bool
 }
 
 type sqlCPUProfiler struct {
@@ -72,6 +76,11 @@ type sqlCPUProfiler struct {
 	collector atomic.Value
 }
 
+// ProfileInterval is the length of each profiling window. The samples of a window are attributed to the end
+// of the window, since the CPU profile does not record the time of each sample. The reporter groups the
+// windows by tidb_top_sql_precision_seconds when reporting.
+const ProfileInterval = time.Second
+
 var (
 	defaultProfileBufSize = 100 * 1024
 	profileBufPool        = sync.Pool{
@@ -118,7 +127,6 @@ func (sp *sqlCPUProfiler) startCPUProfileWorker() {
 }
 
 func (sp *sqlCPUProfiler) doCPUProfile() {
-	intervalSecond := variable.TopSQLVariable.PrecisionSeconds.Load()
 	task := sp.newProfileTask()
 	if err := pprof.StartCPUProfile(task.buf); err != nil {
 		// Sleep a while before retry.
@@ -126,13 +134,11 @@ func (sp *sqlCPUProfiler) doCPUProfile() {
 		sp.putTaskToBuffer(task)
 		return
 	}
-	ns := int64(time.Second)*intervalSecond - int64(time.Now().Nanosecond())
-	time.Sleep(time.Nanosecond * time.Duration(ns))
+	// Align the windows to the intervals, so that the windows of different instances are comparable.
+	now := time.Now()
+	time.Sleep(now.Truncate(ProfileInterval).Add(ProfileInterval).Sub(now))
 	pprof.StopCPUProfile()
-	task.end = time.Now().Unix()
-	if task.end < 0 {
-		task.end = 0
-	}
+	task.end = time.Now()
 	sp.taskCh <- task
 }
 
@@ -149,7 +155,7 @@ func (sp *sqlCPUProfiler) startAnalyzeProfileWorker() {
 		stats := sp.parseCPUProfileBySQLLabels(p)
 		sp.handleExportProfileTask(p)
 		if c := sp.GetCollector(); c != nil {
-			c.Collect(uint64(task.end), stats)
+			c.Collect(task.end, stats)
 		}
 		sp.putTaskToBuffer(task)
 	}
@@ -157,7 +163,7 @@ func (sp *sqlCPUProfiler) startAnalyzeProfileWorker() {
 
 type profileData struct {
 	buf *bytes.Buffer
-	end int64
+	end time.Time
 }
 
 func (sp *sqlCPUProfiler) newProfileTask() *profileData {
@@ -196,6 +202,9 @@ func (sp *sqlCPUProfiler) parseCPUProfileBySQLLabels(p *profile.Profile) []SQLCP
 				sqlMap[digest] = stmt
 			}
 			stmt.total += s.Value[idx]
+			if internal := s.Label[labelInternal]; len(internal) > 0 && internal[0] == "true" {
+				stmt.isInternal = true
+			}
 
 			plans := s.Label[labelPlanDigest]
 			for _, plan := range plans {
@@ -215,6 +224,7 @@ func (sp *sqlCPUProfiler) createSQLStats(sqlMap map[string]*sqlStats) []SQLCPUTi
 				SQLDigest:  []byte(sqlDigest),
 				PlanDigest: []byte(planDigest),
 				CPUTimeMs:  uint32(time.Duration(val).Milliseconds()),
+				IsInternal: stmt.isInternal,
 			})
 		}
 	}
@@ -222,8 +232,9 @@ func (sp *sqlCPUProfiler) createSQLStats(sqlMap map[string]*sqlStats) []SQLCPUTi
 }
 
 type sqlStats struct {
-	plans map[string]int64
-	total int64
+	plans      map[string]int64
+	total      int64
+	isInternal bool
 }
 
 // tune use to adjust sql stats. Consider following situation:
@@ -302,12 +313,16 @@ func StopCPUProfile() error {
 }
 
 // CtxWithDigest wrap the ctx with sql digest, if plan digest is not null, wrap with plan digest too.
-func CtxWithDigest(ctx context.Context, sqlDigest, planDigest []byte) context.Context {
-	if len(planDigest) == 0 {
-		return pprof.WithLabels(ctx, pprof.Labels(labelSQLDigest, string(hack.String(sqlDigest))))
+// The internal SQLs are labeled so that their records are marked with IsInternal.
+func CtxWithDigest(ctx context.Context, sqlDigest, planDigest []byte, isInternal bool) context.Context {
+	labels := []string{labelSQLDigest, string(hack.String(sqlDigest))}
+	if len(planDigest) > 0 {
+		labels = append(labels, labelPlanDigest, string(hack.String(planDigest)))
+	}
+	if isInternal {
+		labels = append(labels, labelInternal, "true")
 	}
-	return pprof.WithLabels(ctx, pprof.Labels(labelSQLDigest, string(hack.String(sqlDigest)),
-		labelPlanDigest, string(hack.String(planDigest))))
+	return pprof.WithLabels(ctx, pprof.Labels(labels...))
 }
 
 func (sp *sqlCPUProfiler) startExportCPUProfile(w io.Writer) error {
@@ -350,7 +365,7 @@ func (sp *sqlCPUProfiler) removeLabel(p *profile.Profile) {
 				if !keepLabelSQL {
 					delete(s.Label, k)
 				}
-			case labelSQLDigest, labelPlanDigest:
+			case labelSQLDigest, labelPlanDigest, labelInternal:
 				delete(s.Label, k)
 			}
 		}